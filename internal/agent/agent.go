@@ -0,0 +1,108 @@
+// Package agent implements the envref agent: a long-running local process
+// that keeps backend instances, with their signed-in sessions, and a cache
+// of the secret values read through them. The CLI talks to it over a unix
+// socket, so that a resolve triggered on every directory change does not
+// sign in to and query each backend again.
+//
+// The protocol is one JSON Request per connection, answered by one JSON
+// Response. Backends are identified by their configuration: the agent
+// opens a backend the first time it sees a configuration and reuses it for
+// every later request with the same one, so editing .envref.yaml takes
+// effect immediately. Only reads go through the agent.
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/xcke/envref/internal/config"
+)
+
+// SocketFileName is the file name of the agent socket.
+const SocketFileName = "agent.sock"
+
+// DefaultTTL is how long the agent caches a value when no TTL is given.
+const DefaultTTL = 10 * time.Minute
+
+// Request operations.
+const (
+	// OpStatus returns the agent's Status.
+	OpStatus = "status"
+
+	// OpGet reads Keys from Backend, from the cache where possible.
+	// Keys that do not exist are omitted from Response.Values. With TTL
+	// set, every key is read again and cached for TTL instead of the
+	// agent's default.
+	OpGet = "get"
+
+	// OpList returns the cached entries, without their values.
+	OpList = "list"
+
+	// OpInvalidate drops Keys of Backend from the cache, all of its values
+	// if Keys is empty, or every cached value if Backend is nil.
+	// Response.Count is the number of values dropped.
+	OpInvalidate = "invalidate"
+
+	// OpStop shuts the agent down.
+	OpStop = "stop"
+)
+
+// Request is a message from the CLI to the agent.
+type Request struct {
+	Op      string                `json:"op"`
+	Backend *config.BackendConfig `json:"backend,omitempty"`
+	Keys    []string              `json:"keys,omitempty"`
+	TTL     time.Duration         `json:"ttl,omitempty"`
+}
+
+// Response is the agent's answer to a Request. Error is set if the request
+// failed.
+type Response struct {
+	Values  map[string]string `json:"values,omitempty"`
+	Entries []Entry           `json:"entries,omitempty"`
+	Count   int               `json:"count,omitempty"`
+	Status  *Status           `json:"status,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// Entry describes a cached value.
+type Entry struct {
+	Backend string    `json:"backend"`
+	Type    string    `json:"type"`
+	Key     string    `json:"key"`
+	Expires time.Time `json:"expires"`
+}
+
+// Status describes a running agent.
+type Status struct {
+	PID      int           `json:"pid"`
+	Started  time.Time     `json:"started"`
+	TTL      time.Duration `json:"ttl"`
+	Backends int           `json:"backends"`
+	Values   int           `json:"values"`
+}
+
+// DefaultSocketPath returns the agent socket path used when none is
+// configured: envref/agent.sock in $XDG_RUNTIME_DIR if it is set, otherwise
+// in the user's cache directory.
+func DefaultSocketPath() (string, error) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "envref", SocketFileName), nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "envref", SocketFileName), nil
+}
+
+// fingerprint identifies a backend configuration.
+func fingerprint(bc config.BackendConfig) string {
+	data, _ := json.Marshal(bc)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
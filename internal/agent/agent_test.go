@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+)
+
+// countingBackend counts the reads that reach a memory backend.
+type countingBackend struct {
+	*backend.MemoryBackend
+	reads int
+}
+
+func (c *countingBackend) Get(key string) (string, error) {
+	c.reads++
+	return c.MemoryBackend.Get(key)
+}
+
+// startAgent serves an agent whose backends all share store on a socket in
+// a temporary directory.
+func startAgent(t *testing.T, store *countingBackend) (*Server, *Client) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), SocketFileName)
+	l, err := Listen(socket)
+	require.NoError(t, err)
+
+	srv := NewServer(func(bc config.BackendConfig) (backend.Backend, error) {
+		return store, nil
+	}, time.Minute)
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(l) }()
+	t.Cleanup(func() {
+		srv.Stop()
+		require.NoError(t, <-done)
+	})
+	return srv, NewClient(socket)
+}
+
+func TestAgent_GetCachesValues(t *testing.T) {
+	store := &countingBackend{MemoryBackend: backend.NewMemoryBackend("mem")}
+	require.NoError(t, store.Set("app/API_KEY", "sk-123"))
+	_, client := startAgent(t, store)
+
+	bc := config.BackendConfig{Name: "mem", Type: "memory"}
+	for i := 0; i < 3; i++ {
+		values, err := client.GetMany(bc, []string{"app/API_KEY", "app/MISSING"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"app/API_KEY": "sk-123"}, values)
+	}
+	// Found values are cached; missing ones are read every time.
+	assert.Equal(t, 4, store.reads)
+
+	st, err := client.Status()
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Backends)
+	assert.Equal(t, 1, st.Values)
+	assert.Equal(t, time.Minute, st.TTL)
+
+	// Invalidation drops the cached value.
+	require.NoError(t, store.Set("app/API_KEY", "sk-456"))
+	dropped, err := client.Invalidate(bc, "app/API_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, 1, dropped)
+	values, err := client.GetMany(bc, []string{"app/API_KEY"})
+	require.NoError(t, err)
+	assert.Equal(t, "sk-456", values["app/API_KEY"])
+}
+
+func TestAgent_Backend(t *testing.T) {
+	store := &countingBackend{MemoryBackend: backend.NewMemoryBackend("mem")}
+	require.NoError(t, store.Set("KEY", "value"))
+	_, client := startAgent(t, store)
+
+	b := client.Backend(config.BackendConfig{Name: "mem"})
+	assert.Equal(t, "mem", b.Name())
+
+	value, err := b.Get("KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	_, err = b.Get("MISSING")
+	assert.ErrorIs(t, err, backend.ErrNotFound)
+	assert.ErrorIs(t, b.Set("KEY", "other"), ErrReadOnly)
+}
+
+func TestAgent_OpenError(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), SocketFileName)
+	l, err := Listen(socket)
+	require.NoError(t, err)
+	srv := NewServer(func(bc config.BackendConfig) (backend.Backend, error) {
+		return nil, assert.AnError
+	}, 0)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	_, err = NewClient(socket).GetMany(config.BackendConfig{Name: "broken"}, []string{"KEY"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `backend "broken"`)
+}
+
+func TestAgent_Stop(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), SocketFileName)
+	l, err := Listen(socket)
+	require.NoError(t, err)
+	srv := NewServer(nil, 0)
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(l) }()
+
+	client := NewClient(socket)
+	require.NoError(t, client.Stop())
+	require.NoError(t, <-done)
+	_, err = client.Status()
+	assert.Error(t, err)
+}
+
+func TestListen(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "nested", SocketFileName)
+
+	// A stale socket is replaced.
+	require.NoError(t, listenAndAbandon(socket))
+	l, err := Listen(socket)
+	require.NoError(t, err)
+
+	srv := NewServer(nil, 0)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	// A live one is not.
+	_, err = Listen(socket)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already running")
+}
+
+// listenAndAbandon leaves a socket file behind with nothing listening.
+func listenAndAbandon(path string) error {
+	l, err := Listen(path)
+	if err != nil {
+		return err
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	return l.Close()
+}
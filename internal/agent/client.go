@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+)
+
+// dialTimeout bounds connecting to the agent, so that a missing agent
+// costs the CLI next to nothing.
+const dialTimeout = 500 * time.Millisecond
+
+// requestTimeout bounds a request, which may wait for a backend to sign in.
+const requestTimeout = 2 * time.Minute
+
+// ErrReadOnly is returned by writes to a backend reached through the agent.
+var ErrReadOnly = errors.New("agent: only reads go through the agent")
+
+// Client sends requests to the agent listening on a unix socket.
+type Client struct {
+	socket string
+	ttl    time.Duration
+}
+
+// NewClient returns a client for the agent listening on socket.
+func NewClient(socket string) *Client {
+	return &Client{socket: socket}
+}
+
+// WithTTL returns a copy of c whose reads fetch every key again and have
+// the agent cache it for ttl, for example to keep values for a while
+// offline.
+func (c *Client) WithTTL(ttl time.Duration) *Client {
+	return &Client{socket: c.socket, ttl: ttl}
+}
+
+// Socket returns the path of the agent socket.
+func (c *Client) Socket() string {
+	return c.socket
+}
+
+// call sends req and returns the agent's response. An error in the
+// response is returned as an error.
+func (c *Client) call(req Request) (Response, error) {
+	conn, err := net.DialTimeout("unix", c.socket, dialTimeout)
+	if err != nil {
+		return Response{}, fmt.Errorf("connecting to agent: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, fmt.Errorf("sending agent request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("reading agent response: %w", err)
+	}
+	if resp.Error != "" {
+		return Response{}, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// Status returns the state of the running agent. It fails if no agent is
+// listening.
+func (c *Client) Status() (Status, error) {
+	resp, err := c.call(Request{Op: OpStatus})
+	if err != nil {
+		return Status{}, err
+	}
+	if resp.Status == nil {
+		return Status{}, errors.New("agent returned no status")
+	}
+	return *resp.Status, nil
+}
+
+// GetMany reads keys from the backend configured by bc. Keys that do not
+// exist are omitted from the result.
+func (c *Client) GetMany(bc config.BackendConfig, keys []string) (map[string]string, error) {
+	resp, err := c.call(Request{Op: OpGet, Backend: &bc, Keys: keys, TTL: c.ttl})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Values == nil {
+		resp.Values = make(map[string]string)
+	}
+	return resp.Values, nil
+}
+
+// Entries describes the values cached by the agent. The values themselves
+// are not sent.
+func (c *Client) Entries() ([]Entry, error) {
+	resp, err := c.call(Request{Op: OpList})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
+
+// Invalidate drops keys, or all values if keys is empty, from the agent's
+// cache of the backend configured by bc, and returns how many it dropped.
+func (c *Client) Invalidate(bc config.BackendConfig, keys ...string) (int, error) {
+	resp, err := c.call(Request{Op: OpInvalidate, Backend: &bc, Keys: keys})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// Clear drops every value from the agent's cache and returns how many
+// there were.
+func (c *Client) Clear() (int, error) {
+	resp, err := c.call(Request{Op: OpInvalidate})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// Stop shuts the agent down.
+func (c *Client) Stop() error {
+	_, err := c.call(Request{Op: OpStop})
+	return err
+}
+
+// Backend returns a backend that reads through the agent from the backend
+// configured by bc. Writes fail with ErrReadOnly.
+func (c *Client) Backend(bc config.BackendConfig) backend.Backend {
+	return &remoteBackend{client: c, bc: bc}
+}
+
+// remoteBackend is the Backend returned by Client.Backend.
+type remoteBackend struct {
+	client *Client
+	bc     config.BackendConfig
+}
+
+// Name returns the configured backend name.
+func (r *remoteBackend) Name() string {
+	return r.bc.Name
+}
+
+// Get reads key through the agent.
+func (r *remoteBackend) Get(key string) (string, error) {
+	values, err := r.GetMany([]string{key})
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", backend.ErrNotFound
+	}
+	return value, nil
+}
+
+// GetMany reads keys through the agent in one request.
+func (r *remoteBackend) GetMany(keys []string) (map[string]string, error) {
+	return r.client.GetMany(r.bc, keys)
+}
+
+// Set fails with ErrReadOnly.
+func (r *remoteBackend) Set(key, value string) error {
+	return ErrReadOnly
+}
+
+// Delete fails with ErrReadOnly.
+func (r *remoteBackend) Delete(key string) error {
+	return ErrReadOnly
+}
+
+// List fails with ErrReadOnly.
+func (r *remoteBackend) List() ([]string, error) {
+	return nil, ErrReadOnly
+}
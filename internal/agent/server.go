@@ -0,0 +1,317 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+)
+
+// OpenFunc creates the backend for a configuration, with its middleware.
+type OpenFunc func(bc config.BackendConfig) (backend.Backend, error)
+
+// Server answers agent requests.
+type Server struct {
+	open    OpenFunc
+	ttl     time.Duration
+	now     func() time.Time
+	started time.Time
+
+	mu       sync.Mutex
+	backends map[string]*served
+	listener net.Listener
+	stopped  bool
+}
+
+// served is a backend opened by the agent and the values read through it.
+// mu serializes calls to the backend, which need not be safe for
+// concurrent use.
+type served struct {
+	config  config.BackendConfig
+	backend backend.Backend
+
+	mu     sync.Mutex
+	values map[string]cachedValue
+}
+
+// cachedValue is a cached secret value and when it expires.
+type cachedValue struct {
+	value   string
+	expires time.Time
+}
+
+// NewServer returns a server that opens backends with open and caches the
+// values read through them for ttl. A zero ttl means DefaultTTL.
+func NewServer(open OpenFunc, ttl time.Duration) *Server {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Server{open: open, ttl: ttl, now: time.Now, started: time.Now(), backends: make(map[string]*served)}
+}
+
+// Listen creates the unix socket at path, readable only by the current
+// user. A socket left behind by an agent that is no longer running is
+// replaced; a live one is an error.
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating socket directory: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if _, err := NewClient(path).Status(); err == nil {
+			return nil, fmt.Errorf("an agent is already running on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = l.Close()
+		return nil, fmt.Errorf("securing socket: %w", err)
+	}
+	return l, nil
+}
+
+// Serve accepts connections on l until Stop is called or a stop request
+// arrives, then closes the backends. It returns nil after a stop.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		_ = l.Close()
+		return nil
+	}
+	s.listener = l
+	s.mu.Unlock()
+	defer s.closeBackends()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			stopped := s.stopped
+			s.mu.Unlock()
+			if stopped {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Stop stops Serve.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+}
+
+// handle answers the one request on conn.
+func (s *Server) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		_ = json.NewEncoder(conn).Encode(Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	resp := s.Do(req)
+	_ = json.NewEncoder(conn).Encode(resp)
+	if req.Op == OpStop && resp.Error == "" {
+		s.Stop()
+	}
+}
+
+// Do answers req. A stop request is only acknowledged; the caller stops
+// the server.
+func (s *Server) Do(req Request) Response {
+	switch req.Op {
+	case OpStatus:
+		status := s.status()
+		return Response{Status: &status}
+	case OpGet:
+		if req.Backend == nil {
+			return Response{Error: "get: no backend"}
+		}
+		values, err := s.get(*req.Backend, req.Keys, req.TTL)
+		if err != nil {
+			return Response{Error: err.Error()}
+		}
+		return Response{Values: values}
+	case OpList:
+		return Response{Entries: s.list()}
+	case OpInvalidate:
+		return Response{Count: s.invalidate(req.Backend, req.Keys)}
+	case OpStop:
+		return Response{}
+	default:
+		return Response{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+	}
+}
+
+// status reports the agent's state.
+func (s *Server) status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Status{PID: os.Getpid(), Started: s.started, TTL: s.ttl, Backends: len(s.backends)}
+	now := s.now()
+	for _, sv := range s.backends {
+		sv.mu.Lock()
+		for _, v := range sv.values {
+			if now.Before(v.expires) {
+				st.Values++
+			}
+		}
+		sv.mu.Unlock()
+	}
+	return st
+}
+
+// backend returns the served backend for bc, opening it on first use.
+func (s *Server) backend(bc config.BackendConfig) (*served, error) {
+	id := fingerprint(bc)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sv, ok := s.backends[id]; ok {
+		return sv, nil
+	}
+	b, err := s.open(bc)
+	if err != nil {
+		return nil, fmt.Errorf("backend %q: %w", bc.Name, err)
+	}
+	sv := &served{config: bc, backend: b, values: make(map[string]cachedValue)}
+	s.backends[id] = sv
+	return sv, nil
+}
+
+// get returns the values of keys in bc's backend, reading the ones that
+// are not cached in one batch. A non-zero ttl reads every key and caches
+// it for ttl.
+func (s *Server) get(bc config.BackendConfig, keys []string, ttl time.Duration) (map[string]string, error) {
+	sv, err := s.backend(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	now := s.now()
+	values := make(map[string]string, len(keys))
+	var missing []string
+	for _, key := range keys {
+		if v, ok := sv.values[key]; ok && ttl == 0 && now.Before(v.expires) {
+			values[key] = v.value
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	fetched, err := backend.GetMany(sv.backend, missing)
+	if err != nil {
+		return nil, err
+	}
+	if ttl == 0 {
+		ttl = s.ttl
+	}
+	expires := s.now().Add(ttl)
+	for key, value := range fetched {
+		sv.values[key] = cachedValue{value: value, expires: expires}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// list returns the unexpired cached entries, sorted by backend and key.
+func (s *Server) list() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []Entry
+	now := s.now()
+	for _, sv := range s.backends {
+		sv.mu.Lock()
+		for key, v := range sv.values {
+			if now.Before(v.expires) {
+				entries = append(entries, Entry{Backend: sv.config.Name, Type: sv.config.EffectiveType(), Key: key, Expires: v.expires})
+			}
+		}
+		sv.mu.Unlock()
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Backend != entries[j].Backend {
+			return entries[i].Backend < entries[j].Backend
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// invalidate drops keys, or every value if keys is empty, from the cache
+// of bc's backend, or of every backend if bc is nil. It returns the number
+// of values dropped.
+func (s *Server) invalidate(bc *config.BackendConfig, keys []string) int {
+	s.mu.Lock()
+	var targets []*served
+	if bc == nil {
+		for _, sv := range s.backends {
+			targets = append(targets, sv)
+		}
+	} else if sv, ok := s.backends[fingerprint(*bc)]; ok {
+		targets = append(targets, sv)
+	}
+	s.mu.Unlock()
+
+	dropped := 0
+	for _, sv := range targets {
+		sv.mu.Lock()
+		if len(keys) == 0 {
+			dropped += len(sv.values)
+			sv.values = make(map[string]cachedValue)
+		} else {
+			for _, key := range keys {
+				if _, ok := sv.values[key]; ok {
+					delete(sv.values, key)
+					dropped++
+				}
+			}
+		}
+		sv.mu.Unlock()
+	}
+	return dropped
+}
+
+// closeBackends closes the backends that hold resources.
+func (s *Server) closeBackends() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sv := range s.backends {
+		if c, ok := sv.backend.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+	s.backends = make(map[string]*served)
+}
@@ -0,0 +1,188 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// An entry's hash is the SHA-256 of the previous entry's hash, a newline,
+// and the entry's own JSON encoding with Hash and Sig left empty. Each entry
+// thus commits to every entry before it, so editing, deleting, or reordering
+// lines breaks the chain from that point on. The chain starts at the first
+// entry with a hash; entries written before hashing was introduced have
+// none and are not protected.
+//
+// A hash chain alone does not stop someone from rewriting the rest of the
+// chain after an edit. With a signing key, each hash is also signed with
+// HMAC-SHA256, which cannot be recomputed without the key. Removing entries
+// from the end of the log is detectable only by comparing it with an
+// earlier copy, such as the one committed to git.
+
+// chainHash returns the hash of e chained to the previous entry's hash.
+func chainHash(prev string, e Entry) (string, error) {
+	e.Hash, e.Sig = "", ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("marshaling audit entry: %w", err)
+	}
+	h := sha256.New()
+	_, _ = io.WriteString(h, prev)
+	_, _ = io.WriteString(h, "\n")
+	_, _ = h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sign returns the HMAC-SHA256 signature of an entry hash.
+func sign(key []byte, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = io.WriteString(mac, hash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// tailChunkSize is how many bytes lastHash reads at a time from the end of
+// the log.
+const tailChunkSize = 4096
+
+// lastHash returns the hash of the last entry in the log file, or "" if the
+// log is empty or its last entry has no hash.
+func lastHash(f *os.File) (string, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("reading audit log: %w", err)
+	}
+
+	// Read backwards until the buffer holds the whole last non-empty line.
+	var buf []byte
+	end := info.Size()
+	for end > 0 {
+		n := min(int64(tailChunkSize), end)
+		chunk := make([]byte, n)
+		if _, err := f.ReadAt(chunk, end-n); err != nil {
+			return "", fmt.Errorf("reading audit log: %w", err)
+		}
+		buf = append(chunk, buf...)
+		end -= n
+
+		line := bytes.TrimRight(buf, "\n")
+		if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+			buf = line[i+1:]
+			break
+		}
+	}
+
+	line := bytes.TrimRight(buf, "\n")
+	if len(line) == 0 {
+		return "", nil
+	}
+	var e Entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		return "", fmt.Errorf("parsing last audit entry: %w", err)
+	}
+	return e.Hash, nil
+}
+
+// Problem is an integrity problem found by Verify.
+type Problem struct {
+	// Line is the 1-based line number in the log file.
+	Line int
+	// Message describes the problem.
+	Message string
+}
+
+// VerifyReport summarizes the result of verifying an audit log.
+type VerifyReport struct {
+	// Entries is the number of entries in the log.
+	Entries int
+	// Unchained is the number of entries before the hash chain starts,
+	// which are not protected.
+	Unchained int
+	// Signed is the number of entries whose signature was checked.
+	Signed int
+	// Problems lists the integrity problems, in line order.
+	Problems []Problem
+}
+
+// OK reports whether the log passed verification.
+func (r *VerifyReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify checks the hash chain of JSON-lines audit log data. With a
+// non-nil key it also checks entry signatures: once a signed entry
+// appears, every later entry must carry a valid signature. Malformed lines
+// are reported as problems rather than errors.
+func Verify(data []byte, key []byte) *VerifyReport {
+	report := &VerifyReport{}
+	var (
+		prev    string
+		chained bool
+		signed  bool
+	)
+
+	lineNo := 0
+	start := 0
+	for i := 0; i <= len(data); i++ {
+		if i != len(data) && data[i] != '\n' {
+			continue
+		}
+		line := data[start:i]
+		start = i + 1
+		lineNo++
+		if len(line) == 0 {
+			continue
+		}
+
+		report.Entries++
+		problem := func(format string, args ...any) {
+			report.Problems = append(report.Problems, Problem{Line: lineNo, Message: fmt.Sprintf(format, args...)})
+		}
+
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			problem("malformed entry: %v", err)
+			continue
+		}
+
+		if e.Hash == "" {
+			if chained {
+				problem("entry has no hash but follows hashed entries (inserted or edited)")
+			} else {
+				report.Unchained++
+			}
+			continue
+		}
+		chained = true
+
+		want, err := chainHash(prev, e)
+		if err != nil {
+			problem("%v", err)
+		} else if want != e.Hash {
+			problem("hash mismatch: this entry or the one before it was modified, or entries were removed here")
+		}
+		// Continue the chain from the stored hash, so that one broken link
+		// is reported once.
+		prev = e.Hash
+
+		if key == nil {
+			continue
+		}
+		switch {
+		case e.Sig == "" && signed:
+			problem("entry is not signed but follows signed entries")
+		case e.Sig == "":
+		case !hmac.Equal([]byte(e.Sig), []byte(sign(key, e.Hash))):
+			signed = true
+			problem("invalid signature: the entry was not written with this project's audit key")
+		default:
+			signed = true
+			report.Signed++
+		}
+	}
+	return report
+}
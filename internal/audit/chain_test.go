@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeChain logs n entries with logger and returns the log's lines.
+func writeChain(t *testing.T, logger *Logger, n int) []string {
+	t.Helper()
+	for i := range n {
+		require.NoError(t, logger.Log(Entry{
+			Timestamp: "2025-01-15T10:00:00Z",
+			User:      "alice",
+			Operation: OpSet,
+			Key:       "KEY_" + string(rune('A'+i)),
+			Backend:   "keychain",
+			Project:   "myapp",
+		}))
+	}
+	data, err := os.ReadFile(logger.Path())
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// verifyLines verifies the given log lines.
+func verifyLines(lines []string, key []byte) *VerifyReport {
+	return Verify([]byte(strings.Join(lines, "\n")+"\n"), key)
+}
+
+func TestLogger_Log_ChainsEntries(t *testing.T) {
+	logger := NewLogger(filepath.Join(t.TempDir(), "audit.log"))
+	lines := writeChain(t, logger, 3)
+
+	entries, err := ParseEntries([]byte(strings.Join(lines, "\n")))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, e := range entries {
+		assert.Len(t, e.Hash, 64, "entry %d", i)
+		assert.Empty(t, e.Sig, "entry %d", i)
+	}
+	assert.NotEqual(t, entries[0].Hash, entries[1].Hash)
+
+	report, err := logger.Verify()
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "problems: %v", report.Problems)
+	assert.Equal(t, 3, report.Entries)
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	logger := NewLogger(filepath.Join(t.TempDir(), "audit.log"))
+	lines := writeChain(t, logger, 4)
+
+	tests := []struct {
+		name     string
+		lines    []string
+		wantLine int
+	}{
+		{"modified", []string{lines[0], strings.Replace(lines[1], `"alice"`, `"mallory"`, 1), lines[2], lines[3]}, 2},
+		{"deleted", []string{lines[0], lines[2], lines[3]}, 2},
+		{"reordered", []string{lines[0], lines[2], lines[1], lines[3]}, 2},
+		{"inserted unhashed", []string{lines[0], `{"timestamp":"2025-01-15T10:00:00Z","user":"x","operation":"set","key":"K","backend":"b","project":"p"}`, lines[1], lines[2], lines[3]}, 2},
+		{"malformed", []string{lines[0], "not json", lines[1], lines[2], lines[3]}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := verifyLines(tt.lines, nil)
+			require.False(t, report.OK())
+			assert.Equal(t, tt.wantLine, report.Problems[0].Line)
+		})
+	}
+}
+
+func TestVerify_LegacyEntriesBeforeChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	legacy := `{"timestamp":"2025-01-01T00:00:00Z","user":"bob","operation":"set","key":"OLD","backend":"keychain","project":"myapp"}` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(legacy), 0o644))
+
+	logger := NewLogger(path)
+	lines := writeChain(t, logger, 2)
+	require.Len(t, lines, 3)
+
+	report, err := logger.Verify()
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "problems: %v", report.Problems)
+	assert.Equal(t, 1, report.Unchained)
+}
+
+func TestVerify_Signatures(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// Two unsigned entries, then two signed ones.
+	writeChain(t, NewLogger(path), 2)
+	lines := writeChain(t, NewLogger(path, WithSigningKey(key)), 2)
+	require.Len(t, lines, 4)
+	assert.NotContains(t, lines[1], `"sig"`)
+	assert.Contains(t, lines[3], `"sig"`)
+
+	report := verifyLines(lines, key)
+	assert.True(t, report.OK(), "problems: %v", report.Problems)
+	assert.Equal(t, 2, report.Signed)
+
+	// Without the key, signatures are not checked.
+	report = verifyLines(lines, nil)
+	assert.True(t, report.OK())
+	assert.Zero(t, report.Signed)
+
+	// A different key rejects the signatures.
+	report = verifyLines(lines, []byte("another key"))
+	require.Len(t, report.Problems, 2)
+	assert.Contains(t, report.Problems[0].Message, "invalid signature")
+
+	// Stripping the signature from a signed entry is detected.
+	stripped := append([]string(nil), lines...)
+	stripped[3] = strings.Replace(stripped[3], `"sig"`, `"x"`, 1)
+	report = verifyLines(stripped, key)
+	require.Len(t, report.Problems, 1)
+	assert.Equal(t, 4, report.Problems[0].Line)
+	assert.Contains(t, report.Problems[0].Message, "not signed")
+}
+
+func TestLastHash_LongEntries(t *testing.T) {
+	logger := NewLogger(filepath.Join(t.TempDir(), "audit.log"))
+	for range 3 {
+		require.NoError(t, logger.Log(Entry{Operation: OpSet, Key: "K", Detail: strings.Repeat("x", tailChunkSize+100)}))
+	}
+
+	report, err := logger.Verify()
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "problems: %v", report.Problems)
+}
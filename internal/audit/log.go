@@ -1,10 +1,14 @@
 // Package audit provides an append-only, JSON-lines audit log for tracking
-// secret operations (set, delete, rotate, copy, generate) in an envref project.
+// secret operations (set, delete, rotate, copy, generate, rollback, and
+// optionally reads) in an envref project.
 //
 // The log file is stored at .envref.audit.log in the project root (alongside
 // .envref.yaml). Each line is a JSON object representing a single operation.
 // The file is designed to be committed to git so the full history of who
 // changed what is preserved in version control.
+//
+// Entries are hash-chained, and optionally HMAC-signed, so that Verify can
+// detect lines that were modified, inserted, or deleted after the fact.
 package audit
 
 import (
@@ -13,6 +17,8 @@ import (
 	"os"
 	"os/user"
 	"time"
+
+	"github.com/xcke/envref/internal/secret"
 )
 
 // Operation represents the type of secret operation that was performed.
@@ -30,8 +36,15 @@ const (
 	OpRotate Operation = "rotate"
 	// OpCopy is logged when a secret is copied from another project.
 	OpCopy Operation = "copy"
-	// OpImport is logged when secrets are imported via sync pull.
+	// OpImport is logged when secrets are imported via sync pull or secret
+	// receive.
 	OpImport Operation = "import"
+	// OpRollback is logged when an earlier version of a secret is made
+	// current again.
+	OpRollback Operation = "rollback"
+	// OpRead is logged when a secret value is read, if the project enables
+	// read auditing.
+	OpRead Operation = "read"
 )
 
 // Entry is a single audit log record. Each record captures who performed
@@ -53,21 +66,44 @@ type Entry struct {
 	Profile string `json:"profile,omitempty"`
 	// Detail contains optional extra context (e.g., source project for copy).
 	Detail string `json:"detail,omitempty"`
+	// Hash chains the entry to the one before it. It is set by Log.
+	Hash string `json:"hash,omitempty"`
+	// Sig is the HMAC-SHA256 signature of Hash, set by Log when the logger
+	// has a signing key.
+	Sig string `json:"sig,omitempty"`
 }
 
 // Logger writes audit entries to a JSON-lines file.
 type Logger struct {
-	path string
+	path  string
+	key   []byte
+	sinks []Sink
+}
+
+// LoggerOption configures a Logger.
+type LoggerOption func(*Logger)
+
+// WithSigningKey signs every entry written by the logger with key.
+func WithSigningKey(key []byte) LoggerOption {
+	return func(l *Logger) {
+		l.key = key
+	}
 }
 
 // NewLogger creates a Logger that appends entries to the given file path.
 // The file is created on the first write if it does not exist.
-func NewLogger(path string) *Logger {
-	return &Logger{path: path}
+func NewLogger(path string, opts ...LoggerOption) *Logger {
+	l := &Logger{path: path}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
 }
 
-// Log appends a single audit entry to the log file. The entry's Timestamp
-// and User fields are set automatically if empty.
+// Log appends a single audit entry to the log file and then sends it to the
+// logger's sinks. The entry's Timestamp and User fields are set
+// automatically if empty. Sink errors are returned after the entry has been
+// written locally.
 func (l *Logger) Log(e Entry) error {
 	if e.Timestamp == "" {
 		e.Timestamp = time.Now().UTC().Format(time.RFC3339)
@@ -75,24 +111,44 @@ func (l *Logger) Log(e Entry) error {
 	if e.User == "" {
 		e.User = currentUser()
 	}
+	// The log is committed to git, so a detail must never carry a secret.
+	e.Detail = secret.Redact(e.Detail)
 
-	data, err := json.Marshal(e)
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o644)
 	if err != nil {
-		return fmt.Errorf("marshaling audit entry: %w", err)
+		return fmt.Errorf("opening audit log: %w", err)
 	}
-	data = append(data, '\n')
 
-	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	prev, err := lastHash(f)
 	if err != nil {
-		return fmt.Errorf("opening audit log: %w", err)
+		_ = f.Close()
+		return err
+	}
+	if e.Hash, err = chainHash(prev, e); err != nil {
+		_ = f.Close()
+		return err
 	}
+	e.Sig = ""
+	if l.key != nil {
+		e.Sig = sign(l.key, e.Hash)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("marshaling audit entry: %w", err)
+	}
+	data = append(data, '\n')
 
 	if _, err := f.Write(data); err != nil {
 		_ = f.Close()
 		return fmt.Errorf("writing audit entry: %w", err)
 	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing audit entry: %w", err)
+	}
 
-	return f.Close()
+	return l.send(e)
 }
 
 // Read returns all entries from the audit log, in order from oldest to newest.
@@ -109,6 +165,16 @@ func (l *Logger) Read() ([]Entry, error) {
 	return ParseEntries(data)
 }
 
+// Verify checks the integrity of the audit log; see the package-level
+// Verify. A missing log verifies as empty.
+func (l *Logger) Verify() (*VerifyReport, error) {
+	data, err := os.ReadFile(l.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading audit log: %w", err)
+	}
+	return Verify(data, l.key), nil
+}
+
 // ParseEntries parses JSON-lines audit log data into a slice of Entry values.
 // Blank lines are silently skipped. Malformed lines produce an error.
 func ParseEntries(data []byte) ([]Entry, error) {
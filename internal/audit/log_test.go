@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/xcke/envref/internal/secret"
 )
 
 func TestLogger_Log_CreatesFile(t *testing.T) {
@@ -83,6 +84,24 @@ func TestLogger_Log_AutoFillsTimestampAndUser(t *testing.T) {
 	assert.NotEmpty(t, entries[0].User)
 }
 
+func TestLogger_Log_RedactsDetail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	logger := NewLogger(path)
+
+	secret.Track("audit-detail-secret")
+	require.NoError(t, logger.Log(Entry{
+		Operation: OpSet,
+		Key:       "API_KEY",
+		Detail:    "value audit-detail-secret",
+	}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "audit-detail-secret")
+	assert.Contains(t, string(data), `"detail":"value ***"`)
+}
+
 func TestLogger_Read_EmptyFileReturnsNil(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "audit.log")
@@ -0,0 +1,315 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sink receives a copy of every audit entry written by a Logger, for
+// central collection. Delivery is best-effort: a failing sink never stops
+// the entry from being written to the local log.
+type Sink interface {
+	// Name identifies the sink in error messages.
+	Name() string
+	// Send delivers a single entry.
+	Send(e Entry) error
+}
+
+// WithSinks sends every entry written by the logger to sinks as well.
+func WithSinks(sinks ...Sink) LoggerOption {
+	return func(l *Logger) {
+		l.sinks = append(l.sinks, sinks...)
+	}
+}
+
+// sinkTimeout bounds the delivery of an entry to one sink.
+const sinkTimeout = 5 * time.Second
+
+// send delivers e to every sink and returns their errors joined.
+func (l *Logger) send(e Entry) error {
+	var errs []error
+	for _, s := range l.sinks {
+		if err := s.Send(e); err != nil {
+			errs = append(errs, fmt.Errorf("audit sink %s: %w", s.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syslogPriority is the syslog priority of audit messages: facility
+// authpriv (10), severity notice (5).
+const syslogPriority = 10*8 + 5
+
+// localSyslogSockets are the usual paths of the local syslog socket.
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogSink sends entries to a syslog daemon, as the JSON-encoded message
+// of an authpriv.notice record tagged "envref".
+type SyslogSink struct {
+	network string
+	addr    string
+}
+
+// NewSyslogSink returns a sink for the syslog server at address:
+// "udp://host:port", "tcp://host:port", or "unix:///path". An empty address
+// means the local syslog daemon.
+func NewSyslogSink(address string) (*SyslogSink, error) {
+	if address == "" {
+		return &SyslogSink{}, nil
+	}
+	scheme, addr, ok := strings.Cut(address, "://")
+	if !ok || addr == "" {
+		return nil, fmt.Errorf("invalid syslog address %q", address)
+	}
+	switch scheme {
+	case "udp", "tcp", "unix":
+		return &SyslogSink{network: scheme, addr: addr}, nil
+	default:
+		return nil, fmt.Errorf("invalid syslog address %q: unsupported network %q", address, scheme)
+	}
+}
+
+// Name returns "syslog".
+func (s *SyslogSink) Name() string {
+	return "syslog"
+}
+
+// Send writes e as one syslog message.
+func (s *SyslogSink) Send(e Entry) error {
+	conn, local, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshaling audit entry: %w", err)
+	}
+
+	// The same formats as the standard library's log/syslog: local
+	// daemons expect no hostname, remote ones a full timestamp and
+	// hostname.
+	var msg string
+	if local {
+		msg = fmt.Sprintf("<%d>%s envref[%d]: %s\n", syslogPriority, time.Now().Format(time.Stamp), os.Getpid(), body)
+	} else {
+		host, _ := os.Hostname()
+		msg = fmt.Sprintf("<%d>%s %s envref[%d]: %s\n", syslogPriority, time.Now().Format(time.RFC3339), host, os.Getpid(), body)
+	}
+
+	_ = conn.SetWriteDeadline(time.Now().Add(sinkTimeout))
+	if _, err := io.WriteString(conn, msg); err != nil {
+		return fmt.Errorf("writing to syslog: %w", err)
+	}
+	return nil
+}
+
+// dial connects to the syslog server. local reports whether the connection
+// is to a local socket.
+func (s *SyslogSink) dial() (conn net.Conn, local bool, err error) {
+	if s.network != "" {
+		network := s.network
+		if network == "unix" {
+			network = "unixgram"
+		}
+		conn, err := net.DialTimeout(network, s.addr, sinkTimeout)
+		if err != nil && s.network == "unix" {
+			conn, err = net.DialTimeout("unix", s.addr, sinkTimeout)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("connecting to syslog: %w", err)
+		}
+		return conn, s.network == "unix", nil
+	}
+
+	for _, path := range localSyslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.DialTimeout(network, path, sinkTimeout); err == nil {
+				return conn, true, nil
+			}
+		}
+	}
+	return nil, false, errors.New("no local syslog daemon found; set an address")
+}
+
+// WebhookSink POSTs each entry as a JSON object to a URL.
+type WebhookSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhookSink returns a sink that posts entries to url with the given
+// extra headers.
+func NewWebhookSink(url string, headers map[string]string) *WebhookSink {
+	return &WebhookSink{url: url, headers: headers, client: &http.Client{Timeout: sinkTimeout}}
+}
+
+// Name returns "webhook".
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Send posts e to the webhook URL.
+func (s *WebhookSink) Send(e Entry) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshaling audit entry: %w", err)
+	}
+	return postJSON(s.client, s.url, s.headers, body)
+}
+
+// DefaultOTLPEndpoint is the OTLP/HTTP logs endpoint of a local
+// OpenTelemetry collector.
+const DefaultOTLPEndpoint = "http://localhost:4318/v1/logs"
+
+// OTLPSink exports each entry as an OpenTelemetry log record over
+// OTLP/HTTP with JSON encoding.
+type OTLPSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewOTLPSink returns a sink that exports entries to the OTLP/HTTP logs
+// endpoint url. An empty url falls back to the standard
+// OTEL_EXPORTER_OTLP_LOGS_ENDPOINT and OTEL_EXPORTER_OTLP_ENDPOINT
+// environment variables, then DefaultOTLPEndpoint.
+func NewOTLPSink(url string, headers map[string]string) *OTLPSink {
+	if url == "" {
+		url = os.Getenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT")
+	}
+	if url == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			url = strings.TrimSuffix(base, "/") + "/v1/logs"
+		}
+	}
+	if url == "" {
+		url = DefaultOTLPEndpoint
+	}
+	return &OTLPSink{url: url, headers: headers, client: &http.Client{Timeout: sinkTimeout}}
+}
+
+// Name returns "otlp".
+func (s *OTLPSink) Name() string {
+	return "otlp"
+}
+
+// OTLP/JSON types, limited to the fields envref exports.
+type (
+	otlpLogsData struct {
+		ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+	}
+	otlpResourceLogs struct {
+		Resource  otlpResource    `json:"resource"`
+		ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeLogs struct {
+		Scope      otlpScope       `json:"scope"`
+		LogRecords []otlpLogRecord `json:"logRecords"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpLogRecord struct {
+		TimeUnixNano   string         `json:"timeUnixNano"`
+		SeverityNumber int            `json:"severityNumber"`
+		SeverityText   string         `json:"severityText"`
+		Body           otlpAnyValue   `json:"body"`
+		Attributes     []otlpKeyValue `json:"attributes"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// otlpSeverityInfo is the OpenTelemetry severity number for INFO.
+const otlpSeverityInfo = 9
+
+// Send exports e as one log record.
+func (s *OTLPSink) Send(e Entry) error {
+	ts, err := time.Parse(time.RFC3339, e.Timestamp)
+	if err != nil {
+		ts = time.Now()
+	}
+
+	var attrs []otlpKeyValue
+	for _, kv := range [][2]string{
+		{"envref.operation", string(e.Operation)},
+		{"envref.key", e.Key},
+		{"envref.backend", e.Backend},
+		{"envref.project", e.Project},
+		{"envref.profile", e.Profile},
+		{"envref.detail", e.Detail},
+		{"envref.hash", e.Hash},
+		{"enduser.id", e.User},
+	} {
+		if kv[1] != "" {
+			attrs = append(attrs, otlpKeyValue{Key: kv[0], Value: otlpAnyValue{StringValue: kv[1]}})
+		}
+	}
+
+	data := otlpLogsData{ResourceLogs: []otlpResourceLogs{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			{Key: "service.name", Value: otlpAnyValue{StringValue: "envref"}},
+		}},
+		ScopeLogs: []otlpScopeLogs{{
+			Scope: otlpScope{Name: "envref/audit"},
+			LogRecords: []otlpLogRecord{{
+				TimeUnixNano:   strconv.FormatInt(ts.UnixNano(), 10),
+				SeverityNumber: otlpSeverityInfo,
+				SeverityText:   "INFO",
+				Body:           otlpAnyValue{StringValue: fmt.Sprintf("%s %s %s", e.User, e.Operation, e.Key)},
+				Attributes:     attrs,
+			}},
+		}},
+	}}}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshaling log record: %w", err)
+	}
+	return postJSON(s.client, s.url, s.headers, body)
+}
+
+// postJSON POSTs a JSON body to url and fails on non-2xx responses.
+func postJSON(client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "envref")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("POST %s: %s", url, resp.Status)
+	}
+	return nil
+}
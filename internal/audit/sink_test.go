@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var sinkTestEntry = Entry{
+	Timestamp: "2025-01-15T10:30:00Z",
+	User:      "alice",
+	Operation: OpSet,
+	Key:       "API_KEY",
+	Backend:   "keychain",
+	Project:   "myapp",
+}
+
+// recordingServer returns a test server that records request bodies and
+// headers.
+func recordingServer(t *testing.T, status int) (*httptest.Server, *[]*http.Request, *[][]byte) {
+	t.Helper()
+	var (
+		reqs   []*http.Request
+		bodies [][]byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs = append(reqs, r)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs, &bodies
+}
+
+func TestWebhookSink(t *testing.T) {
+	srv, reqs, bodies := recordingServer(t, http.StatusNoContent)
+
+	sink := NewWebhookSink(srv.URL+"/hook", map[string]string{"Authorization": "Bearer t0ken"})
+	require.NoError(t, sink.Send(sinkTestEntry))
+
+	require.Len(t, *reqs, 1)
+	req := (*reqs)[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/hook", req.URL.Path)
+	assert.Equal(t, "Bearer t0ken", req.Header.Get("Authorization"))
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+
+	var got Entry
+	require.NoError(t, json.Unmarshal((*bodies)[0], &got))
+	assert.Equal(t, sinkTestEntry, got)
+}
+
+func TestWebhookSink_ErrorStatus(t *testing.T) {
+	srv, _, _ := recordingServer(t, http.StatusForbidden)
+
+	err := NewWebhookSink(srv.URL, nil).Send(sinkTestEntry)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
+
+func TestOTLPSink(t *testing.T) {
+	srv, reqs, bodies := recordingServer(t, http.StatusOK)
+
+	require.NoError(t, NewOTLPSink(srv.URL+"/v1/logs", nil).Send(sinkTestEntry))
+	require.Len(t, *reqs, 1)
+
+	var data otlpLogsData
+	require.NoError(t, json.Unmarshal((*bodies)[0], &data))
+	require.Len(t, data.ResourceLogs, 1)
+	rl := data.ResourceLogs[0]
+	assert.Equal(t, "service.name", rl.Resource.Attributes[0].Key)
+	rec := rl.ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, "1736937000000000000", rec.TimeUnixNano)
+	assert.Equal(t, "alice set API_KEY", rec.Body.StringValue)
+
+	attrs := make(map[string]string)
+	for _, kv := range rec.Attributes {
+		attrs[kv.Key] = kv.Value.StringValue
+	}
+	assert.Equal(t, "set", attrs["envref.operation"])
+	assert.Equal(t, "myapp", attrs["envref.project"])
+	assert.NotContains(t, attrs, "envref.profile")
+}
+
+func TestNewOTLPSink_Endpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	assert.Equal(t, DefaultOTLPEndpoint, NewOTLPSink("", nil).url)
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "https://collector:4318/")
+	assert.Equal(t, "https://collector:4318/v1/logs", NewOTLPSink("", nil).url)
+
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "https://logs.example.com/ingest")
+	assert.Equal(t, "https://logs.example.com/ingest", NewOTLPSink("", nil).url)
+
+	assert.Equal(t, "http://explicit/v1/logs", NewOTLPSink("http://explicit/v1/logs", nil).url)
+}
+
+func TestSyslogSink_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	sink, err := NewSyslogSink("udp://" + conn.LocalAddr().String())
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(sinkTestEntry))
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	msg := string(buf[:n])
+	assert.True(t, strings.HasPrefix(msg, "<85>"), "unexpected message: %q", msg)
+	assert.Contains(t, msg, " envref[")
+	assert.Contains(t, msg, `"key":"API_KEY"`)
+}
+
+func TestNewSyslogSink_InvalidAddress(t *testing.T) {
+	for _, addr := range []string{"localhost:514", "http://host", "udp://"} {
+		_, err := NewSyslogSink(addr)
+		assert.Error(t, err, addr)
+	}
+}
+
+// failingSink always fails.
+type failingSink struct{}
+
+func (failingSink) Name() string       { return "failing" }
+func (failingSink) Send(e Entry) error { return io.ErrClosedPipe }
+
+func TestLogger_Log_Sinks(t *testing.T) {
+	srv, _, bodies := recordingServer(t, http.StatusOK)
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	logger := NewLogger(path, WithSinks(failingSink{}, NewWebhookSink(srv.URL, nil)))
+	err := logger.Log(sinkTestEntry)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "audit sink failing")
+
+	// The entry is written locally and delivered to the working sink.
+	entries, err := logger.Read()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Len(t, *bodies, 1)
+
+	var sent Entry
+	require.NoError(t, json.Unmarshal((*bodies)[0], &sent))
+	assert.Equal(t, entries[0].Hash, sent.Hash)
+}
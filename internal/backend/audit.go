@@ -57,6 +57,21 @@ func (a *AuditBackend) Get(key string) (string, error) {
 	return a.inner.Get(key)
 }
 
+// Metadata returns the metadata for key from the underlying backend.
+func (a *AuditBackend) Metadata(key string) (Metadata, error) {
+	return GetMetadata(a.inner, key)
+}
+
+// Identity returns the identity the underlying backend acts as.
+func (a *AuditBackend) Identity() (Identity, error) {
+	return GetIdentity(a.inner)
+}
+
+// Ping checks that the underlying backend is reachable.
+func (a *AuditBackend) Ping() error {
+	return Ping(a.inner)
+}
+
 // Set stores a secret and logs the operation to the audit log.
 // The audit entry is written only if the underlying Set succeeds.
 func (a *AuditBackend) Set(key, value string) error {
@@ -32,8 +32,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/xcke/envref/internal/secret"
 )
 
 // Default timeout for AWS CLI operations.
@@ -99,9 +103,22 @@ type ssmParameter struct {
 	Parameter struct {
 		Name  string `json:"Name"`
 		Value string `json:"Value"`
+		ARN   string `json:"ARN"`
 	} `json:"Parameter"`
 }
 
+// ssmParameters represents the relevant fields from `aws ssm get-parameters`.
+type ssmParameters struct {
+	Parameters []struct {
+		Name  string `json:"Name"`
+		Value string `json:"Value"`
+	} `json:"Parameters"`
+}
+
+// ssmGetParametersMax is the most names `aws ssm get-parameters` accepts
+// per call.
+const ssmGetParametersMax = 10
+
 // ssmParameterList represents the response from `aws ssm describe-parameters`.
 type ssmParameterList struct {
 	Parameters []struct {
@@ -110,6 +127,42 @@ type ssmParameterList struct {
 	NextToken *string `json:"NextToken,omitempty"`
 }
 
+// ssmParameterMetadata represents the metadata fields of a parameter from
+// `aws ssm describe-parameters`.
+type ssmParameterMetadata struct {
+	Parameters []struct {
+		Name             string          `json:"Name"`
+		LastModifiedDate json.RawMessage `json:"LastModifiedDate"`
+		LastModifiedUser string          `json:"LastModifiedUser"`
+	} `json:"Parameters"`
+}
+
+// parseSSMTime parses a timestamp from the AWS CLI, which is an ISO 8601
+// string in CLI v2 and epoch seconds in CLI v1.
+func parseSSMTime(raw json.RawMessage) time.Time {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		t, _ := time.Parse(time.RFC3339Nano, s)
+		return t
+	}
+	var secs float64
+	if err := json.Unmarshal(raw, &secs); err == nil && secs > 0 {
+		return time.Unix(0, int64(secs*float64(time.Second))).UTC()
+	}
+	return time.Time{}
+}
+
+// ssmParameterHistory represents the response from
+// `aws ssm get-parameter-history`.
+type ssmParameterHistory struct {
+	Parameters []struct {
+		Version          int             `json:"Version"`
+		LastModifiedDate json.RawMessage `json:"LastModifiedDate"`
+		LastModifiedUser string          `json:"LastModifiedUser"`
+	} `json:"Parameters"`
+	NextToken *string `json:"NextToken,omitempty"`
+}
+
 // paramName returns the full SSM parameter name for a given key.
 func (b *AWSSSMBackend) paramName(key string) string {
 	return b.prefix + "/" + key
@@ -126,6 +179,34 @@ func (b *AWSSSMBackend) Get(key string) (string, error) {
 	}
 	args = b.appendGlobalFlags(args)
 
+	stdout, err := b.run(args)
+	if err != nil {
+		if isAWSNotFoundErr(err) {
+			return "", ErrNotFound
+		}
+		return "", NewKeyError(b.Name(), key, fmt.Errorf("aws ssm get-parameter: %w", err))
+	}
+	defer secret.ClearBytes(stdout)
+
+	var result ssmParameter
+	if err := json.Unmarshal(stdout, &result); err != nil {
+		return "", NewKeyError(b.Name(), key, fmt.Errorf("parse response: %w", err))
+	}
+
+	return result.Parameter.Value, nil
+}
+
+// ARN returns the Amazon Resource Name of the parameter for key, without
+// decrypting its value. Returns ErrNotFound if no such parameter exists.
+func (b *AWSSSMBackend) ARN(key string) (string, error) {
+	args := []string{
+		"ssm", "get-parameter",
+		"--name", b.paramName(key),
+		"--query", "{Parameter:{Name:Parameter.Name,ARN:Parameter.ARN}}",
+		"--output", "json",
+	}
+	args = b.appendGlobalFlags(args)
+
 	stdout, err := b.run(args)
 	if err != nil {
 		if isAWSNotFoundErr(err) {
@@ -138,10 +219,166 @@ func (b *AWSSSMBackend) Get(key string) (string, error) {
 	if err := json.Unmarshal(stdout, &result); err != nil {
 		return "", NewKeyError(b.Name(), key, fmt.Errorf("parse response: %w", err))
 	}
+	if result.Parameter.ARN == "" {
+		return "", NewKeyError(b.Name(), key, fmt.Errorf("aws ssm get-parameter: no ARN in response"))
+	}
+	return result.Parameter.ARN, nil
+}
+
+// GetMany retrieves the values for keys with `aws ssm get-parameters`, ten
+// parameters per call. Keys that do not exist are omitted from the result.
+func (b *AWSSSMBackend) GetMany(keys []string) (map[string]string, error) {
+	prefixWithSlash := b.prefix + "/"
+	values := make(map[string]string, len(keys))
+	for start := 0; start < len(keys); start += ssmGetParametersMax {
+		end := min(start+ssmGetParametersMax, len(keys))
+
+		args := []string{"ssm", "get-parameters", "--names"}
+		for _, key := range keys[start:end] {
+			args = append(args, b.paramName(key))
+		}
+		args = append(args, "--with-decryption", "--output", "json")
+		args = b.appendGlobalFlags(args)
+
+		stdout, err := b.run(args)
+		if err != nil {
+			return nil, fmt.Errorf("aws ssm get-parameters: %w", err)
+		}
+
+		var result ssmParameters
+		err = json.Unmarshal(stdout, &result)
+		secret.ClearBytes(stdout)
+		if err != nil {
+			return nil, fmt.Errorf("aws ssm get-parameters: parse response: %w", err)
+		}
+		for _, p := range result.Parameters {
+			values[strings.TrimPrefix(p.Name, prefixWithSlash)] = p.Value
+		}
+	}
+	return values, nil
+}
+
+// Metadata returns when the parameter for key was last modified and by
+// which IAM identity. Parameter Store does not record a creation time.
+// Returns ErrNotFound if no such parameter exists.
+func (b *AWSSSMBackend) Metadata(key string) (Metadata, error) {
+	args := []string{
+		"ssm", "describe-parameters",
+		"--parameter-filters",
+		"Key=Name,Option=Equals,Values=" + b.paramName(key),
+		"--output", "json",
+	}
+	args = b.appendGlobalFlags(args)
+
+	stdout, err := b.run(args)
+	if err != nil {
+		return Metadata{}, NewKeyError(b.Name(), key, fmt.Errorf("aws ssm describe-parameters: %w", err))
+	}
+
+	var result ssmParameterMetadata
+	if err := json.Unmarshal(stdout, &result); err != nil {
+		return Metadata{}, NewKeyError(b.Name(), key, fmt.Errorf("parse response: %w", err))
+	}
+	if len(result.Parameters) == 0 {
+		return Metadata{}, ErrNotFound
+	}
+
+	p := result.Parameters[0]
+	return Metadata{
+		Updated:   parseSSMTime(p.LastModifiedDate),
+		UpdatedBy: p.LastModifiedUser,
+	}, nil
+}
+
+// ListVersions returns the versions of the parameter for key, oldest first.
+// Parameter Store keeps the last 100 versions.
+// Returns ErrNotFound if no parameter with that name exists.
+func (b *AWSSSMBackend) ListVersions(key string) ([]Version, error) {
+	var versions []Version
+	var nextToken *string
+
+	for {
+		args := []string{
+			"ssm", "get-parameter-history",
+			"--name", b.paramName(key),
+			"--output", "json",
+		}
+		if nextToken != nil {
+			args = append(args, "--next-token", *nextToken)
+		}
+		args = b.appendGlobalFlags(args)
+
+		stdout, err := b.run(args)
+		if err != nil {
+			if isAWSNotFoundErr(err) {
+				return nil, ErrNotFound
+			}
+			return nil, NewKeyError(b.Name(), key, fmt.Errorf("aws ssm get-parameter-history: %w", err))
+		}
+
+		var result ssmParameterHistory
+		if err := json.Unmarshal(stdout, &result); err != nil {
+			return nil, NewKeyError(b.Name(), key, fmt.Errorf("parse response: %w", err))
+		}
+		for _, p := range result.Parameters {
+			versions = append(versions, Version{
+				Number:    p.Version,
+				Created:   parseSSMTime(p.LastModifiedDate),
+				CreatedBy: p.LastModifiedUser,
+			})
+		}
+
+		if result.NextToken == nil || *result.NextToken == "" {
+			break
+		}
+		nextToken = result.NextToken
+	}
+
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Number < versions[j].Number })
+	versions[len(versions)-1].Current = true
+	return versions, nil
+}
+
+// GetVersion retrieves the value of key at the given parameter version.
+// Returns ErrNotFound if the parameter or version does not exist.
+func (b *AWSSSMBackend) GetVersion(key string, version int) (string, error) {
+	args := []string{
+		"ssm", "get-parameter",
+		"--name", b.paramName(key) + ":" + strconv.Itoa(version),
+		"--with-decryption",
+		"--output", "json",
+	}
+	args = b.appendGlobalFlags(args)
+
+	stdout, err := b.run(args)
+	if err != nil {
+		if isAWSNotFoundErr(err) {
+			return "", ErrNotFound
+		}
+		return "", NewKeyError(b.Name(), key, fmt.Errorf("aws ssm get-parameter: %w", err))
+	}
+	defer secret.ClearBytes(stdout)
 
+	var result ssmParameter
+	if err := json.Unmarshal(stdout, &result); err != nil {
+		return "", NewKeyError(b.Name(), key, fmt.Errorf("parse response: %w", err))
+	}
 	return result.Parameter.Value, nil
 }
 
+// Rollback writes the value of the given version as a new version, since
+// Parameter Store has no rollback operation of its own.
+func (b *AWSSSMBackend) Rollback(key string, version int) error {
+	value, err := b.GetVersion(key, version)
+	if err != nil {
+		return err
+	}
+	return b.Set(key, value)
+}
+
 // Set stores a secret value under the given key in AWS SSM Parameter Store.
 // If a parameter with that name already exists, it is overwritten.
 func (b *AWSSSMBackend) Set(key, value string) error {
@@ -180,6 +417,48 @@ func (b *AWSSSMBackend) Delete(key string) error {
 	return nil
 }
 
+// Ping checks that the AWS CLI has credentials with access to Parameter
+// Store by describing at most one parameter.
+func (b *AWSSSMBackend) Ping() error {
+	args := []string{
+		"ssm", "describe-parameters",
+		"--max-results", "1",
+		"--output", "json",
+	}
+	args = b.appendGlobalFlags(args)
+
+	if _, err := b.run(args); err != nil {
+		return fmt.Errorf("aws-ssm ping: %w", err)
+	}
+	return nil
+}
+
+// stsCallerIdentity represents the relevant fields from
+// `aws sts get-caller-identity --output json`.
+type stsCallerIdentity struct {
+	UserID  string `json:"UserId"`
+	Account string `json:"Account"`
+	Arn     string `json:"Arn"`
+}
+
+// Identity returns the IAM identity the AWS CLI acts as, named by its ARN,
+// with its account and user ID.
+func (b *AWSSSMBackend) Identity() (Identity, error) {
+	args := b.appendGlobalFlags([]string{"sts", "get-caller-identity", "--output", "json"})
+	stdout, err := b.run(args)
+	if err != nil {
+		return Identity{}, fmt.Errorf("aws sts get-caller-identity: %w", err)
+	}
+	var result stsCallerIdentity
+	if err := json.Unmarshal(stdout, &result); err != nil {
+		return Identity{}, fmt.Errorf("aws sts get-caller-identity: parse response: %w", err)
+	}
+	return Identity{Name: result.Arn, Details: map[string]string{
+		"account": result.Account,
+		"user_id": result.UserID,
+	}}, nil
+}
+
 // List returns all secret keys (parameter names) under the configured prefix.
 // The prefix is stripped from the returned keys.
 func (b *AWSSSMBackend) List() ([]string, error) {
@@ -277,6 +556,7 @@ func (b *AWSSSMBackend) run(args []string) ([]byte, error) {
 func isAWSNotFoundErr(err error) bool {
 	msg := strings.ToLower(err.Error())
 	return strings.Contains(msg, "parameternotfound") ||
+		strings.Contains(msg, "parameterversionnotfound") ||
 		strings.Contains(msg, "parameter not found") ||
 		strings.Contains(msg, "does not exist")
 }
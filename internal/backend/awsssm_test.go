@@ -1,12 +1,15 @@
 package backend
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 )
 
 // buildAWSMock compiles the mock aws CLI helper into a temporary directory
@@ -204,3 +207,159 @@ func TestIsAWSNotFoundErr(t *testing.T) {
 		}
 	}
 }
+
+func TestAWSSSMBackend_GetMany(t *testing.T) {
+	awsPath := buildAWSMock(t)
+	b := NewAWSSSMBackend("/test", WithAWSSSMCommand(awsPath))
+
+	// More than one get-parameters call's worth of keys.
+	var keys []string
+	for i := 0; i < 12; i++ {
+		key := fmt.Sprintf("key%02d", i)
+		keys = append(keys, key)
+		if i%3 == 0 {
+			continue // leave some keys missing
+		}
+		if err := b.Set(key, "v"+key); err != nil {
+			t.Fatalf("Set(%q): %v", key, err)
+		}
+	}
+
+	got, err := b.GetMany(keys)
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if len(got) != 8 {
+		t.Errorf("GetMany returned %d values, want 8: %v", len(got), got)
+	}
+	if got["key01"] != "vkey01" || got["key11"] != "vkey11" {
+		t.Errorf("GetMany = %v", got)
+	}
+	if _, ok := got["key00"]; ok {
+		t.Error("missing key should be omitted")
+	}
+}
+
+func TestAWSSSMBackend_Ping(t *testing.T) {
+	awsPath := buildAWSMock(t)
+	if err := NewAWSSSMBackend("/test", WithAWSSSMCommand(awsPath)).Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	err := NewAWSSSMBackend("/test", WithAWSSSMCommand("/nonexistent/aws")).Ping()
+	if err == nil {
+		t.Fatal("Ping with invalid command: expected error, got nil")
+	}
+}
+
+func TestAWSSSMBackend_Identity(t *testing.T) {
+	awsPath := buildAWSMock(t)
+	id, err := GetIdentity(Chain(NewAWSSSMBackend("/test", WithAWSSSMCommand(awsPath)), Redacting()))
+	if err != nil {
+		t.Fatalf("Identity: %v", err)
+	}
+	if id.Name != "arn:aws:iam::123456789012:user/alice" {
+		t.Errorf("Identity name: got %q", id.Name)
+	}
+	if id.Details["account"] != "123456789012" {
+		t.Errorf("Identity account: got %q", id.Details["account"])
+	}
+}
+
+func TestAWSSSMBackend_Metadata(t *testing.T) {
+	awsPath := buildAWSMock(t)
+	b := NewAWSSSMBackend("/test", WithAWSSSMCommand(awsPath))
+	for _, key := range []string{"key", "key2"} {
+		if err := b.Set(key, "v"); err != nil {
+			t.Fatalf("Set(%q): %v", key, err)
+		}
+	}
+
+	md, err := b.Metadata("key")
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	want := time.Date(2024, 1, 15, 10, 30, 0, 123000000, time.UTC)
+	if !md.Updated.Equal(want) {
+		t.Errorf("Updated = %v, want %v", md.Updated, want)
+	}
+	if md.UpdatedBy != "arn:aws:iam::123456789012:user/mock" {
+		t.Errorf("UpdatedBy = %q", md.UpdatedBy)
+	}
+	if !md.Created.IsZero() {
+		t.Errorf("Created should be unknown, got %v", md.Created)
+	}
+
+	if _, err := b.Metadata("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Metadata(missing): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestAWSSSMBackend_ARN(t *testing.T) {
+	awsPath := buildAWSMock(t)
+	b := NewAWSSSMBackend("/test", WithAWSSSMCommand(awsPath))
+	if err := b.Set("app/key", "v"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	arn, err := b.ARN("app/key")
+	if err != nil {
+		t.Fatalf("ARN: %v", err)
+	}
+	if arn != "arn:aws:ssm:us-east-1:123456789012:parameter/test/app/key" {
+		t.Errorf("ARN = %q", arn)
+	}
+
+	if _, err := b.ARN("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ARN(missing): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestParseSSMTime(t *testing.T) {
+	want := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	for _, raw := range []string{`"2024-01-15T10:30:00+00:00"`, `1705314600`} {
+		if got := parseSSMTime(json.RawMessage(raw)); !got.Equal(want) {
+			t.Errorf("parseSSMTime(%s) = %v, want %v", raw, got, want)
+		}
+	}
+	if got := parseSSMTime(nil); !got.IsZero() {
+		t.Errorf("parseSSMTime(nil) = %v, want zero", got)
+	}
+}
+
+func TestAWSSSMBackend_Versions(t *testing.T) {
+	awsPath := buildAWSMock(t)
+	b := NewAWSSSMBackend("/test", WithAWSSSMCommand(awsPath))
+	for _, v := range []string{"one", "two"} {
+		if err := b.Set("key", v); err != nil {
+			t.Fatalf("Set(%q): %v", v, err)
+		}
+	}
+
+	versions, err := b.ListVersions("key")
+	if err != nil {
+		t.Fatalf("ListVersions: %v", err)
+	}
+	if len(versions) != 2 || !versions[1].Current || versions[1].CreatedBy == "" || versions[0].Created.IsZero() {
+		t.Fatalf("ListVersions = %+v", versions)
+	}
+
+	got, err := b.GetVersion("key", 1)
+	if err != nil || got != "one" {
+		t.Fatalf("GetVersion(1) = %q, %v; want one", got, err)
+	}
+	if _, err := b.GetVersion("key", 5); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetVersion(5): got %v, want ErrNotFound", err)
+	}
+
+	if err := b.Rollback("key", 1); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if got, _ := b.Get("key"); got != "one" {
+		t.Errorf("Get after rollback = %q, want one", got)
+	}
+
+	if _, err := b.ListVersions("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ListVersions(missing): got %v, want ErrNotFound", err)
+	}
+}
@@ -40,6 +40,61 @@ type Backend interface {
 	List() ([]string, error)
 }
 
+// BatchGetter is an optional interface for backends that can retrieve many
+// secrets in one round trip (e.g., AWS SSM GetParameters). The resolver
+// uses it, when available, to prefetch all referenced keys up front.
+type BatchGetter interface {
+	// GetMany retrieves the values for keys. Keys that do not exist are
+	// omitted from the result; that is not an error.
+	GetMany(keys []string) (map[string]string, error)
+}
+
+// GetMany retrieves the values for keys from b: in one call if b implements
+// BatchGetter, otherwise with one Get per key. Keys that do not exist are
+// omitted from the result.
+func GetMany(b Backend, keys []string) (map[string]string, error) {
+	if bg, ok := b.(BatchGetter); ok {
+		return bg.GetMany(keys)
+	}
+	return getEach(b, keys)
+}
+
+// getEach retrieves the values for keys from b with one Get per key,
+// omitting keys that do not exist.
+func getEach(b Backend, keys []string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, err := b.Get(key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// HealthChecker is an optional interface for backends that can check they
+// are reachable and usable (e.g., the CLI is installed and signed in, the
+// vault is unlocked) without reading a particular secret.
+type HealthChecker interface {
+	// Ping returns nil if the backend is ready to serve requests, or an
+	// error describing why it is not.
+	Ping() error
+}
+
+// Ping checks that b is reachable: with b.Ping if b implements
+// HealthChecker, otherwise by listing its keys.
+func Ping(b Backend) error {
+	if hc, ok := b.(HealthChecker); ok {
+		return hc.Ping()
+	}
+	_, err := b.List()
+	return err
+}
+
 // ErrNotFound is returned when a requested secret key does not exist
 // in a backend.
 var ErrNotFound = errors.New("secret not found")
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"sort"
@@ -198,3 +199,117 @@ func TestKeyError_Unwrap(t *testing.T) {
 		t.Fatalf("Key: got %q, want %q", target.Key, "api_key")
 	}
 }
+
+// batchMemoryBackend is a memoryBackend that implements BatchGetter and
+// counts GetMany calls.
+type batchMemoryBackend struct {
+	*memoryBackend
+	batchCalls int
+}
+
+func (b *batchMemoryBackend) GetMany(keys []string) (map[string]string, error) {
+	b.batchCalls++
+	values := make(map[string]string)
+	for _, k := range keys {
+		if v, ok := b.secrets[k]; ok {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+func TestGetMany_FallsBackToGet(t *testing.T) {
+	b := newMemoryBackend("mem")
+	b.secrets["a"] = "1"
+	b.secrets["b"] = "2"
+
+	got, err := GetMany(b, []string{"a", "b", "missing"})
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if len(got) != 2 || got["a"] != "1" || got["b"] != "2" {
+		t.Errorf("GetMany = %v", got)
+	}
+}
+
+func TestNamespacedBackend_GetMany(t *testing.T) {
+	inner := &batchMemoryBackend{memoryBackend: newMemoryBackend("mem")}
+	inner.secrets["app/a"] = "1"
+	inner.secrets["other/b"] = "2"
+
+	ns, err := NewNamespacedBackend(inner, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ns.Batched() {
+		t.Error("expected Batched() for a BatchGetter backend")
+	}
+
+	got, err := ns.GetMany([]string{"a", "b"})
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if len(got) != 1 || got["a"] != "1" {
+		t.Errorf("GetMany = %v, want map[a:1]", got)
+	}
+	if inner.batchCalls != 1 {
+		t.Errorf("batchCalls = %d, want 1", inner.batchCalls)
+	}
+
+	plain, _ := NewNamespacedBackend(newMemoryBackend("mem"), "app")
+	if plain.Batched() {
+		t.Error("expected !Batched() for a backend without GetMany")
+	}
+}
+
+func TestPing_FallsBackToList(t *testing.T) {
+	if err := Ping(newMemoryBackend("mem")); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
+func TestNamespacedBackend_Ping(t *testing.T) {
+	ns, err := NewNamespacedBackend(newMemoryBackend("mem"), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Ping(ns); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
+func TestGetMetadata_Unsupported(t *testing.T) {
+	_, err := GetMetadata(newMemoryBackend("mem"), "key")
+	if !errors.Is(err, ErrMetadataUnsupported) {
+		t.Errorf("got %v, want ErrMetadataUnsupported", err)
+	}
+
+	ns, _ := NewNamespacedBackend(newMemoryBackend("mem"), "app")
+	if _, err := ns.Metadata("key"); !errors.Is(err, ErrMetadataUnsupported) {
+		t.Errorf("namespaced: got %v, want ErrMetadataUnsupported", err)
+	}
+}
+
+func TestEncodeBinary_RoundTrip(t *testing.T) {
+	data := []byte{0x00, 0x01, 0xfe, 0xff, '\n'}
+	stored := EncodeBinary(data)
+	if !IsBinary(stored) {
+		t.Fatalf("IsBinary(%q) = false", stored)
+	}
+
+	got, err := DecodeBinary(stored)
+	if err != nil {
+		t.Fatalf("DecodeBinary: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("got %v, want %v", got, data)
+	}
+
+	// Plain values pass through unchanged.
+	if got, _ := DecodeBinary("plain"); string(got) != "plain" {
+		t.Errorf("plain value: got %q", got)
+	}
+	if _, err := DecodeBinary(BinaryPrefix + "!!"); err == nil {
+		t.Error("expected error for invalid base64 data")
+	}
+}
@@ -0,0 +1,36 @@
+package backend
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// BinaryPrefix marks a stored secret value as base64-encoded binary data
+// (e.g., a TLS key or a PKCS#12 keystore). Backends only store strings, and
+// several cannot hold NUL bytes or invalid UTF-8, so binary secrets are
+// stored as BinaryPrefix followed by their standard base64 encoding.
+const BinaryPrefix = "envref:base64:"
+
+// EncodeBinary returns the stored form of the binary value data.
+func EncodeBinary(data []byte) string {
+	return BinaryPrefix + base64.StdEncoding.EncodeToString(data)
+}
+
+// IsBinary reports whether value is the stored form of a binary value.
+func IsBinary(value string) bool {
+	return strings.HasPrefix(value, BinaryPrefix)
+}
+
+// DecodeBinary returns the bytes of a stored value: the decoded data for a
+// binary value, or the value itself otherwise.
+func DecodeBinary(value string) ([]byte, error) {
+	if !IsBinary(value) {
+		return []byte(value), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value[len(BinaryPrefix):])
+	if err != nil {
+		return nil, fmt.Errorf("decoding binary secret: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,95 @@
+package factory
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+)
+
+// ApplyACL makes registry enforce the acl block of cfg, if it has one.
+func ApplyACL(registry *backend.Registry, cfg *config.Config) {
+	if len(cfg.ACL) == 0 {
+		return
+	}
+	checker := &aclChecker{cfg: cfg, callers: make(map[string]aclCaller)}
+	registry.SetAccess(checker.check)
+}
+
+// aclCaller is who a backend acts as, looked up once per backend.
+type aclCaller struct {
+	identities []string // most specific first
+	err        error
+}
+
+// aclChecker is the backend.AccessFunc of a registry with an acl block.
+// The caller is the identity a backend reports (see backend.GetIdentity),
+// or, for backends that report none, the OS user as "user" and
+// "user@host". The caller must be a team member known by that identity and
+// allowed by name or role.
+type aclChecker struct {
+	cfg *config.Config
+
+	mu      sync.Mutex
+	callers map[string]aclCaller
+}
+
+// check implements backend.AccessFunc.
+func (c *aclChecker) check(b backend.Backend, profile, key string) error {
+	allowed, pattern, ok := c.cfg.ACLRule(profile, key)
+	if !ok {
+		return nil
+	}
+	caller := c.caller(b)
+	if caller.err != nil {
+		return fmt.Errorf("%w: %s is restricted by acl %q and the caller cannot be identified: %v", backend.ErrAccessDenied, key, pattern, caller.err)
+	}
+	member := c.cfg.TeamMemberByIdentity(caller.identities...)
+	if member != nil && member.AllowedBy(allowed) {
+		return nil
+	}
+	who := caller.identities[0] + " (not a team member)"
+	if member != nil {
+		who = member.Name
+	}
+	return fmt.Errorf("%w: %s is restricted to %s by acl %q; you are %s", backend.ErrAccessDenied, key, strings.Join(allowed, ", "), pattern, who)
+}
+
+// caller returns who b acts as.
+func (c *aclChecker) caller(b backend.Backend) aclCaller {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if caller, ok := c.callers[b.Name()]; ok {
+		return caller
+	}
+	var caller aclCaller
+	id, err := backend.GetIdentity(b)
+	switch {
+	case err == nil && id.Name != "":
+		caller.identities = []string{id.Name}
+	case err == nil || errors.Is(err, backend.ErrIdentityUnsupported):
+		caller.identities, caller.err = OSIdentities()
+	default:
+		caller.err = err
+	}
+	c.callers[b.Name()] = caller
+	return caller
+}
+
+// OSIdentities returns the OS user as "user@host" and "user".
+func OSIdentities() ([]string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("looking up the current user: %w", err)
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return []string{u.Username}, nil
+	}
+	return []string{u.Username + "@" + host, u.Username}, nil
+}
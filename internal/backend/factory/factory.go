@@ -0,0 +1,497 @@
+// Package factory instantiates the secret backends configured in
+// .envref.yaml. It is shared by the envref commands, which open the vault
+// backend with an interactive passphrase prompt, and by the public Go API,
+// which never prompts.
+package factory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/secret"
+)
+
+// VaultFunc opens the vault backend of bc. Its config values are already
+// decrypted.
+type VaultFunc func(bc config.BackendConfig) (backend.Backend, error)
+
+// Registry builds a registry holding the backends of cfg, each opened by
+// open, with their namespaces and the aliases of cfg, enforcing its acl
+// block (see ApplyACL). It enables memory locking first if cfg asks for it.
+func Registry(cfg *config.Config, open func(bc config.BackendConfig) (backend.Backend, error)) (*backend.Registry, error) {
+	ApplyMemoryConfig(cfg)
+	registry := backend.NewRegistry()
+
+	for _, bc := range cfg.Backends {
+		b, err := open(bc)
+		if err != nil {
+			return nil, fmt.Errorf("backend %q: %w", bc.Name, err)
+		}
+		if err := registry.Register(b); err != nil {
+			return nil, err
+		}
+		if err := registry.SetNamespace(bc.Name, bc.Namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, targets := range cfg.Aliases {
+		if err := registry.SetAlias(name, targets); err != nil {
+			return nil, err
+		}
+	}
+
+	ApplyACL(registry, cfg)
+	return registry, nil
+}
+
+// Chained instantiates the backend for bc wrapped in backend.Redacting and
+// its configured middleware. vault opens the backend if it is a vault.
+func Chained(bc config.BackendConfig, vault VaultFunc) (backend.Backend, error) {
+	b, err := New(bc, vault)
+	if err != nil {
+		return nil, err
+	}
+	middleware, err := Middleware(bc.Middleware)
+	if err != nil {
+		return nil, err
+	}
+	// Redacting is outermost so that values are tracked before any other
+	// middleware can log an error that contains them.
+	return backend.Chain(b, append([]backend.Middleware{backend.Redacting()}, middleware...)...), nil
+}
+
+// ApplyMemoryConfig enables locking of sensitive buffers in memory if the
+// config asks for it. It must run before any backend is created.
+func ApplyMemoryConfig(cfg *config.Config) {
+	if cfg.Memory.Lock {
+		secret.EnableLocking()
+	}
+}
+
+// Middleware instantiates the middleware configured for a backend, in
+// order. Logging and metrics report to stderr.
+func Middleware(configs []config.MiddlewareConfig) ([]backend.Middleware, error) {
+	middleware := make([]backend.Middleware, 0, len(configs))
+	for _, mc := range configs {
+		switch mc.Type {
+		case "logging":
+			middleware = append(middleware, backend.Logging(os.Stderr))
+		case "metrics":
+			middleware = append(middleware, backend.Metrics(os.Stderr))
+		case "cache":
+			ttl, err := mc.CacheTTL()
+			if err != nil {
+				return nil, fmt.Errorf("cache middleware: %w", err)
+			}
+			middleware = append(middleware, backend.Cache(ttl))
+		case "rate-limit":
+			if mc.Rate <= 0 {
+				return nil, fmt.Errorf("rate-limit middleware: rate must be positive")
+			}
+			middleware = append(middleware, backend.RateLimit(mc.Rate))
+		default:
+			return nil, fmt.Errorf("unknown middleware type %q", mc.Type)
+		}
+	}
+	return middleware, nil
+}
+
+// New instantiates a backend based on its config type, decrypting any
+// !encrypted config values first. vault opens the backend if it is a vault.
+func New(bc config.BackendConfig, vault VaultFunc) (backend.Backend, error) {
+	bc, err := DecryptConfig(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch bc.EffectiveType() {
+	case "keychain":
+		return newKeychain(bc)
+	case "vault":
+		return vault(bc)
+	case "1password":
+		return newOnePassword(bc)
+	case "aws-ssm":
+		return newAWSSSM(bc), nil
+	case "oci-vault":
+		return newOCIVault(bc), nil
+	case "hashicorp-vault":
+		return newHashiVault(bc)
+	case "plugin":
+		return newPlugin(bc)
+	case "memory":
+		return newMemory(bc)
+	default:
+		return nil, fmt.Errorf("unknown backend type %q", bc.EffectiveType())
+	}
+}
+
+// DecryptConfig returns bc with its !encrypted config values
+// decrypted using the config passphrase. Decrypted values are tracked so
+// that they are masked in errors and logs.
+func DecryptConfig(bc config.BackendConfig) (config.BackendConfig, error) {
+	if len(bc.Encrypted) == 0 {
+		return bc, nil
+	}
+	passphrase, err := config.Passphrase()
+	if err != nil {
+		return bc, err
+	}
+	decrypted, err := bc.Decrypt(passphrase)
+	if err != nil {
+		return bc, err
+	}
+	for _, key := range bc.Encrypted {
+		secret.Track(decrypted.Config[key])
+	}
+	return decrypted, nil
+}
+
+// newKeychain creates a KeychainBackend from the backend config.
+// Optional config key: "require_presence" (comma-separated key globs whose
+// reads need Touch ID or the account password; "*" for every key).
+func newKeychain(bc config.BackendConfig) (*backend.KeychainBackend, error) {
+	var policy backend.PresencePolicy
+	for _, pattern := range strings.Split(bc.Config["require_presence"], ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			policy.Patterns = append(policy.Patterns, pattern)
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("keychain require_presence: %w", err)
+	}
+	return backend.NewKeychainBackend(backend.WithPresencePolicy(policy)), nil
+}
+
+// newOnePassword creates a OnePasswordBackend from the backend config.
+// Optional config keys: "vault" (default "Personal"), "account" (optional),
+// "session" and "session_ttl" (see SessionConfig).
+func newOnePassword(bc config.BackendConfig) (*backend.OnePasswordBackend, error) {
+	vault := bc.Config["vault"]
+	if vault == "" {
+		vault = "Personal"
+	}
+
+	var opts []backend.OnePasswordOption
+	if account := bc.Config["account"]; account != "" {
+		opts = append(opts, backend.WithOnePasswordAccount(account))
+	}
+	if command := bc.Config["command"]; command != "" {
+		opts = append(opts, backend.WithOnePasswordCommand(command))
+	}
+	mode, ttl, err := SessionConfig(bc)
+	if err != nil {
+		return nil, err
+	}
+	if mode != "" {
+		opts = append(opts, backend.WithOnePasswordSession(mode, ttl))
+	}
+	return backend.NewOnePasswordBackend(vault, opts...), nil
+}
+
+// SessionConfig parses the "session" (a backend.KnownSessionCaches mode) and
+// "session_ttl" (a duration such as "30m") config keys of backends that sign
+// in separately from each operation.
+func SessionConfig(bc config.BackendConfig) (string, time.Duration, error) {
+	mode := bc.Config["session"]
+	if mode != "" {
+		if err := backend.ValidateSessionCache(mode); err != nil {
+			return "", 0, fmt.Errorf("%s: %w", bc.Name, err)
+		}
+	}
+	var ttl time.Duration
+	if s := bc.Config["session_ttl"]; s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return "", 0, fmt.Errorf("%s: invalid session_ttl %q (use a duration such as 30m)", bc.Name, s)
+		}
+		ttl = d
+	}
+	return mode, ttl, nil
+}
+
+// newPlugin creates a PluginBackend from the backend config.
+// If config.command is set, it is used as the plugin executable path.
+// Otherwise, the plugin is discovered by searching $PATH for
+// "envref-backend-<name>". config.protocol selects the plugin protocol
+// version ("1", the default, "2", or "3" for gRPC).
+func newPlugin(bc config.BackendConfig) (*backend.PluginBackend, error) {
+	var opts []backend.PluginOption
+	if v := bc.Config["protocol"]; v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil || !slices.Contains(backend.PluginProtocolVersions, version) {
+			return nil, fmt.Errorf("plugin %q: unsupported protocol %q (supported: 1, 2, 3)", bc.Name, v)
+		}
+		opts = append(opts, backend.WithPluginProtocol(version))
+	}
+
+	command := bc.Config["command"]
+	if command == "" {
+		var err error
+		command, err = backend.DiscoverPlugin(bc.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return backend.NewPluginBackend(bc.Name, command, opts...), nil
+}
+
+// newMemory creates a MemoryBackend from the backend config.
+// Optional config key: "path" (plaintext JSON file that persists the store;
+// without it, secrets only live for the current command).
+func newMemory(bc config.BackendConfig) (*backend.MemoryBackend, error) {
+	if path := bc.Config["path"]; path != "" {
+		return backend.NewFileMemoryBackend(bc.Name, path)
+	}
+	return backend.NewMemoryBackend(bc.Name), nil
+}
+
+// newAWSSSM creates an AWSSSMBackend from the backend config.
+// Optional config keys: "prefix" (default "/envref"), "region" (optional),
+// "profile" (optional).
+func newAWSSSM(bc config.BackendConfig) *backend.AWSSSMBackend {
+	prefix := bc.Config["prefix"]
+	if prefix == "" {
+		prefix = "/envref"
+	}
+
+	var opts []backend.AWSSSMOption
+	if region := bc.Config["region"]; region != "" {
+		opts = append(opts, backend.WithAWSSSMRegion(region))
+	}
+	if profile := bc.Config["profile"]; profile != "" {
+		opts = append(opts, backend.WithAWSSSMProfile(profile))
+	}
+	if command := bc.Config["command"]; command != "" {
+		opts = append(opts, backend.WithAWSSSMCommand(command))
+	}
+	return backend.NewAWSSSMBackend(prefix, opts...)
+}
+
+// newOCIVault creates an OCIVaultBackend from the backend config.
+// Required config keys: "vault_id", "compartment_id", "key_id".
+// Optional config keys: "profile" (optional).
+func newOCIVault(bc config.BackendConfig) *backend.OCIVaultBackend {
+	vaultID := bc.Config["vault_id"]
+	compartmentID := bc.Config["compartment_id"]
+	keyID := bc.Config["key_id"]
+
+	var opts []backend.OCIVaultOption
+	if profile := bc.Config["profile"]; profile != "" {
+		opts = append(opts, backend.WithOCIVaultProfile(profile))
+	}
+	if command := bc.Config["command"]; command != "" {
+		opts = append(opts, backend.WithOCIVaultCommand(command))
+	}
+	return backend.NewOCIVaultBackend(vaultID, compartmentID, keyID, opts...)
+}
+
+// newHashiVault creates a HashiVaultBackend from the backend config.
+// Optional config keys: "mount" (default "secret"), "prefix" (default "envref"),
+// "addr" (optional), "namespace" (optional), "token" (optional),
+// "auth_method" with "auth_<param>" login params (optional), and "session"
+// and "session_ttl" (see SessionConfig).
+func newHashiVault(bc config.BackendConfig) (*backend.HashiVaultBackend, error) {
+	mount := bc.Config["mount"]
+	if mount == "" {
+		mount = "secret"
+	}
+	prefix := bc.Config["prefix"]
+	if prefix == "" {
+		prefix = "envref"
+	}
+
+	var opts []backend.HashiVaultOption
+	if addr := bc.Config["addr"]; addr != "" {
+		opts = append(opts, backend.WithHashiVaultAddr(addr))
+	}
+	if namespace := bc.Config["namespace"]; namespace != "" {
+		opts = append(opts, backend.WithHashiVaultNamespace(namespace))
+	}
+	if token := bc.Config["token"]; token != "" {
+		opts = append(opts, backend.WithHashiVaultToken(token))
+	}
+	if command := bc.Config["command"]; command != "" {
+		opts = append(opts, backend.WithHashiVaultCommand(command))
+	}
+	mode, ttl, err := SessionConfig(bc)
+	if err != nil {
+		return nil, err
+	}
+	if method := bc.Config["auth_method"]; method != "" {
+		params := make(map[string]string)
+		for k, v := range bc.Config {
+			if param, ok := strings.CutPrefix(k, "auth_"); ok && param != "method" {
+				params[param] = v
+			}
+		}
+		opts = append(opts, backend.WithHashiVaultLogin(method, params), backend.WithHashiVaultSession(mode, ttl))
+	} else if mode != "" {
+		return nil, fmt.Errorf("%s: session requires auth_method", bc.Name)
+	}
+	return backend.NewHashiVaultBackend(mount, prefix, opts...), nil
+}
+
+// Vault opens the vault backend of bc without prompting: the passphrase
+// comes from ENVREF_VAULT_PASSPHRASE or config.passphrase, and a shared
+// vault is opened with the age identity of VaultIdentities instead. If the
+// vault is initialized, the passphrase or identity is verified against it.
+func Vault(bc config.BackendConfig) (backend.Backend, error) {
+	shared, err := VaultShared(bc)
+	if err != nil {
+		return nil, err
+	}
+	var passphrase string
+	if shared {
+		if ids, err := VaultIdentities(bc); err != nil {
+			return nil, err
+		} else if len(ids) == 0 {
+			return nil, backend.ErrVaultIdentityRequired
+		}
+	} else {
+		passphrase = os.Getenv("ENVREF_VAULT_PASSPHRASE")
+		if passphrase == "" {
+			passphrase = bc.Config["passphrase"]
+		}
+		if passphrase == "" {
+			return nil, fmt.Errorf("vault passphrase required: set ENVREF_VAULT_PASSPHRASE or config.passphrase in %s", config.FullFileName)
+		}
+	}
+
+	opts, err := VaultOptions(bc)
+	if err != nil {
+		return nil, err
+	}
+	v, err := backend.NewVaultBackend(passphrase, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	initialized, err := v.IsInitialized()
+	if err != nil {
+		_ = v.Close()
+		return nil, fmt.Errorf("checking vault: %w", err)
+	}
+	if initialized {
+		if err := v.VerifyPassphrase(); err != nil {
+			_ = v.Close()
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// VaultOptions returns the VaultBackend options for the vault backend
+// config: the database path, the KDF used by vault init, and the age
+// identities that open a shared vault.
+func VaultOptions(bc config.BackendConfig) ([]backend.VaultOption, error) {
+	var opts []backend.VaultOption
+	if path := bc.Config["path"]; path != "" {
+		opts = append(opts, backend.WithVaultPath(path))
+	}
+	kdf, err := VaultKDF(bc, "")
+	if err != nil {
+		return nil, err
+	}
+	identities, err := VaultIdentities(bc)
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) > 0 {
+		opts = append(opts, backend.WithVaultIdentities(identities...))
+	}
+	return append(opts, backend.WithVaultKDF(kdf)), nil
+}
+
+// VaultPath returns the absolute path of the vault database of bc:
+// config.path, or backend.DefaultVaultPath.
+func VaultPath(bc config.BackendConfig) (string, error) {
+	if path := bc.Config["path"]; path != "" {
+		return filepath.Abs(path)
+	}
+	return backend.DefaultVaultPath()
+}
+
+// VaultShared reports whether the vault database of bc is shared with age
+// recipients (see backend.VaultBackend.Grant).
+func VaultShared(bc config.BackendConfig) (bool, error) {
+	path, err := VaultPath(bc)
+	if err != nil {
+		return false, err
+	}
+	shared, err := backend.IsSharedVault(path)
+	if err != nil {
+		return false, fmt.Errorf("checking vault: %w", err)
+	}
+	return shared, nil
+}
+
+// VaultIdentities returns the age identities in the file named by
+// ENVREF_VAULT_IDENTITY or config.identity, or nil if neither is set.
+func VaultIdentities(bc config.BackendConfig) ([]age.Identity, error) {
+	path := os.Getenv("ENVREF_VAULT_IDENTITY")
+	if path == "" {
+		path = bc.Config["identity"]
+	}
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("vault identity: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("vault identity %s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// VaultKDF returns the KDF parameters configured for the vault backend:
+// config.kdf ("argon2id" or "scrypt", overridden by algorithm if it is not
+// empty), config.argon2_time, config.argon2_memory (KiB),
+// config.argon2_threads, and config.scrypt_work_factor. Unset costs use the
+// defaults.
+func VaultKDF(bc config.BackendConfig, algorithm string) (backend.KDFParams, error) {
+	kdf := backend.KDFParams{Algorithm: bc.Config["kdf"]}
+	if algorithm != "" {
+		kdf.Algorithm = algorithm
+	}
+
+	for _, opt := range []struct {
+		key  string
+		bits int
+		set  func(uint64)
+	}{
+		{"argon2_time", 32, func(n uint64) { kdf.Time = uint32(n) }},
+		{"argon2_memory", 32, func(n uint64) { kdf.Memory = uint32(n) }},
+		{"argon2_threads", 8, func(n uint64) { kdf.Threads = uint8(n) }},
+		{"scrypt_work_factor", 8, func(n uint64) { kdf.WorkFactor = int(n) }},
+	} {
+		s := bc.Config[opt.key]
+		if s == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(s, 10, opt.bits)
+		if err != nil || n == 0 {
+			return backend.KDFParams{}, fmt.Errorf("vault config %s: invalid value %q", opt.key, s)
+		}
+		opt.set(n)
+	}
+
+	if err := kdf.Validate(); err != nil {
+		return backend.KDFParams{}, fmt.Errorf("vault config: %w", err)
+	}
+	return kdf, nil
+}
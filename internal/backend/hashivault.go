@@ -21,6 +21,20 @@
 //	      addr: https://vault.example.com:8200  # Vault server address (optional, uses VAULT_ADDR)
 //	      namespace: admin           # Vault enterprise namespace (optional)
 //	      token: s.xxx              # Vault token (optional, uses VAULT_TOKEN)
+//	      auth_method: userpass      # sign in with `vault login -method` (optional)
+//	      auth_username: alice       # auth_<param> keys are passed to the login method
+//	      session: keychain          # reuse the login token across invocations (optional)
+//
+// # Sessions
+//
+// With "auth_method", the backend runs `vault login -method=<method>
+// -no-store` the first time it needs the CLI, passing each
+// "auth_<param>" config key as a param=value argument, and uses the
+// returned token for every later command. A resolve of many keys therefore
+// logs in once. With "session: keychain", the token is also stored in the OS
+// keychain and reused by later invocations until its lease (capped at
+// "session_ttl", default 30m) expires. A rejected token is discarded and the
+// backend logs in again.
 //
 // # How secrets are stored
 //
@@ -34,9 +48,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/xcke/envref/internal/secret"
 )
 
 // Default timeout for Vault CLI operations.
@@ -53,6 +74,12 @@ type HashiVaultBackend struct {
 	token     string        // optional Vault token
 	command   string        // path to the vault CLI executable
 	timeout   time.Duration // max time per CLI invocation
+
+	authMethod  string            // optional `vault login` method
+	authParams  map[string]string // param=value arguments for the login method
+	sessionMode string            // SessionCacheProcess (default) or SessionCacheKeychain
+	sessionTTL  time.Duration     // upper bound for a keychain-cached token
+	session     *session          // nil unless authMethod is set
 }
 
 // HashiVaultOption configures optional settings for HashiVaultBackend.
@@ -86,6 +113,27 @@ func WithHashiVaultCommand(command string) HashiVaultOption {
 	}
 }
 
+// WithHashiVaultLogin signs in with `vault login -method=<method>` once and
+// uses the returned token for later commands. params are passed to the
+// login method as param=value arguments.
+func WithHashiVaultLogin(method string, params map[string]string) HashiVaultOption {
+	return func(b *HashiVaultBackend) {
+		b.authMethod = method
+		b.authParams = params
+	}
+}
+
+// WithHashiVaultSession selects where the login token is cached: mode is
+// SessionCacheProcess (the default) or SessionCacheKeychain. ttl caps how
+// long a keychain-cached token is reused (zero means DefaultSessionTTL). It
+// has no effect without WithHashiVaultLogin.
+func WithHashiVaultSession(mode string, ttl time.Duration) HashiVaultOption {
+	return func(b *HashiVaultBackend) {
+		b.sessionMode = mode
+		b.sessionTTL = ttl
+	}
+}
+
 // NewHashiVaultBackend creates a new HashiVaultBackend that delegates to the
 // `vault` CLI. The mount parameter specifies the KV v2 secrets engine mount
 // path, and prefix specifies the path prefix within the mount.
@@ -99,6 +147,14 @@ func NewHashiVaultBackend(mount, prefix string, opts ...HashiVaultOption) *Hashi
 	for _, opt := range opts {
 		opt(b)
 	}
+	if b.authMethod != "" {
+		mode := b.sessionMode
+		if mode == "" {
+			mode = SessionCacheProcess
+		}
+		item := "session:hashicorp-vault:" + b.addr + ":" + b.namespace + ":" + b.authMethod
+		b.session = newSession(mode, item, b.sessionTTL, b.login)
+	}
 	return b
 }
 
@@ -149,6 +205,12 @@ func (b *HashiVaultBackend) Get(key string) (string, error) {
 		return "", NewKeyError(b.Name(), key, fmt.Errorf("vault kv get: %w", err))
 	}
 
+	return b.kvValue(key, stdout)
+}
+
+// kvValue extracts the "value" field from a `vault kv get` response.
+func (b *HashiVaultBackend) kvValue(key string, stdout []byte) (string, error) {
+	defer secret.ClearBytes(stdout)
 	var result vaultKVGetResponse
 	if err := json.Unmarshal(stdout, &result); err != nil {
 		return "", NewKeyError(b.Name(), key, fmt.Errorf("parse response: %w", err))
@@ -167,6 +229,125 @@ func (b *HashiVaultBackend) Get(key string) (string, error) {
 	return strVal, nil
 }
 
+// vaultKVMetadataResponse represents the relevant fields from
+// `vault kv metadata get -format=json`.
+type vaultKVMetadataResponse struct {
+	Data struct {
+		CreatedTime    time.Time `json:"created_time"`
+		UpdatedTime    time.Time `json:"updated_time"`
+		CurrentVersion int       `json:"current_version"`
+		Versions       map[string]struct {
+			CreatedTime  time.Time `json:"created_time"`
+			DeletionTime string    `json:"deletion_time"`
+			Destroyed    bool      `json:"destroyed"`
+		} `json:"versions"`
+	} `json:"data"`
+}
+
+// Metadata returns when the secret for key was created and last updated,
+// from its KV v2 metadata. Vault does not record the writer.
+// Returns ErrNotFound if no secret with that path exists.
+func (b *HashiVaultBackend) Metadata(key string) (Metadata, error) {
+	result, err := b.kvMetadata(key)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return Metadata{Created: result.Data.CreatedTime, Updated: result.Data.UpdatedTime}, nil
+}
+
+// ListVersions returns the KV v2 versions of the secret for key, oldest
+// first. Returns ErrNotFound if no secret with that path exists.
+func (b *HashiVaultBackend) ListVersions(key string) ([]Version, error) {
+	result, err := b.kvMetadata(key)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]Version, 0, len(result.Data.Versions))
+	for n, v := range result.Data.Versions {
+		number, err := strconv.Atoi(n)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, Version{
+			Number:  number,
+			Created: v.CreatedTime,
+			Current: number == result.Data.CurrentVersion,
+			Deleted: v.Destroyed || v.DeletionTime != "",
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Number < versions[j].Number })
+	return versions, nil
+}
+
+// GetVersion retrieves the value of key at the given KV v2 version.
+// Returns ErrNotFound if the secret or version does not exist, or the
+// version was deleted.
+func (b *HashiVaultBackend) GetVersion(key string, version int) (string, error) {
+	args := []string{
+		"kv", "get",
+		"-mount=" + b.mount,
+		"-version=" + strconv.Itoa(version),
+		"-format=json",
+		b.secretPath(key),
+	}
+	args = b.appendGlobalFlags(args)
+
+	stdout, err := b.run(args)
+	if err != nil {
+		if isHashiVaultNotFoundErr(err) {
+			return "", ErrNotFound
+		}
+		return "", NewKeyError(b.Name(), key, fmt.Errorf("vault kv get: %w", err))
+	}
+	return b.kvValue(key, stdout)
+}
+
+// Rollback restores the given version of key with `vault kv rollback`,
+// which writes its data as a new version.
+func (b *HashiVaultBackend) Rollback(key string, version int) error {
+	args := []string{
+		"kv", "rollback",
+		"-mount=" + b.mount,
+		"-version=" + strconv.Itoa(version),
+		b.secretPath(key),
+	}
+	args = b.appendGlobalFlags(args)
+
+	if _, err := b.run(args); err != nil {
+		if isHashiVaultNotFoundErr(err) {
+			return ErrNotFound
+		}
+		return NewKeyError(b.Name(), key, fmt.Errorf("vault kv rollback: %w", err))
+	}
+	return nil
+}
+
+// kvMetadata reads the KV v2 metadata of the secret for key.
+func (b *HashiVaultBackend) kvMetadata(key string) (*vaultKVMetadataResponse, error) {
+	args := []string{
+		"kv", "metadata", "get",
+		"-mount=" + b.mount,
+		"-format=json",
+		b.secretPath(key),
+	}
+	args = b.appendGlobalFlags(args)
+
+	stdout, err := b.run(args)
+	if err != nil {
+		if isHashiVaultNotFoundErr(err) {
+			return nil, ErrNotFound
+		}
+		return nil, NewKeyError(b.Name(), key, fmt.Errorf("vault kv metadata get: %w", err))
+	}
+
+	var result vaultKVMetadataResponse
+	if err := json.Unmarshal(stdout, &result); err != nil {
+		return nil, NewKeyError(b.Name(), key, fmt.Errorf("parse response: %w", err))
+	}
+	return &result, nil
+}
+
 // Set stores a secret value under the given key in HashiCorp Vault.
 // If a secret at that path already exists, it is overwritten (creating a
 // new version in KV v2).
@@ -206,6 +387,44 @@ func (b *HashiVaultBackend) Delete(key string) error {
 	return nil
 }
 
+// Ping checks that the Vault server is reachable and the token is valid by
+// looking up the token.
+func (b *HashiVaultBackend) Ping() error {
+	args := []string{"token", "lookup", "-format=json"}
+	args = b.appendGlobalFlags(args)
+
+	if _, err := b.run(args); err != nil {
+		return fmt.Errorf("hashicorp-vault ping: %w", err)
+	}
+	return nil
+}
+
+// vaultTokenLookupResponse represents the relevant fields from
+// `vault token lookup -format=json`.
+type vaultTokenLookupResponse struct {
+	Data struct {
+		DisplayName string   `json:"display_name"`
+		Policies    []string `json:"policies"`
+	} `json:"data"`
+}
+
+// Identity returns the identity of the Vault token, named by its display
+// name (e.g., "ldap-alice"), with its policies.
+func (b *HashiVaultBackend) Identity() (Identity, error) {
+	args := b.appendGlobalFlags([]string{"token", "lookup", "-format=json"})
+	stdout, err := b.run(args)
+	if err != nil {
+		return Identity{}, fmt.Errorf("vault token lookup: %w", err)
+	}
+	var result vaultTokenLookupResponse
+	if err := json.Unmarshal(stdout, &result); err != nil {
+		return Identity{}, fmt.Errorf("vault token lookup: parse response: %w", err)
+	}
+	return Identity{Name: result.Data.DisplayName, Details: map[string]string{
+		"policies": strings.Join(result.Data.Policies, ", "),
+	}}, nil
+}
+
 // List returns all secret keys under the configured prefix.
 // The prefix is stripped from the returned keys.
 func (b *HashiVaultBackend) List() ([]string, error) {
@@ -255,18 +474,85 @@ func (b *HashiVaultBackend) appendGlobalFlags(args []string) []string {
 }
 
 // run executes the vault CLI with the given arguments and returns stdout.
+// With a login method configured, the login token is used, and a command
+// rejected because the token expired is retried once after logging in again.
 func (b *HashiVaultBackend) run(args []string) ([]byte, error) {
-	cmd := exec.Command(b.command, args...) //nolint:gosec // Command path comes from trusted config or default "vault"
+	if b.session == nil {
+		return b.runVault(args, b.token, false)
+	}
+
+	token, fresh, err := b.session.get()
+	if err != nil {
+		return nil, fmt.Errorf("vault login: %w", err)
+	}
+	stdout, err := b.runVault(args, token, false)
+	if err == nil || fresh || !isHashiVaultAuthErr(err) {
+		return stdout, err
+	}
+
+	b.session.invalidate(token)
+	if token, _, err = b.session.get(); err != nil {
+		return nil, fmt.Errorf("vault login: %w", err)
+	}
+	return b.runVault(args, token, false)
+}
+
+// vaultLoginResponse represents the relevant fields from
+// `vault login -format=json`.
+type vaultLoginResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+}
+
+// login runs `vault login` with the configured method and returns the
+// client token and its lease. The user may be prompted on the terminal,
+// e.g. for a password.
+func (b *HashiVaultBackend) login() (string, time.Duration, error) {
+	args := []string{"login", "-method=" + b.authMethod, "-format=json", "-no-store"}
+	args = b.appendGlobalFlags(args)
+	params := make([]string, 0, len(b.authParams))
+	for k, v := range b.authParams {
+		params = append(params, k+"="+v)
+	}
+	sort.Strings(params)
+	args = append(args, params...)
+
+	stdout, err := b.runVault(args, "", true)
+	if err != nil {
+		return "", 0, err
+	}
+	defer secret.ClearBytes(stdout)
+	var resp vaultLoginResponse
+	if err := json.Unmarshal(stdout, &resp); err != nil {
+		return "", 0, fmt.Errorf("parse login response: %w", err)
+	}
+	return resp.Auth.ClientToken, time.Duration(resp.Auth.LeaseDuration) * time.Second, nil
+}
+
+// runVault executes the vault CLI once. A non-empty token is passed in
+// VAULT_TOKEN. An interactive command reads the terminal and shows its
+// prompts, and gets sessionLoginTimeout instead of the per-operation timeout.
+func (b *HashiVaultBackend) runVault(args []string, token string, interactive bool) ([]byte, error) {
+	cmd := exec.Command(b.command, slices.Clip(args)...) //nolint:gosec // Command path comes from trusted config or default "vault"
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
+	timeout := b.timeout
+	if interactive {
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+		timeout = sessionLoginTimeout
+	}
+
 	// Set VAULT_TOKEN env var if configured, inheriting the rest of the
 	// parent environment (which includes VAULT_ADDR, VAULT_TOKEN, etc.
 	// from the user's shell).
-	if b.token != "" {
-		cmd.Env = append(cmd.Environ(), "VAULT_TOKEN="+b.token)
+	if token != "" {
+		cmd.Env = append(cmd.Environ(), "VAULT_TOKEN="+token)
 	}
 
 	done := make(chan error, 1)
@@ -287,14 +573,23 @@ func (b *HashiVaultBackend) run(args []string) ([]byte, error) {
 			}
 			return nil, err
 		}
-	case <-time.After(b.timeout):
+	case <-time.After(timeout):
 		_ = cmd.Process.Kill()
-		return nil, fmt.Errorf("vault cli timed out after %s", b.timeout)
+		return nil, fmt.Errorf("vault cli timed out after %s", timeout)
 	}
 
 	return stdout.Bytes(), nil
 }
 
+// isHashiVaultAuthErr checks whether an error from the Vault CLI indicates
+// that the token expired or was revoked.
+func isHashiVaultAuthErr(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "permission denied") ||
+		strings.Contains(msg, "missing client token") ||
+		strings.Contains(msg, "invalid token")
+}
+
 // isHashiVaultNotFoundErr checks whether an error from the Vault CLI indicates
 // that a secret or path was not found.
 func isHashiVaultNotFoundErr(err error) bool {
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 )
 
 // buildVaultMock compiles the mock vault CLI helper into a temporary directory
@@ -220,3 +221,140 @@ func TestIsHashiVaultNotFoundErr(t *testing.T) {
 		}
 	}
 }
+
+func TestHashiVaultBackend_Ping(t *testing.T) {
+	vaultPath := buildVaultMock(t)
+	if err := NewHashiVaultBackend("secret", "test", WithHashiVaultCommand(vaultPath)).Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	err := NewHashiVaultBackend("secret", "test", WithHashiVaultCommand("/nonexistent/vault")).Ping()
+	if err == nil {
+		t.Fatal("Ping with invalid command: expected error, got nil")
+	}
+}
+
+func TestHashiVaultBackend_Identity(t *testing.T) {
+	vaultPath := buildVaultMock(t)
+	id, err := NewHashiVaultBackend("secret", "test", WithHashiVaultCommand(vaultPath)).Identity()
+	if err != nil {
+		t.Fatalf("Identity: %v", err)
+	}
+	if id.Name != "ldap-alice" {
+		t.Errorf("Identity name: got %q", id.Name)
+	}
+	if id.Details["policies"] != "default, dev" {
+		t.Errorf("Identity policies: got %q", id.Details["policies"])
+	}
+}
+
+func TestHashiVaultBackend_Metadata(t *testing.T) {
+	vaultPath := buildVaultMock(t)
+	b := NewHashiVaultBackend("secret", "test", WithHashiVaultCommand(vaultPath))
+	if err := b.Set("key", "v"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	md, err := b.Metadata("key")
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if !md.Created.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !md.Updated.Equal(time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Metadata = %+v", md)
+	}
+
+	if _, err := b.Metadata("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Metadata(missing): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestHashiVaultBackend_Versions(t *testing.T) {
+	vaultPath := buildVaultMock(t)
+	b := NewHashiVaultBackend("secret", "test", WithHashiVaultCommand(vaultPath))
+	for _, v := range []string{"one", "two", "three"} {
+		if err := b.Set("key", v); err != nil {
+			t.Fatalf("Set(%q): %v", v, err)
+		}
+	}
+
+	versions, err := b.ListVersions("key")
+	if err != nil {
+		t.Fatalf("ListVersions: %v", err)
+	}
+	if len(versions) != 3 || versions[0].Number != 1 || !versions[2].Current || versions[0].Current {
+		t.Fatalf("ListVersions = %+v, want 1..3 with 3 current", versions)
+	}
+
+	got, err := b.GetVersion("key", 1)
+	if err != nil || got != "one" {
+		t.Fatalf("GetVersion(1) = %q, %v; want one", got, err)
+	}
+	if _, err := b.GetVersion("key", 9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetVersion(9): got %v, want ErrNotFound", err)
+	}
+
+	if err := b.Rollback("key", 1); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if got, _ := b.Get("key"); got != "one" {
+		t.Errorf("Get after rollback = %q, want one", got)
+	}
+	if versions, _ := b.ListVersions("key"); len(versions) != 4 {
+		t.Errorf("rollback should add a version, got %d", len(versions))
+	}
+
+	if _, err := b.ListVersions("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ListVersions(missing): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestHashiVaultBackend_LoginSession(t *testing.T) {
+	vaultPath := buildVaultMock(t)
+	b := NewHashiVaultBackend("secret", "envref",
+		WithHashiVaultCommand(vaultPath),
+		WithHashiVaultLogin("userpass", map[string]string{"username": "alice", "password": "pw"}),
+	)
+
+	for _, k := range []string{"a", "b", "c"} {
+		if err := b.Set(k, "value-"+k); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if got, err := b.Get(k); err != nil || got != "value-"+k {
+			t.Fatalf("Get: got %q, %v", got, err)
+		}
+	}
+	logins := mockLog(t, vaultPath, "logins.log")
+	if len(logins) != 1 || logins[0] != "userpass password=pw username=alice" {
+		t.Fatalf("logins: got %q, want one userpass login", logins)
+	}
+
+	// An expired token is replaced by logging in again.
+	if err := os.WriteFile(filepath.Join(filepath.Dir(vaultPath), "token"), []byte("token-other"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := b.Get("a"); err != nil || got != "value-a" {
+		t.Fatalf("Get after expiry: got %q, %v", got, err)
+	}
+	if logins := mockLog(t, vaultPath, "logins.log"); len(logins) != 2 {
+		t.Fatalf("logins after expiry: got %d, want 2", len(logins))
+	}
+}
+
+func TestHashiVaultBackend_LoginSessionKeychain(t *testing.T) {
+	defer setupMockKeyring()()
+	vaultPath := buildVaultMock(t)
+
+	for i := 0; i < 3; i++ {
+		b := NewHashiVaultBackend("secret", "envref",
+			WithHashiVaultCommand(vaultPath),
+			WithHashiVaultLogin("userpass", map[string]string{"username": "alice"}),
+			WithHashiVaultSession(SessionCacheKeychain, 0),
+		)
+		if err := b.Ping(); err != nil {
+			t.Fatalf("Ping: %v", err)
+		}
+	}
+	if logins := mockLog(t, vaultPath, "logins.log"); len(logins) != 1 {
+		t.Fatalf("logins across backends: got %d, want 1", len(logins))
+	}
+}
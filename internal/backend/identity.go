@@ -0,0 +1,46 @@
+package backend
+
+import "errors"
+
+// Identity describes who a backend acts as when it reads and writes
+// secrets.
+type Identity struct {
+	// Name identifies the caller in the store's own terms (e.g., an IAM
+	// ARN, a Vault token display name, or a 1Password account email).
+	Name string
+
+	// Details holds further facts about the identity for display, such as
+	// the AWS account or the Vault token policies. It may be nil.
+	Details map[string]string
+}
+
+// IdentityProvider is an optional interface for backends that can tell
+// which identity they act as. It is used to check the acl block of
+// .envref.yaml against the identity the store itself knows the caller by.
+type IdentityProvider interface {
+	// Identity returns the identity the backend acts as.
+	Identity() (Identity, error)
+}
+
+// ErrIdentityUnsupported is returned by GetIdentity for backends that
+// cannot tell which identity they act as.
+var ErrIdentityUnsupported = errors.New("backend does not report its identity")
+
+// GetIdentity returns the identity b acts as, or ErrIdentityUnsupported if
+// b does not implement IdentityProvider.
+func GetIdentity(b Backend) (Identity, error) {
+	if ip, ok := b.(IdentityProvider); ok {
+		return ip.Identity()
+	}
+	return Identity{}, ErrIdentityUnsupported
+}
+
+// ErrAccessDenied is returned, wrapped, for a secret the access rules of
+// a Registry (see Registry.SetAccess) do not allow.
+var ErrAccessDenied = errors.New("access denied")
+
+// AccessFunc decides whether a secret may be read or written. It is called
+// with the backend holding the secret, the profile scope ("" for the
+// project scope), and the key, and returns an error wrapping
+// ErrAccessDenied if access is not allowed.
+type AccessFunc func(b Backend, profile, key string) error
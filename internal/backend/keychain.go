@@ -10,6 +10,7 @@ package backend
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"sort"
 	"sync"
 
@@ -32,9 +33,27 @@ const keychainIndexKey = "__envref_key_index__"
 // Because go-keyring does not support listing keys, KeychainBackend
 // maintains a separate index entry that tracks all stored keys as a
 // JSON array. This index is updated atomically with each Set/Delete.
+//
+// Reads of keys selected by a PresencePolicy first require the user to
+// confirm their presence (Touch ID or the account password on macOS). One
+// confirmation covers all reads by the same backend, i.e. one envref
+// command.
 type KeychainBackend struct {
-	service string
-	mu      sync.Mutex
+	service   string
+	presence  PresencePolicy
+	confirmed bool
+	mu        sync.Mutex
+}
+
+// KeychainOption configures a KeychainBackend.
+type KeychainOption func(*KeychainBackend)
+
+// WithPresencePolicy requires a user-presence check before reading the keys
+// selected by policy.
+func WithPresencePolicy(policy PresencePolicy) KeychainOption {
+	return func(k *KeychainBackend) {
+		k.presence = policy
+	}
 }
 
 // keyringProvider abstracts the go-keyring functions for testing.
@@ -53,10 +72,14 @@ var keyringProvider = struct {
 // NewKeychainBackend creates a new KeychainBackend. The service name
 // is derived from the keychainServicePrefix to namespace envref secrets
 // in the OS keychain.
-func NewKeychainBackend() *KeychainBackend {
-	return &KeychainBackend{
+func NewKeychainBackend(opts ...KeychainOption) *KeychainBackend {
+	k := &KeychainBackend{
 		service: keychainServicePrefix,
 	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
 }
 
 // Name returns "keychain", the identifier used in .envref.yaml configuration
@@ -67,8 +90,13 @@ func (k *KeychainBackend) Name() string {
 
 // Get retrieves the secret value for the given key from the OS keychain.
 // Returns ErrNotFound if the key does not exist. Other errors are returned
-// as *KeychainError with a classified kind and actionable hint.
+// as *KeychainError with a classified kind and actionable hint. If the
+// presence policy selects key, the user must confirm their presence first.
 func (k *KeychainBackend) Get(key string) (string, error) {
+	if err := k.checkPresence(key); err != nil {
+		return "", err
+	}
+
 	val, err := keyringProvider.Get(k.service, key)
 	if err != nil {
 		if isNotFoundErr(err) {
@@ -125,6 +153,81 @@ func (k *KeychainBackend) Delete(key string) error {
 	return nil
 }
 
+// checkPresence asks the user to confirm their presence before key is read,
+// if the presence policy requires it and no earlier read was confirmed.
+// Keys missing from the index return ErrNotFound without a prompt, so that
+// fallback lookups through other backends do not prompt needlessly.
+func (k *KeychainBackend) checkPresence(key string) error {
+	if !k.presence.Required(key) {
+		return nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.confirmed {
+		return nil
+	}
+
+	keys, err := k.loadIndex()
+	if err != nil {
+		return classifyKeychainErr("get", key, err)
+	}
+	if !slices.Contains(keys, key) {
+		return ErrNotFound
+	}
+
+	if err := presenceProvider(fmt.Sprintf("read the secret %s", key)); err != nil {
+		return fmt.Errorf("keychain get %q: %w", key, err)
+	}
+	k.confirmed = true
+	return nil
+}
+
+// Ping checks that the OS keychain can be read by loading the key index.
+// Errors are returned as *KeychainError with a classified kind and
+// actionable hint.
+func (k *KeychainBackend) Ping() error {
+	if _, err := keyringProvider.Get(k.service, keychainIndexKey); err != nil && !isNotFoundErr(err) {
+		return classifyKeychainErr("ping", "", err)
+	}
+	return nil
+}
+
+// KeychainItem returns an envref item stored in the OS keychain outside the
+// secret index, such as the passphrase for encrypted config values. It does
+// not appear in List. Returns ErrNotFound if the item does not exist.
+func KeychainItem(name string) (string, error) {
+	val, err := keyringProvider.Get(keychainServicePrefix, name)
+	if err != nil {
+		if isNotFoundErr(err) {
+			return "", ErrNotFound
+		}
+		return "", classifyKeychainErr("get", name, err)
+	}
+	return val, nil
+}
+
+// SetKeychainItem stores an envref item in the OS keychain outside the
+// secret index. See KeychainItem.
+func SetKeychainItem(name, value string) error {
+	if err := keyringProvider.Set(keychainServicePrefix, name, value); err != nil {
+		return classifyKeychainErr("set", name, err)
+	}
+	return nil
+}
+
+// DeleteKeychainItem removes an envref item stored with SetKeychainItem.
+// Returns ErrNotFound if the item does not exist.
+func DeleteKeychainItem(name string) error {
+	if err := keyringProvider.Delete(keychainServicePrefix, name); err != nil {
+		if isNotFoundErr(err) {
+			return ErrNotFound
+		}
+		return classifyKeychainErr("delete", name, err)
+	}
+	return nil
+}
+
 // List returns all secret keys stored in this backend by reading the
 // key index. The returned keys are sorted alphabetically. Errors are
 // returned as *KeychainError with a classified kind and actionable hint.
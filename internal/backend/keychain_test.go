@@ -393,3 +393,111 @@ func TestKeychainBackend_LargeValue(t *testing.T) {
 		t.Fatalf("Get large value: length got %d, want %d", len(val), len(largeVal))
 	}
 }
+
+func TestKeychainBackend_Ping(t *testing.T) {
+	cleanup := setupMockKeyring()
+	defer cleanup()
+
+	if err := NewKeychainBackend().Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
+// setupMockPresence replaces the presence provider with one that returns
+// result and counts its calls.
+func setupMockPresence(t *testing.T, result error) *int {
+	t.Helper()
+	orig := presenceProvider
+	calls := 0
+	presenceProvider = func(string) error {
+		calls++
+		return result
+	}
+	t.Cleanup(func() { presenceProvider = orig })
+	return &calls
+}
+
+func TestPresencePolicy_Required(t *testing.T) {
+	policy := PresencePolicy{Patterns: []string{"stripe_*", "myapp/prod/*"}}
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"myapp/stripe_key", true},
+		{"myapp/staging/stripe_key", true},
+		{"myapp/prod/db_password", true},
+		{"myapp/db_password", false},
+		{"other/prod/db_password", false},
+	}
+	for _, tt := range tests {
+		if got := policy.Required(tt.key); got != tt.want {
+			t.Errorf("Required(%q): got %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	if !(PresencePolicy{Patterns: []string{"*"}}).Required("myapp/staging/key") {
+		t.Error(`"*" should match every key`)
+	}
+	if (PresencePolicy{}).Required("key") {
+		t.Error("empty policy should not require presence")
+	}
+	if err := (PresencePolicy{Patterns: []string{"["}}).Validate(); err == nil {
+		t.Error("Validate: expected error for invalid pattern")
+	}
+}
+
+func TestKeychainBackend_PresenceRequired(t *testing.T) {
+	cleanup := setupMockKeyring()
+	defer cleanup()
+	calls := setupMockPresence(t, nil)
+
+	kb := NewKeychainBackend(WithPresencePolicy(PresencePolicy{Patterns: []string{"prod_*"}}))
+	for _, k := range []string{"prod_key", "prod_other", "dev_key"} {
+		if err := kb.Set(k, "value"); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+
+	if _, err := kb.Get("dev_key"); err != nil {
+		t.Fatalf("Get(dev_key): %v", err)
+	}
+	if *calls != 0 {
+		t.Fatalf("presence checked for unprotected key (%d calls)", *calls)
+	}
+
+	// Missing keys do not prompt.
+	if _, err := kb.Get("prod_missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(prod_missing): got %v, want ErrNotFound", err)
+	}
+	if *calls != 0 {
+		t.Fatalf("presence checked for missing key (%d calls)", *calls)
+	}
+
+	// One confirmation covers later reads.
+	for _, k := range []string{"prod_key", "prod_other"} {
+		if v, err := kb.Get(k); err != nil || v != "value" {
+			t.Fatalf("Get(%s): got %q, %v", k, v, err)
+		}
+	}
+	if *calls != 1 {
+		t.Errorf("presence checks: got %d, want 1", *calls)
+	}
+}
+
+func TestKeychainBackend_PresenceDenied(t *testing.T) {
+	cleanup := setupMockKeyring()
+	defer cleanup()
+	calls := setupMockPresence(t, ErrPresenceDenied)
+
+	kb := NewKeychainBackend(WithPresencePolicy(PresencePolicy{Patterns: []string{"*"}}))
+	if err := kb.Set("key", "value"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := kb.Get("key"); !errors.Is(err, ErrPresenceDenied) {
+			t.Fatalf("Get: got %v, want ErrPresenceDenied", err)
+		}
+	}
+	if *calls != 2 {
+		t.Errorf("a denied check must not be remembered: got %d calls, want 2", *calls)
+	}
+}
@@ -0,0 +1,87 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrOffline is returned in offline mode for a secret that has no
+// last-known-good value.
+var ErrOffline = errors.New("no last-known-good value (offline)")
+
+// KnownValues holds the last value read of each secret, by backend name and
+// storage key, for LastKnownGood.
+type KnownValues interface {
+	// Lookup returns the last value read of key from backend, and when it
+	// was read.
+	Lookup(backend, key string) (value string, at time.Time, ok bool)
+	// Remember records value as the last value read of key from backend.
+	Remember(backend, key, value string)
+}
+
+// LastKnownGood returns middleware that records every value read in known
+// and serves the recorded value when the backend fails, calling fallback
+// with the key and the time the value was read. A secret the backend
+// reports as not found is not served from known, as it was deleted.
+//
+// With offline set, the backend is not called at all: reads are served
+// from known only, and fail with ErrOffline for keys it does not hold.
+func LastKnownGood(known KnownValues, offline bool, fallback func(name, key string, at time.Time)) Middleware {
+	return func(b Backend) Backend {
+		return &lastGoodBackend{wrapper: wrapper{inner: b}, known: known, offline: offline, fallback: fallback}
+	}
+}
+
+// lastGoodBackend is the Backend returned by LastKnownGood.
+type lastGoodBackend struct {
+	wrapper
+	known    KnownValues
+	offline  bool
+	fallback func(name, key string, at time.Time)
+}
+
+// Get reads key from the backend, falling back to its last-known-good
+// value when the backend fails.
+func (l *lastGoodBackend) Get(key string) (string, error) {
+	if l.offline {
+		if value, _, ok := l.known.Lookup(l.Name(), key); ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("%s: %w", key, ErrOffline)
+	}
+
+	value, err := l.inner.Get(key)
+	if err == nil {
+		l.known.Remember(l.Name(), key, value)
+		return value, nil
+	}
+	if errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+	known, at, ok := l.known.Lookup(l.Name(), key)
+	if !ok {
+		return "", err
+	}
+	if l.fallback != nil {
+		l.fallback(l.Name(), key, at)
+	}
+	return known, nil
+}
+
+// GetMany reads keys in one batch and records their values. A failing
+// batch is returned as is, so that callers retry each key with Get and
+// its fallback.
+func (l *lastGoodBackend) GetMany(keys []string) (map[string]string, error) {
+	if l.offline {
+		return nil, ErrOffline
+	}
+	values, err := GetMany(l.inner, keys)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range values {
+		l.known.Remember(l.Name(), key, value)
+	}
+	return values, nil
+}
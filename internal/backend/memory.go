@@ -0,0 +1,131 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// MemoryBackend stores secrets in memory, optionally persisted to a
+// plaintext JSON file. It is meant for tests and CI, where a deterministic
+// store is needed and no keychain or secret manager is available. Values are
+// not encrypted; never use it for real secrets.
+//
+// Without a file, secrets live only as long as the backend. With a file,
+// the store is read when the backend is created and written after every Set
+// and Delete, so secrets persist across envref invocations. The file is a
+// JSON object mapping stored keys (e.g., "myapp/api_key") to values, which
+// makes it easy to seed a test fixture by hand.
+type MemoryBackend struct {
+	name string
+	path string
+
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// NewMemoryBackend creates an empty in-memory backend with the given name.
+func NewMemoryBackend(name string) *MemoryBackend {
+	return &MemoryBackend{name: name, secrets: make(map[string]string)}
+}
+
+// NewFileMemoryBackend creates an in-memory backend persisted to the JSON
+// file at path. A missing file is treated as an empty store and created on
+// the first write.
+func NewFileMemoryBackend(name, path string) (*MemoryBackend, error) {
+	m := NewMemoryBackend(name)
+	m.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("memory: reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &m.secrets); err != nil {
+		return nil, fmt.Errorf("memory: parsing %s: %w", path, err)
+	}
+	if m.secrets == nil {
+		m.secrets = make(map[string]string)
+	}
+	return m, nil
+}
+
+// Name returns the backend name.
+func (m *MemoryBackend) Name() string {
+	return m.name
+}
+
+// Get retrieves a secret value by key.
+func (m *MemoryBackend) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+// Set stores a secret value under the given key.
+func (m *MemoryBackend) Set(key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets[key] = value
+	return m.save()
+}
+
+// Delete removes a secret by key.
+func (m *MemoryBackend) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.secrets[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.secrets, key)
+	return m.save()
+}
+
+// List returns all secret keys in sorted order.
+func (m *MemoryBackend) List() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.secrets))
+	for k := range m.secrets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Ping always succeeds for an in-memory store. A file-backed store was
+// already read when the backend was created.
+func (m *MemoryBackend) Ping() error {
+	return nil
+}
+
+// save writes the store to its file, if it has one. The caller must hold
+// m.mu. Keys are written in sorted order, so the file is deterministic.
+func (m *MemoryBackend) save() error {
+	if m.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(m.secrets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("memory: encoding store: %w", err)
+	}
+	if dir := filepath.Dir(m.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("memory: creating %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(m.path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("memory: writing %s: %w", m.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemoryBackend_Interface(t *testing.T) {
+	var _ Backend = &MemoryBackend{}
+	var _ HealthChecker = &MemoryBackend{}
+}
+
+func TestMemoryBackend_SetGetDeleteList(t *testing.T) {
+	m := NewMemoryBackend("mem")
+
+	if err := m.Set("b", "2"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := m.Set("a", "1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, err := m.Get("a"); err != nil || v != "1" {
+		t.Fatalf("Get: got %q, %v", v, err)
+	}
+	if _, err := m.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get missing: got %v, want ErrNotFound", err)
+	}
+	keys, err := m.List()
+	if err != nil || strings.Join(keys, ",") != "a,b" {
+		t.Fatalf("List: got %v, %v", keys, err)
+	}
+	if err := m.Delete("a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := m.Delete("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Delete missing: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileMemoryBackend_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets", "store.json")
+
+	m, err := NewFileMemoryBackend("mem", path)
+	if err != nil {
+		t.Fatalf("NewFileMemoryBackend: %v", err)
+	}
+	if keys, _ := m.List(); len(keys) != 0 {
+		t.Fatalf("List on missing file: got %v, want empty", keys)
+	}
+	if err := m.Set("myapp/b", "2"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := m.Set("myapp/a", "1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading store: %v", err)
+	}
+	want := "{\n  \"myapp/a\": \"1\",\n  \"myapp/b\": \"2\"\n}\n"
+	if string(data) != want {
+		t.Errorf("store file = %q, want %q", data, want)
+	}
+
+	reopened, err := NewFileMemoryBackend("mem", path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if v, err := reopened.Get("myapp/a"); err != nil || v != "1" {
+		t.Errorf("Get after reopen: got %q, %v", v, err)
+	}
+}
+
+func TestFileMemoryBackend_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileMemoryBackend("mem", path); err == nil || !strings.Contains(err.Error(), "parsing") {
+		t.Errorf("expected parse error, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"time"
+)
+
+// Metadata describes when a secret was written and by whom. Fields the
+// store does not record are left at their zero value.
+type Metadata struct {
+	// Created is when the secret was first stored.
+	Created time.Time
+	// Updated is when the secret's value was last written.
+	Updated time.Time
+	// UpdatedBy identifies who last wrote the value, in the store's own
+	// terms (e.g., a user name, an IAM ARN, or a 1Password user ID).
+	UpdatedBy string
+}
+
+// MetadataProvider is an optional interface for backends that record when
+// secrets were written and by whom. It is used by `secret list --long` and
+// to find secrets that are due for rotation.
+type MetadataProvider interface {
+	// Metadata returns the metadata for key.
+	// Returns ErrNotFound if the key does not exist.
+	Metadata(key string) (Metadata, error)
+}
+
+// ErrMetadataUnsupported is returned by GetMetadata for backends that do
+// not record secret metadata.
+var ErrMetadataUnsupported = errors.New("backend does not record secret metadata")
+
+// GetMetadata returns the metadata for key from b, or
+// ErrMetadataUnsupported if b does not implement MetadataProvider.
+func GetMetadata(b Backend, key string) (Metadata, error) {
+	if mp, ok := b.(MetadataProvider); ok {
+		return mp.Metadata(key)
+	}
+	return Metadata{}, ErrMetadataUnsupported
+}
+
+// writerIdentity returns the identity recorded as the writer of secrets
+// stored by backends that keep their own metadata: "user@host", or
+// whichever part is known.
+func writerIdentity() string {
+	name := ""
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	switch {
+	case name != "" && host != "":
+		return name + "@" + host
+	case name != "":
+		return name
+	default:
+		return host
+	}
+}
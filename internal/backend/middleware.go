@@ -0,0 +1,690 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xcke/envref/internal/secret"
+)
+
+// Middleware wraps a Backend to add behavior that is the same for every
+// backend type, such as logging or caching.
+type Middleware func(Backend) Backend
+
+// Chain wraps b in the given middleware. The first middleware is the
+// outermost: it sees each call first and its result last.
+func Chain(b Backend, middleware ...Middleware) Backend {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		b = middleware[i](b)
+	}
+	return b
+}
+
+// wrapper forwards Backend and the optional backend interfaces to inner.
+// Middleware types embed it and override the methods they change; a type
+// that overrides Get should override GetMany as well, since batch reads do
+// not go through Get.
+type wrapper struct {
+	inner Backend
+}
+
+// Name returns the name of the underlying backend.
+func (w wrapper) Name() string { return w.inner.Name() }
+
+// Get retrieves a secret from the underlying backend.
+func (w wrapper) Get(key string) (string, error) { return w.inner.Get(key) }
+
+// Set stores a secret in the underlying backend.
+func (w wrapper) Set(key, value string) error { return w.inner.Set(key, value) }
+
+// Delete removes a secret from the underlying backend.
+func (w wrapper) Delete(key string) error { return w.inner.Delete(key) }
+
+// List returns the keys of the underlying backend.
+func (w wrapper) List() ([]string, error) { return w.inner.List() }
+
+// GetMany retrieves many secrets from the underlying backend.
+func (w wrapper) GetMany(keys []string) (map[string]string, error) {
+	return GetMany(w.inner, keys)
+}
+
+// Ping checks that the underlying backend is reachable.
+func (w wrapper) Ping() error { return Ping(w.inner) }
+
+// Metadata returns the metadata for key from the underlying backend.
+func (w wrapper) Metadata(key string) (Metadata, error) { return GetMetadata(w.inner, key) }
+
+// Identity returns the identity the underlying backend acts as.
+func (w wrapper) Identity() (Identity, error) { return GetIdentity(w.inner) }
+
+// ListVersions returns the versions of key from the underlying backend.
+func (w wrapper) ListVersions(key string) ([]Version, error) {
+	vb, ok := w.inner.(VersionedBackend)
+	if !ok {
+		return nil, ErrVersioningUnsupported
+	}
+	return vb.ListVersions(key)
+}
+
+// GetVersion retrieves key at the given version from the underlying backend.
+func (w wrapper) GetVersion(key string, version int) (string, error) {
+	vb, ok := w.inner.(VersionedBackend)
+	if !ok {
+		return "", ErrVersioningUnsupported
+	}
+	return vb.GetVersion(key, version)
+}
+
+// Rollback restores the given version of key in the underlying backend.
+func (w wrapper) Rollback(key string, version int) error {
+	vb, ok := w.inner.(VersionedBackend)
+	if !ok {
+		return ErrVersioningUnsupported
+	}
+	return vb.Rollback(key, version)
+}
+
+// Close closes the underlying backend if it holds resources.
+func (w wrapper) Close() error {
+	if c, ok := w.inner.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Logging returns middleware that writes one line to out per backend
+// operation, with the key, the duration, and any error. Secret values are
+// never written.
+func Logging(out io.Writer) Middleware {
+	return func(b Backend) Backend {
+		return &loggingBackend{wrapper: wrapper{inner: b}, out: out}
+	}
+}
+
+// loggingBackend is the Backend returned by Logging.
+type loggingBackend struct {
+	wrapper
+	out io.Writer
+}
+
+// log writes the line for one operation started at start.
+func (l *loggingBackend) log(op, key string, start time.Time, err error) {
+	line := fmt.Sprintf("backend %q: %s", l.Name(), op)
+	if key != "" {
+		line += " " + key
+	}
+	line += fmt.Sprintf(" (%s)", time.Since(start).Round(time.Microsecond))
+	if err != nil {
+		line += ": " + secret.Redact(err.Error())
+	}
+	_, _ = fmt.Fprintln(l.out, line)
+}
+
+// Get retrieves a secret and logs the call.
+func (l *loggingBackend) Get(key string) (string, error) {
+	start := time.Now()
+	value, err := l.inner.Get(key)
+	l.log("get", key, start, err)
+	return value, err
+}
+
+// GetMany retrieves many secrets and logs the call.
+func (l *loggingBackend) GetMany(keys []string) (map[string]string, error) {
+	start := time.Now()
+	values, err := GetMany(l.inner, keys)
+	l.log("get-many", fmt.Sprintf("%d keys, %d found", len(keys), len(values)), start, err)
+	return values, err
+}
+
+// Set stores a secret and logs the call.
+func (l *loggingBackend) Set(key, value string) error {
+	start := time.Now()
+	err := l.inner.Set(key, value)
+	l.log("set", key, start, err)
+	return err
+}
+
+// Delete removes a secret and logs the call.
+func (l *loggingBackend) Delete(key string) error {
+	start := time.Now()
+	err := l.inner.Delete(key)
+	l.log("delete", key, start, err)
+	return err
+}
+
+// List returns the backend's keys and logs the call.
+func (l *loggingBackend) List() ([]string, error) {
+	start := time.Now()
+	keys, err := l.inner.List()
+	l.log("list", "", start, err)
+	return keys, err
+}
+
+// Ping checks the backend and logs the call.
+func (l *loggingBackend) Ping() error {
+	start := time.Now()
+	err := Ping(l.inner)
+	l.log("ping", "", start, err)
+	return err
+}
+
+// Trace returns middleware that logs each backend operation to logger at
+// debug level, with the backend, the key, the duration, and any error.
+// Secret values are never logged.
+func Trace(logger *slog.Logger) Middleware {
+	return func(b Backend) Backend {
+		return &traceBackend{wrapper: wrapper{inner: b}, logger: logger}
+	}
+}
+
+// traceBackend is the Backend returned by Trace.
+type traceBackend struct {
+	wrapper
+	logger *slog.Logger
+}
+
+// log logs one operation started at start, with attrs.
+func (t *traceBackend) log(op string, start time.Time, err error, attrs ...any) {
+	attrs = append([]any{"backend", t.Name(), "op", op}, attrs...)
+	attrs = append(attrs, "duration", time.Since(start).Round(time.Microsecond))
+	if err != nil {
+		attrs = append(attrs, "error", secret.Redact(err.Error()))
+	}
+	t.logger.Debug("backend call", attrs...)
+}
+
+// Get retrieves a secret and logs the call.
+func (t *traceBackend) Get(key string) (string, error) {
+	start := time.Now()
+	value, err := t.inner.Get(key)
+	t.log("get", start, err, "key", key)
+	return value, err
+}
+
+// GetMany retrieves many secrets and logs the call.
+func (t *traceBackend) GetMany(keys []string) (map[string]string, error) {
+	start := time.Now()
+	values, err := GetMany(t.inner, keys)
+	t.log("get-many", start, err, "keys", len(keys), "found", len(values))
+	return values, err
+}
+
+// Set stores a secret and logs the call.
+func (t *traceBackend) Set(key, value string) error {
+	start := time.Now()
+	err := t.inner.Set(key, value)
+	t.log("set", start, err, "key", key)
+	return err
+}
+
+// Delete removes a secret and logs the call.
+func (t *traceBackend) Delete(key string) error {
+	start := time.Now()
+	err := t.inner.Delete(key)
+	t.log("delete", start, err, "key", key)
+	return err
+}
+
+// List returns the backend's keys and logs the call.
+func (t *traceBackend) List() ([]string, error) {
+	start := time.Now()
+	keys, err := t.inner.List()
+	t.log("list", start, err)
+	return keys, err
+}
+
+// Ping checks the backend and logs the call.
+func (t *traceBackend) Ping() error {
+	start := time.Now()
+	err := Ping(t.inner)
+	t.log("ping", start, err)
+	return err
+}
+
+// Progress returns middleware that calls report before and after each
+// read of the backend (Get and GetMany), with the backend's name and the
+// number of keys read, e.g. to show which backends a slow resolve waits on.
+func Progress(report func(name string, keys int, done bool)) Middleware {
+	return func(b Backend) Backend {
+		return &progressBackend{wrapper: wrapper{inner: b}, report: report}
+	}
+}
+
+// progressBackend is the Backend returned by Progress.
+type progressBackend struct {
+	wrapper
+	report func(name string, keys int, done bool)
+}
+
+// Get retrieves a secret and reports the read.
+func (p *progressBackend) Get(key string) (string, error) {
+	p.report(p.Name(), 1, false)
+	defer p.report(p.Name(), 1, true)
+	return p.inner.Get(key)
+}
+
+// GetMany retrieves many secrets and reports the read.
+func (p *progressBackend) GetMany(keys []string) (map[string]string, error) {
+	p.report(p.Name(), len(keys), false)
+	defer p.report(p.Name(), len(keys), true)
+	return GetMany(p.inner, keys)
+}
+
+// OpStats holds the counters the metrics middleware keeps for one
+// operation.
+type OpStats struct {
+	// Calls is the number of times the operation was called.
+	Calls int
+	// NotFound is the number of calls that returned ErrNotFound.
+	NotFound int
+	// Errors is the number of calls that failed for any other reason.
+	Errors int
+	// Total is the time spent in the operation across all calls.
+	Total time.Duration
+}
+
+// MetricsBackend counts calls, failures, and time spent per operation of
+// the backend it wraps.
+type MetricsBackend struct {
+	wrapper
+	out   io.Writer
+	mu    sync.Mutex
+	stats map[string]*OpStats
+}
+
+// Metrics returns middleware that records per-operation counters (see
+// MetricsBackend) and writes a summary to out when the backend is closed.
+// A nil out records without reporting.
+func Metrics(out io.Writer) Middleware {
+	return func(b Backend) Backend {
+		return &MetricsBackend{wrapper: wrapper{inner: b}, out: out, stats: make(map[string]*OpStats)}
+	}
+}
+
+// Stats returns a copy of the counters, keyed by operation name.
+func (m *MetricsBackend) Stats() map[string]OpStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]OpStats, len(m.stats))
+	for op, s := range m.stats {
+		out[op] = *s
+	}
+	return out
+}
+
+// record adds one call of op, started at start, to the counters.
+func (m *MetricsBackend) record(op string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats[op]
+	if s == nil {
+		s = &OpStats{}
+		m.stats[op] = s
+	}
+	s.Calls++
+	s.Total += elapsed
+	switch {
+	case errors.Is(err, ErrNotFound):
+		s.NotFound++
+	case err != nil:
+		s.Errors++
+	}
+}
+
+// Get retrieves a secret and records the call.
+func (m *MetricsBackend) Get(key string) (string, error) {
+	start := time.Now()
+	value, err := m.inner.Get(key)
+	m.record("get", start, err)
+	return value, err
+}
+
+// GetMany retrieves many secrets and records the call.
+func (m *MetricsBackend) GetMany(keys []string) (map[string]string, error) {
+	start := time.Now()
+	values, err := GetMany(m.inner, keys)
+	m.record("get-many", start, err)
+	return values, err
+}
+
+// Set stores a secret and records the call.
+func (m *MetricsBackend) Set(key, value string) error {
+	start := time.Now()
+	err := m.inner.Set(key, value)
+	m.record("set", start, err)
+	return err
+}
+
+// Delete removes a secret and records the call.
+func (m *MetricsBackend) Delete(key string) error {
+	start := time.Now()
+	err := m.inner.Delete(key)
+	m.record("delete", start, err)
+	return err
+}
+
+// List returns the backend's keys and records the call.
+func (m *MetricsBackend) List() ([]string, error) {
+	start := time.Now()
+	keys, err := m.inner.List()
+	m.record("list", start, err)
+	return keys, err
+}
+
+// Close writes the summary and closes the underlying backend.
+func (m *MetricsBackend) Close() error {
+	if m.out != nil {
+		stats := m.Stats()
+		ops := make([]string, 0, len(stats))
+		for op := range stats {
+			ops = append(ops, op)
+		}
+		sort.Strings(ops)
+		for _, op := range ops {
+			s := stats[op]
+			_, _ = fmt.Fprintf(m.out, "backend %q: %s: %d calls, %d not found, %d errors, %s total\n",
+				m.Name(), op, s.Calls, s.NotFound, s.Errors, s.Total.Round(time.Microsecond))
+		}
+	}
+	return m.wrapper.Close()
+}
+
+// Cache returns middleware that keeps values read from the backend in
+// memory for ttl. Writes through the cache invalidate the written key.
+// Keys that are not found are not cached.
+func Cache(ttl time.Duration) Middleware {
+	return func(b Backend) Backend {
+		return &cacheBackend{wrapper: wrapper{inner: b}, ttl: ttl, now: time.Now, entries: make(map[string]cacheEntry)}
+	}
+}
+
+// cacheBackend is the Backend returned by Cache.
+type cacheBackend struct {
+	wrapper
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// cacheEntry is a cached value and when it expires.
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// lookup returns the cached value of key, if it has not expired.
+func (c *cacheBackend) lookup(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !c.now().Before(e.expires) {
+		return "", false
+	}
+	return e.value, true
+}
+
+// store caches value for key.
+func (c *cacheBackend) store(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{value: value, expires: c.now().Add(c.ttl)}
+}
+
+// invalidate drops key from the cache.
+func (c *cacheBackend) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// Get returns the cached value of key, reading and caching it on a miss.
+func (c *cacheBackend) Get(key string) (string, error) {
+	if value, ok := c.lookup(key); ok {
+		return value, nil
+	}
+	value, err := c.inner.Get(key)
+	if err != nil {
+		return "", err
+	}
+	c.store(key, value)
+	return value, nil
+}
+
+// GetMany returns cached values and reads the rest in one batch.
+func (c *cacheBackend) GetMany(keys []string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	var missing []string
+	for _, key := range keys {
+		if value, ok := c.lookup(key); ok {
+			values[key] = value
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+	fetched, err := GetMany(c.inner, missing)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range fetched {
+		c.store(key, value)
+		values[key] = value
+	}
+	return values, nil
+}
+
+// Set invalidates key and stores the secret.
+func (c *cacheBackend) Set(key, value string) error {
+	c.invalidate(key)
+	return c.inner.Set(key, value)
+}
+
+// Delete invalidates key and removes the secret.
+func (c *cacheBackend) Delete(key string) error {
+	c.invalidate(key)
+	return c.inner.Delete(key)
+}
+
+// Rollback invalidates key and restores the given version.
+func (c *cacheBackend) Rollback(key string, version int) error {
+	c.invalidate(key)
+	return c.wrapper.Rollback(key, version)
+}
+
+// RateLimit returns middleware that spaces calls to the backend so that at
+// most perSecond of them start each second. Calls over the limit wait.
+func RateLimit(perSecond float64) Middleware {
+	return func(b Backend) Backend {
+		return &rateLimitBackend{
+			wrapper:  wrapper{inner: b},
+			interval: time.Duration(float64(time.Second) / perSecond),
+			sleep:    time.Sleep,
+		}
+	}
+}
+
+// rateLimitBackend is the Backend returned by RateLimit.
+type rateLimitBackend struct {
+	wrapper
+	interval time.Duration
+	sleep    func(time.Duration)
+	mu       sync.Mutex
+	next     time.Time
+}
+
+// wait blocks until the next call may start.
+func (r *rateLimitBackend) wait() {
+	r.mu.Lock()
+	now := time.Now()
+	start := now
+	if r.next.After(now) {
+		start = r.next
+	}
+	r.next = start.Add(r.interval)
+	r.mu.Unlock()
+
+	if d := start.Sub(now); d > 0 {
+		r.sleep(d)
+	}
+}
+
+// Get waits for the rate limit, then retrieves a secret.
+func (r *rateLimitBackend) Get(key string) (string, error) {
+	r.wait()
+	return r.inner.Get(key)
+}
+
+// GetMany waits once for a backend that reads keys in one batch, and once
+// per key otherwise.
+func (r *rateLimitBackend) GetMany(keys []string) (map[string]string, error) {
+	if _, ok := r.inner.(BatchGetter); !ok {
+		return getEach(r, keys)
+	}
+	r.wait()
+	return GetMany(r.inner, keys)
+}
+
+// Set waits for the rate limit, then stores a secret.
+func (r *rateLimitBackend) Set(key, value string) error {
+	r.wait()
+	return r.inner.Set(key, value)
+}
+
+// Delete waits for the rate limit, then removes a secret.
+func (r *rateLimitBackend) Delete(key string) error {
+	r.wait()
+	return r.inner.Delete(key)
+}
+
+// List waits for the rate limit, then lists keys.
+func (r *rateLimitBackend) List() ([]string, error) {
+	r.wait()
+	return r.inner.List()
+}
+
+// Ping waits for the rate limit, then checks the backend.
+func (r *rateLimitBackend) Ping() error {
+	r.wait()
+	return r.wrapper.Ping()
+}
+
+// Metadata waits for the rate limit, then reads metadata.
+func (r *rateLimitBackend) Metadata(key string) (Metadata, error) {
+	r.wait()
+	return r.wrapper.Metadata(key)
+}
+
+// ListVersions waits for the rate limit, then lists versions.
+func (r *rateLimitBackend) ListVersions(key string) ([]Version, error) {
+	r.wait()
+	return r.wrapper.ListVersions(key)
+}
+
+// GetVersion waits for the rate limit, then reads a version.
+func (r *rateLimitBackend) GetVersion(key string, version int) (string, error) {
+	r.wait()
+	return r.wrapper.GetVersion(key, version)
+}
+
+// Rollback waits for the rate limit, then restores a version.
+func (r *rateLimitBackend) Rollback(key string, version int) error {
+	r.wait()
+	return r.wrapper.Rollback(key, version)
+}
+
+// Redacting returns middleware that records every secret value read from or
+// written to the backend with secret.Track, so that messages printed later
+// in the process have them replaced by secret.Mask. Errors it returns are
+// redacted as well, in case a backend echoes a value it failed to store.
+// envref applies it to every configured backend, outside any other
+// middleware.
+func Redacting() Middleware {
+	return func(b Backend) Backend {
+		return &redactingBackend{wrapper: wrapper{inner: b}}
+	}
+}
+
+// redactingBackend is the Backend returned by Redacting.
+type redactingBackend struct {
+	wrapper
+}
+
+// Get retrieves a secret and tracks its value.
+func (r *redactingBackend) Get(key string) (string, error) {
+	value, err := r.inner.Get(key)
+	secret.Track(value)
+	return value, secret.RedactError(err)
+}
+
+// GetMany retrieves many secrets and tracks their values.
+func (r *redactingBackend) GetMany(keys []string) (map[string]string, error) {
+	values, err := GetMany(r.inner, keys)
+	for _, v := range values {
+		secret.Track(v)
+	}
+	return values, secret.RedactError(err)
+}
+
+// Set tracks value, then stores it.
+func (r *redactingBackend) Set(key, value string) error {
+	secret.Track(value)
+	return secret.RedactError(r.inner.Set(key, value))
+}
+
+// GetVersion retrieves an earlier version of a secret and tracks its value.
+func (r *redactingBackend) GetVersion(key string, version int) (string, error) {
+	value, err := r.wrapper.GetVersion(key, version)
+	secret.Track(value)
+	return value, secret.RedactError(err)
+}
+
+// OnWrite returns middleware that calls fn with the key after every
+// successful Set, Delete, or Rollback, for example to invalidate a copy of
+// the value cached outside the process.
+func OnWrite(fn func(key string)) Middleware {
+	return func(b Backend) Backend {
+		return &onWriteBackend{wrapper: wrapper{inner: b}, fn: fn}
+	}
+}
+
+// onWriteBackend is the Backend returned by OnWrite.
+type onWriteBackend struct {
+	wrapper
+	fn func(key string)
+}
+
+// Set stores the secret, then calls fn.
+func (o *onWriteBackend) Set(key, value string) error {
+	if err := o.inner.Set(key, value); err != nil {
+		return err
+	}
+	o.fn(key)
+	return nil
+}
+
+// Delete removes the secret, then calls fn.
+func (o *onWriteBackend) Delete(key string) error {
+	if err := o.inner.Delete(key); err != nil {
+		return err
+	}
+	o.fn(key)
+	return nil
+}
+
+// Rollback restores the given version, then calls fn.
+func (o *onWriteBackend) Rollback(key string, version int) error {
+	if err := o.wrapper.Rollback(key, version); err != nil {
+		return err
+	}
+	o.fn(key)
+	return nil
+}
@@ -0,0 +1,411 @@
+package backend
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xcke/envref/internal/secret"
+)
+
+// countingMemoryBackend is a memoryBackend that counts Get calls.
+type countingMemoryBackend struct {
+	*memoryBackend
+	gets int
+}
+
+func (c *countingMemoryBackend) Get(key string) (string, error) {
+	c.gets++
+	return c.memoryBackend.Get(key)
+}
+
+// closingMemoryBackend is a memoryBackend that records Close.
+type closingMemoryBackend struct {
+	*memoryBackend
+	closed bool
+}
+
+func (c *closingMemoryBackend) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestChain_Order(t *testing.T) {
+	var calls []string
+	tag := func(name string) Middleware {
+		return func(b Backend) Backend {
+			return &tagBackend{wrapper: wrapper{inner: b}, name: name, calls: &calls}
+		}
+	}
+
+	b := Chain(newMemoryBackend("mem"), tag("outer"), tag("inner"))
+	_, _ = b.Get("key")
+
+	if strings.Join(calls, ",") != "outer,inner" {
+		t.Errorf("call order = %v, want [outer inner]", calls)
+	}
+	if b.Name() != "mem" {
+		t.Errorf("Name() = %q, want %q", b.Name(), "mem")
+	}
+}
+
+// tagBackend records its name on each Get.
+type tagBackend struct {
+	wrapper
+	name  string
+	calls *[]string
+}
+
+func (t *tagBackend) Get(key string) (string, error) {
+	*t.calls = append(*t.calls, t.name)
+	return t.inner.Get(key)
+}
+
+func TestMiddleware_ForwardsOptionalInterfaces(t *testing.T) {
+	inner := &closingMemoryBackend{memoryBackend: newMemoryBackend("mem")}
+	b := Chain(inner, Logging(io.Discard), Cache(time.Minute))
+
+	if _, err := GetMetadata(b, "key"); !errors.Is(err, ErrMetadataUnsupported) {
+		t.Errorf("Metadata: got %v, want ErrMetadataUnsupported", err)
+	}
+	if _, err := b.(VersionedBackend).ListVersions("key"); !errors.Is(err, ErrVersioningUnsupported) {
+		t.Errorf("ListVersions: got %v, want ErrVersioningUnsupported", err)
+	}
+
+	c, ok := b.(io.Closer)
+	if !ok {
+		t.Fatal("middleware backend does not implement io.Closer")
+	}
+	if err := c.Close(); err != nil || !inner.closed {
+		t.Errorf("Close did not reach the inner backend (err: %v)", err)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	inner := newMemoryBackend("mem")
+	var out bytes.Buffer
+	b := Chain(inner, Logging(&out))
+
+	if err := b.Set("api_key", "s3cret"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	_, _ = b.Get("missing")
+
+	log := out.String()
+	if !strings.Contains(log, `backend "mem": set api_key`) {
+		t.Errorf("expected set line, got: %q", log)
+	}
+	if !strings.Contains(log, "get missing") || !strings.Contains(log, ErrNotFound.Error()) {
+		t.Errorf("expected get line with error, got: %q", log)
+	}
+	if strings.Contains(log, "s3cret") {
+		t.Errorf("log must not contain secret values: %q", log)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	inner := newMemoryBackend("mem")
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	b := Chain(inner, Trace(logger))
+
+	if err := b.Set("api_key", "s3cret"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	_, _ = b.Get("missing")
+
+	log := out.String()
+	if !strings.Contains(log, "backend=mem op=set key=api_key duration=") {
+		t.Errorf("expected set record, got: %q", log)
+	}
+	if !strings.Contains(log, "op=get key=missing") || !strings.Contains(log, ErrNotFound.Error()) {
+		t.Errorf("expected get record with error, got: %q", log)
+	}
+	if strings.Contains(log, "s3cret") {
+		t.Errorf("log must not contain secret values: %q", log)
+	}
+
+	out.Reset()
+	quiet := Chain(inner, Trace(slog.New(slog.NewTextHandler(&out, nil))))
+	_, _ = quiet.Get("api_key")
+	if out.Len() != 0 {
+		t.Errorf("expected no records below debug level, got: %q", out.String())
+	}
+}
+
+func TestProgress(t *testing.T) {
+	inner := newMemoryBackend("mem")
+	inner.secrets["a"] = "1"
+	var reports []string
+	b := Chain(inner, Progress(func(name string, keys int, done bool) {
+		reports = append(reports, fmt.Sprintf("%s %d %v", name, keys, done))
+	}))
+
+	if v, err := b.Get("a"); err != nil || v != "1" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if _, err := GetMany(b, []string{"a", "missing"}); err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	_ = b.Set("b", "2")
+
+	want := []string{"mem 1 false", "mem 1 true", "mem 2 false", "mem 2 true"}
+	if strings.Join(reports, ",") != strings.Join(want, ",") {
+		t.Errorf("reports = %v, want %v", reports, want)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	inner := newMemoryBackend("mem")
+	inner.secrets["a"] = "1"
+	var out bytes.Buffer
+	b := Chain(inner, Metrics(&out))
+
+	_, _ = b.Get("a")
+	_, _ = b.Get("missing")
+	_ = b.Set("b", "2")
+
+	stats := b.(*MetricsBackend).Stats()
+	if got := stats["get"]; got.Calls != 2 || got.NotFound != 1 || got.Errors != 0 {
+		t.Errorf("get stats = %+v", got)
+	}
+	if got := stats["set"]; got.Calls != 1 {
+		t.Errorf("set stats = %+v", got)
+	}
+
+	if err := b.(io.Closer).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !strings.Contains(out.String(), `backend "mem": get: 2 calls, 1 not found, 0 errors`) {
+		t.Errorf("unexpected summary: %q", out.String())
+	}
+}
+
+func TestCache(t *testing.T) {
+	inner := &countingMemoryBackend{memoryBackend: newMemoryBackend("mem")}
+	inner.secrets["a"] = "1"
+	b := Chain(inner, Cache(time.Minute))
+	now := time.Now()
+	b.(*cacheBackend).now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if v, err := b.Get("a"); err != nil || v != "1" {
+			t.Fatalf("Get = %q, %v", v, err)
+		}
+	}
+	if inner.gets != 1 {
+		t.Errorf("inner Get calls = %d, want 1", inner.gets)
+	}
+
+	// A write through the cache invalidates the key.
+	if err := b.Set("a", "2"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, _ := b.Get("a"); v != "2" {
+		t.Errorf("Get after Set = %q, want %q", v, "2")
+	}
+
+	// Expired entries are read again.
+	now = now.Add(2 * time.Minute)
+	_, _ = b.Get("a")
+	if inner.gets != 3 {
+		t.Errorf("inner Get calls = %d, want 3", inner.gets)
+	}
+
+	// Missing keys are not cached.
+	_, _ = b.Get("missing")
+	_, _ = b.Get("missing")
+	if inner.gets != 5 {
+		t.Errorf("inner Get calls = %d, want 5", inner.gets)
+	}
+}
+
+func TestCache_GetMany(t *testing.T) {
+	inner := &batchMemoryBackend{memoryBackend: newMemoryBackend("mem")}
+	inner.secrets["a"] = "1"
+	inner.secrets["b"] = "2"
+	b := Chain(inner, Cache(time.Minute))
+
+	if _, err := GetMany(b, []string{"a"}); err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	got, err := GetMany(b, []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if got["a"] != "1" || got["b"] != "2" {
+		t.Errorf("GetMany = %v", got)
+	}
+	if inner.batchCalls != 2 {
+		t.Errorf("inner GetMany calls = %d, want 2", inner.batchCalls)
+	}
+	if _, err := GetMany(b, []string{"a", "b"}); err != nil || inner.batchCalls != 2 {
+		t.Errorf("fully cached GetMany reached the backend (calls %d, err %v)", inner.batchCalls, err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	inner := newMemoryBackend("mem")
+	inner.secrets["a"] = "1"
+	b := Chain(inner, RateLimit(10))
+	var slept time.Duration
+	b.(*rateLimitBackend).sleep = func(d time.Duration) { slept += d }
+
+	for i := 0; i < 3; i++ {
+		_, _ = b.Get("a")
+	}
+	// The fake sleep does not advance the clock, so the second and third
+	// calls wait about 100ms and 200ms.
+	if slept < 250*time.Millisecond || slept > 350*time.Millisecond {
+		t.Errorf("total wait = %s, want about 300ms", slept)
+	}
+
+	// Without batch support, GetMany is limited per key.
+	slept = 0
+	_, _ = GetMany(b, []string{"a", "b", "c"})
+	if slept < 250*time.Millisecond {
+		t.Errorf("GetMany wait = %s, want about 300ms", slept)
+	}
+}
+
+// echoingBackend fails every Set with an error that includes the value, as
+// some CLIs do.
+type echoingBackend struct {
+	*memoryBackend
+}
+
+func (e *echoingBackend) Set(key, value string) error {
+	return NewKeyError(e.Name(), key, fmt.Errorf("rejected value %q", value))
+}
+
+func TestRedacting(t *testing.T) {
+	inner := newMemoryBackend("mem")
+	_ = inner.Set("token", "redacting-read-value")
+	var out bytes.Buffer
+	b := Chain(&echoingBackend{inner}, Redacting(), Logging(&out))
+
+	err := b.Set("api_key", "redacting-written-value")
+	if err == nil || strings.Contains(err.Error(), "redacting-written-value") {
+		t.Fatalf("Set error must not contain the value: %v", err)
+	}
+	var keyErr *KeyError
+	if !errors.As(err, &keyErr) {
+		t.Errorf("redacted error lost its type: %v", err)
+	}
+	if strings.Contains(out.String(), "redacting-written-value") {
+		t.Errorf("log must not contain the value: %q", out.String())
+	}
+
+	if _, err := b.Get("token"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := secret.Redact("token is redacting-read-value"); got != "token is "+secret.Mask {
+		t.Errorf("read value not tracked: %q", got)
+	}
+}
+
+func TestOnWrite(t *testing.T) {
+	var written []string
+	b := Chain(newMemoryBackend("mem"), OnWrite(func(key string) { written = append(written, key) }))
+
+	if err := b.Set("a", "1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, err := b.Get("a"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if err := b.Delete("a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	// Failed writes are not reported.
+	if err := b.Delete("a"); err == nil {
+		t.Fatal("expected Delete of a missing key to fail")
+	}
+
+	if strings.Join(written, ",") != "a,a" {
+		t.Errorf("written = %v, want [a a]", written)
+	}
+}
+
+// knownMap is a KnownValues kept in memory.
+type knownMap map[string]string
+
+func (k knownMap) Lookup(backend, key string) (string, time.Time, bool) {
+	value, ok := k[backend+"/"+key]
+	return value, time.Unix(0, 0), ok
+}
+
+func (k knownMap) Remember(backend, key, value string) { k[backend+"/"+key] = value }
+
+// flakyBackend is a memoryBackend whose reads fail while down is set.
+type flakyBackend struct {
+	*memoryBackend
+	down bool
+}
+
+func (f *flakyBackend) Get(key string) (string, error) {
+	if f.down {
+		return "", errors.New("connection refused")
+	}
+	return f.memoryBackend.Get(key)
+}
+
+func TestLastKnownGood(t *testing.T) {
+	inner := &flakyBackend{memoryBackend: newMemoryBackend("mem")}
+	inner.secrets["a"] = "1"
+	known := knownMap{}
+	var fallbacks []string
+	b := Chain(inner, LastKnownGood(known, false, func(name, key string, at time.Time) {
+		fallbacks = append(fallbacks, name+"/"+key)
+	}))
+
+	if v, err := b.Get("a"); err != nil || v != "1" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if known["mem/a"] != "1" {
+		t.Errorf("known = %v, want the value read", known)
+	}
+
+	// A failing backend serves the last value read.
+	inner.down = true
+	if v, err := b.Get("a"); err != nil || v != "1" {
+		t.Errorf("Get while down = %q, %v", v, err)
+	}
+	if len(fallbacks) != 1 || fallbacks[0] != "mem/a" {
+		t.Errorf("fallbacks = %v", fallbacks)
+	}
+	if _, err := b.Get("b"); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Get of an unknown key while down: err = %v", err)
+	}
+
+	// A deleted secret is not served from the known values.
+	inner.down = false
+	delete(inner.secrets, "a")
+	if _, err := b.Get("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a deleted key: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLastKnownGood_Offline(t *testing.T) {
+	inner := &countingMemoryBackend{memoryBackend: newMemoryBackend("mem")}
+	inner.secrets["a"] = "2"
+	b := Chain(inner, LastKnownGood(knownMap{"mem/a": "1"}, true, nil))
+
+	if v, err := b.Get("a"); err != nil || v != "1" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+	if _, err := b.Get("b"); !errors.Is(err, ErrOffline) {
+		t.Errorf("Get of an unknown key: err = %v, want ErrOffline", err)
+	}
+	if _, err := GetMany(b, []string{"a"}); !errors.Is(err, ErrOffline) {
+		t.Errorf("GetMany: err = %v, want ErrOffline", err)
+	}
+	if inner.gets != 0 {
+		t.Errorf("inner Get calls = %d, want 0", inner.gets)
+	}
+}
@@ -2,9 +2,22 @@ package backend
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
+// DefaultNamespace is the key template used when a backend does not
+// configure one. It stores keys as "<project>/<key>", or
+// "<project>/<profile>/<key>" for profile-scoped secrets.
+const DefaultNamespace = "{project}/{profile}/{key}"
+
+// namespacePlaceholder matches a {name} placeholder in a key template.
+var namespacePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// namespaceSeparators are the characters dropped next to {profile} when a
+// template is expanded without a profile.
+const namespaceSeparators = "/-_.:"
+
 // NamespacedBackend wraps a Backend and prefixes all keys with a project
 // namespace to avoid collisions when multiple projects share the same
 // backend (e.g., the OS keychain).
@@ -13,6 +26,10 @@ import (
 // For example, with project "myapp" and key "api_key", the underlying
 // backend stores "myapp/api_key".
 //
+// The layout can be changed with a key template (see
+// NewTemplateNamespacedBackend), e.g. "{project}-{key}" for backends whose
+// naming rules do not allow slashes.
+//
 // List() returns only keys belonging to this project's namespace,
 // with the prefix stripped.
 type NamespacedBackend struct {
@@ -20,6 +37,8 @@ type NamespacedBackend struct {
 	project string
 	profile string
 	prefix  string
+	suffix  string
+	access  AccessFunc // set by Registry.Namespaced; nil allows all
 }
 
 // NewNamespacedBackend creates a NamespacedBackend that wraps the given backend
@@ -27,17 +46,7 @@ type NamespacedBackend struct {
 //
 // The project name must not be empty.
 func NewNamespacedBackend(inner Backend, project string) (*NamespacedBackend, error) {
-	if project == "" {
-		return nil, fmt.Errorf("project name must not be empty")
-	}
-	if inner == nil {
-		return nil, fmt.Errorf("inner backend must not be nil")
-	}
-	return &NamespacedBackend{
-		inner:   inner,
-		project: project,
-		prefix:  project + "/",
-	}, nil
+	return NewTemplateNamespacedBackend(inner, DefaultNamespace, project, "")
 }
 
 // Name returns the name of the underlying backend.
@@ -52,17 +61,119 @@ func (n *NamespacedBackend) Project() string {
 
 // Get retrieves the secret value for the namespaced key.
 func (n *NamespacedBackend) Get(key string) (string, error) {
-	return n.inner.Get(n.prefix + key)
+	if err := n.allow(key); err != nil {
+		return "", err
+	}
+	return n.inner.Get(n.StorageKey(key))
+}
+
+// GetMany retrieves the values for the namespaced keys, in one call if the
+// underlying backend implements BatchGetter. Missing keys are omitted.
+func (n *NamespacedBackend) GetMany(keys []string) (map[string]string, error) {
+	storageKeys := make([]string, len(keys))
+	byStorageKey := make(map[string]string, len(keys))
+	for i, key := range keys {
+		if err := n.allow(key); err != nil {
+			return nil, err
+		}
+		storageKeys[i] = n.StorageKey(key)
+		byStorageKey[storageKeys[i]] = key
+	}
+	found, err := GetMany(n.inner, storageKeys)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(found))
+	for sk, value := range found {
+		values[byStorageKey[sk]] = value
+	}
+	return values, nil
+}
+
+// Batched reports whether the underlying backend implements BatchGetter,
+// so that GetMany costs a single call.
+func (n *NamespacedBackend) Batched() bool {
+	_, ok := n.inner.(BatchGetter)
+	return ok
+}
+
+// Metadata returns the metadata for the namespaced key, or
+// ErrMetadataUnsupported if the underlying backend does not record it.
+func (n *NamespacedBackend) Metadata(key string) (Metadata, error) {
+	return GetMetadata(n.inner, n.StorageKey(key))
+}
+
+// ListVersions returns the versions of the namespaced key, or
+// ErrVersioningUnsupported if the underlying backend does not keep them.
+func (n *NamespacedBackend) ListVersions(key string) ([]Version, error) {
+	vb, ok := n.inner.(VersionedBackend)
+	if !ok {
+		return nil, ErrVersioningUnsupported
+	}
+	return vb.ListVersions(n.StorageKey(key))
+}
+
+// GetVersion retrieves the namespaced key at the given version, or returns
+// ErrVersioningUnsupported if the underlying backend does not keep versions.
+func (n *NamespacedBackend) GetVersion(key string, version int) (string, error) {
+	if err := n.allow(key); err != nil {
+		return "", err
+	}
+	vb, ok := n.inner.(VersionedBackend)
+	if !ok {
+		return "", ErrVersioningUnsupported
+	}
+	return vb.GetVersion(n.StorageKey(key), version)
+}
+
+// Rollback makes the given version of the namespaced key current, or
+// returns ErrVersioningUnsupported if the underlying backend does not keep
+// versions.
+func (n *NamespacedBackend) Rollback(key string, version int) error {
+	if err := n.allow(key); err != nil {
+		return err
+	}
+	vb, ok := n.inner.(VersionedBackend)
+	if !ok {
+		return ErrVersioningUnsupported
+	}
+	return vb.Rollback(n.StorageKey(key), version)
+}
+
+// Ping checks that the underlying backend is reachable.
+func (n *NamespacedBackend) Ping() error {
+	return Ping(n.inner)
 }
 
 // Set stores a secret value under the namespaced key.
 func (n *NamespacedBackend) Set(key, value string) error {
-	return n.inner.Set(n.prefix+key, value)
+	if err := n.allow(key); err != nil {
+		return err
+	}
+	return n.inner.Set(n.StorageKey(key), value)
 }
 
 // Delete removes the secret for the namespaced key.
 func (n *NamespacedBackend) Delete(key string) error {
-	return n.inner.Delete(n.prefix + key)
+	if err := n.allow(key); err != nil {
+		return err
+	}
+	return n.inner.Delete(n.StorageKey(key))
+}
+
+// allow checks the access rule of the registry the backend came from, if
+// any, for key.
+func (n *NamespacedBackend) allow(key string) error {
+	if n.access == nil {
+		return nil
+	}
+	return n.access(n.inner, n.profile, key)
+}
+
+// StorageKey returns the name under which key is stored in the underlying
+// backend.
+func (n *NamespacedBackend) StorageKey(key string) string {
+	return n.prefix + key + n.suffix
 }
 
 // Profile returns the profile scope, if any. Returns empty string for
@@ -81,8 +192,8 @@ func (n *NamespacedBackend) List() ([]string, error) {
 
 	var keys []string
 	for _, k := range allKeys {
-		if strings.HasPrefix(k, n.prefix) {
-			keys = append(keys, k[len(n.prefix):])
+		if len(k) > len(n.prefix)+len(n.suffix) && strings.HasPrefix(k, n.prefix) && strings.HasSuffix(k, n.suffix) {
+			keys = append(keys, k[len(n.prefix):len(k)-len(n.suffix)])
 		}
 	}
 	return keys, nil
@@ -94,19 +205,86 @@ func (n *NamespacedBackend) List() ([]string, error) {
 //
 // Both project and profile must be non-empty.
 func NewProfileNamespacedBackend(inner Backend, project, profile string) (*NamespacedBackend, error) {
-	if project == "" {
-		return nil, fmt.Errorf("project name must not be empty")
-	}
 	if profile == "" {
 		return nil, fmt.Errorf("profile name must not be empty")
 	}
+	return NewTemplateNamespacedBackend(inner, DefaultNamespace, project, profile)
+}
+
+// NewTemplateNamespacedBackend creates a NamespacedBackend whose keys are
+// laid out by template. The template may use the placeholders {project},
+// {profile}, and {key}; {key} must appear exactly once. An empty template
+// means DefaultNamespace.
+//
+// When profile is empty, {profile} is removed together with one adjacent
+// separator, so "{project}/{profile}/{key}" stores project-scoped keys as
+// "<project>/<key>". A template without {profile} stores profile-scoped and
+// project-scoped secrets under the same names.
+func NewTemplateNamespacedBackend(inner Backend, template, project, profile string) (*NamespacedBackend, error) {
+	if project == "" {
+		return nil, fmt.Errorf("project name must not be empty")
+	}
 	if inner == nil {
 		return nil, fmt.Errorf("inner backend must not be nil")
 	}
+	if strings.Contains(profile, ",") {
+		return nil, fmt.Errorf("profile %q combines several profiles; secrets are scoped to a single profile", profile)
+	}
+	if template == "" {
+		template = DefaultNamespace
+	}
+	if err := ValidateNamespace(template); err != nil {
+		return nil, err
+	}
+
+	expanded := template
+	if profile == "" {
+		expanded = dropProfile(expanded)
+	}
+	expanded = strings.NewReplacer("{project}", project, "{profile}", profile).Replace(expanded)
+	prefix, suffix, _ := strings.Cut(expanded, "{key}")
+
 	return &NamespacedBackend{
 		inner:   inner,
 		project: project,
 		profile: profile,
-		prefix:  project + "/" + profile + "/",
+		prefix:  prefix,
+		suffix:  suffix,
 	}, nil
 }
+
+// ValidateNamespace checks that template is a usable key template: it may
+// only use the {project}, {profile}, and {key} placeholders, and must
+// contain {key} exactly once.
+func ValidateNamespace(template string) error {
+	for _, p := range namespacePlaceholder.FindAllString(template, -1) {
+		switch p {
+		case "{project}", "{profile}", "{key}":
+		default:
+			return fmt.Errorf("namespace %q: unknown placeholder %s (use {project}, {profile}, or {key})", template, p)
+		}
+	}
+	if n := strings.Count(template, "{key}"); n != 1 {
+		return fmt.Errorf("namespace %q: must contain {key} exactly once", template)
+	}
+	return nil
+}
+
+// dropProfile removes each {profile} placeholder from template along with
+// the separator that follows it, or the one before it if none follows.
+func dropProfile(template string) string {
+	for {
+		i := strings.Index(template, "{profile}")
+		if i < 0 {
+			return template
+		}
+		end := i + len("{profile}")
+		switch {
+		case end < len(template) && strings.IndexByte(namespaceSeparators, template[end]) >= 0:
+			end++
+		case i > 0 && strings.IndexByte(namespaceSeparators, template[i-1]) >= 0:
+			i--
+		}
+		template = template[:i] + template[end:]
+	}
+}
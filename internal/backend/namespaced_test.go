@@ -2,6 +2,7 @@ package backend
 
 import (
 	"errors"
+	"strings"
 	"testing"
 )
 
@@ -454,3 +455,104 @@ func TestNamespacedBackend_RegistryIntegration(t *testing.T) {
 		t.Fatalf("Registry.Get with keychain: got %q, want %q", val, "keychain_secret")
 	}
 }
+
+func TestNewTemplateNamespacedBackend_Layouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		profile  string
+		want     string
+	}{
+		{"default project", "", "", "myapp/api_key"},
+		{"default profile", "", "staging", "myapp/staging/api_key"},
+		{"flat", "{project}-{key}", "", "myapp-api_key"},
+		{"flat ignores profile", "{project}-{key}", "staging", "myapp-api_key"},
+		{"ssm path", "/{project}/{profile}/{key}", "staging", "/myapp/staging/api_key"},
+		{"ssm path without profile", "/{project}/{profile}/{key}", "", "/myapp/api_key"},
+		{"profile last", "{project}.{key}.{profile}", "", "myapp.api_key"},
+		{"suffix", "{key}@{project}", "", "api_key@myapp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newMemoryBackend("ssm")
+			nb, err := NewTemplateNamespacedBackend(inner, tt.template, "myapp", tt.profile)
+			if err != nil {
+				t.Fatalf("NewTemplateNamespacedBackend: %v", err)
+			}
+			if err := nb.Set("api_key", "v"); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			if _, err := inner.Get(tt.want); err != nil {
+				t.Fatalf("inner key %q not found: %v", tt.want, err)
+			}
+
+			keys, err := nb.List()
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(keys) != 1 || keys[0] != "api_key" {
+				t.Fatalf("List: got %v, want [api_key]", keys)
+			}
+		})
+	}
+}
+
+func TestNewTemplateNamespacedBackend_ListFiltersSuffix(t *testing.T) {
+	inner := newMemoryBackend("gcp")
+	_ = inner.Set("api_key@myapp", "1")
+	_ = inner.Set("api_key@other", "2")
+	_ = inner.Set("db_pass@myapp", "3")
+
+	nb, err := NewTemplateNamespacedBackend(inner, "{key}@{project}", "myapp", "")
+	if err != nil {
+		t.Fatalf("NewTemplateNamespacedBackend: %v", err)
+	}
+	keys, err := nb.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("List: got %v, want 2 keys", keys)
+	}
+	for _, k := range keys {
+		if k != "api_key" && k != "db_pass" {
+			t.Fatalf("List: unexpected key %q", k)
+		}
+	}
+}
+
+func TestValidateNamespace(t *testing.T) {
+	valid := []string{DefaultNamespace, "{project}-{key}", "{key}", "prefix/{project}/{key}"}
+	for _, tmpl := range valid {
+		if err := ValidateNamespace(tmpl); err != nil {
+			t.Errorf("ValidateNamespace(%q): unexpected error: %v", tmpl, err)
+		}
+	}
+
+	invalid := []string{"{project}", "{project}/{key}/{key}", "{project}/{env}/{key}", "{Project}/{key}"}
+	for _, tmpl := range invalid {
+		if err := ValidateNamespace(tmpl); err == nil {
+			t.Errorf("ValidateNamespace(%q): expected error", tmpl)
+		}
+	}
+}
+
+func TestNamespacedBackend_VersioningUnsupported(t *testing.T) {
+	ns, err := NewNamespacedBackend(newMemoryBackend("mem"), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ns.ListVersions("key"); !errors.Is(err, ErrVersioningUnsupported) {
+		t.Errorf("ListVersions: got %v, want ErrVersioningUnsupported", err)
+	}
+	if err := ns.Rollback("key", 1); !errors.Is(err, ErrVersioningUnsupported) {
+		t.Errorf("Rollback: got %v, want ErrVersioningUnsupported", err)
+	}
+}
+
+func TestNewTemplateNamespacedBackend_CombinedProfile(t *testing.T) {
+	_, err := NewTemplateNamespacedBackend(newMemoryBackend("mem"), "", "myapp", "production,eu")
+	if err == nil || !strings.Contains(err.Error(), "combines several profiles") {
+		t.Fatalf("expected combined profile error, got %v", err)
+	}
+}
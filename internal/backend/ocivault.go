@@ -40,6 +40,8 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/xcke/envref/internal/secret"
 )
 
 // Default timeout for OCI CLI operations.
@@ -138,6 +140,7 @@ func (b *OCIVaultBackend) Get(key string) (string, error) {
 	if err != nil {
 		return "", NewKeyError(b.Name(), key, fmt.Errorf("oci get secret bundle: %w", err))
 	}
+	defer secret.ClearBytes(stdout)
 
 	var bundle ociSecretBundle
 	if err := json.Unmarshal(stdout, &bundle); err != nil {
@@ -150,14 +153,16 @@ func (b *OCIVaultBackend) Get(key string) (string, error) {
 		return "", NewKeyError(b.Name(), key, fmt.Errorf("decode secret content: %w", err))
 	}
 
-	return string(decoded), nil
+	return secret.TakeString(decoded), nil
 }
 
 // Set stores a secret value under the given key in OCI Vault.
 // If a secret with that name already exists, a new version is created.
 // Otherwise, a new secret is created.
 func (b *OCIVaultBackend) Set(key, value string) error {
-	encoded := base64.StdEncoding.EncodeToString([]byte(value))
+	raw := []byte(value)
+	encoded := base64.StdEncoding.EncodeToString(raw)
+	secret.ClearBytes(raw)
 
 	// Check if the secret already exists.
 	secretID, err := b.findSecretID(key)
@@ -221,6 +226,22 @@ func (b *OCIVaultBackend) Delete(key string) error {
 	return nil
 }
 
+// Ping checks that the OCI CLI is authenticated and can read the
+// configured vault.
+func (b *OCIVaultBackend) Ping() error {
+	args := []string{
+		"kms", "management", "vault", "get",
+		"--vault-id", b.vaultID,
+		"--output", "json",
+	}
+	args = b.appendGlobalFlags(args)
+
+	if _, err := b.run(args); err != nil {
+		return fmt.Errorf("oci-vault ping: %w", err)
+	}
+	return nil
+}
+
 // List returns all active secret keys in the configured vault and compartment.
 func (b *OCIVaultBackend) List() ([]string, error) {
 	args := []string{
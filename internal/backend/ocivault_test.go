@@ -224,3 +224,16 @@ func TestIsOCINotFoundErr(t *testing.T) {
 		}
 	}
 }
+
+func TestOCIVaultBackend_Ping(t *testing.T) {
+	ociPath := buildOCIMock(t)
+	b := NewOCIVaultBackend("vault-ocid", "compartment-ocid", "key-ocid", WithOCIVaultCommand(ociPath))
+	if err := b.Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	b = NewOCIVaultBackend("vault-ocid", "compartment-ocid", "key-ocid", WithOCIVaultCommand("/nonexistent/oci"))
+	if err := b.Ping(); err == nil {
+		t.Fatal("Ping with invalid command: expected error, got nil")
+	}
+}
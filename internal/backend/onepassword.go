@@ -18,6 +18,17 @@
 //	    config:
 //	      vault: Personal          # 1Password vault name (default: "Personal")
 //	      account: my.1password.com # optional: account shorthand or URL
+//	      session: process          # optional: sign in once per invocation ("process" or "keychain")
+//
+// # Sessions
+//
+// Without the desktop app integration, every op command needs a session
+// token. With the "session" option, the backend runs `op signin --raw` the
+// first time it needs the CLI and passes the token to every later command,
+// so resolving many keys prompts for the account password once. With
+// "keychain", the token is also stored in the OS keychain and reused by
+// later invocations until it expires (after "session_ttl", default 30m). A
+// rejected token is discarded and the backend signs in again.
 //
 // # How secrets are stored
 //
@@ -33,9 +44,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/xcke/envref/internal/secret"
 )
 
 // Default timeout for 1Password CLI operations.
@@ -52,6 +68,10 @@ type OnePasswordBackend struct {
 	account string // optional account shorthand or URL
 	command string // path to the op CLI executable
 	timeout time.Duration
+
+	sessionMode string        // "", SessionCacheProcess, or SessionCacheKeychain
+	sessionTTL  time.Duration // lifetime of a keychain-cached session
+	session     *session      // nil unless sessionMode is set
 }
 
 // OnePasswordOption configures optional settings for OnePasswordBackend.
@@ -71,6 +91,17 @@ func WithOnePasswordCommand(command string) OnePasswordOption {
 	}
 }
 
+// WithOnePasswordSession signs in with `op signin` once and reuses the
+// session token for later commands. mode is SessionCacheProcess or
+// SessionCacheKeychain; ttl bounds how long a keychain-cached token is
+// reused (zero means DefaultSessionTTL).
+func WithOnePasswordSession(mode string, ttl time.Duration) OnePasswordOption {
+	return func(o *OnePasswordBackend) {
+		o.sessionMode = mode
+		o.sessionTTL = ttl
+	}
+}
+
 // NewOnePasswordBackend creates a new OnePasswordBackend that delegates to
 // the `op` CLI. The vault parameter specifies which 1Password vault to use.
 func NewOnePasswordBackend(vault string, opts ...OnePasswordOption) *OnePasswordBackend {
@@ -82,6 +113,9 @@ func NewOnePasswordBackend(vault string, opts ...OnePasswordOption) *OnePassword
 	for _, opt := range opts {
 		opt(b)
 	}
+	if b.sessionMode != "" {
+		b.session = newSession(b.sessionMode, "session:1password:"+b.account, b.sessionTTL, b.signin)
+	}
 	return b
 }
 
@@ -94,9 +128,12 @@ func (o *OnePasswordBackend) Name() string {
 // opItem represents the relevant fields of a 1Password item returned by
 // `op item get --format json`.
 type opItem struct {
-	ID    string    `json:"id"`
-	Title string    `json:"title"`
-	Fields []opField `json:"fields,omitempty"`
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Fields       []opField `json:"fields,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	LastEditedBy string    `json:"last_edited_by"`
 }
 
 // opField represents a field within a 1Password item.
@@ -126,6 +163,7 @@ func (o *OnePasswordBackend) Get(key string) (string, error) {
 		}
 		return "", NewKeyError(o.Name(), key, fmt.Errorf("op get: %w", err))
 	}
+	defer secret.ClearBytes(stdout)
 
 	var item opItem
 	if err := json.Unmarshal(stdout, &item); err != nil {
@@ -142,6 +180,28 @@ func (o *OnePasswordBackend) Get(key string) (string, error) {
 	return "", NewKeyError(o.Name(), key, fmt.Errorf("item has no notesPlain field"))
 }
 
+// Metadata returns when the item for key was created and last edited, and
+// the ID of the 1Password user who last edited it.
+// Returns ErrNotFound if no item with that title exists in the vault.
+func (o *OnePasswordBackend) Metadata(key string) (Metadata, error) {
+	args := []string{"item", "get", key, "--vault", o.vault, "--format", "json"}
+	args = o.appendAccountFlag(args)
+
+	stdout, err := o.run(args)
+	if err != nil {
+		if isOpNotFoundErr(err) {
+			return Metadata{}, ErrNotFound
+		}
+		return Metadata{}, NewKeyError(o.Name(), key, fmt.Errorf("op get: %w", err))
+	}
+
+	var item opItem
+	if err := json.Unmarshal(stdout, &item); err != nil {
+		return Metadata{}, NewKeyError(o.Name(), key, fmt.Errorf("parse response: %w", err))
+	}
+	return Metadata{Created: item.CreatedAt, Updated: item.UpdatedAt, UpdatedBy: item.LastEditedBy}, nil
+}
+
 // Set stores a secret value under the given key in 1Password.
 // If an item with that title already exists, it is updated. Otherwise,
 // a new Secure Note item is created.
@@ -195,6 +255,41 @@ func (o *OnePasswordBackend) Delete(key string) error {
 	return nil
 }
 
+// Ping checks that the op CLI is signed in and can access the configured
+// vault.
+func (o *OnePasswordBackend) Ping() error {
+	args := []string{"vault", "get", o.vault, "--format", "json"}
+	args = o.appendAccountFlag(args)
+
+	if _, err := o.run(args); err != nil {
+		return fmt.Errorf("1password ping: %w", err)
+	}
+	return nil
+}
+
+// opWhoami represents the relevant fields from `op whoami --format json`.
+type opWhoami struct {
+	URL   string `json:"url"`
+	Email string `json:"email"`
+}
+
+// Identity returns the 1Password user the op CLI is signed in as, named by
+// their email address, with the account's sign-in address.
+func (o *OnePasswordBackend) Identity() (Identity, error) {
+	args := o.appendAccountFlag([]string{"whoami", "--format", "json"})
+	stdout, err := o.run(args)
+	if err != nil {
+		return Identity{}, fmt.Errorf("op whoami: %w", err)
+	}
+	var result opWhoami
+	if err := json.Unmarshal(stdout, &result); err != nil {
+		return Identity{}, fmt.Errorf("op whoami: parse response: %w", err)
+	}
+	return Identity{Name: result.Email, Details: map[string]string{
+		"account": result.URL,
+	}}, nil
+}
+
 // List returns all secret keys (item titles) in the configured vault.
 func (o *OnePasswordBackend) List() ([]string, error) {
 	args := []string{
@@ -237,14 +332,58 @@ func (o *OnePasswordBackend) appendAccountFlag(args []string) []string {
 }
 
 // run executes the op CLI with the given arguments and returns stdout.
-// It handles timeouts and maps common error patterns.
+// With a session configured, the session token is passed with --session,
+// and a command rejected because the session expired is retried once after
+// signing in again.
 func (o *OnePasswordBackend) run(args []string) ([]byte, error) {
+	if o.session == nil {
+		return o.runOp(args, false)
+	}
+
+	token, fresh, err := o.session.get()
+	if err != nil {
+		return nil, fmt.Errorf("op signin: %w", err)
+	}
+	stdout, err := o.runOp(append(slices.Clip(args), "--session", token), false)
+	if err == nil || fresh || !isOpSessionErr(err) {
+		return stdout, err
+	}
+
+	o.session.invalidate(token)
+	if token, _, err = o.session.get(); err != nil {
+		return nil, fmt.Errorf("op signin: %w", err)
+	}
+	return o.runOp(append(slices.Clip(args), "--session", token), false)
+}
+
+// signin runs `op signin --raw` and returns the session token. The user
+// may be prompted for the account password on the terminal.
+func (o *OnePasswordBackend) signin() (string, time.Duration, error) {
+	args := o.appendAccountFlag([]string{"signin", "--raw"})
+	stdout, err := o.runOp(args, true)
+	if err != nil {
+		return "", 0, err
+	}
+	return strings.TrimSpace(secret.TakeString(stdout)), 0, nil
+}
+
+// runOp executes the op CLI once. It handles timeouts and maps common error
+// patterns. An interactive command reads the terminal and shows its prompts,
+// and gets sessionLoginTimeout instead of the per-operation timeout.
+func (o *OnePasswordBackend) runOp(args []string, interactive bool) ([]byte, error) {
 	cmd := exec.Command(o.command, args...) //nolint:gosec // Command path comes from trusted config or default "op"
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
+	timeout := o.timeout
+	if interactive {
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+		timeout = sessionLoginTimeout
+	}
+
 	done := make(chan error, 1)
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("start op: %w", err)
@@ -263,14 +402,24 @@ func (o *OnePasswordBackend) run(args []string) ([]byte, error) {
 			}
 			return nil, err
 		}
-	case <-time.After(o.timeout):
+	case <-time.After(timeout):
 		_ = cmd.Process.Kill()
-		return nil, fmt.Errorf("op timed out after %s", o.timeout)
+		return nil, fmt.Errorf("op timed out after %s", timeout)
 	}
 
 	return stdout.Bytes(), nil
 }
 
+// isOpSessionErr checks whether an error from the op CLI indicates that the
+// session token expired or was revoked.
+func isOpSessionErr(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "not currently signed in") ||
+		strings.Contains(msg, "session expired") ||
+		strings.Contains(msg, "invalid session") ||
+		strings.Contains(msg, "authentication required")
+}
+
 // isOpNotFoundErr checks whether an error from the op CLI indicates that
 // the requested item was not found. The op CLI v2 prints "[ERROR] ..."
 // messages to stderr with patterns like "isn't an item" or "not found".
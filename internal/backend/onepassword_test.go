@@ -6,7 +6,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 // buildOpMock compiles the mock op CLI helper into a temporary directory
@@ -197,3 +199,113 @@ func TestIsOpNotFoundErr(t *testing.T) {
 		}
 	}
 }
+
+func TestOnePasswordBackend_Ping(t *testing.T) {
+	opPath := buildOpMock(t)
+	if err := NewOnePasswordBackend("TestVault", WithOnePasswordCommand(opPath)).Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	err := NewOnePasswordBackend("TestVault", WithOnePasswordCommand("/nonexistent/op")).Ping()
+	if err == nil {
+		t.Fatal("Ping with invalid command: expected error, got nil")
+	}
+}
+
+func TestOnePasswordBackend_Identity(t *testing.T) {
+	opPath := buildOpMock(t)
+	id, err := NewOnePasswordBackend("TestVault", WithOnePasswordCommand(opPath)).Identity()
+	if err != nil {
+		t.Fatalf("Identity: %v", err)
+	}
+	if id.Name != "alice@example.com" {
+		t.Errorf("Identity name: got %q", id.Name)
+	}
+	if id.Details["account"] != "my.1password.com" {
+		t.Errorf("Identity account: got %q", id.Details["account"])
+	}
+
+	if _, err := GetIdentity(NewMemoryBackend("mem")); !errors.Is(err, ErrIdentityUnsupported) {
+		t.Errorf("GetIdentity of a memory backend: got %v, want ErrIdentityUnsupported", err)
+	}
+}
+
+func TestOnePasswordBackend_Metadata(t *testing.T) {
+	opPath := buildOpMock(t)
+	b := NewOnePasswordBackend("TestVault", WithOnePasswordCommand(opPath))
+	if err := b.Set("key", "v"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	md, err := b.Metadata("key")
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if !md.Updated.Equal(time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)) || md.UpdatedBy != "MOCKUSERID" {
+		t.Errorf("Metadata = %+v", md)
+	}
+
+	if _, err := b.Metadata("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Metadata(missing): got %v, want ErrNotFound", err)
+	}
+}
+
+// mockLog returns the lines of a log written by a mock CLI built into the
+// same directory as command.
+func mockLog(t *testing.T, command, name string) []string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(command), name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("reading %s: %v", name, err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestOnePasswordBackend_SessionReuse(t *testing.T) {
+	opPath := buildOpMock(t)
+	b := NewOnePasswordBackend("TestVault", WithOnePasswordCommand(opPath), WithOnePasswordSession(SessionCacheProcess, 0))
+
+	for _, k := range []string{"a", "b", "c"} {
+		if err := b.Set(k, "value-"+k); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if got, err := b.Get(k); err != nil || got != "value-"+k {
+			t.Fatalf("Get: got %q, %v", got, err)
+		}
+	}
+	if signins := mockLog(t, opPath, "signins.log"); len(signins) != 1 {
+		t.Fatalf("signins: got %d, want 1", len(signins))
+	}
+
+	// A revoked session is replaced by signing in again.
+	if err := os.WriteFile(filepath.Join(filepath.Dir(opPath), "session"), []byte("revoked"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := b.Get("a"); err != nil || got != "value-a" {
+		t.Fatalf("Get after revocation: got %q, %v", got, err)
+	}
+	if signins := mockLog(t, opPath, "signins.log"); len(signins) != 2 {
+		t.Fatalf("signins after revocation: got %d, want 2", len(signins))
+	}
+}
+
+func TestOnePasswordBackend_SessionKeychain(t *testing.T) {
+	defer setupMockKeyring()()
+	opPath := buildOpMock(t)
+
+	for i := 0; i < 3; i++ {
+		b := NewOnePasswordBackend("TestVault", WithOnePasswordCommand(opPath), WithOnePasswordSession(SessionCacheKeychain, 0))
+		if err := b.Ping(); err != nil {
+			t.Fatalf("Ping: %v", err)
+		}
+	}
+	if signins := mockLog(t, opPath, "signins.log"); len(signins) != 1 {
+		t.Fatalf("signins across backends: got %d, want 1", len(signins))
+	}
+	if _, err := KeychainItem("session:1password:"); err != nil {
+		t.Errorf("session not stored in keychain: %v", err)
+	}
+}
@@ -14,7 +14,7 @@
 // # Request Format
 //
 //	{
-//	  "operation": "get" | "set" | "delete" | "list",
+//	  "operation": "get" | "set" | "delete" | "list" | "ping",
 //	  "key": "secret-key",         // present for get, set, delete
 //	  "value": "secret-value"      // present for set only
 //	}
@@ -29,7 +29,8 @@
 //
 // If the response contains a non-empty "error" field, the operation is
 // considered failed. For "get", a response error of "not found" is mapped
-// to ErrNotFound.
+// to ErrNotFound. A "ping" request should succeed with an empty response
+// once the plugin can reach its store.
 //
 // # Plugin Discovery
 //
@@ -45,50 +46,131 @@
 //
 // If no "command" config is specified, the plugin is discovered by searching
 // $PATH for "envref-backend-<name>".
+//
+// # Protocol Version 2
+//
+// With the backend config option protocol: "2", envref starts the plugin
+// once, with the argument "serve", and keeps it running until the command
+// finishes. Requests and responses are newline-delimited JSON objects. The
+// first request is a handshake listing the protocol versions envref speaks:
+//
+//	{"operation": "handshake", "versions": [2]}
+//
+// The plugin replies with the version it chose and the optional operations
+// it supports:
+//
+//	{"version": 2, "capabilities": ["get_many", "ping"]}
+//
+// Every later request carries an "id" that the plugin copies into its
+// response. Besides the version 1 operations, a plugin with the "get_many"
+// capability accepts
+//
+//	{"id": 3, "operation": "get_many", "keys": ["a", "b"]}
+//
+// and replies with the keys it found:
+//
+//	{"id": 3, "values": {"a": "1"}}
+//
+// The plugin should exit when stdin is closed.
+//
+// # Protocol Version 3
+//
+// With protocol: "3", the plugin is a long-lived process serving the gRPC
+// service in pkg/plugin/pluginpb/backend.proto with
+// github.com/hashicorp/go-plugin, which negotiates the protocol version in
+// its handshake and reports a plugin that crashes; envref then starts it
+// again on the next operation. A secret that does not exist is reported
+// with the NotFound status code, and an operation the plugin does not
+// offer with Unimplemented. Go plugins implement plugin.Backend and call
+// plugin.Serve from package github.com/xcke/envref/pkg/plugin.
 package backend
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os/exec"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/xcke/envref/internal/secret"
+	"github.com/xcke/envref/pkg/plugin"
 )
 
 // Default timeout for plugin operations.
 const pluginTimeout = 30 * time.Second
 
+// pluginHandshakeTimeout bounds the version 2 handshake, so that a plugin
+// that only speaks version 1 (and waits for stdin to close) fails fast.
+const pluginHandshakeTimeout = 5 * time.Second
+
+// PluginProtocolVersions lists the plugin protocol versions envref speaks.
+var PluginProtocolVersions = []int{1, 2, 3}
+
 // pluginRequest is the JSON request sent to a plugin's stdin.
 type pluginRequest struct {
-	Operation string `json:"operation"`
-	Key       string `json:"key,omitempty"`
-	Value     string `json:"value,omitempty"`
+	ID        int      `json:"id,omitempty"`
+	Operation string   `json:"operation"`
+	Key       string   `json:"key,omitempty"`
+	Value     string   `json:"value,omitempty"`
+	Keys      []string `json:"keys,omitempty"`
+	Versions  []int    `json:"versions,omitempty"`
 }
 
 // pluginResponse is the JSON response read from a plugin's stdout.
 type pluginResponse struct {
-	Value string   `json:"value,omitempty"`
-	Keys  []string `json:"keys,omitempty"`
-	Error string   `json:"error,omitempty"`
+	ID           int               `json:"id,omitempty"`
+	Value        string            `json:"value,omitempty"`
+	Keys         []string          `json:"keys,omitempty"`
+	Values       map[string]string `json:"values,omitempty"`
+	Version      int               `json:"version,omitempty"`
+	Capabilities []string          `json:"capabilities,omitempty"`
+	Error        string            `json:"error,omitempty"`
 }
 
 // PluginBackend delegates secret operations to an external executable.
 type PluginBackend struct {
-	name    string        // Backend name (used in ref:// URIs and config)
-	command string        // Path to the plugin executable
-	timeout time.Duration // Per-operation timeout
+	name     string        // Backend name (used in ref:// URIs and config)
+	command  string        // Path to the plugin executable
+	timeout  time.Duration // Per-operation timeout
+	protocol int           // Protocol version (1: one process per call)
+
+	mu      sync.Mutex
+	session *pluginSession // Running version 2 plugin, started on first use
+	grpc    *grpcPlugin    // Running version 3 plugin, started on first use
+}
+
+// PluginOption configures a PluginBackend.
+type PluginOption func(*PluginBackend)
+
+// WithPluginProtocol sets the protocol version used to talk to the plugin.
+// Version 1 (the default) starts the plugin for every operation; version 2
+// keeps one plugin process running, and version 3 serves gRPC with
+// go-plugin (see the package documentation).
+func WithPluginProtocol(version int) PluginOption {
+	return func(p *PluginBackend) {
+		p.protocol = version
+	}
 }
 
 // NewPluginBackend creates a new PluginBackend that delegates to the given
 // executable. The name is the backend identifier used in config and ref:// URIs.
-func NewPluginBackend(name, command string) *PluginBackend {
-	return &PluginBackend{
-		name:    name,
-		command: command,
-		timeout: pluginTimeout,
+func NewPluginBackend(name, command string, opts ...PluginOption) *PluginBackend {
+	p := &PluginBackend{
+		name:     name,
+		command:  command,
+		timeout:  pluginTimeout,
+		protocol: 1,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	return p
 }
 
 // Name returns the backend identifier.
@@ -129,13 +211,94 @@ func (p *PluginBackend) List() ([]string, error) {
 	return resp.Keys, nil
 }
 
-// execute runs the plugin executable with the given request and returns the
-// parsed response. It handles timeouts, exit codes, and error mapping.
+// GetMany retrieves many secrets in one request from a version 2 plugin
+// with the "get_many" capability or a version 3 plugin that implements it,
+// and with one get per key otherwise.
+func (p *PluginBackend) GetMany(keys []string) (map[string]string, error) {
+	if !p.hasCapability("get_many") {
+		return getEach(p, keys)
+	}
+	resp, err := p.execute(pluginRequest{Operation: "get_many", Keys: keys})
+	if errors.Is(err, plugin.ErrUnimplemented) {
+		return getEach(p, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Values == nil {
+		return map[string]string{}, nil
+	}
+	return resp.Values, nil
+}
+
+// Ping sends a "ping" request to the plugin. Plugins that predate the
+// operation reply with an unknown-operation error, which still shows the
+// executable runs and speaks the protocol, so it is not treated as a failure.
+// A version 2 plugin without the "ping" capability is reachable once the
+// handshake succeeds.
+func (p *PluginBackend) Ping() error {
+	if p.protocol >= 2 && !p.hasCapability("ping") {
+		_, err := p.startedSession()
+		return err
+	}
+	_, err := p.execute(pluginRequest{Operation: "ping"})
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "unknown operation") {
+		return nil
+	}
+	return err
+}
+
+// Close stops a running version 2 or 3 plugin. It is a no-op for version 1.
+func (p *PluginBackend) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.grpc != nil {
+		p.grpc.process.Kill()
+		p.grpc = nil
+	}
+	if p.session == nil {
+		return nil
+	}
+	err := p.session.close()
+	p.session = nil
+	return err
+}
+
+// execute sends req to the plugin and returns the parsed response, using
+// the configured protocol version.
 func (p *PluginBackend) execute(req pluginRequest) (*pluginResponse, error) {
+	if p.protocol < 2 {
+		return p.executeOnce(req)
+	}
+	if p.protocol >= 3 {
+		return p.executeGRPC(req)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, err := p.sessionLocked()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.call(req, p.timeout)
+	if err != nil {
+		// The plugin is in an unknown state; start a fresh one next time.
+		_ = s.kill()
+		p.session = nil
+		return nil, fmt.Errorf("plugin %q: %w", p.name, err)
+	}
+	return p.checkResponse(resp)
+}
+
+// executeOnce runs the plugin executable with the given request and returns
+// the parsed response (protocol version 1). It handles timeouts, exit codes,
+// and error mapping.
+func (p *PluginBackend) executeOnce(req pluginRequest) (*pluginResponse, error) {
 	reqBytes, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("plugin %q: marshal request: %w", p.name, err)
 	}
+	defer secret.ClearBytes(reqBytes)
 
 	cmd := exec.Command(p.command, "serve") //nolint:gosec // Plugin path comes from trusted config
 	cmd.Stdin = bytes.NewReader(reqBytes)
@@ -171,20 +334,218 @@ func (p *PluginBackend) execute(req pluginRequest) (*pluginResponse, error) {
 	}
 
 	// Parse response.
+	defer secret.ClearBytes(stdout.Bytes())
 	var resp pluginResponse
 	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
 		return nil, fmt.Errorf("plugin %q: invalid JSON response: %w", p.name, err)
 	}
+	return p.checkResponse(&resp)
+}
 
-	// Map plugin errors.
+// checkResponse maps an error reported in resp to a Go error.
+func (p *PluginBackend) checkResponse(resp *pluginResponse) (*pluginResponse, error) {
 	if resp.Error != "" {
 		if isNotFoundError(resp.Error) {
 			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("plugin %q: %s", p.name, resp.Error)
 	}
+	return resp, nil
+}
+
+// hasCapability reports whether the plugin speaks protocol version 2 and
+// announced the optional operation op in its handshake. It starts the
+// plugin if needed; a plugin that fails to start has no capabilities. A
+// version 3 plugin reports unimplemented operations per call instead.
+func (p *PluginBackend) hasCapability(op string) bool {
+	if p.protocol < 2 {
+		return false
+	}
+	if p.protocol >= 3 {
+		return true
+	}
+	s, err := p.startedSession()
+	return err == nil && s.capabilities[op]
+}
+
+// startedSession returns the running version 2 plugin, starting it first
+// if needed.
+func (p *PluginBackend) startedSession() (*pluginSession, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sessionLocked()
+}
+
+// sessionLocked is startedSession for callers holding p.mu.
+func (p *PluginBackend) sessionLocked() (*pluginSession, error) {
+	if p.session != nil {
+		return p.session, nil
+	}
+	s, err := startPluginSession(p.command, p.protocol)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %q: %w", p.name, err)
+	}
+	p.session = s
+	return s, nil
+}
+
+// pluginSession is a running plugin that speaks protocol version 2.
+type pluginSession struct {
+	cmd          *exec.Cmd
+	stdin        io.WriteCloser
+	lines        chan pluginLine
+	stderr       *bytes.Buffer
+	nextID       int
+	version      int
+	capabilities map[string]bool
+}
+
+// pluginLine is one line read from the plugin's stdout, or the read error.
+type pluginLine struct {
+	data []byte
+	err  error
+}
+
+// startPluginSession starts the plugin at command and performs the
+// handshake, offering protocol versions from 2 up to maxVersion.
+func startPluginSession(command string, maxVersion int) (*pluginSession, error) {
+	cmd := exec.Command(command, "serve") //nolint:gosec // Plugin path comes from trusted config
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	s := &pluginSession{
+		cmd:    cmd,
+		stdin:  stdin,
+		lines:  make(chan pluginLine),
+		stderr: &bytes.Buffer{},
+	}
+	cmd.Stderr = s.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+
+	// Read stdout lines in the background so that calls can time out.
+	go func() {
+		r := bufio.NewReader(stdout)
+		for {
+			data, err := r.ReadBytes('\n')
+			if len(data) > 0 && err == io.EOF {
+				err = nil
+			}
+			s.lines <- pluginLine{data: data, err: err}
+			if err != nil {
+				close(s.lines)
+				return
+			}
+		}
+	}()
+
+	var offered []int
+	for _, v := range PluginProtocolVersions {
+		if v >= 2 && v <= maxVersion {
+			offered = append(offered, v)
+		}
+	}
+	resp, err := s.roundTrip(pluginRequest{Operation: "handshake", Versions: offered}, pluginHandshakeTimeout)
+	if err != nil {
+		_ = s.kill()
+		return nil, fmt.Errorf("protocol %d handshake: %w", maxVersion, err)
+	}
+	if resp.Error != "" {
+		_ = s.kill()
+		return nil, fmt.Errorf("protocol %d handshake: %s", maxVersion, resp.Error)
+	}
+	if !slices.Contains(offered, resp.Version) {
+		_ = s.kill()
+		return nil, fmt.Errorf("protocol %d handshake: plugin chose unsupported version %d", maxVersion, resp.Version)
+	}
+	s.version = resp.Version
+	s.capabilities = make(map[string]bool, len(resp.Capabilities))
+	for _, c := range resp.Capabilities {
+		s.capabilities[c] = true
+	}
+	return s, nil
+}
 
-	return &resp, nil
+// call sends req with the next request ID and returns the plugin's
+// response.
+func (s *pluginSession) call(req pluginRequest, timeout time.Duration) (*pluginResponse, error) {
+	s.nextID++
+	req.ID = s.nextID
+	resp, err := s.roundTrip(req, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ID != req.ID {
+		return nil, fmt.Errorf("response id %d does not match request id %d", resp.ID, req.ID)
+	}
+	return resp, nil
+}
+
+// roundTrip writes req as one line and reads one response line.
+func (s *pluginSession) roundTrip(req pluginRequest, timeout time.Duration) (*pluginResponse, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	data = append(data, '\n')
+	defer secret.ClearBytes(data)
+	if _, err := s.stdin.Write(data); err != nil {
+		return nil, fmt.Errorf("write request: %w%s", err, s.stderrSuffix())
+	}
+
+	select {
+	case line, ok := <-s.lines:
+		if !ok || line.err != nil {
+			return nil, fmt.Errorf("plugin exited%s", s.stderrSuffix())
+		}
+		defer secret.ClearBytes(line.data)
+		var resp pluginResponse
+		if err := json.Unmarshal(line.data, &resp); err != nil {
+			return nil, fmt.Errorf("invalid JSON response: %w", err)
+		}
+		return &resp, nil
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+// stderrSuffix returns the plugin's stderr output formatted for appending
+// to an error message, or "" if there is none.
+func (s *pluginSession) stderrSuffix() string {
+	if msg := strings.TrimSpace(s.stderr.String()); msg != "" {
+		return ": " + msg
+	}
+	return ""
+}
+
+// close closes the plugin's stdin and waits for it to exit, killing it if
+// it does not exit within the handshake timeout.
+func (s *pluginSession) close() error {
+	_ = s.stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- s.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(pluginHandshakeTimeout):
+		_ = s.cmd.Process.Kill()
+		<-done
+		return fmt.Errorf("plugin did not exit after stdin was closed")
+	}
+}
+
+// kill stops the plugin immediately.
+func (s *pluginSession) kill() error {
+	_ = s.stdin.Close()
+	err := s.cmd.Process.Kill()
+	_ = s.cmd.Wait()
+	return err
 }
 
 // isNotFoundError checks whether a plugin error message indicates a
@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+
+	"github.com/xcke/envref/pkg/plugin"
+)
+
+// grpcPlugin is a running plugin that speaks protocol version 3.
+type grpcPlugin struct {
+	process *goplugin.Client
+	client  *plugin.Client
+}
+
+// startGRPCPlugin starts the plugin at command and performs the go-plugin
+// handshake, which negotiates the protocol version.
+func startGRPCPlugin(name, command string) (*grpcPlugin, error) {
+	process := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig:  plugin.Handshake,
+		VersionedPlugins: plugin.PluginSet(nil),
+		Cmd:              exec.Command(command, "serve"), //nolint:gosec // Plugin path comes from trusted config
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+		StartTimeout:     pluginHandshakeTimeout,
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Name:   "plugin." + name,
+			Output: os.Stderr,
+			Level:  hclog.Warn,
+		}),
+	})
+	conn, err := process.Client()
+	if err != nil {
+		process.Kill()
+		return nil, fmt.Errorf("protocol %d handshake: %w", plugin.ProtocolVersion, err)
+	}
+	raw, err := conn.Dispense(plugin.PluginName)
+	if err != nil {
+		process.Kill()
+		return nil, fmt.Errorf("protocol %d handshake: %w", plugin.ProtocolVersion, err)
+	}
+	client, ok := raw.(*plugin.Client)
+	if !ok {
+		process.Kill()
+		return nil, fmt.Errorf("protocol %d handshake: unexpected plugin type %T", plugin.ProtocolVersion, raw)
+	}
+	return &grpcPlugin{process: process, client: client}, nil
+}
+
+// executeGRPC sends req to the running version 3 plugin, starting it first
+// if needed, and returns the response in the shape of the JSON protocols.
+func (p *PluginBackend) executeGRPC(req pluginRequest) (*pluginResponse, error) {
+	g, err := p.startedGRPC()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	var resp pluginResponse
+	switch req.Operation {
+	case "get":
+		resp.Value, err = g.client.Get(ctx, req.Key)
+	case "get_many":
+		resp.Values, err = g.client.GetMany(ctx, req.Keys)
+	case "set":
+		err = g.client.Set(ctx, req.Key, req.Value)
+	case "delete":
+		err = g.client.Delete(ctx, req.Key)
+	case "list":
+		resp.Keys, err = g.client.List(ctx)
+	case "ping":
+		err = g.client.Ping(ctx)
+	default:
+		return nil, fmt.Errorf("plugin %q: unknown operation %q", p.name, req.Operation)
+	}
+	switch {
+	case err == nil:
+		return &resp, nil
+	case errors.Is(err, plugin.ErrNotFound):
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("plugin %q: %w", p.name, err)
+}
+
+// startedGRPC returns the running version 3 plugin, starting it first if
+// needed or if it has exited.
+func (p *PluginBackend) startedGRPC() (*grpcPlugin, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.grpc != nil && !p.grpc.process.Exited() {
+		return p.grpc, nil
+	}
+	g, err := startGRPCPlugin(p.name, p.command)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %q: %w", p.name, err)
+	}
+	p.grpc = g
+	return g, nil
+}
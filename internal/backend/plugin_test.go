@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -15,6 +16,12 @@ import (
 // go toolchain is not available.
 func buildTestPlugin(t *testing.T) string {
 	t.Helper()
+	return buildTestPluginFrom(t, "plugin_helper.go")
+}
+
+// buildTestPluginFrom is buildTestPlugin for the helper in testdata/src.
+func buildTestPluginFrom(t *testing.T, src string) string {
+	t.Helper()
 
 	if _, err := exec.LookPath("go"); err != nil {
 		t.Skip("go toolchain not available, skipping plugin tests")
@@ -27,8 +34,7 @@ func buildTestPlugin(t *testing.T) string {
 	}
 	binPath := filepath.Join(dir, binName)
 
-	src := filepath.Join("testdata", "plugin_helper.go")
-	cmd := exec.Command("go", "build", "-o", binPath, src)
+	cmd := exec.Command("go", "build", "-o", binPath, filepath.Join("testdata", src))
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
 		t.Fatalf("failed to build test plugin: %v", err)
@@ -224,3 +230,204 @@ func TestDiscoverPlugin_NotFound(t *testing.T) {
 		t.Fatal("DiscoverPlugin: expected error for nonexistent plugin, got nil")
 	}
 }
+
+func TestPluginBackend_Ping(t *testing.T) {
+	binPath := buildTestPlugin(t)
+
+	// The test plugin does not implement "ping"; an unknown-operation reply
+	// still shows it is runnable.
+	if err := NewPluginBackend("test", binPath).Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	if err := NewPluginBackend("test", "/nonexistent/plugin").Ping(); err == nil {
+		t.Fatal("Ping with invalid command: expected error, got nil")
+	}
+}
+
+// pluginStarts returns how often the test plugin at binPath was started.
+func pluginStarts(t *testing.T, binPath string) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(binPath), "starts.log"))
+	if err != nil {
+		return 0
+	}
+	return strings.Count(string(data), "start\n")
+}
+
+func TestPluginBackend_Protocol2(t *testing.T) {
+	binPath := buildTestPlugin(t)
+	p := NewPluginBackend("test", binPath, WithPluginProtocol(2))
+	defer func() { _ = p.Close() }()
+
+	if err := p.Set("a", "1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := p.Set("b", "2"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, err := p.Get("a"); err != nil || v != "1" {
+		t.Fatalf("Get: got %q, %v", v, err)
+	}
+	if _, err := p.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get missing: got %v, want ErrNotFound", err)
+	}
+	keys, err := p.List()
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("List: got %v, %v", keys, err)
+	}
+	if err := p.Delete("b"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	got, err := GetMany(p, []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if len(got) != 1 || got["a"] != "1" {
+		t.Fatalf("GetMany: got %v, want map[a:1]", got)
+	}
+	if err := p.Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	if n := pluginStarts(t, binPath); n != 1 {
+		t.Errorf("plugin started %d times, want 1", n)
+	}
+
+	// After Close, the next call starts a new plugin process.
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if v, err := p.Get("a"); err != nil || v != "1" {
+		t.Fatalf("Get after Close: got %q, %v", v, err)
+	}
+	if n := pluginStarts(t, binPath); n != 2 {
+		t.Errorf("plugin started %d times, want 2", n)
+	}
+}
+
+func TestPluginBackend_Protocol2HandshakeFailure(t *testing.T) {
+	// A version 1 plugin answers the handshake with an unknown-operation
+	// error and exits.
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "v1-plugin")
+	script := "#!/bin/sh\nread line\necho '{\"error\": \"unknown operation: handshake\"}'"
+	if err := os.WriteFile(scriptPath, []byte(script), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+
+	p := NewPluginBackend("v1", scriptPath, WithPluginProtocol(2))
+	defer func() { _ = p.Close() }()
+	_, err := p.Get("key")
+	if err == nil || !strings.Contains(err.Error(), "handshake") {
+		t.Fatalf("Get: got %v, want handshake error", err)
+	}
+	if err := p.Ping(); err == nil {
+		t.Fatal("Ping: expected handshake error, got nil")
+	}
+}
+
+func TestPluginBackend_Protocol2UnsupportedVersion(t *testing.T) {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "v3-plugin")
+	script := "#!/bin/sh\nread line\necho '{\"version\": 3}'\ncat >/dev/null"
+	if err := os.WriteFile(scriptPath, []byte(script), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+
+	p := NewPluginBackend("v3", scriptPath, WithPluginProtocol(2))
+	defer func() { _ = p.Close() }()
+	_, err := p.Get("key")
+	if err == nil || !strings.Contains(err.Error(), "unsupported version 3") {
+		t.Fatalf("Get: got %v, want unsupported version error", err)
+	}
+}
+
+func TestPluginBackend_Protocol3(t *testing.T) {
+	binPath := buildTestPluginFrom(t, "grpc_plugin_helper.go")
+	p := NewPluginBackend("test", binPath, WithPluginProtocol(3))
+	defer func() { _ = p.Close() }()
+
+	if err := p.Set("a", "1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := p.Set("b", "2"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, err := p.Get("a"); err != nil || v != "1" {
+		t.Fatalf("Get: got %q, %v", v, err)
+	}
+	if _, err := p.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get missing: got %v, want ErrNotFound", err)
+	}
+	if err := p.Delete("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Delete missing: got %v, want ErrNotFound", err)
+	}
+	keys, err := p.List()
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("List: got %v, %v", keys, err)
+	}
+	if err := p.Delete("b"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	got, err := GetMany(p, []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if len(got) != 1 || got["a"] != "1" {
+		t.Fatalf("GetMany: got %v, want map[a:1]", got)
+	}
+	if err := p.Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	if n := pluginStarts(t, binPath); n != 1 {
+		t.Errorf("plugin started %d times, want 1", n)
+	}
+
+	// After Close, the next call starts a new, empty plugin process.
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := p.Get("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Close: got %v, want ErrNotFound", err)
+	}
+	if n := pluginStarts(t, binPath); n != 2 {
+		t.Errorf("plugin started %d times, want 2", n)
+	}
+}
+
+func TestPluginBackend_Protocol3WithoutGetMany(t *testing.T) {
+	binPath := buildTestPluginFrom(t, "grpc_plugin_helper.go")
+	t.Setenv("GRPC_HELPER_NO_GET_MANY", "1")
+	p := NewPluginBackend("test", binPath, WithPluginProtocol(3))
+	defer func() { _ = p.Close() }()
+
+	if err := p.Set("a", "1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, err := GetMany(p, []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if len(got) != 1 || got["a"] != "1" {
+		t.Fatalf("GetMany: got %v, want map[a:1]", got)
+	}
+}
+
+func TestPluginBackend_Protocol3HandshakeFailure(t *testing.T) {
+	// A JSON plugin never prints the go-plugin handshake line.
+	binPath := buildTestPlugin(t)
+	p := NewPluginBackend("json", binPath, WithPluginProtocol(3))
+	defer func() { _ = p.Close() }()
+
+	_, err := p.Get("key")
+	if err == nil || !strings.Contains(err.Error(), "protocol 3 handshake") {
+		t.Fatalf("Get: got %v, want handshake error", err)
+	}
+	if err := p.Ping(); err == nil {
+		t.Fatal("Ping: expected handshake error, got nil")
+	}
+}
@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrPresenceDenied is returned when the user cancels or fails a
+// user-presence check (Touch ID or the account password).
+var ErrPresenceDenied = errors.New("user presence check was denied")
+
+// ErrPresenceUnsupported is returned when a user-presence check is required
+// on a platform that cannot perform one. Access is refused rather than
+// silently allowed.
+var ErrPresenceUnsupported = errors.New("user presence checks are only supported on macOS")
+
+// presenceProvider asks the user to confirm their presence, showing reason
+// in the system prompt. It is replaced in tests.
+var presenceProvider = verifyPresence
+
+// PresencePolicy selects the keys whose reads require a user-presence
+// check. The zero value requires none.
+type PresencePolicy struct {
+	// Patterns are path.Match globs. A key matches if a pattern matches the
+	// full stored key (e.g., "myapp/prod/*") or its last path element
+	// (e.g., "stripe_*"). "*" matches every key.
+	Patterns []string
+}
+
+// Required reports whether reading key requires a user-presence check.
+func (p PresencePolicy) Required(key string) bool {
+	name := key[strings.LastIndex(key, "/")+1:]
+	for _, pattern := range p.Patterns {
+		if pattern == "*" {
+			return true
+		}
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that every pattern is a valid glob.
+func (p PresencePolicy) Validate() error {
+	for _, pattern := range p.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid key pattern %q", pattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// presenceScript asks LocalAuthentication to verify the device owner with
+// Touch ID, falling back to the account password. It prints "ok",
+// "denied", or "unavailable". Running it through osascript keeps envref
+// free of cgo.
+const presenceScript = `ObjC.import('LocalAuthentication');
+ObjC.import('Foundation');
+function run(argv) {
+	const policy = 2; // LAPolicyDeviceOwnerAuthentication
+	const ctx = $.LAContext.alloc.init;
+	if (!ctx.canEvaluatePolicyError(policy, null)) {
+		return 'unavailable';
+	}
+	let result = null;
+	ctx.evaluatePolicyLocalizedReasonReply(policy, argv[0], function (ok, err) {
+		result = ok ? 'ok' : 'denied';
+	});
+	while (result === null) {
+		$.NSRunLoop.currentRunLoop.runUntilDate($.NSDate.dateWithTimeIntervalSinceNow(0.1));
+	}
+	return result;
+}`
+
+// verifyPresence shows the macOS user-presence prompt with the given
+// reason and waits for the user to respond.
+func verifyPresence(reason string) error {
+	cmd := exec.Command("osascript", "-l", "JavaScript", "-e", presenceScript, reason)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("user presence check: %s", msg)
+		}
+		return fmt.Errorf("user presence check: %w", err)
+	}
+
+	switch strings.TrimSpace(stdout.String()) {
+	case "ok":
+		return nil
+	case "unavailable":
+		return fmt.Errorf("user presence check: no Touch ID or password authentication available")
+	default:
+		return ErrPresenceDenied
+	}
+}
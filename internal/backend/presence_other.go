@@ -0,0 +1,9 @@
+//go:build !darwin
+
+package backend
+
+// verifyPresence always fails outside macOS, so that secrets that require a
+// user-presence check are never read without one.
+func verifyPresence(string) error {
+	return ErrPresenceUnsupported
+}
@@ -5,20 +5,31 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/xcke/envref/internal/suggest"
 )
 
 // Registry manages an ordered collection of secret backends and provides
 // fallback resolution: when getting a secret, backends are tried in order
-// until one returns a value.
+// until one returns a value. Aliases name an explicit, ordered subset of the
+// registered backends to use instead of the full fallback chain.
+// Namespaces give a backend its own key template for project and profile
+// scoping. An access rule restricts which secrets may be used through
+// namespaced backends.
 type Registry struct {
-	backends []Backend
-	byName   map[string]Backend
+	backends   []Backend
+	byName     map[string]Backend
+	aliases    map[string][]string
+	namespaces map[string]string
+	access     AccessFunc
 }
 
 // NewRegistry creates an empty Registry.
 func NewRegistry() *Registry {
 	return &Registry{
-		byName: make(map[string]Backend),
+		byName:     make(map[string]Backend),
+		aliases:    make(map[string][]string),
+		namespaces: make(map[string]string),
 	}
 }
 
@@ -35,6 +46,128 @@ func (r *Registry) Register(b Backend) error {
 	return nil
 }
 
+// SetAlias defines name as an alias for the given backends, tried in the
+// order listed. Every target must already be registered, and the alias must
+// not shadow a registered backend.
+func (r *Registry) SetAlias(name string, targets []string) error {
+	if _, exists := r.byName[name]; exists {
+		return fmt.Errorf("alias %q shadows a registered backend", name)
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("alias %q must list at least one backend", name)
+	}
+	for _, target := range targets {
+		if r.byName[target] == nil {
+			return fmt.Errorf("alias %q: %w", name, r.Unregistered(target))
+		}
+	}
+	r.aliases[name] = append([]string(nil), targets...)
+	return nil
+}
+
+// Alias returns the ordered backend names for the alias, and whether the
+// alias is defined.
+func (r *Registry) Alias(name string) ([]string, bool) {
+	targets, ok := r.aliases[name]
+	return targets, ok
+}
+
+// Aliases returns a copy of all defined aliases.
+func (r *Registry) Aliases() map[string][]string {
+	out := make(map[string][]string, len(r.aliases))
+	for name, targets := range r.aliases {
+		out[name] = append([]string(nil), targets...)
+	}
+	return out
+}
+
+// GetVia retrieves a secret through the named alias, trying its backends in
+// order. Errors other than ErrNotFound stop the search, as in Get.
+func (r *Registry) GetVia(alias, key string) (string, error) {
+	val, _, err := r.LookupVia(alias, key)
+	return val, err
+}
+
+// LookupVia is GetVia that also returns the name of the backend that had
+// the key.
+func (r *Registry) LookupVia(alias, key string) (string, string, error) {
+	targets, ok := r.aliases[alias]
+	if !ok {
+		return "", "", fmt.Errorf("alias %q is not defined", alias)
+	}
+	for _, name := range targets {
+		val, err := r.byName[name].Get(key)
+		if err == nil {
+			return val, name, nil
+		}
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return "", "", NewKeyError(name, key, err)
+	}
+	return "", "", ErrNotFound
+}
+
+// SetNamespace sets the key template used to scope the named backend's keys
+// by project and profile (see NewTemplateNamespacedBackend). The backend
+// must already be registered. An empty template restores DefaultNamespace.
+func (r *Registry) SetNamespace(name, template string) error {
+	if r.byName[name] == nil {
+		return r.Unregistered(name)
+	}
+	if template == "" {
+		delete(r.namespaces, name)
+		return nil
+	}
+	if err := ValidateNamespace(template); err != nil {
+		return fmt.Errorf("backend %q: %w", name, err)
+	}
+	r.namespaces[name] = template
+	return nil
+}
+
+// Namespace returns the key template for the named backend, or
+// DefaultNamespace if none was set.
+func (r *Registry) Namespace(name string) string {
+	if template, ok := r.namespaces[name]; ok {
+		return template
+	}
+	return DefaultNamespace
+}
+
+// Namespaced wraps the named backend in a NamespacedBackend scoped to
+// project and, if non-empty, profile, using the backend's key template.
+func (r *Registry) Namespaced(name, project, profile string) (*NamespacedBackend, error) {
+	b := r.byName[name]
+	if b == nil {
+		return nil, r.Unregistered(name)
+	}
+	ns, err := NewTemplateNamespacedBackend(b, r.Namespace(name), project, profile)
+	if err != nil {
+		return nil, err
+	}
+	ns.access = r.access
+	return ns, nil
+}
+
+// SetAccess makes the backends returned by Namespaced check access with
+// fn before each read or write of a secret value. A nil fn allows all
+// access.
+func (r *Registry) SetAccess(fn AccessFunc) {
+	r.access = fn
+}
+
+// Access returns the access rule set with SetAccess, or nil.
+func (r *Registry) Access() AccessFunc {
+	return r.access
+}
+
+// Unregistered returns the error for a backend name that is not
+// registered, suggesting registered names close to it.
+func (r *Registry) Unregistered(name string) error {
+	return fmt.Errorf("backend %q is not registered%s", name, suggest.FormatSuggestion(suggest.Keys(name, r.Names())))
+}
+
 // Len returns the number of registered backends.
 func (r *Registry) Len() int {
 	return len(r.backends)
@@ -65,18 +198,25 @@ func (r *Registry) BackendsIter() []Backend {
 // If a backend returns an error other than ErrNotFound, that error is
 // returned immediately (wrapped in a KeyError).
 func (r *Registry) Get(key string) (string, error) {
+	val, _, err := r.Lookup(key)
+	return val, err
+}
+
+// Lookup is Get that also returns the name of the backend that had the
+// key.
+func (r *Registry) Lookup(key string) (string, string, error) {
 	for _, b := range r.backends {
 		val, err := b.Get(key)
 		if err == nil {
-			return val, nil
+			return val, b.Name(), nil
 		}
 		if errors.Is(err, ErrNotFound) {
 			continue
 		}
 		// Non-ErrNotFound error: stop and report.
-		return "", NewKeyError(b.Name(), key, err)
+		return "", "", NewKeyError(b.Name(), key, err)
 	}
-	return "", ErrNotFound
+	return "", "", ErrNotFound
 }
 
 // GetFrom retrieves a secret from a specific named backend.
@@ -84,7 +224,7 @@ func (r *Registry) Get(key string) (string, error) {
 func (r *Registry) GetFrom(backendName, key string) (string, error) {
 	b := r.byName[backendName]
 	if b == nil {
-		return "", fmt.Errorf("backend %q is not registered", backendName)
+		return "", r.Unregistered(backendName)
 	}
 	val, err := b.Get(key)
 	if err != nil {
@@ -98,7 +238,7 @@ func (r *Registry) GetFrom(backendName, key string) (string, error) {
 func (r *Registry) SetIn(backendName, key, value string) error {
 	b := r.byName[backendName]
 	if b == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return r.Unregistered(backendName)
 	}
 	if err := b.Set(key, value); err != nil {
 		return NewKeyError(backendName, key, err)
@@ -111,7 +251,7 @@ func (r *Registry) SetIn(backendName, key, value string) error {
 func (r *Registry) DeleteFrom(backendName, key string) error {
 	b := r.byName[backendName]
 	if b == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return r.Unregistered(backendName)
 	}
 	if err := b.Delete(key); err != nil {
 		return NewKeyError(backendName, key, err)
@@ -124,7 +264,7 @@ func (r *Registry) DeleteFrom(backendName, key string) error {
 func (r *Registry) ListFrom(backendName string) ([]string, error) {
 	b := r.byName[backendName]
 	if b == nil {
-		return nil, fmt.Errorf("backend %q is not registered", backendName)
+		return nil, r.Unregistered(backendName)
 	}
 	keys, err := b.List()
 	if err != nil {
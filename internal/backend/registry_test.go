@@ -372,3 +372,156 @@ func TestRegistry_String(t *testing.T) {
 		}
 	})
 }
+
+func TestRegistry_SetAlias(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(newMemoryBackend("keychain"))
+	_ = r.Register(newMemoryBackend("vault"))
+
+	if err := r.SetAlias("secrets", []string{"vault", "keychain"}); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+	targets, ok := r.Alias("secrets")
+	if !ok || len(targets) != 2 || targets[0] != "vault" || targets[1] != "keychain" {
+		t.Errorf("Alias(secrets) = %v, %v; want [vault keychain], true", targets, ok)
+	}
+	if _, ok := r.Alias("missing"); ok {
+		t.Error("Alias(missing): expected false")
+	}
+
+	errCases := map[string][]string{
+		"vault":   {"keychain"}, // shadows a backend
+		"empty":   nil,
+		"unknown": {"keychain", "nope"},
+	}
+	for name, targets := range errCases {
+		if err := r.SetAlias(name, targets); err == nil {
+			t.Errorf("SetAlias(%q, %v): expected error, got nil", name, targets)
+		}
+	}
+}
+
+func TestRegistry_Namespaced(t *testing.T) {
+	r := NewRegistry()
+	ssm := newMemoryBackend("ssm")
+	_ = r.Register(ssm)
+	_ = r.Register(newMemoryBackend("keychain"))
+
+	if got := r.Namespace("ssm"); got != DefaultNamespace {
+		t.Errorf("Namespace(ssm) = %q, want default", got)
+	}
+	if err := r.SetNamespace("ssm", "/{project}/{profile}/{key}"); err != nil {
+		t.Fatalf("SetNamespace: %v", err)
+	}
+
+	nb, err := r.Namespaced("ssm", "myapp", "prod")
+	if err != nil {
+		t.Fatalf("Namespaced: %v", err)
+	}
+	_ = nb.Set("db_pass", "x")
+	if _, err := ssm.Get("/myapp/prod/db_pass"); err != nil {
+		t.Errorf("expected key stored as /myapp/prod/db_pass: %v", err)
+	}
+
+	if err := r.SetNamespace("ssm", ""); err != nil {
+		t.Fatalf("SetNamespace reset: %v", err)
+	}
+	if got := r.Namespace("ssm"); got != DefaultNamespace {
+		t.Errorf("Namespace(ssm) after reset = %q, want default", got)
+	}
+
+	if err := r.SetNamespace("missing", "{key}"); err == nil {
+		t.Error("SetNamespace(missing): expected error")
+	}
+	if err := r.SetNamespace("keychain", "{project}"); err == nil {
+		t.Error("SetNamespace without {key}: expected error")
+	}
+	if _, err := r.Namespaced("missing", "myapp", ""); err == nil {
+		t.Error("Namespaced(missing): expected error")
+	}
+}
+
+func TestRegistry_GetVia(t *testing.T) {
+	r := NewRegistry()
+	first := newMemoryBackend("first")
+	second := newMemoryBackend("second")
+	_ = r.Register(first)
+	_ = r.Register(second)
+	_ = first.Set("key", "from-first")
+	_ = second.Set("key", "from-second")
+	_ = first.Set("only_first", "x")
+
+	if err := r.SetAlias("secrets", []string{"second", "first"}); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+	if err := r.SetAlias("narrow", []string{"second"}); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+
+	val, err := r.GetVia("secrets", "key")
+	if err != nil || val != "from-second" {
+		t.Errorf("GetVia(secrets, key) = %q, %v; want from-second", val, err)
+	}
+	val, err = r.GetVia("secrets", "only_first")
+	if err != nil || val != "x" {
+		t.Errorf("GetVia(secrets, only_first) = %q, %v; want x", val, err)
+	}
+	if _, err := r.GetVia("narrow", "only_first"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetVia(narrow, only_first): expected ErrNotFound, got %v", err)
+	}
+	if _, err := r.GetVia("undefined", "key"); err == nil {
+		t.Error("GetVia(undefined): expected error, got nil")
+	}
+}
+
+func TestRegistry_Unregistered(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(newMemoryBackend("keychain"))
+	_ = r.Register(newMemoryBackend("vault"))
+
+	if got, want := r.Unregistered("keychian").Error(), `backend "keychian" is not registered; did you mean keychain?`; got != want {
+		t.Errorf("Unregistered(keychian) = %q, want %q", got, want)
+	}
+	if got, want := r.Unregistered("aws").Error(), `backend "aws" is not registered`; got != want {
+		t.Errorf("Unregistered(aws) = %q, want %q", got, want)
+	}
+}
+
+func TestRegistry_SetAccess(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(newMemoryBackend("secrets"))
+	var checked []string
+	r.SetAccess(func(b Backend, profile, key string) error {
+		checked = append(checked, b.Name()+":"+profile+":"+key)
+		if key == "PROD_DB" {
+			return fmt.Errorf("%w: restricted", ErrAccessDenied)
+		}
+		return nil
+	})
+
+	ns, err := r.Namespaced("secrets", "app", "staging")
+	if err != nil {
+		t.Fatalf("Namespaced: %v", err)
+	}
+	if err := ns.Set("API_KEY", "v"); err != nil {
+		t.Fatalf("Set(API_KEY): %v", err)
+	}
+	if v, err := ns.Get("API_KEY"); err != nil || v != "v" {
+		t.Errorf("Get(API_KEY) = %q, %v; want v", v, err)
+	}
+	if err := ns.Set("PROD_DB", "v"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Set(PROD_DB): expected ErrAccessDenied, got %v", err)
+	}
+	if _, err := ns.Get("PROD_DB"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Get(PROD_DB): expected ErrAccessDenied, got %v", err)
+	}
+	if _, err := ns.GetMany([]string{"API_KEY", "PROD_DB"}); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("GetMany: expected ErrAccessDenied, got %v", err)
+	}
+	if err := ns.Delete("PROD_DB"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Delete(PROD_DB): expected ErrAccessDenied, got %v", err)
+	}
+	if checked[0] != "secrets:staging:API_KEY" {
+		t.Errorf("access checked with %q, want secrets:staging:API_KEY", checked[0])
+	}
+}
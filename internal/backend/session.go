@@ -0,0 +1,164 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session caching modes for backends whose CLI signs in separately from
+// each secret operation.
+const (
+	// SessionCacheProcess signs in once per envref invocation and reuses
+	// the session for every key resolved by that invocation.
+	SessionCacheProcess = "process"
+
+	// SessionCacheKeychain also stores the session token in the OS keychain,
+	// so later invocations reuse it until it expires.
+	SessionCacheKeychain = "keychain"
+)
+
+// KnownSessionCaches lists the supported session caching modes.
+var KnownSessionCaches = []string{SessionCacheProcess, SessionCacheKeychain}
+
+// DefaultSessionTTL is how long a cached session token is reused when the
+// backend does not report its lifetime. It matches the 30-minute idle
+// timeout of 1Password CLI sessions.
+const DefaultSessionTTL = 30 * time.Minute
+
+// sessionLoginTimeout bounds a sign-in, which may wait for the user to type
+// a password or approve a prompt.
+const sessionLoginTimeout = 2 * time.Minute
+
+// ValidateSessionCache checks that mode is a known session caching mode.
+func ValidateSessionCache(mode string) error {
+	for _, m := range KnownSessionCaches {
+		if mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown session cache %q (supported: %s)", mode, strings.Join(KnownSessionCaches, ", "))
+}
+
+// sessionLogin signs in and returns a session token and its lifetime. A zero
+// lifetime means the backend did not report one.
+type sessionLogin func() (token string, ttl time.Duration, err error)
+
+// session caches the token returned by a backend's sign-in, so a resolve of
+// many keys signs in once instead of once per key. With SessionCacheKeychain
+// the token is also stored in the OS keychain under item, together with its
+// expiry.
+type session struct {
+	mode  string
+	item  string
+	ttl   time.Duration
+	login sessionLogin
+
+	mu    sync.Mutex
+	token string
+}
+
+// cachedSession is the keychain representation of a session token.
+type cachedSession struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// newSession returns a session cache. A zero ttl means DefaultSessionTTL.
+func newSession(mode, item string, ttl time.Duration, login sessionLogin) *session {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return &session{mode: mode, item: item, ttl: ttl, login: login}
+}
+
+// get returns the cached token, signing in if there is none. fresh reports
+// whether this call signed in; a fresh token that is rejected is not worth
+// retrying. Keychain errors are not fatal: the session then lasts only for
+// this invocation.
+func (s *session) get() (token string, fresh bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" {
+		return s.token, false, nil
+	}
+	if s.mode == SessionCacheKeychain {
+		if token := s.loadLocked(); token != "" {
+			s.token = token
+			return token, false, nil
+		}
+	}
+
+	token, ttl, err := s.login()
+	if err != nil {
+		return "", false, err
+	}
+	if token == "" {
+		return "", false, errors.New("sign-in returned an empty session token")
+	}
+	s.token = token
+
+	if s.mode == SessionCacheKeychain {
+		if ttl <= 0 || ttl > s.ttl {
+			ttl = s.ttl
+		}
+		s.storeLocked(token, ttl)
+	}
+	return token, true, nil
+}
+
+// invalidate drops token after the backend rejected it, so the next get
+// signs in again. A token that was already replaced is left alone.
+func (s *session) invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != token {
+		return
+	}
+	s.token = ""
+	if s.mode == SessionCacheKeychain {
+		_ = DeleteKeychainItem(s.item)
+	}
+}
+
+// loadLocked returns the unexpired token stored in the keychain, or "".
+// The caller must hold s.mu.
+func (s *session) loadLocked() string {
+	return CachedSession(s.item)
+}
+
+// storeLocked stores token in the keychain. The caller must hold s.mu.
+func (s *session) storeLocked(token string, ttl time.Duration) {
+	_ = CacheSession(s.item, token, ttl)
+}
+
+// CachedSession returns the unexpired session token stored in the OS
+// keychain under item, or "" if there is none. An expired or unreadable
+// entry is deleted.
+func CachedSession(item string) string {
+	data, err := KeychainItem(item)
+	if err != nil {
+		return ""
+	}
+	var cached cachedSession
+	if err := json.Unmarshal([]byte(data), &cached); err != nil || !time.Now().Before(cached.Expires) {
+		_ = DeleteKeychainItem(item)
+		return ""
+	}
+	return cached.Token
+}
+
+// CacheSession stores token in the OS keychain under item, to be returned
+// by CachedSession until ttl elapses.
+func CacheSession(item, token string, ttl time.Duration) error {
+	data, err := json.Marshal(cachedSession{Token: token, Expires: time.Now().Add(ttl)})
+	if err != nil {
+		return err
+	}
+	return SetKeychainItem(item, string(data))
+}
@@ -0,0 +1,62 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestValidateSessionCache(t *testing.T) {
+	for _, mode := range KnownSessionCaches {
+		if err := ValidateSessionCache(mode); err != nil {
+			t.Errorf("ValidateSessionCache(%q): %v", mode, err)
+		}
+	}
+	if err := ValidateSessionCache("disk"); err == nil {
+		t.Error("ValidateSessionCache(disk): expected error")
+	}
+}
+
+func TestSession_KeychainExpiry(t *testing.T) {
+	defer setupMockKeyring()()
+
+	logins := 0
+	login := func() (string, time.Duration, error) {
+		logins++
+		return fmt.Sprintf("token-%d", logins), time.Hour, nil
+	}
+
+	// The reported lifetime is capped by the session TTL.
+	s := newSession(SessionCacheKeychain, "session:test", time.Minute, login)
+	if token, fresh, err := s.get(); err != nil || token != "token-1" || !fresh {
+		t.Fatalf("get: got %q, %v, %v", token, fresh, err)
+	}
+	data, err := KeychainItem("session:test")
+	if err != nil {
+		t.Fatalf("KeychainItem: %v", err)
+	}
+	var cached cachedSession
+	if err := json.Unmarshal([]byte(data), &cached); err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(cached.Expires) > time.Minute {
+		t.Errorf("expiry %s exceeds the session TTL", cached.Expires)
+	}
+
+	// An expired keychain entry is discarded.
+	expired, _ := json.Marshal(cachedSession{Token: "token-1", Expires: time.Now().Add(-time.Second)})
+	if err := SetKeychainItem("session:test", string(expired)); err != nil {
+		t.Fatal(err)
+	}
+	s2 := newSession(SessionCacheKeychain, "session:test", time.Minute, login)
+	if token, _, err := s2.get(); err != nil || token != "token-2" {
+		t.Fatalf("get after expiry: got %q, %v", token, err)
+	}
+
+	s2.invalidate("token-2")
+	if _, err := KeychainItem("session:test"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("KeychainItem after invalidate: got %v, want ErrNotFound", err)
+	}
+}
@@ -1,7 +1,7 @@
 // aws_mock is a test helper that mimics the AWS CLI for testing
 // the AWSSSMBackend. It is built and used by awsssm_test.go.
 //
-// Usage: aws_mock ssm get-parameter|put-parameter|delete-parameter|describe-parameters [args...]
+// Usage: aws_mock ssm get-parameter|get-parameters|put-parameter|delete-parameter|describe-parameters [args...]
 //
 // State is persisted in a JSON file in the executable's directory so that
 // multiple invocations maintain consistent state within a single test.
@@ -13,6 +13,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -22,6 +23,11 @@ func main() {
 		fatal("usage: aws_mock ssm <subcommand> [args...]")
 	}
 
+	if args[0] == "sts" && args[1] == "get-caller-identity" {
+		fmt.Println(`{"UserId":"AIDAEXAMPLE","Account":"123456789012","Arn":"arn:aws:iam::123456789012:user/alice"}`)
+		return
+	}
+
 	if args[0] != "ssm" {
 		fatal("Unknown service: %s", args[0])
 	}
@@ -34,12 +40,16 @@ func main() {
 	switch subcmd {
 	case "get-parameter":
 		handleGetParameter(store, rest)
+	case "get-parameters":
+		handleGetParameters(store, rest)
 	case "put-parameter":
 		handlePutParameter(store, rest)
 	case "delete-parameter":
 		handleDeleteParameter(store, rest)
 	case "describe-parameters":
 		handleDescribeParameters(store, rest)
+	case "get-parameter-history":
+		handleGetParameterHistory(rest)
 	default:
 		fatal("Unknown operation: %s", subcmd)
 	}
@@ -51,6 +61,16 @@ func handleGetParameter(store map[string]string, args []string) {
 		fatal("An error occurred (MissingParameterException) when calling the GetParameter operation: parameter name is required")
 	}
 
+	// A "name:version" selector reads from the version history.
+	if i := strings.LastIndex(name, ":"); i > 0 {
+		history := loadVersions()[name[:i]]
+		n, err := strconv.Atoi(name[i+1:])
+		if err != nil || n < 1 || n > len(history) {
+			fatal("An error occurred (ParameterVersionNotFound) when calling the GetParameter operation: version %s of %q not found", name[i+1:], name[:i])
+		}
+		store[name] = history[n-1]
+	}
+
 	val, ok := store[name]
 	if !ok {
 		fatal("An error occurred (ParameterNotFound) when calling the GetParameter operation: parameter %q does not exist", name)
@@ -61,11 +81,52 @@ func handleGetParameter(store map[string]string, args []string) {
 			"Name":  name,
 			"Value": val,
 			"Type":  "SecureString",
+			"ARN":   "arn:aws:ssm:us-east-1:123456789012:parameter" + name,
 		},
 	}
 	writeJSON(resp)
 }
 
+func handleGetParameters(store map[string]string, args []string) {
+	// --names takes every argument up to the next flag.
+	var names []string
+	for i, a := range args {
+		if a != "--names" {
+			continue
+		}
+		for _, n := range args[i+1:] {
+			if strings.HasPrefix(n, "--") {
+				break
+			}
+			names = append(names, n)
+		}
+	}
+	if len(names) == 0 || len(names) > 10 {
+		fatal("An error occurred (ValidationException) when calling the GetParameters operation: between 1 and 10 names are required")
+	}
+
+	params := []map[string]string{}
+	invalid := []string{}
+	for _, name := range names {
+		val, ok := store[name]
+		if !ok {
+			invalid = append(invalid, name)
+			continue
+		}
+		params = append(params, map[string]string{
+			"Name":  name,
+			"Value": val,
+			"Type":  "SecureString",
+		})
+	}
+
+	resp := map[string]interface{}{
+		"Parameters":        params,
+		"InvalidParameters": invalid,
+	}
+	writeJSON(resp)
+}
+
 func handlePutParameter(store map[string]string, args []string) {
 	name := flagValue(args, "--name", "")
 	value := flagValue(args, "--value", "")
@@ -77,8 +138,12 @@ func handlePutParameter(store map[string]string, args []string) {
 	store[name] = value
 	saveStore(store)
 
+	versions := loadVersions()
+	versions[name] = append(versions[name], value)
+	saveVersions(versions)
+
 	resp := map[string]interface{}{
-		"Version": 1,
+		"Version": len(versions[name]),
 		"Tier":    "Standard",
 	}
 	writeJSON(resp)
@@ -98,6 +163,10 @@ func handleDeleteParameter(store map[string]string, args []string) {
 	delete(store, name)
 	saveStore(store)
 
+	versions := loadVersions()
+	delete(versions, name)
+	saveVersions(versions)
+
 	// AWS CLI returns empty response on successful delete.
 	fmt.Print("{}")
 }
@@ -107,12 +176,17 @@ func handleDescribeParameters(store map[string]string, args []string) {
 	filter := flagValue(args, "--parameter-filters", "")
 
 	// Extract prefix from filter string "Key=Name,Option=BeginsWith,Values=/prefix/"
+	// (or an exact name with Option=Equals).
 	prefix := ""
+	exact := false
 	if filter != "" {
 		for _, part := range strings.Split(filter, ",") {
 			if strings.HasPrefix(part, "Values=") {
 				prefix = strings.TrimPrefix(part, "Values=")
 			}
+			if part == "Option=Equals" {
+				exact = true
+			}
 		}
 	}
 
@@ -125,10 +199,12 @@ func handleDescribeParameters(store map[string]string, args []string) {
 	sort.Strings(names)
 
 	for _, name := range names {
-		if prefix == "" || strings.HasPrefix(name, prefix) {
+		if prefix == "" || (exact && name == prefix) || (!exact && strings.HasPrefix(name, prefix)) {
 			params = append(params, map[string]string{
-				"Name": name,
-				"Type": "SecureString",
+				"Name":             name,
+				"Type":             "SecureString",
+				"LastModifiedDate": "2024-01-15T10:30:00.123000+00:00",
+				"LastModifiedUser": "arn:aws:iam::123456789012:user/mock",
 			})
 		}
 	}
@@ -153,6 +229,45 @@ func flagValue(args []string, flag, def string) string {
 	return def
 }
 
+func handleGetParameterHistory(args []string) {
+	name := flagValue(args, "--name", "")
+	history, ok := loadVersions()[name]
+	if !ok {
+		fatal("An error occurred (ParameterNotFound) when calling the GetParameterHistory operation: parameter %q does not exist", name)
+	}
+
+	params := make([]map[string]interface{}, 0, len(history))
+	for i := range history {
+		params = append(params, map[string]interface{}{
+			"Name":             name,
+			"Type":             "SecureString",
+			"Version":          i + 1,
+			"LastModifiedDate": fmt.Sprintf("2024-01-%02dT00:00:00+00:00", i+1),
+			"LastModifiedUser": "arn:aws:iam::123456789012:user/mock",
+		})
+	}
+	writeJSON(map[string]interface{}{"Parameters": params})
+}
+
+// versionsPath holds every value written to each parameter, oldest first.
+func versionsPath() string {
+	exe, _ := os.Executable()
+	return filepath.Join(filepath.Dir(exe), "aws_versions.json")
+}
+
+func loadVersions() map[string][]string {
+	versions := make(map[string][]string)
+	if data, err := os.ReadFile(versionsPath()); err == nil {
+		_ = json.Unmarshal(data, &versions)
+	}
+	return versions
+}
+
+func saveVersions(versions map[string][]string) {
+	data, _ := json.Marshal(versions)
+	_ = os.WriteFile(versionsPath(), data, 0o644)
+}
+
 func storePath() string {
 	exe, _ := os.Executable()
 	return filepath.Join(filepath.Dir(exe), "aws_store.json")
@@ -0,0 +1,95 @@
+// grpc_plugin_helper is a test plugin executable that serves an in-memory
+// store with plugin protocol version 3 (gRPC). It is built and used by
+// plugin_test.go.
+//
+// Usage: grpc_plugin_helper serve
+//
+// Every start is recorded in starts.log next to the executable. Setting
+// GRPC_HELPER_NO_GET_MANY serves a backend without GetMany.
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/xcke/envref/pkg/plugin"
+)
+
+type store struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (s *store) Get(_ context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	if !ok {
+		return "", plugin.ErrNotFound
+	}
+	return v, nil
+}
+
+func (s *store) Set(_ context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *store) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; !ok {
+		return plugin.ErrNotFound
+	}
+	delete(s.values, key)
+	return nil
+}
+
+func (s *store) List(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// batchStore adds GetMany to store.
+type batchStore struct {
+	*store
+}
+
+func (s batchStore) GetMany(_ context.Context, keys []string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string]string)
+	for _, k := range keys {
+		if v, ok := s.values[k]; ok {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+func main() {
+	if exe, err := os.Executable(); err == nil {
+		if f, err := os.OpenFile(filepath.Join(filepath.Dir(exe), "starts.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644); err == nil {
+			_, _ = f.WriteString("start\n")
+			_ = f.Close()
+		}
+	}
+
+	s := &store{values: make(map[string]string)}
+	if os.Getenv("GRPC_HELPER_NO_GET_MANY") != "" {
+		plugin.Serve(s)
+		return
+	}
+	plugin.Serve(batchStore{s})
+}
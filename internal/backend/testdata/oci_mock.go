@@ -37,6 +37,8 @@ func main() {
 		handleVault(store, args[1:])
 	case "secrets":
 		handleSecrets(store, args[1:])
+	case "kms":
+		fmt.Println(`{"data":{"lifecycle-state":"ACTIVE"}}`)
 	default:
 		fatal("ServiceError: unknown service: %s", service)
 	}
@@ -3,8 +3,12 @@
 //
 // Usage: op_mock item get|create|edit|delete|list [args...]
 //
+//	op_mock signin --raw
+//
 // State is persisted in a JSON file in the executable's directory so that
 // multiple invocations maintain consistent state within a single test.
+// signin issues a new session token and records the sign-in; commands run
+// with --session fail unless they pass the latest token.
 package main
 
 import (
@@ -20,6 +24,9 @@ type item struct {
 	ID    string  `json:"id"`
 	Title string  `json:"title"`
 	Fields []field `json:"fields,omitempty"`
+	CreatedAt    string `json:"created_at,omitempty"`
+	UpdatedAt    string `json:"updated_at,omitempty"`
+	LastEditedBy string `json:"last_edited_by,omitempty"`
 }
 
 type field struct {
@@ -40,6 +47,22 @@ func main() {
 		fatal("usage: op_mock item <subcommand> [args...]")
 	}
 
+	if args[0] == "signin" {
+		handleSignin()
+		return
+	}
+	checkSession(args)
+
+	if args[0] == "whoami" {
+		fmt.Println(`{"url":"my.1password.com","email":"alice@example.com","user_uuid":"U1","account_uuid":"A1"}`)
+		return
+	}
+
+	if args[0] == "vault" && args[1] == "get" {
+		fmt.Println(`{"id":"vault-id","name":"Personal"}`)
+		return
+	}
+
 	if args[0] != "item" {
 		fatal("unknown command: %s", args[0])
 	}
@@ -82,6 +105,9 @@ func handleGet(store map[string]string, args []string) {
 		Fields: []field{
 			{ID: "notesPlain", Label: "notesPlain", Value: val, Type: "STRING"},
 		},
+		CreatedAt:    "2024-01-01T00:00:00Z",
+		UpdatedAt:    "2024-02-01T12:00:00Z",
+		LastEditedBy: "MOCKUSERID",
 	}
 	writeJSON(resp)
 }
@@ -162,6 +188,35 @@ func handleList(store map[string]string, args []string) {
 	writeJSON(items)
 }
 
+// handleSignin issues session token "session-N" for the Nth sign-in.
+func handleSignin() {
+	logPath := mockPath("signins.log")
+	data, _ := os.ReadFile(logPath)
+	n := strings.Count(string(data), "\n") + 1
+	_ = os.WriteFile(logPath, append(data, "signin\n"...), 0o644)
+
+	token := fmt.Sprintf("session-%d", n)
+	_ = os.WriteFile(mockPath("session"), []byte(token), 0o644)
+	fmt.Println(token)
+}
+
+// checkSession fails like op does when --session is not the latest token.
+func checkSession(args []string) {
+	token := flagValue(args, "--session", "")
+	if token == "" {
+		return
+	}
+	valid, err := os.ReadFile(mockPath("session"))
+	if err != nil || string(valid) != token {
+		fatal("[ERROR] 2024/01/01 00:00:00 You are not currently signed in. Please run `op signin --help` for instructions")
+	}
+}
+
+func mockPath(name string) string {
+	exe, _ := os.Executable()
+	return filepath.Join(filepath.Dir(exe), name)
+}
+
 // flagValue extracts the value of a --flag from args. Returns def if not found.
 func flagValue(args []string, flag, def string) string {
 	for i, a := range args {
@@ -173,8 +228,7 @@ func flagValue(args []string, flag, def string) string {
 }
 
 func storePath() string {
-	exe, _ := os.Executable()
-	return filepath.Join(filepath.Dir(exe), "op_store.json")
+	return mockPath("op_store.json")
 }
 
 func loadStore() map[string]string {
@@ -8,9 +8,14 @@
 // to stdout. State is persisted in a temporary JSON file so that multiple
 // invocations (set, get, list, delete) maintain consistent state within a
 // single test.
+//
+// If the first request is a protocol version 2 handshake, the helper keeps
+// serving newline-delimited requests until stdin is closed. Every start is
+// recorded in starts.log next to the executable.
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -19,15 +24,22 @@ import (
 )
 
 type request struct {
-	Operation string `json:"operation"`
-	Key       string `json:"key,omitempty"`
-	Value     string `json:"value,omitempty"`
+	ID        int      `json:"id,omitempty"`
+	Operation string   `json:"operation"`
+	Key       string   `json:"key,omitempty"`
+	Value     string   `json:"value,omitempty"`
+	Keys      []string `json:"keys,omitempty"`
+	Versions  []int    `json:"versions,omitempty"`
 }
 
 type response struct {
-	Value string   `json:"value,omitempty"`
-	Keys  []string `json:"keys,omitempty"`
-	Error string   `json:"error,omitempty"`
+	ID           int               `json:"id,omitempty"`
+	Value        string            `json:"value,omitempty"`
+	Keys         []string          `json:"keys,omitempty"`
+	Values       map[string]string `json:"values,omitempty"`
+	Version      int               `json:"version,omitempty"`
+	Capabilities []string          `json:"capabilities,omitempty"`
+	Error        string            `json:"error,omitempty"`
 }
 
 func main() {
@@ -36,12 +48,47 @@ func main() {
 		os.Exit(1)
 	}
 
+	recordStart()
+
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		writeResponse(response{Error: "invalid request: no input"})
+		return
+	}
 	var req request
-	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+	if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
 		writeResponse(response{Error: fmt.Sprintf("invalid request: %v", err)})
 		return
 	}
 
+	if req.Operation != "handshake" {
+		writeResponse(handle(req))
+		return
+	}
+
+	// Protocol version 2: answer the handshake, then serve until EOF.
+	if !contains(req.Versions, 2) {
+		writeResponse(response{Error: "no supported protocol version"})
+		return
+	}
+	writeResponse(response{Version: 2, Capabilities: []string{"get_many", "ping"}})
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			writeResponse(response{Error: fmt.Sprintf("invalid request: %v", err)})
+			continue
+		}
+		var resp response
+		if req.Operation != "ping" {
+			resp = handle(req)
+		}
+		resp.ID = req.ID
+		writeResponse(resp)
+	}
+}
+
+// handle processes a single request against the store.
+func handle(req request) response {
 	store := loadStore()
 	var resp response
 
@@ -70,11 +117,37 @@ func main() {
 		}
 		sort.Strings(keys)
 		resp.Keys = keys
+	case "get_many":
+		resp.Values = make(map[string]string)
+		for _, k := range req.Keys {
+			if val, ok := store[k]; ok {
+				resp.Values[k] = val
+			}
+		}
 	default:
 		resp.Error = fmt.Sprintf("unknown operation: %s", req.Operation)
 	}
 
-	writeResponse(resp)
+	return resp
+}
+
+func contains(versions []int, v int) bool {
+	for _, x := range versions {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func recordStart() {
+	exe, _ := os.Executable()
+	f, err := os.OpenFile(filepath.Join(filepath.Dir(exe), "starts.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	_, _ = f.WriteString("start\n")
+	_ = f.Close()
 }
 
 func storePath() string {
@@ -3,8 +3,12 @@
 //
 // Usage: vault_mock kv get|put|list|metadata [args...]
 //
+//	vault_mock login -method=<method> [param=value...]
+//
 // State is persisted in a JSON file in the executable's directory so that
 // multiple invocations maintain consistent state within a single test.
+// login issues a new token and records the login; once a token was issued,
+// commands run with another VAULT_TOKEN are denied.
 package main
 
 import (
@@ -13,6 +17,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -22,6 +27,17 @@ func main() {
 		fatal("usage: vault_mock kv <subcommand> [args...]")
 	}
 
+	if args[0] == "login" {
+		handleLogin(args[1:])
+		return
+	}
+	checkToken()
+
+	if args[0] == "token" && args[1] == "lookup" {
+		fmt.Println(`{"data":{"display_name":"ldap-alice","policies":["default","dev"]}}`)
+		return
+	}
+
 	if args[0] != "kv" {
 		fatal("Error: unknown command %q", args[0])
 	}
@@ -38,9 +54,13 @@ func main() {
 		handleKVPut(store, rest)
 	case "list":
 		handleKVList(store, rest)
+	case "rollback":
+		handleKVRollback(store, rest)
 	case "metadata":
 		if len(rest) > 0 && rest[0] == "delete" {
 			handleKVMetadataDelete(store, rest[1:])
+		} else if len(rest) > 0 && rest[0] == "get" {
+			handleKVMetadataGet(store, rest[1:])
 		} else {
 			fatal("Error: unknown metadata subcommand")
 		}
@@ -51,6 +71,7 @@ func main() {
 
 func handleKVGet(store map[string]string, args []string) {
 	mount, rest := extractFlag(args, "-mount")
+	version, rest := extractFlag(rest, "-version")
 	_, rest = extractFlag(rest, "-format")
 	// Remaining args: global flags and the path.
 	_, rest = extractFlag(rest, "-address")
@@ -68,6 +89,14 @@ func handleKVGet(store map[string]string, args []string) {
 	if !ok {
 		fatal("No value found at %s", mount+"/data/"+path)
 	}
+	if version != "" {
+		history := loadVersions()[fullKey]
+		n, err := strconv.Atoi(version)
+		if err != nil || n < 1 || n > len(history) {
+			fatal("No value found at %s", mount+"/data/"+path)
+		}
+		val = history[n-1]
+	}
 
 	resp := map[string]interface{}{
 		"data": map[string]interface{}{
@@ -104,6 +133,7 @@ func handleKVPut(store map[string]string, args []string) {
 	fullKey := mount + "/" + path
 	store[fullKey] = value
 	saveStore(store)
+	appendVersion(fullKey, value)
 
 	resp := map[string]interface{}{
 		"data": map[string]interface{}{
@@ -186,6 +216,42 @@ func handleKVMetadataDelete(store map[string]string, args []string) {
 	fmt.Print("{}")
 }
 
+func handleKVMetadataGet(store map[string]string, args []string) {
+	mount, rest := extractFlag(args, "-mount")
+	_, rest = extractFlag(rest, "-format")
+	_, rest = extractFlag(rest, "-address")
+	_, rest = extractFlag(rest, "-namespace")
+
+	if len(rest) == 0 {
+		fatal("Error: not enough arguments")
+	}
+	path := rest[len(rest)-1]
+
+	if _, ok := store[mount+"/"+path]; !ok {
+		fatal("No value found at %s/metadata/%s", mount, path)
+	}
+
+	history := loadVersions()[mount+"/"+path]
+	versions := make(map[string]interface{}, len(history))
+	for i := range history {
+		versions[strconv.Itoa(i+1)] = map[string]interface{}{
+			"created_time":  fmt.Sprintf("2024-01-%02dT00:00:00.000000Z", i+1),
+			"deletion_time": "",
+			"destroyed":     false,
+		}
+	}
+
+	resp := map[string]interface{}{
+		"data": map[string]interface{}{
+			"created_time":    "2024-01-01T00:00:00.000000Z",
+			"updated_time":    "2024-02-01T12:00:00.000000Z",
+			"current_version": len(history),
+			"versions":        versions,
+		},
+	}
+	writeJSON(resp)
+}
+
 // extractFlag extracts a flag like "-mount=value" or "-mount value" from args.
 // Returns the value and the remaining args with the flag removed.
 func extractFlag(args []string, flag string) (string, []string) {
@@ -211,9 +277,92 @@ func extractFlag(args []string, flag string) (string, []string) {
 	return value, remaining
 }
 
-func storePath() string {
+func handleKVRollback(store map[string]string, args []string) {
+	mount, rest := extractFlag(args, "-mount")
+	version, rest := extractFlag(rest, "-version")
+	_, rest = extractFlag(rest, "-address")
+	_, rest = extractFlag(rest, "-namespace")
+
+	if len(rest) == 0 {
+		fatal("Error: not enough arguments")
+	}
+	fullKey := mount + "/" + rest[len(rest)-1]
+
+	history := loadVersions()[fullKey]
+	n, err := strconv.Atoi(version)
+	if err != nil || n < 1 || n > len(history) {
+		fatal("No value found at %s", fullKey)
+	}
+	store[fullKey] = history[n-1]
+	saveStore(store)
+	appendVersion(fullKey, history[n-1])
+	fmt.Print("{}")
+}
+
+// versionsPath holds every value written to each secret, oldest first.
+func versionsPath() string {
+	exe, _ := os.Executable()
+	return filepath.Join(filepath.Dir(exe), "vault_versions.json")
+}
+
+func loadVersions() map[string][]string {
+	versions := make(map[string][]string)
+	if data, err := os.ReadFile(versionsPath()); err == nil {
+		_ = json.Unmarshal(data, &versions)
+	}
+	return versions
+}
+
+func appendVersion(fullKey, value string) {
+	versions := loadVersions()
+	versions[fullKey] = append(versions[fullKey], value)
+	data, _ := json.Marshal(versions)
+	_ = os.WriteFile(versionsPath(), data, 0o644)
+}
+
+// handleLogin issues token "token-N" for the Nth login. The log records
+// the method and params of each login.
+func handleLogin(args []string) {
+	method, rest := extractFlag(args, "-method")
+	_, rest = extractFlag(rest, "-format")
+	_, rest = extractFlag(rest, "-address")
+	_, rest = extractFlag(rest, "-namespace")
+	params := make([]string, 0, len(rest))
+	for _, a := range rest {
+		if a != "-no-store" {
+			params = append(params, a)
+		}
+	}
+
+	logPath := mockPath("logins.log")
+	data, _ := os.ReadFile(logPath)
+	n := strings.Count(string(data), "\n") + 1
+	line := strings.TrimSpace(method + " " + strings.Join(params, " "))
+	_ = os.WriteFile(logPath, append(data, line+"\n"...), 0o644)
+
+	token := fmt.Sprintf("token-%d", n)
+	_ = os.WriteFile(mockPath("token"), []byte(token), 0o644)
+	fmt.Printf(`{"auth":{"client_token":%q,"lease_duration":3600}}`+"\n", token)
+}
+
+// checkToken denies requests made with a stale VAULT_TOKEN.
+func checkToken() {
+	valid, err := os.ReadFile(mockPath("token"))
+	if err != nil {
+		return
+	}
+	if os.Getenv("VAULT_TOKEN") != string(valid) {
+		fatal("Error making API request.\n\nCode: 403. Errors:\n\n* permission denied")
+	}
+}
+
+func mockPath(name string) string {
 	exe, _ := os.Executable()
-	return filepath.Join(filepath.Dir(exe), "vault_store.json")
+	return filepath.Join(filepath.Dir(exe), name)
+}
+
+func storePath() string {
+	return mockPath("vault_store.json")
 }
 
 func loadStore() map[string]string {
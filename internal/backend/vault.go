@@ -4,19 +4,19 @@
 // storage and age (filippo.io/age) for per-value encryption. The vault
 // is stored at a configurable path (default: ~/.config/envref/vault.db).
 //
-// Each secret value is encrypted independently using age with a
-// passphrase-based recipient derived from the master password. The
-// master password is never stored; it must be provided each time the
-// vault is accessed.
+// Each secret value is encrypted independently with a key derived from
+// the master password, using Argon2id (the default for new vaults) or
+// age's scrypt passphrase encryption (see KDFParams). The master password
+// is never stored; it must be provided each time the vault is accessed.
+// A vault shared with a team encrypts values with a random data key
+// instead, wrapped for each member's age public key (see Grant).
 package backend
 
 import (
-	"bytes"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -25,7 +25,6 @@ import (
 	"time"
 
 	"filippo.io/age"
-	"filippo.io/age/armor"
 	_ "modernc.org/sqlite"
 
 	"github.com/xcke/envref/internal/secret"
@@ -39,15 +38,21 @@ const defaultVaultFile = "vault.db"
 // age encryption. It implements the Backend interface.
 //
 // The vault uses a single SQLite table to store key-value pairs. Values
-// are encrypted using age's scrypt-based passphrase encryption, which
-// derives a key from the master password using scrypt with a random salt.
+// are encrypted with a key derived from the master password by the KDF
+// recorded in the vault's metadata: Argon2id with XChaCha20-Poly1305, or,
+// for vaults created before the KDF was configurable, age's scrypt-based
+// passphrase encryption with a random salt per value.
 //
 // Thread safety is provided via a sync.Mutex.
 type VaultBackend struct {
 	dbPath     string
 	passphrase []byte
+	identities []age.Identity // open a shared vault; see WithVaultIdentities
+	kdf        KDFParams      // KDF for Initialize; existing vaults keep theirs
 	mu         sync.Mutex
 	db         *sql.DB
+	cipher     *vaultCipher
+	closed     bool
 }
 
 // VaultOption configures a VaultBackend.
@@ -60,39 +65,67 @@ func WithVaultPath(path string) VaultOption {
 	}
 }
 
+// WithVaultKDF sets the key derivation used when the vault is initialized.
+// It has no effect on an initialized vault; use Rekey to change its KDF.
+func WithVaultKDF(kdf KDFParams) VaultOption {
+	return func(v *VaultBackend) {
+		v.kdf = kdf
+	}
+}
+
+// WithVaultIdentities sets the age identities that open a shared vault
+// (see Grant). They are ignored by vaults encrypted with a passphrase.
+func WithVaultIdentities(identities ...age.Identity) VaultOption {
+	return func(v *VaultBackend) {
+		v.identities = identities
+	}
+}
+
 // NewVaultBackend creates a new VaultBackend with the given passphrase.
-// The passphrase is used to encrypt and decrypt secret values via age
-// scrypt-based passphrase encryption.
+// The passphrase is used to derive the keys that encrypt and decrypt
+// secret values. It may be empty if age identities are given with
+// WithVaultIdentities, for opening a shared vault.
 //
 // Options can be used to configure the database path. If no path is
 // specified, the default (~/.config/envref/vault.db) is used.
 //
 // The database is created lazily on first access.
 func NewVaultBackend(passphrase string, opts ...VaultOption) (*VaultBackend, error) {
-	if passphrase == "" {
-		return nil, fmt.Errorf("vault passphrase must not be empty")
+	v := &VaultBackend{}
+	for _, opt := range opts {
+		opt(v)
 	}
 
-	v := &VaultBackend{
-		passphrase: []byte(passphrase),
+	if passphrase == "" && len(v.identities) == 0 {
+		return nil, fmt.Errorf("vault passphrase must not be empty")
 	}
-
-	for _, opt := range opts {
-		opt(v)
+	if passphrase != "" {
+		v.passphrase = []byte(passphrase)
+		_ = secret.Lock(v.passphrase)
 	}
 
 	// Use default path if not configured.
 	if v.dbPath == "" {
-		configDir, err := os.UserConfigDir()
+		path, err := DefaultVaultPath()
 		if err != nil {
-			return nil, fmt.Errorf("vault: determining config directory: %w", err)
+			return nil, err
 		}
-		v.dbPath = filepath.Join(configDir, defaultVaultDir, defaultVaultFile)
+		v.dbPath = path
 	}
 
 	return v, nil
 }
 
+// DefaultVaultPath returns the vault database path used when none is
+// configured: envref/vault.db in the user's config directory.
+func DefaultVaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("vault: determining config directory: %w", err)
+	}
+	return filepath.Join(configDir, defaultVaultDir, defaultVaultFile), nil
+}
+
 // Name returns "vault", the identifier used in .envref.yaml configuration
 // and ref:// URIs.
 func (v *VaultBackend) Name() string {
@@ -132,6 +165,73 @@ func (v *VaultBackend) Get(key string) (string, error) {
 	return plaintext, nil
 }
 
+// GetMany retrieves and decrypts the values for keys with a single query.
+// Keys that do not exist are omitted from the result. Returns
+// ErrVaultLocked if the vault is locked.
+func (v *VaultBackend) GetMany(keys []string) (map[string]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	db, err := v.open()
+	if err != nil {
+		return nil, fmt.Errorf("vault get: %w", err)
+	}
+	if err := v.checkLocked(db); err != nil {
+		return nil, fmt.Errorf("vault get: %w", err)
+	}
+
+	values := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(keys)), ",")
+	rows, err := db.Query("SELECT key, value FROM secrets WHERE key IN ("+placeholders+")", args...) //nolint:gosec // only placeholders are concatenated
+	if err != nil {
+		return nil, fmt.Errorf("vault get: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var key, encrypted string
+		if err := rows.Scan(&key, &encrypted); err != nil {
+			return nil, fmt.Errorf("vault get: %w", err)
+		}
+		plaintext, err := v.decrypt(encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("vault get %q: decrypt: %w", key, err)
+		}
+		values[key] = plaintext
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("vault get: %w", err)
+	}
+	return values, nil
+}
+
+// Ping checks that the vault database can be opened, is not locked, and,
+// once initialized, that the passphrase is correct. Returns ErrVaultLocked
+// or ErrWrongPassphrase accordingly.
+func (v *VaultBackend) Ping() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	db, err := v.open()
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	if err := v.checkLocked(db); err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	if err := v.verifyPassphraseUnlocked(db); err != nil && !errors.Is(err, ErrVaultNotInitialized) {
+		return fmt.Errorf("vault: %w", err)
+	}
+	return nil
+}
+
 // Set encrypts and stores a secret value under the given key. If the
 // key already exists, its value is overwritten. Returns ErrVaultLocked
 // if the vault is locked.
@@ -153,11 +253,7 @@ func (v *VaultBackend) Set(key, value string) error {
 		return fmt.Errorf("vault set %q: encrypt: %w", key, err)
 	}
 
-	_, err = db.Exec(
-		"INSERT INTO secrets (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value",
-		key, encrypted,
-	)
-	if err != nil {
+	if err := upsertSecret(db, key, encrypted); err != nil {
 		return fmt.Errorf("vault set %q: %w", key, err)
 	}
 
@@ -241,9 +337,15 @@ func (v *VaultBackend) Close() error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
-	// Clear the passphrase from memory.
-	secret.ClearBytes(v.passphrase)
+	// Clear the passphrase and derived key from memory.
+	secret.Wipe(v.passphrase)
 	v.passphrase = nil
+	v.identities = nil
+	v.closed = true
+	if v.cipher != nil {
+		v.cipher.clear()
+		v.cipher = nil
+	}
 
 	if v.db != nil {
 		err := v.db.Close()
@@ -299,6 +401,30 @@ func (v *VaultBackend) Initialize() error {
 		return fmt.Errorf("vault init: checking existing token: %w", err)
 	}
 
+	// Vaults that already hold secrets but were never initialized keep the
+	// KDF they were written with.
+	var storedKDF string
+	err = db.QueryRow("SELECT value FROM metadata WHERE key = ?", metadataKDFKey).Scan(&storedKDF)
+	if errors.Is(err, sql.ErrNoRows) {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM secrets").Scan(&count); err != nil {
+			return fmt.Errorf("vault init: %w", err)
+		}
+		if count == 0 {
+			c, err := newVaultCipher(v.passphrase, v.kdf)
+			if err != nil {
+				return fmt.Errorf("vault init: %w", err)
+			}
+			if err := storeKDFParams(db, c.kdf); err != nil {
+				return fmt.Errorf("vault init: %w", err)
+			}
+			v.cipher.clear()
+			v.cipher = c
+		}
+	} else if err != nil {
+		return fmt.Errorf("vault init: reading KDF parameters: %w", err)
+	}
+
 	// Encrypt the verification plaintext with the current passphrase.
 	token, err := v.encrypt(metadataVerifyPlaintext)
 	if err != nil {
@@ -313,6 +439,183 @@ func (v *VaultBackend) Initialize() error {
 	return nil
 }
 
+// KDF returns the key derivation parameters the vault uses, without the
+// salt.
+func (v *VaultBackend) KDF() (KDFParams, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, err := v.open(); err != nil {
+		return KDFParams{}, fmt.Errorf("vault: %w", err)
+	}
+	kdf := v.cipher.kdf
+	kdf.Salt = nil
+	return kdf, nil
+}
+
+// Rekey re-encrypts every secret and the verification token under
+// newPassphrase, deriving keys with kdf (a fresh salt is generated). The
+// current passphrase must be correct and the vault must not be locked.
+// All values are rewritten in one transaction, so a failed rekey leaves the
+// vault unchanged.
+func (v *VaultBackend) Rekey(newPassphrase string, kdf KDFParams) error {
+	if newPassphrase == "" {
+		return fmt.Errorf("vault rekey: new passphrase must not be empty")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	db, err := v.open()
+	if err != nil {
+		return fmt.Errorf("vault rekey: %w", err)
+	}
+	if err := v.checkLocked(db); err != nil {
+		return fmt.Errorf("vault rekey: %w", err)
+	}
+	if err := v.verifyPassphraseUnlocked(db); err != nil {
+		return fmt.Errorf("vault rekey: %w", err)
+	}
+	if v.cipher.kdf.Algorithm == KDFRecipients {
+		return fmt.Errorf("vault rekey: the vault is shared with age recipients; use grant and revoke to change its members")
+	}
+
+	kdf.Salt = nil
+	newPass := []byte(newPassphrase)
+	_ = secret.Lock(newPass)
+	next, err := newVaultCipher(newPass, kdf)
+	if err != nil {
+		secret.Wipe(newPass)
+		return fmt.Errorf("vault rekey: %w", err)
+	}
+	rekeyed := false
+	defer func() {
+		if !rekeyed {
+			next.clear()
+			secret.Wipe(newPass)
+		}
+	}()
+
+	tx, err := v.reencrypt(db, next)
+	if err != nil {
+		return fmt.Errorf("vault rekey: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := storeKDFParams(tx, next.kdf); err != nil {
+		return fmt.Errorf("vault rekey: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("vault rekey: %w", err)
+	}
+
+	rekeyed = true
+	secret.Wipe(v.passphrase)
+	v.passphrase = newPass
+	v.cipher.clear()
+	v.cipher = next
+	return nil
+}
+
+// loadCipher returns the cipher for the KDF parameters stored in the
+// vault, or for legacy scrypt parameters if none are stored. For a shared
+// vault, it is the data key unwrapped with the vault's age identities.
+func (v *VaultBackend) loadCipher(db *sql.DB) (*vaultCipher, error) {
+	kdf := legacyKDFParams()
+	var stored string
+	err := db.QueryRow("SELECT value FROM metadata WHERE key = ?", metadataKDFKey).Scan(&stored)
+	switch {
+	case err == nil:
+		if kdf, err = unmarshalKDFParams(stored); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("reading KDF parameters: %w", err)
+	}
+	if kdf.Algorithm == KDFRecipients {
+		key, err := unwrapDataKey(db, v.identities)
+		if err != nil {
+			return nil, err
+		}
+		return newDataKeyCipher(key), nil
+	}
+	if len(v.passphrase) == 0 {
+		return nil, ErrVaultPassphraseRequired
+	}
+	return newVaultCipher(v.passphrase, kdf)
+}
+
+// reencrypt decrypts every secret and the verification token with the
+// current cipher and begins a transaction that rewrites them encrypted with
+// next. The caller adds its own changes and commits, or rolls back.
+func (v *VaultBackend) reencrypt(db *sql.DB, next *vaultCipher) (*sql.Tx, error) {
+	rows, err := db.Query("SELECT key, value FROM secrets")
+	if err != nil {
+		return nil, err
+	}
+	reencrypted := make(map[string]string)
+	for rows.Next() {
+		var key, encrypted string
+		if err := rows.Scan(&key, &encrypted); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		plaintext, err := v.decrypt(encrypted)
+		if err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("%q: decrypt: %w", key, err)
+		}
+		reencrypted[key], err = next.encrypt(plaintext)
+		if err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("%q: encrypt: %w", key, err)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	token, err := next.encrypt(metadataVerifyPlaintext)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting verification token: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	for key, encrypted := range reencrypted {
+		if _, err := tx.Exec("UPDATE secrets SET value = ? WHERE key = ?", encrypted, key); err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("%q: %w", key, err)
+		}
+	}
+	if _, err := tx.Exec("UPDATE metadata SET value = ? WHERE key = ?", token, metadataVerifyKey); err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("storing verification token: %w", err)
+	}
+	return tx, nil
+}
+
+// execer is implemented by *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// storeKDFParams writes kdf to the metadata table.
+func storeKDFParams(db execer, kdf KDFParams) error {
+	value, err := marshalKDFParams(kdf)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(
+		"INSERT INTO metadata (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value",
+		metadataKDFKey, value,
+	)
+	if err != nil {
+		return fmt.Errorf("storing KDF parameters: %w", err)
+	}
+	return nil
+}
+
 // IsInitialized returns true if the vault has a verification token
 // stored (i.e., vault init has been run).
 func (v *VaultBackend) IsInitialized() (bool, error) {
@@ -503,7 +806,7 @@ func (v *VaultBackend) checkLocked(db *sql.DB) error {
 // open lazily opens (or returns) the SQLite database connection and
 // ensures the secrets and metadata tables exist. Must be called with v.mu held.
 func (v *VaultBackend) open() (*sql.DB, error) {
-	if v.passphrase == nil {
+	if v.closed {
 		return nil, ErrVaultClosed
 	}
 	if v.db != nil {
@@ -531,6 +834,12 @@ func (v *VaultBackend) open() (*sql.DB, error) {
 		return nil, fmt.Errorf("initializing vault schema: %w", err)
 	}
 
+	// Vaults created before secret metadata was recorded lack its columns.
+	if err := migrateSecretsTable(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("migrating vault schema: %w", err)
+	}
+
 	// Create the metadata table for vault state (verification token, etc.).
 	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS metadata (
 		key   TEXT PRIMARY KEY NOT NULL,
@@ -541,82 +850,126 @@ func (v *VaultBackend) open() (*sql.DB, error) {
 		return nil, fmt.Errorf("initializing vault metadata schema: %w", err)
 	}
 
-	v.db = db
-	return v.db, nil
-}
-
-// ErrVaultClosed is returned when an operation is attempted on a vault whose
-// passphrase has been cleared (after Close).
-var ErrVaultClosed = errors.New("vault is closed: passphrase has been cleared from memory")
-
-// encrypt encrypts a plaintext string using age scrypt passphrase encryption
-// and returns the ASCII-armored ciphertext.
-func (v *VaultBackend) encrypt(plaintext string) (string, error) {
-	if v.passphrase == nil {
-		return "", ErrVaultClosed
+	// Create the members table of shared vaults.
+	if err := createMembersTable(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("initializing vault members schema: %w", err)
 	}
-	recipient, err := age.NewScryptRecipient(string(v.passphrase))
+
+	cipher, err := v.loadCipher(db)
 	if err != nil {
-		return "", fmt.Errorf("creating age recipient: %w", err)
+		_ = db.Close()
+		return nil, fmt.Errorf("vault key derivation: %w", err)
 	}
-	// Use a lower work factor for per-value encryption to keep operations
-	// fast. The default scrypt work factor is tuned for file encryption
-	// where you do it once; we encrypt many small values.
-	recipient.SetWorkFactor(15)
 
-	var buf bytes.Buffer
-	armorWriter := armor.NewWriter(&buf)
+	v.db = db
+	v.cipher = cipher
+	return v.db, nil
+}
+
+// secretMetadataColumns are the columns added to the secrets table to
+// record when and by whom each secret was written. Existing rows get empty
+// values, reported as unknown.
+var secretMetadataColumns = []string{"created_at", "updated_at", "updated_by"}
 
-	writer, err := age.Encrypt(armorWriter, recipient)
+// migrateSecretsTable adds any missing secretMetadataColumns to the secrets
+// table.
+func migrateSecretsTable(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA table_info(secrets)")
 	if err != nil {
-		return "", fmt.Errorf("creating age writer: %w", err)
+		return err
 	}
-
-	if _, err := io.WriteString(writer, plaintext); err != nil {
-		return "", fmt.Errorf("writing plaintext: %w", err)
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		existing[name] = true
 	}
-
-	if err := writer.Close(); err != nil {
-		return "", fmt.Errorf("closing age writer: %w", err)
+	if err := rows.Close(); err != nil {
+		return err
 	}
 
-	if err := armorWriter.Close(); err != nil {
-		return "", fmt.Errorf("closing armor writer: %w", err)
+	for _, col := range secretMetadataColumns {
+		if existing[col] {
+			continue
+		}
+		if _, err := db.Exec("ALTER TABLE secrets ADD COLUMN " + col + " TEXT NOT NULL DEFAULT ''"); err != nil {
+			return fmt.Errorf("adding column %s: %w", col, err)
+		}
 	}
-
-	return buf.String(), nil
+	return nil
 }
 
-// decrypt decrypts an ASCII-armored age ciphertext using the vault passphrase
-// and returns the plaintext string.
-func (v *VaultBackend) decrypt(armored string) (string, error) {
-	if v.passphrase == nil {
-		return "", ErrVaultClosed
-	}
-	identity, err := age.NewScryptIdentity(string(v.passphrase))
-	if err != nil {
-		return "", fmt.Errorf("creating age identity: %w", err)
-	}
+// upsertSecret stores an encrypted value under key, recording the write
+// time and writer. created_at is only set when the key is new.
+func upsertSecret(db *sql.DB, key, encrypted string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := db.Exec(
+		`INSERT INTO secrets (key, value, created_at, updated_at, updated_by) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at, updated_by = excluded.updated_by`,
+		key, encrypted, now, now, writerIdentity(),
+	)
+	return err
+}
 
-	armorReader := armor.NewReader(strings.NewReader(armored))
+// Metadata returns when key was created and last updated, and by whom.
+// Times are zero for secrets stored before the vault recorded them.
+// Returns ErrNotFound if the key does not exist, or ErrVaultLocked if the
+// vault is locked.
+func (v *VaultBackend) Metadata(key string) (Metadata, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
 
-	reader, err := age.Decrypt(armorReader, identity)
+	db, err := v.open()
 	if err != nil {
-		return "", fmt.Errorf("decrypting: %w", err)
+		return Metadata{}, fmt.Errorf("vault metadata: %w", err)
+	}
+	if err := v.checkLocked(db); err != nil {
+		return Metadata{}, fmt.Errorf("vault metadata: %w", err)
 	}
 
-	plaintext, err := io.ReadAll(reader)
+	var created, updated, updatedBy string
+	err = db.QueryRow("SELECT created_at, updated_at, updated_by FROM secrets WHERE key = ?", key).Scan(&created, &updated, &updatedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Metadata{}, ErrNotFound
+	}
 	if err != nil {
-		return "", fmt.Errorf("reading plaintext: %w", err)
+		return Metadata{}, fmt.Errorf("vault metadata %q: %w", key, err)
 	}
 
-	// Convert to string before clearing the byte slice. The string will
-	// hold its own copy; clearing the original byte slice reduces the
-	// number of copies of the secret in memory.
-	result := string(plaintext)
-	secret.ClearBytes(plaintext)
+	md := Metadata{UpdatedBy: updatedBy}
+	md.Created, _ = time.Parse(time.RFC3339, created)
+	md.Updated, _ = time.Parse(time.RFC3339, updated)
+	return md, nil
+}
+
+// ErrVaultClosed is returned when an operation is attempted on a vault whose
+// passphrase has been cleared (after Close).
+var ErrVaultClosed = errors.New("vault is closed: passphrase has been cleared from memory")
 
-	return result, nil
+// encrypt encrypts a plaintext string with the vault's KDF. Must be called
+// with v.mu held, after open.
+func (v *VaultBackend) encrypt(plaintext string) (string, error) {
+	if v.closed {
+		return "", ErrVaultClosed
+	}
+	return v.cipher.encrypt(plaintext)
+}
+
+// decrypt decrypts a stored value and returns the plaintext string. Must be
+// called with v.mu held, after open.
+func (v *VaultBackend) decrypt(stored string) (string, error) {
+	if v.closed {
+		return "", ErrVaultClosed
+	}
+	return v.cipher.decrypt(stored)
 }
 
 // DBPath returns the path to the vault database file.
@@ -721,11 +1074,7 @@ func (v *VaultBackend) Import(export *VaultExport) (int, error) {
 			return count, fmt.Errorf("vault import: encrypting %q: %w", key, err)
 		}
 
-		_, err = db.Exec(
-			"INSERT INTO secrets (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value",
-			key, encrypted,
-		)
-		if err != nil {
+		if err := upsertSecret(db, key, encrypted); err != nil {
 			return count, fmt.Errorf("vault import: storing %q: %w", key, err)
 		}
 		count++
@@ -0,0 +1,333 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/xcke/envref/internal/secret"
+)
+
+// Key derivation functions supported by the vault.
+const (
+	// KDFArgon2id derives one key from the passphrase with Argon2id and
+	// encrypts each value with XChaCha20-Poly1305. It is the default for
+	// new vaults.
+	KDFArgon2id = "argon2id"
+
+	// KDFScrypt encrypts each value with age's scrypt passphrase encryption,
+	// deriving a key per value. Vaults created before the KDF was
+	// configurable use it.
+	KDFScrypt = "scrypt"
+
+	// KDFRecipients marks a vault shared with age recipients. No key is
+	// derived: values are encrypted with XChaCha20-Poly1305 under a random
+	// data key, wrapped for each member (see VaultBackend.Grant). It cannot
+	// be chosen for a new vault.
+	KDFRecipients = "recipients"
+)
+
+// KnownKDFs lists the key derivation functions supported by the vault.
+var KnownKDFs = []string{KDFArgon2id, KDFScrypt}
+
+// Default key derivation parameters. The Argon2id defaults follow the
+// second recommended option of RFC 9106 (64 MiB, 3 passes).
+const (
+	DefaultArgon2Time    uint32 = 3
+	DefaultArgon2Memory  uint32 = 64 * 1024 // KiB
+	DefaultArgon2Threads uint8  = 4
+
+	// DefaultScryptWorkFactor is lower than age's default, which is tuned
+	// for encrypting a file once rather than many small values.
+	DefaultScryptWorkFactor = 15
+)
+
+// metadataKDFKey is the metadata key that stores the vault's KDFParams as
+// JSON. Vaults without it use scrypt with DefaultScryptWorkFactor.
+const metadataKDFKey = "__envref_kdf__"
+
+// sealedPrefix marks a value encrypted with the Argon2id-derived key. Values
+// without it are age-armored scrypt ciphertexts.
+const sealedPrefix = "envref:xchacha20poly1305:"
+
+// argon2KeyLen is the length of the derived key (XChaCha20-Poly1305 key).
+const argon2KeyLen = chacha20poly1305.KeySize
+
+// argon2SaltLen is the length of the random salt used for Argon2id.
+const argon2SaltLen = 16
+
+// KDFParams selects how the vault derives encryption keys from its
+// passphrase. Zero parameters are replaced by the defaults.
+type KDFParams struct {
+	// Algorithm is KDFArgon2id, KDFScrypt, or, for a shared vault,
+	// KDFRecipients.
+	Algorithm string `json:"algorithm"`
+
+	// Time, Memory (in KiB), and Threads are the Argon2id cost parameters.
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+
+	// Salt is the Argon2id salt, generated when the vault is initialized or
+	// rekeyed.
+	Salt []byte `json:"salt,omitempty"`
+
+	// WorkFactor is the scrypt work factor (log2 of N).
+	WorkFactor int `json:"work_factor,omitempty"`
+}
+
+// DefaultKDFParams returns the KDF parameters used for new vaults.
+func DefaultKDFParams() KDFParams {
+	return KDFParams{
+		Algorithm: KDFArgon2id,
+		Time:      DefaultArgon2Time,
+		Memory:    DefaultArgon2Memory,
+		Threads:   DefaultArgon2Threads,
+	}
+}
+
+// legacyKDFParams returns the parameters of vaults that store none.
+func legacyKDFParams() KDFParams {
+	return KDFParams{Algorithm: KDFScrypt, WorkFactor: DefaultScryptWorkFactor}
+}
+
+// withDefaults returns p with zero cost parameters replaced by the defaults
+// for its algorithm.
+func (p KDFParams) withDefaults() KDFParams {
+	switch p.Algorithm {
+	case "", KDFArgon2id:
+		p.Algorithm = KDFArgon2id
+		if p.Time == 0 {
+			p.Time = DefaultArgon2Time
+		}
+		if p.Memory == 0 {
+			p.Memory = DefaultArgon2Memory
+		}
+		if p.Threads == 0 {
+			p.Threads = DefaultArgon2Threads
+		}
+	case KDFScrypt:
+		if p.WorkFactor == 0 {
+			p.WorkFactor = DefaultScryptWorkFactor
+		}
+	}
+	return p
+}
+
+// Validate checks that the algorithm is known and its cost parameters are
+// usable.
+func (p KDFParams) Validate() error {
+	p = p.withDefaults()
+	switch p.Algorithm {
+	case KDFArgon2id:
+		if p.Memory < 8*uint32(p.Threads) {
+			return fmt.Errorf("argon2id memory must be at least %d KiB for %d threads", 8*uint32(p.Threads), p.Threads)
+		}
+	case KDFScrypt:
+		if p.WorkFactor < 10 || p.WorkFactor > 22 {
+			return fmt.Errorf("scrypt work factor must be between 10 and 22, got %d", p.WorkFactor)
+		}
+	case KDFRecipients:
+	default:
+		return fmt.Errorf("unknown KDF %q (supported: %s)", p.Algorithm, strings.Join(KnownKDFs, ", "))
+	}
+	return nil
+}
+
+// String describes the algorithm and its cost parameters, without the salt.
+func (p KDFParams) String() string {
+	p = p.withDefaults()
+	switch p.Algorithm {
+	case KDFScrypt:
+		return fmt.Sprintf("scrypt (work factor %d)", p.WorkFactor)
+	case KDFRecipients:
+		return "a data key wrapped for age recipients"
+	}
+	return fmt.Sprintf("argon2id (time %d, memory %d KiB, threads %d)", p.Time, p.Memory, p.Threads)
+}
+
+// vaultCipher encrypts and decrypts vault values for one passphrase and
+// set of KDF parameters.
+type vaultCipher struct {
+	passphrase []byte
+	kdf        KDFParams
+	key        []byte // Argon2id-derived key, computed on first use, or data key
+}
+
+// newVaultCipher returns a cipher for passphrase and kdf. For Argon2id, a
+// missing salt is generated.
+func newVaultCipher(passphrase []byte, kdf KDFParams) (*vaultCipher, error) {
+	kdf = kdf.withDefaults()
+	if err := kdf.Validate(); err != nil {
+		return nil, err
+	}
+	if kdf.Algorithm == KDFRecipients {
+		return nil, fmt.Errorf("a vault is shared with %s by granting members access, not by vault init", KDFRecipients)
+	}
+	if kdf.Algorithm == KDFArgon2id && len(kdf.Salt) == 0 {
+		kdf.Salt = make([]byte, argon2SaltLen)
+		if _, err := rand.Read(kdf.Salt); err != nil {
+			return nil, fmt.Errorf("generating salt: %w", err)
+		}
+	}
+	return &vaultCipher{passphrase: passphrase, kdf: kdf}, nil
+}
+
+// newDataKeyCipher returns the cipher of a shared vault with data key key.
+func newDataKeyCipher(key []byte) *vaultCipher {
+	_ = secret.Lock(key)
+	return &vaultCipher{kdf: KDFParams{Algorithm: KDFRecipients}, key: key}
+}
+
+// derivedKey returns the Argon2id key, deriving it on first use. For a
+// shared vault, it is the data key.
+func (c *vaultCipher) derivedKey() []byte {
+	if c.key == nil {
+		c.key = argon2.IDKey(c.passphrase, c.kdf.Salt, c.kdf.Time, c.kdf.Memory, c.kdf.Threads, argon2KeyLen)
+		_ = secret.Lock(c.key)
+	}
+	return c.key
+}
+
+// clear removes the derived key from memory.
+func (c *vaultCipher) clear() {
+	secret.Wipe(c.key)
+	c.key = nil
+}
+
+// encrypt encrypts plaintext with the cipher's KDF.
+func (c *vaultCipher) encrypt(plaintext string) (string, error) {
+	if c.kdf.Algorithm == KDFScrypt {
+		return c.encryptScrypt(plaintext)
+	}
+
+	aead, err := chacha20poly1305.NewX(c.derivedKey())
+	if err != nil {
+		return "", fmt.Errorf("creating cipher: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating nonce: %w", err)
+	}
+	buf := []byte(plaintext)
+	defer secret.ClearBytes(buf)
+	sealed := aead.Seal(nonce, nonce, buf, nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt decrypts a stored value. Values are decrypted according to their
+// format, so a vault can hold scrypt values written before it switched to
+// Argon2id.
+func (c *vaultCipher) decrypt(stored string) (string, error) {
+	if !strings.HasPrefix(stored, sealedPrefix) {
+		return c.decryptScrypt(stored)
+	}
+	if c.kdf.Algorithm == KDFScrypt {
+		return "", fmt.Errorf("value was encrypted with argon2id, but the vault uses %s", c.kdf.Algorithm)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(stored[len(sealedPrefix):])
+	if err != nil {
+		return "", fmt.Errorf("decoding ciphertext: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(c.derivedKey())
+	if err != nil {
+		return "", fmt.Errorf("creating cipher: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("decrypting: ciphertext too short")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypting: %w", err)
+	}
+	return secret.TakeString(plaintext), nil
+}
+
+// encryptScrypt encrypts a plaintext string using age scrypt passphrase
+// encryption and returns the ASCII-armored ciphertext.
+func (c *vaultCipher) encryptScrypt(plaintext string) (string, error) {
+	recipient, err := age.NewScryptRecipient(string(c.passphrase))
+	if err != nil {
+		return "", fmt.Errorf("creating age recipient: %w", err)
+	}
+	recipient.SetWorkFactor(c.kdf.WorkFactor)
+
+	var buf bytes.Buffer
+	armorWriter := armor.NewWriter(&buf)
+
+	writer, err := age.Encrypt(armorWriter, recipient)
+	if err != nil {
+		return "", fmt.Errorf("creating age writer: %w", err)
+	}
+
+	if _, err := io.WriteString(writer, plaintext); err != nil {
+		return "", fmt.Errorf("writing plaintext: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("closing age writer: %w", err)
+	}
+
+	if err := armorWriter.Close(); err != nil {
+		return "", fmt.Errorf("closing armor writer: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// decryptScrypt decrypts an ASCII-armored age ciphertext using the
+// passphrase and returns the plaintext string.
+func (c *vaultCipher) decryptScrypt(armored string) (string, error) {
+	identity, err := age.NewScryptIdentity(string(c.passphrase))
+	if err != nil {
+		return "", fmt.Errorf("creating age identity: %w", err)
+	}
+
+	armorReader := armor.NewReader(strings.NewReader(armored))
+
+	reader, err := age.Decrypt(armorReader, identity)
+	if err != nil {
+		return "", fmt.Errorf("decrypting: %w", err)
+	}
+
+	plaintext, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("reading plaintext: %w", err)
+	}
+
+	// The string holds its own copy; clearing the byte slice reduces the
+	// number of copies of the secret in memory.
+	return secret.TakeString(plaintext), nil
+}
+
+// marshalKDFParams encodes p for the metadata table.
+func marshalKDFParams(p KDFParams) (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("encoding KDF parameters: %w", err)
+	}
+	return string(data), nil
+}
+
+// unmarshalKDFParams decodes KDF parameters stored in the metadata table.
+func unmarshalKDFParams(s string) (KDFParams, error) {
+	var p KDFParams
+	if err := json.Unmarshal([]byte(s), &p); err != nil {
+		return KDFParams{}, fmt.Errorf("decoding KDF parameters: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return KDFParams{}, err
+	}
+	return p.withDefaults(), nil
+}
@@ -0,0 +1,204 @@
+package backend
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cheapArgon2 keeps Argon2id fast in tests.
+var cheapArgon2 = KDFParams{Algorithm: KDFArgon2id, Time: 1, Memory: 1024, Threads: 1}
+
+// storedValue returns the encrypted value stored for key.
+func storedValue(t *testing.T, v *VaultBackend, key string) string {
+	t.Helper()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	db, err := v.open()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	var value string
+	if err := db.QueryRow("SELECT value FROM secrets WHERE key = ?", key).Scan(&value); err != nil {
+		t.Fatalf("reading %s: %v", key, err)
+	}
+	return value
+}
+
+func TestKDFParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  KDFParams
+		wantErr string
+	}{
+		{"defaults", KDFParams{}, ""},
+		{"argon2id", cheapArgon2, ""},
+		{"scrypt", KDFParams{Algorithm: KDFScrypt, WorkFactor: 18}, ""},
+		{"scrypt default work factor", KDFParams{Algorithm: KDFScrypt}, ""},
+		{"unknown", KDFParams{Algorithm: "pbkdf2"}, "unknown KDF"},
+		{"low memory", KDFParams{Algorithm: KDFArgon2id, Memory: 8, Threads: 4}, "memory must be at least 32 KiB"},
+		{"low work factor", KDFParams{Algorithm: KDFScrypt, WorkFactor: 5}, "work factor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Validate: got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVaultBackend_InitializeDefaultsToArgon2id(t *testing.T) {
+	v := testVault(t)
+	if err := v.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	kdf, err := v.KDF()
+	if err != nil {
+		t.Fatalf("KDF: %v", err)
+	}
+	if kdf.Algorithm != KDFArgon2id || kdf.Memory != DefaultArgon2Memory || kdf.Salt != nil {
+		t.Errorf("KDF: got %+v, want default argon2id without salt", kdf)
+	}
+
+	if err := v.Set("api_key", "s3cret"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if stored := storedValue(t, v, "api_key"); !strings.HasPrefix(stored, sealedPrefix) {
+		t.Errorf("stored value not sealed with argon2id key: %q", stored)
+	}
+}
+
+func TestVaultBackend_KDFPersists(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "vault.db")
+	v, err := NewVaultBackend("pass", WithVaultPath(dbPath), WithVaultKDF(cheapArgon2))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	if err := v.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if err := v.Set("k", "value"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	_ = v.Close()
+
+	// The KDF option only applies to Initialize; reopening uses the stored
+	// parameters.
+	v2, err := NewVaultBackend("pass", WithVaultPath(dbPath), WithVaultKDF(KDFParams{Algorithm: KDFScrypt}))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	defer func() { _ = v2.Close() }()
+	if err := v2.VerifyPassphrase(); err != nil {
+		t.Fatalf("VerifyPassphrase: %v", err)
+	}
+	if got, err := v2.Get("k"); err != nil || got != "value" {
+		t.Fatalf("Get: got %q, %v", got, err)
+	}
+	if kdf, _ := v2.KDF(); kdf.Algorithm != KDFArgon2id || kdf.Memory != cheapArgon2.Memory {
+		t.Errorf("KDF after reopen: got %+v", kdf)
+	}
+
+	wrong, err := NewVaultBackend("wrong", WithVaultPath(dbPath))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	defer func() { _ = wrong.Close() }()
+	if err := wrong.VerifyPassphrase(); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("VerifyPassphrase with wrong passphrase: got %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestVaultBackend_LegacyVaultKeepsScrypt(t *testing.T) {
+	v := testVault(t)
+
+	// Secrets written before initialization use the legacy scrypt format.
+	if err := v.Set("k", "value"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := v.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if kdf, _ := v.KDF(); kdf.Algorithm != KDFScrypt {
+		t.Errorf("KDF: got %q, want scrypt", kdf.Algorithm)
+	}
+	if stored := storedValue(t, v, "k"); strings.HasPrefix(stored, sealedPrefix) {
+		t.Errorf("legacy value was rewritten: %q", stored)
+	}
+	if got, err := v.Get("k"); err != nil || got != "value" {
+		t.Fatalf("Get: got %q, %v", got, err)
+	}
+}
+
+func TestVaultBackend_Rekey(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "vault.db")
+	v, err := NewVaultBackend("old-pass", WithVaultPath(dbPath), WithVaultKDF(KDFParams{Algorithm: KDFScrypt}))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	if err := v.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	for _, k := range []string{"a", "b"} {
+		if err := v.Set(k, "value-"+k); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+
+	if err := v.Rekey("new-pass", cheapArgon2); err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+	if got, err := v.Get("a"); err != nil || got != "value-a" {
+		t.Fatalf("Get after Rekey: got %q, %v", got, err)
+	}
+	if stored := storedValue(t, v, "b"); !strings.HasPrefix(stored, sealedPrefix) {
+		t.Errorf("value not re-encrypted: %q", stored)
+	}
+	_ = v.Close()
+
+	old, err := NewVaultBackend("old-pass", WithVaultPath(dbPath))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	defer func() { _ = old.Close() }()
+	if err := old.VerifyPassphrase(); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("old passphrase: got %v, want ErrWrongPassphrase", err)
+	}
+
+	reopened, err := NewVaultBackend("new-pass", WithVaultPath(dbPath))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	defer func() { _ = reopened.Close() }()
+	if got, err := reopened.Get("b"); err != nil || got != "value-b" {
+		t.Fatalf("Get with new passphrase: got %q, %v", got, err)
+	}
+}
+
+func TestVaultBackend_RekeyWrongPassphrase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "vault.db")
+	v, err := NewVaultBackend("pass", WithVaultPath(dbPath), WithVaultKDF(cheapArgon2))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	if err := v.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	_ = v.Close()
+
+	wrong, err := NewVaultBackend("wrong", WithVaultPath(dbPath))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	defer func() { _ = wrong.Close() }()
+	if err := wrong.Rekey("new-pass", cheapArgon2); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Rekey with wrong passphrase: got %v, want ErrWrongPassphrase", err)
+	}
+}
@@ -0,0 +1,372 @@
+package backend
+
+// This file implements shared vaults: a vault whose values are encrypted
+// with a random data key, wrapped with age for the X25519 public key of each
+// member, so that a team can commit one vault file and each member opens it
+// with their own age identity instead of a shared passphrase.
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/xcke/envref/internal/secret"
+)
+
+// ErrVaultIdentityRequired is returned when a shared vault is opened without
+// age identities.
+var ErrVaultIdentityRequired = errors.New("vault is shared with age recipients: set ENVREF_VAULT_IDENTITY or config.identity to your age identity file")
+
+// ErrNotVaultMember is returned when none of the age identities a shared
+// vault is opened with belongs to one of its members.
+var ErrNotVaultMember = errors.New("none of your age identities is a member of the vault: ask a member to run 'envref vault grant'")
+
+// ErrVaultPassphraseRequired is returned when a vault encrypted with a
+// passphrase is opened with age identities only.
+var ErrVaultPassphraseRequired = errors.New("vault is encrypted with a passphrase: set ENVREF_VAULT_PASSPHRASE or config.passphrase")
+
+// VaultMember is a member of a shared vault.
+type VaultMember struct {
+	// Name identifies the member, e.g. their name in the team roster.
+	Name string
+
+	// Recipient is the member's age X25519 public key (age1...).
+	Recipient string
+}
+
+// createMembersTable creates the table holding the data key of a shared
+// vault, wrapped for each member.
+func createMembersTable(db execer) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS members (
+		name        TEXT PRIMARY KEY NOT NULL,
+		recipient   TEXT NOT NULL,
+		wrapped_key TEXT NOT NULL
+	)`)
+	return err
+}
+
+// IsSharedVault reports whether the vault database at path is shared with
+// age recipients, and so is opened with an age identity rather than a
+// passphrase. A missing database is not shared.
+func IsSharedVault(path string) (bool, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return false, fmt.Errorf("opening vault database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var tables int
+	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'metadata'").Scan(&tables)
+	if err != nil || tables == 0 {
+		return false, err
+	}
+	var stored string
+	err = db.QueryRow("SELECT value FROM metadata WHERE key = ?", metadataKDFKey).Scan(&stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading KDF parameters: %w", err)
+	}
+	kdf, err := unmarshalKDFParams(stored)
+	if err != nil {
+		return false, err
+	}
+	return kdf.Algorithm == KDFRecipients, nil
+}
+
+// Shared reports whether the vault is shared with age recipients.
+func (v *VaultBackend) Shared() (bool, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, err := v.open(); err != nil {
+		return false, fmt.Errorf("vault: %w", err)
+	}
+	return v.cipher.kdf.Algorithm == KDFRecipients, nil
+}
+
+// Members returns the members of a shared vault, sorted by name. It is
+// empty for a vault encrypted with a passphrase.
+func (v *VaultBackend) Members() ([]VaultMember, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	db, err := v.open()
+	if err != nil {
+		return nil, fmt.Errorf("vault members: %w", err)
+	}
+	members, err := readMembers(db)
+	if err != nil {
+		return nil, fmt.Errorf("vault members: %w", err)
+	}
+	return members, nil
+}
+
+// Grant gives members access to the vault, replacing the public key of a
+// member granted before. The first grant turns a vault encrypted with a
+// passphrase into a shared vault: every value is re-encrypted with a new
+// data key, and the passphrase no longer opens it. To keep the caller from
+// locking themselves out, one of the vault's age identities must then
+// belong to one of members.
+func (v *VaultBackend) Grant(members ...VaultMember) error {
+	if len(members) == 0 {
+		return fmt.Errorf("vault grant: no members given")
+	}
+	for _, m := range members {
+		if strings.TrimSpace(m.Name) == "" {
+			return fmt.Errorf("vault grant: member name must not be empty")
+		}
+		if _, err := age.ParseX25519Recipient(m.Recipient); err != nil {
+			return fmt.Errorf("vault grant %s: invalid age public key: %w", m.Name, err)
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	db, err := v.open()
+	if err != nil {
+		return fmt.Errorf("vault grant: %w", err)
+	}
+	if err := v.checkLocked(db); err != nil {
+		return fmt.Errorf("vault grant: %w", err)
+	}
+	if err := v.verifyPassphraseUnlocked(db); err != nil {
+		return fmt.Errorf("vault grant: %w", err)
+	}
+
+	if v.cipher.kdf.Algorithm == KDFRecipients {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("vault grant: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+		if err := storeMembers(tx, v.cipher.key, members); err != nil {
+			return fmt.Errorf("vault grant: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("vault grant: %w", err)
+		}
+		return nil
+	}
+
+	if !slices.ContainsFunc(members, v.isOwnRecipient) {
+		return fmt.Errorf("vault grant: the passphrase no longer opens the vault once it is shared, but none of your age identities (ENVREF_VAULT_IDENTITY or config.identity) is among the members granted; grant your own public key too")
+	}
+	next, err := newSharedCipher()
+	if err != nil {
+		return fmt.Errorf("vault grant: %w", err)
+	}
+	if err := v.rewrap(db, next, members, false); err != nil {
+		next.clear()
+		return fmt.Errorf("vault grant: %w", err)
+	}
+	return nil
+}
+
+// Revoke removes members from a shared vault. The data key is replaced and
+// every value re-encrypted, so that a copy of the old key kept by a
+// revoked member opens nothing written after. At least one member must
+// remain.
+func (v *VaultBackend) Revoke(names ...string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	db, err := v.open()
+	if err != nil {
+		return fmt.Errorf("vault revoke: %w", err)
+	}
+	if err := v.checkLocked(db); err != nil {
+		return fmt.Errorf("vault revoke: %w", err)
+	}
+	if v.cipher.kdf.Algorithm != KDFRecipients {
+		return fmt.Errorf("vault revoke: the vault is not shared; members are added with vault grant")
+	}
+	if err := v.verifyPassphraseUnlocked(db); err != nil {
+		return fmt.Errorf("vault revoke: %w", err)
+	}
+
+	members, err := readMembers(db)
+	if err != nil {
+		return fmt.Errorf("vault revoke: %w", err)
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(members, func(m VaultMember) bool { return m.Name == name }) {
+			return fmt.Errorf("vault revoke: %q is not a member of the vault", name)
+		}
+	}
+	remaining := slices.DeleteFunc(members, func(m VaultMember) bool { return slices.Contains(names, m.Name) })
+	if len(remaining) == 0 {
+		return fmt.Errorf("vault revoke: at least one member must keep access")
+	}
+
+	next, err := newSharedCipher()
+	if err != nil {
+		return fmt.Errorf("vault revoke: %w", err)
+	}
+	if err := v.rewrap(db, next, remaining, true); err != nil {
+		next.clear()
+		return fmt.Errorf("vault revoke: %w", err)
+	}
+	return nil
+}
+
+// rewrap re-encrypts the vault with the shared cipher next and stores its
+// data key for members, in one transaction. With replace, other members
+// are removed. On success, next becomes the vault's cipher.
+func (v *VaultBackend) rewrap(db *sql.DB, next *vaultCipher, members []VaultMember, replace bool) error {
+	tx, err := v.reencrypt(db, next)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if replace {
+		if _, err := tx.Exec("DELETE FROM members"); err != nil {
+			return err
+		}
+	}
+	if err := storeMembers(tx, next.key, members); err != nil {
+		return err
+	}
+	if err := storeKDFParams(tx, next.kdf); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	v.cipher.clear()
+	v.cipher = next
+	return nil
+}
+
+// isOwnRecipient reports whether m is the public key of one of the vault's
+// age identities.
+func (v *VaultBackend) isOwnRecipient(m VaultMember) bool {
+	for _, id := range v.identities {
+		if x, ok := id.(*age.X25519Identity); ok && x.Recipient().String() == m.Recipient {
+			return true
+		}
+	}
+	return false
+}
+
+// newSharedCipher returns the cipher of a new random data key.
+func newSharedCipher() (*vaultCipher, error) {
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating data key: %w", err)
+	}
+	return newDataKeyCipher(key), nil
+}
+
+// readMembers returns the members of the vault, sorted by name.
+func readMembers(db *sql.DB) ([]VaultMember, error) {
+	rows, err := db.Query("SELECT name, recipient FROM members ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var members []VaultMember
+	for rows.Next() {
+		var m VaultMember
+		if err := rows.Scan(&m.Name, &m.Recipient); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// storeMembers stores key wrapped for each of members.
+func storeMembers(tx *sql.Tx, key []byte, members []VaultMember) error {
+	for _, m := range members {
+		wrapped, err := wrapDataKey(key, m.Recipient)
+		if err != nil {
+			return fmt.Errorf("wrapping data key for %s: %w", m.Name, err)
+		}
+		_, err = tx.Exec(
+			`INSERT INTO members (name, recipient, wrapped_key) VALUES (?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET recipient = excluded.recipient, wrapped_key = excluded.wrapped_key`,
+			m.Name, m.Recipient, wrapped,
+		)
+		if err != nil {
+			return fmt.Errorf("storing member %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// wrapDataKey encrypts key for the age public key recipient and returns
+// the ASCII-armored ciphertext.
+func wrapDataKey(key []byte, recipient string) (string, error) {
+	r, err := age.ParseX25519Recipient(recipient)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	armorWriter := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armorWriter, r)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(key); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	if err := armorWriter.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// unwrapDataKey returns the data key of a shared vault, decrypted from the
+// first member entry one of identities can open.
+func unwrapDataKey(db *sql.DB, identities []age.Identity) ([]byte, error) {
+	if len(identities) == 0 {
+		return nil, ErrVaultIdentityRequired
+	}
+	rows, err := db.Query("SELECT wrapped_key FROM members")
+	if err != nil {
+		return nil, fmt.Errorf("reading members: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var wrapped string
+		if err := rows.Scan(&wrapped); err != nil {
+			return nil, fmt.Errorf("reading members: %w", err)
+		}
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(wrapped)), identities...)
+		if err != nil {
+			continue
+		}
+		key, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("unwrapping data key: %w", err)
+		}
+		if len(key) != chacha20poly1305.KeySize {
+			secret.Wipe(key)
+			return nil, fmt.Errorf("unwrapping data key: unexpected key length %d", len(key))
+		}
+		return key, nil
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading members: %w", err)
+	}
+	return nil, ErrNotVaultMember
+}
@@ -0,0 +1,154 @@
+package backend
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// newTestIdentity generates an age identity and its vault member entry.
+func newTestIdentity(t *testing.T, name string) (*age.X25519Identity, VaultMember) {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity: %v", err)
+	}
+	return id, VaultMember{Name: name, Recipient: id.Recipient().String()}
+}
+
+// openSharedVault opens the vault at path with the given identities.
+func openSharedVault(t *testing.T, path string, ids ...age.Identity) *VaultBackend {
+	t.Helper()
+	v, err := NewVaultBackend("", WithVaultPath(path), WithVaultIdentities(ids...))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	t.Cleanup(func() { _ = v.Close() })
+	return v
+}
+
+func TestVaultBackend_GrantSharesVault(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "vault.db")
+	alice, aliceMember := newTestIdentity(t, "alice")
+	bob, bobMember := newTestIdentity(t, "bob")
+
+	v, err := NewVaultBackend("pass", WithVaultPath(dbPath), WithVaultKDF(cheapArgon2), WithVaultIdentities(alice))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	if err := v.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if err := v.Set("api_key", "s3cret"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// Granting only others would lock the caller out.
+	if err := v.Grant(bobMember); err == nil || !strings.Contains(err.Error(), "grant your own public key") {
+		t.Fatalf("Grant without own key: got %v", err)
+	}
+	if shared, _ := IsSharedVault(dbPath); shared {
+		t.Fatal("vault shared after a refused grant")
+	}
+
+	if err := v.Grant(aliceMember, bobMember); err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	if got, err := v.Get("api_key"); err != nil || got != "s3cret" {
+		t.Errorf("Get after grant: got %q, %v", got, err)
+	}
+	_ = v.Close()
+
+	if shared, err := IsSharedVault(dbPath); err != nil || !shared {
+		t.Fatalf("IsSharedVault: got %v, %v", shared, err)
+	}
+
+	// Each member opens the vault with their own identity.
+	vb := openSharedVault(t, dbPath, bob)
+	if err := vb.VerifyPassphrase(); err != nil {
+		t.Fatalf("VerifyPassphrase as bob: %v", err)
+	}
+	if got, err := vb.Get("api_key"); err != nil || got != "s3cret" {
+		t.Errorf("Get as bob: got %q, %v", got, err)
+	}
+	members, err := vb.Members()
+	if err != nil || len(members) != 2 || members[0].Name != "alice" || members[1].Name != "bob" {
+		t.Errorf("Members: got %+v, %v", members, err)
+	}
+
+	// The passphrase no longer opens it, and rekeying is refused.
+	vp, err := NewVaultBackend("pass", WithVaultPath(dbPath))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	defer func() { _ = vp.Close() }()
+	if _, err := vp.Get("api_key"); !errors.Is(err, ErrVaultIdentityRequired) {
+		t.Errorf("Get with passphrase: got %v, want ErrVaultIdentityRequired", err)
+	}
+	if err := vb.Rekey("new", cheapArgon2); err == nil || !strings.Contains(err.Error(), "grant and revoke") {
+		t.Errorf("Rekey on shared vault: got %v", err)
+	}
+}
+
+func TestVaultBackend_RevokeRotatesDataKey(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "vault.db")
+	alice, aliceMember := newTestIdentity(t, "alice")
+	bob, bobMember := newTestIdentity(t, "bob")
+
+	v, err := NewVaultBackend("pass", WithVaultPath(dbPath), WithVaultKDF(cheapArgon2), WithVaultIdentities(alice))
+	if err != nil {
+		t.Fatalf("NewVaultBackend: %v", err)
+	}
+	defer func() { _ = v.Close() }()
+	if err := v.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if err := v.Grant(aliceMember, bobMember); err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	if err := v.Set("api_key", "s3cret"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	before := storedValue(t, v, "api_key")
+
+	if err := v.Revoke("carol"); err == nil || !strings.Contains(err.Error(), `"carol" is not a member`) {
+		t.Errorf("Revoke unknown member: got %v", err)
+	}
+	if err := v.Revoke("alice", "bob"); err == nil || !strings.Contains(err.Error(), "at least one member") {
+		t.Errorf("Revoke all members: got %v", err)
+	}
+	if err := v.Revoke("bob"); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if storedValue(t, v, "api_key") == before {
+		t.Error("value not re-encrypted after revoke")
+	}
+	if got, err := v.Get("api_key"); err != nil || got != "s3cret" {
+		t.Errorf("Get after revoke: got %q, %v", got, err)
+	}
+
+	vb := openSharedVault(t, dbPath, bob)
+	if _, err := vb.Get("api_key"); !errors.Is(err, ErrNotVaultMember) {
+		t.Errorf("Get as revoked member: got %v, want ErrNotVaultMember", err)
+	}
+}
+
+func TestVaultBackend_GrantValidatesMembers(t *testing.T) {
+	v := testVault(t)
+	if err := v.Grant(VaultMember{Name: "alice", Recipient: "not-a-key"}); err == nil || !strings.Contains(err.Error(), "invalid age public key") {
+		t.Errorf("Grant invalid key: got %v", err)
+	}
+	if err := v.Revoke("alice"); err == nil || !strings.Contains(err.Error(), "not shared") {
+		t.Errorf("Revoke on passphrase vault: got %v", err)
+	}
+}
+
+func TestIsSharedVault_Missing(t *testing.T) {
+	shared, err := IsSharedVault(filepath.Join(t.TempDir(), "missing.db"))
+	if err != nil || shared {
+		t.Errorf("IsSharedVault: got %v, %v", shared, err)
+	}
+}
@@ -1,11 +1,13 @@
 package backend
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 // testVault creates a VaultBackend in a temporary directory for testing.
@@ -1117,3 +1119,128 @@ func searchString(s, substr string) bool {
 	}
 	return false
 }
+
+func TestVaultBackend_GetMany(t *testing.T) {
+	v := testVault(t)
+	if err := v.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	for k, val := range map[string]string{"a": "1", "b": "2", "c": "3"} {
+		if err := v.Set(k, val); err != nil {
+			t.Fatalf("Set(%q): %v", k, err)
+		}
+	}
+
+	got, err := v.GetMany([]string{"a", "c", "missing"})
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if len(got) != 2 || got["a"] != "1" || got["c"] != "3" {
+		t.Errorf("GetMany = %v, want map[a:1 c:3]", got)
+	}
+
+	if err := v.Lock(); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if _, err := v.GetMany([]string{"a"}); !errors.Is(err, ErrVaultLocked) {
+		t.Errorf("GetMany on locked vault: got %v, want ErrVaultLocked", err)
+	}
+}
+
+func TestVaultBackend_Ping(t *testing.T) {
+	v := testVault(t)
+
+	// An uninitialized vault is usable.
+	if err := v.Ping(); err != nil {
+		t.Fatalf("Ping before init: %v", err)
+	}
+
+	if err := v.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if err := v.Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	wrong, err := NewVaultBackend("wrong-passphrase", WithVaultPath(v.DBPath()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = wrong.Close() })
+	if err := wrong.Ping(); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Ping with wrong passphrase: got %v, want ErrWrongPassphrase", err)
+	}
+
+	if err := v.Lock(); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if err := v.Ping(); !errors.Is(err, ErrVaultLocked) {
+		t.Errorf("Ping on locked vault: got %v, want ErrVaultLocked", err)
+	}
+}
+
+func TestVaultBackend_Metadata(t *testing.T) {
+	v := testVault(t)
+
+	before := time.Now().UTC().Add(-time.Second)
+	if err := v.Set("api_key", "v1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	md, err := v.Metadata("api_key")
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if md.Created.Before(before) || !md.Updated.Equal(md.Created) {
+		t.Errorf("Metadata = %+v, want created = updated >= %v", md, before)
+	}
+	if md.UpdatedBy == "" {
+		t.Error("expected UpdatedBy to be recorded")
+	}
+
+	if err := v.Set("api_key", "v2"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	updated, err := v.Metadata("api_key")
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if !updated.Created.Equal(md.Created) {
+		t.Errorf("overwriting must keep Created: got %v, want %v", updated.Created, md.Created)
+	}
+
+	if _, err := v.Metadata("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Metadata(missing): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestVaultBackend_MetadataMigratesOldVault(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "vault.db")
+
+	// A vault created before metadata columns existed.
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE secrets (key TEXT PRIMARY KEY NOT NULL, value TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+
+	v, err := NewVaultBackend("pw", WithVaultPath(dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = v.Close() })
+
+	if err := v.Set("key", "value"); err != nil {
+		t.Fatalf("Set after migration: %v", err)
+	}
+	md, err := v.Metadata("key")
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if md.Updated.IsZero() {
+		t.Error("expected Updated to be recorded after migration")
+	}
+}
@@ -0,0 +1,43 @@
+package backend
+
+import (
+	"errors"
+	"time"
+)
+
+// Version describes one stored version of a secret.
+type Version struct {
+	// Number is the store's version number, starting at 1.
+	Number int
+	// Created is when the version was written.
+	Created time.Time
+	// CreatedBy identifies who wrote the version, if the store records it.
+	CreatedBy string
+	// Current reports whether this is the version Get returns.
+	Current bool
+	// Deleted reports whether the version's value has been deleted or
+	// destroyed, so it can no longer be read or rolled back to.
+	Deleted bool
+}
+
+// VersionedBackend is an optional interface for backends that keep earlier
+// values of a secret when it is overwritten (e.g., HashiCorp Vault KV v2,
+// AWS SSM Parameter Store).
+type VersionedBackend interface {
+	// ListVersions returns the versions of key, oldest first.
+	// Returns ErrNotFound if the key does not exist.
+	ListVersions(key string) ([]Version, error)
+
+	// GetVersion retrieves the value of key at the given version.
+	// Returns ErrNotFound if the key or version does not exist.
+	GetVersion(key string, version int) (string, error)
+
+	// Rollback makes the value of the given version current again. Stores
+	// do this by writing it as a new version, so the rollback itself can be
+	// undone.
+	Rollback(key string, version int) error
+}
+
+// ErrVersioningUnsupported is returned for version operations on backends
+// that do not keep earlier values of secrets.
+var ErrVersioningUnsupported = errors.New("backend does not keep secret versions")
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+)
+
+// writeACLTestConfig appends a team with the current OS user as alice, who
+// has role, and an acl restricting PROD_* to admins, to the config in dir.
+func writeACLTestConfig(t *testing.T, dir, base, role string) {
+	t.Helper()
+	u, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+	writeTestFile(t, dir, config.FullFileName, base+`team:
+  - name: alice
+    public_key: age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+    roles: [`+role+`]
+    identities: ["`+u.Username+`"]
+acl:
+  PROD_*: [admin]
+`)
+}
+
+func TestACL_Enforced(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "acl-app")
+	chdir(t, dir)
+	for _, key := range []string{"PROD_DB", "STAGING_DB"} {
+		if _, _, err := execCmd(t, "secret", "set", key, "--value", "v", "--no-env"); err != nil {
+			t.Fatalf("secret set %s: %v", key, err)
+		}
+	}
+	base, err := os.ReadFile(filepath.Join(dir, config.FullFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeACLTestConfig(t, dir, string(base), "contributor")
+
+	if stdout, _, err := execCmd(t, "secret", "get", "STAGING_DB"); err != nil || stdout != "v\n" {
+		t.Errorf("secret get STAGING_DB = %q, %v; want v", stdout, err)
+	}
+	_, _, err = execCmd(t, "secret", "get", "PROD_DB")
+	if !errors.Is(err, backend.ErrAccessDenied) {
+		t.Fatalf("secret get PROD_DB: expected access denied, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `restricted to admin by acl "PROD_*"; you are alice`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, _, err := execCmd(t, "secret", "set", "PROD_API_KEY", "--value", "v", "--no-env"); !errors.Is(err, backend.ErrAccessDenied) {
+		t.Errorf("secret set PROD_API_KEY: expected access denied, got %v", err)
+	}
+
+	writeTestFile(t, dir, ".env", "DB=ref://secrets/PROD_DB\n")
+	_, stderr, err := execCmd(t, "resolve", "--strict")
+	if err == nil {
+		t.Fatal("resolve --strict: expected an error for a restricted secret")
+	}
+	if !strings.Contains(stderr, "access denied") {
+		t.Errorf("expected the denial on stderr, got %q", stderr)
+	}
+
+	// An admin may read it.
+	writeACLTestConfig(t, dir, string(base), "admin")
+	if stdout, _, err := execCmd(t, "secret", "get", "PROD_DB"); err != nil || stdout != "v\n" {
+		t.Errorf("secret get PROD_DB as admin = %q, %v; want v", stdout, err)
+	}
+}
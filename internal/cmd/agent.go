@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/agent"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/backend/factory"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+)
+
+// newAgentCmd creates the agent command group for the local resolve agent.
+func newAgentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Run a local agent that keeps backend sessions and a secret cache",
+		Long: `Run a local agent that keeps backends open, with their signed-in
+sessions, and caches the secret values read through them.
+
+While the agent is running, 'envref resolve' and 'envref run' read secrets
+through it over a unix socket instead of opening every backend themselves,
+so a resolve triggered by direnv on every directory change completes in
+milliseconds. Writes still go directly to the backends and drop the old
+value from the agent's cache.
+
+The socket is $XDG_RUNTIME_DIR/envref/agent.sock, or agent.sock in the
+envref directory of the user's cache directory; set ENVREF_AGENT_SOCKET to
+use another path. Set ENVREF_NO_AGENT=1 to bypass a running agent.
+
+The agent opens backends with the environment it was started with.`,
+	}
+
+	cmd.AddCommand(newAgentStartCmd())
+	cmd.AddCommand(newAgentStopCmd())
+	cmd.AddCommand(newAgentStatusCmd())
+
+	return cmd
+}
+
+// newAgentStartCmd creates the agent start subcommand.
+func newAgentStartCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start the agent",
+		Long: `Start the agent and serve requests until it is stopped with
+'envref agent stop' or an interrupt.
+
+By default the agent runs in the foreground, which suits a service manager
+such as launchd or systemd. Use --detach to start it in the background.
+
+Examples:
+  envref agent start --detach           # start in the background
+  envref agent start --ttl 30m          # cache values for 30 minutes`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ttl, _ := cmd.Flags().GetDuration("ttl")
+			detach, _ := cmd.Flags().GetBool("detach")
+			return runAgentStart(cmd, ttl, detach)
+		},
+	}
+
+	cmd.Flags().Duration("ttl", agent.DefaultTTL, "how long to cache secret values")
+	cmd.Flags().Bool("detach", false, "start the agent in the background")
+
+	return cmd
+}
+
+// runAgentStart serves agent requests, or starts a detached agent.
+func runAgentStart(cmd *cobra.Command, ttl time.Duration, detach bool) error {
+	out := output.NewWriter(cmd)
+	if ttl <= 0 {
+		return fmt.Errorf("--ttl must be positive")
+	}
+
+	socket, err := agentSocketPath()
+	if err != nil {
+		return err
+	}
+	if detach {
+		return startDetachedAgent(cmd, socket, ttl)
+	}
+
+	// Lock memory for the agent's lifetime if the config here asks for it.
+	if cwd, err := os.Getwd(); err == nil {
+		if cfg, _, err := config.Load(cwd); err == nil {
+			factory.ApplyMemoryConfig(cfg)
+		}
+	}
+
+	l, err := agent.Listen(socket)
+	if err != nil {
+		return fmt.Errorf("starting agent: %w", err)
+	}
+	srv := agent.NewServer(createChainedBackend, ttl)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; ok {
+			srv.Stop()
+		}
+	}()
+
+	out.Info("agent listening on %s\n", socket)
+	if err := srv.Serve(l); err != nil {
+		return fmt.Errorf("agent: %w", err)
+	}
+	out.Info("agent stopped\n")
+	return nil
+}
+
+// agentStartTimeout bounds the wait for a detached agent to answer.
+const agentStartTimeout = 5 * time.Second
+
+// startDetachedAgent runs 'envref agent start' in the background and waits
+// until it answers on socket.
+func startDetachedAgent(cmd *cobra.Command, socket string, ttl time.Duration) error {
+	client := agent.NewClient(socket)
+	if _, err := client.Status(); err == nil {
+		return fmt.Errorf("an agent is already running on %s", socket)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("finding envref executable: %w", err)
+	}
+	child := exec.Command(exe, "agent", "start", "--ttl", ttl.String())
+	child.Env = append(os.Environ(), "ENVREF_AGENT_SOCKET="+socket)
+	child.SysProcAttr = detachedProcAttr()
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("starting agent: %w", err)
+	}
+	pid := child.Process.Pid
+	_ = child.Process.Release()
+
+	deadline := time.Now().Add(agentStartTimeout)
+	for {
+		if _, err := client.Status(); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("agent (pid %d) did not start listening on %s", pid, socket)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	output.NewWriter(cmd).Info("agent started (pid %d) on %s\n", pid, socket)
+	return nil
+}
+
+// newAgentStopCmd creates the agent stop subcommand.
+func newAgentStopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the running agent",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAgentStop(cmd)
+		},
+	}
+}
+
+// runAgentStop asks the running agent to shut down.
+func runAgentStop(cmd *cobra.Command) error {
+	socket, err := agentSocketPath()
+	if err != nil {
+		return err
+	}
+	if err := agent.NewClient(socket).Stop(); err != nil {
+		return fmt.Errorf("no agent is running on %s", socket)
+	}
+	output.NewWriter(cmd).Info("agent stopped\n")
+	return nil
+}
+
+// newAgentStatusCmd creates the agent status subcommand.
+func newAgentStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show whether the agent is running",
+		Long: `Show whether the agent is running, and how many backends it has open
+and values it has cached. Exits with an error if no agent is running.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAgentStatus(cmd)
+		},
+	}
+}
+
+// runAgentStatus prints the state of the running agent.
+func runAgentStatus(cmd *cobra.Command) error {
+	socket, err := agentSocketPath()
+	if err != nil {
+		return err
+	}
+	st, err := agent.NewClient(socket).Status()
+	if err != nil {
+		return fmt.Errorf("no agent is running on %s", socket)
+	}
+
+	out := output.NewWriter(cmd)
+	out.Info("agent running on %s\n", socket)
+	out.Info("  pid:      %d\n", st.PID)
+	out.Info("  uptime:   %s\n", time.Since(st.Started).Round(time.Second))
+	out.Info("  ttl:      %s\n", st.TTL)
+	out.Info("  backends: %d\n", st.Backends)
+	out.Info("  cached:   %d value(s)\n", st.Values)
+	return nil
+}
+
+// agentSocketPath returns the agent socket path: ENVREF_AGENT_SOCKET, or
+// agent.DefaultSocketPath.
+func agentSocketPath() (string, error) {
+	if path := os.Getenv("ENVREF_AGENT_SOCKET"); path != "" {
+		return path, nil
+	}
+	path, err := agent.DefaultSocketPath()
+	if err != nil {
+		return "", fmt.Errorf("determining agent socket: %w", err)
+	}
+	return path, nil
+}
+
+// runningAgent returns a client for the running agent, or nil if none is
+// running or ENVREF_NO_AGENT is set.
+func runningAgent() *agent.Client {
+	if os.Getenv("ENVREF_NO_AGENT") != "" {
+		return nil
+	}
+	socket, err := agentSocketPath()
+	if err != nil {
+		return nil
+	}
+	if _, err := os.Stat(socket); err != nil {
+		return nil
+	}
+	client := agent.NewClient(socket)
+	if _, err := client.Status(); err != nil {
+		return nil
+	}
+	return client
+}
+
+// buildResolveRegistry returns the registry used to resolve references:
+// one that reads through the running agent, or, if there is none, the one
+// built by buildRegistry.
+func buildResolveRegistry(cfg *config.Config) (*backend.Registry, error) {
+	client := runningAgent()
+	if client == nil {
+		return buildRegistry(cfg)
+	}
+	return buildAgentRegistry(client, cfg)
+}
+
+// buildAgentRegistry returns a registry whose backends read through the
+// agent, with the namespaces and aliases of cfg. Backend calls are logged
+// to the default slog logger (see backend.Trace).
+func buildAgentRegistry(client *agent.Client, cfg *config.Config) (*backend.Registry, error) {
+	registry := backend.NewRegistry()
+	for _, bc := range cfg.Backends {
+		b := backend.Chain(client.Backend(agentBackendConfig(bc)), backend.Trace(slog.Default()), backend.Redacting())
+		if err := registry.Register(b); err != nil {
+			return nil, err
+		}
+		if err := registry.SetNamespace(bc.Name, bc.Namespace); err != nil {
+			return nil, err
+		}
+	}
+	for name, targets := range cfg.Aliases {
+		if err := registry.SetAlias(name, targets); err != nil {
+			return nil, err
+		}
+	}
+	factory.ApplyACL(registry, cfg)
+	return registry, nil
+}
+
+// agentInvalidating returns middleware that drops each written key from the
+// running agent's cache of bc's backend. Without an agent it does nothing.
+func agentInvalidating(bc config.BackendConfig) backend.Middleware {
+	return backend.OnWrite(func(key string) {
+		socket, err := agentSocketPath()
+		if err != nil {
+			return
+		}
+		if _, err := os.Stat(socket); err != nil {
+			return
+		}
+		_, _ = agent.NewClient(socket).Invalidate(agentBackendConfig(bc), key)
+	})
+}
+
+// agentPathKeys are the backend config keys holding file paths, which the
+// agent, running in another directory, needs as absolute paths.
+var agentPathKeys = []string{"path", "command"}
+
+// agentBackendConfig returns bc as sent to the agent: with relative file
+// paths made absolute. A command without a directory is left to be looked
+// up in PATH.
+func agentBackendConfig(bc config.BackendConfig) config.BackendConfig {
+	var cfg map[string]string
+	for _, key := range agentPathKeys {
+		value := bc.Config[key]
+		if value == "" || filepath.IsAbs(value) || slices.Contains(bc.Encrypted, key) {
+			continue
+		}
+		if key == "command" && !strings.ContainsRune(value, '/') && !strings.ContainsRune(value, filepath.Separator) {
+			continue
+		}
+		abs, err := filepath.Abs(value)
+		if err != nil {
+			continue
+		}
+		if cfg == nil {
+			cfg = maps.Clone(bc.Config)
+		}
+		cfg[key] = abs
+	}
+	if cfg != nil {
+		bc.Config = cfg
+	}
+	return bc
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package cmd
+
+import "syscall"
+
+// detachedProcAttr returns nil: the agent runs as an ordinary child
+// process, which outlives envref.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return nil
+}
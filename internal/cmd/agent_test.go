@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/agent"
+	"github.com/xcke/envref/internal/config"
+)
+
+// startTestAgent serves an in-process agent on a socket in a temporary
+// directory and points ENVREF_AGENT_SOCKET at it.
+func startTestAgent(t *testing.T) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), agent.SocketFileName)
+	t.Setenv("ENVREF_AGENT_SOCKET", socket)
+
+	l, err := agent.Listen(socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := agent.NewServer(createChainedBackend, 0)
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(l) }()
+	t.Cleanup(func() {
+		srv.Stop()
+		if err := <-done; err != nil {
+			t.Errorf("serve: %v", err)
+		}
+	})
+}
+
+func TestResolveCmd_ThroughAgent(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "API_KEY=ref://secrets/API_KEY\nPORT=3000\n")
+	chdir(t, dir)
+	startTestAgent(t)
+
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "sk-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	stdout, _, err := execCmd(t, "resolve")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if !strings.Contains(stdout, "API_KEY=sk-123") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	stdout, _, err = execCmd(t, "agent", "status")
+	if err != nil {
+		t.Fatalf("agent status: %v", err)
+	}
+	if !strings.Contains(stdout, "cached:   1 value(s)") {
+		t.Errorf("expected one cached value, got %q", stdout)
+	}
+
+	// A write drops the cached value.
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "sk-456", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	stdout, _, err = execCmd(t, "agent", "status")
+	if err != nil {
+		t.Fatalf("agent status: %v", err)
+	}
+	if !strings.Contains(stdout, "cached:   0 value(s)") {
+		t.Errorf("expected the value to be dropped, got %q", stdout)
+	}
+}
+
+func TestAgentStatusCmd_NotRunning(t *testing.T) {
+	t.Setenv("ENVREF_AGENT_SOCKET", filepath.Join(t.TempDir(), agent.SocketFileName))
+
+	_, _, err := execCmd(t, "agent", "status")
+	if err == nil || !strings.Contains(err.Error(), "no agent is running") {
+		t.Fatalf("expected no agent, got %v", err)
+	}
+	if runningAgent() != nil {
+		t.Error("runningAgent returned a client without an agent")
+	}
+}
+
+func TestAgentBackendConfig(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	bc := config.BackendConfig{Name: "p", Config: map[string]string{
+		"path":    "data/secrets.json",
+		"command": "op",
+		"vault":   "Personal",
+	}}
+	got := agentBackendConfig(bc)
+
+	want, _ := filepath.Abs("data/secrets.json")
+	if got.Config["path"] != want {
+		t.Errorf("path = %q, want %q", got.Config["path"], want)
+	}
+	if got.Config["command"] != "op" || got.Config["vault"] != "Personal" {
+		t.Errorf("unexpected config: %v", got.Config)
+	}
+	if bc.Config["path"] != "data/secrets.json" {
+		t.Error("the original config was modified")
+	}
+
+	got = agentBackendConfig(config.BackendConfig{Config: map[string]string{"command": "./bin/envref-backend-x"}})
+	if !filepath.IsAbs(got.Config["command"]) {
+		t.Errorf("relative command not made absolute: %q", got.Config["command"])
+	}
+}
+
+func TestBuildResolveRegistry_NoAgent(t *testing.T) {
+	startTestAgent(t)
+	t.Setenv("ENVREF_NO_AGENT", "1")
+
+	registry, err := buildResolveRegistry(&config.Config{Backends: []config.BackendConfig{{Name: "mem", Type: "memory"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Backend("mem").List(); err != nil {
+		t.Errorf("expected a direct backend, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/resolve"
+	"github.com/xcke/envref/internal/schema"
+)
+
+// checkTypedValue validates value against the "# @type:" annotation of the
+// given entry. Entries without a type annotation accept any value. ref://
+// values are not checked since they are placeholders for the real value.
+func checkTypedValue(entry parser.Entry, value string) error {
+	typ, ok := entry.Annotation(parser.AnnotationType)
+	if !ok || typ == "" || ref.IsRef(value) || ref.IsEncrypted(value) {
+		return nil
+	}
+	rule, err := schema.RuleFromType(typ)
+	if err != nil {
+		return fmt.Errorf("%s: %w", entry.Key, err)
+	}
+	if err := schema.ValidateValue(rule, value); err != nil {
+		return fmt.Errorf("%s: %w", entry.Key, err)
+	}
+	return nil
+}
+
+// checkResolvedTypes validates each resolved entry against the type
+// annotation of the corresponding key in env and returns all violations.
+func checkResolvedTypes(env *envfile.Env, entries []resolve.Entry) []error {
+	var errs []error
+	for _, entry := range entries {
+		source, ok := env.Get(entry.Key)
+		if !ok {
+			continue
+		}
+		if err := checkTypedValue(source, entry.Value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// configSchema returns the schema declared in the schema: block of cfg, or
+// nil when cfg is nil or declares no rules. The rules were checked when the
+// config was loaded.
+func configSchema(cfg *config.Config) *schema.Schema {
+	if cfg == nil || len(cfg.Schema) == 0 {
+		return nil
+	}
+	return &schema.Schema{Keys: cfg.Schema, Schemes: ref.Schemes(cfg.RefSchemes)}
+}
+
+// loadConfigSchema loads the project config from the working directory and
+// returns its schema. It returns nil without error when there is no project
+// config or the config declares no schema.
+func loadConfigSchema() (*schema.Schema, error) {
+	cfg, err := loadOptionalConfig()
+	if err != nil {
+		return nil, err
+	}
+	return configSchema(cfg), nil
+}
+
+// loadOptionalConfig loads the project config from the working directory.
+// It returns nil without error when there is no project config.
+func loadOptionalConfig() (*config.Config, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, _, err := config.Load(cwd)
+	if err != nil && !errors.Is(err, config.ErrNotFound) {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	return cfg, nil
+}
+
+// checkSchema validates resolved entries against s and the unresolved values
+// in env against its ref requirements. A nil schema reports nothing.
+func checkSchema(s *schema.Schema, env *envfile.Env, entries []resolve.Entry) []error {
+	if s == nil {
+		return nil
+	}
+	resolved := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		resolved[entry.Key] = entry.Value
+	}
+	source := make(map[string]string, env.Len())
+	for _, entry := range env.All() {
+		source[entry.Key] = entry.Value
+	}
+
+	var errs []error
+	for _, e := range s.CheckRefs(source).Errors {
+		errs = append(errs, errors.New(e.String()))
+	}
+	for _, e := range s.Validate(resolved).Errors {
+		errs = append(errs, errors.New(e.String()))
+	}
+	return errs
+}
+
+// checkSchemaValue validates a single value against the rule for key in s.
+// Keys without a rule, and a nil schema, accept any value.
+func checkSchemaValue(s *schema.Schema, key, value string) error {
+	if s == nil {
+		return nil
+	}
+	if err := s.ValidateKey(key, value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
@@ -10,15 +10,18 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/xcke/envref/internal/output"
 	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/ref"
 )
 
-// auditFinding represents a potential plaintext secret found by the audit command.
+// auditFinding represents a potential plaintext secret found by the audit
+// and scan commands.
 type auditFinding struct {
 	File    string
 	Line    int
 	Key     string
 	Reason  string
 	Pattern string // which detection method flagged it
+	Commit  string // commit that added the line, for git history findings
 }
 
 // newAuditCmd creates the audit subcommand.
@@ -59,6 +62,8 @@ Examples:
 	cmd.Flags().String("local-file", ".env.local", "path to the .env.local override file")
 	cmd.Flags().Float64("min-entropy", 3.5, "minimum Shannon entropy to flag a value (bits per character)")
 
+	cmd.AddCommand(newAuditVerifyCmd())
+
 	return cmd
 }
 
@@ -86,7 +91,7 @@ func runAudit(cmd *cobra.Command, envPath, localPath string, minEntropy float64)
 		return nil
 	}
 
-	printAuditFindings(w, allFindings)
+	printAuditFindings(w, "Audit", allFindings)
 
 	return fmt.Errorf("%d potential secret(s) found", len(allFindings))
 }
@@ -117,8 +122,8 @@ func auditFile(path string, minEntropy float64) ([]auditFinding, error) {
 
 // auditEntry checks a single entry for potential plaintext secrets.
 func auditEntry(path string, entry parser.Entry, minEntropy float64) []auditFinding {
-	// Skip ref:// references — these are already safe.
-	if entry.IsRef {
+	// Skip ref:// references and enc:// values — these are already safe.
+	if entry.IsRef || ref.IsEncrypted(entry.Value) {
 		return nil
 	}
 
@@ -220,6 +225,7 @@ var secretKeyWords = []string{
 	"SECRET",
 	"PASSWORD",
 	"PASSWD",
+	"PASSPHRASE",
 	"TOKEN",
 	"API_KEY",
 	"APIKEY",
@@ -308,18 +314,23 @@ func shannonEntropy(s string) float64 {
 	return entropy
 }
 
-// printAuditFindings formats and prints all audit findings to stderr.
-func printAuditFindings(w *output.Writer, findings []auditFinding) {
+// printAuditFindings formats and prints all findings to stderr. title names
+// the command in the summary line ("Audit", "Scan").
+func printAuditFindings(w *output.Writer, title string, findings []auditFinding) {
 	out := w.Stderr()
 
-	// Group by file, preserving order of first appearance.
+	// Group by file (and commit), preserving order of first appearance.
 	var fileOrder []string
 	grouped := make(map[string][]auditFinding)
 	for _, f := range findings {
-		if _, exists := grouped[f.File]; !exists {
-			fileOrder = append(fileOrder, f.File)
+		file := f.File
+		if f.Commit != "" {
+			file = fmt.Sprintf("%s (commit %.12s)", f.File, f.Commit)
+		}
+		if _, exists := grouped[file]; !exists {
+			fileOrder = append(fileOrder, file)
 		}
-		grouped[f.File] = append(grouped[f.File], f)
+		grouped[file] = append(grouped[file], f)
 	}
 
 	for _, file := range fileOrder {
@@ -334,7 +345,8 @@ func printAuditFindings(w *output.Writer, findings []auditFinding) {
 	}
 
 	hint := "Use ref:// references to keep secrets out of .env files"
-	_, _ = fmt.Fprintf(out, "\nAudit found %s. %s\n",
+	_, _ = fmt.Fprintf(out, "\n%s found %s. %s\n",
+		title,
 		w.Red(fmt.Sprintf("%d potential secret(s)", len(findings))),
 		hint,
 	)
@@ -1,13 +1,144 @@
 package cmd
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"path/filepath"
 
 	"github.com/xcke/envref/internal/audit"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/resolve"
+	"github.com/xcke/envref/internal/secret"
 )
 
 // newAuditLogger creates an audit logger that writes to the .envref.audit.log
-// file in the given config directory (the directory containing .envref.yaml).
-func newAuditLogger(configDir string) *audit.Logger {
-	return audit.NewLogger(filepath.Join(configDir, audit.DefaultFileName))
+// file in the given config directory (the directory containing .envref.yaml)
+// and to the configured remote sinks. If the config enables audit signing,
+// entries are signed with the project's audit key, which is created on first
+// use. Audit logging is best-effort, so a keychain failure leaves entries
+// unsigned; "envref audit verify" then reports them.
+func newAuditLogger(cfg *config.Config, configDir string) *audit.Logger {
+	opts := []audit.LoggerOption{audit.WithSinks(auditSinks(cfg.Audit.Sinks)...)}
+	if cfg.Audit.Sign {
+		if key, err := auditSigningKey(cfg.Project, true); err == nil {
+			_ = secret.Lock(key)
+			opts = append(opts, audit.WithSigningKey(key))
+		}
+	}
+	return audit.NewLogger(filepath.Join(configDir, audit.DefaultFileName), opts...)
+}
+
+// auditRead logs a read of key from the backend named backendName, in the
+// scope of profile, if the config enables read auditing.
+func auditRead(cfg *config.Config, configDir, key, backendName, profile string) {
+	if !cfg.Audit.Reads {
+		return
+	}
+	_ = newAuditLogger(cfg, configDir).Log(audit.Entry{
+		Operation: audit.OpRead,
+		Key:       key,
+		Backend:   backendName,
+		Project:   cfg.Project,
+		Profile:   profile,
+	})
+}
+
+// auditRefReads logs a read of each reference in env that result resolved,
+// once per reference, if the config enables read auditing. An entry names
+// the key and the backend of the reference, which for a fallback or alias
+// reference is not necessarily the backend that held the secret.
+func auditRefReads(cfg *config.Config, configDir, profile string, env *envfile.Env, result *resolve.Result) {
+	if !cfg.Audit.Reads {
+		return
+	}
+	failed := make(map[string]bool, len(result.Errors))
+	for _, keyErr := range result.Errors {
+		failed[keyErr.Ref] = true
+	}
+
+	var refs []ref.Reference
+	for _, entry := range env.All() {
+		if entry.IsRef {
+			if parsed, err := ref.Parse(entry.Value); err == nil {
+				refs = append(refs, parsed)
+			}
+			continue
+		}
+		for _, emb := range ref.FindAll(entry.Value) {
+			refs = append(refs, emb.Ref)
+		}
+	}
+
+	logger := newAuditLogger(cfg, configDir)
+	seen := make(map[string]bool, len(refs))
+	for _, r := range refs {
+		if failed[r.Raw] || seen[r.Raw] || r.Backend == ref.SelfBackend {
+			continue
+		}
+		seen[r.Raw] = true
+		_ = logger.Log(audit.Entry{
+			Operation: audit.OpRead,
+			Key:       r.Path,
+			Backend:   r.Backend,
+			Project:   cfg.Project,
+			Profile:   profile,
+			Detail:    "resolve",
+		})
+	}
+}
+
+// auditSinks creates the configured audit sinks. Sinks with an invalid
+// configuration are skipped; config validation reports them.
+func auditSinks(configs []config.AuditSinkConfig) []audit.Sink {
+	var sinks []audit.Sink
+	for _, sc := range configs {
+		switch sc.Type {
+		case "syslog":
+			if s, err := audit.NewSyslogSink(sc.Address); err == nil {
+				sinks = append(sinks, s)
+			}
+		case "webhook":
+			sinks = append(sinks, audit.NewWebhookSink(sc.URL, sc.Headers))
+		case "otlp":
+			sinks = append(sinks, audit.NewOTLPSink(sc.URL, sc.Headers))
+		}
+	}
+	return sinks
+}
+
+// auditKeyItem returns the name of the keychain item holding a project's
+// audit signing key.
+func auditKeyItem(project string) string {
+	return "audit-key:" + project
+}
+
+// auditSigningKey returns the project's audit signing key from the OS
+// keychain. If create is set, a missing key is generated and stored;
+// otherwise backend.ErrNotFound is returned.
+func auditSigningKey(project string, create bool) ([]byte, error) {
+	item := auditKeyItem(project)
+	encoded, err := backend.KeychainItem(item)
+	if errors.Is(err, backend.ErrNotFound) && create {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("generating audit key: %w", err)
+		}
+		if err := backend.SetKeychainItem(item, hex.EncodeToString(key)); err != nil {
+			return nil, fmt.Errorf("storing audit key: %w", err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("audit key %s in keychain is corrupt: %w", item, err)
+	}
+	return key, nil
 }
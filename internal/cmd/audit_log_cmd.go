@@ -19,7 +19,8 @@ func newAuditLogCmd() *cobra.Command {
 		Use:   "audit-log",
 		Short: "Show the secret operations audit log",
 		Long: `Display the audit log of secret operations (set, delete, generate,
-rotate, copy, import) for the current project.
+rotate, copy, import) for the current project. With "audit: {reads: true}"
+in .envref.yaml, secret reads are logged as well.
 
 The audit log is stored as .envref.audit.log in the project root (next to
 .envref.yaml) and tracks who performed what secret operation, when, and
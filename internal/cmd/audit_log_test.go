@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/audit"
+	"github.com/xcke/envref/internal/config"
+)
+
+func TestAuditLog_GlobalWebhookSink(t *testing.T) {
+	var received []audit.Entry
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e audit.Entry
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Errorf("decoding webhook body: %v", err)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer t0ken" {
+			t.Errorf("Authorization header = %q", got)
+		}
+		received = append(received, e)
+	}))
+	defer srv.Close()
+
+	t.Setenv("ENVREF_CONFIG_DIR", t.TempDir())
+	writeTestFile(t, os.Getenv("ENVREF_CONFIG_DIR"), "config.yaml",
+		"audit:\n  sinks:\n    - type: webhook\n      url: "+srv.URL+"\n      headers:\n        Authorization: Bearer t0ken\n")
+
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "s3cret-value", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected 1 webhook delivery, got %d", len(received))
+	}
+	if e := received[0]; e.Key != "API_KEY" || e.Operation != audit.OpSet || e.Project != "app" || e.Hash == "" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
+
+func TestAuditLog_Reads(t *testing.T) {
+	t.Setenv("ENVREF_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "API_KEY=ref://secrets/API_KEY\nDB_URL=postgres://app:${ref://secrets/DB_PASS}@db\nMISSING=ref://secrets/MISSING\n")
+	chdir(t, dir)
+
+	for _, args := range [][]string{
+		{"secret", "set", "API_KEY", "--value", "s3cret-value", "--no-env"},
+		{"secret", "set", "DB_PASS", "--value", "db-s3cret", "--no-env"},
+		{"secret", "get", "API_KEY"},
+	} {
+		if _, _, err := execCmd(t, args...); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+	_, _, _ = execCmd(t, "resolve")
+
+	logger := audit.NewLogger(filepath.Join(dir, audit.DefaultFileName))
+	reads := func() []audit.Entry {
+		t.Helper()
+		entries, err := logger.Read()
+		if err != nil {
+			t.Fatalf("reading audit log: %v", err)
+		}
+		var reads []audit.Entry
+		for _, e := range entries {
+			if e.Operation == audit.OpRead {
+				reads = append(reads, e)
+			}
+		}
+		return reads
+	}
+	if got := reads(); len(got) != 0 {
+		t.Fatalf("reads are logged by default: %+v", got)
+	}
+
+	cfg, err := os.ReadFile(filepath.Join(dir, config.FullFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, config.FullFileName, string(cfg)+"audit:\n  reads: true\n")
+
+	if _, _, err := execCmd(t, "secret", "get", "API_KEY"); err != nil {
+		t.Fatalf("secret get: %v", err)
+	}
+	_, _, _ = execCmd(t, "resolve")
+
+	got := reads()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 read entries, got %+v", got)
+	}
+	if e := got[0]; e.Key != "API_KEY" || e.Backend != "secrets" || e.Project != "app" || e.Detail != "" {
+		t.Errorf("unexpected secret get entry: %+v", e)
+	}
+	if e := got[1]; e.Key != "API_KEY" || e.Backend != "secrets" || e.Detail != "resolve" {
+		t.Errorf("unexpected resolve entry: %+v", e)
+	}
+	if e := got[2]; e.Key != "DB_PASS" || e.Backend != "secrets" || e.Detail != "resolve" {
+		t.Errorf("unexpected resolve entry: %+v", e)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, audit.DefaultFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Error("audit log contains a secret value")
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/audit"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+)
+
+// newAuditVerifyCmd creates the audit verify subcommand.
+func newAuditVerifyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Check the audit log for modified, inserted, or deleted entries",
+		Long: `Verify the integrity of the secret operations audit log
+(.envref.audit.log).
+
+Every entry carries a hash that chains it to the entry before it, so editing,
+inserting, reordering, or deleting lines breaks the chain. Entries written by
+envref versions without hashing come first in older logs and are counted as
+unprotected.
+
+If "audit: {sign: true}" is set in .envref.yaml, entries are also signed with
+a per-project key kept in the OS keychain, and verify checks the signatures
+when the key is available on this machine. Signatures stop someone without
+the key from rewriting the chain after an edit.
+
+Removing entries from the end of the log cannot be detected from the log
+alone; compare it with the copy committed to git.
+
+The command exits with code 1 if any problem is found.
+
+Examples:
+  envref audit verify`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAuditVerify(cmd)
+		},
+	}
+}
+
+// runAuditVerify implements the audit verify command logic.
+func runAuditVerify(cmd *cobra.Command) error {
+	w := output.NewWriter(cmd)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, configDir, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	var opts []audit.LoggerOption
+	if cfg.Audit.Sign {
+		key, err := auditSigningKey(cfg.Project, false)
+		switch {
+		case err == nil:
+			opts = append(opts, audit.WithSigningKey(key))
+		case errors.Is(err, backend.ErrNotFound):
+			w.Warn("signatures not checked: no audit key for project %q in the keychain\n", cfg.Project)
+		default:
+			w.Warn("signatures not checked: %v\n", err)
+		}
+	}
+
+	logger := audit.NewLogger(filepath.Join(configDir, audit.DefaultFileName), opts...)
+	report, err := logger.Verify()
+	if err != nil {
+		return err
+	}
+
+	if !report.OK() {
+		out := w.Stderr()
+		for _, p := range report.Problems {
+			_, _ = fmt.Fprintf(out, "  line %d: %s\n", p.Line, p.Message)
+		}
+		return fmt.Errorf("audit log verification failed: %d problem(s) in %d entries", len(report.Problems), report.Entries)
+	}
+
+	if report.Unchained > 0 {
+		w.Warn("%d entries predate hashing and are not protected\n", report.Unchained)
+	}
+	if !w.IsQuiet() {
+		summary := fmt.Sprintf("%d entries, hash chain intact", report.Entries)
+		if report.Signed > 0 {
+			summary += fmt.Sprintf(", %d signatures valid", report.Signed)
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", w.Green("OK"), summary)
+	}
+	return nil
+}
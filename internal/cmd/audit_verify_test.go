@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/audit"
+	"github.com/zalando/go-keyring"
+)
+
+func TestAuditVerifyCmd_IntactAndTampered(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+
+	for _, kv := range []string{"A=1", "B=2", "C=3"} {
+		key, value, _ := strings.Cut(kv, "=")
+		if _, _, err := execCmd(t, "secret", "set", key, "--value", value, "--no-env"); err != nil {
+			t.Fatalf("secret set: %v", err)
+		}
+	}
+
+	stdout, _, err := execCmd(t, "audit", "verify")
+	if err != nil {
+		t.Fatalf("audit verify: %v", err)
+	}
+	if !strings.Contains(stdout, "3 entries, hash chain intact") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	// Delete the middle entry.
+	logPath := filepath.Join(dir, audit.DefaultFileName)
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	writeTestFile(t, dir, audit.DefaultFileName, lines[0]+lines[2])
+
+	_, stderr, err := execCmd(t, "audit", "verify")
+	if err == nil || !strings.Contains(err.Error(), "1 problem(s) in 2 entries") {
+		t.Fatalf("expected verification failure, got %v", err)
+	}
+	if !strings.Contains(stderr, "line 2: hash mismatch") {
+		t.Errorf("expected problem on line 2, got %q", stderr)
+	}
+}
+
+func TestAuditVerifyCmd_Signed(t *testing.T) {
+	keyring.MockInit()
+
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "signed-app")
+	cfg, err := os.ReadFile(filepath.Join(dir, ".envref.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, ".envref.yaml", string(cfg)+"audit:\n  sign: true\n")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "secret", "set", "A", "--value", "1", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, audit.DefaultFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"sig":"`) {
+		t.Fatalf("entry not signed: %s", data)
+	}
+
+	stdout, _, err := execCmd(t, "audit", "verify")
+	if err != nil {
+		t.Fatalf("audit verify: %v", err)
+	}
+	if !strings.Contains(stdout, "1 signatures valid") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	// Without the key, verification still checks the chain but warns.
+	if err := keyring.Delete("envref", auditKeyItem("signed-app")); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, err := execCmd(t, "audit", "verify")
+	if err != nil {
+		t.Fatalf("audit verify without key: %v", err)
+	}
+	if !strings.Contains(stderr, "signatures not checked") {
+		t.Errorf("expected warning, got %q", stderr)
+	}
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/backend/factory"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/ref"
+)
+
+// newAWSCmd creates the aws command group.
+func newAWSCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "aws",
+		Short: "Reference secrets stored in AWS from ECS and Lambda",
+		Long: `Print the environment of an ECS container or Lambda function, with the
+secrets referenced by the ARN of their SSM parameter instead of their
+value, so that AWS injects them at runtime and they never pass through a
+deploy pipeline.
+
+Nothing is resolved: plain values are copied from the .env files, and each
+ref:// reference must point at a parameter of an aws-ssm backend. The
+parameter of the profile is used if it exists, as 'envref resolve' does,
+and the project's otherwise. References to other backends, refs embedded
+in a value, and refs with an encoding are errors.`,
+	}
+
+	cmd.AddCommand(newAWSECSCmd())
+	cmd.AddCommand(newAWSLambdaCmd())
+
+	return cmd
+}
+
+// newAWSECSCmd creates the aws ecs subcommand.
+func newAWSECSCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ecs",
+		Short: "Print the environment and secrets blocks of an ECS container definition",
+		Long: `Print the "environment" and "secrets" blocks of an ECS container definition
+as JSON. Secrets are referenced by parameter ARN in valueFrom, and ECS
+injects their values when the task starts. The task execution role needs
+ssm:GetParameters on the parameters, and kms:Decrypt on their key if it
+is not the default one.
+
+Examples:
+  envref aws ecs -P production > env.json
+  jq --slurpfile env env.json '.containerDefinitions[0] += $env[0]' task.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			return runAWS(cmd, profile, "ecs")
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
+
+	return cmd
+}
+
+// newAWSLambdaCmd creates the aws lambda subcommand.
+func newAWSLambdaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lambda",
+		Short: "Print the environment of a Lambda function configuration",
+		Long: `Print the environment of a Lambda function configuration as JSON, in the
+form 'aws lambda update-function-configuration --environment' reads.
+
+Lambda cannot inject secrets itself, so each secret is set to the ARN of
+its parameter and the function reads the value at startup, for example
+through the AWS Parameters and Secrets Lambda Extension. The function's
+role needs ssm:GetParameter on the parameters, and kms:Decrypt on their
+key if it is not the default one.
+
+Examples:
+  envref aws lambda -P production > env.json
+  aws lambda update-function-configuration --function-name api --environment file://env.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			return runAWS(cmd, profile, "lambda")
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
+
+	return cmd
+}
+
+// ecsKeyValue is an entry of the environment block of an ECS container
+// definition.
+type ecsKeyValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ecsSecret is an entry of the secrets block of an ECS container
+// definition.
+type ecsSecret struct {
+	Name      string `json:"name"`
+	ValueFrom string `json:"valueFrom"`
+}
+
+// ecsContainerEnv is the environment of an ECS container definition.
+type ecsContainerEnv struct {
+	Environment []ecsKeyValue `json:"environment"`
+	Secrets     []ecsSecret   `json:"secrets"`
+}
+
+// lambdaEnv is the environment of a Lambda function configuration.
+type lambdaEnv struct {
+	Variables map[string]string `json:"Variables"`
+}
+
+// awsSSMBackend is a configured aws-ssm backend.
+type awsSSMBackend struct {
+	cfg config.BackendConfig
+	ssm *backend.AWSSSMBackend
+}
+
+// runAWS implements the aws ecs and aws lambda command logic.
+func runAWS(cmd *cobra.Command, profileOverride, target string) error {
+	cfg, projectDir, err := loadServeConfig()
+	if err != nil {
+		return err
+	}
+	profile := cfg.EffectiveProfile(profileOverride)
+	env, err := loadProjectEnv(cmd, cfg, projectDir, profile)
+	if err != nil {
+		return err
+	}
+
+	var ssmBackends []awsSSMBackend
+	for _, bc := range cfg.Backends {
+		if bc.EffectiveType() != "aws-ssm" {
+			continue
+		}
+		b, err := factory.New(bc, nil)
+		if err != nil {
+			return fmt.Errorf("initializing backend %q: %w", bc.Name, err)
+		}
+		ssmBackends = append(ssmBackends, awsSSMBackend{cfg: bc, ssm: b.(*backend.AWSSSMBackend)})
+	}
+
+	out := ecsContainerEnv{Environment: []ecsKeyValue{}, Secrets: []ecsSecret{}}
+	var errs []string
+	for _, e := range env.All() {
+		if !e.IsRef {
+			if ref.ContainsRef(e.Value) {
+				errs = append(errs, fmt.Sprintf("%s: embeds a reference, but AWS can only inject whole values", e.Key))
+				continue
+			}
+			out.Environment = append(out.Environment, ecsKeyValue{Name: e.Key, Value: e.Value})
+			continue
+		}
+		parsed, err := ref.Parse(e.Value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: invalid ref:// URI: %v", e.Key, err))
+			continue
+		}
+		if parsed.Encoding != "" {
+			errs = append(errs, fmt.Sprintf("%s: AWS cannot apply encoding %q", e.Key, parsed.Encoding))
+			continue
+		}
+		if len(parsed.Transforms) > 0 {
+			errs = append(errs, fmt.Sprintf("%s: AWS cannot apply %s", e.Key, parsed.Transforms[0]))
+			continue
+		}
+		arn, err := awsParameterARN(cfg, ssmBackends, parsed, profile)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", e.Key, err))
+			continue
+		}
+		out.Secrets = append(out.Secrets, ecsSecret{Name: e.Key, ValueFrom: arn})
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot reference the environment from %s:\n  %s", target, strings.Join(errs, "\n  "))
+	}
+
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetIndent("", "  ")
+	if target == "ecs" {
+		return enc.Encode(out)
+	}
+	vars := make(map[string]string, len(out.Environment)+len(out.Secrets))
+	for _, kv := range out.Environment {
+		vars[kv.Name] = kv.Value
+	}
+	for _, s := range out.Secrets {
+		vars[s.Name] = s.ValueFrom
+	}
+	return enc.Encode(lambdaEnv{Variables: vars})
+}
+
+// awsParameterARN returns the ARN of the SSM parameter that r resolves to
+// for project and profile. A ref naming an aws-ssm backend is looked up
+// there, one naming an alias in its aws-ssm members, and any other in
+// every aws-ssm backend, in order.
+func awsParameterARN(cfg *config.Config, ssmBackends []awsSSMBackend, r ref.Reference, profile string) (string, error) {
+	var candidates []awsSSMBackend
+	switch members, isAlias := cfg.Aliases[r.Backend]; {
+	case slices.ContainsFunc(cfg.Backends, func(bc config.BackendConfig) bool { return bc.Name == r.Backend }):
+		for _, b := range ssmBackends {
+			if b.cfg.Name == r.Backend {
+				candidates = append(candidates, b)
+			}
+		}
+		if len(candidates) == 0 {
+			return "", fmt.Errorf("backend %q is not an aws-ssm backend", r.Backend)
+		}
+	case isAlias:
+		for _, b := range ssmBackends {
+			if slices.Contains(members, b.cfg.Name) {
+				candidates = append(candidates, b)
+			}
+		}
+		if len(candidates) == 0 {
+			return "", fmt.Errorf("alias %q has no aws-ssm backend", r.Backend)
+		}
+	default:
+		candidates = ssmBackends
+		if len(candidates) == 0 {
+			return "", fmt.Errorf("no aws-ssm backend is configured in %s", config.FullFileName)
+		}
+	}
+
+	scopes := []string{""}
+	if profile != "" {
+		scopes = []string{profile, ""}
+	}
+	for _, b := range candidates {
+		for _, scope := range scopes {
+			ns, err := backend.NewTemplateNamespacedBackend(b.ssm, b.cfg.Namespace, cfg.Project, scope)
+			if err != nil {
+				return "", err
+			}
+			arn, err := b.ssm.ARN(ns.StorageKey(r.Path))
+			if errors.Is(err, backend.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return "", fmt.Errorf("backend %q: %w", b.cfg.Name, err)
+			}
+			return arn, nil
+		}
+	}
+	return "", fmt.Errorf("secret %q not found in any aws-ssm backend", r.Path)
+}
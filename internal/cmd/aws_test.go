@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeAWSTestConfig writes a config whose aws-ssm backend "ssm" runs a
+// fake aws CLI that has the parameters /envref/app/API_KEY and
+// /envref/app/production/DB.
+func writeAWSTestConfig(t *testing.T, dir string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake aws is a shell script")
+	}
+	script := `#!/bin/sh
+case "$4" in
+/envref/app/API_KEY|/envref/app/production/DB)
+  echo "{\"Parameter\":{\"Name\":\"$4\",\"ARN\":\"arn:aws:ssm:eu-west-1:111122223333:parameter$4\"}}" ;;
+*) echo "An error occurred (ParameterNotFound) when calling the GetParameter operation" >&2; exit 254 ;;
+esac
+`
+	aws := filepath.Join(t.TempDir(), "aws")
+	if err := os.WriteFile(aws, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, ".envref.yaml", `project: app
+backends:
+  - name: ssm
+    type: aws-ssm
+    config:
+      command: `+aws+`
+  - name: local
+    type: memory
+`)
+}
+
+func TestAWSECSCmd(t *testing.T) {
+	dir := t.TempDir()
+	writeAWSTestConfig(t, dir)
+	writeTestFile(t, dir, ".env", "PORT=8080\nAPI_KEY=ref://ssm/API_KEY\nDB=ref://secrets/DB\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "aws", "ecs", "-P", "production")
+	if err != nil {
+		t.Fatalf("aws ecs: %v", err)
+	}
+	var got ecsContainerEnv
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout)
+	}
+	want := ecsContainerEnv{
+		Environment: []ecsKeyValue{{Name: "PORT", Value: "8080"}},
+		Secrets: []ecsSecret{
+			{Name: "API_KEY", ValueFrom: "arn:aws:ssm:eu-west-1:111122223333:parameter/envref/app/API_KEY"},
+			{Name: "DB", ValueFrom: "arn:aws:ssm:eu-west-1:111122223333:parameter/envref/app/production/DB"},
+		},
+	}
+	if len(got.Environment) != 1 || got.Environment[0] != want.Environment[0] ||
+		len(got.Secrets) != 2 || got.Secrets[0] != want.Secrets[0] || got.Secrets[1] != want.Secrets[1] {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestAWSLambdaCmd(t *testing.T) {
+	dir := t.TempDir()
+	writeAWSTestConfig(t, dir)
+	writeTestFile(t, dir, ".env", "PORT=8080\nAPI_KEY=ref://ssm/API_KEY\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "aws", "lambda")
+	if err != nil {
+		t.Fatalf("aws lambda: %v", err)
+	}
+	var got lambdaEnv
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout)
+	}
+	if len(got.Variables) != 2 || got.Variables["PORT"] != "8080" ||
+		got.Variables["API_KEY"] != "arn:aws:ssm:eu-west-1:111122223333:parameter/envref/app/API_KEY" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestAWSECSCmd_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeAWSTestConfig(t, dir)
+	writeTestFile(t, dir, ".env", "DB=ref://secrets/DB\nTOKEN=ref://local/TOKEN\nURL=https://${ref://ssm/API_KEY}@host\nCERT=ref://ssm/API_KEY?encoding=base64file\n")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "aws", "ecs")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		`DB: secret "DB" not found in any aws-ssm backend`,
+		`TOKEN: backend "local" is not an aws-ssm backend`,
+		"URL: embeds a reference",
+		`CERT: AWS cannot apply encoding "base64file"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%v", want, err)
+		}
+	}
+}
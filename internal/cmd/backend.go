@@ -1,14 +1,20 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 
 	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/backend/factory"
 	"github.com/xcke/envref/internal/config"
 	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/secret"
+	"github.com/xcke/envref/internal/suggest"
 )
 
 // backendDescriptions maps backend type names to human-readable descriptions.
@@ -20,6 +26,7 @@ var backendDescriptions = map[string]string{
 	"aws-ssm":         "AWS Systems Manager Parameter Store",
 	"oci-vault":       "Oracle Cloud Infrastructure Vault",
 	"hashicorp-vault": "HashiCorp Vault",
+	"memory":          "In-memory or plaintext file store (tests and CI only)",
 }
 
 // newBackendCmd creates the backend command group for managing secret backends.
@@ -35,6 +42,7 @@ the first backend that returns a value wins.`,
 	}
 
 	cmd.AddCommand(newBackendListCmd())
+	cmd.AddCommand(newBackendTestCmd())
 
 	return cmd
 }
@@ -47,7 +55,9 @@ func newBackendListCmd() *cobra.Command {
 		Long: `List secret backends configured in the project's .envref.yaml.
 
 By default, shows only backends configured for the current project.
-Use --all to also show all supported backend types.
+Use --all to also show all supported backend types. With --verbose, each
+backend's config is shown; values of keys that look like credentials
+(token, password, passphrase, ...) are masked unless --show-secrets is set.
 
 Examples:
   envref backend list          # show configured backends
@@ -55,17 +65,20 @@ Examples:
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			all, _ := cmd.Flags().GetBool("all")
-			return runBackendList(cmd, all)
+			showSecrets, _ := cmd.Flags().GetBool("show-secrets")
+			return runBackendList(cmd, all, showSecrets)
 		},
 	}
 
 	cmd.Flags().Bool("all", false, "show all supported backend types, not just configured ones")
+	cmd.Flags().Bool("show-secrets", false, "show credential values in --verbose config output")
 
 	return cmd
 }
 
 // runBackendList prints configured backends and optionally all supported types.
-func runBackendList(cmd *cobra.Command, all bool) error {
+// Credential config values are masked unless showSecrets is true.
+func runBackendList(cmd *cobra.Command, all, showSecrets bool) error {
 	w := output.NewWriter(cmd)
 
 	cwd, err := os.Getwd()
@@ -85,7 +98,16 @@ func runBackendList(cmd *cobra.Command, all bool) error {
 		for _, b := range cfg.Backends {
 			w.Info("  %-20s type=%s\n", b.Name, b.EffectiveType())
 			if w.IsVerbose() {
-				for k, v := range b.Config {
+				keys := make([]string, 0, len(b.Config))
+				for k := range b.Config {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				for _, k := range keys {
+					v := b.Config[k]
+					if isSecretKeyName(k) && !showSecrets {
+						v = secret.Mask
+					}
 					w.Verbose("    %s=%s\n", k, v)
 				}
 			}
@@ -127,3 +149,137 @@ func printSupportedTypes(w *output.Writer) {
 		w.Info("  %-20s %s\n", name, desc)
 	}
 }
+
+// newBackendTestCmd creates the backend test subcommand.
+func newBackendTestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test [name...]",
+		Short: "Check that configured backends are reachable",
+		Long: `Check that each configured secret backend is reachable and usable,
+without reading any secret: the CLI is installed and signed in, the server
+answers, or the local vault is unlocked and the passphrase is correct.
+
+With names, only those backends are checked. Exits with an error if any
+backend fails its check.
+
+Examples:
+  envref backend test                 # check all configured backends
+  envref backend test vault           # check one backend
+  envref backend test --format json   # machine-readable results`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			return runBackendTest(cmd, args, format)
+		},
+	}
+
+	cmd.Flags().String("format", "plain", "output format: plain, json")
+
+	return cmd
+}
+
+// backendHealth is the result of checking one configured backend.
+type backendHealth struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// runBackendTest pings the selected backends and reports the results.
+func runBackendTest(cmd *cobra.Command, names []string, formatStr string) error {
+	format, err := parseFormat(formatStr)
+	if err != nil {
+		return err
+	}
+	if format != FormatPlain && format != FormatJSON {
+		return fmt.Errorf("unsupported format %q for backend test (use plain or json)", formatStr)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, _, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	selected := cfg.Backends
+	if len(names) > 0 {
+		byName := make(map[string]config.BackendConfig, len(cfg.Backends))
+		all := make([]string, 0, len(cfg.Backends))
+		for _, bc := range cfg.Backends {
+			byName[bc.Name] = bc
+			all = append(all, bc.Name)
+		}
+		selected = nil
+		for _, name := range names {
+			bc, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("unknown backend %q%s", name, suggest.FormatSuggestion(suggest.Keys(name, all)))
+			}
+			selected = append(selected, bc)
+		}
+	}
+	if len(selected) == 0 {
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
+	}
+
+	results := checkBackends(selected)
+
+	failed := 0
+	for _, r := range results {
+		if !r.OK {
+			failed++
+		}
+	}
+
+	if format == FormatJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		w := output.NewWriter(cmd)
+		nameWidth := 0
+		for _, r := range results {
+			nameWidth = max(nameWidth, len(r.Name))
+		}
+		for _, r := range results {
+			if r.OK {
+				_, _ = fmt.Fprintf(w.Stdout(), "%s %-*s  %s\n", w.Green("[ok]"), nameWidth, r.Name, r.Type)
+			} else {
+				_, _ = fmt.Fprintf(w.Stdout(), "%s %-*s  %s: %s\n", w.Red("[!!]"), nameWidth, r.Name, r.Type, r.Error)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d backend(s) failed", failed, len(results))
+	}
+	return nil
+}
+
+// checkBackends creates each backend and pings it. A backend that cannot be
+// created (e.g., a missing plugin or vault passphrase) fails its check.
+func checkBackends(backends []config.BackendConfig) []backendHealth {
+	results := make([]backendHealth, 0, len(backends))
+	for _, bc := range backends {
+		r := backendHealth{Name: bc.Name, Type: bc.EffectiveType()}
+		b, err := factory.New(bc, openVaultBackend)
+		if err == nil {
+			err = backend.Ping(b)
+			if c, ok := b.(io.Closer); ok {
+				_ = c.Close()
+			}
+		}
+		if err != nil {
+			r.Error = err.Error()
+		} else {
+			r.OK = true
+		}
+		results = append(results, r)
+	}
+	return results
+}
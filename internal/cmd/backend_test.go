@@ -188,3 +188,111 @@ func TestBackendCmd_Help(t *testing.T) {
 		t.Errorf("expected 'list' subcommand in help, got: %q", out)
 	}
 }
+
+func TestBackendTestCmd(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "test-passphrase")
+	cfgContent := "project: testproject\nbackends:\n" +
+		"  - name: vault\n    type: vault\n    config:\n      path: " + filepath.Join(dir, "vault.db") + "\n" +
+		"  - name: broken\n    type: plugin\n    config:\n      command: " + filepath.Join(dir, "missing-plugin") + "\n"
+	writeTestFile(t, dir, ".envref.yaml", cfgContent)
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "backend", "test", "vault")
+	if err != nil {
+		t.Fatalf("backend test vault: %v", err)
+	}
+	if !contains(stdout, "[ok] vault") {
+		t.Errorf("expected vault to pass, got: %q", stdout)
+	}
+
+	stdout, _, err = execCmd(t, "backend", "test")
+	if err == nil || !contains(err.Error(), "1 of 2 backend(s) failed") {
+		t.Fatalf("expected one failure, got %v", err)
+	}
+	if !contains(stdout, "[!!] broken") || !contains(stdout, "plugin") {
+		t.Errorf("expected broken plugin to fail, got: %q", stdout)
+	}
+
+	stdout, _, _ = execCmd(t, "backend", "test", "--format", "json")
+	if !contains(stdout, `"name": "broken"`) || !contains(stdout, `"ok": false`) || !contains(stdout, `"error":`) {
+		t.Errorf("unexpected JSON output: %q", stdout)
+	}
+
+	_, _, err = execCmd(t, "backend", "test", "vualt")
+	if err == nil || !contains(err.Error(), `unknown backend "vualt"`) || !contains(err.Error(), "vault") {
+		t.Errorf("expected unknown backend error with suggestion, got %v", err)
+	}
+}
+
+func TestBackendTestCmd_InvalidPluginProtocol(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	writeTestFile(t, dir, ".envref.yaml", "project: testproject\nbackends:\n  - name: mystore\n    type: plugin\n    config:\n      command: /bin/true\n      protocol: \"4\"\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "backend", "test")
+	if err == nil || !contains(stdout, `unsupported protocol "4"`) {
+		t.Errorf("expected unsupported protocol error, got %v: %q", err, stdout)
+	}
+}
+
+func TestBackendTestCmd_InvalidPresencePattern(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	writeTestFile(t, dir, ".envref.yaml", "project: testproject\nbackends:\n  - name: keychain\n    type: keychain\n    config:\n      require_presence: \"prod_*, [\"\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "backend", "test")
+	if err == nil || !contains(stdout, `invalid key pattern "["`) {
+		t.Errorf("expected invalid pattern error, got %v: %q", err, stdout)
+	}
+}
+
+func TestBackendTestCmd_InvalidSessionConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		want    string
+	}{
+		{"unknown cache", "  - name: op\n    type: 1password\n    config:\n      session: disk\n", `unknown session cache "disk"`},
+		{"bad ttl", "  - name: op\n    type: 1password\n    config:\n      session: process\n      session_ttl: soon\n", `invalid session_ttl "soon"`},
+		{"vault without login", "  - name: hashicorp-vault\n    type: hashicorp-vault\n    config:\n      session: keychain\n", "session requires auth_method"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			writeTestFile(t, dir, ".envref.yaml", "project: testproject\nbackends:\n"+tt.backend)
+			chdir(t, dir)
+
+			stdout, _, err := execCmd(t, "backend", "test")
+			if err == nil || !contains(stdout, tt.want) {
+				t.Errorf("expected %q, got %v: %q", tt.want, err, stdout)
+			}
+		})
+	}
+}
+
+func TestBackendListCmd_MasksCredentials(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", "project: testproject\nbackends:\n  - name: hashicorp-vault\n    type: hashicorp-vault\n    config:\n      addr: https://vault.example.com\n      token: hvs.list-secret\n")
+	chdir(t, dir)
+
+	_, stderr, err := execCmd(t, "backend", "list", "--verbose")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !contains(stderr, "addr=https://vault.example.com") || !contains(stderr, "token=***") {
+		t.Errorf("expected addr and masked token, got: %q", stderr)
+	}
+	if contains(stderr, "hvs.list-secret") {
+		t.Errorf("token must be masked, got: %q", stderr)
+	}
+
+	_, stderr, _ = execCmd(t, "backend", "list", "--verbose", "--show-secrets")
+	if !contains(stderr, "token=hvs.list-secret") {
+		t.Errorf("expected token with --show-secrets, got: %q", stderr)
+	}
+}
@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/backend/factory"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/resolve"
+)
+
+// newBenchCmd creates the bench subcommand.
+func newBenchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Time each stage of resolving the current project",
+		Long: `Time each stage of resolving the current project and print a
+breakdown, to tell whether a slow resolve is spent in envref or in a backend.
+
+The stages are:
+  config       loading .envref.yaml and the global config
+  parse        reading and parsing each env file
+  merge        merging the env layers
+  interpolate  rewriting ref schemes and expanding ${VAR} references
+  open         creating each backend
+  fetch        reading secrets from each backend
+  resolve      envref's own work while resolving references
+
+Backends are opened directly, as if no agent were running, and the resolved
+values are not printed. With --runs, every stage is repeated and the mean
+time is reported.
+
+Examples:
+  envref bench                        # one run
+  envref bench --runs 10              # mean of 10 runs
+  envref bench --profile staging      # bench the staging profile
+  envref bench --format json          # machine-readable timings`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			runs, _ := cmd.Flags().GetInt("runs")
+			format, _ := cmd.Flags().GetString("format")
+			return runBench(cmd, profile, runs, format)
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
+	cmd.Flags().IntP("runs", "n", 1, "number of runs to average")
+	cmd.Flags().String("format", "plain", "output format: plain, json")
+
+	return cmd
+}
+
+// benchStage is the time spent in one stage, summed over all runs.
+type benchStage struct {
+	Stage    string        `json:"stage"`
+	Target   string        `json:"target,omitempty"`
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"-"`
+	Mean     float64       `json:"mean_ms"`
+}
+
+// benchRecorder accumulates stage timings across runs, in the order the
+// stages first ran.
+type benchRecorder struct {
+	stages []*benchStage
+	index  map[string]*benchStage
+}
+
+// add records d for the stage on target. The detail of the last run wins.
+func (r *benchRecorder) add(stage, target string, d time.Duration, detail string) {
+	id := stage + "\x00" + target
+	s, ok := r.index[id]
+	if !ok {
+		s = &benchStage{Stage: stage, Target: target}
+		r.index[id] = s
+		r.stages = append(r.stages, s)
+	}
+	s.Duration += d
+	s.Detail = detail
+}
+
+// benchReport is the result of runBench.
+type benchReport struct {
+	Runs     int           `json:"runs"`
+	Stages   []*benchStage `json:"stages"`
+	Total    float64       `json:"total_ms"`
+	Backends float64       `json:"backends_ms"`
+}
+
+// runBench times runs resolutions of the current project and prints the
+// mean time of each stage.
+func runBench(cmd *cobra.Command, profileOverride string, runs int, formatStr string) error {
+	format, err := parseFormat(formatStr)
+	if err != nil {
+		return err
+	}
+	if format != FormatPlain && format != FormatJSON {
+		return fmt.Errorf("unsupported format %q for bench (use plain or json)", formatStr)
+	}
+	if runs < 1 {
+		return fmt.Errorf("--runs must be at least 1")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+
+	rec := &benchRecorder{index: make(map[string]*benchStage)}
+	for i := 0; i < runs; i++ {
+		if err := benchOnce(rec, cwd, profileOverride); err != nil {
+			return err
+		}
+	}
+
+	report := benchReport{Runs: runs, Stages: rec.stages}
+	for _, s := range rec.stages {
+		s.Mean = benchMillis(s.Duration / time.Duration(runs))
+		report.Total += s.Mean
+		if s.Stage == "open" || s.Stage == "fetch" {
+			report.Backends += s.Mean
+		}
+	}
+
+	if format == FormatJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printBenchReport(output.NewWriter(cmd).Stdout(), report)
+	return nil
+}
+
+// benchOnce runs every stage of a resolve once, recording the timings in
+// rec. It follows loadProjectEnv and runResolve, timing each step.
+func benchOnce(rec *benchRecorder, cwd, profileOverride string) error {
+	start := time.Now()
+	cfg, projectDir, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	rec.add("config", config.FullFileName, time.Since(start), "")
+
+	profile := cfg.EffectiveProfile(profileOverride)
+	required := resolveFilePath(projectDir, cfg.EnvFile)
+	merged := envfile.NewEnv()
+	var mergeTime time.Duration
+	for _, path := range projectEnvPaths(cfg, projectDir, profile) {
+		load := envfile.LoadOptional
+		if path == required {
+			load = envfile.Load
+		}
+		start = time.Now()
+		layer, _, err := load(path)
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", path, err)
+		}
+		target, relErr := filepath.Rel(projectDir, path)
+		if relErr != nil {
+			target = path
+		}
+		rec.add("parse", target, time.Since(start), fmt.Sprintf("%d entries", layer.Len()))
+
+		start = time.Now()
+		merged = envfile.Merge(merged, layer)
+		mergeTime += time.Since(start)
+	}
+	rec.add("merge", "", mergeTime, fmt.Sprintf("%d keys", merged.Len()))
+
+	start = time.Now()
+	merged.ApplySchemes(ref.Schemes(cfg.RefSchemes))
+	merged.ApplyEncryption(cfg.Encryption.Key)
+	if cfg.InterpolateSystemEnv {
+		envfile.InterpolateWithEnviron(merged, os.Environ())
+	} else {
+		envfile.Interpolate(merged)
+	}
+	rec.add("interpolate", "", time.Since(start), "")
+
+	if !merged.HasAnyRefs() || len(cfg.Backends) == 0 {
+		return nil
+	}
+
+	factory.ApplyMemoryConfig(cfg)
+	registry := backend.NewRegistry()
+	defer registry.CloseAll()
+	var timed []*benchBackend
+	for _, bc := range cfg.Backends {
+		start = time.Now()
+		b, err := createChainedBackend(bc)
+		if err != nil {
+			return fmt.Errorf("initializing backends: backend %q: %w", bc.Name, err)
+		}
+		rec.add("open", bc.Name, time.Since(start), bc.EffectiveType())
+
+		tb := &benchBackend{Backend: b}
+		timed = append(timed, tb)
+		if err := registry.Register(tb); err != nil {
+			return err
+		}
+		if err := registry.SetNamespace(bc.Name, bc.Namespace); err != nil {
+			return err
+		}
+	}
+	for name, targets := range cfg.Aliases {
+		if err := registry.SetAlias(name, targets); err != nil {
+			return err
+		}
+	}
+
+	start = time.Now()
+	result, err := resolve.ResolveWithProfiles(merged, registry, cfg.Project, cfg.ProfileChain(profile))
+	if err != nil {
+		return fmt.Errorf("resolving references: %w", err)
+	}
+	total := time.Since(start)
+
+	var fetched time.Duration
+	for _, tb := range timed {
+		rec.add("fetch", tb.Name(), tb.elapsed, fmt.Sprintf("%d call(s)", tb.calls))
+		fetched += tb.elapsed
+	}
+	refs := 0
+	for _, e := range result.Entries {
+		if e.WasRef {
+			refs++
+		}
+	}
+	rec.add("resolve", "", total-fetched, fmt.Sprintf("%d resolved, %d failed", refs, len(result.Errors)))
+	return nil
+}
+
+// printBenchReport writes the stage table and a summary of the time spent
+// in backends.
+func printBenchReport(w io.Writer, report benchReport) {
+	stageWidth, targetWidth := len("STAGE"), len("TARGET")
+	for _, s := range report.Stages {
+		stageWidth = max(stageWidth, len(s.Stage))
+		targetWidth = max(targetWidth, len(s.Target))
+	}
+
+	_, _ = fmt.Fprintf(w, "%-*s  %-*s  %10s  %s\n", stageWidth, "STAGE", targetWidth, "TARGET", "TIME", "DETAIL")
+	for _, s := range report.Stages {
+		line := fmt.Sprintf("%-*s  %-*s  %10s  %s", stageWidth, s.Stage, targetWidth, s.Target, formatBenchMillis(s.Mean), s.Detail)
+		_, _ = fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+	_, _ = fmt.Fprintf(w, "%-*s  %-*s  %10s\n", stageWidth, "total", targetWidth, "", formatBenchMillis(report.Total))
+
+	if report.Runs > 1 {
+		_, _ = fmt.Fprintf(w, "\nmean of %d runs\n", report.Runs)
+	}
+	if report.Total > 0 && report.Backends > 0 {
+		_, _ = fmt.Fprintf(w, "\nbackends: %s of %s (%.0f%%)\n",
+			formatBenchMillis(report.Backends), formatBenchMillis(report.Total), 100*report.Backends/report.Total)
+	}
+}
+
+// benchMillis converts d to milliseconds.
+func benchMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// formatBenchMillis formats a time in milliseconds, to the microsecond.
+func formatBenchMillis(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Microsecond).String()
+}
+
+// benchBackend times the reads that reach a backend.
+type benchBackend struct {
+	backend.Backend
+
+	mu      sync.Mutex
+	calls   int
+	elapsed time.Duration
+}
+
+// record adds one call that started at start.
+func (b *benchBackend) record(start time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls++
+	b.elapsed += time.Since(start)
+}
+
+// Get reads a secret and records the time it took.
+func (b *benchBackend) Get(key string) (string, error) {
+	defer b.record(time.Now())
+	return b.Backend.Get(key)
+}
+
+// GetMany reads many secrets and records the time it took.
+func (b *benchBackend) GetMany(keys []string) (map[string]string, error) {
+	defer b.record(time.Now())
+	return backend.GetMany(b.Backend, keys)
+}
+
+// Close closes the backend if it holds resources.
+func (b *benchBackend) Close() error {
+	if c, ok := b.Backend.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBenchCmd(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "API_KEY=ref://secrets/API_KEY\nHOST=localhost\nURL=http://${HOST}\n")
+	writeTestFile(t, dir, ".env.local", "DEBUG=1\n")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "sk-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	stdout, _, err := execCmd(t, "bench")
+	if err != nil {
+		t.Fatalf("bench: %v", err)
+	}
+	for _, want := range []string{"config", "parse        .env ", ".env.local", "merge", "interpolate", "open         secrets", "fetch        secrets", "1 resolved, 0 failed", "total", "backends:"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "sk-123") {
+		t.Error("bench printed a secret value")
+	}
+
+	stdout, _, err = execCmd(t, "bench", "--runs", "3", "--format", "json")
+	if err != nil {
+		t.Fatalf("bench --format json: %v", err)
+	}
+	var report benchReport
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout)
+	}
+	if report.Runs != 3 || len(report.Stages) != 8 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestBenchCmd_NoRefs(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", "project: app\n")
+	writeTestFile(t, dir, ".env", "PORT=3000\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "bench")
+	if err != nil {
+		t.Fatalf("bench: %v", err)
+	}
+	if strings.Contains(stdout, "fetch") || strings.Contains(stdout, "backends:") {
+		t.Errorf("expected no backend stages:\n%s", stdout)
+	}
+}
+
+func TestBenchCmd_InvalidRuns(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", "project: app\n")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "bench", "--runs", "0"); err == nil {
+		t.Fatal("expected an error for --runs 0")
+	}
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/agent"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/suggest"
+)
+
+// newCacheCmd creates the cache command group for the agent's secret cache.
+func newCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect, clear, or warm the agent's secret cache",
+		Long: `Inspect, clear, or warm the cache of secret values kept by the envref
+agent (see 'envref agent'). The cache lives in the agent, so these commands
+need a running agent.
+
+Writes through envref already drop the values they replace; use
+'envref cache clear' after changing a secret outside envref, and
+'envref cache warm' to read every secret of the project ahead of time, for
+example before going offline.`,
+	}
+
+	cmd.AddCommand(newCacheStatusCmd())
+	cmd.AddCommand(newCacheClearCmd())
+	cmd.AddCommand(newCacheWarmCmd())
+
+	return cmd
+}
+
+// newCacheStatusCmd creates the cache status subcommand.
+func newCacheStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "List the cached keys and when they expire",
+		Long: `List the keys whose values the agent has cached, by backend, with the
+time left until each expires. Values are never shown.
+
+Examples:
+  envref cache status                  # table of cached keys
+  envref cache status --format json    # machine-readable list`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			return runCacheStatus(cmd, format)
+		},
+	}
+
+	cmd.Flags().String("format", "plain", "output format: plain, json")
+
+	return cmd
+}
+
+// runCacheStatus prints the agent's cached entries.
+func runCacheStatus(cmd *cobra.Command, formatStr string) error {
+	format, err := parseFormat(formatStr)
+	if err != nil {
+		return err
+	}
+	if format != FormatPlain && format != FormatJSON {
+		return fmt.Errorf("unsupported format %q for cache status (use plain or json)", formatStr)
+	}
+
+	client, err := requireAgent()
+	if err != nil {
+		return err
+	}
+	entries, err := client.Entries()
+	if err != nil {
+		return fmt.Errorf("listing cache: %w", err)
+	}
+
+	if format == FormatJSON {
+		if entries == nil {
+			entries = []agent.Entry{}
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	w := output.NewWriter(cmd)
+	if len(entries) == 0 {
+		w.Info("no cached values\n")
+		return nil
+	}
+
+	backendWidth, keyWidth := len("BACKEND"), len("KEY")
+	for _, e := range entries {
+		backendWidth = max(backendWidth, len(e.Backend))
+		keyWidth = max(keyWidth, len(e.Key))
+	}
+	out := w.Stdout()
+	_, _ = fmt.Fprintf(out, "%-*s  %-*s  %s\n", backendWidth, "BACKEND", keyWidth, "KEY", "EXPIRES IN")
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(out, "%-*s  %-*s  %s\n", backendWidth, e.Backend, keyWidth, e.Key, time.Until(e.Expires).Round(time.Second))
+	}
+	w.Verbose("\n%d cached value(s) in the agent on %s\n", len(entries), client.Socket())
+	return nil
+}
+
+// newCacheClearCmd creates the cache clear subcommand.
+func newCacheClearCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Drop cached values",
+		Long: `Drop cached values from the agent, so that the next resolve reads them
+from the backends again. Backend sessions are kept.
+
+Examples:
+  envref cache clear                   # drop every cached value
+  envref cache clear --backend vault   # drop the values of one backend`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backendName, _ := cmd.Flags().GetString("backend")
+			return runCacheClear(cmd, backendName)
+		},
+	}
+
+	cmd.Flags().StringP("backend", "b", "", "only drop the values of this backend of the current project")
+
+	return cmd
+}
+
+// runCacheClear drops every cached value, or those of one backend.
+func runCacheClear(cmd *cobra.Command, backendName string) error {
+	client, err := requireAgent()
+	if err != nil {
+		return err
+	}
+
+	var dropped int
+	if backendName == "" {
+		dropped, err = client.Clear()
+	} else {
+		var bc config.BackendConfig
+		if bc, err = projectBackendConfig(backendName); err != nil {
+			return err
+		}
+		dropped, err = client.Invalidate(agentBackendConfig(bc))
+	}
+	if err != nil {
+		return fmt.Errorf("clearing cache: %w", err)
+	}
+
+	output.NewWriter(cmd).Info("cleared %d cached value(s)\n", dropped)
+	return nil
+}
+
+// projectBackendConfig returns the config of the named backend of the
+// project in the working directory.
+func projectBackendConfig(name string) (config.BackendConfig, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return config.BackendConfig{}, fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, _, err := config.Load(cwd)
+	if err != nil {
+		return config.BackendConfig{}, fmt.Errorf("loading config: %w", err)
+	}
+	names := make([]string, 0, len(cfg.Backends))
+	for _, bc := range cfg.Backends {
+		if bc.Name == name {
+			return bc, nil
+		}
+		names = append(names, bc.Name)
+	}
+	return config.BackendConfig{}, fmt.Errorf("unknown backend %q%s", name, suggest.FormatSuggestion(suggest.Keys(name, names)))
+}
+
+// newCacheWarmCmd creates the cache warm subcommand.
+func newCacheWarmCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "warm",
+		Short: "Read every secret of the project into the cache",
+		Long: `Resolve every reference of the current project through the agent, so
+that later resolves are served from its cache. Values are not printed.
+
+With --ttl, every value is read again and kept for that long instead of the
+agent's default, for example to keep working through a flight.
+
+Examples:
+  envref cache warm                    # warm the default profile
+  envref cache warm --profile staging  # warm the staging profile
+  envref cache warm --ttl 8h           # keep the values for 8 hours`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			ttl, _ := cmd.Flags().GetDuration("ttl")
+			return runCacheWarm(cmd, profile, ttl)
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
+	cmd.Flags().Duration("ttl", 0, "keep the values this long instead of the agent's default")
+
+	return cmd
+}
+
+// runCacheWarm resolves the project's references through the agent.
+func runCacheWarm(cmd *cobra.Command, profileOverride string, ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("--ttl must not be negative")
+	}
+	client, err := requireAgent()
+	if err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, projectDir, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	profile := cfg.EffectiveProfile(profileOverride)
+	env, err := loadProjectEnv(cmd, cfg, projectDir, profile)
+	if err != nil {
+		return err
+	}
+
+	w := output.NewWriter(cmd)
+	if !env.HasAnyRefs() || len(cfg.Backends) == 0 {
+		w.Info("no references to warm\n")
+		return nil
+	}
+
+	if ttl > 0 {
+		client = client.WithTTL(ttl)
+	}
+	registry, err := buildAgentRegistry(client, cfg)
+	if err != nil {
+		return fmt.Errorf("initializing backends: %w", err)
+	}
+	result, err := resolveWithProgress(cmd, env, registry, cfg, cfg.ProfileChain(profile))
+	if err != nil {
+		return fmt.Errorf("resolving references: %w", err)
+	}
+	auditRefReads(cfg, projectDir, profile, env, result)
+	for _, keyErr := range result.Errors {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: %s\n", keyErr.Error())
+	}
+
+	warmed := 0
+	for _, e := range result.Entries {
+		if e.WasRef {
+			warmed++
+		}
+	}
+	w.Info("warmed %d value(s)\n", warmed)
+	if len(result.Errors) > 0 {
+		return withExitCode(unresolvedExitCode(result.Errors), fmt.Errorf("%d reference(s) could not be resolved", len(result.Errors)))
+	}
+	return nil
+}
+
+// requireAgent returns a client for the running agent, or an error saying
+// how to start one.
+func requireAgent() (*agent.Client, error) {
+	socket, err := agentSocketPath()
+	if err != nil {
+		return nil, err
+	}
+	client := agent.NewClient(socket)
+	if _, err := client.Status(); err != nil {
+		return nil, fmt.Errorf("no agent is running on %s; the cache lives in the agent (start it with 'envref agent start --detach')", socket)
+	}
+	return client, nil
+}
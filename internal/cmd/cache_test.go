@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/agent"
+)
+
+func TestCacheCmds(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "API_KEY=ref://secrets/API_KEY\nDB_PASS=ref://secrets/DB_PASS\n")
+	chdir(t, dir)
+
+	for _, kv := range []string{"API_KEY=sk-123", "DB_PASS=hunter2"} {
+		key, value, _ := strings.Cut(kv, "=")
+		if _, _, err := execCmd(t, "secret", "set", key, "--value", value, "--no-env"); err != nil {
+			t.Fatalf("secret set: %v", err)
+		}
+	}
+	startTestAgent(t)
+
+	stdout, _, err := execCmd(t, "cache", "warm", "--ttl", "8h")
+	if err != nil {
+		t.Fatalf("cache warm: %v", err)
+	}
+	if !strings.Contains(stdout, "warmed 2 value(s)") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	stdout, _, err = execCmd(t, "cache", "status")
+	if err != nil {
+		t.Fatalf("cache status: %v", err)
+	}
+	for _, want := range []string{"secrets  app/API_KEY", "app/DB_PASS"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("status missing %q:\n%s", want, stdout)
+		}
+	}
+	if !strings.Contains(stdout, "8h0m0s") && !strings.Contains(stdout, "7h59m") {
+		t.Errorf("status does not show the --ttl of cache warm:\n%s", stdout)
+	}
+	if strings.Contains(stdout, "sk-123") {
+		t.Error("cache status printed a secret value")
+	}
+
+	stdout, _, err = execCmd(t, "cache", "status", "--format", "json")
+	if err != nil {
+		t.Fatalf("cache status --format json: %v", err)
+	}
+	var entries []agent.Entry
+	if err := json.Unmarshal([]byte(stdout), &entries); err != nil || len(entries) != 2 {
+		t.Fatalf("unexpected JSON (%v): %s", err, stdout)
+	}
+
+	if _, _, err := execCmd(t, "cache", "clear", "--backend", "secret"); err == nil || !strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("expected a suggestion for an unknown backend, got %v", err)
+	}
+	stdout, _, err = execCmd(t, "cache", "clear", "--backend", "secrets")
+	if err != nil {
+		t.Fatalf("cache clear --backend: %v", err)
+	}
+	if !strings.Contains(stdout, "cleared 2 cached value(s)") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	if _, _, err := execCmd(t, "resolve"); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	stdout, _, err = execCmd(t, "cache", "clear")
+	if err != nil {
+		t.Fatalf("cache clear: %v", err)
+	}
+	if !strings.Contains(stdout, "cleared 2 cached value(s)") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+	stdout, _, _ = execCmd(t, "cache", "status")
+	if !strings.Contains(stdout, "no cached values") {
+		t.Errorf("expected an empty cache, got %q", stdout)
+	}
+}
+
+func TestCacheCmds_NoAgent(t *testing.T) {
+	t.Setenv("ENVREF_AGENT_SOCKET", filepath.Join(t.TempDir(), agent.SocketFileName))
+
+	for _, args := range [][]string{{"cache", "status"}, {"cache", "clear"}, {"cache", "warm"}} {
+		_, _, err := execCmd(t, args...)
+		if err == nil || !strings.Contains(err.Error(), "envref agent start") {
+			t.Errorf("%v: expected a hint to start the agent, got %v", args, err)
+		}
+	}
+}
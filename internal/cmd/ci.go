@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/resolve"
+)
+
+// CI platforms supported by "envref ci export".
+const (
+	ciGitHub   = "github"
+	ciGitLab   = "gitlab"
+	ciCircleCI = "circleci"
+)
+
+// ciPlatforms lists the supported platforms with the environment variable
+// that each one sets in its jobs, used to detect the platform.
+var ciPlatforms = []struct {
+	name, detect string
+}{
+	{ciGitHub, "GITHUB_ACTIONS"},
+	{ciGitLab, "GITLAB_CI"},
+	{ciCircleCI, "CIRCLECI"},
+}
+
+// defaultGitLabDotenv is the file written for a GitLab dotenv report.
+const defaultGitLabDotenv = "envref.env"
+
+// newCICmd creates the ci command group.
+func newCICmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ci",
+		Short: "Pass the resolved environment to later CI steps",
+		Long: `Pass the resolved environment to later steps of a CI job, in the way each
+CI platform provides.`,
+	}
+
+	cmd.AddCommand(newCIExportCmd())
+
+	return cmd
+}
+
+// newCIExportCmd creates the ci export subcommand.
+func newCIExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the resolved environment to later CI steps",
+		Long: `Resolve the environment and export it to the later steps of the CI job.
+Every reference must resolve. The platform is detected from the environment
+unless --platform is given:
+
+  github    Masks every value that came from a ref:// reference with
+            ::add-mask:: and appends the variables to $GITHUB_ENV.
+  gitlab    Writes a dotenv file (envref.env by default) to declare as an
+            artifacts:reports:dotenv report, which passes the variables to
+            later jobs. GitLab cannot mask variables from a dotenv report,
+            and multi-line values are not supported.
+  circleci  Appends export lines to $BASH_ENV, which later steps source.
+            CircleCI cannot mask values set at run time.
+
+Secret values are never printed, except to the files named above.
+
+Examples:
+  envref ci export                                # detect the platform
+  envref ci export --platform gitlab --profile production
+  envref ci export --platform github --output env.txt`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			platform, _ := cmd.Flags().GetString("platform")
+			profile, _ := cmd.Flags().GetString("profile")
+			outPath, _ := cmd.Flags().GetString("output")
+			return runCIExport(cmd, platform, profile, outPath)
+		},
+	}
+
+	cmd.Flags().String("platform", "", "CI platform: github, gitlab, circleci (detected by default)")
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
+	cmd.Flags().StringP("output", "o", "", "file to write instead of the platform's default")
+
+	return cmd
+}
+
+// runCIExport implements the ci export command logic.
+func runCIExport(cmd *cobra.Command, platform, profileOverride, outPath string) error {
+	if platform == "" {
+		platform = detectCIPlatform()
+		if platform == "" {
+			return fmt.Errorf("no CI platform detected; use --platform github, gitlab, or circleci")
+		}
+	}
+
+	var envVar string
+	switch platform {
+	case ciGitHub:
+		envVar = "GITHUB_ENV"
+	case ciCircleCI:
+		envVar = "BASH_ENV"
+	case ciGitLab:
+		if outPath == "" {
+			outPath = defaultGitLabDotenv
+		}
+	default:
+		return fmt.Errorf("unknown CI platform %q (use github, gitlab, or circleci)", platform)
+	}
+	if outPath == "" {
+		outPath = os.Getenv(envVar)
+		if outPath == "" {
+			return fmt.Errorf("$%s is not set; use --output to choose the file", envVar)
+		}
+	}
+
+	entries, err := resolveEnvEntries(cmd, profileOverride, true)
+	if err != nil {
+		return err
+	}
+
+	w := output.NewWriter(cmd)
+	switch platform {
+	case ciGitHub:
+		// Mask before the values reach any file a later step might print.
+		writeGitHubMasks(w.Stdout(), entries)
+		err = appendCIFile(outPath, func(f io.Writer) error { return writeGitHubEnv(f, entries) })
+	case ciGitLab:
+		err = writeGitLabDotenv(outPath, entries)
+	case ciCircleCI:
+		err = appendCIFile(outPath, func(f io.Writer) error { return formatKVShell(f, entryPairs(entries)) })
+	}
+	if err != nil {
+		return err
+	}
+
+	secrets := 0
+	for _, e := range entries {
+		if e.WasRef {
+			secrets++
+		}
+	}
+	if secrets > 0 && platform != ciGitHub {
+		w.Warn("%s cannot mask the %d secret value(s) exported; they are not printed by envref, but will be if a later step prints them\n", platform, secrets)
+	}
+	w.Info("exported %d variable(s) to %s\n", len(entries), outPath)
+	return nil
+}
+
+// detectCIPlatform returns the CI platform the process runs on, or "".
+func detectCIPlatform() string {
+	for _, p := range ciPlatforms {
+		if os.Getenv(p.detect) == "true" {
+			return p.name
+		}
+	}
+	return ""
+}
+
+// entryPairs converts resolved entries to key-value pairs for formatKVPairs.
+func entryPairs(entries []resolve.Entry) []kvPair {
+	pairs := make([]kvPair, len(entries))
+	for i, e := range entries {
+		pairs[i] = kvPair{Key: e.Key, Value: e.Value}
+	}
+	return pairs
+}
+
+// appendCIFile appends what write writes to the file at path, creating it
+// if needed.
+func appendCIFile(path string, write func(io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeGitHubMasks writes an ::add-mask:: workflow command for each line of
+// every value that came from a reference. GitHub masks line by line, so a
+// multi-line value needs a command per line.
+func writeGitHubMasks(w io.Writer, entries []resolve.Entry) {
+	for _, e := range entries {
+		if !e.WasRef {
+			continue
+		}
+		for _, line := range strings.Split(strings.ReplaceAll(e.Value, "\r\n", "\n"), "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "::add-mask::%s\n", escapeGitHubData(line))
+		}
+	}
+}
+
+// escapeGitHubData escapes a workflow command value.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// writeGitHubEnv writes entries in the $GITHUB_ENV format, using the
+// multi-line syntax with a random delimiter for every value so that no
+// value can end the block early.
+func writeGitHubEnv(w io.Writer, entries []resolve.Entry) error {
+	for _, e := range entries {
+		delim, err := randomDelimiter()
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", e.Key, delim, e.Value, delim); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// randomDelimiter returns a heredoc delimiter no value will contain.
+func randomDelimiter() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating delimiter: %w", err)
+	}
+	return "ENVREF_" + hex.EncodeToString(b), nil
+}
+
+// writeGitLabDotenv writes entries as a GitLab dotenv report to a new
+// private file at path. GitLab reads KEY=VALUE lines and rejects multi-line
+// values.
+func writeGitLabDotenv(path string, entries []resolve.Entry) error {
+	var b strings.Builder
+	for _, e := range entries {
+		if strings.ContainsAny(e.Value, "\r\n") {
+			return fmt.Errorf("%s: GitLab dotenv reports do not support multi-line values", e.Key)
+		}
+		fmt.Fprintf(&b, "%s=%s\n", e.Key, e.Value)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/resolve"
+)
+
+// setupCIProject creates a project with a plain value and a secret.
+func setupCIProject(t *testing.T) string {
+	t.Helper()
+	for _, p := range ciPlatforms {
+		t.Setenv(p.detect, "")
+	}
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "PORT=3000\nAPI_KEY=ref://secrets/API_KEY\n")
+	chdir(t, dir)
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "sk-1%2", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	return dir
+}
+
+func TestCIExport_GitHub(t *testing.T) {
+	dir := setupCIProject(t)
+	envFile := filepath.Join(dir, "github_env")
+	writeTestFile(t, dir, "github_env", "EXISTING=1\n")
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_ENV", envFile)
+
+	stdout, _, err := execCmd(t, "ci", "export")
+	if err != nil {
+		t.Fatalf("ci export: %v", err)
+	}
+	if !strings.HasPrefix(stdout, "::add-mask::sk-1%252\n") {
+		t.Errorf("secret not masked first:\n%s", stdout)
+	}
+	if strings.Contains(stdout, "3000") {
+		t.Errorf("plain value masked:\n%s", stdout)
+	}
+
+	data, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := regexp.MustCompile(`^EXISTING=1\nPORT<<(ENVREF_\w+)\n3000\n(ENVREF_\w+)\nAPI_KEY<<(ENVREF_\w+)\nsk-1%2\n(ENVREF_\w+)\n$`)
+	m := re.FindStringSubmatch(string(data))
+	if m == nil || m[1] != m[2] || m[3] != m[4] {
+		t.Errorf("unexpected $GITHUB_ENV:\n%s", data)
+	}
+}
+
+func TestCIExport_GitHubMultilineMask(t *testing.T) {
+	var b strings.Builder
+	writeGitHubMasks(&b, []resolve.Entry{
+		{Key: "CERT", Value: "line one\r\n\nline two\n", WasRef: true},
+		{Key: "HOST", Value: "example.com"},
+	})
+	want := "::add-mask::line one\n::add-mask::line two\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+func TestCIExport_GitLab(t *testing.T) {
+	dir := setupCIProject(t)
+
+	stdout, stderr, err := execCmd(t, "ci", "export", "--platform", "gitlab")
+	if err != nil {
+		t.Fatalf("ci export: %v", err)
+	}
+	if !strings.Contains(stdout, "exported 2 variable(s) to envref.env") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+	if !strings.Contains(stderr, "gitlab cannot mask the 1 secret value(s)") {
+		t.Errorf("missing masking warning: %q", stderr)
+	}
+
+	path := filepath.Join(dir, "envref.env")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "PORT=3000\nAPI_KEY=sk-1%2\n" {
+		t.Errorf("unexpected dotenv report: %q", data)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode %o, want 600", perm)
+	}
+}
+
+func TestCIExport_GitLabMultiline(t *testing.T) {
+	dir := setupCIProject(t)
+	writeTestFile(t, dir, ".env", "CERT=\"a\nb\"\n")
+
+	_, _, err := execCmd(t, "ci", "export", "--platform", "gitlab")
+	if err == nil || !strings.Contains(err.Error(), "CERT: GitLab dotenv reports do not support multi-line values") {
+		t.Fatalf("expected multi-line error, got %v", err)
+	}
+}
+
+func TestCIExport_CircleCI(t *testing.T) {
+	dir := setupCIProject(t)
+	bashEnv := filepath.Join(dir, "bash_env")
+	t.Setenv("CIRCLECI", "true")
+	t.Setenv("BASH_ENV", bashEnv)
+
+	if _, _, err := execCmd(t, "ci", "export"); err != nil {
+		t.Fatalf("ci export: %v", err)
+	}
+	data, err := os.ReadFile(bashEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "export PORT=3000\nexport API_KEY=sk-1%2\n" {
+		t.Errorf("unexpected $BASH_ENV: %q", data)
+	}
+}
+
+func TestCIExport_Errors(t *testing.T) {
+	setupCIProject(t)
+	t.Setenv("GITHUB_ENV", "")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"ci", "export"}, "no CI platform detected"},
+		{[]string{"ci", "export", "--platform", "jenkins"}, `unknown CI platform "jenkins"`},
+		{[]string{"ci", "export", "--platform", "github"}, "$GITHUB_ENV is not set"},
+	}
+	for _, tt := range tests {
+		_, _, err := execCmd(t, tt.args...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.want, err)
+		}
+	}
+}
+
+func TestCIExport_UnresolvedRef(t *testing.T) {
+	dir := setupCIProject(t)
+	writeTestFile(t, dir, ".env", "MISSING=ref://secrets/MISSING\n")
+
+	_, _, err := execCmd(t, "ci", "export", "--platform", "gitlab")
+	if err == nil {
+		t.Fatal("expected an error for an unresolved reference")
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "envref.env")); !os.IsNotExist(statErr) {
+		t.Errorf("dotenv report written despite the error")
+	}
+}
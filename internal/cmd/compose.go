@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/resolve"
+	"github.com/xcke/envref/internal/suggest"
+)
+
+// newComposeCmd creates the compose subcommand.
+func newComposeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compose [service...]",
+		Short: "Write the resolved environment as Docker Compose env files",
+		Long: `Resolve the environment, as 'envref resolve' does, and write it in the
+env file format Docker Compose reads, quoted so that Compose neither
+interpolates nor trims the values.
+
+Services and the keys each receives are declared in .envref.yaml:
+
+  compose:
+    services:
+      - name: api
+        keys: [DATABASE_URL, "API_*"]
+      - name: web
+        keys: ["NEXT_PUBLIC_*"]
+
+Without --out-dir the env file is printed: every key, or the keys of the one
+named service. Pass it to Compose for interpolation of compose.yaml:
+
+  docker compose --env-file <(envref compose) up
+
+With --out-dir, a <service>.env file is written for each named service, or
+for every configured service, to reference from compose.yaml with env_file.
+The files hold secrets: keep the directory out of version control.
+
+Examples:
+  envref compose                                  # every key to stdout
+  envref compose api                              # the api service's keys
+  envref compose --out-dir .envref/compose        # one file per service
+  envref compose web --out-dir .envref/compose    # only web.env`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			outDir, _ := cmd.Flags().GetString("out-dir")
+			strict, _ := cmd.Flags().GetBool("strict")
+			return runCompose(cmd, args, profile, outDir, strict)
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
+	cmd.Flags().String("out-dir", "", "write a <service>.env file per service to this directory")
+	cmd.Flags().Bool("strict", false, "fail with no output if any reference cannot be resolved")
+
+	return cmd
+}
+
+// runCompose implements the compose command logic.
+func runCompose(cmd *cobra.Command, names []string, profileOverride, outDir string, strict bool) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, _, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	if outDir == "" && len(names) > 1 {
+		return fmt.Errorf("only one service can be printed; use --out-dir to write a file per service")
+	}
+	if outDir != "" && len(names) == 0 {
+		if len(cfg.Compose.Services) == 0 {
+			return fmt.Errorf("no compose services configured in %s", config.FullFileName)
+		}
+		names = cfg.Compose.Names()
+	}
+	services := make([]config.ComposeService, len(names))
+	for i, name := range names {
+		service, ok := cfg.Compose.Service(name)
+		if !ok {
+			return fmt.Errorf("unknown compose service %q%s", name, suggest.FormatSuggestion(suggest.Keys(name, cfg.Compose.Names())))
+		}
+		services[i] = service
+	}
+
+	entries, err := resolveEnvEntries(cmd, profileOverride, strict)
+	if err != nil {
+		return err
+	}
+
+	if outDir == "" {
+		service := config.ComposeService{}
+		if len(services) == 1 {
+			service = services[0]
+		}
+		return writeComposeEnv(cmd.OutOrStdout(), entries, service)
+	}
+
+	w := output.NewWriter(cmd)
+	if err := os.MkdirAll(outDir, 0o700); err != nil {
+		return fmt.Errorf("creating %s: %w", outDir, err)
+	}
+	for _, service := range services {
+		path := filepath.Join(outDir, service.Name+".env")
+		n, err := writeComposeEnvFile(path, entries, service)
+		if err != nil {
+			return err
+		}
+		w.Info("wrote %s (%d keys)\n", path, n)
+		if gitTracksPath(path) {
+			w.Warn("%s is not ignored by git and holds secrets\n", path)
+		}
+	}
+	return nil
+}
+
+// writeComposeEnvFile writes the keys of service to a new private file at
+// path, replacing it atomically, and returns how many it wrote.
+func writeComposeEnvFile(path string, entries []resolve.Entry, service config.ComposeService) (int, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return 0, fmt.Errorf("writing %s: %w", path, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := writeComposeEnv(tmp, entries, service); err != nil {
+		_ = tmp.Close()
+		return 0, fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	n := 0
+	for _, e := range entries {
+		if service.Includes(e.Key) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// writeComposeEnv writes the entries service receives as a Compose env
+// file.
+func writeComposeEnv(w io.Writer, entries []resolve.Entry, service config.ComposeService) error {
+	for _, e := range entries {
+		if !service.Includes(e.Key) {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", e.Key, composeQuote(e.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// composeQuote quotes a value for a Compose env file. Compose interpolates
+// ${VAR} in unquoted and double-quoted values and trims unquoted ones, so
+// anything beyond a plain word is single-quoted, which Compose reads
+// literally. Values a single-quoted string cannot hold (a single quote, a
+// backslash, or a newline) are double-quoted with escapes instead.
+func composeQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(s, " \t\n\r'\"\\$`#=") {
+		return s
+	}
+	if !strings.ContainsAny(s, "'\\\n\r") {
+		return "'" + s + "'"
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// gitTracksPath reports whether git would pick up the file at path: it is
+// inside a git work tree and not ignored.
+func gitTracksPath(path string) bool {
+	// check-ignore exits 0 for ignored paths, 1 for others, and 128
+	// outside a work tree.
+	err := exec.Command("git", "-C", filepath.Dir(path), "check-ignore", "-q", filepath.Base(path)).Run()
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == 1
+}
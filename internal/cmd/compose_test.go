@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const composeTestConfig = `project: app
+compose:
+  services:
+    - name: api
+      keys: [DATABASE_URL, "API_*"]
+    - name: web
+      keys: ["WEB_*"]
+`
+
+func TestComposeCmd_Stdout(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", composeTestConfig)
+	writeTestFile(t, dir, ".env", "DATABASE_URL=postgres://db/app\nAPI_KEY='a b$c'\nWEB_PORT=3000\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "compose")
+	if err != nil {
+		t.Fatalf("compose: %v", err)
+	}
+	want := "DATABASE_URL=postgres://db/app\nAPI_KEY='a b$c'\nWEB_PORT=3000\n"
+	if stdout != want {
+		t.Errorf("compose:\ngot  %q\nwant %q", stdout, want)
+	}
+
+	stdout, _, err = execCmd(t, "compose", "web")
+	if err != nil {
+		t.Fatalf("compose web: %v", err)
+	}
+	if stdout != "WEB_PORT=3000\n" {
+		t.Errorf("compose web: got %q", stdout)
+	}
+}
+
+func TestComposeCmd_OutDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", composeTestConfig)
+	writeTestFile(t, dir, ".env", "DATABASE_URL=postgres://db/app\nAPI_KEY=sk-123\nWEB_PORT=3000\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "compose", "--out-dir", "out")
+	if err != nil {
+		t.Fatalf("compose --out-dir: %v", err)
+	}
+	if !strings.Contains(stdout, "wrote "+filepath.Join("out", "api.env")+" (2 keys)") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	files := map[string]string{
+		"api.env": "DATABASE_URL=postgres://db/app\nAPI_KEY=sk-123\n",
+		"web.env": "WEB_PORT=3000\n",
+	}
+	for name, want := range files {
+		path := filepath.Join(dir, "out", name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading %s: %v", name, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s: got %q, want %q", name, data, want)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("%s: mode %o, want 600", name, perm)
+		}
+	}
+
+	if err := os.Remove(filepath.Join(dir, "out", "web.env")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := execCmd(t, "compose", "api", "--out-dir", "out"); err != nil {
+		t.Fatalf("compose api --out-dir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "web.env")); !os.IsNotExist(err) {
+		t.Error("compose api should only write api.env")
+	}
+}
+
+func TestComposeCmd_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", composeTestConfig)
+	writeTestFile(t, dir, ".env", "A=1\n")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "compose", "apu")
+	if err == nil || !strings.Contains(err.Error(), `unknown compose service "apu"`) || !strings.Contains(err.Error(), "api") {
+		t.Errorf("expected an unknown service error with a suggestion, got %v", err)
+	}
+
+	_, _, err = execCmd(t, "compose", "api", "web")
+	if err == nil || !strings.Contains(err.Error(), "--out-dir") {
+		t.Errorf("expected printing two services to fail, got %v", err)
+	}
+
+	writeTestFile(t, dir, ".envref.yaml", "project: app\n")
+	_, _, err = execCmd(t, "compose", "--out-dir", "out")
+	if err == nil || !strings.Contains(err.Error(), "no compose services configured") {
+		t.Errorf("expected a missing services error, got %v", err)
+	}
+}
+
+func TestComposeQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "''"},
+		{"plain", "plain"},
+		{"postgres://u@h:5432/db?x=1", "'postgres://u@h:5432/db?x=1'"},
+		{"has space", "'has space'"},
+		{"${HOME}", "'${HOME}'"},
+		{"a #comment", "'a #comment'"},
+		{"it's", `"it's"`},
+		{`C:\path`, `"C:\\path"`},
+		{"line1\nline2", `"line1\nline2"`},
+		{"it's $5 \"now\"", `"it's \$5 \"now\""`},
+	}
+	for _, tt := range tests {
+		if got := composeQuote(tt.in); got != tt.want {
+			t.Errorf("composeQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -2,15 +2,19 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/backend"
 	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
 )
 
 // newConfigCmd creates the config command group.
@@ -25,6 +29,10 @@ The effective configuration is the result of merging the global config
 	}
 
 	cmd.AddCommand(newConfigShowCmd())
+	cmd.AddCommand(newConfigGetCmd())
+	cmd.AddCommand(newConfigSetCmd())
+	cmd.AddCommand(newConfigValidateCmd())
+	cmd.AddCommand(newConfigSchemaCmd())
 
 	return cmd
 }
@@ -64,8 +72,12 @@ type configShowOutput struct {
 	Project       string                `json:"project"`
 	EnvFile       string                `json:"env_file"`
 	LocalFile     string                `json:"local_file"`
+	EnvFiles      []string              `json:"env_files,omitempty"`
 	ActiveProfile string                `json:"active_profile,omitempty"`
+	OSOverrides   []string              `json:"os_overrides,omitempty"`
+	AppliedOS     string                `json:"applied_os,omitempty"`
 	Backends      []configBackendOutput `json:"backends,omitempty"`
+	Aliases       map[string][]string   `json:"aliases,omitempty"`
 	Profiles      map[string]string     `json:"profiles,omitempty"`
 	ConfigFile    string                `json:"config_file"`
 	GlobalConfig  string                `json:"global_config,omitempty"`
@@ -73,9 +85,273 @@ type configShowOutput struct {
 
 // configBackendOutput represents a backend in JSON output.
 type configBackendOutput struct {
-	Name   string            `json:"name"`
-	Type   string            `json:"type"`
-	Config map[string]string `json:"config,omitempty"`
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Namespace  string            `json:"namespace,omitempty"`
+	Config     map[string]string `json:"config,omitempty"`
+	Middleware []string          `json:"middleware,omitempty"`
+}
+
+// newConfigGetCmd creates the config get subcommand.
+func newConfigGetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get <path>",
+		Short: "Print a value from .envref.yaml or the global config",
+		Long: `Print the value at a key path in the project .envref.yaml, or in the global
+config with --global. Nested keys are separated by dots and list elements
+are selected with [index]. Mappings and lists are printed as YAML.
+
+The value is read from the file as written; use 'envref config show' for
+the effective configuration after merging and environment overrides.
+
+Examples:
+  envref config get project
+  envref config get backends[0].config.region
+  envref config get --global active_profile`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			global, _ := cmd.Flags().GetBool("global")
+			return runConfigGet(cmd, args[0], global)
+		},
+	}
+
+	cmd.Flags().Bool("global", false, "read the global config instead of .envref.yaml")
+
+	return cmd
+}
+
+// runConfigGet prints the value at keyPath in the selected config file.
+func runConfigGet(cmd *cobra.Command, keyPath string, global bool) error {
+	path, err := configFilePath(global)
+	if err != nil {
+		return err
+	}
+	value, err := config.GetValue(path, keyPath)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), value)
+	return nil
+}
+
+// newConfigSetCmd creates the config set subcommand.
+func newConfigSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set <path> <value>",
+		Short: "Set a value in .envref.yaml or the global config",
+		Long: `Set the value at a key path in the project .envref.yaml, or in the global
+config with --global. Comments and key order are preserved.
+
+Nested keys are separated by dots and list elements are selected with
+[index]; missing keys are created, and an index equal to the list length
+appends an element. The value is parsed as YAML, so "true" and "8080" keep
+their types and "[vault, keychain]" sets a list.
+
+The edited file must still match the config schema (see 'envref config
+validate'), so a misspelled field name is rejected instead of written.
+
+With --encrypt, the value of a backend config key (such as an API token) is
+encrypted and written as an !encrypted value, so the file stays safe to
+commit. It is decrypted when the backend is created, using the vault
+passphrase from ENVREF_VAULT_PASSPHRASE or the OS keychain; --remember
+stores the passphrase in the keychain.
+
+Examples:
+  envref config set active_profile staging
+  envref config set backends[0].config.region us-east-1
+  envref config set aliases.secrets "[vault, keychain]"
+  envref config set --global backends[0].name keychain
+  envref config set --encrypt backends[1].config.token hvs.XXXX`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			global, _ := cmd.Flags().GetBool("global")
+			encrypt, _ := cmd.Flags().GetBool("encrypt")
+			remember, _ := cmd.Flags().GetBool("remember")
+			if remember && !encrypt {
+				return fmt.Errorf("--remember requires --encrypt")
+			}
+			return runConfigSet(cmd, args[0], args[1], global, encrypt, remember)
+		},
+	}
+
+	cmd.Flags().Bool("global", false, "edit the global config instead of .envref.yaml")
+	cmd.Flags().Bool("encrypt", false, "encrypt the value with the vault passphrase (backend config keys only)")
+	cmd.Flags().Bool("remember", false, "store the passphrase in the OS keychain (with --encrypt)")
+
+	return cmd
+}
+
+// runConfigSet writes value at keyPath in the selected config file,
+// encrypted if requested.
+func runConfigSet(cmd *cobra.Command, keyPath, value string, global, encrypt, remember bool) error {
+	w := output.NewWriter(cmd)
+
+	path, err := configFilePath(global)
+	if err != nil {
+		return err
+	}
+	if !encrypt {
+		if err := config.SetValue(path, keyPath, value); err != nil {
+			return err
+		}
+		w.Info("set %s in %s\n", keyPath, path)
+		return nil
+	}
+
+	passphrase, err := config.Passphrase()
+	if errors.Is(err, config.ErrNoPassphrase) {
+		passphrase, err = promptVaultPassphraseForAccess(cmd)
+	}
+	if err != nil {
+		return fmt.Errorf("passphrase: %w", err)
+	}
+	ciphertext, err := config.EncryptValue(value, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := config.SetEncryptedValue(path, keyPath, ciphertext); err != nil {
+		return err
+	}
+	if remember {
+		if err := backend.SetKeychainItem(config.PassphraseKeychainItem, passphrase); err != nil {
+			return fmt.Errorf("storing passphrase in keychain: %w", err)
+		}
+	}
+	w.Info("set %s (encrypted) in %s\n", keyPath, path)
+	return nil
+}
+
+// configFilePath returns the global config path, or the nearest project
+// .envref.yaml.
+func configFilePath(global bool) (string, error) {
+	if global {
+		path := config.GlobalConfigPath()
+		if path == "" {
+			return "", fmt.Errorf("cannot determine global config directory")
+		}
+		return path, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("getting working directory: %w", err)
+	}
+	return config.FindFile(cwd)
+}
+
+// newConfigValidateCmd creates the config validate subcommand.
+func newConfigValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [path]",
+		Short: "Check .envref.yaml against the config JSON Schema",
+		Long: `Check a config file against the envref JSON Schema and report each problem
+with its line and column.
+
+Unknown fields (e.g. a typo like "activ_profile") and values of the wrong
+type are silently ignored when the config is loaded; this command catches
+them. Semantic checks (missing project name, duplicate backends, undefined
+active profile, ...) are reported as well, except for the global config,
+which only holds defaults.
+
+Without an argument, the nearest .envref.yaml is checked. The command exits
+with code 1 if any problem is found.
+
+Examples:
+  envref config validate                  # check the project config
+  envref config validate ~/.config/envref/config.yaml`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := ""
+			if len(args) == 1 {
+				path = args[0]
+			}
+			return runConfigValidate(cmd, path)
+		},
+	}
+
+	return cmd
+}
+
+// runConfigValidate checks the config at path (or the nearest project
+// config when path is empty) against the JSON Schema and Config.Validate.
+func runConfigValidate(cmd *cobra.Command, path string) error {
+	w := output.NewWriter(cmd)
+	errOut := cmd.ErrOrStderr()
+
+	if path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("getting working directory: %w", err)
+		}
+		path, err = config.FindFile(cwd)
+		if err != nil {
+			return err
+		}
+	}
+
+	schemaErrs, err := config.CheckFile(path)
+	if err != nil {
+		return err
+	}
+	for _, e := range schemaErrs {
+		_, _ = fmt.Fprintf(errOut, "%s:%s\n", path, e.Error())
+	}
+	problems := len(schemaErrs)
+
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		return err
+	}
+	// The global config is a set of defaults and need not be complete (it
+	// usually has no project name), so only the schema applies to it.
+	var ve *config.ValidationError
+	if !isGlobalConfig(path) && errors.As(cfg.Validate(), &ve) {
+		for _, p := range ve.Problems {
+			_, _ = fmt.Fprintf(errOut, "%s: %s\n", path, p)
+		}
+		problems += len(ve.Problems)
+	}
+	for _, warning := range cfg.Warnings() {
+		w.Warn("%s: %s\n", path, warning)
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%s: %d problem(s) found", path, problems)
+	}
+	w.Info("%s: %s is valid\n", w.Green("OK"), path)
+	return nil
+}
+
+// isGlobalConfig reports whether path refers to the global config file.
+func isGlobalConfig(path string) bool {
+	global := config.GlobalConfigPath()
+	if global == "" {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	return abs == filepath.Clean(global)
+}
+
+// newConfigSchemaCmd creates the config schema subcommand.
+func newConfigSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema for .envref.yaml",
+		Long: `Print the JSON Schema describing .envref.yaml.
+
+Save it and point your editor at it for completion and inline validation,
+e.g. with the YAML language server:
+
+  envref config schema > .envref.schema.json
+  # yaml-language-server: $schema=.envref.schema.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := cmd.OutOrStdout().Write(config.JSONSchema())
+			return err
+		},
+	}
 }
 
 // runConfigShow implements the config show command logic.
@@ -113,7 +389,9 @@ func printConfigJSON(w io.Writer, cfg *config.Config, projectDir string) error {
 		Project:       cfg.Project,
 		EnvFile:       cfg.EnvFile,
 		LocalFile:     cfg.LocalFile,
-		ActiveProfile: cfg.ActiveProfile,
+		EnvFiles:      cfg.EnvFiles,
+		OSOverrides:   sortedOSNames(cfg),
+		ActiveProfile: cfg.EffectiveProfile(""),
 		ConfigFile:    filepath.Join(projectDir, config.FullFileName),
 	}
 
@@ -123,13 +401,20 @@ func printConfigJSON(w io.Writer, cfg *config.Config, projectDir string) error {
 		}
 	}
 
+	if _, ok := cfg.OS[runtime.GOOS]; ok {
+		output.AppliedOS = runtime.GOOS
+	}
+
 	for _, b := range cfg.Backends {
 		output.Backends = append(output.Backends, configBackendOutput{
-			Name:   b.Name,
-			Type:   b.EffectiveType(),
-			Config: b.Config,
+			Name:       b.Name,
+			Type:       b.EffectiveType(),
+			Namespace:  b.Namespace,
+			Config:     displayBackendConfig(b),
+			Middleware: middlewareLabels(b),
 		})
 	}
+	output.Aliases = cfg.Aliases
 
 	if len(cfg.Profiles) > 0 {
 		output.Profiles = make(map[string]string, len(cfg.Profiles))
@@ -143,6 +428,23 @@ func printConfigJSON(w io.Writer, cfg *config.Config, projectDir string) error {
 	return enc.Encode(output)
 }
 
+// middlewareLabels describes a backend's middleware, outermost first, e.g.
+// "cache(ttl=5m)".
+func middlewareLabels(b config.BackendConfig) []string {
+	var labels []string
+	for _, m := range b.Middleware {
+		switch {
+		case m.Type == "cache" && m.TTL != "":
+			labels = append(labels, fmt.Sprintf("%s(ttl=%s)", m.Type, m.TTL))
+		case m.Type == "rate-limit":
+			labels = append(labels, fmt.Sprintf("%s(rate=%g)", m.Type, m.Rate))
+		default:
+			labels = append(labels, m.Type)
+		}
+	}
+	return labels
+}
+
 // printConfigPlain outputs the effective config in a human-readable format.
 func printConfigPlain(w io.Writer, cfg *config.Config, projectDir string) error {
 	write := func(format string, args ...interface{}) {
@@ -152,9 +454,15 @@ func printConfigPlain(w io.Writer, cfg *config.Config, projectDir string) error
 	write("Project: %s\n", cfg.Project)
 	write("EnvFile: %s\n", cfg.EnvFile)
 	write("LocalFile: %s\n", cfg.LocalFile)
+	if len(cfg.EnvFiles) > 0 {
+		write("EnvFiles: %s\n", strings.Join(cfg.EnvFiles, ", "))
+	}
+	if len(cfg.OS) > 0 {
+		write("OSOverrides: %s\n", strings.Join(osOverrideLabels(cfg), ", "))
+	}
 
-	if cfg.ActiveProfile != "" {
-		write("ActiveProfile: %s\n", cfg.ActiveProfile)
+	if profile := cfg.EffectiveProfile(""); profile != "" {
+		write("ActiveProfile: %s\n", profile)
 	}
 
 	// Backends.
@@ -162,15 +470,34 @@ func printConfigPlain(w io.Writer, cfg *config.Config, projectDir string) error
 		write("\nBackends:\n")
 		for _, b := range cfg.Backends {
 			write("  - %s (type: %s)\n", b.Name, b.EffectiveType())
+			if b.Namespace != "" {
+				write("    namespace: %s\n", b.Namespace)
+			}
+			if len(b.Middleware) > 0 {
+				write("    middleware: %s\n", strings.Join(middlewareLabels(b), ", "))
+			}
 			if len(b.Config) > 0 {
-				keys := sortedKeys(b.Config)
-				for _, k := range keys {
-					write("    %s: %s\n", k, b.Config[k])
+				display := displayBackendConfig(b)
+				for _, k := range sortedKeys(display) {
+					write("    %s: %s\n", k, display[k])
 				}
 			}
 		}
 	}
 
+	// Aliases.
+	if len(cfg.Aliases) > 0 {
+		write("\nAliases:\n")
+		names := make([]string, 0, len(cfg.Aliases))
+		for name := range cfg.Aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			write("  - %s -> %s\n", name, strings.Join(cfg.Aliases[name], ", "))
+		}
+	}
+
 	// Profiles.
 	if len(cfg.Profiles) > 0 {
 		write("\nProfiles:\n")
@@ -178,7 +505,7 @@ func printConfigPlain(w io.Writer, cfg *config.Config, projectDir string) error
 		for _, name := range names {
 			envFile := cfg.ProfileEnvFile(name)
 			active := ""
-			if name == cfg.ActiveProfile {
+			if name == cfg.EffectiveProfile("") {
 				active = " (active)"
 			}
 			write("  - %s -> %s%s\n", name, envFile, active)
@@ -212,8 +539,16 @@ func printConfigTable(w io.Writer, cfg *config.Config, projectDir string) error
 		{Key: "local_file", Value: cfg.LocalFile},
 	}
 
-	if cfg.ActiveProfile != "" {
-		pairs = append(pairs, kvPair{Key: "active_profile", Value: cfg.ActiveProfile})
+	if len(cfg.EnvFiles) > 0 {
+		pairs = append(pairs, kvPair{Key: "env_files", Value: strings.Join(cfg.EnvFiles, ", ")})
+	}
+
+	if len(cfg.OS) > 0 {
+		pairs = append(pairs, kvPair{Key: "os", Value: strings.Join(osOverrideLabels(cfg), ", ")})
+	}
+
+	if profile := cfg.EffectiveProfile(""); profile != "" {
+		pairs = append(pairs, kvPair{Key: "active_profile", Value: profile})
 	}
 
 	if len(cfg.Backends) > 0 {
@@ -240,6 +575,44 @@ func printConfigTable(w io.Writer, cfg *config.Config, projectDir string) error
 	return formatKVTable(w, pairs)
 }
 
+// sortedOSNames returns the systems with an os: section, sorted.
+func sortedOSNames(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.OS))
+	for name := range cfg.OS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// osOverrideLabels returns the sorted os: section names, marking the one
+// applied on this system.
+func osOverrideLabels(cfg *config.Config) []string {
+	labels := sortedOSNames(cfg)
+	for i, name := range labels {
+		if name == runtime.GOOS {
+			labels[i] += " (active)"
+		}
+	}
+	return labels
+}
+
+// displayBackendConfig returns the backend's config with encrypted values
+// shown as the !encrypted tag instead of their ciphertext.
+func displayBackendConfig(b config.BackendConfig) map[string]string {
+	if len(b.Encrypted) == 0 {
+		return b.Config
+	}
+	out := make(map[string]string, len(b.Config))
+	for k, v := range b.Config {
+		if b.IsEncrypted(k) {
+			v = config.EncryptedTag
+		}
+		out[k] = v
+	}
+	return out
+}
+
 // sortedKeys returns the keys of a map sorted alphabetically.
 func sortedKeys(m map[string]string) []string {
 	keys := make([]string, 0, len(m))
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"encoding/json"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -221,6 +222,34 @@ backends:
 	assert.Equal(t, "https://vault.example.com", output.Backends[0].Config["address"])
 }
 
+func TestConfigShowCmd_BackendMiddleware(t *testing.T) {
+	dir := t.TempDir()
+	cfgContent := `project: myapp
+backends:
+  - name: ssm
+    type: aws-ssm
+    middleware:
+      - type: cache
+        ttl: 1m
+      - type: rate-limit
+        rate: 5
+      - type: logging
+`
+	writeTestFile(t, dir, config.FullFileName, cfgContent)
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "config", "show")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "middleware: cache(ttl=1m), rate-limit(rate=5), logging")
+
+	stdout, _, err = execCmd(t, "config", "show", "--format", "json")
+	require.NoError(t, err)
+	var output configShowOutput
+	require.NoError(t, json.Unmarshal([]byte(stdout), &output))
+	require.Len(t, output.Backends, 1)
+	assert.Equal(t, []string{"cache(ttl=1m)", "rate-limit(rate=5)", "logging"}, output.Backends[0].Middleware)
+}
+
 func TestConfigShowCmd_Help(t *testing.T) {
 	stdout, _, err := execCmd(t, "config", "show", "--help")
 	require.NoError(t, err)
@@ -317,3 +346,116 @@ func TestConfigShowCmd_ConfigFileLocation(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, stdout, "Config: "+filepath.Join(dir, config.FullFileName))
 }
+
+func TestConfigValidateCmd_Valid(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, config.FullFileName, "project: myapp\nbackends:\n  - name: keychain\n")
+	chdir(t, dir)
+
+	stdout, stderr, err := execCmd(t, "config", "validate")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "is valid")
+	assert.Empty(t, stderr)
+}
+
+func TestConfigValidateCmd_Problems(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, config.FullFileName, "activ_profile: dev\nbackends:\n  - name: keychain\n  - name: keychain\n")
+
+	_, stderr, err := execCmd(t, "config", "validate", path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "3 problem(s) found")
+	assert.Contains(t, stderr, path+`:1:1: unknown field "activ_profile"; did you mean active_profile?`)
+	assert.Contains(t, stderr, "project name is required")
+	assert.Contains(t, stderr, `duplicate backend name "keychain"`)
+}
+
+func TestConfigValidateCmd_GlobalConfigSkipsSemanticChecks(t *testing.T) {
+	globalDir := t.TempDir()
+	t.Setenv("ENVREF_CONFIG_DIR", globalDir)
+	path := writeTestFile(t, globalDir, config.GlobalFileName, "backends:\n  - name: keychain\n")
+
+	_, _, err := execCmd(t, "config", "validate", path)
+	assert.NoError(t, err)
+}
+
+func TestConfigSchemaCmd(t *testing.T) {
+	stdout, _, err := execCmd(t, "config", "schema")
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Contains(t, doc, "properties")
+}
+
+func TestConfigGetSetCmd(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, config.FullFileName, "# envref config\nproject: myapp\nbackends:\n  - name: ssm\n    type: aws-ssm\n    config:\n      region: eu-west-1 # primary region\n")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "config", "set", "backends[0].config.region", "us-east-1")
+	require.NoError(t, err)
+	_, _, err = execCmd(t, "config", "set", "active_profile", "staging")
+	require.NoError(t, err)
+
+	stdout, _, err := execCmd(t, "config", "get", "backends[0].config.region")
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1\n", stdout)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# envref config\n")
+	assert.Contains(t, string(data), "region: us-east-1 # primary region\n")
+	assert.Contains(t, string(data), "active_profile: staging\n")
+
+	_, _, err = execCmd(t, "config", "get", "backends[0].config.missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "key not found")
+
+	_, _, err = execCmd(t, "config", "set", "activ_profile", "dev")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "did you mean active_profile?")
+}
+
+func TestConfigSetCmd_Global(t *testing.T) {
+	globalDir := t.TempDir()
+	t.Setenv("ENVREF_CONFIG_DIR", globalDir)
+	chdir(t, t.TempDir())
+
+	_, _, err := execCmd(t, "config", "set", "--global", "backends[0].name", "keychain")
+	require.NoError(t, err)
+
+	stdout, _, err := execCmd(t, "config", "get", "--global", "backends")
+	require.NoError(t, err)
+	assert.Equal(t, "- name: keychain\n", stdout)
+}
+
+func TestConfigSetCmd_Encrypt(t *testing.T) {
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "test-passphrase")
+	dir := t.TempDir()
+	vaultPath := filepath.Join(dir, "encrypted-path.db")
+	path := writeVaultTestConfig(t, dir, "myapp", "placeholder.db")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "config", "set", "--encrypt", "backends[0].config.path", vaultPath)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "path: !encrypted ")
+	assert.NotContains(t, string(data), vaultPath)
+
+	stdout, _, err := execCmd(t, "config", "show")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "path: !encrypted\n")
+
+	// The backend sees the decrypted value.
+	_, _, err = execCmd(t, "vault", "init")
+	require.NoError(t, err)
+	_, err = os.Stat(vaultPath)
+	assert.NoError(t, err, "vault should be created at the decrypted path")
+
+	_, _, err = execCmd(t, "config", "set", "--encrypt", "project", "other")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only supported for backends[N].config.<key>")
+}
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+)
+
+// newDevcontainerCmd creates the devcontainer subcommand.
+func newDevcontainerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "devcontainer",
+		Short: "Pass the environment into a dev container through devcontainer.json",
+		Long: `Add every key of the project's environment to devcontainer.json as
+"${localEnv:KEY}", so that a dev container started under 'envref run' gets
+the resolved values:
+
+  envref run -- devcontainer up --workspace-folder .
+  envref run -- code .
+
+Only key names are written, never values, so devcontainer.json stays safe
+to commit. Keys already in the property are updated, other entries are
+kept. Run the command again after adding keys to the environment.
+
+By default the keys go to remoteEnv, which applies to the processes of the
+tools connected to the container. With --target containerEnv they are set
+on the container itself when it is created, and changes need a rebuild.
+
+devcontainer.json may contain comments, but they are not kept when the file
+is rewritten. GitHub Codespaces has no local environment; there, install
+envref in the container and run commands with 'envref run' instead.
+
+Examples:
+  envref devcontainer                             # update .devcontainer/devcontainer.json
+  envref devcontainer --target containerEnv
+  envref devcontainer --print                     # show the snippet instead`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, _ := cmd.Flags().GetString("file")
+			target, _ := cmd.Flags().GetString("target")
+			profile, _ := cmd.Flags().GetString("profile")
+			printOnly, _ := cmd.Flags().GetBool("print")
+			return runDevcontainer(cmd, file, target, profile, printOnly)
+		},
+	}
+
+	cmd.Flags().String("file", "", "devcontainer.json to update (default: .devcontainer/devcontainer.json)")
+	cmd.Flags().String("target", "remoteEnv", "property to fill: remoteEnv, containerEnv")
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
+	cmd.Flags().Bool("print", false, "print the snippet without changing any file")
+
+	return cmd
+}
+
+// runDevcontainer implements the devcontainer command logic.
+func runDevcontainer(cmd *cobra.Command, file, target, profileOverride string, printOnly bool) error {
+	switch target {
+	case "remoteEnv", "containerEnv":
+	default:
+		return fmt.Errorf("unknown target %q (use remoteEnv or containerEnv)", target)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, projectDir, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	env, err := loadProjectEnv(cmd, cfg, projectDir, cfg.EffectiveProfile(profileOverride))
+	if err != nil {
+		return err
+	}
+	keys := env.Keys()
+
+	w := output.NewWriter(cmd)
+	if printOnly {
+		snippet := []jsonMember{{Key: target, Value: devcontainerEnv(nil, keys)}}
+		data, err := encodeJSONObject(snippet, "\t")
+		if err != nil {
+			return err
+		}
+		_, err = w.Stdout().Write(data)
+		return err
+	}
+
+	path := devcontainerPath(projectDir, file)
+	original, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s not found; add a dev container configuration first, or use --print", path)
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	stripped, hadComments := stripJSONC(original)
+	members, err := decodeJSONObject(stripped)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	var existing json.RawMessage
+	i := -1
+	for j, m := range members {
+		if m.Key == target {
+			existing, i = m.Value, j
+		}
+	}
+	var current []jsonMember
+	if existing != nil {
+		if current, err = decodeJSONObject(existing); err != nil {
+			return fmt.Errorf("parsing %s: %s: %w", path, target, err)
+		}
+	}
+	updated := devcontainerEnv(current, keys)
+	if i < 0 {
+		members = append(members, jsonMember{Key: target, Value: updated})
+	} else {
+		members[i].Value = updated
+	}
+
+	data, err := encodeJSONObject(members, detectIndent(original))
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, original) {
+		w.Info("%s is up to date (%d keys)\n", path, len(keys))
+		return nil
+	}
+	if hadComments {
+		w.Warn("comments in %s are not kept\n", path)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec // devcontainer.json holds no secrets
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	w.Info("wrote %d keys to %s in %s\n", len(keys), target, path)
+	w.Info("start the container under envref, e.g. 'envref run -- devcontainer up --workspace-folder .'\n")
+	return nil
+}
+
+// devcontainerPath returns the devcontainer.json to update: file if given,
+// otherwise .devcontainer/devcontainer.json, or .devcontainer.json if only
+// that one exists.
+func devcontainerPath(projectDir, file string) string {
+	if file != "" {
+		return resolveFilePath(projectDir, file)
+	}
+	path := filepath.Join(projectDir, ".devcontainer", "devcontainer.json")
+	if !fileExists(path) {
+		if alt := filepath.Join(projectDir, ".devcontainer.json"); fileExists(alt) {
+			return alt
+		}
+	}
+	return path
+}
+
+// devcontainerEnv returns the members of an env property with every key
+// set to its local environment variable, keeping the other members.
+func devcontainerEnv(current []jsonMember, keys []string) json.RawMessage {
+	members := append([]jsonMember(nil), current...)
+	for _, key := range keys {
+		value := mustMarshalJSON("${localEnv:" + key + "}")
+		found := false
+		for i := range members {
+			if members[i].Key == key {
+				members[i].Value, found = value, true
+			}
+		}
+		if !found {
+			members = append(members, jsonMember{Key: key, Value: value})
+		}
+	}
+	data, _ := encodeJSONObject(members, "")
+	return bytes.TrimSpace(data)
+}
+
+// jsonMember is a member of a JSON object whose order is kept.
+type jsonMember struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// decodeJSONObject decodes a JSON object into its members, in order.
+func decodeJSONObject(data []byte) ([]jsonMember, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	var members []jsonMember
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		members = append(members, jsonMember{Key: key, Value: value})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON object")
+	}
+	return members, nil
+}
+
+// encodeJSONObject encodes members as a JSON object indented with indent,
+// or compact if indent is empty.
+func encodeJSONObject(members []jsonMember, indent string) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(mustMarshalJSON(m.Key))
+		b.WriteByte(':')
+		if err := json.Compact(&b, m.Value); err != nil {
+			return nil, fmt.Errorf("encoding %s: %w", m.Key, err)
+		}
+	}
+	b.WriteByte('}')
+	if indent == "" {
+		return b.Bytes(), nil
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, b.Bytes(), "", indent); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// mustMarshalJSON encodes a string as JSON without escaping HTML.
+func mustMarshalJSON(s string) json.RawMessage {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return bytes.TrimSpace(b.Bytes())
+}
+
+// stripJSONC removes the comments and trailing commas that JSON with
+// comments allows, and reports whether there were comments.
+func stripJSONC(data []byte) ([]byte, bool) {
+	out := make([]byte, 0, len(data))
+	hadComments := false
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			hadComments = true
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			hadComments = true
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+		case c == '}' || c == ']':
+			// Drop a comma left before the closing bracket.
+			j := len(out) - 1
+			for j >= 0 && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, hadComments
+}
+
+// detectIndent returns the indentation of the first indented line of
+// data, or a tab.
+func detectIndent(data []byte) string {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && len(trimmed) < len(line) {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+	return "\t"
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDevcontainerCmd_UpdatesFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", "project: app\n")
+	writeTestFile(t, dir, ".env", "PORT=3000\nAPI_KEY=ref://secrets/API_KEY\n")
+	if err := os.Mkdir(filepath.Join(dir, ".devcontainer"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, filepath.Join(".devcontainer", "devcontainer.json"), `// For format details, see https://aka.ms/devcontainer.json.
+{
+  "name": "app",
+  "image": "mcr.microsoft.com/devcontainers/go:1", /* pinned */
+  "remoteEnv": {
+    "EDITOR": "vim",
+    "PORT": "8080",
+  },
+}
+`)
+	chdir(t, dir)
+
+	stdout, stderr, err := execCmd(t, "devcontainer")
+	if err != nil {
+		t.Fatalf("devcontainer: %v", err)
+	}
+	path := filepath.Join(dir, ".devcontainer", "devcontainer.json")
+	if !strings.Contains(stdout, "wrote 2 keys to remoteEnv in "+path) {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+	if !strings.Contains(stderr, "comments in "+path+" are not kept") {
+		t.Errorf("missing comments warning: %q", stderr)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "name": "app",
+  "image": "mcr.microsoft.com/devcontainers/go:1",
+  "remoteEnv": {
+    "EDITOR": "vim",
+    "PORT": "${localEnv:PORT}",
+    "API_KEY": "${localEnv:API_KEY}"
+  }
+}
+`
+	if string(data) != want {
+		t.Errorf("devcontainer.json:\ngot  %s\nwant %s", data, want)
+	}
+
+	stdout, _, err = execCmd(t, "devcontainer")
+	if err != nil {
+		t.Fatalf("devcontainer again: %v", err)
+	}
+	if !strings.Contains(stdout, "is up to date (2 keys)") {
+		t.Errorf("expected no change on rerun, got %q", stdout)
+	}
+}
+
+func TestDevcontainerCmd_ContainerEnvRootFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", "project: app\n")
+	writeTestFile(t, dir, ".env", "PORT=3000\n")
+	writeTestFile(t, dir, ".devcontainer.json", "{\n\t\"image\": \"debian\"\n}\n")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "devcontainer", "--target", "containerEnv"); err != nil {
+		t.Fatalf("devcontainer: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ".devcontainer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n\t\"image\": \"debian\",\n\t\"containerEnv\": {\n\t\t\"PORT\": \"${localEnv:PORT}\"\n\t}\n}\n"
+	if string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestDevcontainerCmd_Print(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", "project: app\n")
+	writeTestFile(t, dir, ".env", "PORT=3000\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "devcontainer", "--print")
+	if err != nil {
+		t.Fatalf("devcontainer --print: %v", err)
+	}
+	want := "{\n\t\"remoteEnv\": {\n\t\t\"PORT\": \"${localEnv:PORT}\"\n\t}\n}\n"
+	if stdout != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+}
+
+func TestDevcontainerCmd_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", "project: app\n")
+	writeTestFile(t, dir, ".env", "PORT=3000\n")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "devcontainer")
+	if err == nil || !strings.Contains(err.Error(), "not found; add a dev container configuration first") {
+		t.Errorf("expected missing file error, got %v", err)
+	}
+	_, _, err = execCmd(t, "devcontainer", "--target", "env")
+	if err == nil || !strings.Contains(err.Error(), `unknown target "env"`) {
+		t.Errorf("expected unknown target error, got %v", err)
+	}
+}
+
+func TestStripJSONC(t *testing.T) {
+	in := `{"url": "http://x/*y*/", // c
+"a": [1, 2,], /* b */ "s": "q\"//"}`
+	out, had := stripJSONC([]byte(in))
+	if !had {
+		t.Error("comments not reported")
+	}
+	want := "{\"url\": \"http://x/*y*/\", \n\"a\": [1, 2],  \"s\": \"q\\\"//\"}"
+	if string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+)
+
+// newDirenvCmd creates the direnv command group.
+func newDirenvCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "direnv",
+		Short: "Helpers for the direnv integration",
+		Long: `Helpers for loading envref through direnv (https://direnv.net).
+
+direnv only reloads an environment when a file it watches changes. By
+default it watches .envrc alone, so edits to .envref.yaml or .env.staging
+go unnoticed until the next 'direnv reload'. These helpers tell direnv
+which files envref reads.`,
+	}
+
+	cmd.AddCommand(newDirenvFilesCmd())
+	cmd.AddCommand(newDirenvStdlibCmd())
+
+	return cmd
+}
+
+// newDirenvFilesCmd creates the direnv files subcommand.
+func newDirenvFilesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "files",
+		Short: "List the files the resolved environment depends on",
+		Long: `Print, one per line, the absolute paths of the files that 'envref
+resolve' reads for the current project: the project config, the global
+config if there is one, and every env layer of the profile, including layers
+that do not exist yet so that creating one triggers a reload.
+
+In an .envrc, pass each path to direnv's watch_file:
+
+  while IFS= read -r file; do watch_file "$file"; done < <(envref direnv files)`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			return runDirenvFiles(cmd, profile)
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
+
+	return cmd
+}
+
+// runDirenvFiles prints the files to watch for the current project.
+func runDirenvFiles(cmd *cobra.Command, profileOverride string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, projectDir, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	out := output.NewWriter(cmd).Stdout()
+	for _, path := range direnvWatchPaths(cfg, projectDir, cfg.EffectiveProfile(profileOverride)) {
+		_, _ = fmt.Fprintln(out, path)
+	}
+	return nil
+}
+
+// direnvWatchPaths returns the files whose changes affect the resolved
+// environment of profile.
+func direnvWatchPaths(cfg *config.Config, projectDir, profile string) []string {
+	paths := []string{filepath.Join(projectDir, config.FullFileName)}
+	if global := config.GlobalConfigPath(); global != "" {
+		if _, err := os.Stat(global); err == nil {
+			paths = append(paths, global)
+		}
+	}
+	return append(paths, projectEnvPaths(cfg, projectDir, profile)...)
+}
+
+// direnvStdlib defines use_envref for direnv. It watches the files envref
+// reads before resolving, so that a failed resolve still reloads once the
+// files are fixed.
+const direnvStdlib = `# envref integration for direnv. Load it once from ~/.config/direnv/direnvrc:
+#
+#   eval "$(envref direnv stdlib)"
+#
+# then write 'use envref' (or 'use envref <profile>') in an .envrc.
+use_envref() {
+  local profile=${1:-} file
+  while IFS= read -r file; do
+    watch_file "$file"
+  done < <(envref direnv files --profile "$profile")
+  eval "$(envref resolve --direnv --profile "$profile")"
+}
+`
+
+// newDirenvStdlibCmd creates the direnv stdlib subcommand.
+func newDirenvStdlibCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stdlib",
+		Short: "Print a 'use envref' function for direnv",
+		Long: `Print the definition of use_envref, a direnv function that watches every
+file the project's environment depends on and then loads it with
+'envref resolve --direnv'.
+
+Load it from ~/.config/direnv/direnvrc:
+
+  eval "$(envref direnv stdlib)"
+
+and use it in an .envrc:
+
+  use envref            # the active profile
+  use envref staging    # a specific profile`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, _ = fmt.Fprint(output.NewWriter(cmd).Stdout(), direnvStdlib)
+			return nil
+		},
+	}
+}
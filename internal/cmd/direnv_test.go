@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirenvFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ENVREF_CONFIG_DIR", t.TempDir())
+	writeTestFile(t, dir, ".envref.yaml", "project: app\nactive_profile: staging\n")
+	writeTestFile(t, dir, ".env", "A=1\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "direnv", "files")
+	if err != nil {
+		t.Fatalf("direnv files: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, ".envref.yaml"),
+		filepath.Join(dir, ".env"),
+		filepath.Join(dir, ".env.staging"),
+		filepath.Join(dir, ".env.local"),
+		filepath.Join(dir, ".env.staging.local"),
+	}
+	if got := strings.Split(strings.TrimSpace(stdout), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("direnv files:\ngot  %q\nwant %q", got, want)
+	}
+
+	stdout, _, err = execCmd(t, "direnv", "files", "--profile", "production")
+	if err != nil {
+		t.Fatalf("direnv files --profile: %v", err)
+	}
+	if !strings.Contains(stdout, filepath.Join(dir, ".env.production")) || strings.Contains(stdout, ".env.staging") {
+		t.Errorf("expected the production layer only, got:\n%s", stdout)
+	}
+}
+
+func TestDirenvFiles_GlobalConfig(t *testing.T) {
+	dir := t.TempDir()
+	globalDir := t.TempDir()
+	t.Setenv("ENVREF_CONFIG_DIR", globalDir)
+	writeTestFile(t, globalDir, "config.yaml", "project: global\n")
+	writeTestFile(t, dir, ".envref.yaml", "project: app\n")
+	writeTestFile(t, dir, ".env", "A=1\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "direnv", "files")
+	if err != nil {
+		t.Fatalf("direnv files: %v", err)
+	}
+	if !strings.Contains(stdout, filepath.Join(globalDir, "config.yaml")) {
+		t.Errorf("expected the global config to be watched, got:\n%s", stdout)
+	}
+}
+
+func TestDirenvStdlib(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+
+	stdout, _, err := execCmd(t, "direnv", "stdlib")
+	if err != nil {
+		t.Fatalf("direnv stdlib: %v", err)
+	}
+
+	// Stand in for direnv's watch_file and for envref itself.
+	script := stdout + `
+watch_file() { echo "watch $1"; }
+envref() {
+  case "$1" in
+    direnv) printf '%s\n' "/p/.envref.yaml" "/p/my .env" ;;
+    resolve) echo "export RESOLVED_PROFILE=$4" ;;
+  esac
+}
+use_envref staging
+echo "profile=$RESOLVED_PROFILE"
+`
+	out, err := exec.Command(bash, "-c", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("running stdlib: %v\n%s", err, out)
+	}
+	want := "watch /p/.envref.yaml\nwatch /p/my .env\nprofile=staging\n"
+	if string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bufio"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
 	"github.com/xcke/envref/internal/output"
 	"github.com/xcke/envref/internal/parser"
 )
@@ -26,40 +28,52 @@ type issue struct {
 // newDoctorCmd creates the doctor subcommand.
 func newDoctorCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "doctor",
-		Short: "Check for common issues in .env files",
+		Use:     "doctor",
+		Aliases: []string{"lint"},
+		Short:   "Check for common issues in .env files",
 		Long: `Scan .env files for common problems that may cause subtle bugs or security issues.
 
 Checks performed:
   - Duplicate keys (last value wins, but earlier definitions are shadowed)
+  - Keys that differ only by case (e.g. API_KEY and Api_Key)
+  - Trailing whitespace inside quoted values
+  - Unterminated ${ interpolations (missing closing brace)
+  - Mistyped references (ref:/, ref//, REF://)
   - Trailing whitespace in unquoted values
   - Unquoted values containing spaces (may lose data with some tools)
   - Empty values without explicit intent (KEY= with no value or quotes)
   - .env file not listed in .gitignore (risk of committing secrets)
+  - Plaintext values for keys that require_refs in .envref.yaml says must
+    be ref:// references, in files that are committed to git
   - .envrc exists but is not trusted by direnv
+  - Configured secret backends that are unreachable, locked, or not signed
+    in (skip with --skip-backends)
 
 The command exits with code 1 if any issues are found, making it suitable
 for CI pipelines and pre-commit hooks.
 
 Examples:
   envref doctor                        # check .env and .env.local
-  envref doctor --file .env.staging    # check a specific file`,
+  envref doctor --file .env.staging    # check a specific file
+  envref doctor --skip-backends        # offline: only check files`,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			envFile, _ := cmd.Flags().GetString("file")
 			localFile, _ := cmd.Flags().GetString("local-file")
-			return runDoctor(cmd, envFile, localFile)
+			skipBackends, _ := cmd.Flags().GetBool("skip-backends")
+			return runDoctor(cmd, envFile, localFile, skipBackends)
 		},
 	}
 
 	cmd.Flags().StringP("file", "f", ".env", "path to the .env file")
 	cmd.Flags().String("local-file", ".env.local", "path to the .env.local override file")
+	cmd.Flags().Bool("skip-backends", false, "do not check that configured secret backends are reachable")
 
 	return cmd
 }
 
 // runDoctor implements the doctor command logic.
-func runDoctor(cmd *cobra.Command, envPath, localPath string) error {
+func runDoctor(cmd *cobra.Command, envPath, localPath string, skipBackends bool) error {
 	w := output.NewWriter(cmd)
 
 	var allIssues []issue
@@ -77,8 +91,12 @@ func runDoctor(cmd *cobra.Command, envPath, localPath string) error {
 	}
 
 	// Check project-level concerns.
+	allIssues = append(allIssues, checkRequiredRefs(envPath, localPath)...)
 	allIssues = append(allIssues, checkGitignore(envPath)...)
 	allIssues = append(allIssues, checkDirenvTrust()...)
+	if !skipBackends {
+		allIssues = append(allIssues, checkBackendReachability()...)
+	}
 
 	if len(allIssues) == 0 {
 		if !w.IsQuiet() {
@@ -337,6 +355,57 @@ func direnvAllowed(envrcPath string) bool {
 	return err == nil
 }
 
+// checkRequiredRefs reports plaintext values in committed env files for keys
+// that the project's require_refs policy says must be references. Config
+// errors are left to checkBackendReachability.
+func checkRequiredRefs(paths ...string) []issue {
+	policy, err := loadRefPolicy()
+	if err != nil {
+		return nil
+	}
+	var issues []issue
+	for _, path := range paths {
+		fileIssues, err := policy.checkFile(path)
+		if err != nil {
+			continue
+		}
+		issues = append(issues, fileIssues...)
+	}
+	return issues
+}
+
+// checkBackendReachability pings the secret backends configured for the
+// current project, so an unreachable or locked backend is reported once
+// rather than as a failure for every ref:// key at resolve time.
+func checkBackendReachability() []issue {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	cfg, configDir, err := config.Load(cwd)
+	if errors.Is(err, config.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return []issue{{
+			File:    config.FullFileName,
+			Message: fmt.Sprintf("cannot load config: %v", err),
+		}}
+	}
+
+	var issues []issue
+	for _, r := range checkBackends(cfg.Backends) {
+		if r.OK {
+			continue
+		}
+		issues = append(issues, issue{
+			File:    filepath.Join(configDir, config.FullFileName),
+			Message: fmt.Sprintf("backend %q (%s) is not reachable: %s", r.Name, r.Type, r.Error),
+		})
+	}
+	return issues
+}
+
 // printIssues formats and prints all issues to the writer.
 func printIssues(w *output.Writer, issues []issue) {
 	out := w.Stderr()
@@ -82,6 +82,32 @@ func TestDoctorCmd_DuplicateKeys(t *testing.T) {
 	}
 }
 
+func TestDoctorCmd_LintSuspiciousValues(t *testing.T) {
+	dir := t.TempDir()
+	envPath := writeTestFile(t, dir, ".env", "API_KEY=ref:/keychain/api\nURL=${HOST\nApi_Key=x\n")
+	writeTestFile(t, dir, ".gitignore", ".env\n")
+
+	root := NewRootCmd()
+	errBuf := new(bytes.Buffer)
+	root.SetOut(new(bytes.Buffer))
+	root.SetErr(errBuf)
+	root.SetArgs([]string{"lint",
+		"--file", envPath,
+		"--local-file", filepath.Join(dir, ".env.local"),
+	})
+
+	if err := root.Execute(); err == nil {
+		t.Fatal("expected error for suspicious values, got nil")
+	}
+
+	stderr := errBuf.String()
+	for _, want := range []string{"mistyped reference", "unterminated ${", "differs only by case"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("expected %q in output, got %q", want, stderr)
+		}
+	}
+}
+
 func TestDoctorCmd_TrailingWhitespace(t *testing.T) {
 	dir := t.TempDir()
 	envPath := writeTestFile(t, dir, ".env", "DB_HOST=localhost   \nDB_PORT=5432\n")
@@ -457,3 +483,22 @@ func TestGitignoreCovers(t *testing.T) {
 		})
 	}
 }
+
+func TestDoctor_UnreachableBackend(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	writeTestFile(t, dir, ".envref.yaml", "project: testproject\nbackends:\n  - name: broken\n    type: plugin\n    config:\n      command: "+filepath.Join(dir, "missing-plugin")+"\n")
+	chdir(t, dir)
+
+	_, stderr, err := execCmd(t, "doctor")
+	if err == nil {
+		t.Fatal("expected doctor to report the unreachable backend")
+	}
+	if !strings.Contains(stderr, `backend "broken" (plugin) is not reachable`) {
+		t.Errorf("expected unreachable backend issue, got: %q", stderr)
+	}
+
+	if _, _, err := execCmd(t, "doctor", "--skip-backends"); err != nil {
+		t.Errorf("doctor --skip-backends: %v", err)
+	}
+}
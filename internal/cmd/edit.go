@@ -29,6 +29,7 @@ Examples:
   envref edit                          # edit .env
   envref edit --local                  # edit .env.local
   envref edit --profile staging        # edit .env.staging
+  envref edit --profile staging --local  # edit .env.staging.local
   envref edit --config                 # edit .envref.yaml
   envref edit .env.production          # edit a specific file`,
 		Args: cobra.MaximumNArgs(1),
@@ -46,7 +47,7 @@ Examples:
 		},
 	}
 
-	cmd.Flags().BoolP("local", "l", false, "edit .env.local instead of .env")
+	cmd.Flags().BoolP("local", "l", false, "edit .env.local instead of .env (.env.<profile>.local with --profile)")
 	cmd.Flags().BoolP("config", "c", false, "edit the .envref.yaml config file")
 	cmd.Flags().StringP("profile", "P", "", "edit the .env.<profile> file for the given profile")
 	cmd.MarkFlagsMutuallyExclusive("local", "config", "profile")
@@ -79,6 +80,8 @@ func runEdit(cmd *cobra.Command, explicitFile string, useLocal, useConfig bool,
 	switch {
 	case useConfig:
 		targetFile = resolveFilePath(projectDir, config.FullFileName)
+	case useLocal && profile != "":
+		targetFile = resolveFilePath(projectDir, cfg.ProfileLocalFile(profile))
 	case useLocal:
 		targetFile = resolveFilePath(projectDir, cfg.LocalFile)
 	case profile != "":
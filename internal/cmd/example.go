@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/parser"
+)
+
+// newExampleCmd creates the example subcommand.
+func newExampleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "example",
+		Short: "Generate or update .env.example from your .env file",
+		Long: `Generate .env.example from the keys in your .env file so that the example
+never drifts from the real configuration.
+
+The generated file contains every key with its comments, annotations
+(# @type, # @description), and ref:// references, but never plaintext values. When
+.env.example already exists, placeholder values written by hand are kept
+for keys that are still present; new keys are added with an empty value and
+keys no longer in .env are removed.
+
+Use --check in CI to fail when .env.example is out of date instead of
+writing it.
+
+Examples:
+  envref example                         # write .env.example
+  envref example --check                 # exit 1 if .env.example has drifted
+  envref example --output config.example # write to a custom path`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envFile, _ := cmd.Flags().GetString("file")
+			outFile, _ := cmd.Flags().GetString("output")
+			check, _ := cmd.Flags().GetBool("check")
+			return runExample(cmd, envFile, outFile, check)
+		},
+	}
+
+	cmd.Flags().StringP("file", "f", ".env", "path to the .env file")
+	cmd.Flags().StringP("output", "o", ".env.example", "path to the example file to write")
+	cmd.Flags().Bool("check", false, "fail if the example file is out of date instead of writing it")
+
+	return cmd
+}
+
+// runExample builds the example env from envPath and either writes it to
+// examplePath or, in check mode, compares it to the existing file.
+func runExample(cmd *cobra.Command, envPath, examplePath string, check bool) error {
+	w := output.NewWriter(cmd)
+
+	env, warnings, err := envfile.Load(envPath)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", envPath, err)
+	}
+	printWarnings(cmd, envPath, warnings)
+
+	existing, exampleWarnings, err := envfile.LoadOptional(examplePath)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", examplePath, err)
+	}
+	printWarnings(cmd, examplePath, exampleWarnings)
+
+	example := buildExampleEnv(env, existing)
+	content := example.Bytes()
+
+	if check {
+		current, readErr := os.ReadFile(examplePath)
+		if readErr != nil && !os.IsNotExist(readErr) {
+			return fmt.Errorf("reading %s: %w", examplePath, readErr)
+		}
+		if bytes.Equal(current, content) {
+			w.Info("%s: %s is up to date\n", w.Green("OK"), examplePath)
+			return nil
+		}
+
+		missing, extra := exampleDrift(env, existing)
+		for _, key := range missing {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "missing from %s: %s\n", examplePath, key)
+		}
+		for _, key := range extra {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "not in %s: %s\n", envPath, key)
+		}
+		return fmt.Errorf("%s is out of date (run 'envref example' to update it)", examplePath)
+	}
+
+	if err := os.WriteFile(examplePath, content, 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", examplePath, err)
+	}
+	w.Info("wrote %d key(s) to %s\n", example.Len(), examplePath)
+	return nil
+}
+
+// buildExampleEnv returns a copy of env suitable for committing as an
+// example: ref:// values are kept, plaintext values are replaced by the
+// placeholder already present in existing (if any) or left empty.
+// Comments and annotations are preserved.
+func buildExampleEnv(env, existing *envfile.Env) *envfile.Env {
+	example := envfile.NewEnv()
+	for _, entry := range env.All() {
+		placeholder := parser.Entry{
+			Key:           entry.Key,
+			Annotations:   entry.Annotations,
+			Comment:       entry.Comment,
+			InlineComment: entry.InlineComment,
+		}
+		if entry.IsRef {
+			placeholder.Value = entry.Value
+			placeholder.IsRef = true
+		} else if prev, ok := existing.Get(entry.Key); ok && !prev.IsRef {
+			placeholder.Value = prev.Value
+		}
+		placeholder.Raw = placeholder.Value
+		example.Set(placeholder)
+	}
+	return example
+}
+
+// exampleDrift returns the keys present in env but missing from example,
+// and the keys present in example but no longer in env, both sorted.
+func exampleDrift(env, example *envfile.Env) (missing, extra []string) {
+	envKeys := keySet(env.Keys())
+	exampleKeys := keySet(example.Keys())
+	for key := range envKeys {
+		if _, ok := exampleKeys[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	for key := range exampleKeys {
+		if _, ok := envKeys[key]; !ok {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	return missing, extra
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExampleCmd_Generate(t *testing.T) {
+	dir := t.TempDir()
+	envPath := writeTestFile(t, dir, ".env", "# @type: int\n# @description: Listen port\nPORT=8080\nAPI_KEY=ref://secrets/api_key\nDB_PASS=hunter2\n")
+	examplePath := filepath.Join(dir, ".env.example")
+
+	if _, _, err := execCmd(t, "example", "--file", envPath, "--output", examplePath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(examplePath)
+	if err != nil {
+		t.Fatalf("reading example: %v", err)
+	}
+	want := "# @type: int\n# @description: Listen port\nPORT=\nAPI_KEY=ref://secrets/api_key\nDB_PASS=\n"
+	if string(content) != want {
+		t.Errorf("got %q, want %q", string(content), want)
+	}
+	if strings.Contains(string(content), "hunter2") {
+		t.Error("example must never contain plaintext values")
+	}
+}
+
+func TestExampleCmd_UpdateKeepsPlaceholders(t *testing.T) {
+	dir := t.TempDir()
+	envPath := writeTestFile(t, dir, ".env", "PORT=8080\nNEW_KEY=value\n")
+	examplePath := writeTestFile(t, dir, ".env.example", "PORT=3000\nREMOVED=x\n")
+
+	if _, _, err := execCmd(t, "example", "--file", envPath, "--output", examplePath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(examplePath)
+	if err != nil {
+		t.Fatalf("reading example: %v", err)
+	}
+	want := "PORT=3000\nNEW_KEY=\n"
+	if string(content) != want {
+		t.Errorf("got %q, want %q", string(content), want)
+	}
+}
+
+func TestExampleCmd_Check(t *testing.T) {
+	dir := t.TempDir()
+	envPath := writeTestFile(t, dir, ".env", "PORT=8080\nAPI_KEY=ref://secrets/api_key\n")
+	examplePath := filepath.Join(dir, ".env.example")
+
+	t.Run("missing example fails", func(t *testing.T) {
+		_, stderr, err := execCmd(t, "example", "--check", "--file", envPath, "--output", examplePath)
+		if err == nil {
+			t.Fatal("expected error when example is missing")
+		}
+		if !strings.Contains(stderr, "missing from") || !strings.Contains(stderr, "PORT") {
+			t.Errorf("stderr: got %q", stderr)
+		}
+		if _, statErr := os.Stat(examplePath); !os.IsNotExist(statErr) {
+			t.Error("--check must not write the example file")
+		}
+	})
+
+	t.Run("up to date passes", func(t *testing.T) {
+		if _, _, err := execCmd(t, "example", "--file", envPath, "--output", examplePath); err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if _, _, err := execCmd(t, "example", "--check", "--file", envPath, "--output", examplePath); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("drift fails", func(t *testing.T) {
+		writeTestFile(t, dir, ".env", "PORT=8080\nAPI_KEY=ref://secrets/api_key\nEXTRA=1\n")
+		_, stderr, err := execCmd(t, "example", "--check", "--file", envPath, "--output", examplePath)
+		if err == nil {
+			t.Fatal("expected drift error")
+		}
+		if !strings.Contains(stderr, "EXTRA") {
+			t.Errorf("stderr should name the drifted key, got %q", stderr)
+		}
+	})
+}
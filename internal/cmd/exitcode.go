@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/resolve"
+)
+
+// Exit codes, so that scripts can tell failures apart. See
+// 'envref help exit-codes'.
+const (
+	exitGeneral    = 1 // any other error
+	exitUsage      = 2 // unknown command or flag, or wrong arguments
+	exitConfig     = 3 // .envref.yaml missing, unreadable, or invalid
+	exitParse      = 4 // a .env file cannot be parsed
+	exitUnresolved = 5 // references could not be resolved
+	exitBackend    = 6 // a backend is unavailable, locked, or failing
+	exitValidation = 7 // values fail validation
+)
+
+// codedError is an error that makes envref exit with code.
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+
+func (e *codedError) Unwrap() error { return e.err }
+
+// withExitCode returns err, making envref exit with code.
+func withExitCode(code int, err error) error {
+	return &codedError{code: code, err: err}
+}
+
+// unresolvedExitCode returns the exit code for references that failed to
+// resolve with errs: exitBackend if a backend failed, exitUnresolved if
+// secrets are missing.
+func unresolvedExitCode(errs []resolve.KeyErr) int {
+	for _, e := range errs {
+		if errors.Is(e.Err, resolve.ErrBackendUnavailable) {
+			return exitBackend
+		}
+	}
+	return exitUnresolved
+}
+
+// exitCode returns the code envref exits with after err.
+func exitCode(err error) int {
+	var exitErr *exitError
+	var coded *codedError
+	var keyErr *backend.KeyError
+	var keychainErr *backend.KeychainError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.As(err, &coded):
+		return coded.code
+	case strings.HasPrefix(err.Error(), "unknown command "):
+		return exitUsage
+	case errors.As(err, new(*config.LoadError)), errors.Is(err, config.ErrNotFound):
+		return exitConfig
+	case errors.As(err, new(*parser.ParseError)), errors.Is(err, parser.ErrUnsupportedEncoding):
+		return exitParse
+	case errors.Is(err, resolve.ErrBackendUnavailable),
+		errors.Is(err, backend.ErrVaultLocked),
+		errors.Is(err, backend.ErrVaultNotInitialized),
+		errors.Is(err, backend.ErrWrongPassphrase),
+		errors.Is(err, backend.ErrPresenceDenied),
+		errors.Is(err, backend.ErrAccessDenied),
+		errors.As(err, &keychainErr),
+		errors.As(err, &keyErr) && !errors.Is(err, backend.ErrNotFound):
+		return exitBackend
+	default:
+		return exitGeneral
+	}
+}
+
+// markUsageErrors makes the flag and argument errors of cmd and its
+// subcommands exit with exitUsage.
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(exitUsage, err)
+	})
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			if err := args(cmd, a); err != nil {
+				return withExitCode(exitUsage, err)
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
+
+// newExitCodesCmd creates the exit-codes help topic.
+func newExitCodesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "exit-codes",
+		Short: "Exit codes and what they mean",
+		Long: `envref exits with a code that tells the kind of failure, so that scripts
+can branch on it instead of matching error messages:
+
+  0  success
+  1  any other error
+  2  usage error: unknown command or flag, or wrong arguments
+  3  config error: no .envref.yaml, or it cannot be read or is invalid
+  4  parse error: a .env file cannot be parsed
+  5  unresolved references: secrets are missing (with --strict, or where
+     every reference must resolve)
+  6  backend unavailable: a backend failed, is locked, or refused access
+  7  validation failure: values fail the schema or type annotations, or
+     'envref validate' found problems
+
+'envref run' exits with the code of the command it ran, once that has
+started, so the codes above only apply to failures before it.
+
+Example:
+  envref resolve --strict > .env.resolved
+  case $? in
+    5) echo "missing secrets: run 'envref onboard'" ;;
+    6) echo "backend unavailable: try 'envref vault unlock'" ;;
+  esac`,
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/resolve"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"other", errors.New("boom"), exitGeneral},
+		{"child exit", &exitError{code: 42}, 42},
+		{"coded", fmt.Errorf("wrapped: %w", withExitCode(exitValidation, errors.New("bad"))), exitValidation},
+		{"unknown command", errors.New(`unknown command "nope" for "envref"`), exitUsage},
+		{"config not found", fmt.Errorf("loading config: %w", config.ErrNotFound), exitConfig},
+		{"config invalid", &config.LoadError{Err: errors.New("invalid")}, exitConfig},
+		{"parse error", fmt.Errorf("loading .env: %w", &parser.ParseError{Line: 1, Message: "bad"}), exitParse},
+		{"vault locked", fmt.Errorf("opening vault: %w", backend.ErrVaultLocked), exitBackend},
+		{"backend unavailable", fmt.Errorf("x: %w", resolve.ErrBackendUnavailable), exitBackend},
+		{"key error", backend.NewKeyError("vault", "k", errors.New("timeout")), exitBackend},
+		{"access denied", fmt.Errorf("retrieving secret: %w", backend.ErrAccessDenied), exitBackend},
+		{"key not found", backend.NewKeyError("vault", "k", backend.ErrNotFound), exitGeneral},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnresolvedExitCode(t *testing.T) {
+	missing := []resolve.KeyErr{{Key: "A", Err: backend.ErrNotFound}}
+	if got := unresolvedExitCode(missing); got != exitUnresolved {
+		t.Errorf("missing secret: got %d, want %d", got, exitUnresolved)
+	}
+	failing := append(missing, resolve.KeyErr{Key: "B", Err: fmt.Errorf("x: %w", resolve.ErrBackendUnavailable)})
+	if got := unresolvedExitCode(failing); got != exitBackend {
+		t.Errorf("failing backend: got %d, want %d", got, exitBackend)
+	}
+}
+
+func TestExitCode_Commands(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		example string
+		memory  bool
+		args    []string
+		want    int
+	}{
+		{name: "unknown flag", args: []string{"resolve", "--bogus"}, want: exitUsage},
+		{name: "wrong arguments", args: []string{"get", "A", "B"}, want: exitUsage},
+		{name: "parse error", env: "A=\"unterminated\n", args: []string{"resolve"}, want: exitParse},
+		{name: "no backends", env: "A=ref://secrets/a\n", args: []string{"resolve"}, want: exitConfig},
+		{name: "unresolved", env: "A=ref://secrets/a\n", memory: true, args: []string{"resolve", "--strict"}, want: exitUnresolved},
+		{name: "validation", env: "A=1\n", example: "A=\nB=\n", args: []string{"validate"}, want: exitValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupProject(t, "exitcodes", tt.env, "")
+			if tt.memory {
+				writeMemoryTestConfig(t, dir, "exitcodes")
+			}
+			if tt.example != "" {
+				writeTestFile(t, dir, ".env.example", tt.example)
+			}
+			chdir(t, dir)
+
+			_, _, err := execCmd(t, tt.args...)
+			if got := exitCode(err); got != tt.want {
+				t.Errorf("exit code = %d, want %d (err: %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestExitCode_NoConfig(t *testing.T) {
+	chdir(t, t.TempDir())
+	_, _, err := execCmd(t, "resolve")
+	if got := exitCode(err); got != exitConfig {
+		t.Errorf("exit code = %d, want %d (err: %v)", got, exitConfig, err)
+	}
+}
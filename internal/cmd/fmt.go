@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/output"
+)
+
+// newFmtCmd creates the fmt subcommand.
+func newFmtCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fmt [FILE...]",
+		Short: "Normalize the layout of .env files",
+		Long: `Rewrite .env files in a consistent layout without changing their values:
+KEY=VALUE without spaces around "=", quotes only where they are needed,
+comments and annotations kept in place, and runs of blank lines collapsed.
+
+Values that must not be interpolated stay single-quoted, and heredoc values
+stay heredocs. With --sort, the keys of each section are sorted; sections
+are separated by blank lines or by comments that are not attached to a key,
+and keys keep the comments directly above them.
+
+Without arguments, .env and .env.local are formatted if they exist. Use "-"
+to format standard input to standard output.
+
+Use --check in CI to fail when a file is not formatted instead of rewriting
+it; the files that would change are listed.
+
+Examples:
+  envref fmt                      # format .env and .env.local
+  envref fmt --sort .env.staging  # format and sort a specific file
+  envref fmt --check              # exit 1 if a file is not formatted
+  cat .env | envref fmt -         # format stdin`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sortKeys, _ := cmd.Flags().GetBool("sort")
+			check, _ := cmd.Flags().GetBool("check")
+			return runFmt(cmd, args, envfile.FormatOptions{Sort: sortKeys}, check)
+		},
+	}
+
+	cmd.Flags().Bool("sort", false, "sort keys within each section")
+	cmd.Flags().Bool("check", false, "fail if a file is not formatted instead of rewriting it")
+
+	return cmd
+}
+
+// runFmt formats each of paths, or .env and .env.local when paths is
+// empty. In check mode, files are left untouched and the unformatted ones
+// are listed on stdout.
+func runFmt(cmd *cobra.Command, paths []string, opts envfile.FormatOptions, check bool) error {
+	w := output.NewWriter(cmd)
+
+	if len(paths) == 0 {
+		for _, path := range []string{".env", ".env.local"} {
+			if fileExists(path) {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no .env or .env.local file found")
+		}
+	}
+
+	var unformatted int
+	for _, path := range paths {
+		var data []byte
+		var err error
+		if path == stdinPath {
+			data, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", envPathName(path), err)
+		}
+
+		formatted, err := envfile.Format(data, opts)
+		if err != nil {
+			return fmt.Errorf("formatting %s: %w", envPathName(path), err)
+		}
+
+		switch {
+		case check:
+			if !bytes.Equal(data, formatted) {
+				unformatted++
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), envPathName(path))
+			}
+		case path == stdinPath:
+			if _, err := cmd.OutOrStdout().Write(formatted); err != nil {
+				return fmt.Errorf("writing stdout: %w", err)
+			}
+		case !bytes.Equal(data, formatted):
+			if err := os.WriteFile(path, formatted, 0o644); err != nil {
+				return fmt.Errorf("writing %s: %w", path, err)
+			}
+			w.Info("formatted %s\n", path)
+		}
+	}
+
+	if unformatted > 0 {
+		return fmt.Errorf("%d file(s) not formatted (run 'envref fmt' to fix)", unformatted)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFmtCmd(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".env", "# Server\nPORT = \"8080\"\n\n\nHOST='localhost'\n")
+	writeTestFile(t, dir, ".env.local", "DEBUG=true\n")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "fmt", "--check")
+	require.Error(t, err)
+
+	stdout, _, err := execCmd(t, "fmt")
+	require.NoError(t, err)
+	assert.Equal(t, "formatted .env\n", stdout, "files already formatted are not rewritten")
+
+	data, err := os.ReadFile(filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Server\nPORT=8080\n\nHOST=localhost\n", string(data))
+
+	stdout, _, err = execCmd(t, "fmt", "--check")
+	require.NoError(t, err)
+	assert.Empty(t, stdout)
+}
+
+func TestFmtCmd_Check(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".env.staging", "B=2\nA=1\n")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "fmt", "--check", ".env.staging")
+	require.NoError(t, err)
+
+	stdout, _, err := execCmd(t, "fmt", "--check", "--sort", ".env.staging")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 file(s) not formatted")
+	assert.Equal(t, ".env.staging\n", stdout)
+
+	data, err := os.ReadFile(filepath.Join(dir, ".env.staging"))
+	require.NoError(t, err)
+	assert.Equal(t, "B=2\nA=1\n", string(data), "--check must not rewrite the file")
+}
+
+func TestFmtCmd_Stdin(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	stdout, _, err := execCmdWithStdin(t, "Z = 1\nA = 2\n", "fmt", "--sort", "-")
+	require.NoError(t, err)
+	assert.Equal(t, "A=2\nZ=1\n", stdout)
+}
+
+func TestFmtCmd_NoFiles(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	_, _, err := execCmd(t, "fmt")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no .env or .env.local file found")
+}
+
+func TestFmtCmd_ParseError(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".env", "FOO=\"unterminated\n")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "fmt")
+	require.Error(t, err)
+	assert.Equal(t, exitParse, exitCode(err))
+}
@@ -19,6 +19,10 @@ const (
 	FormatShell OutputFormat = "shell"
 	// FormatTable outputs aligned columns with headers.
 	FormatTable OutputFormat = "table"
+	// FormatTerraform outputs a flat JSON object of string values, as
+	// Terraform's external data source expects. It is selected with
+	// resolve --terraform-json rather than --format.
+	FormatTerraform OutputFormat = "terraform"
 )
 
 // validFormats lists all accepted --format values.
@@ -54,6 +58,8 @@ func formatKVPairs(w io.Writer, pairs []kvPair, format OutputFormat) error {
 		return formatKVShell(w, pairs)
 	case FormatTable:
 		return formatKVTable(w, pairs)
+	case FormatTerraform:
+		return formatKVTerraform(w, pairs)
 	default:
 		return formatKVPlain(w, pairs)
 	}
@@ -76,16 +82,91 @@ func formatKVJSON(w io.Writer, pairs []kvPair) error {
 	return enc.Encode(pairs)
 }
 
+// formatKVTerraform outputs a JSON object mapping each key to its value.
+func formatKVTerraform(w io.Writer, pairs []kvPair) error {
+	m := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		m[p.Key] = p.Value
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
 // formatKVShell outputs export KEY=VALUE pairs with shell-safe quoting.
 func formatKVShell(w io.Writer, pairs []kvPair) error {
 	for _, p := range pairs {
-		if _, err := fmt.Fprintf(w, "export %s=%s\n", p.Key, shellQuote(p.Value)); err != nil {
+		if err := writeShellExport(w, p.Key, p.Value); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// writeShellExport writes an export statement for key and value that any
+// POSIX shell evaluates to exactly value, as eval "$(envref resolve
+// --direnv)" does. Keys that are not shell variable names and values
+// holding a NUL byte, which no shell variable can, are errors rather than
+// being written in a form the shell would misparse.
+func writeShellExport(w io.Writer, key, value string) error {
+	if !isShellName(key) {
+		return fmt.Errorf("cannot export %q to the shell: not a valid variable name", key)
+	}
+	if strings.IndexByte(value, 0) >= 0 {
+		return fmt.Errorf("cannot export %s to the shell: value contains a NUL byte", key)
+	}
+	_, err := fmt.Fprintf(w, "export %s=%s\n", key, shellQuote(value))
+	return err
+}
+
+// isShellName reports whether s is a POSIX shell variable name: a letter or
+// underscore followed by letters, digits, and underscores.
+func isShellName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// shellQuote quotes s as one shell word. Values made only of characters
+// that are never special to a shell are left bare for readability; any
+// other value is wrapped in single quotes, inside which a shell interprets
+// nothing; an embedded single quote ends the quoted part, is escaped with
+// a backslash, and starts a new one. Checking against the safe characters
+// rather than the special ones means quotes, newlines, backticks, $(),
+// globs, tildes, and bytes no list anticipated are always quoted.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for i := 0; i < len(s) && safe; i++ {
+		safe = isShellSafe(s[i])
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isShellSafe reports whether c needs no quoting anywhere in a shell word.
+func isShellSafe(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("_@%+=:,./-", c) >= 0
+}
+
 // formatKVTable outputs an aligned table with KEY and VALUE columns.
 func formatKVTable(w io.Writer, pairs []kvPair) error {
 	if len(pairs) == 0 {
@@ -117,6 +198,39 @@ func formatKVTable(w io.Writer, pairs []kvPair) error {
 	return nil
 }
 
+// writeTable writes rows as aligned columns under header, with a line of
+// dashes between them. Nothing is written when there are no rows.
+func writeTable(w io.Writer, header []string, rows [][]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i, col := range row {
+			widths[i] = max(widths[i], len(col))
+		}
+	}
+
+	dashes := make([]string, len(header))
+	for i, width := range widths {
+		dashes[i] = strings.Repeat("-", width)
+	}
+	for _, row := range append([][]string{header, dashes}, rows...) {
+		cols := make([]string, len(row))
+		for i, col := range row {
+			cols[i] = fmt.Sprintf("%-*s", widths[i], col)
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(strings.Join(cols, "  "), " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // formatSingleValue writes a single value in the specified format.
 // Used by the get command which returns a single key-value.
 func formatSingleValue(w io.Writer, key, value string, format OutputFormat) error {
@@ -126,8 +240,7 @@ func formatSingleValue(w io.Writer, key, value string, format OutputFormat) erro
 		enc.SetIndent("", "  ")
 		return enc.Encode(kvPair{Key: key, Value: value})
 	case FormatShell:
-		_, err := fmt.Fprintf(w, "export %s=%s\n", key, shellQuote(value))
-		return err
+		return writeShellExport(w, key, value)
 	case FormatTable:
 		return formatKVTable(w, []kvPair{{Key: key, Value: value}})
 	default:
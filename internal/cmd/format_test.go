@@ -141,6 +141,31 @@ func TestFormatKVPairs_Shell(t *testing.T) {
 	}
 }
 
+func TestFormatKVPairs_Shell_Rejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		pair    kvPair
+		wantErr string
+	}{
+		{"key with command", kvPair{Key: "A;touch pwned", Value: "x"}, "not a valid variable name"},
+		{"key with dot", kvPair{Key: "app.port", Value: "1"}, "not a valid variable name"},
+		{"key with leading digit", kvPair{Key: "1PORT", Value: "1"}, "not a valid variable name"},
+		{"NUL in value", kvPair{Key: "TOKEN", Value: "a\x00b"}, "NUL byte"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := formatKVPairs(buf, []kvPair{tt.pair}, FormatShell)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("expected no output, got %q", buf.String())
+			}
+		})
+	}
+}
+
 func TestFormatKVPairs_Table(t *testing.T) {
 	pairs := []kvPair{
 		{Key: "DB_HOST", Value: "localhost"},
@@ -294,3 +319,29 @@ func TestFormatKVPairs_JSON_SpecialChars(t *testing.T) {
 		t.Errorf("JSON should preserve newlines: got %q", result[1].Value)
 	}
 }
+
+func TestFormatKVPairs_Terraform(t *testing.T) {
+	pairs := []kvPair{
+		{Key: "DB_HOST", Value: "localhost"},
+		{Key: "API_KEY", Value: "sk-\"123\""},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := formatKVPairs(buf, pairs, FormatTerraform); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var result map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("output is not a JSON object of strings: %v\noutput: %s", err, buf.String())
+	}
+	if len(result) != 2 || result["DB_HOST"] != "localhost" || result["API_KEY"] != `sk-"123"` {
+		t.Errorf("got %v", result)
+	}
+}
+
+func TestParseFormat_RejectsTerraform(t *testing.T) {
+	if _, err := parseFormat("terraform"); err == nil {
+		t.Error("terraform should only be selectable with resolve --terraform-json")
+	}
+}
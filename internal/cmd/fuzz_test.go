@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// FuzzShellExport evaluates the shell output of arbitrary values with every
+// POSIX shell available, as eval "$(envref resolve --direnv)" does, and
+// checks that each one reads back exactly the value: nothing is expanded,
+// executed, or split, whatever quotes, newlines, or substitutions it holds.
+func FuzzShellExport(f *testing.F) {
+	var shells []string
+	for _, name := range []string{"sh", "dash", "bash", "zsh"} {
+		if path, err := exec.LookPath(name); err == nil {
+			shells = append(shells, path)
+		}
+	}
+	if len(shells) == 0 {
+		f.Skip("no POSIX shell found")
+	}
+
+	seeds := []string{
+		"",
+		"plain",
+		"hello world",
+		"it's",
+		`say "hi"`,
+		"'",
+		"''",
+		`'\''`,
+		"line1\nline2",
+		"trailing newline\n",
+		"\r\n\t\v\f",
+		"$HOME",
+		"${HOME:-x}",
+		"$(touch pwned)",
+		"`touch pwned`",
+		"a;touch pwned",
+		"a && touch pwned",
+		"a | cat",
+		"> pwned",
+		"*",
+		"?",
+		"[a-z]",
+		"{a,b}",
+		"~",
+		"~/bin:~root",
+		"a=~/x",
+		"#comment",
+		"!!",
+		`back\slash`,
+		`\`,
+		"-n",
+		"--",
+		"%s %d",
+		"ünïcödé ✓",
+		"\x01\x1b[31mred\x1b[0m\x7f",
+		"\xff\xfe invalid utf-8",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		if strings.IndexByte(value, 0) >= 0 {
+			// Rejected by writeShellExport: no shell variable holds a NUL.
+			return
+		}
+		var script bytes.Buffer
+		if err := writeShellExport(&script, "ENVREF_FUZZ", value); err != nil {
+			t.Fatalf("writeShellExport(%q): %v", value, err)
+		}
+		script.WriteString(`printf '%s' "$ENVREF_FUZZ"`)
+
+		for _, shell := range shells {
+			cmd := exec.Command(shell, "-c", script.String())
+			cmd.Dir = t.TempDir()
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("%s -c %q: %v", shell, script.String(), err)
+			}
+			if string(out) != value {
+				t.Errorf("%s read back %q from %q, want %q", shell, out, script.String(), value)
+			}
+		}
+	})
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
+	"unicode"
 
 	"github.com/spf13/cobra"
 	"github.com/xcke/envref/internal/output"
@@ -12,7 +14,7 @@ import (
 // newGetCmd creates the get subcommand.
 func newGetCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "get <KEY>",
+		Use:   "get [KEY]",
 		Short: "Print the value of an environment variable",
 		Long: `Look up a single key from the merged .env and .env.local files and print
 its value to stdout.
@@ -21,16 +23,36 @@ When a profile file is specified with --profile-file, it is loaded between
 .env and .env.local: .env ← profile ← .env.local.
 
 If the value is an unresolved ref:// reference, it is printed as-is.
+With --raw, the value is printed exactly as written in the file that sets
+it, quotes included, before ${VAR} interpolation and ref scheme rewriting,
+e.g. to copy a templated value to another file or debug interpolation.
+Reading a deprecated name listed in key_aliases prints a warning.
 Use --file to specify a custom .env file path.
 
-Output format can be specified with --format (plain, json, shell, table).`,
-		Args: cobra.ExactArgs(1),
+Output format can be specified with --format (plain, json, shell, table).
+
+With --origin, show where the value comes from instead: the layer (base,
+profile, or local) and file:line that set it, the earlier layers it
+overrides, and for a ref:// reference the backend that served the secret
+after aliases, fallback, and profile namespaces (the secret itself is not
+printed). Use it to debug "why is this value wrong". --format json gives
+the same as a JSON object.
+
+Without a KEY on a terminal, pick the key from a list that narrows down
+as you type, as with fzf.`,
+		Args: keyArg,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			envFile, _ := cmd.Flags().GetString("file")
 			localFile, _ := cmd.Flags().GetString("local-file")
 			profileFile, _ := cmd.Flags().GetString("profile-file")
 			formatStr, _ := cmd.Flags().GetString("format")
-			return runGet(cmd, args[0], envFile, profileFile, localFile, formatStr)
+			origin, _ := cmd.Flags().GetBool("origin")
+			raw, _ := cmd.Flags().GetBool("raw")
+			var key string
+			if len(args) > 0 {
+				key = args[0]
+			}
+			return runGet(cmd, key, envFile, profileFile, localFile, formatStr, origin, raw)
 		},
 	}
 
@@ -38,29 +60,72 @@ Output format can be specified with --format (plain, json, shell, table).`,
 	cmd.Flags().String("local-file", ".env.local", "path to the .env.local override file")
 	cmd.Flags().String("profile-file", "", "path to a profile-specific .env file (e.g., .env.staging)")
 	cmd.Flags().String("format", "plain", "output format: plain, json, shell, table")
+	cmd.Flags().Bool("origin", false, "show the layer, file, line, and backend the value comes from")
+	cmd.Flags().Bool("raw", false, "print the value as written in the file, without interpolation")
 
 	return cmd
 }
 
-// runGet loads env files, merges them, and prints the value for the given key.
-func runGet(cmd *cobra.Command, key, envPath, profilePath, localPath, formatStr string) error {
+// runGet loads env files, merges them, and prints the value for the given key,
+// or with origin where it comes from. With raw, the value is the literal
+// text of the file. An empty key is picked interactively on a terminal.
+func runGet(cmd *cobra.Command, key, envPath, profilePath, localPath, formatStr string, origin, raw bool) error {
 	format, err := parseFormat(formatStr)
 	if err != nil {
 		return err
 	}
+	if origin && format != FormatPlain && format != FormatJSON {
+		return fmt.Errorf("unsupported format %q for get --origin (use plain or json)", formatStr)
+	}
 
-	env, err := loadAndMergeEnv(cmd, envPath, profilePath, localPath)
+	cfg := workingConfig()
+	env, err := loadAndMergeEnv(cmd, cfg, envPath, profilePath, localPath)
 	if err != nil {
 		return err
 	}
 
+	if key == "" && canPickKey(cmd) {
+		if key, err = pickKey(cmd, env.Keys()); err != nil {
+			return err
+		}
+	}
+
 	entry, found := env.Get(key)
 	if !found {
 		hint := suggest.FormatSuggestion(suggest.Keys(key, env.Keys()))
 		return fmt.Errorf("key %q not found%s", key, hint)
 	}
+	if cfg != nil {
+		if renamed, ok := cfg.DeprecatedKey(key); ok {
+			output.NewWriter(cmd).Warn("%s is deprecated, use %s instead\n", key, renamed)
+		}
+	}
 
-	return formatSingleValue(cmd.OutOrStdout(), entry.Key, entry.Value, format)
+	value := entry.Value
+	if raw {
+		value = rawValue(entry)
+	}
+	if origin {
+		return runGetOrigin(cmd, cfg, env, entry, value, envPath, profilePath, localPath, format)
+	}
+	return formatSingleValue(cmd.OutOrStdout(), entry.Key, value, format)
+}
+
+// rawValue returns the value of entry as written in its file, quotes
+// included, without the trailing inline comment.
+func rawValue(entry parser.Entry) string {
+	raw := strings.TrimSpace(entry.Raw)
+	if entry.InlineComment == "" {
+		return raw
+	}
+	// The comment starts at the first "#" followed by exactly its text;
+	// earlier ones are part of the value.
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && strings.TrimSpace(raw[i+1:]) == entry.InlineComment {
+			return strings.TrimRightFunc(raw[:i], unicode.IsSpace)
+		}
+	}
+	return raw
 }
 
 // printWarnings writes parser warnings to stderr for the given file.
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/ref"
+)
+
+// valueOrigin is where the value of a key comes from, as shown by
+// get --origin.
+type valueOrigin struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	layerDefinition
+	// Overrides lists the earlier layers that set the key too.
+	Overrides []layerDefinition `json:"overrides,omitempty"`
+	// Refs lists where each ref:// URI in the value was resolved.
+	Refs []refOrigin `json:"refs,omitempty"`
+}
+
+// layerDefinition is a line of an env layer that sets a key.
+type layerDefinition struct {
+	Layer string `json:"layer"`
+	File  string `json:"file"`
+	Line  int    `json:"line"`
+}
+
+// refOrigin is the backend that served a ref:// URI, or why none did.
+type refOrigin struct {
+	Ref     string `json:"ref"`
+	Backend string `json:"backend,omitempty"`
+	Profile string `json:"profile,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// runGetOrigin prints where the merged entry of env, shown as value, comes
+// from: the layers that set it and, for references, the backends that
+// serve them. cfg is the config env was loaded with, or nil.
+func runGetOrigin(cmd *cobra.Command, cfg *config.Config, env *envfile.Env, entry parser.Entry, value, envPath, profilePath, localPath string, format OutputFormat) error {
+	origin := valueOrigin{Key: entry.Key, Value: value}
+	defs := keyDefinitions(cmd, cfg, entry.Key, envPath, profilePath, localPath)
+	if n := len(defs); n > 0 {
+		origin.layerDefinition = defs[n-1]
+		origin.Overrides = defs[:n-1]
+	}
+
+	var resolveErr error
+	if entry.IsRef || ref.ContainsRef(entry.Value) {
+		origin.Refs, resolveErr = resolveRefOrigins(cmd, env, entry)
+	}
+
+	if format == FormatJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(origin); err != nil {
+			return err
+		}
+	} else {
+		printValueOrigin(output.NewWriter(cmd), origin)
+	}
+	return resolveErr
+}
+
+// keyDefinitions returns the definitions of key in the layers at envPath,
+// profilePath, and localPath, in merge order. Like keySources, it ignores
+// errors, since the layers are loaded and merged already.
+func keyDefinitions(cmd *cobra.Command, cfg *config.Config, key, envPath, profilePath, localPath string) []layerDefinition {
+	var defs []layerDefinition
+	for _, l := range []struct{ name, path string }{
+		{layerBase, envPath},
+		{layerProfile, profilePath},
+		{layerLocal, localPath},
+	} {
+		if l.path == "" {
+			continue
+		}
+		env, _, err := loadEnvFile(cmd, l.path, false, cfg)
+		if err != nil {
+			continue
+		}
+		if e, ok := env.Get(key); ok {
+			defs = append(defs, layerDefinition{Layer: l.name, File: envPathName(l.path), Line: e.Line})
+		}
+	}
+	return defs
+}
+
+// resolveRefOrigins resolves the references in the value of entry with the
+// backends of the project config, following ref://self references through
+// env, and returns where each was found.
+func resolveRefOrigins(cmd *cobra.Command, env *envfile.Env, entry parser.Entry) ([]refOrigin, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, projectDir, err := config.Load(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	if len(cfg.Backends) == 0 {
+		return nil, withExitCode(exitConfig, fmt.Errorf("ref:// references found but no backends configured in %s", config.FullFileName))
+	}
+
+	// Resolve only entry and the variables its ref://self chain copies.
+	sub := envfile.NewEnv()
+	for e, ok := entry, true; ok; {
+		if _, dup := sub.Get(e.Key); dup {
+			break
+		}
+		sub.Set(e)
+		parsed, err := ref.Parse(e.Value)
+		if !e.IsRef || err != nil || parsed.Backend != ref.SelfBackend {
+			break
+		}
+		e, ok = env.Get(parsed.Path)
+	}
+
+	registry, err := buildResolveRegistry(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("initializing backends: %w", err)
+	}
+	defer registry.CloseAll()
+
+	profile := cfg.EffectiveProfile("")
+	result, err := resolveWithProgress(cmd, sub, registry, cfg, cfg.ProfileChain(profile))
+	if err != nil {
+		return nil, fmt.Errorf("resolving references: %w", err)
+	}
+	auditRefReads(cfg, projectDir, profile, sub, result)
+
+	var origins []refOrigin
+	for _, e := range result.Entries {
+		if e.Key != entry.Key {
+			continue
+		}
+		for _, o := range e.Origins {
+			origins = append(origins, refOrigin{Ref: o.Ref, Backend: o.Backend, Profile: o.Profile})
+		}
+	}
+	for _, keyErr := range result.Errors {
+		origins = append(origins, refOrigin{Ref: keyErr.Ref, Error: keyErr.Err.Error()})
+	}
+	if !result.Resolved() {
+		return origins, withExitCode(unresolvedExitCode(result.Errors), fmt.Errorf("%d reference(s) could not be resolved", len(result.Errors)))
+	}
+	return origins, nil
+}
+
+// printValueOrigin writes origin as indented lines under the key.
+func printValueOrigin(w *output.Writer, origin valueOrigin) {
+	out := w.Stdout()
+	_, _ = fmt.Fprintln(out, w.Bold(origin.Key))
+	_, _ = fmt.Fprintf(out, "  value:     %s\n", origin.Value)
+	if origin.File != "" {
+		_, _ = fmt.Fprintf(out, "  set in:    %s:%d (%s)\n", origin.File, origin.Line, origin.Layer)
+	}
+	for _, d := range origin.Overrides {
+		_, _ = fmt.Fprintf(out, "  overrides: %s:%d (%s)\n", d.File, d.Line, d.Layer)
+	}
+	for _, r := range origin.Refs {
+		switch {
+		case r.Error != "":
+			_, _ = fmt.Fprintf(out, "  %s: %s\n", r.Ref, w.Red(r.Error))
+		case r.Profile != "":
+			_, _ = fmt.Fprintf(out, "  %s: served by %s (profile %s)\n", r.Ref, w.Cyan(r.Backend), r.Profile)
+		default:
+			_, _ = fmt.Fprintf(out, "  %s: served by %s\n", r.Ref, w.Cyan(r.Backend))
+		}
+	}
+}
@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/xcke/envref/internal/config"
 )
 
 func writeTestFile(t *testing.T, dir, name, content string) string {
@@ -203,3 +206,119 @@ func TestGetCmd_MissingLocalFileIsOptional(t *testing.T) {
 		t.Errorf("expected %q, got %q", "8080\n", got)
 	}
 }
+
+func TestGetCmd_DeprecatedKey(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, config.FullFileName, "project: app\nkey_aliases:\n  DATABASE_URL: [DB_URL]\n")
+	writeTestFile(t, dir, ".env", "DATABASE_URL=postgres://db\n")
+	chdir(t, dir)
+
+	stdout, stderr, err := execCmd(t, "get", "DB_URL")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if stdout != "postgres://db\n" {
+		t.Errorf("got %q, want the value of DATABASE_URL", stdout)
+	}
+	if !strings.Contains(stderr, "DB_URL is deprecated, use DATABASE_URL instead") {
+		t.Errorf("expected a deprecation warning, got %q", stderr)
+	}
+
+	_, stderr, err = execCmd(t, "get", "DATABASE_URL")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if stderr != "" {
+		t.Errorf("expected no warning for the new name, got %q", stderr)
+	}
+}
+
+func TestGetCmd_Origin(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "DB_HOST=localhost\nAPI_KEY=ref://keys/api_key\nCOPY=ref://self/API_KEY\n")
+	writeTestFile(t, dir, ".env.staging", "# staging\nDB_HOST=staging-db\n")
+	writeTestFile(t, dir, ".env.local", "DB_HOST=127.0.0.1\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "get", "DB_HOST", "--origin", "--profile-file", ".env.staging")
+	if err != nil {
+		t.Fatalf("get --origin: %v", err)
+	}
+	for _, want := range []string{
+		"value:     127.0.0.1",
+		"set in:    .env.local:1 (local)",
+		"overrides: .env:1 (base)",
+		"overrides: .env.staging:2 (profile)",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output %q does not contain %q", stdout, want)
+		}
+	}
+
+	// A reference is resolved, without printing the secret, and reports
+	// the backend that served it.
+	_, stderr, err := execCmd(t, "get", "COPY", "--origin")
+	if err == nil || !strings.Contains(stderr+err.Error(), "could not be resolved") {
+		t.Errorf("expected an unresolved reference, got %v", err)
+	}
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "s3cret", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	stdout, _, err = execCmd(t, "get", "COPY", "--origin", "--format", "json")
+	if err != nil {
+		t.Fatalf("get --origin --format json: %v", err)
+	}
+	var origin valueOrigin
+	if err := json.Unmarshal([]byte(stdout), &origin); err != nil {
+		t.Fatalf("parsing JSON: %v\n%s", err, stdout)
+	}
+	if origin.Layer != layerBase || origin.Line != 3 || len(origin.Refs) != 1 ||
+		origin.Refs[0].Ref != "ref://keys/api_key" || origin.Refs[0].Backend != "secrets" {
+		t.Errorf("unexpected origin: %+v", origin)
+	}
+	if strings.Contains(stdout, "s3cret") {
+		t.Errorf("origin output contains the secret: %s", stdout)
+	}
+
+	if _, _, err := execCmd(t, "get", "DB_HOST", "--origin", "--format", "shell"); err == nil {
+		t.Error("expected an error for --origin with --format shell")
+	}
+}
+
+func TestGetCmd_Raw(t *testing.T) {
+	dir := t.TempDir()
+	envPath := writeTestFile(t, dir, ".env", "HOST=db\n"+
+		"URL=\"postgres://${HOST}:5432\" # primary # db\n"+
+		"PLAIN=a#b # note\n"+
+		"TAG='#1' # tag\n"+
+		"API_KEY=secret://api_key\n")
+	localPath := filepath.Join(dir, ".env.local")
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"URL", `"postgres://${HOST}:5432"`},
+		{"PLAIN", "a#b"},
+		{"TAG", "'#1'"},
+		{"API_KEY", "secret://api_key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			stdout, _, err := execCmd(t, "get", tt.key, "--raw", "--file", envPath, "--local-file", localPath)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if stdout != tt.want+"\n" {
+				t.Errorf("expected %q, got %q", tt.want+"\n", stdout)
+			}
+		})
+	}
+
+	// Without --raw the value is interpolated.
+	stdout, _, err := execCmd(t, "get", "URL", "--file", envPath, "--local-file", localPath)
+	if err != nil || stdout != "postgres://db:5432\n" {
+		t.Errorf("get URL: got %q, %v", stdout, err)
+	}
+}
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/platform"
+	"github.com/xcke/envref/internal/suggest"
+)
+
+// newGHCmd creates the gh command group.
+func newGHCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "gh",
+		Aliases: []string{"github"},
+		Short:   "Write resolved secrets to GitHub Actions",
+		Long: `Write resolved secrets to the GitHub Actions secrets of a repository and
+its deployment environments, so that workflows get the same values as
+local development.`,
+	}
+
+	cmd.AddCommand(newGHSyncCmd())
+
+	return cmd
+}
+
+// newGHSyncCmd creates the gh sync subcommand.
+func newGHSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Write mapped secrets to GitHub Actions repository and environment secrets",
+		Long: `Resolve the environment, as 'envref run' does, and write the keys mapped in
+the github section of .envref.yaml to GitHub Actions secrets. Every
+reference must resolve. Values are encrypted to the repository's public
+key (a libsodium sealed box) before they leave the machine.
+
+  github:
+    repo: acme/shop              # defaults to the origin remote
+    secrets:                     # repository secrets
+      - NPM_TOKEN                # key NPM_TOKEN as secret NPM_TOKEN
+    environments:
+      - name: production
+        profile: production      # defaults to the active profile
+        secrets:
+          - DB_URL=DATABASE_URL  # key DATABASE_URL as secret DB_URL
+
+Repository secrets are resolved with --profile, or the active profile; each
+environment with its own profile if it has one. With --environment, only
+the named environments are written.
+
+GitHub never reveals secret values, so every mapped secret is written. The
+changes are listed, by name only, and applied after confirmation. Secrets
+that are not mapped are kept unless --prune is given.
+
+The token is read from GITHUB_TOKEN or GH_TOKEN, or from the GitHub CLI if
+it is logged in. It needs write access to the repository's secrets (and,
+for fine-grained tokens, its environments). GITHUB_API_URL selects a GitHub
+Enterprise Server.
+
+Examples:
+  envref gh sync --dry-run                  # show what would change
+  envref gh sync --environment production
+  envref gh sync --repo acme/shop --yes
+  envref gh sync --prune                    # also remove unmapped secrets`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			repo, _ := cmd.Flags().GetString("repo")
+			environments, _ := cmd.Flags().GetStringArray("environment")
+			prune, _ := cmd.Flags().GetBool("prune")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			yes, _ := cmd.Flags().GetBool("yes")
+			return runGHSync(cmd, profile, repo, environments, prune, dryRun, yes)
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
+	cmd.Flags().String("repo", "", "repository to write to, as owner/name (overrides github.repo)")
+	cmd.Flags().StringArray("environment", nil, "write only this environment's secrets (repeatable)")
+	cmd.Flags().Bool("prune", false, "remove secrets that are not mapped")
+	cmd.Flags().Bool("dry-run", false, "show the changes without writing them")
+	cmd.Flags().BoolP("yes", "y", false, "apply the changes without asking")
+
+	return cmd
+}
+
+// ghScope is a set of secrets to write: the repository's, or those of one
+// environment.
+type ghScope struct {
+	// environment is the environment name, or "" for the repository.
+	environment string
+	// profile is the profile the secrets are resolved with.
+	profile string
+	secrets []config.GitHubSecret
+}
+
+// ghPlan is the changes to one scope.
+type ghPlan struct {
+	secrets *platform.GitHubSecrets
+	desired map[string]string
+	changes platform.Changes
+}
+
+// runGHSync implements the gh sync command logic.
+func runGHSync(cmd *cobra.Command, profile, repo string, environments []string, prune, dryRun, yes bool) error {
+	cfg, projectDir, err := loadServeConfig()
+	if err != nil {
+		return err
+	}
+	scopes, err := ghScopes(cfg.GitHub, profile, environments)
+	if err != nil {
+		return err
+	}
+	if repo == "" {
+		repo = cfg.GitHub.Repo
+	}
+	if repo == "" {
+		if repo, err = ghRepo(projectDir); err != nil {
+			return err
+		}
+	}
+	token, err := ghToken()
+	if err != nil {
+		return err
+	}
+	apiURL := os.Getenv("GITHUB_API_URL")
+	if apiURL == "" {
+		apiURL = platform.GitHubAPI
+	}
+
+	// Resolve each profile once.
+	resolved := make(map[string]map[string]string)
+	ctx := cmdContext(cmd)
+	w := output.NewWriter(cmd)
+	var plans []ghPlan
+	for _, scope := range scopes {
+		env, ok := resolved[scope.profile]
+		if !ok {
+			entries, err := resolveEnvEntries(cmd, scope.profile, true)
+			if err != nil {
+				return err
+			}
+			env = make(map[string]string, len(entries))
+			for _, e := range entries {
+				env[e.Key] = e.Value
+			}
+			resolved[scope.profile] = env
+		}
+
+		desired := make(map[string]string, len(scope.secrets))
+		for _, s := range scope.secrets {
+			value, ok := env[s.Key]
+			if !ok {
+				keys := make([]string, 0, len(env))
+				for key := range env {
+					keys = append(keys, key)
+				}
+				return fmt.Errorf("secret %s: key %q is not in the environment%s", s.Name, s.Key, suggest.FormatSuggestion(suggest.Keys(s.Key, keys)))
+			}
+			desired[s.Name] = value
+		}
+
+		secrets := platform.NewGitHubSecrets(apiURL, token, repo, scope.environment)
+		current, err := secrets.Vars(ctx)
+		if err != nil {
+			return err
+		}
+		changes := platform.Diff(current, desired, false, prune)
+		w.Info("%s: %d to add, %d to update, %d to remove\n",
+			secrets.Describe(), len(changes.Added), len(changes.Updated), len(changes.Removed))
+		printKeyChanges(w, changes.Added, changes.Updated, changes.Removed)
+		if len(changes.Kept) > 0 {
+			w.Info("kept %d secret(s) that are not mapped (use --prune to remove): %s\n", len(changes.Kept), strings.Join(changes.Kept, ", "))
+		}
+		plans = append(plans, ghPlan{secrets: secrets, desired: desired, changes: changes})
+	}
+
+	if !slices.ContainsFunc(plans, func(p ghPlan) bool { return !p.changes.Empty() }) {
+		w.Info("%s is up to date\n", repo)
+		return nil
+	}
+	if dryRun {
+		w.Info("(dry run: no changes made)\n")
+		return nil
+	}
+	if !yes {
+		ok, err := confirmChanges(cmd, repo)
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	for _, p := range plans {
+		if p.changes.Empty() {
+			continue
+		}
+		if err := p.secrets.Apply(ctx, p.changes.Set(p.desired), p.changes.Removed); err != nil {
+			return err
+		}
+		w.Info("updated %s: %d added, %d updated, %d removed\n",
+			p.secrets.Describe(), len(p.changes.Added), len(p.changes.Updated), len(p.changes.Removed))
+	}
+	return nil
+}
+
+// ghScopes returns the scopes of gh, the repository's first, resolved with
+// profile unless an environment names its own. If environments is not
+// empty, only those environments are returned.
+func ghScopes(gh config.GitHubConfig, profile string, environments []string) ([]ghScope, error) {
+	var scopes []ghScope
+	if len(environments) == 0 && len(gh.Secrets) > 0 {
+		secrets, err := config.ParseGitHubSecrets(gh.Secrets)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, ghScope{profile: profile, secrets: secrets})
+	}
+
+	names := make([]string, len(gh.Environments))
+	for i, env := range gh.Environments {
+		names[i] = env.Name
+	}
+	for _, name := range environments {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("environment %q is not in the github section of %s%s", name, config.FullFileName, suggest.FormatSuggestion(suggest.Keys(name, names)))
+		}
+	}
+	for _, env := range gh.Environments {
+		if len(environments) > 0 && !slices.Contains(environments, env.Name) || len(env.Secrets) == 0 {
+			continue
+		}
+		secrets, err := config.ParseGitHubSecrets(env.Secrets)
+		if err != nil {
+			return nil, err
+		}
+		scope := ghScope{environment: env.Name, profile: env.Profile, secrets: secrets}
+		if scope.profile == "" {
+			scope.profile = profile
+		}
+		scopes = append(scopes, scope)
+	}
+
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("no secrets are mapped in the github section of %s", config.FullFileName)
+	}
+	return scopes, nil
+}
+
+// ghToken returns the GitHub token from GITHUB_TOKEN or GH_TOKEN, or from
+// the GitHub CLI.
+func ghToken() (string, error) {
+	for _, name := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token, nil
+		}
+	}
+	if out, err := exec.Command("gh", "auth", "token").Output(); err == nil {
+		if token := strings.TrimSpace(string(out)); token != "" {
+			return token, nil
+		}
+	}
+	return "", fmt.Errorf("github: set GITHUB_TOKEN or log in with 'gh auth login'")
+}
+
+// ghRemotePattern matches the owner/name of a GitHub remote URL, such as
+// git@github.com:acme/shop.git or https://github.com/acme/shop.
+var ghRemotePattern = regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?[^:/]+(?::\d+)?[:/]([^/]+/[^/]+?)(?:\.git)?/?$`)
+
+// ghRepo returns the repository from GITHUB_REPOSITORY, as set in GitHub
+// Actions, or from the origin remote of the git repository at dir.
+func ghRepo(dir string) (string, error) {
+	if repo := os.Getenv("GITHUB_REPOSITORY"); repo != "" {
+		return repo, nil
+	}
+	git := exec.Command("git", "remote", "get-url", "origin")
+	git.Dir = dir
+	out, err := git.Output()
+	if err != nil {
+		return "", fmt.Errorf("no repository given (use --repo or set github.repo in %s)", config.FullFileName)
+	}
+	repo, ok := parseGHRemote(strings.TrimSpace(string(out)))
+	if !ok {
+		return "", fmt.Errorf("cannot tell the repository from origin remote %q (use --repo)", strings.TrimSpace(string(out)))
+	}
+	return repo, nil
+}
+
+// parseGHRemote returns the owner/name of a remote URL.
+func parseGHRemote(remote string) (string, bool) {
+	m := ghRemotePattern.FindStringSubmatch(remote)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+func TestParseGHRemote(t *testing.T) {
+	for remote, want := range map[string]string{
+		"git@github.com:acme/shop.git":         "acme/shop",
+		"https://github.com/acme/shop":         "acme/shop",
+		"https://github.com/acme/shop.git":     "acme/shop",
+		"ssh://git@github.com/acme/shop.git":   "acme/shop",
+		"https://ghe.example.com:8443/a/b.git": "a/b",
+	} {
+		if got, ok := parseGHRemote(remote); !ok || got != want {
+			t.Errorf("parseGHRemote(%q) = %q, %v; want %q", remote, got, ok, want)
+		}
+	}
+	if _, ok := parseGHRemote("/srv/git/shop"); ok {
+		t.Error("a local path should not parse")
+	}
+}
+
+// fakeGitHub serves the secrets API for repository acme/shop, which has
+// the secret OLD, and its environment prod, and records the writes.
+func fakeGitHub(t *testing.T) *[]string {
+	t.Helper()
+	pub, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/public-key"):
+			_, _ = fmt.Fprintf(w, `{"key_id":"k1","key":%q}`, base64.StdEncoding.EncodeToString(pub[:]))
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/shop/actions/secrets":
+			_, _ = fmt.Fprint(w, `{"total_count":1,"secrets":[{"name":"OLD"}]}`)
+		case r.Method == http.MethodGet:
+			_, _ = fmt.Fprint(w, `{"total_count":0,"secrets":[]}`)
+		default:
+			calls = append(calls, r.Method+" "+r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("GITHUB_API_URL", srv.URL)
+	t.Setenv("GITHUB_TOKEN", "tok")
+	return &calls
+}
+
+func TestGHSyncCmd(t *testing.T) {
+	calls := fakeGitHub(t)
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".envref.yaml", `project: app
+backends:
+  - name: secrets
+    type: memory
+    config:
+      path: `+dir+`/secrets.json
+github:
+  repo: acme/shop
+  secrets: [NPM_TOKEN]
+  environments:
+    - name: prod
+      secrets: ["DB=DATABASE_URL"]
+`)
+	writeTestFile(t, dir, ".env", "NPM_TOKEN=ref://secrets/NPM_TOKEN\nDATABASE_URL=postgres://db\n")
+	chdir(t, dir)
+	if _, _, err := execCmd(t, "secret", "set", "NPM_TOKEN", "--value", "npm-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	stdout, _, err := execCmd(t, "gh", "sync", "--dry-run", "--prune")
+	if err != nil {
+		t.Fatalf("gh sync --dry-run: %v", err)
+	}
+	if !strings.Contains(stdout, "github repository acme/shop: 1 to add, 0 to update, 1 to remove") ||
+		!strings.Contains(stdout, "github environment prod of acme/shop: 1 to add, 0 to update, 0 to remove") ||
+		!strings.Contains(stdout, "  + DB\n") {
+		t.Errorf("unexpected dry run output:\n%s", stdout)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("dry run wrote secrets: %v", *calls)
+	}
+
+	stdout, _, err = execCmd(t, "gh", "sync", "--environment", "prod", "--yes")
+	if err != nil {
+		t.Fatalf("gh sync: %v", err)
+	}
+	if strings.Contains(stdout, "npm-123") || strings.Contains(stdout, "postgres://db") {
+		t.Error("gh sync printed a secret value")
+	}
+	if strings.Join(*calls, ",") != "PUT /repos/acme/shop/environments/prod/secrets/DB" {
+		t.Errorf("unexpected writes: %v", *calls)
+	}
+
+	_, _, err = execCmd(t, "gh", "sync", "--environment", "prd")
+	if err == nil || !strings.Contains(err.Error(), `environment "prd" is not in the github section`) || !strings.Contains(err.Error(), "prod") {
+		t.Errorf("expected an unknown environment error, got %v", err)
+	}
+}
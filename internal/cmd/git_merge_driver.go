@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/output"
+)
+
+// gitMergeDriverName is the name the driver is configured under in git, as
+// used in .gitattributes: "merge=envref".
+const gitMergeDriverName = "envref"
+
+// gitMergeDriverPatterns are the files installed to use the driver.
+var gitMergeDriverPatterns = []string{".env", ".env.*"}
+
+// newGitMergeDriverCmd creates the git-merge-driver subcommand.
+func newGitMergeDriverCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "git-merge-driver <ANCESTOR> <OURS> <THEIRS> [PATH]",
+		Short: "Merge .env files key by key as a git merge driver",
+		Long: `Merge .env files key by key when git merges branches, instead of line by
+line. Run 'envref git-merge-driver install' once per clone to set it up.
+
+Git calls the driver with the common ancestor, our version, and their
+version of a file (%O, %A, and %B), and the path being merged (%P). The
+changes each side made are merged per key:
+  - keys added, changed, or deleted on one side only are taken as is
+  - keys changed the same way on both sides are kept
+  - keys changed in different ways on both sides are conflicts
+
+The comments and key order of our version are kept. Conflicting keys are
+written between conflict markers and the driver exits with code 1, so git
+reports the file as conflicted. A version that cannot be parsed is merged
+line by line with 'git merge-file' instead.
+
+Examples:
+  envref git-merge-driver install    # configure git for this repository
+  git merge feature                  # .env files now merge key by key`,
+		Args: cobra.RangeArgs(3, 4),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[1]
+			if len(args) == 4 {
+				path = args[3]
+			}
+			return runGitMergeDriver(cmd, args[0], args[1], args[2], path)
+		},
+	}
+
+	cmd.AddCommand(newGitMergeDriverInstallCmd())
+
+	return cmd
+}
+
+// runGitMergeDriver merges the changes theirs made to ancestor into ours,
+// writing the result to ours. path is the name of the merged file for
+// messages.
+func runGitMergeDriver(cmd *cobra.Command, ancestorPath, oursPath, theirsPath, path string) error {
+	w := output.NewWriter(cmd)
+
+	var files [3][]byte
+	for i, p := range []string{ancestorPath, oursPath, theirsPath} {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", p, err)
+		}
+		files[i] = data
+	}
+
+	mark := func(envfile.Conflict) (envfile.Side, error) { return envfile.MarkConflict, nil }
+	result, err := envfile.ThreeWayMerge(files[0], files[1], files[2], mark)
+	if err != nil {
+		w.Warn("cannot merge %s key by key, merging line by line: %v\n", path, err)
+		return gitMergeFile(cmd, ancestorPath, oursPath, theirsPath)
+	}
+
+	if err := os.WriteFile(oursPath, result.Data, 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", oursPath, err)
+	}
+	w.Verbose("merged %s: %d added, %d updated, %d removed\n", path, len(result.Added), len(result.Updated), len(result.Removed))
+
+	if len(result.Conflicts) > 0 {
+		for _, key := range result.Conflicts {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "conflict: %s\n", key)
+		}
+		return withExitCode(exitGeneral, fmt.Errorf("%d conflicting key(s) in %s: %s", len(result.Conflicts), path, strings.Join(result.Conflicts, ", ")))
+	}
+	return nil
+}
+
+// gitMergeFile merges the files line by line with git merge-file, writing
+// the result to ours. It fails when there are conflicts.
+func gitMergeFile(cmd *cobra.Command, ancestorPath, oursPath, theirsPath string) error {
+	git := exec.Command("git", "merge-file", "-L", "ours", "-L", "base", "-L", "theirs", oursPath, ancestorPath, theirsPath)
+	git.Stderr = cmd.ErrOrStderr()
+	err := git.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		return withExitCode(exitGeneral, fmt.Errorf("%d conflict(s) in %s", exitErr.ExitCode(), oursPath))
+	}
+	if err != nil {
+		return fmt.Errorf("running git merge-file: %w", err)
+	}
+	return nil
+}
+
+// newGitMergeDriverInstallCmd creates the git-merge-driver install
+// subcommand.
+func newGitMergeDriverInstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "install",
+		Short: "Configure git to merge .env files with envref",
+		Long: `Configure the current git repository to merge .env files key by key.
+
+The driver is defined in the repository's git config, which is not
+committed, so each clone runs this once:
+  git config merge.envref.name "envref key-wise .env merge"
+  git config merge.envref.driver "envref git-merge-driver %O %A %B %P"
+
+The files that use it are listed in .gitattributes at the root of the
+repository, which is committed:
+  .env merge=envref
+  .env.* merge=envref
+
+Running install again is safe; entries already present are kept.
+
+Examples:
+  envref git-merge-driver install`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGitMergeDriverInstall(cmd)
+		},
+	}
+}
+
+// runGitMergeDriverInstall implements the git-merge-driver install command
+// logic.
+func runGitMergeDriverInstall(cmd *cobra.Command) error {
+	w := output.NewWriter(cmd)
+
+	top, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return fmt.Errorf("not inside a git repository")
+	}
+	root := strings.TrimSpace(string(top))
+
+	settings := [][2]string{
+		{"merge." + gitMergeDriverName + ".name", "envref key-wise .env merge"},
+		{"merge." + gitMergeDriverName + ".driver", "envref git-merge-driver %O %A %B %P"},
+	}
+	for _, s := range settings {
+		git := exec.Command("git", "config", s[0], s[1])
+		git.Dir = root
+		if out, err := git.CombinedOutput(); err != nil {
+			return fmt.Errorf("setting git config %s: %w: %s", s[0], err, strings.TrimSpace(string(out)))
+		}
+		w.Info("  set git config %s\n", s[0])
+	}
+
+	msgOut := cmd.OutOrStdout()
+	if w.IsQuiet() {
+		msgOut = io.Discard
+	}
+	for _, pattern := range gitMergeDriverPatterns {
+		entry := pattern + " merge=" + gitMergeDriverName
+		if err := ensureFileEntry(msgOut, filepath.Join(root, ".gitattributes"), entry); err != nil {
+			return err
+		}
+	}
+
+	w.Info("\n.env files in %s now merge key by key (commit .gitattributes to share this)\n", root)
+	return nil
+}
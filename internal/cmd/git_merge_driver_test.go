@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitMergeDriverCmd_Clean(t *testing.T) {
+	dir := t.TempDir()
+	ancestor := writeTestFile(t, dir, "base", "HOST=localhost\nPORT=80\nOLD=1\n")
+	ours := writeTestFile(t, dir, "ours", "HOST=localhost\nPORT=8080\nOLD=1\n")
+	theirs := writeTestFile(t, dir, "theirs", "HOST=example.com\nPORT=80\nNEW=2\n")
+
+	_, stderr, err := execCmd(t, "git-merge-driver", ancestor, ours, theirs, ".env")
+	require.NoError(t, err, stderr)
+
+	data, err := os.ReadFile(ours)
+	require.NoError(t, err)
+	assert.Equal(t, "HOST=example.com\nPORT=8080\nNEW=2\n", string(data))
+}
+
+func TestGitMergeDriverCmd_Conflict(t *testing.T) {
+	dir := t.TempDir()
+	ancestor := writeTestFile(t, dir, "base", "HOST=localhost\nPORT=80\n")
+	ours := writeTestFile(t, dir, "ours", "HOST=ours.local\nPORT=80\n")
+	theirs := writeTestFile(t, dir, "theirs", "HOST=theirs.local\nPORT=81\n")
+
+	_, stderr, err := execCmd(t, "git-merge-driver", ancestor, ours, theirs, ".env")
+	require.Error(t, err)
+	assert.Equal(t, exitGeneral, exitCode(err))
+	assert.Contains(t, err.Error(), "1 conflicting key(s) in .env: HOST")
+	assert.Contains(t, stderr, "conflict: HOST")
+
+	data, err := os.ReadFile(ours)
+	require.NoError(t, err)
+	assert.Equal(t, "<<<<<<< ours\nHOST=ours.local\n=======\nHOST=theirs.local\n>>>>>>> theirs\nPORT=81\n", string(data))
+}
+
+func TestGitMergeDriverCmd_LineFallback(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	ancestor := writeTestFile(t, dir, "base", "A=1\nB=\"open\n")
+	ours := writeTestFile(t, dir, "ours", "A=1\nB=\"open\n")
+	theirs := writeTestFile(t, dir, "theirs", "A=2\nB=\"open\n")
+
+	_, stderr, err := execCmd(t, "git-merge-driver", ancestor, ours, theirs)
+	require.NoError(t, err, stderr)
+	assert.Contains(t, stderr, "merging line by line")
+
+	data, err := os.ReadFile(ours)
+	require.NoError(t, err)
+	assert.Equal(t, "A=2\nB=\"open\n", string(data))
+}
+
+func TestGitMergeDriverInstallCmd(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	chdir(t, dir)
+	if out, err := exec.Command("git", "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	writeTestFile(t, dir, ".gitattributes", "*.png binary\n")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	chdir(t, filepath.Join(dir, "sub"))
+
+	stdout, stderr, err := execCmd(t, "git-merge-driver", "install")
+	require.NoError(t, err, stderr)
+	assert.Contains(t, stdout, "update .gitattributes (added .env merge=envref)")
+
+	driver, err := exec.Command("git", "config", "merge.envref.driver").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "envref git-merge-driver %O %A %B %P", strings.TrimSpace(string(driver)))
+
+	data, err := os.ReadFile(filepath.Join(dir, ".gitattributes"))
+	require.NoError(t, err)
+	assert.Equal(t, "*.png binary\n.env merge=envref\n.env.* merge=envref\n", string(data))
+
+	// Installing again changes nothing.
+	stdout, stderr, err = execCmd(t, "git-merge-driver", "install")
+	require.NoError(t, err, stderr)
+	assert.Contains(t, stdout, "skip .gitattributes")
+	again, err := os.ReadFile(filepath.Join(dir, ".gitattributes"))
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(again))
+}
+
+func TestGitMergeDriverInstallCmd_NotARepo(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	_, _, err := execCmd(t, "git-merge-driver", "install")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not inside a git repository")
+}
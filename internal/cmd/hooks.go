@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/resolve"
+)
+
+// Hook names, exposed to hook commands as ENVREF_HOOK.
+const (
+	hookPreResolve  = "pre_resolve"
+	hookPostResolve = "post_resolve"
+)
+
+// newHooksCmd creates the hooks command group.
+func newHooksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Allow or deny the project's resolve hooks",
+		Long: `Allow or deny the hooks.pre_resolve and hooks.post_resolve commands of
+the project's .envref.yaml.
+
+Hooks run shell commands on every resolve and run, including the ones
+direnv triggers, and a post hook sees every resolved secret. So, like
+'direnv allow', project hooks only run once you have reviewed and allowed
+them, and again after every change to them. Hooks set only in the global
+config always run.`,
+	}
+
+	cmd.AddCommand(newHooksAllowCmd())
+	cmd.AddCommand(newHooksDenyCmd())
+
+	return cmd
+}
+
+// newHooksAllowCmd creates the hooks allow subcommand.
+func newHooksAllowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "allow",
+		Short: "Allow the project's current hooks to run",
+		Long: `Print the project's hooks and allow them to run until they change.
+
+Examples:
+  envref hooks allow`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHooksAllow(cmd)
+		},
+	}
+}
+
+// newHooksDenyCmd creates the hooks deny subcommand.
+func newHooksDenyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "deny",
+		Short: "Stop the project's hooks from running",
+		Long: `Revoke an earlier 'envref hooks allow' for the project.
+
+Examples:
+  envref hooks deny`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHooksDeny(cmd)
+		},
+	}
+}
+
+// runHooksAllow records the hooks of the project in the working directory
+// as trusted.
+func runHooksAllow(cmd *cobra.Command) error {
+	cfg, projectDir, err := loadHooksConfig()
+	if err != nil {
+		return err
+	}
+	w := output.NewWriter(cmd)
+	if cfg.Hooks.IsEmpty() {
+		w.Info("no hooks configured in %s\n", config.FullFileName)
+		return nil
+	}
+	if err := config.TrustHooks(projectDir, cfg.Hooks); err != nil {
+		return fmt.Errorf("allowing hooks: %w", err)
+	}
+	if cfg.Hooks.PreResolve != "" {
+		w.Info("pre_resolve:  %s\n", cfg.Hooks.PreResolve)
+	}
+	if cfg.Hooks.PostResolve != "" {
+		w.Info("post_resolve: %s\n", cfg.Hooks.PostResolve)
+	}
+	w.Info("hooks allowed for %s\n", projectDir)
+	return nil
+}
+
+// runHooksDeny removes the trust record of the project in the working
+// directory.
+func runHooksDeny(cmd *cobra.Command) error {
+	_, projectDir, err := loadHooksConfig()
+	if err != nil {
+		return err
+	}
+	if err := config.UntrustHooks(projectDir); err != nil {
+		return fmt.Errorf("denying hooks: %w", err)
+	}
+	output.NewWriter(cmd).Info("hooks denied for %s\n", projectDir)
+	return nil
+}
+
+// loadHooksConfig loads the config of the project in the working directory.
+func loadHooksConfig() (*config.Config, string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, "", fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, projectDir, err := config.Load(cwd)
+	if err != nil {
+		return nil, "", fmt.Errorf("loading config: %w", err)
+	}
+	return cfg, projectDir, nil
+}
+
+// runHook runs the hook called name from hooks through the system shell in
+// projectDir. Resolved entries, if any, are added to the hook's environment;
+// pre-resolve hooks are passed none. The hook's output goes to stderr so it
+// never mixes with resolved values written to stdout. An unset hook is a
+// no-op, and project hooks that have not been allowed with 'envref hooks
+// allow' are an error.
+func runHook(cmd *cobra.Command, name string, hooks config.HooksConfig, projectDir string, entries []resolve.Entry) error {
+	command := hooks.PreResolve
+	if name == hookPostResolve {
+		command = hooks.PostResolve
+	}
+	if command == "" {
+		return nil
+	}
+	trusted, err := config.HooksTrusted(projectDir, hooks)
+	if err != nil {
+		return fmt.Errorf("checking hooks: %w", err)
+	}
+	if !trusted {
+		return withExitCode(exitConfig, fmt.Errorf("hooks in %s are new or changed; review them and run 'envref hooks allow' in %s", config.FullFileName, projectDir))
+	}
+	output.NewWriter(cmd).Verbose("running %s hook: %s\n", name, command)
+
+	hook := shellCommand(command)
+	hook.Dir = projectDir
+	hook.Env = append(os.Environ(), "ENVREF_HOOK="+name)
+	for _, entry := range entries {
+		hook.Env = append(hook.Env, entry.Key+"="+entry.Value)
+	}
+	hook.Stdout = cmd.ErrOrStderr()
+	hook.Stderr = cmd.ErrOrStderr()
+
+	if err := hook.Run(); err != nil {
+		return fmt.Errorf("%s hook failed: %w", name, err)
+	}
+	return nil
+}
+
+// shellCommand returns a command that runs line through the system shell.
+func shellCommand(line string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", line)
+	}
+	return exec.Command("sh", "-c", line)
+}
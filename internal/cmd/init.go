@@ -29,7 +29,8 @@ Optional:
   .envrc         — direnv integration (with --direnv flag)
 
 Existing files are skipped unless --force is used.
-The .env.local entry is appended to .gitignore if not already present.`,
+The .env.local and .env.*.local (per-profile local overrides) entries are
+appended to .gitignore if not already present.`,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			project, _ := cmd.Flags().GetString("project")
@@ -107,6 +108,9 @@ APP_PORT=3000
 # Requires: direnv (https://direnv.net)
 # Run 'direnv allow' after creating this file.
 
+# Reload when .envref.yaml or an env file changes.
+while IFS= read -r file; do watch_file "$file"; done < <(envref direnv files 2>/dev/null)
+
 eval "$(envref resolve --direnv 2>/dev/null)" || true
 `
 
@@ -135,8 +139,10 @@ eval "$(envref resolve --direnv 2>/dev/null)" || true
 	}
 
 	// Update .gitignore.
-	if err := ensureGitignoreEntry(msgOut, filepath.Join(dir, ".gitignore"), ".env.local"); err != nil {
-		return err
+	for _, entry := range []string{".env.local", ".env.*.local"} {
+		if err := ensureFileEntry(msgOut, filepath.Join(dir, ".gitignore"), entry); err != nil {
+			return err
+		}
 	}
 
 	w.Info("\nInitialized envref project %q in %s\n", project, dir)
@@ -189,9 +195,10 @@ func writeInitFile(out io.Writer, path, content string, force bool) error {
 	return nil
 }
 
-// ensureGitignoreEntry appends entry to the .gitignore file at path if it is
-// not already present. Creates the file if it does not exist.
-func ensureGitignoreEntry(out io.Writer, path, entry string) error {
+// ensureFileEntry appends entry to the line-based file at path, such as
+// .gitignore, if it is not already present. Creates the file if it does not
+// exist.
+func ensureFileEntry(out io.Writer, path, entry string) error {
 	// Read existing content.
 	data, err := os.ReadFile(path)
 	if err != nil && !os.IsNotExist(err) {
@@ -203,7 +210,7 @@ func ensureGitignoreEntry(out io.Writer, path, entry string) error {
 	// Check if entry already present (exact line match).
 	for _, line := range strings.Split(content, "\n") {
 		if strings.TrimSpace(line) == entry {
-			_, _ = fmt.Fprintf(out, "  skip .gitignore (%s already listed)\n", entry)
+			_, _ = fmt.Fprintf(out, "  skip %s (%s already listed)\n", filepath.Base(path), entry)
 			return nil
 		}
 	}
@@ -221,9 +228,9 @@ func ensureGitignoreEntry(out io.Writer, path, entry string) error {
 	}
 
 	if len(data) == 0 {
-		_, _ = fmt.Fprintf(out, "  create .gitignore\n")
+		_, _ = fmt.Fprintf(out, "  create %s\n", filepath.Base(path))
 	} else {
-		_, _ = fmt.Fprintf(out, "  update .gitignore (added %s)\n", entry)
+		_, _ = fmt.Fprintf(out, "  update %s (added %s)\n", filepath.Base(path), entry)
 	}
 
 	return nil
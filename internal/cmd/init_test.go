@@ -97,6 +97,9 @@ func TestInitCmd_DirenvFlag(t *testing.T) {
 	if !strings.Contains(string(envrcData), "envref resolve --direnv") {
 		t.Errorf(".envrc should contain envref resolve command, got:\n%s", envrcData)
 	}
+	if !strings.Contains(string(envrcData), "envref direnv files") {
+		t.Errorf(".envrc should watch the files envref reads, got:\n%s", envrcData)
+	}
 
 	// Verify output mentions direnv allow.
 	output := buf.String()
@@ -259,6 +262,9 @@ func TestInitCmd_AppendToExistingGitignore(t *testing.T) {
 	if !strings.Contains(content, ".env.local") {
 		t.Errorf(".gitignore should contain '.env.local', got:\n%s", content)
 	}
+	if !strings.Contains(content, ".env.*.local") {
+		t.Errorf(".gitignore should contain '.env.*.local', got:\n%s", content)
+	}
 
 	output := buf.String()
 	if !strings.Contains(output, "update .gitignore") {
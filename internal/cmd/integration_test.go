@@ -68,6 +68,16 @@ func setupProject(t *testing.T, project string, envContent, localContent string)
 	return dir
 }
 
+// writeMemoryTestConfig writes an .envref.yaml for project that uses a
+// memory backend named "secrets", persisted to secrets.json in dir so that
+// secrets survive across commands.
+func writeMemoryTestConfig(t *testing.T, dir, project string) {
+	t.Helper()
+	cfgContent := "project: " + project + "\nbackends:\n  - name: secrets\n    type: memory\n    config:\n      path: " +
+		filepath.Join(dir, "secrets.json") + "\n"
+	writeTestFile(t, dir, config.FullFileName, cfgContent)
+}
+
 // chdir changes to the specified directory and returns a cleanup function
 // that restores the original directory.
 func chdir(t *testing.T, dir string) {
@@ -789,6 +799,75 @@ func TestIntegration_SecretSet_BlankLineStdin(t *testing.T) {
 	}
 }
 
+// --- Secret Commands (success paths) -----------------------------------------
+// These tests use the memory backend, which needs no keychain or secret manager.
+
+func TestIntegration_SecretLifecycle_MemoryBackend(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "testproject")
+	writeTestFile(t, dir, ".env", "HOST=localhost\nAPI_KEY=ref://secrets/api_key\n")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "sk-test-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	stdout, _, err := execCmd(t, "secret", "get", "api_key")
+	if err != nil {
+		t.Fatalf("secret get: %v", err)
+	}
+	if strings.TrimSpace(stdout) != "sk-test-123" {
+		t.Errorf("secret get: got %q, want %q", stdout, "sk-test-123")
+	}
+
+	stdout, _, err = execCmd(t, "secret", "list")
+	if err != nil {
+		t.Fatalf("secret list: %v", err)
+	}
+	if !strings.Contains(stdout, "api_key") {
+		t.Errorf("secret list: expected api_key, got %q", stdout)
+	}
+
+	stdout, stderr, err := execCmd(t, "resolve", "--strict")
+	if err != nil {
+		t.Fatalf("resolve --strict: %v (stderr: %s)", err, stderr)
+	}
+	if stdout != "HOST=localhost\nAPI_KEY=sk-test-123\n" {
+		t.Errorf("resolve --strict: got %q", stdout)
+	}
+
+	if _, _, err := execCmd(t, "secret", "delete", "api_key", "--force"); err != nil {
+		t.Fatalf("secret delete: %v", err)
+	}
+	if _, _, err := execCmd(t, "resolve", "--strict"); err == nil {
+		t.Error("resolve --strict after delete: expected error, got nil")
+	}
+}
+
+func TestIntegration_Resolve_SeededMemoryBackend(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "testproject")
+	writeTestFile(t, dir, "secrets.json", `{"testproject/db_pass": "hunter2", "testproject/staging/db_pass": "staging-pass"}`)
+	writeTestFile(t, dir, ".env", "DB_PASS=ref://secrets/db_pass\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "resolve", "--strict")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if stdout != "DB_PASS=hunter2\n" {
+		t.Errorf("resolve: got %q, want %q", stdout, "DB_PASS=hunter2\n")
+	}
+
+	stdout, _, err = execCmd(t, "resolve", "--strict", "--profile", "staging")
+	if err != nil {
+		t.Fatalf("resolve --profile staging: %v", err)
+	}
+	if stdout != "DB_PASS=staging-pass\n" {
+		t.Errorf("resolve --profile staging: got %q, want %q", stdout, "DB_PASS=staging-pass\n")
+	}
+}
+
 // --- Resolve Command (no-ref paths and error paths) --------------------------
 
 func TestIntegration_Resolve_NoRefs_PlainOutput(t *testing.T) {
@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/ref"
+)
+
+// k8sManagedByLabel marks the Secrets written by envref.
+const k8sManagedByLabel = "app.kubernetes.io/managed-by"
+
+// newK8sCmd creates the k8s command group.
+func newK8sCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "k8s",
+		Aliases: []string{"kubernetes"},
+		Short:   "Write the resolved environment to a Kubernetes cluster",
+		Long: `Write the resolved environment to a Kubernetes cluster, for teams that
+deploy from a laptop or a simple CI job. kubectl must be installed; it is
+run with the current kubeconfig.`,
+	}
+
+	cmd.AddCommand(newK8sSyncCmd())
+
+	return cmd
+}
+
+// newK8sSyncCmd creates the k8s sync subcommand.
+func newK8sSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Create or update a Kubernetes Secret from the resolved environment",
+		Long: `Resolve the environment, as 'envref run' does, and store every key in a
+Kubernetes Secret, creating it if needed. Every reference must resolve.
+
+Keys already in the Secret but no longer in the environment are kept unless
+--prune is given. The Secret gets the configured labels and
+app.kubernetes.io/managed-by=envref; its other labels and annotations are
+kept. Only key names are printed, never values.
+
+A ref with ?encoding=base64file is stored decoded, so that the key holds the
+file when the Secret is mounted as a volume.
+
+The target is configured in .envref.yaml and can be overridden with flags:
+
+  kubernetes:
+    context: prod-cluster
+    namespace: shop
+    secret: api-env          # defaults to the project name
+    labels: ["app=api"]
+
+Examples:
+  envref k8s sync --dry-run                      # show what would change
+  envref k8s sync --profile production
+  envref k8s sync -n shop --secret api-env --label app=api
+  envref k8s sync --prune                        # also remove stale keys`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			prune, _ := cmd.Flags().GetBool("prune")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return runK8sSync(cmd, profile, prune, dryRun)
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
+	cmd.Flags().String("context", "", "kubeconfig context (overrides kubernetes.context)")
+	cmd.Flags().StringP("namespace", "n", "", "namespace of the Secret (overrides kubernetes.namespace)")
+	cmd.Flags().String("secret", "", "name of the Secret (overrides kubernetes.secret)")
+	cmd.Flags().StringArrayP("label", "l", nil, "label to set on the Secret, as key=value (replaces kubernetes.labels; repeatable)")
+	cmd.Flags().Bool("prune", false, "remove keys that are no longer in the environment")
+	cmd.Flags().Bool("dry-run", false, "show the changes without writing the Secret")
+
+	return cmd
+}
+
+// k8sSecret is the part of a Kubernetes Secret that envref reads and
+// writes.
+type k8sSecret struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   k8sObjectMeta     `json:"metadata"`
+	Type       string            `json:"type,omitempty"`
+	Data       map[string]string `json:"data,omitempty"`
+}
+
+// k8sObjectMeta is the part of a Kubernetes object's metadata that envref
+// reads and writes.
+type k8sObjectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+}
+
+// runK8sSync implements the k8s sync command logic.
+func runK8sSync(cmd *cobra.Command, profileOverride string, prune, dryRun bool) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, _, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	target := cfg.Kubernetes
+	if v, _ := cmd.Flags().GetString("context"); v != "" {
+		target.Context = v
+	}
+	if v, _ := cmd.Flags().GetString("namespace"); v != "" {
+		target.Namespace = v
+	}
+	if v, _ := cmd.Flags().GetString("secret"); v != "" {
+		target.Secret = v
+	}
+	if cmd.Flags().Changed("label") {
+		target.Labels, _ = cmd.Flags().GetStringArray("label")
+	}
+	if target.Secret == "" {
+		target.Secret = cfg.Project
+	}
+	labels, err := config.ParseLabels(target.Labels)
+	if err != nil {
+		return err
+	}
+	labels[k8sManagedByLabel] = "envref"
+
+	kubectl, err := newKubectl(target)
+	if err != nil {
+		return err
+	}
+
+	entries, err := resolveEnvEntries(cmd, profileOverride, true)
+	if err != nil {
+		return err
+	}
+
+	existing, err := kubectl.getSecret(target.Secret)
+	if err != nil {
+		return err
+	}
+
+	secret := &k8sSecret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   k8sObjectMeta{Name: target.Secret, Namespace: target.Namespace},
+		Type:       "Opaque",
+		Data:       make(map[string]string, len(entries)),
+	}
+	if existing != nil {
+		secret.Metadata.Annotations = existing.Metadata.Annotations
+		secret.Metadata.ResourceVersion = existing.Metadata.ResourceVersion
+		secret.Metadata.Labels = maps.Clone(existing.Metadata.Labels)
+		if existing.Type != "" {
+			secret.Type = existing.Type
+		}
+	}
+	if secret.Metadata.Labels == nil {
+		secret.Metadata.Labels = make(map[string]string, len(labels))
+	}
+	maps.Copy(secret.Metadata.Labels, labels)
+
+	var added, updated, removed, kept []string
+	unchanged := 0
+	old := map[string]string{}
+	if existing != nil && existing.Data != nil {
+		old = existing.Data
+	}
+	for _, e := range entries {
+		// Secret data is base64 already, so a base64file value is stored
+		// as is and holds the decoded file when mounted.
+		value := e.Value
+		if e.Encoding != ref.EncodingBase64File {
+			value = base64.StdEncoding.EncodeToString([]byte(e.Value))
+		}
+		secret.Data[e.Key] = value
+		switch prev, ok := old[e.Key]; {
+		case !ok:
+			added = append(added, e.Key)
+		case prev != value:
+			updated = append(updated, e.Key)
+		default:
+			unchanged++
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(old)) {
+		if _, ok := secret.Data[key]; ok {
+			continue
+		}
+		if prune {
+			removed = append(removed, key)
+		} else {
+			secret.Data[key] = old[key]
+			kept = append(kept, key)
+		}
+	}
+
+	changed := existing == nil || len(added)+len(updated)+len(removed) > 0 ||
+		!maps.Equal(existing.Metadata.Labels, secret.Metadata.Labels)
+	if changed && !dryRun {
+		manifest, err := json.Marshal(secret)
+		if err != nil {
+			return fmt.Errorf("encoding secret: %w", err)
+		}
+		op := "replace"
+		if existing == nil {
+			op = "create"
+		}
+		if _, err := kubectl.run(manifest, op, "-f", "-"); err != nil {
+			return fmt.Errorf("writing secret %s: %w", kubectl.describe(target.Secret), err)
+		}
+	}
+
+	w := output.NewWriter(cmd)
+	verb := "updated"
+	switch {
+	case !changed:
+		verb = "unchanged"
+	case dryRun && existing == nil:
+		verb = "would create"
+	case dryRun:
+		verb = "would update"
+	case existing == nil:
+		verb = "created"
+	}
+	w.Info("%s secret %s: %d added, %d updated, %d removed, %d unchanged\n",
+		verb, kubectl.describe(target.Secret), len(added), len(updated), len(removed), unchanged)
+	for _, key := range added {
+		w.Info("  + %s\n", key)
+	}
+	for _, key := range updated {
+		w.Info("  ~ %s\n", key)
+	}
+	for _, key := range removed {
+		w.Info("  - %s\n", key)
+	}
+	if len(kept) > 0 {
+		w.Info("kept %d key(s) not in the environment (use --prune to remove): %s\n", len(kept), strings.Join(kept, ", "))
+	}
+	if dryRun {
+		w.Info("(dry run: no changes made)\n")
+	}
+	return nil
+}
+
+// kubectl runs kubectl against a context and namespace.
+type kubectl struct {
+	command   string
+	context   string
+	namespace string
+}
+
+// newKubectl finds kubectl in PATH.
+func newKubectl(target config.KubernetesConfig) (*kubectl, error) {
+	command, err := exec.LookPath("kubectl")
+	if err != nil {
+		return nil, fmt.Errorf("kubectl not found in PATH (install it from https://kubernetes.io/docs/tasks/tools/)")
+	}
+	return &kubectl{command: command, context: target.Context, namespace: target.Namespace}, nil
+}
+
+// describe returns name qualified with the namespace, if one is set.
+func (k *kubectl) describe(name string) string {
+	if k.namespace == "" {
+		return name
+	}
+	return k.namespace + "/" + name
+}
+
+// run runs kubectl with args and stdin, and returns its stdout. On failure
+// the error holds kubectl's stderr.
+func (k *kubectl) run(stdin []byte, args ...string) ([]byte, error) {
+	var global []string
+	if k.context != "" {
+		global = append(global, "--context", k.context)
+	}
+	if k.namespace != "" {
+		global = append(global, "--namespace", k.namespace)
+	}
+	c := exec.Command(k.command, append(global, args...)...) //nolint:gosec // kubectl from PATH
+	c.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); errors.As(err, &exitErr) && msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// getSecret returns the Secret called name, or nil if it does not exist.
+func (k *kubectl) getSecret(name string) (*k8sSecret, error) {
+	out, err := k.run(nil, "get", "secret", name, "--ignore-not-found", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("reading secret %s: %w", k.describe(name), err)
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	var s k8sSecret
+	if err := json.Unmarshal(out, &s); err != nil {
+		return nil, fmt.Errorf("reading secret %s: %w", k.describe(name), err)
+	}
+	return &s, nil
+}
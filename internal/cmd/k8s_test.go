@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeKubectl puts a kubectl on PATH that keeps a single Secret in a state
+// directory and logs its arguments, and returns the directory.
+func fakeKubectl(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake kubectl is a shell script")
+	}
+	state := t.TempDir()
+	bin := t.TempDir()
+	script := `#!/bin/sh
+echo "$*" >> "` + state + `/log"
+while [ "$1" = "--context" ] || [ "$1" = "--namespace" ]; do shift 2; done
+case "$1" in
+get) [ -f "` + state + `/secret.json" ] && cat "` + state + `/secret.json"; exit 0 ;;
+create|replace) cat > "` + state + `/secret.json"; exit 0 ;;
+esac
+echo "unexpected kubectl call" >&2
+exit 1
+`
+	if err := os.WriteFile(filepath.Join(bin, "kubectl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return state
+}
+
+// readFakeSecret returns the Secret stored by fakeKubectl with its data
+// decoded.
+func readFakeSecret(t *testing.T, state string) (k8sSecret, map[string]string) {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join(state, "secret.json"))
+	if err != nil {
+		t.Fatalf("reading stored secret: %v", err)
+	}
+	var s k8sSecret
+	if err := json.Unmarshal(raw, &s); err != nil {
+		t.Fatalf("stored secret is not JSON: %v", err)
+	}
+	data := make(map[string]string, len(s.Data))
+	for k, v := range s.Data {
+		decoded, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			t.Fatalf("data %s is not base64: %v", k, err)
+		}
+		data[k] = string(decoded)
+	}
+	return s, data
+}
+
+func TestK8sSync(t *testing.T) {
+	state := fakeKubectl(t)
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "A=1\nAPI_KEY=ref://secrets/API_KEY\n")
+	chdir(t, dir)
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "sk-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	stdout, _, err := execCmd(t, "k8s", "sync", "-n", "shop", "--label", "app=api")
+	if err != nil {
+		t.Fatalf("k8s sync: %v", err)
+	}
+	if !strings.Contains(stdout, "created secret shop/app: 2 added") || !strings.Contains(stdout, "+ API_KEY") {
+		t.Errorf("unexpected output:\n%s", stdout)
+	}
+	if strings.Contains(stdout, "sk-123") {
+		t.Error("k8s sync printed a secret value")
+	}
+	secret, data := readFakeSecret(t, state)
+	if secret.Metadata.Name != "app" || secret.Metadata.Namespace != "shop" || secret.Type != "Opaque" {
+		t.Errorf("metadata = %+v, type %q", secret.Metadata, secret.Type)
+	}
+	if secret.Metadata.Labels["app"] != "api" || secret.Metadata.Labels[k8sManagedByLabel] != "envref" {
+		t.Errorf("labels = %v", secret.Metadata.Labels)
+	}
+	if len(data) != 2 || data["A"] != "1" || data["API_KEY"] != "sk-123" {
+		t.Errorf("data = %v", data)
+	}
+	log, _ := os.ReadFile(filepath.Join(state, "log"))
+	if !strings.Contains(string(log), "--namespace shop create -f -") {
+		t.Errorf("expected a create in namespace shop, log:\n%s", log)
+	}
+
+	t.Run("keeps keys unless pruning", func(t *testing.T) {
+		writeTestFile(t, dir, ".env", "B=2\nAPI_KEY=ref://secrets/API_KEY\n")
+		stdout, _, err := execCmd(t, "k8s", "sync", "-n", "shop")
+		if err != nil {
+			t.Fatalf("k8s sync: %v", err)
+		}
+		if !strings.Contains(stdout, "updated secret shop/app: 1 added, 0 updated, 0 removed, 1 unchanged") ||
+			!strings.Contains(stdout, "kept 1 key(s) not in the environment (use --prune to remove): A") {
+			t.Errorf("unexpected output:\n%s", stdout)
+		}
+		secret, data := readFakeSecret(t, state)
+		if data["A"] != "1" || data["B"] != "2" {
+			t.Errorf("data = %v", data)
+		}
+		if secret.Metadata.Labels["app"] != "api" {
+			t.Errorf("existing labels should be kept, got %v", secret.Metadata.Labels)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		stdout, _, err := execCmd(t, "k8s", "sync", "-n", "shop", "--prune", "--dry-run")
+		if err != nil {
+			t.Fatalf("k8s sync --dry-run: %v", err)
+		}
+		if !strings.Contains(stdout, "would update secret shop/app") || !strings.Contains(stdout, "  - A\n") || !strings.Contains(stdout, "(dry run") {
+			t.Errorf("unexpected output:\n%s", stdout)
+		}
+		if _, data := readFakeSecret(t, state); data["A"] != "1" {
+			t.Error("dry run changed the secret")
+		}
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		if _, _, err := execCmd(t, "k8s", "sync", "-n", "shop", "--prune"); err != nil {
+			t.Fatalf("k8s sync --prune: %v", err)
+		}
+		if _, data := readFakeSecret(t, state); len(data) != 2 || data["A"] != "" {
+			t.Errorf("expected A to be pruned, data = %v", data)
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		before, _ := os.ReadFile(filepath.Join(state, "log"))
+		stdout, _, err := execCmd(t, "k8s", "sync", "-n", "shop")
+		if err != nil {
+			t.Fatalf("k8s sync: %v", err)
+		}
+		if !strings.Contains(stdout, "unchanged secret shop/app") {
+			t.Errorf("unexpected output:\n%s", stdout)
+		}
+		after, _ := os.ReadFile(filepath.Join(state, "log"))
+		if strings.Count(string(after), "replace") != strings.Count(string(before), "replace") {
+			t.Error("an unchanged secret should not be written")
+		}
+	})
+
+	t.Run("unresolved reference", func(t *testing.T) {
+		writeTestFile(t, dir, ".env", "MISSING=ref://secrets/MISSING\n")
+		if _, _, err := execCmd(t, "k8s", "sync", "-n", "shop", "--prune"); err == nil {
+			t.Fatal("expected an error for an unresolved reference")
+		}
+		if _, data := readFakeSecret(t, state); data["B"] != "2" {
+			t.Error("a failed resolve should not change the secret")
+		}
+	})
+}
+
+func TestK8sSync_ConfigTarget(t *testing.T) {
+	state := fakeKubectl(t)
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", `project: app
+kubernetes:
+  context: prod
+  namespace: shop
+  secret: api-env
+  labels: ["tier=backend"]
+`)
+	writeTestFile(t, dir, ".env", "A=1\n")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "k8s", "sync"); err != nil {
+		t.Fatalf("k8s sync: %v", err)
+	}
+	secret, _ := readFakeSecret(t, state)
+	if secret.Metadata.Name != "api-env" || secret.Metadata.Labels["tier"] != "backend" {
+		t.Errorf("metadata = %+v", secret.Metadata)
+	}
+	log, _ := os.ReadFile(filepath.Join(state, "log"))
+	if !strings.Contains(string(log), "--context prod --namespace shop get secret api-env") {
+		t.Errorf("unexpected kubectl calls:\n%s", log)
+	}
+
+	if _, _, err := execCmd(t, "k8s", "sync", "--label", "broken"); err == nil || !strings.Contains(err.Error(), "invalid label") {
+		t.Errorf("expected an invalid label error, got %v", err)
+	}
+}
@@ -1,8 +1,16 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
 	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
 	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/ref"
 )
 
 // newListCmd creates the list subcommand.
@@ -15,54 +23,219 @@ func newListCmd() *cobra.Command {
 When a profile file is specified with --profile-file, it is loaded between
 .env and .env.local: .env ← profile ← .env.local.
 
+Any of the files can be - to read it from stdin, as in
+"./gen-env | envref list --file -".
+
 By default, values that are ref:// secret references are masked. Use
 --show-secrets to reveal the full ref:// URIs.
 
-Output format can be specified with --format (plain, json, shell, table).`,
+Use --long to include the type and description annotations declared above
+each key in the .env file:
+
+  # @type: int
+  # @description: HTTP listen port
+  PORT=8080
+
+Keys without a @description show their inline or leading comment instead.
+
+Output format can be specified with --format (plain, json, shell, table).
+JSON gives an array of objects with the key, the value (masked like the
+plain output), whether it is a ref:// reference and to which backend, and
+the layer (base, profile, or local), file, and line that set it, for
+dashboards and editor plugins. With --long, they include the type and
+description too. The table shows where each value comes from (base,
+profile, or local), whether it is a ref:// reference and to which
+backend, and the value.
+On a terminal, plain output shows ref:// values in cyan and values that
+.env.local or the profile file override in yellow (see --no-color).`,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			envFile, _ := cmd.Flags().GetString("file")
 			localFile, _ := cmd.Flags().GetString("local-file")
 			profileFile, _ := cmd.Flags().GetString("profile-file")
 			showSecrets, _ := cmd.Flags().GetBool("show-secrets")
+			long, _ := cmd.Flags().GetBool("long")
 			formatStr, _ := cmd.Flags().GetString("format")
-			return runList(cmd, envFile, profileFile, localFile, showSecrets, formatStr)
+			return runList(cmd, envFile, profileFile, localFile, showSecrets, long, formatStr)
 		},
 	}
 
-	cmd.Flags().StringP("file", "f", ".env", "path to the .env file")
+	cmd.Flags().StringP("file", "f", ".env", "path to the .env file (- for stdin)")
 	cmd.Flags().String("local-file", ".env.local", "path to the .env.local override file")
 	cmd.Flags().String("profile-file", "", "path to a profile-specific .env file (e.g., .env.staging)")
 	cmd.Flags().Bool("show-secrets", false, "show ref:// values instead of masking them")
+	cmd.Flags().BoolP("long", "l", false, "include type and description annotations")
 	cmd.Flags().String("format", "plain", "output format: plain, json, shell, table")
 
 	return cmd
 }
 
 // runList loads env files, merges them, and prints all key-value pairs.
-func runList(cmd *cobra.Command, envPath, profilePath, localPath string, showSecrets bool, formatStr string) error {
+func runList(cmd *cobra.Command, envPath, profilePath, localPath string, showSecrets, long bool, formatStr string) error {
 	format, err := parseFormat(formatStr)
 	if err != nil {
 		return err
 	}
 
-	merged, err := loadAndMergeEnv(cmd, envPath, profilePath, localPath)
+	cfg := workingConfig()
+	merged, err := loadAndMergeEnv(cmd, cfg, envPath, profilePath, localPath)
 	if err != nil {
 		return err
 	}
 
+	// Plain output on a terminal shows references and overridden values
+	// in color.
+	value := func(entry parser.Entry) string { return displayValue(entry, showSecrets) }
+	if w := output.NewWriter(cmd).ForStdout(); w.ColorEnabled() && format == FormatPlain {
+		sources := keySources(cmd, cfg, envPath, profilePath, localPath)
+		value = func(entry parser.Entry) string {
+			v := displayValue(entry, showSecrets)
+			switch {
+			case entry.IsRef:
+				return w.Cyan(v)
+			case sources[entry.Key].overrides:
+				return w.Yellow(v)
+			default:
+				return v
+			}
+		}
+	}
+
 	all := merged.All()
+	if format == FormatJSON {
+		return formatListJSON(cmd.OutOrStdout(), all, keySources(cmd, cfg, envPath, profilePath, localPath), showSecrets, long)
+	}
+	if long {
+		pairs := toAnnotatedPairs(all, showSecrets)
+		for i, entry := range all {
+			pairs[i].Value = value(entry)
+		}
+		return formatAnnotatedPairs(cmd.OutOrStdout(), pairs, format)
+	}
+
+	if format == FormatTable {
+		return formatListTable(cmd.OutOrStdout(), all, keySources(cmd, cfg, envPath, profilePath, localPath), showSecrets)
+	}
+
 	pairs := make([]kvPair, len(all))
 	for i, entry := range all {
 		pairs[i] = kvPair{
 			Key:   entry.Key,
-			Value: displayValue(entry, showSecrets),
+			Value: value(entry),
 		}
 	}
 
 	return formatKVPairs(cmd.OutOrStdout(), pairs, format)
 }
 
+// Layers of the merge, as shown in the SOURCE column of list.
+const (
+	layerBase    = "base"
+	layerProfile = "profile"
+	layerLocal   = "local"
+)
+
+// keySource is where the merged value of a key comes from.
+type keySource struct {
+	// layer is the last layer that sets the key.
+	layer string
+	// overrides is true when an earlier layer sets the key too.
+	overrides bool
+	// file and line locate the definition in the layer.
+	file string
+	line int
+}
+
+// keySources returns the source of each key of the files at envPath,
+// profilePath, and localPath, loaded with cfg. The files are loaded and
+// merged already, so errors and warnings are ignored here.
+func keySources(cmd *cobra.Command, cfg *config.Config, envPath, profilePath, localPath string) map[string]keySource {
+	sources := make(map[string]keySource)
+	for _, l := range []struct{ name, path string }{
+		{layerBase, envPath},
+		{layerProfile, profilePath},
+		{layerLocal, localPath},
+	} {
+		if l.path == "" {
+			continue
+		}
+		env, _, err := loadEnvFile(cmd, l.path, false, cfg)
+		if err != nil {
+			continue
+		}
+		for _, entry := range env.All() {
+			_, seen := sources[entry.Key]
+			sources[entry.Key] = keySource{layer: l.name, overrides: seen, file: envPathName(l.path), line: entry.Line}
+		}
+	}
+	return sources
+}
+
+// formatListTable writes entries as a table with their source layer,
+// whether they are a reference and to which backend, and their value,
+// masked for references unless showSecrets is true.
+func formatListTable(w io.Writer, entries []parser.Entry, sources map[string]keySource, showSecrets bool) error {
+	rows := make([][]string, len(entries))
+	for i, entry := range entries {
+		isRef, backendName := "no", "-"
+		if entry.IsRef {
+			isRef = "yes"
+			if parsed, err := ref.Parse(entry.Value); err == nil {
+				backendName = parsed.Backend
+			}
+		}
+		rows[i] = []string{entry.Key, orDash(sources[entry.Key].layer), isRef, backendName, displayValue(entry, showSecrets)}
+	}
+	return writeTable(w, []string{"KEY", "SOURCE", "REF", "BACKEND", "VALUE"}, rows)
+}
+
+// listEntry is a merged variable with where it comes from, as written by
+// list --format json.
+type listEntry struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	IsRef       bool   `json:"is_ref"`
+	Backend     string `json:"backend,omitempty"`
+	Layer       string `json:"layer"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Overrides   bool   `json:"overrides"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// formatListJSON writes entries as a JSON array of listEntry, masking
+// references unless showSecrets is true and, with long, including the
+// type and description annotations.
+func formatListJSON(w io.Writer, entries []parser.Entry, sources map[string]keySource, showSecrets, long bool) error {
+	annotated := toAnnotatedPairs(entries, showSecrets)
+	list := make([]listEntry, len(entries))
+	for i, entry := range entries {
+		src := sources[entry.Key]
+		list[i] = listEntry{
+			Key:       entry.Key,
+			Value:     displayValue(entry, showSecrets),
+			IsRef:     entry.IsRef,
+			Layer:     src.layer,
+			File:      src.file,
+			Line:      src.line,
+			Overrides: src.overrides,
+		}
+		if entry.IsRef {
+			if parsed, err := ref.Parse(entry.Value); err == nil {
+				list[i].Backend = parsed.Backend
+			}
+		}
+		if long {
+			list[i].Type = annotated[i].Type
+			list[i].Description = annotated[i].Description
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
+
 // displayValue returns the value to display for an entry. If the entry is a
 // ref:// reference and showSecrets is false, the value is masked.
 func displayValue(entry parser.Entry, showSecrets bool) string {
@@ -71,3 +244,123 @@ func displayValue(entry parser.Entry, showSecrets bool) string {
 	}
 	return entry.Value
 }
+
+// annotatedPair is a key-value pair with its type and description
+// annotations, used by list --long.
+type annotatedPair struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// toAnnotatedPairs converts entries to annotatedPairs, masking ref:// values
+// unless showSecrets is true.
+func toAnnotatedPairs(entries []parser.Entry, showSecrets bool) []annotatedPair {
+	pairs := make([]annotatedPair, len(entries))
+	for i, entry := range entries {
+		typ, _ := entry.Annotation(parser.AnnotationType)
+		desc, _ := entry.Annotation(parser.AnnotationDescription)
+		if desc == "" {
+			desc = entryDocumentation(entry)
+		}
+		pairs[i] = annotatedPair{
+			Key:         entry.Key,
+			Value:       displayValue(entry, showSecrets),
+			Type:        typ,
+			Description: desc,
+		}
+	}
+	return pairs
+}
+
+// entryDocumentation returns the free-form documentation for an entry: its
+// inline comment, or otherwise its leading comment block collapsed to a
+// single line.
+func entryDocumentation(entry parser.Entry) string {
+	if entry.InlineComment != "" {
+		return entry.InlineComment
+	}
+	return strings.Join(strings.Fields(entry.Comment), " ")
+}
+
+// formatAnnotatedPairs writes annotated pairs in the specified format.
+func formatAnnotatedPairs(w io.Writer, pairs []annotatedPair, format OutputFormat) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(pairs)
+	case FormatShell:
+		for _, p := range pairs {
+			if p.Type != "" {
+				if _, err := fmt.Fprintf(w, "# @type: %s\n", p.Type); err != nil {
+					return err
+				}
+			}
+			if p.Description != "" {
+				if _, err := fmt.Fprintf(w, "# @description: %s\n", p.Description); err != nil {
+					return err
+				}
+			}
+			if err := writeShellExport(w, p.Key, p.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatTable:
+		return formatAnnotatedTable(w, pairs)
+	default:
+		for _, p := range pairs {
+			line := p.Key + "=" + p.Value
+			var notes []string
+			if p.Type != "" {
+				notes = append(notes, "("+p.Type+")")
+			}
+			if p.Description != "" {
+				notes = append(notes, p.Description)
+			}
+			if len(notes) > 0 {
+				line += "  # " + strings.Join(notes, " ")
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// formatAnnotatedTable outputs an aligned table with KEY, TYPE, VALUE, and
+// DESCRIPTION columns.
+func formatAnnotatedTable(w io.Writer, pairs []annotatedPair) error {
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	maxKey, maxType, maxValue := len("KEY"), len("TYPE"), len("VALUE")
+	for _, p := range pairs {
+		maxKey = max(maxKey, len(p.Key))
+		maxType = max(maxType, len(p.Type))
+		maxValue = max(maxValue, len(p.Value))
+	}
+
+	row := func(key, typ, value, desc string) error {
+		line := fmt.Sprintf("%-*s  %-*s  %-*s  %s", maxKey, key, maxType, typ, maxValue, value, desc)
+		_, err := fmt.Fprintln(w, strings.TrimRight(line, " "))
+		return err
+	}
+
+	if err := row("KEY", "TYPE", "VALUE", "DESCRIPTION"); err != nil {
+		return err
+	}
+	if err := row(strings.Repeat("-", maxKey), strings.Repeat("-", maxType), strings.Repeat("-", maxValue), strings.Repeat("-", 11)); err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		if err := row(p.Key, p.Type, p.Value, p.Description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -154,3 +157,190 @@ func TestListCmd_RejectsArguments(t *testing.T) {
 		t.Fatal("expected error for unexpected argument, got nil")
 	}
 }
+
+func TestListCmd_Long(t *testing.T) {
+	dir := t.TempDir()
+	envPath := writeTestFile(t, dir, ".env", "# @type: int\n# @description: HTTP listen port\nPORT=8080\nHOST=localhost\n")
+	localPath := filepath.Join(dir, ".env.local")
+
+	t.Run("plain", func(t *testing.T) {
+		stdout, _, err := execCmd(t, "list", "--long", "--file", envPath, "--local-file", localPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := "PORT=8080  # (int) HTTP listen port\nHOST=localhost\n"
+		if stdout != expected {
+			t.Errorf("expected %q, got %q", expected, stdout)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		stdout, _, err := execCmd(t, "list", "-l", "--format", "json", "--file", envPath, "--local-file", localPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(stdout, `"type": "int"`) || !strings.Contains(stdout, `"description": "HTTP listen port"`) {
+			t.Errorf("expected type and description in JSON, got %q", stdout)
+		}
+	})
+
+	t.Run("table", func(t *testing.T) {
+		stdout, _, err := execCmd(t, "list", "-l", "--format", "table", "--file", envPath, "--local-file", localPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		lines := strings.Split(strings.TrimRight(stdout, "\n"), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("expected 4 lines, got %d: %q", len(lines), stdout)
+		}
+		if lines[0] != "KEY   TYPE  VALUE      DESCRIPTION" {
+			t.Errorf("header: got %q", lines[0])
+		}
+		if lines[2] != "PORT  int   8080       HTTP listen port" {
+			t.Errorf("row: got %q", lines[2])
+		}
+	})
+
+	t.Run("local override keeps annotations", func(t *testing.T) {
+		local := writeTestFile(t, dir, ".env.local.override", "PORT=9090\n")
+		stdout, _, err := execCmd(t, "list", "--long", "--file", envPath, "--local-file", local)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(stdout, "PORT=9090  # (int) HTTP listen port\n") {
+			t.Errorf("unexpected output %q", stdout)
+		}
+	})
+}
+
+func TestListCmd_LongUsesComments(t *testing.T) {
+	dir := t.TempDir()
+	envPath := writeTestFile(t, dir, ".env", "# Database host\nDB_HOST=localhost\nDB_PORT=5432 # default postgres port\n")
+
+	stdout, _, err := execCmd(t, "list", "--long", "--file", envPath, "--local-file", filepath.Join(dir, ".env.local"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "DB_HOST=localhost  # Database host\nDB_PORT=5432  # default postgres port\n"
+	if stdout != expected {
+		t.Errorf("expected %q, got %q", expected, stdout)
+	}
+}
+
+func TestListCmd_Color(t *testing.T) {
+	dir := t.TempDir()
+	envPath := writeTestFile(t, dir, ".env", "PORT=8080\nHOST=localhost\nAPI_KEY=ref://secrets/api_key\n")
+	localPath := writeTestFile(t, dir, ".env.local", "PORT=9090\n")
+	t.Setenv("FORCE_COLOR", "1")
+
+	stdout, _, err := execCmd(t, "list", "--file", envPath, "--local-file", localPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "PORT=\033[33m9090\033[0m\nHOST=localhost\nAPI_KEY=\033[36mref://***\033[0m\n"
+	if stdout != expected {
+		t.Errorf("expected %q, got %q", expected, stdout)
+	}
+
+	t.Run("no color", func(t *testing.T) {
+		stdout, _, err := execCmd(t, "list", "--no-color", "--file", envPath, "--local-file", localPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Contains(stdout, "\033[") {
+			t.Errorf("expected no escape codes with --no-color, got %q", stdout)
+		}
+
+		t.Setenv("NO_COLOR", "1")
+		stdout, _, err = execCmd(t, "list", "--file", envPath, "--local-file", localPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Contains(stdout, "\033[") {
+			t.Errorf("expected no escape codes with NO_COLOR, got %q", stdout)
+		}
+	})
+
+	t.Run("json is never colored", func(t *testing.T) {
+		stdout, _, err := execCmd(t, "list", "--format", "json", "--file", envPath, "--local-file", localPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Contains(stdout, "\033[") {
+			t.Errorf("expected no escape codes in JSON, got %q", stdout)
+		}
+	})
+}
+
+func TestListCmd_NoColorWhenPiped(t *testing.T) {
+	dir := t.TempDir()
+	envPath := writeTestFile(t, dir, ".env", "API_KEY=ref://secrets/api_key\n")
+
+	stdout, _, err := execCmd(t, "list", "--file", envPath, "--local-file", filepath.Join(dir, ".env.local"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout != "API_KEY=ref://***\n" {
+		t.Errorf("expected plain output for a non-terminal, got %q", stdout)
+	}
+}
+
+func TestListCmd_Table(t *testing.T) {
+	dir := t.TempDir()
+	envPath := writeTestFile(t, dir, ".env", "PORT=8080\nAPI_KEY=ref://secrets/api_key\n")
+	profilePath := writeTestFile(t, dir, ".env.staging", "DB_HOST=staging-db\n")
+	localPath := writeTestFile(t, dir, ".env.local", "PORT=9090\n")
+
+	stdout, _, err := execCmd(t, "list", "--format", "table", "--file", envPath, "--profile-file", profilePath, "--local-file", localPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "KEY      SOURCE   REF  BACKEND  VALUE\n" +
+		"-------  -------  ---  -------  ----------\n" +
+		"PORT     local    no   -        9090\n" +
+		"API_KEY  base     yes  secrets  ref://***\n" +
+		"DB_HOST  profile  no   -        staging-db\n"
+	if stdout != expected {
+		t.Errorf("expected %q, got %q", expected, stdout)
+	}
+
+	stdout, _, err = execCmd(t, "list", "--format", "table", "--show-secrets", "--file", envPath, "--local-file", localPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stdout, "API_KEY  base    yes  secrets  ref://secrets/api_key\n") {
+		t.Errorf("expected unmasked reference, got %q", stdout)
+	}
+}
+
+func TestListCmd_JSON(t *testing.T) {
+	dir := t.TempDir()
+	envPath := writeTestFile(t, dir, ".env", "PORT=8080\nAPI_KEY=ref://secrets/api_key\n")
+	profilePath := writeTestFile(t, dir, ".env.staging", "DB_HOST=staging-db\n")
+	localPath := writeTestFile(t, dir, ".env.local", "# override\nPORT=9090\n")
+
+	stdout, _, err := execCmd(t, "list", "--format", "json", "--file", envPath, "--profile-file", profilePath, "--local-file", localPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []listEntry
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("parsing JSON: %v\n%s", err, stdout)
+	}
+	expected := []listEntry{
+		{Key: "PORT", Value: "9090", Layer: "local", File: localPath, Line: 2, Overrides: true},
+		{Key: "API_KEY", Value: "ref://***", IsRef: true, Backend: "secrets", Layer: "base", File: envPath, Line: 2},
+		{Key: "DB_HOST", Value: "staging-db", Layer: "profile", File: profilePath, Line: 1},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	stdout, _, err = execCmdWithStdin(t, "HOST=localhost\n", "list", "--format", "json", "--file", "-", "--local-file", localPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stdout, `"file": "stdin"`) {
+		t.Errorf("expected stdin as the file, got %q", stdout)
+	}
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/output"
+)
+
+// configureLogging wraps the RunE of cmd and all its subcommands so that,
+// while they run, the default slog logger writes to the command's stderr
+// at the level of the verbosity flags and in the format of --log-format
+// (see output.NewLogger). Backends and the resolve pipeline log through it.
+func configureLogging(cmd *cobra.Command) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			logger, err := output.NewLogger(cmd)
+			if err != nil {
+				return withExitCode(exitUsage, err)
+			}
+			prev := slog.Default()
+			slog.SetDefault(logger)
+			defer slog.SetDefault(prev)
+			return run(cmd, args)
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		configureLogging(sub)
+	}
+}
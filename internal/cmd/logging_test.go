@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLogging_VerboseResolve(t *testing.T) {
+	dir := setupProject(t, "logging", "API_KEY=ref://secrets/api_key\n", "")
+	writeMemoryTestConfig(t, dir, "logging")
+	chdir(t, dir)
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "logging-secret-value", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	_, stderr, err := execCmd(t, "resolve")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if strings.Contains(stderr, "reference resolved") {
+		t.Errorf("expected no log records by default, got: %q", stderr)
+	}
+
+	_, stderr, err = execCmd(t, "resolve", "-v")
+	if err != nil {
+		t.Fatalf("resolve -v: %v", err)
+	}
+	if !strings.Contains(stderr, `level=INFO msg="reference resolved" key=API_KEY ref=ref://secrets/api_key duration=`) {
+		t.Errorf("expected reference record, got: %q", stderr)
+	}
+	if strings.Contains(stderr, "backend call") {
+		t.Errorf("expected backend calls only with --debug, got: %q", stderr)
+	}
+}
+
+func TestLogging_DebugJSON(t *testing.T) {
+	dir := setupProject(t, "logging", "API_KEY=ref://secrets/api_key\n", "")
+	writeMemoryTestConfig(t, dir, "logging")
+	chdir(t, dir)
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "logging-secret-value", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	_, stderr, err := execCmd(t, "resolve", "--debug", "--log-format", "json")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	var backendCall bool
+	for _, line := range strings.Split(stderr, "\n") {
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON record %q: %v", line, err)
+		}
+		if record["msg"] == "backend call" && record["backend"] == "secrets" {
+			backendCall = true
+		}
+	}
+	if !backendCall {
+		t.Errorf("expected a backend call record, got: %q", stderr)
+	}
+	if strings.Contains(stderr, "logging-secret-value") {
+		t.Errorf("log must not contain secret values: %q", stderr)
+	}
+}
+
+func TestLogging_InvalidFormat(t *testing.T) {
+	dir := setupProject(t, "logging", "A=1\n", "")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "resolve", "--log-format", "xml")
+	if err == nil || !strings.Contains(err.Error(), `invalid log format "xml"`) {
+		t.Fatalf("expected invalid format error, got %v", err)
+	}
+	if code := exitCode(err); code != exitUsage {
+		t.Errorf("exit code = %d, want %d", code, exitUsage)
+	}
+}
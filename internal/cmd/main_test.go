@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// TestMain keeps the tests away from the user's keychain and cache
+// directory, where resolving keeps last-known-good values.
+func TestMain(m *testing.M) {
+	keyring.MockInit()
+	dir, err := os.MkdirTemp("", "envref-cache-")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("XDG_CACHE_HOME", dir)
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/jsonrpc"
+	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/resolve"
+	"github.com/xcke/envref/internal/suggest"
+)
+
+// mcpProtocolVersions are the Model Context Protocol versions the server
+// speaks, newest first.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// newMCPCmd creates the mcp subcommand.
+func newMCPCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve read-only tools to AI assistants over the Model Context Protocol",
+		Long: `Run a Model Context Protocol server on stdin and stdout, so that coding
+assistants can answer questions about the project's environment. The tools
+are read-only and never return values: only key names, ref:// references,
+and where each key is defined.
+
+Tools:
+  list_keys    the keys of the merged environment and whether each is a reference
+  explain_key  the env layers that define a key, which one wins, and its documentation
+  check_refs   whether every reference resolves, without the values
+
+Register the server with an assistant, for example in .mcp.json:
+
+  {"mcpServers": {"envref": {"command": "envref", "args": ["mcp"]}}}
+
+The server answers for the project of the working directory it is started
+in, and exits when stdin is closed.`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serveStdio(cmd, newMCPServer(cmd))
+		},
+	}
+}
+
+// mcpTool is a tool offered to the client. run returns the tool's text
+// output; an error is reported to the client as a failed tool call.
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+	Annotations map[string]any `json:"annotations"`
+
+	run func(cmd *cobra.Command, args mcpToolArgs) (string, error)
+}
+
+// mcpToolArgs are the arguments the tools accept.
+type mcpToolArgs struct {
+	Key     string `json:"key"`
+	Profile string `json:"profile"`
+}
+
+// mcpTools returns the tools of the server.
+func mcpTools() []mcpTool {
+	profile := map[string]any{
+		"type":        "string",
+		"description": "Environment profile, such as staging; the active profile by default.",
+	}
+	readOnly := map[string]any{"readOnlyHint": true}
+	return []mcpTool{
+		{
+			Name:        "list_keys",
+			Description: "List the environment variables of the project after merging its .env layers, and whether each is a ref:// secret reference. Values are never returned.",
+			InputSchema: map[string]any{"type": "object", "properties": map[string]any{"profile": profile}},
+			Annotations: readOnly,
+			run:         mcpListKeys,
+		},
+		{
+			Name:        "explain_key",
+			Description: "Explain where an environment variable comes from: the .env layers that define it, which one wins, whether it is a secret reference, and its documentation comments. Values are never returned.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"key":     map[string]any{"type": "string", "description": "The variable name, such as DATABASE_URL."},
+					"profile": profile,
+				},
+				"required": []string{"key"},
+			},
+			Annotations: readOnly,
+			run:         mcpExplainKey,
+		},
+		{
+			Name:        "check_refs",
+			Description: "Check that every ref:// secret reference of the project resolves in its backend, and report the ones that do not. Values are never returned.",
+			InputSchema: map[string]any{"type": "object", "properties": map[string]any{"profile": profile}},
+			Annotations: readOnly,
+			run:         mcpCheckRefs,
+		},
+	}
+}
+
+// newMCPServer returns an MCP server whose tools run against cmd.
+func newMCPServer(cmd *cobra.Command) *jsonrpc.Server {
+	tools := mcpTools()
+	srv := jsonrpc.NewServer()
+
+	srv.Handle("initialize", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if err := jsonrpc.DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		protocol := mcpProtocolVersions[0]
+		if slices.Contains(mcpProtocolVersions, p.ProtocolVersion) {
+			protocol = p.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": protocol,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "envref", "version": version},
+			"instructions":    "Tools to inspect the environment variables of the envref project in the working directory. They never return secret values.",
+		}, nil
+	})
+	srv.Handle("notifications/initialized", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return nil, nil
+	})
+	srv.Handle("ping", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return struct{}{}, nil
+	})
+	srv.Handle("tools/list", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return map[string]any{"tools": tools}, nil
+	})
+	srv.Handle("tools/call", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p struct {
+			Name      string      `json:"name"`
+			Arguments mcpToolArgs `json:"arguments"`
+		}
+		if err := jsonrpc.DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		i := slices.IndexFunc(tools, func(t mcpTool) bool { return t.Name == p.Name })
+		if i < 0 {
+			return nil, jsonrpc.InvalidParams("unknown tool %q", p.Name)
+		}
+		text, err := tools[i].run(cmd, p.Arguments)
+		if err != nil {
+			text = err.Error()
+		}
+		return map[string]any{
+			"content": []map[string]any{{"type": "text", "text": text}},
+			"isError": err != nil,
+		}, nil
+	})
+
+	return srv
+}
+
+// mcpListKeys lists the keys of the merged environment.
+func mcpListKeys(cmd *cobra.Command, args mcpToolArgs) (string, error) {
+	cfg, projectDir, err := loadServeConfig()
+	if err != nil {
+		return "", err
+	}
+	profile := cfg.EffectiveProfile(args.Profile)
+	env, err := loadProjectEnv(cmd, cfg, projectDir, profile)
+	if err != nil {
+		return "", err
+	}
+	if env.Len() == 0 {
+		return "The environment has no keys.", nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d key(s) in project %q%s:\n", env.Len(), cfg.Project, profileLabel(profile))
+	for _, e := range env.All() {
+		fmt.Fprintf(&b, "%s: %s\n", e.Key, describeEntry(e))
+	}
+	return b.String(), nil
+}
+
+// mcpExplainKey describes where a key is defined.
+func mcpExplainKey(cmd *cobra.Command, args mcpToolArgs) (string, error) {
+	if args.Key == "" {
+		return "", fmt.Errorf("key is required")
+	}
+	cfg, projectDir, err := loadServeConfig()
+	if err != nil {
+		return "", err
+	}
+	profile := cfg.EffectiveProfile(args.Profile)
+
+	var b strings.Builder
+	var winner *parser.Entry
+	var names []string
+	layers := cfg.EnvLayers(profile)
+	for i, path := range projectEnvPaths(cfg, projectDir, profile) {
+		entries, err := parseEnvLayer(path)
+		if err != nil {
+			return "", err
+		}
+		for j, e := range entries {
+			names = append(names, e.Key)
+			if e.Key != args.Key {
+				continue
+			}
+			if winner == nil {
+				fmt.Fprintf(&b, "%s is defined in (later layers override earlier ones):\n", args.Key)
+			}
+			fmt.Fprintf(&b, "  %s:%d: %s\n", layers[i], e.Line, describeEntry(e))
+			winner = &entries[j]
+		}
+	}
+	if winner == nil {
+		layerList := strings.Join(layers, ", ")
+		return "", fmt.Errorf("%s is not defined in %s%s", args.Key, layerList, suggest.FormatSuggestion(suggest.Keys(args.Key, names)))
+	}
+
+	fmt.Fprintf(&b, "The value used%s is %s.\n", profileLabel(profile), describeEntry(*winner))
+	if winner.Comment != "" {
+		fmt.Fprintf(&b, "Documentation:\n  %s\n", strings.ReplaceAll(winner.Comment, "\n", "\n  "))
+	}
+	for _, a := range winner.Annotations {
+		fmt.Fprintf(&b, "@%s: %s\n", a.Name, a.Value)
+	}
+	if pattern, ok := cfg.RequiresRef(args.Key); ok {
+		fmt.Fprintf(&b, "It matches require_refs pattern %q, so committed files must hold a ref:// reference.\n", pattern)
+	}
+	return b.String(), nil
+}
+
+// mcpCheckRefs reports which references resolve.
+func mcpCheckRefs(cmd *cobra.Command, args mcpToolArgs) (string, error) {
+	cfg, projectDir, err := loadServeConfig()
+	if err != nil {
+		return "", err
+	}
+	profile := cfg.EffectiveProfile(args.Profile)
+	env, err := loadProjectEnv(cmd, cfg, projectDir, profile)
+	if err != nil {
+		return "", err
+	}
+	if !env.HasAnyRefs() {
+		return "The environment has no references.", nil
+	}
+	if len(cfg.Backends) == 0 {
+		return "", fmt.Errorf("the environment has references but no backends are configured")
+	}
+
+	registry, err := buildResolveRegistry(cfg)
+	if err != nil {
+		return "", fmt.Errorf("initializing backends: %w", err)
+	}
+	defer registry.CloseAll()
+	result, err := resolve.ResolveWithProfiles(env, registry, cfg.Project, cfg.ProfileChain(profile))
+	if err != nil {
+		return "", fmt.Errorf("resolving references: %w", err)
+	}
+	auditRefReads(cfg, projectDir, profile, env, result)
+
+	failed := make(map[string]resolve.KeyErr, len(result.Errors))
+	for _, keyErr := range result.Errors {
+		failed[keyErr.Key] = keyErr
+	}
+	var lines []string
+	for _, e := range env.All() {
+		if !e.IsRef {
+			continue
+		}
+		if keyErr, ok := failed[e.Key]; ok {
+			lines = append(lines, fmt.Sprintf("FAIL %s: %v (%s)\n", e.Key, keyErr.Err, e.Value))
+		} else {
+			lines = append(lines, fmt.Sprintf("ok   %s (%s)\n", e.Key, e.Value))
+		}
+	}
+	return fmt.Sprintf("%d reference(s) resolved, %d failed%s.\n%s",
+		len(lines)-len(failed), len(failed), profileLabel(profile), strings.Join(lines, "")), nil
+}
+
+// describeEntry describes an entry without its value.
+func describeEntry(e parser.Entry) string {
+	switch {
+	case e.IsRef:
+		return "secret reference " + e.Value
+	case e.Value == "":
+		return "empty"
+	case strings.Contains(e.Raw, "${"):
+		return "plain value built from other variables"
+	default:
+		return "plain value"
+	}
+}
+
+// profileLabel returns " (profile NAME)", or "" without a profile.
+func profileLabel(profile string) string {
+	if profile == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (profile %s)", profile)
+}
+
+// parseEnvLayer parses the env file at path. A missing file has no entries.
+func parseEnvLayer(path string) ([]parser.Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", filepath.Base(path), err)
+	}
+	defer func() { _ = f.Close() }()
+	entries, _, err := parser.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Base(path), err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// mcpToolText returns the text and error flag of a tools/call result.
+func mcpToolText(t *testing.T, result json.RawMessage) (string, bool) {
+	t.Helper()
+	var r struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(result, &r); err != nil || len(r.Content) != 1 {
+		t.Fatalf("unexpected tool result %s: %v", result, err)
+	}
+	return r.Content[0].Text, r.IsError
+}
+
+func TestMCPCmd_Tools(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "PORT=3000\n# Key for the payments API.\nAPI_KEY=ref://secrets/API_KEY\nMISSING=ref://secrets/MISSING\n")
+	writeTestFile(t, dir, ".env.local", "PORT=4000\n")
+	chdir(t, dir)
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "sk-live-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	requests := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"list_keys","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"explain_key","arguments":{"key":"PORT"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"explain_key","arguments":{"key":"API_KEY"}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"check_refs","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"explain_key","arguments":{"key":"API_KY"}}}`,
+	}
+	stdout, stderr, err := execCmdWithStdin(t, strings.Join(requests, "\n")+"\n", "mcp")
+	if err != nil {
+		t.Fatalf("mcp: %v\n%s", err, stderr)
+	}
+	for _, secret := range []string{"sk-live-123", "3000", "4000"} {
+		if strings.Contains(stdout, secret) {
+			t.Errorf("output contains the value %q:\n%s", secret, stdout)
+		}
+	}
+
+	var resps []rpcResponse
+	for _, line := range strings.Split(strings.TrimSuffix(stdout, "\n"), "\n") {
+		var resp rpcResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("stdout line %q is not a response: %v", line, err)
+		}
+		if resp.Error != nil {
+			t.Fatalf("response %d: %s", resp.ID, resp.Error.Message)
+		}
+		resps = append(resps, resp)
+	}
+	if len(resps) != 7 {
+		t.Fatalf("got %d responses, want 7:\n%s", len(resps), stdout)
+	}
+
+	if !strings.Contains(string(resps[0].Result), `"protocolVersion":"2025-03-26"`) {
+		t.Errorf("initialize: %s", resps[0].Result)
+	}
+	for _, name := range []string{"list_keys", "explain_key", "check_refs"} {
+		if !strings.Contains(string(resps[1].Result), `"name":"`+name+`"`) {
+			t.Errorf("tools/list misses %s: %s", name, resps[1].Result)
+		}
+	}
+
+	text, _ := mcpToolText(t, resps[2].Result)
+	if !strings.Contains(text, "3 key(s)") || !strings.Contains(text, "API_KEY: secret reference ref://secrets/API_KEY") ||
+		!strings.Contains(text, "PORT: plain value") {
+		t.Errorf("list_keys:\n%s", text)
+	}
+
+	text, _ = mcpToolText(t, resps[3].Result)
+	if !strings.Contains(text, ".env:1: plain value") || !strings.Contains(text, ".env.local:1: plain value") {
+		t.Errorf("explain_key PORT:\n%s", text)
+	}
+
+	text, _ = mcpToolText(t, resps[4].Result)
+	if !strings.Contains(text, "Key for the payments API.") {
+		t.Errorf("explain_key API_KEY:\n%s", text)
+	}
+
+	text, isError := mcpToolText(t, resps[5].Result)
+	if isError || !strings.Contains(text, "1 reference(s) resolved, 1 failed") ||
+		!strings.Contains(text, "ok   API_KEY") || !strings.Contains(text, "FAIL MISSING") {
+		t.Errorf("check_refs:\n%s", text)
+	}
+
+	text, isError = mcpToolText(t, resps[6].Result)
+	if !isError || !strings.Contains(text, "API_KY is not defined") || !strings.Contains(text, "API_KEY") {
+		t.Errorf("explain_key of a missing key:\n%s", text)
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/parser"
+)
+
+// Merge strategies for keys set to different values in both files.
+const (
+	mergeOurs        = "ours"
+	mergeTheirs      = "theirs"
+	mergeInteractive = "interactive"
+)
+
+// newMergeCmd creates the merge subcommand.
+func newMergeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge <BASE> <INCOMING>",
+		Short: "Merge two .env files key by key",
+		Long: `Merge the keys of INCOMING into BASE, keeping the layout and comments of
+BASE. Keys only in INCOMING are added with their comments, next to the key
+that precedes them in INCOMING. Keys only in BASE are kept.
+
+A key set to different values in both files is a conflict. Choose how
+conflicts are resolved with --strategy:
+  ours         keep the value of BASE
+  theirs       take the value of INCOMING
+  interactive  ask for each conflicting key
+
+Without --strategy, the merge fails if there are conflicts and lists them.
+
+The merged file replaces BASE unless --output is given; use "--output -"
+to print it instead.
+
+Examples:
+  envref merge .env .env.alice                      # fail on conflicts
+  envref merge .env .env.alice --strategy theirs    # incoming values win
+  envref merge .env .env.alice --strategy interactive
+  envref merge .env .env.alice -s ours -o -         # print the result`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			strategy, _ := cmd.Flags().GetString("strategy")
+			outPath, _ := cmd.Flags().GetString("output")
+			return runMerge(cmd, args[0], args[1], strategy, outPath)
+		},
+	}
+
+	cmd.Flags().StringP("strategy", "s", "", "resolve conflicts with: ours, theirs, interactive")
+	cmd.Flags().StringP("output", "o", "", `file to write the merged result to (default: BASE, "-" for stdout)`)
+
+	return cmd
+}
+
+// runMerge implements the merge command logic.
+func runMerge(cmd *cobra.Command, basePath, incomingPath, strategy, outPath string) error {
+	var resolve envfile.Resolver
+	switch strategy {
+	case "":
+	case mergeOurs:
+		resolve = func(envfile.Conflict) (envfile.Side, error) { return envfile.KeepOurs, nil }
+	case mergeTheirs:
+		resolve = func(envfile.Conflict) (envfile.Side, error) { return envfile.KeepTheirs, nil }
+	case mergeInteractive:
+		resolve = promptConflict(cmd, basePath, incomingPath)
+	default:
+		return withExitCode(exitUsage, fmt.Errorf("invalid strategy %q: must be one of %s, %s, %s", strategy, mergeOurs, mergeTheirs, mergeInteractive))
+	}
+
+	base, err := os.ReadFile(basePath)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", basePath, err)
+	}
+	incoming, err := os.ReadFile(incomingPath)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", incomingPath, err)
+	}
+
+	result, err := envfile.MergeFiles(base, incoming, resolve)
+	var conflictErr *envfile.ConflictError
+	if errors.As(err, &conflictErr) {
+		for _, c := range conflictErr.Conflicts {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "conflict: %s\n", c.Key)
+		}
+		return fmt.Errorf("%w (choose a --strategy: %s, %s, or %s)", err, mergeOurs, mergeTheirs, mergeInteractive)
+	}
+	if err != nil {
+		return fmt.Errorf("merging %s into %s: %w", incomingPath, basePath, err)
+	}
+
+	if outPath == "-" {
+		_, err := cmd.OutOrStdout().Write(result.Data)
+		return err
+	}
+	if outPath == "" {
+		outPath = basePath
+	}
+	if err := os.WriteFile(outPath, result.Data, 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", outPath, err)
+	}
+
+	w := output.NewWriter(cmd)
+	w.Info("merged %s into %s: %d added, %d updated\n", incomingPath, outPath, len(result.Added), len(result.Updated))
+	printKeyChanges(w, result.Added, result.Updated, nil)
+	return nil
+}
+
+// promptConflict returns a Resolver that asks on the terminal which value
+// of each conflicting key to keep.
+func promptConflict(cmd *cobra.Command, basePath, incomingPath string) envfile.Resolver {
+	scanner := bufio.NewScanner(cmd.InOrStdin())
+	errOut := cmd.ErrOrStderr()
+	return func(c envfile.Conflict) (envfile.Side, error) {
+		_, _ = fmt.Fprintf(errOut, "%s\n  ours:   %s (%s)\n  theirs: %s (%s)\n", c.Key, conflictValue(c.Ours), basePath, conflictValue(c.Theirs), incomingPath)
+		for {
+			_, _ = fmt.Fprint(errOut, "Keep [o]urs or [t]heirs? ")
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return 0, fmt.Errorf("reading answer: %w", err)
+				}
+				return 0, fmt.Errorf("merge cancelled: no answer for %s", c.Key)
+			}
+			switch strings.TrimSpace(strings.ToLower(scanner.Text())) {
+			case "o", "ours":
+				return envfile.KeepOurs, nil
+			case "t", "theirs":
+				return envfile.KeepTheirs, nil
+			}
+		}
+	}
+}
+
+// conflictValue returns the value of one side of a conflict for display.
+func conflictValue(e *parser.Entry) string {
+	if e == nil {
+		return "(deleted)"
+	}
+	return e.Value
+}
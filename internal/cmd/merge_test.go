@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMergeFiles(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".env", "# Server\nHOST=localhost\nPORT=8080\n")
+	writeTestFile(t, dir, ".env.alice", "HOST=alice.local\n# Alice's flag\nFEATURE_X=on\nPORT=8080\n")
+	chdir(t, dir)
+	return dir
+}
+
+func TestMergeCmd_Conflicts(t *testing.T) {
+	dir := setupMergeFiles(t)
+
+	_, stderr, err := execCmd(t, "merge", ".env", ".env.alice")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 conflicting key(s): HOST")
+	assert.Contains(t, stderr, "conflict: HOST")
+
+	data, err := os.ReadFile(filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Server\nHOST=localhost\nPORT=8080\n", string(data), "a failed merge must not write BASE")
+}
+
+func TestMergeCmd_Strategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		stdin    string
+		want     string
+	}{
+		{"ours", "", "# Server\nHOST=localhost\n# Alice's flag\nFEATURE_X=on\nPORT=8080\n"},
+		{"theirs", "", "# Server\nHOST=alice.local\n# Alice's flag\nFEATURE_X=on\nPORT=8080\n"},
+		{"interactive", "x\nt\n", "# Server\nHOST=alice.local\n# Alice's flag\nFEATURE_X=on\nPORT=8080\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			dir := setupMergeFiles(t)
+
+			stdout, stderr, err := execCmdWithStdin(t, tt.stdin, "merge", ".env", ".env.alice", "--strategy", tt.strategy)
+			require.NoError(t, err)
+			assert.Contains(t, stdout, "merged .env.alice into .env")
+			assert.Contains(t, stdout, "+ FEATURE_X")
+			if tt.strategy == "interactive" {
+				assert.Contains(t, stderr, "ours:   localhost (.env)")
+				assert.Contains(t, stderr, "theirs: alice.local (.env.alice)")
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, ".env"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+		})
+	}
+}
+
+func TestMergeCmd_InteractiveNoAnswer(t *testing.T) {
+	setupMergeFiles(t)
+
+	_, _, err := execCmdWithStdin(t, "", "merge", ".env", ".env.alice", "--strategy", "interactive")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no answer for HOST")
+}
+
+func TestMergeCmd_Output(t *testing.T) {
+	dir := setupMergeFiles(t)
+
+	stdout, _, err := execCmd(t, "merge", ".env", ".env.alice", "-s", "theirs", "-o", "-")
+	require.NoError(t, err)
+	assert.Equal(t, "# Server\nHOST=alice.local\n# Alice's flag\nFEATURE_X=on\nPORT=8080\n", stdout)
+
+	_, _, err = execCmd(t, "merge", ".env", ".env.alice", "-s", "ours", "-o", ".env.merged")
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, ".env.merged"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "HOST=localhost\n")
+
+	data, err = os.ReadFile(filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Server\nHOST=localhost\nPORT=8080\n", string(data))
+}
+
+func TestMergeCmd_InvalidStrategy(t *testing.T) {
+	setupMergeFiles(t)
+
+	_, _, err := execCmd(t, "merge", ".env", ".env.alice", "--strategy", "union")
+	require.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/offline"
+	"github.com/xcke/envref/internal/output"
+)
+
+// offlineKeyItem returns the name of the keychain item holding the key
+// that encrypts a project's last-known-good values.
+func offlineKeyItem(project string) string {
+	return "offline-key:" + project
+}
+
+// lastKnownGood records the values read during a resolve and serves them
+// again when a backend cannot be reached (see backend.LastKnownGood).
+type lastKnownGood struct {
+	store   *offline.Store
+	offline bool
+
+	mu        sync.Mutex
+	fallbacks []string
+	oldest    time.Time
+}
+
+// openLastKnownGood opens the last-known-good values of the project of
+// cfg, in offline mode if the --offline flag of cmd is set. Keeping values
+// is best-effort: when it is disabled by config or the keychain cannot be
+// used, nil is returned and resolution goes to the backends as usual. In
+// offline mode these are errors, as there would be nothing to resolve from.
+func openLastKnownGood(cmd *cobra.Command, cfg *config.Config) (*lastKnownGood, error) {
+	w := output.NewWriter(cmd)
+	offlineMode, _ := cmd.Flags().GetBool("offline")
+
+	if cfg == nil || cfg.Offline.Disabled {
+		if offlineMode {
+			return nil, withExitCode(exitConfig, fmt.Errorf("--offline needs last-known-good values, which offline.disabled turns off in %s", config.FullFileName))
+		}
+		return nil, nil
+	}
+
+	store, err := openOfflineStore(cfg.Project, !offlineMode)
+	if err != nil {
+		if offlineMode {
+			return nil, fmt.Errorf("opening last-known-good values: %w", err)
+		}
+		w.Verbose("not keeping last-known-good values: %v\n", err)
+		return nil, nil
+	}
+	if offlineMode {
+		w.Verbose("offline: resolving from %d last-known-good value(s)\n", store.Len())
+	}
+	return &lastKnownGood{store: store, offline: offlineMode}, nil
+}
+
+// openOfflineStore opens the store of project with its key from the OS
+// keychain. If create is set, a missing key is generated and stored. A
+// store that can no longer be decrypted is replaced by an empty one.
+func openOfflineStore(project string, create bool) (*offline.Store, error) {
+	path, err := offline.DefaultPath(project)
+	if err != nil {
+		return nil, err
+	}
+
+	item := offlineKeyItem(project)
+	encoded, err := backend.KeychainItem(item)
+	if errors.Is(err, backend.ErrNotFound) {
+		if !create {
+			return nil, fmt.Errorf("no values kept yet for project %q; resolve once while online", project)
+		}
+		key := make([]byte, offline.KeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("generating offline key: %w", err)
+		}
+		if err := backend.SetKeychainItem(item, hex.EncodeToString(key)); err != nil {
+			return nil, fmt.Errorf("storing offline key: %w", err)
+		}
+		return offline.New(path, key), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("offline key %s in keychain is corrupt: %w", item, err)
+	}
+
+	store, err := offline.Open(path, key)
+	if errors.Is(err, offline.ErrCorrupt) && create {
+		return offline.New(path, key), nil
+	}
+	return store, err
+}
+
+// middleware returns the backend middleware that records and serves the
+// values.
+func (l *lastKnownGood) middleware() backend.Middleware {
+	return backend.LastKnownGood(l.store, l.offline, l.fallback)
+}
+
+// fallback is called for each value served because its backend failed.
+func (l *lastKnownGood) fallback(name, key string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fallbacks = append(l.fallbacks, name+":"+key)
+	if l.oldest.IsZero() || at.Before(l.oldest) {
+		l.oldest = at
+	}
+}
+
+// finish saves the values read and warns on cmd about values served
+// because their backend could not be reached.
+func (l *lastKnownGood) finish(cmd *cobra.Command) {
+	w := output.NewWriter(cmd)
+	if err := l.store.Save(); err != nil {
+		w.Verbose("saving last-known-good values: %v\n", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.fallbacks) > 0 {
+		w.Warn("%d secret(s) could not be fetched; using last-known-good values (oldest read %s)\n",
+			len(l.fallbacks), l.oldest.Local().Format("2006-01-02 15:04"))
+		for _, f := range l.fallbacks {
+			w.Verbose("  last-known-good: %s\n", f)
+		}
+	}
+}
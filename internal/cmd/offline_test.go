@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/config"
+)
+
+func TestResolveCmd_Offline(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "offline-app")
+	writeTestFile(t, dir, ".env", "DB_PASS=ref://secrets/db_pass\n")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "resolve", "--offline"); err == nil {
+		t.Fatal("expected --offline to fail before any value was kept")
+	}
+
+	if _, _, err := execCmd(t, "secret", "set", "db_pass", "--value", "old", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	if _, stderr, err := execCmd(t, "resolve"); err != nil {
+		t.Fatalf("resolve: %v\n%s", err, stderr)
+	}
+
+	// Offline, the value read last is served even though it changed since.
+	if _, _, err := execCmd(t, "secret", "set", "db_pass", "--value", "new", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	stdout, stderr, err := execCmd(t, "resolve", "--offline")
+	if err != nil {
+		t.Fatalf("resolve --offline: %v\n%s", err, stderr)
+	}
+	if want := "DB_PASS=old\n"; stdout != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+
+	// A key never read online cannot be resolved offline.
+	writeTestFile(t, dir, ".env", "DB_PASS=ref://secrets/db_pass\nAPI_KEY=ref://secrets/api_key\n")
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "k", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	_, stderr, err = execCmd(t, "resolve", "--offline", "--strict")
+	if err == nil {
+		t.Fatal("expected an error for a secret with no last-known-good value")
+	}
+	if !strings.Contains(stderr+err.Error(), "API_KEY") {
+		t.Errorf("expected API_KEY to be reported, got %q / %v", stderr, err)
+	}
+}
+
+func TestResolveCmd_OfflineDisabled(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "offline-disabled")
+	data, err := os.ReadFile(filepath.Join(dir, config.FullFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, config.FullFileName, string(data)+"offline:\n  disabled: true\n")
+	writeTestFile(t, dir, ".env", "DB_PASS=ref://secrets/db_pass\n")
+	chdir(t, dir)
+
+	_, _, err = execCmd(t, "resolve", "--offline")
+	if err == nil || !strings.Contains(err.Error(), "offline.disabled") {
+		t.Errorf("expected an offline.disabled error, got %v", err)
+	}
+}
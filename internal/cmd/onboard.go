@@ -89,7 +89,7 @@ func runOnboard(cmd *cobra.Command, profileOverride, backendName string, dryRun
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("no backends configured in %s — add a backend to .envref.yaml first", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s — add a backend to .envref.yaml first", config.FullFileName))
 	}
 
 	// Determine target backend for storing secrets.
@@ -106,28 +106,18 @@ func runOnboard(cmd *cobra.Command, profileOverride, backendName string, dryRun
 
 	targetBackend := registry.Backend(backendName)
 	if targetBackend == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return registry.Unregistered(backendName)
 	}
 
 	// Determine active profile.
 	profile := cfg.EffectiveProfile(profileOverride)
 
-	// Resolve file paths.
-	envPath := resolveFilePath(projectDir, cfg.EnvFile)
-	localPath := resolveFilePath(projectDir, cfg.LocalFile)
-
-	var profilePath string
-	if profile != "" {
-		profileEnvFile := cfg.ProfileEnvFile(profile)
-		profilePath = resolveFilePath(projectDir, profileEnvFile)
-	}
-
 	// Load and merge environment.
-	if !fileExists(envPath) {
+	if !fileExists(resolveFilePath(projectDir, cfg.EnvFile)) {
 		return fmt.Errorf("no %s file found — run \"envref init\" to create one", cfg.EnvFile)
 	}
 
-	env, err := loadAndMergeEnv(cmd, envPath, profilePath, localPath)
+	env, err := loadProjectEnv(cmd, cfg, projectDir, profile)
 	if err != nil {
 		return fmt.Errorf("loading environment: %w", err)
 	}
@@ -140,7 +130,7 @@ func runOnboard(cmd *cobra.Command, profileOverride, backendName string, dryRun
 	_, _ = fmt.Fprintf(out, "%s %s\n", w.Bold("Backend:"), backendName)
 
 	// Collect missing secrets: refs that fail to resolve.
-	missing, err := findMissingSecrets(env, registry, cfg.Project, profile)
+	missing, err := findMissingSecrets(env, registry, cfg.Project, cfg.ProfileChain(profile))
 	if err != nil {
 		return fmt.Errorf("checking secrets: %w", err)
 	}
@@ -187,12 +177,7 @@ func runOnboard(cmd *cobra.Command, profileOverride, backendName string, dryRun
 	_, _ = fmt.Fprintf(out, "\nLet's set up each missing secret. Press Ctrl+C to abort.\n\n")
 
 	// Build namespaced backend for storing.
-	var nsBackend *backend.NamespacedBackend
-	if profile != "" {
-		nsBackend, err = backend.NewProfileNamespacedBackend(targetBackend, cfg.Project, profile)
-	} else {
-		nsBackend, err = backend.NewNamespacedBackend(targetBackend, cfg.Project)
-	}
+	nsBackend, err := registry.Namespaced(backendName, cfg.Project, profile)
 	if err != nil {
 		return fmt.Errorf("creating namespaced backend: %w", err)
 	}
@@ -224,7 +209,7 @@ func runOnboard(cmd *cobra.Command, profileOverride, backendName string, dryRun
 		}
 
 		// Log the operation to the audit log (best-effort).
-		_ = newAuditLogger(projectDir).Log(audit.Entry{
+		_ = newAuditLogger(cfg, projectDir).Log(audit.Entry{
 			Operation: audit.OpSet,
 			Key:       m.Path,
 			Backend:   backendName,
@@ -255,13 +240,13 @@ func runOnboard(cmd *cobra.Command, profileOverride, backendName string, dryRun
 }
 
 // findMissingSecrets identifies ref:// entries that fail to resolve.
-func findMissingSecrets(env *envfile.Env, registry *backend.Registry, project, profile string) ([]missingSecret, error) {
+func findMissingSecrets(env *envfile.Env, registry *backend.Registry, project string, profiles []string) ([]missingSecret, error) {
 	if !env.HasRefs() {
 		return nil, nil
 	}
 
 	// Attempt resolution.
-	result, err := resolve.ResolveWithProfile(env, registry, project, profile)
+	result, err := resolve.ResolveWithProfiles(env, registry, project, profiles)
 	if err != nil {
 		return nil, err
 	}
@@ -285,6 +270,10 @@ func findMissingSecrets(env *envfile.Env, registry *backend.Registry, project, p
 		}
 
 		parsed, parseErr := ref.Parse(keyErr.Ref)
+		if parseErr == nil && parsed.Backend == ref.SelfBackend {
+			// Refs to other variables are not secrets to set.
+			continue
+		}
 		if parseErr == nil {
 			m.Backend = parsed.Backend
 			m.Path = parsed.Path
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/tui"
+)
+
+// canPickKey reports whether a missing key argument can be picked
+// interactively: stdin and stderr are terminals.
+func canPickKey(cmd *cobra.Command) bool {
+	in, ok := cmd.InOrStdin().(*os.File)
+	if !ok || !tui.IsTerminal(in) {
+		return false
+	}
+	errOut, ok := cmd.ErrOrStderr().(*os.File)
+	return ok && tui.IsTerminal(errOut)
+}
+
+// keyArg requires exactly one key argument, or none when it can be picked
+// interactively (see pickKey).
+func keyArg(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && canPickKey(cmd) {
+		return nil
+	}
+	return cobra.ExactArgs(1)(cmd, args)
+}
+
+// pickKey lets the user choose one of keys with a fuzzy-searchable picker.
+// The picker draws on stderr so that stdout stays free for the value.
+func pickKey(cmd *cobra.Command, keys []string) (string, error) {
+	if len(keys) == 0 {
+		return "", fmt.Errorf("no keys to pick from")
+	}
+	t, err := tui.Open(cmd.InOrStdin().(*os.File), cmd.ErrOrStderr())
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = t.Close() }()
+
+	key, err := tui.Pick(t, "key", keys)
+	if errors.Is(err, tui.ErrCancelled) {
+		return "", withExitCode(exitUsage, fmt.Errorf("no key picked"))
+	}
+	return key, err
+}
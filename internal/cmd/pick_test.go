@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xcke/envref/internal/config"
+)
+
+func TestKeyArg_NotATerminal(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "myapp")
+	writeTestFile(t, dir, ".env", "PORT=8080\n")
+	chdir(t, dir)
+
+	// Without a terminal there is nothing to pick with, so the key is
+	// required as before.
+	for _, args := range [][]string{{"get"}, {"secret", "get"}} {
+		_, _, err := execCmd(t, args...)
+		require.Error(t, err, args)
+		assert.Contains(t, err.Error(), "accepts 1 arg(s), received 0")
+		assert.Equal(t, exitUsage, exitCode(err))
+	}
+
+	_, _, err := execCmd(t, "secret", "get", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "key must not be empty")
+}
+
+func TestSecretGetKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "myapp")
+	chdir(t, dir)
+
+	for _, args := range [][]string{
+		{"secret", "set", "api_key", "--value", "a"},
+		{"secret", "set", "db_pass", "--value", "b", "--profile", "staging"},
+		{"secret", "set", "api_key", "--value", "c", "--profile", "staging"},
+		{"secret", "rotate", "api_key"},
+	} {
+		_, _, err := execCmd(t, args...)
+		require.NoError(t, err, args)
+	}
+
+	cfg, _, err := config.Load(dir)
+	require.NoError(t, err)
+	registry, err := buildRegistry(cfg)
+	require.NoError(t, err)
+	defer registry.CloseAll()
+
+	keys, err := secretGetKeys(registry, "secrets", "myapp", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api_key", "staging/api_key", "staging/db_pass"}, keys, "history entries are left out")
+
+	keys, err = secretGetKeys(registry, "secrets", "myapp", []string{"staging"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api_key", "db_pass"}, keys)
+}
@@ -0,0 +1,436 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/audit"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/platform"
+)
+
+// newPushCmd creates the push subcommand.
+func newPushCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "push <platform>",
+		Short: "Write the resolved environment to a hosting platform's config vars",
+		Long: `Resolve the environment, as 'envref run' does, and write it to the config
+vars of an app on a hosting platform. Every reference must resolve. With
+--raw, the secrets stored in a backend for the project are written instead,
+under their own names.
+
+The changes are listed, by key name only, and applied after confirmation.
+Vars of the app that are not in the environment are kept unless --prune is
+given.
+
+Platforms:
+  heroku   Heroku Platform API; token from HEROKU_API_KEY or 'heroku login'
+  fly      flyctl; secret values cannot be read back, so every key is written
+  render   Render API; key from RENDER_API_KEY; --app is the service ID
+  vercel   Vercel API; token from VERCEL_TOKEN, team from VERCEL_ORG_ID
+  netlify  Netlify API; token from NETLIFY_AUTH_TOKEN; --app is the site ID
+  dotenv-vault
+           a .env.vault file; --app is its path (default .env.vault)
+
+The app defaults to HEROKU_APP, FLY_APP, RENDER_SERVICE_ID,
+VERCEL_PROJECT_ID, or NETLIFY_SITE_ID.
+
+dotenv-vault encrypts each environment of a .env.vault file with its own
+DOTENV_KEY, for teams that share config with the dotenv-vault ecosystem.
+The key is read from DOTENV_KEY or from the .env.keys file next to the
+vault. Pushing to an environment that has none generates one and adds it
+to .env.keys, which must not be committed.
+
+Vercel, Netlify, and dotenv-vault keep vars per environment. The environment is
+--environment, or follows the profile: production for the production and
+prod profiles, development for development, dev, and local, and preview
+for any other profile. On Netlify these are the production, deploy-preview,
+and dev deploy contexts; --environment also accepts branch-deploy. In a
+.env.vault, preview is the staging environment; --environment also accepts
+ci.
+
+Examples:
+  envref push heroku --app shop-api --dry-run    # show what would change
+  envref push fly --app shop-api -P production
+  envref push render --app srv-abc123 --prune --yes
+  envref push heroku --raw --backend vault       # the stored secrets only
+  envref push vercel --app shop-web -P staging   # the preview environment
+  envref push dotenv-vault -P production         # write .env.vault`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: platform.Names(),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			plat, err := openPlatform(cmd, args[0], profile)
+			if err != nil {
+				return err
+			}
+			raw, _ := cmd.Flags().GetBool("raw")
+			backendName, _ := cmd.Flags().GetString("backend")
+			prune, _ := cmd.Flags().GetBool("prune")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			yes, _ := cmd.Flags().GetBool("yes")
+			if backendName != "" && !raw {
+				return fmt.Errorf("--backend requires --raw")
+			}
+			return runPush(cmd, plat, profile, raw, backendName, prune, dryRun, yes)
+		},
+	}
+
+	cmd.Flags().String("app", "", "app (or Render service ID, or .env.vault path) to write to")
+	cmd.Flags().String("environment", "", "Vercel, Netlify, or dotenv-vault environment to write to (default: from the profile)")
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
+	cmd.Flags().Bool("raw", false, "write the secrets stored in a backend instead of the resolved environment")
+	cmd.Flags().StringP("backend", "b", "", "backend to read secrets from with --raw (default: first configured)")
+	cmd.Flags().Bool("prune", false, "remove vars that are not in the environment")
+	cmd.Flags().Bool("dry-run", false, "show the changes without writing them")
+	cmd.Flags().BoolP("yes", "y", false, "apply the changes without asking")
+
+	return cmd
+}
+
+// newPullCmd creates the pull subcommand.
+func newPullCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pull <platform>",
+		Short: "Import a hosting platform's config vars into a backend",
+		Long: `Read the config vars of an app on a hosting platform and store them as
+secrets of the project in a backend, so that config that was edited in a
+dashboard can be brought under envref.
+
+The changes are listed, by key name only, and applied after confirmation.
+Secrets that already exist with a different value are kept unless --force
+is given. Fly.io does not reveal secret values, so it cannot be pulled
+from. Pulling from dotenv-vault imports an existing .env.vault, decrypted
+with DOTENV_KEY or the .env.keys file.
+
+See 'envref push --help' for the platforms and their credentials.
+
+Examples:
+  envref pull heroku --app shop-api --dry-run
+  envref pull render --app srv-abc123 --backend vault -P production
+  envref pull heroku --app shop-api --force --yes
+  envref pull vercel --app shop-web --environment production
+  DOTENV_KEY='dotenv://:key_...' envref pull dotenv-vault --environment production`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: platform.Names(),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			plat, err := openPlatform(cmd, args[0], profile)
+			if err != nil {
+				return err
+			}
+			backendName, _ := cmd.Flags().GetString("backend")
+			force, _ := cmd.Flags().GetBool("force")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			yes, _ := cmd.Flags().GetBool("yes")
+			return runPull(cmd, plat, profile, backendName, force, dryRun, yes)
+		},
+	}
+
+	cmd.Flags().String("app", "", "app (or Render service ID, or .env.vault path) to read from")
+	cmd.Flags().String("environment", "", "Vercel, Netlify, or dotenv-vault environment to read from (default: from the profile)")
+	cmd.Flags().StringP("profile", "P", "", "profile scope for secrets (e.g., staging, production)")
+	cmd.Flags().StringP("backend", "b", "", "backend to import secrets into (default: first configured)")
+	cmd.Flags().Bool("force", false, "overwrite secrets that have a different value")
+	cmd.Flags().Bool("dry-run", false, "show the changes without writing them")
+	cmd.Flags().BoolP("yes", "y", false, "apply the changes without asking")
+
+	return cmd
+}
+
+// openPlatform returns the platform called name for the --app and
+// --environment flags. Without --environment, a platform that keeps vars
+// per environment uses the one of the profile.
+func openPlatform(cmd *cobra.Command, name, profile string) (platform.Platform, error) {
+	app, _ := cmd.Flags().GetString("app")
+	environment, _ := cmd.Flags().GetString("environment")
+	if environment == "" && platform.HasEnvironments(name) {
+		if cfg, _, err := loadServeConfig(); err == nil {
+			profile = cfg.EffectiveProfile(profile)
+		}
+		environment = platform.ProfileEnvironment(profile)
+	}
+	return platform.New(name, app, environment)
+}
+
+// runPush implements the push command logic.
+func runPush(cmd *cobra.Command, plat platform.Platform, profile string, raw bool, backendName string, prune, dryRun, yes bool) error {
+	desired := make(map[string]string)
+	if raw {
+		ns, _, closeAll, err := openProjectBackend(backendName, profile)
+		if err != nil {
+			return err
+		}
+		defer closeAll()
+		keys, err := ns.List()
+		if err != nil {
+			return fmt.Errorf("listing secrets: %w", err)
+		}
+		for _, key := range keys {
+			value, err := ns.Get(key)
+			if err != nil {
+				return fmt.Errorf("reading secret %q: %w", key, err)
+			}
+			desired[key] = value
+		}
+	} else {
+		entries, err := resolveEnvEntries(cmd, profile, true)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			desired[e.Key] = e.Value
+		}
+	}
+	if len(desired) == 0 {
+		return fmt.Errorf("nothing to push: the environment is empty")
+	}
+
+	ctx := cmdContext(cmd)
+	current, err := plat.Vars(ctx)
+	if err != nil {
+		return err
+	}
+	changes := platform.Diff(current, desired, plat.Readable(), prune)
+
+	w := output.NewWriter(cmd)
+	w.Info("%s: %d to add, %d to update, %d to remove, %d unchanged\n",
+		plat.Describe(), len(changes.Added), len(changes.Updated), len(changes.Removed), changes.Unchanged)
+	printKeyChanges(w, changes.Added, changes.Updated, changes.Removed)
+	if len(changes.Kept) > 0 {
+		w.Info("kept %d var(s) not in the environment (use --prune to remove): %s\n", len(changes.Kept), strings.Join(changes.Kept, ", "))
+	}
+	if !plat.Readable() && len(changes.Updated) > 0 {
+		w.Info("(values cannot be read back from %s, so existing keys are always written)\n", plat.Describe())
+	}
+
+	if changes.Empty() {
+		w.Info("%s is up to date\n", plat.Describe())
+		return nil
+	}
+	if dryRun {
+		w.Info("(dry run: no changes made)\n")
+		return nil
+	}
+	if !yes {
+		ok, err := confirmChanges(cmd, plat.Describe())
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	if err := plat.Apply(ctx, changes.Set(desired), changes.Removed); err != nil {
+		return err
+	}
+	w.Info("updated %s: %d added, %d updated, %d removed\n",
+		plat.Describe(), len(changes.Added), len(changes.Updated), len(changes.Removed))
+	return nil
+}
+
+// runPull implements the pull command logic.
+func runPull(cmd *cobra.Command, plat platform.Platform, profile, backendName string, force, dryRun, yes bool) error {
+	if !plat.Readable() {
+		return fmt.Errorf("%s does not reveal its values, so it cannot be pulled from", plat.Describe())
+	}
+	ctx := cmdContext(cmd)
+	vars, err := plat.Vars(ctx)
+	if err != nil {
+		return err
+	}
+	if len(vars) == 0 {
+		return fmt.Errorf("%s has no config vars", plat.Describe())
+	}
+
+	stored, target, err := importSecrets(cmd, vars, plat.Describe(), "pull from "+plat.Describe(), profile, backendName, force, dryRun, yes, false)
+	if err != nil || stored == 0 {
+		return err
+	}
+	output.NewWriter(cmd).Info("pulled %d secret(s) from %s into %s\n", stored, plat.Describe(), target.label())
+	return nil
+}
+
+// importSecrets stores vars, read from source, as secrets of the project
+// in the backend called backendName, or the first configured one, logging
+// detail to the audit log. Secrets that exist with a different value are
+// kept unless force is set. With writeEnv, a ref:// entry is added to the
+// .env file for each stored secret. It returns how many secrets were
+// stored, none if the changes were not confirmed, and where.
+func importSecrets(cmd *cobra.Command, vars map[string]string, source, detail, profile, backendName string, force, dryRun, yes, writeEnv bool) (int, projectBackend, error) {
+	ns, target, closeAll, err := openProjectBackend(backendName, profile)
+	if err != nil {
+		return 0, target, err
+	}
+	defer closeAll()
+
+	var added, updated, skipped []string
+	unchanged := 0
+	for _, key := range slices.Sorted(maps.Keys(vars)) {
+		switch existing, err := ns.Get(key); {
+		case err != nil:
+			added = append(added, key)
+		case existing == vars[key]:
+			unchanged++
+		case force:
+			updated = append(updated, key)
+		default:
+			skipped = append(skipped, key)
+		}
+	}
+
+	w := output.NewWriter(cmd)
+	w.Info("%s into %s: %d to add, %d to update, %d unchanged\n",
+		source, target.label(), len(added), len(updated), unchanged)
+	printKeyChanges(w, added, updated, nil)
+	if len(skipped) > 0 {
+		w.Info("skipped %d secret(s) with a different value (use --force to overwrite): %s\n", len(skipped), strings.Join(skipped, ", "))
+	}
+
+	if len(added)+len(updated) == 0 {
+		w.Info("%s is up to date\n", target.label())
+		return 0, target, nil
+	}
+	if dryRun {
+		w.Info("(dry run: no changes made)\n")
+		return 0, target, nil
+	}
+	if !yes {
+		ok, err := confirmChanges(cmd, target.label())
+		if err != nil || !ok {
+			return 0, target, err
+		}
+	}
+
+	logger := newAuditLogger(target.cfg, target.configDir)
+	for _, key := range slices.Concat(added, updated) {
+		if err := ns.Set(key, vars[key]); err != nil {
+			return 0, target, fmt.Errorf("storing secret %q: %w", key, err)
+		}
+		// Log the operation to the audit log (best-effort).
+		_ = logger.Log(audit.Entry{
+			Operation: audit.OpImport,
+			Key:       key,
+			Backend:   target.backend,
+			Project:   target.cfg.Project,
+			Profile:   target.profile,
+			Detail:    detail,
+		})
+		if writeEnv {
+			if err := syncEnvRef(cmd, target.cfg, target.configDir, key, target.backend, target.profile); err != nil {
+				w.Warn("could not update .env file: %v\n", err)
+			}
+		}
+	}
+	return len(added) + len(updated), target, nil
+}
+
+// projectBackend identifies the backend and scope that openProjectBackend
+// opened.
+type projectBackend struct {
+	cfg       *config.Config
+	configDir string
+	backend   string
+	profile   string
+}
+
+// label describes the backend and profile, such as `backend "vault"
+// (profile "staging")`.
+func (p projectBackend) label() string {
+	if p.profile == "" {
+		return fmt.Sprintf("backend %q", p.backend)
+	}
+	return fmt.Sprintf("backend %q (profile %q)", p.backend, p.profile)
+}
+
+// openProjectBackend opens the backend called backendName, or the first
+// configured one, scoped to the project of the working directory and
+// profile. closeAll closes the backends.
+func openProjectBackend(backendName, profile string) (backend.Backend, projectBackend, func(), error) {
+	var target projectBackend
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, target, nil, fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, configDir, err := config.Load(cwd)
+	if err != nil {
+		return nil, target, nil, fmt.Errorf("loading config: %w", err)
+	}
+	if len(cfg.Backends) == 0 {
+		return nil, target, nil, withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
+	}
+	if backendName == "" {
+		backendName = cfg.Backends[0].Name
+	}
+
+	registry, err := buildRegistry(cfg)
+	if err != nil {
+		return nil, target, nil, fmt.Errorf("initializing backends: %w", err)
+	}
+	if registry.Backend(backendName) == nil {
+		registry.CloseAll()
+		return nil, target, nil, registry.Unregistered(backendName)
+	}
+	profile = cfg.EffectiveProfile(profile)
+	ns, err := registry.Namespaced(backendName, cfg.Project, profile)
+	if err != nil {
+		registry.CloseAll()
+		return nil, target, nil, fmt.Errorf("creating namespaced backend: %w", err)
+	}
+	target = projectBackend{cfg: cfg, configDir: configDir, backend: backendName, profile: profile}
+	return ns, target, func() { registry.CloseAll() }, nil
+}
+
+// printKeyChanges lists added, updated, and removed keys.
+func printKeyChanges(w *output.Writer, added, updated, removed []string) {
+	for _, key := range added {
+		w.Info("  + %s\n", key)
+	}
+	for _, key := range updated {
+		w.Info("  ~ %s\n", key)
+	}
+	for _, key := range removed {
+		w.Info("  - %s\n", key)
+	}
+}
+
+// confirmChanges asks whether to apply the listed changes to target.
+func confirmChanges(cmd *cobra.Command, target string) (bool, error) {
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Apply these changes to %s? [y/N] ", target)
+	answer, err := readLine(cmd.InOrStdin())
+	if err != nil {
+		return false, fmt.Errorf("reading confirmation: %w", err)
+	}
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	if answer != "y" && answer != "yes" {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "cancelled")
+		return false, nil
+	}
+	return true, nil
+}
+
+// cmdContext returns the command's context, or the background context
+// when it has none.
+func cmdContext(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeFlyctl puts a flyctl on PATH whose app has the secrets listed in
+// names, logs its arguments, and keeps what is imported, and returns its
+// state directory.
+func fakeFlyctl(t *testing.T, names ...string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake flyctl is a shell script")
+	}
+	state := t.TempDir()
+	bin := t.TempDir()
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = `{"name":"` + name + `","digest":"abc"}`
+	}
+	writeTestFile(t, state, "list.json", "["+strings.Join(list, ",")+"]")
+	script := `#!/bin/sh
+echo "$*" >> "` + state + `/log"
+case "$2" in
+list) cat "` + state + `/list.json" ;;
+import) cat >> "` + state + `/import" ;;
+unset) ;;
+*) echo "unexpected flyctl call" >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(bin, "flyctl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return state
+}
+
+func TestPushCmd_Fly(t *testing.T) {
+	state := fakeFlyctl(t, "API_KEY", "OLD")
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "A=1\nAPI_KEY=ref://secrets/API_KEY\n")
+	chdir(t, dir)
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "sk-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	stdout, _, err := execCmd(t, "push", "fly", "--app", "shop", "--dry-run")
+	if err != nil {
+		t.Fatalf("push --dry-run: %v", err)
+	}
+	if !strings.Contains(stdout, "fly app shop: 1 to add, 1 to update, 0 to remove") ||
+		!strings.Contains(stdout, "kept 1 var(s) not in the environment (use --prune to remove): OLD") {
+		t.Errorf("unexpected dry run output:\n%s", stdout)
+	}
+	if _, err := os.Stat(filepath.Join(state, "import")); err == nil {
+		t.Error("dry run imported secrets")
+	}
+
+	_, stderr, err := execCmdWithStdin(t, "n\n", "push", "fly", "--app", "shop")
+	if err != nil || !strings.Contains(stderr, "Apply these changes to fly app shop? [y/N]") || !strings.Contains(stderr, "cancelled") {
+		t.Fatalf("declined push: %v\n%s", err, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(state, "import")); err == nil {
+		t.Error("declined push imported secrets")
+	}
+
+	stdout, _, err = execCmdWithStdin(t, "y\n", "push", "fly", "--app", "shop", "--prune")
+	if err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if !strings.Contains(stdout, "  + A\n") || !strings.Contains(stdout, "  ~ API_KEY\n") || !strings.Contains(stdout, "  - OLD\n") ||
+		!strings.Contains(stdout, "updated fly app shop: 1 added, 1 updated, 1 removed") {
+		t.Errorf("unexpected output:\n%s", stdout)
+	}
+	if strings.Contains(stdout, "sk-123") {
+		t.Error("push printed a secret value")
+	}
+	imported, _ := os.ReadFile(filepath.Join(state, "import"))
+	if string(imported) != "A=1\nAPI_KEY=sk-123\n" {
+		t.Errorf("imported %q", imported)
+	}
+	log, _ := os.ReadFile(filepath.Join(state, "log"))
+	if !strings.Contains(string(log), "secrets import --app shop --stage\n") || !strings.Contains(string(log), "secrets unset --app shop OLD\n") {
+		t.Errorf("unexpected flyctl calls:\n%s", log)
+	}
+}
+
+func TestPushCmd_Raw(t *testing.T) {
+	state := fakeFlyctl(t)
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "A=1\n")
+	chdir(t, dir)
+	if _, _, err := execCmd(t, "secret", "set", "TOKEN", "--value", "t-1", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	if _, _, err := execCmd(t, "push", "fly", "--app", "shop", "--raw", "--yes"); err != nil {
+		t.Fatalf("push --raw: %v", err)
+	}
+	imported, _ := os.ReadFile(filepath.Join(state, "import"))
+	if string(imported) != "TOKEN=t-1\n" {
+		t.Errorf("imported %q", imported)
+	}
+}
+
+func TestPullCmd_Fly(t *testing.T) {
+	fakeFlyctl(t)
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "pull", "fly", "--app", "shop")
+	if err == nil || !strings.Contains(err.Error(), "cannot be pulled from") {
+		t.Fatalf("expected an error, got %v", err)
+	}
+}
+
+// fakePlatform is a readable platform holding fixed vars.
+type fakePlatform struct {
+	vars map[string]string
+}
+
+func (p *fakePlatform) Describe() string { return "fake app" }
+func (p *fakePlatform) Readable() bool   { return true }
+
+func (p *fakePlatform) Vars(ctx context.Context) (map[string]string, error) {
+	return p.vars, nil
+}
+
+func (p *fakePlatform) Apply(ctx context.Context, set map[string]string, unset []string) error {
+	return nil
+}
+
+func TestRunPull(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+	for key, value := range map[string]string{"SAME": "1", "DIFF": "old"} {
+		if _, _, err := execCmd(t, "secret", "set", key, "--value", value, "--no-env"); err != nil {
+			t.Fatalf("secret set: %v", err)
+		}
+	}
+	plat := &fakePlatform{vars: map[string]string{"SAME": "1", "DIFF": "new", "NEW": "n"}}
+
+	pull := func(force bool) string {
+		t.Helper()
+		cmd := newPullCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		if err := runPull(cmd, plat, "", "", force, false, true); err != nil {
+			t.Fatalf("pull: %v", err)
+		}
+		return out.String()
+	}
+
+	out := pull(false)
+	if !strings.Contains(out, `fake app into backend "secrets": 1 to add, 0 to update, 1 unchanged`) ||
+		!strings.Contains(out, "skipped 1 secret(s) with a different value (use --force to overwrite): DIFF") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if got, _, _ := execCmd(t, "secret", "get", "NEW"); strings.TrimSpace(got) != "n" {
+		t.Errorf("NEW = %q", got)
+	}
+	if got, _, _ := execCmd(t, "secret", "get", "DIFF"); strings.TrimSpace(got) != "old" {
+		t.Errorf("DIFF was overwritten without --force: %q", got)
+	}
+
+	out = pull(true)
+	if !strings.Contains(out, "  ~ DIFF\n") || !strings.Contains(out, "pulled 1 secret(s) from fake app") {
+		t.Errorf("unexpected output with --force:\n%s", out)
+	}
+	if got, _, _ := execCmd(t, "secret", "get", "DIFF"); strings.TrimSpace(got) != "new" {
+		t.Errorf("DIFF = %q", got)
+	}
+}
+
+func TestPushCmd_Environment(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+	t.Setenv("ENVREF_PROFILE", "")
+
+	_, _, err := execCmd(t, "push", "vercel", "--app", "web")
+	if err == nil || !strings.Contains(err.Error(), "vercel sets vars per environment") {
+		t.Errorf("expected an environment error, got %v", err)
+	}
+	_, _, err = execCmd(t, "push", "netlify", "--app", "site", "--environment", "staging")
+	if err == nil || !strings.Contains(err.Error(), `unknown netlify environment "staging"`) {
+		t.Errorf("expected an unknown environment error, got %v", err)
+	}
+	_, _, err = execCmd(t, "push", "heroku", "--app", "shop", "--environment", "production")
+	if err == nil || !strings.Contains(err.Error(), "heroku has no environments") {
+		t.Errorf("expected a no environments error, got %v", err)
+	}
+}
+
+func TestPushPullCmd_DotenvVault(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "API_KEY=abc\nPORT=8080\n")
+	chdir(t, dir)
+	t.Setenv("DOTENV_KEY", "")
+
+	if _, _, err := execCmd(t, "push", "dotenv-vault", "--environment", "staging", "--yes"); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	vault, err := os.ReadFile(filepath.Join(dir, ".env.vault"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(vault), "DOTENV_VAULT_STAGING=") || strings.Contains(string(vault), "abc") {
+		t.Errorf("unexpected .env.vault:\n%s", vault)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".env.keys")); err != nil {
+		t.Errorf("expected .env.keys: %v", err)
+	}
+
+	stdout, stderr, err := execCmd(t, "pull", "dotenv-vault", "--environment", "staging", "--yes")
+	if err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if !strings.Contains(stdout+stderr, "pulled 2 secret(s) from dotenv-vault environment staging of .env.vault") {
+		t.Errorf("unexpected pull output:\n%s%s", stdout, stderr)
+	}
+	if got, _, _ := execCmd(t, "secret", "get", "API_KEY"); strings.TrimSpace(got) != "abc" {
+		t.Errorf("API_KEY = %q", got)
+	}
+}
@@ -0,0 +1,468 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/output"
+)
+
+// pluginNamePattern matches valid plugin backend names. The name becomes part
+// of the executable name (envref-backend-<name>), so it is kept to lowercase
+// letters, digits, and dashes.
+var pluginNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// newPluginCmd creates the plugin command group for plugin backend authors.
+func newPluginCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Develop secret backend plugins",
+		Long: `Tools for writing plugin backends.
+
+A plugin backend is an executable named envref-backend-<name> that envref
+talks to over a JSON protocol on stdin/stdout (protocols 1 and 2), or over
+gRPC with go-plugin (protocol 3). See docs/secret-backends.md.`,
+	}
+
+	cmd.AddCommand(newPluginNewCmd())
+
+	return cmd
+}
+
+// newPluginNewCmd creates the plugin new subcommand.
+func newPluginNewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "new <NAME>",
+		Short: "Generate a Go plugin backend skeleton",
+		Long: `Generate a working Go plugin backend in the directory envref-backend-<NAME>.
+
+Creates the following files:
+  go.mod        — Go module for the plugin
+  main.go       — protocol handling (versions 1 and 2) and an in-memory store
+  main_test.go  — tests that drive the plugin through the protocol
+  Makefile      — build, test, and install targets
+  README.md     — how to configure the plugin in .envref.yaml
+
+Replace the in-memory store in main.go with a client for your secret store.
+Existing files are skipped unless --force is used.
+
+The skeleton speaks the JSON protocols and has no dependencies. Protocol 3
+(gRPC) plugins are written with github.com/xcke/envref/pkg/plugin instead:
+implement plugin.Backend and call plugin.Serve from main, as described in
+docs/secret-backends.md.
+
+Examples:
+  envref plugin new mystore                                 # ./envref-backend-mystore
+  envref plugin new mystore --module github.com/me/mystore  # custom module path
+  envref plugin new mystore --dir ~/src                     # ~/src/envref-backend-mystore`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			module, _ := cmd.Flags().GetString("module")
+			dir, _ := cmd.Flags().GetString("dir")
+			force, _ := cmd.Flags().GetBool("force")
+
+			if dir == "" {
+				var err error
+				dir, err = os.Getwd()
+				if err != nil {
+					return fmt.Errorf("getting working directory: %w", err)
+				}
+			}
+
+			return runPluginNew(cmd, args[0], module, dir, force)
+		},
+	}
+
+	cmd.Flags().String("module", "", "Go module path (defaults to envref-backend-<NAME>)")
+	cmd.Flags().String("dir", "", "parent directory for the plugin (defaults to current directory)")
+	cmd.Flags().Bool("force", false, "overwrite existing files")
+
+	return cmd
+}
+
+// runPluginNew writes the plugin skeleton for name into
+// dir/envref-backend-<name>.
+func runPluginNew(cmd *cobra.Command, name, module, dir string, force bool) error {
+	if !pluginNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid plugin name %q: use lowercase letters, digits, and dashes", name)
+	}
+	binary := "envref-backend-" + name
+	if module == "" {
+		module = binary
+	}
+
+	w := output.NewWriter(cmd)
+	msgOut := cmd.OutOrStdout()
+	if w.IsQuiet() {
+		msgOut = io.Discard
+	}
+
+	pluginDir := filepath.Join(dir, binary)
+	if err := os.MkdirAll(pluginDir, 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", pluginDir, err)
+	}
+
+	r := strings.NewReplacer("__NAME__", name, "__BINARY__", binary, "__MODULE__", module)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"go.mod", pluginGoModTemplate},
+		{"main.go", pluginMainTemplate},
+		{"main_test.go", pluginTestTemplate},
+		{"Makefile", pluginMakefileTemplate},
+		{"README.md", pluginReadmeTemplate},
+	}
+	for _, f := range files {
+		if err := writeInitFile(msgOut, filepath.Join(pluginDir, f.name), r.Replace(f.content), force); err != nil {
+			return err
+		}
+	}
+
+	w.Info("\nCreated plugin %q in %s\n", name, pluginDir)
+	w.Info("Run 'make test' there, then 'make install' to put %s on $PATH.\n", binary)
+	return nil
+}
+
+const pluginGoModTemplate = `module __MODULE__
+
+go 1.22
+`
+
+const pluginMainTemplate = `// Command __BINARY__ is an envref secret backend plugin.
+//
+// envref runs "__BINARY__ serve" and sends JSON requests on stdin. With
+// protocol version 1 the plugin handles one request and exits; with
+// protocol version 2 (protocol: "2" in .envref.yaml) it answers a handshake
+// and then serves newline-delimited requests until stdin is closed.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// errNotFound is returned by a store for missing keys. envref treats any
+// error message containing "not found" as a missing key and tries the next
+// backend.
+var errNotFound = errors.New("not found")
+
+// store is the secret store behind the plugin. Replace memoryStore with a
+// client for your secret store.
+type store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+	List() ([]string, error)
+	// Ping checks that the store is reachable without reading a secret.
+	Ping() error
+}
+
+// memoryStore is a store that keeps secrets in memory. It only lives as
+// long as the plugin process.
+type memoryStore struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{secrets: make(map[string]string)}
+}
+
+func (m *memoryStore) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.secrets[key]
+	if !ok {
+		return "", errNotFound
+	}
+	return v, nil
+}
+
+func (m *memoryStore) Set(key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets[key] = value
+	return nil
+}
+
+func (m *memoryStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.secrets[key]; !ok {
+		return errNotFound
+	}
+	delete(m.secrets, key)
+	return nil
+}
+
+func (m *memoryStore) List() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.secrets))
+	for k := range m.secrets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m *memoryStore) Ping() error {
+	return nil
+}
+
+// request is a JSON request from envref.
+type request struct {
+	ID        int      ` + "`json:\"id,omitempty\"`" + `
+	Operation string   ` + "`json:\"operation\"`" + `
+	Key       string   ` + "`json:\"key,omitempty\"`" + `
+	Value     string   ` + "`json:\"value,omitempty\"`" + `
+	Keys      []string ` + "`json:\"keys,omitempty\"`" + `
+	Versions  []int    ` + "`json:\"versions,omitempty\"`" + `
+}
+
+// response is a JSON response to envref.
+type response struct {
+	ID           int               ` + "`json:\"id,omitempty\"`" + `
+	Value        string            ` + "`json:\"value,omitempty\"`" + `
+	Keys         []string          ` + "`json:\"keys,omitempty\"`" + `
+	Values       map[string]string ` + "`json:\"values,omitempty\"`" + `
+	Version      int               ` + "`json:\"version,omitempty\"`" + `
+	Capabilities []string          ` + "`json:\"capabilities,omitempty\"`" + `
+	Error        string            ` + "`json:\"error,omitempty\"`" + `
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "serve" {
+		fmt.Fprintf(os.Stderr, "usage: %s serve\n", os.Args[0])
+		os.Exit(2)
+	}
+	if err := serve(os.Stdin, os.Stdout, newMemoryStore()); err != nil {
+		fmt.Fprintf(os.Stderr, "__BINARY__: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serve reads requests from r and writes responses to w. A first request
+// other than a handshake is a protocol version 1 request: it is answered
+// and serve returns.
+func serve(r io.Reader, w io.Writer, s store) error {
+	dec := json.NewDecoder(r)
+	enc := json.NewEncoder(w)
+
+	var req request
+	if err := dec.Decode(&req); err != nil {
+		return enc.Encode(response{Error: fmt.Sprintf("invalid request: %v", err)})
+	}
+	if req.Operation != "handshake" {
+		return enc.Encode(handle(s, req))
+	}
+
+	if !slices.Contains(req.Versions, 2) {
+		return enc.Encode(response{Error: "no supported protocol version"})
+	}
+	if err := enc.Encode(response{Version: 2, Capabilities: []string{"get_many", "ping"}}); err != nil {
+		return err
+	}
+	for {
+		var req request
+		if err := dec.Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		resp := handle(s, req)
+		resp.ID = req.ID
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// handle runs a single operation against the store.
+func handle(s store, req request) response {
+	var resp response
+	var err error
+
+	switch req.Operation {
+	case "get":
+		resp.Value, err = s.Get(req.Key)
+	case "set":
+		err = s.Set(req.Key, req.Value)
+	case "delete":
+		err = s.Delete(req.Key)
+	case "list":
+		resp.Keys, err = s.List()
+	case "get_many":
+		resp.Values = make(map[string]string, len(req.Keys))
+		for _, key := range req.Keys {
+			v, getErr := s.Get(key)
+			if errors.Is(getErr, errNotFound) {
+				continue
+			}
+			if getErr != nil {
+				err = getErr
+				break
+			}
+			resp.Values[key] = v
+		}
+	case "ping":
+		err = s.Ping()
+	default:
+		err = fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+
+	if err != nil {
+		return response{Error: err.Error()}
+	}
+	return resp
+}
+`
+
+const pluginTestTemplate = `package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// exchange sends the JSON-encoded requests to serve and returns the decoded
+// responses.
+func exchange(t *testing.T, s store, reqs ...request) []response {
+	t.Helper()
+	var in bytes.Buffer
+	for _, req := range reqs {
+		if err := json.NewEncoder(&in).Encode(req); err != nil {
+			t.Fatalf("encode request: %v", err)
+		}
+	}
+	var out bytes.Buffer
+	if err := serve(&in, &out, s); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	var resps []response
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var resp response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response %q: %v", scanner.Text(), err)
+		}
+		resps = append(resps, resp)
+	}
+	return resps
+}
+
+func TestProtocol1(t *testing.T) {
+	s := newMemoryStore()
+
+	if resps := exchange(t, s, request{Operation: "set", Key: "api_key", Value: "s3cret"}); len(resps) != 1 || resps[0].Error != "" {
+		t.Fatalf("set: %+v", resps)
+	}
+	resps := exchange(t, s, request{Operation: "get", Key: "api_key"})
+	if len(resps) != 1 || resps[0].Value != "s3cret" {
+		t.Fatalf("get: %+v", resps)
+	}
+	resps = exchange(t, s, request{Operation: "get", Key: "missing"})
+	if len(resps) != 1 || !strings.Contains(resps[0].Error, "not found") {
+		t.Fatalf("get missing: %+v", resps)
+	}
+	resps = exchange(t, s, request{Operation: "bogus"})
+	if len(resps) != 1 || !strings.Contains(resps[0].Error, "unknown operation") {
+		t.Fatalf("unknown operation: %+v", resps)
+	}
+}
+
+func TestProtocol2(t *testing.T) {
+	resps := exchange(t, newMemoryStore(),
+		request{Operation: "handshake", Versions: []int{2}},
+		request{ID: 1, Operation: "set", Key: "a", Value: "1"},
+		request{ID: 2, Operation: "get_many", Keys: []string{"a", "b"}},
+		request{ID: 3, Operation: "list"},
+		request{ID: 4, Operation: "delete", Key: "a"},
+		request{ID: 5, Operation: "ping"},
+	)
+	if len(resps) != 6 {
+		t.Fatalf("got %d responses, want 6: %+v", len(resps), resps)
+	}
+	if resps[0].Version != 2 {
+		t.Errorf("handshake: %+v", resps[0])
+	}
+	for i, resp := range resps[1:] {
+		if resp.ID != i+1 || resp.Error != "" {
+			t.Errorf("response %d: %+v", i+1, resp)
+		}
+	}
+	if len(resps[2].Values) != 1 || resps[2].Values["a"] != "1" {
+		t.Errorf("get_many: %+v", resps[2])
+	}
+	if len(resps[3].Keys) != 1 || resps[3].Keys[0] != "a" {
+		t.Errorf("list: %+v", resps[3])
+	}
+}
+
+func TestProtocol2_UnsupportedVersion(t *testing.T) {
+	resps := exchange(t, newMemoryStore(), request{Operation: "handshake", Versions: []int{3}})
+	if len(resps) != 1 || resps[0].Error == "" {
+		t.Fatalf("handshake: %+v", resps)
+	}
+}
+`
+
+const pluginMakefileTemplate = `BINARY := __BINARY__
+
+.PHONY: build test install clean
+
+build:
+	go build -o $(BINARY) .
+
+test:
+	go test ./...
+
+install:
+	go install .
+
+clean:
+	rm -f $(BINARY)
+`
+
+const pluginReadmeTemplate = "# __BINARY__\n\n" +
+	"An [envref](https://github.com/xcke/envref) secret backend plugin.\n\n" +
+	"## Build\n\n" +
+	"```sh\n" +
+	"make test\n" +
+	"make install   # puts __BINARY__ in $(go env GOPATH)/bin\n" +
+	"```\n\n" +
+	"## Configure\n\n" +
+	"Add the backend to `.envref.yaml`:\n\n" +
+	"```yaml\n" +
+	"backends:\n" +
+	"  - name: __NAME__\n" +
+	"    type: plugin\n" +
+	"    config:\n" +
+	"      protocol: \"2\"\n" +
+	"```\n\n" +
+	"If `__BINARY__` is not on `$PATH`, set `command` to its path. Check the\n" +
+	"setup with `envref backend test __NAME__`.\n\n" +
+	"## Implement\n\n" +
+	"`main.go` handles the protocol and keeps secrets in memory. Replace\n" +
+	"`memoryStore` with a client for your secret store; return `errNotFound`\n" +
+	"for missing keys.\n\n" +
+	"This plugin speaks the JSON protocols 1 and 2. To serve protocol 3 (gRPC)\n" +
+	"instead, implement `plugin.Backend` from\n" +
+	"[`github.com/xcke/envref/pkg/plugin`](https://pkg.go.dev/github.com/xcke/envref/pkg/plugin)\n" +
+	"and call `plugin.Serve` from `main`.\n"
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPluginNewCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	stdout, _, err := execCmd(t, "plugin", "new", "mystore", "--dir", dir, "--module", "example.com/mystore")
+	if err != nil {
+		t.Fatalf("plugin new: %v", err)
+	}
+	pluginDir := filepath.Join(dir, "envref-backend-mystore")
+	for _, name := range []string{"go.mod", "main.go", "main_test.go", "Makefile", "README.md"} {
+		if _, err := os.Stat(filepath.Join(pluginDir, name)); err != nil {
+			t.Errorf("expected %s to be created: %v", name, err)
+		}
+		if !contains(stdout, "create "+name) {
+			t.Errorf("expected create message for %s, got: %q", name, stdout)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(pluginDir, "go.mod"))
+	if err != nil {
+		t.Fatalf("reading go.mod: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "module example.com/mystore\n") {
+		t.Errorf("unexpected go.mod: %q", data)
+	}
+	data, err = os.ReadFile(filepath.Join(pluginDir, "README.md"))
+	if err != nil {
+		t.Fatalf("reading README.md: %v", err)
+	}
+	if !contains(string(data), "github.com/xcke/envref/pkg/plugin") {
+		t.Errorf("README should point protocol 3 plugins at pkg/plugin: %q", data)
+	}
+
+	// Existing files are kept without --force.
+	stdout, _, err = execCmd(t, "plugin", "new", "mystore", "--dir", dir)
+	if err != nil {
+		t.Fatalf("plugin new (again): %v", err)
+	}
+	if !contains(stdout, "skip main.go (already exists)") {
+		t.Errorf("expected skip message, got: %q", stdout)
+	}
+}
+
+func TestPluginNewCmd_GeneratedPluginPassesTests(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+	dir := t.TempDir()
+
+	if _, _, err := execCmd(t, "plugin", "new", "mystore", "--dir", dir); err != nil {
+		t.Fatalf("plugin new: %v", err)
+	}
+	cmd := exec.Command("go", "test", "./...")
+	cmd.Dir = filepath.Join(dir, "envref-backend-mystore")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test in generated plugin: %v\n%s", err, out)
+	}
+}
+
+func TestPluginNewCmd_InvalidName(t *testing.T) {
+	_, _, err := execCmd(t, "plugin", "new", "My Store", "--dir", t.TempDir())
+	if err == nil || !contains(err.Error(), "invalid plugin name") {
+		t.Errorf("expected invalid name error, got: %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/procfile"
+	"github.com/xcke/envref/internal/suggest"
+)
+
+// procfileBasePort is the PORT of the first process when the environment
+// sets none, as in foreman.
+const procfileBasePort = 5000
+
+// procfileStopTimeout is how long processes are given to exit before they
+// are killed.
+const procfileStopTimeout = 5 * time.Second
+
+// procExit is the result of a process that exited.
+type procExit struct {
+	index int
+	err   error
+}
+
+// runProcfile starts the processes of the Procfile at path named in names,
+// or all of them, with environ, and waits for them. When one exits, or
+// envref is interrupted, the others are stopped. The exit code is that of
+// the first process to exit.
+func runProcfile(cmd *cobra.Command, path string, names []string, environ []string) error {
+	all, err := procfile.ParseFile(path)
+	if err != nil {
+		return fmt.Errorf("reading Procfile: %w", err)
+	}
+	procNames := make([]string, len(all))
+	for i, p := range all {
+		procNames[i] = p.Name
+	}
+	for _, name := range names {
+		if !slices.Contains(procNames, name) {
+			return fmt.Errorf("no process %q in %s%s", name, path, suggest.FormatSuggestion(suggest.Keys(name, procNames)))
+		}
+	}
+
+	basePort := procfileBasePort
+	if port, ok := lookupEnv(environ, "PORT"); ok {
+		if n, err := strconv.Atoi(port); err == nil {
+			basePort = n
+		}
+	}
+
+	// Output is prefixed with the process name when several run.
+	var procs []procfile.Process
+	var ports []int
+	for i, p := range all {
+		if len(names) == 0 || slices.Contains(names, p.Name) {
+			procs = append(procs, p)
+			ports = append(ports, basePort+100*i)
+		}
+	}
+	width := 0
+	for _, p := range procs {
+		width = max(width, len(p.Name))
+	}
+	var mu sync.Mutex
+	var writers []*prefixWriter
+	label := func(name string) string {
+		return fmt.Sprintf("%-*s | ", width, name)
+	}
+
+	exits := make(chan procExit, len(procs))
+	var started []*exec.Cmd
+	exited := make([]bool, len(procs))
+	stop := func(force bool) {
+		for i, c := range started {
+			if !exited[i] {
+				signalProcessGroup(c.Process, force)
+			}
+		}
+	}
+	for i, p := range procs {
+		child := shellCommand(p.Command)
+		child.SysProcAttr = processGroupAttr()
+		child.Env = append(slices.Clone(environ), "PORT="+strconv.Itoa(ports[i]))
+		if len(procs) == 1 {
+			child.Stdin = os.Stdin
+			child.Stdout = cmd.OutOrStdout()
+			child.Stderr = cmd.ErrOrStderr()
+		} else {
+			stdout := &prefixWriter{w: cmd.OutOrStdout(), mu: &mu, prefix: label(p.Name)}
+			stderr := &prefixWriter{w: cmd.ErrOrStderr(), mu: &mu, prefix: label(p.Name)}
+			writers = append(writers, stdout, stderr)
+			child.Stdout, child.Stderr = stdout, stderr
+		}
+		if err := child.Start(); err != nil {
+			stop(true)
+			return fmt.Errorf("starting %s: %w", p.Name, err)
+		}
+		started = append(started, child)
+		go func() {
+			exits <- procExit{index: i, err: child.Wait()}
+		}()
+	}
+
+	// Stop the processes when one exits or envref is interrupted.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	var first *procExit
+	remaining := len(procs)
+	var kill <-chan time.Time
+	for remaining > 0 {
+		select {
+		case exit := <-exits:
+			exited[exit.index] = true
+			remaining--
+			if len(procs) > 1 {
+				writeProcExit(cmd.ErrOrStderr(), &mu, label(procs[exit.index].Name), exit.err)
+			}
+			if first != nil || kill != nil {
+				continue
+			}
+			first = &exit
+		case <-sigCh:
+			if kill != nil {
+				stop(true)
+				continue
+			}
+		case <-kill:
+			stop(true)
+			continue
+		}
+		if kill == nil {
+			stop(false)
+			kill = time.After(procfileStopTimeout)
+		}
+	}
+	for _, w := range writers {
+		w.Flush()
+	}
+
+	if first == nil {
+		return nil
+	}
+	var execExitErr *exec.ExitError
+	if errors.As(first.err, &execExitErr) {
+		return &exitError{code: execExitErr.ExitCode()}
+	}
+	return first.err
+}
+
+// writeProcExit reports how a process exited.
+func writeProcExit(w io.Writer, mu *sync.Mutex, label string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	status := "exited"
+	if err != nil {
+		status = err.Error()
+	}
+	_, _ = fmt.Fprintf(w, "%s%s\n", label, status)
+}
+
+// lookupEnv returns the last value of key in environ.
+func lookupEnv(environ []string, key string) (string, bool) {
+	for i := len(environ) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(environ[i], "="); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// prefixWriter writes each line written to it to w, after prefix. Writers
+// that share mu do not interleave lines.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+// Write implements io.Writer. A partial line is held until it is complete
+// or the writer is flushed.
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		p.writeLine(p.buf[:i+1])
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes a partial line, if any.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.writeLine(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+// writeLine writes line after the prefix.
+func (p *prefixWriter) writeLine(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = io.WriteString(p.w, p.prefix)
+	_, _ = p.w.Write(line)
+}
@@ -0,0 +1,25 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// processGroupAttr returns nil: processes are stopped one by one.
+func processGroupAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// signalProcessGroup kills p: there is no signal to ask a process to stop
+// on this system.
+func signalProcessGroup(p *os.Process, force bool) {
+	_ = p.Kill()
+}
+
+// sendProcessGroup kills p, whichever signal is named: there are no
+// signals on this system.
+func sendProcessGroup(p *os.Process, _ string) {
+	_ = p.Kill()
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	w := &prefixWriter{w: &out, mu: &mu, prefix: "web | "}
+	_, _ = w.Write([]byte("one\ntw"))
+	_, _ = w.Write([]byte("o\nthree"))
+	w.Flush()
+	if got := out.String(); got != "web | one\nweb | two\nweb | three\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+// setupProcfileProject writes a project whose Procfile has a web process
+// that keeps running and a worker that exits with code 3.
+func setupProcfileProject(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on Windows: test uses /bin/sh")
+	}
+	dir := setupProject(t, "testproject", "GREETING=hi\n", "")
+	writeTestFile(t, dir, "Procfile", `web: echo "web $PORT"; sleep 10
+worker: sleep 0.3; echo "worker $PORT $GREETING"; exit 3
+`)
+	chdir(t, dir)
+}
+
+func TestRunCmd_Procfile(t *testing.T) {
+	setupProcfileProject(t)
+
+	stdout, stderr, err := execCmd(t, "run", "--procfile", "Procfile")
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) || exitErr.code != 3 {
+		t.Fatalf("expected exit code 3, got %v\n%s", err, stderr)
+	}
+	for _, want := range []string{"web    | web 5000\n", "worker | worker 5100 hi\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("missing %q in output:\n%s", want, stdout)
+		}
+	}
+	if !strings.Contains(stderr, "worker | exit status 3\n") {
+		t.Errorf("missing worker exit in stderr:\n%s", stderr)
+	}
+}
+
+func TestRunCmd_ProcfileProcess(t *testing.T) {
+	setupProcfileProject(t)
+
+	stdout, _, err := execCmd(t, "run", "--procfile", "Procfile", "worker")
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) || exitErr.code != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	if stdout != "worker 5100 hi\n" {
+		t.Errorf("got %q", stdout)
+	}
+
+	_, _, err = execCmd(t, "run", "--procfile", "Procfile", "wroker")
+	if err == nil || !strings.Contains(err.Error(), `no process "wroker" in Procfile`) || !strings.Contains(err.Error(), "worker") {
+		t.Errorf("expected an unknown process error, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// processGroupAttr starts a process in a new process group, so that the
+// processes it starts can be stopped with it.
+func processGroupAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalProcessGroup asks the process group of p to stop, or kills it if
+// force is set.
+func signalProcessGroup(p *os.Process, force bool) {
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+	_ = syscall.Kill(-p.Pid, sig)
+}
+
+// stopSignals are the signals of stopSignalNames.
+var stopSignals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"KILL": syscall.SIGKILL,
+}
+
+// sendProcessGroup sends the signal called name, one of stopSignalNames,
+// to the process group of p.
+func sendProcessGroup(p *os.Process, name string) {
+	_ = syscall.Kill(-p.Pid, stopSignals[name])
+}
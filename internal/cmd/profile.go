@@ -13,6 +13,7 @@ import (
 	"github.com/xcke/envref/internal/config"
 	"github.com/xcke/envref/internal/envfile"
 	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/suggest"
 )
 
 // newProfileCmd creates the profile command group for managing environment profiles.
@@ -24,12 +25,14 @@ func newProfileCmd() *cobra.Command {
 (e.g., development, staging, production).
 
 Profiles define which .env file overlays are applied during resolution.
-The merge order is: .env ← .env.<profile> ← .env.local
+The merge order is: .env ← .env.<profile> ← .env.local ← .env.<profile>.local
 
-Use subcommands to list available profiles.`,
+Use subcommands to list available profiles, or "profile current" to see
+the effective profile and the files it merges.`,
 	}
 
 	cmd.AddCommand(newProfileListCmd())
+	cmd.AddCommand(newProfileCurrentCmd())
 	cmd.AddCommand(newProfileUseCmd())
 	cmd.AddCommand(newProfileCreateCmd())
 	cmd.AddCommand(newProfileDiffCmd())
@@ -152,6 +155,9 @@ func runProfileCreate(cmd *cobra.Command, name string) error {
 	if strings.Contains(name, ".") {
 		return fmt.Errorf("profile name %q must not contain dots", name)
 	}
+	if strings.Contains(name, config.ProfileSeparator) {
+		return fmt.Errorf("profile name %q must not contain %q, which combines profiles", name, config.ProfileSeparator)
+	}
 	if strings.Contains(name, "/") || strings.Contains(name, "\\") {
 		return fmt.Errorf("profile name %q must not contain path separators", name)
 	}
@@ -253,12 +259,14 @@ func runProfileUse(cmd *cobra.Command, name string) error {
 		return nil
 	}
 
-	// Validate that the profile exists (in config or on disk).
-	if !cfg.HasProfile(name) {
-		envFile := ".env." + name
-		diskPath := filepath.Join(projectDir, envFile)
-		if _, statErr := os.Stat(diskPath); statErr != nil {
-			return fmt.Errorf("profile %q not found (not in config and %s does not exist)", name, envFile)
+	// Validate that each combined profile exists (in config or on disk).
+	for _, part := range config.SplitProfiles(name) {
+		if !cfg.HasProfile(part) {
+			envFile := ".env." + part
+			diskPath := filepath.Join(projectDir, envFile)
+			if _, statErr := os.Stat(diskPath); statErr != nil {
+				return fmt.Errorf("profile %q not found (not in config and %s does not exist)%s", part, envFile, suggestProfile(cfg, projectDir, part))
+			}
 		}
 	}
 
@@ -268,6 +276,11 @@ func runProfileUse(cmd *cobra.Command, name string) error {
 	}
 
 	w.Info("Active profile set to %q\n", name)
+	if branch := config.CurrentBranch(projectDir); branch != "" {
+		if selected, ok := cfg.BranchProfile(branch); ok && selected != name {
+			w.Warn("branch_profiles selects profile %q on branch %q, which takes precedence over active_profile\n", selected, branch)
+		}
+	}
 	return nil
 }
 
@@ -292,6 +305,53 @@ func runProfileList(cmd *cobra.Command) error {
 		return fmt.Errorf("loading config: %w", err)
 	}
 
+	profiles, err := discoverProfiles(cfg, projectDir)
+	if err != nil {
+		return err
+	}
+
+	if len(profiles) == 0 {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "no profiles found")
+		return nil
+	}
+
+	// Sort profiles by name for stable output.
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := cmd.OutOrStdout()
+	for _, name := range names {
+		p := profiles[name]
+		marker := "  "
+		if p.Active {
+			marker = "* "
+		}
+
+		var status []string
+		if p.InConfig {
+			status = append(status, "config")
+		}
+		if parent := cfg.Profiles[name].Inherits; parent != "" {
+			status = append(status, "inherits "+parent)
+		}
+		if p.OnDisk {
+			status = append(status, "file")
+		} else {
+			status = append(status, "no file")
+		}
+
+		_, _ = fmt.Fprintf(out, "%s%-20s %s (%s)\n", marker, p.Name, p.EnvFile, strings.Join(status, ", "))
+	}
+
+	return nil
+}
+
+// discoverProfiles returns the profiles of the project by name: those in
+// the config and those with a convention-based .env.NAME file in projectDir.
+func discoverProfiles(cfg *config.Config, projectDir string) (map[string]*profileInfo, error) {
 	activeProfile := cfg.EffectiveProfile("")
 
 	// Collect profiles from config.
@@ -311,7 +371,7 @@ func runProfileList(cmd *cobra.Command) error {
 	// Discover convention-based .env.* files on disk.
 	entries, err := os.ReadDir(projectDir)
 	if err != nil {
-		return fmt.Errorf("reading project directory: %w", err)
+		return nil, fmt.Errorf("reading project directory: %w", err)
 	}
 
 	for _, entry := range entries {
@@ -345,40 +405,25 @@ func runProfileList(cmd *cobra.Command) error {
 		}
 	}
 
-	if len(profiles) == 0 {
-		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "no profiles found")
-		return nil
-	}
+	return profiles, nil
+}
 
-	// Sort profiles by name for stable output.
-	names := make([]string, 0, len(profiles))
-	for name := range profiles {
-		names = append(names, name)
+// suggestProfile returns a "did you mean" hint naming the profiles close to
+// name, or "" if name is a known profile (see discoverProfiles) or none are
+// close.
+func suggestProfile(cfg *config.Config, projectDir, name string) string {
+	profiles, err := discoverProfiles(cfg, projectDir)
+	if err != nil {
+		return ""
 	}
-	sort.Strings(names)
-
-	out := cmd.OutOrStdout()
-	for _, name := range names {
-		p := profiles[name]
-		marker := "  "
-		if p.Active {
-			marker = "* "
-		}
-
-		var status []string
-		if p.InConfig {
-			status = append(status, "config")
-		}
-		if p.OnDisk {
-			status = append(status, "file")
-		} else {
-			status = append(status, "no file")
-		}
-
-		_, _ = fmt.Fprintf(out, "%s%-20s %s (%s)\n", marker, p.Name, p.EnvFile, strings.Join(status, ", "))
+	if _, ok := profiles[name]; ok {
+		return ""
 	}
-
-	return nil
+	names := make([]string, 0, len(profiles))
+	for n := range profiles {
+		names = append(names, n)
+	}
+	return suggest.FormatSuggestion(suggest.Keys(name, names))
 }
 
 // newProfileDiffCmd creates the profile diff subcommand.
@@ -437,7 +482,7 @@ func runProfileDiff(cmd *cobra.Command, profileA, profileB, formatStr string) er
 		return fmt.Errorf("loading config: %w", err)
 	}
 
-	w := output.NewWriter(cmd)
+	w := output.NewWriter(cmd).ForStdout()
 
 	// Load effective env for each profile.
 	envA, err := loadProfileEnv(cmd, cfg, projectDir, profileA)
@@ -465,11 +510,7 @@ func runProfileDiff(cmd *cobra.Command, profileA, profileB, formatStr string) er
 
 // loadProfileEnv loads the effective merged environment for a given profile.
 func loadProfileEnv(cmd *cobra.Command, cfg *config.Config, projectDir, profile string) (*envfile.Env, error) {
-	envPath := resolveFilePath(projectDir, cfg.EnvFile)
-	localPath := resolveFilePath(projectDir, cfg.LocalFile)
-	profilePath := resolveFilePath(projectDir, cfg.ProfileEnvFile(profile))
-
-	return loadAndMergeEnv(cmd, envPath, profilePath, localPath)
+	return loadProjectEnv(cmd, cfg, projectDir, profile)
 }
 
 // computeProfileDiff compares two Envs and returns a sorted list of differences.
@@ -673,23 +714,25 @@ func formatDiffTable(out io.Writer, diffs []diffEntry, profileA, profileB string
 		maxA, strings.Repeat("-", maxA), strings.Repeat("-", maxB))
 
 	for _, d := range diffs {
+		// Markers are padded before they are colored, as escape codes
+		// would count toward the width.
 		var marker, valA, valB string
 		switch d.Kind {
 		case "only_a":
-			marker = w.Red("-")
+			marker = w.Red(fmt.Sprintf("%-4s", "-"))
 			valA = d.ValueA
 			valB = ""
 		case "only_b":
-			marker = w.Green("+")
+			marker = w.Green(fmt.Sprintf("%-4s", "+"))
 			valA = ""
 			valB = d.ValueB
 		case "changed":
-			marker = w.Yellow("~")
+			marker = w.Yellow(fmt.Sprintf("%-4s", "~"))
 			valA = d.ValueA
 			valB = d.ValueB
 		}
-		_, _ = fmt.Fprintf(out, "%-*s  %-*s  %-*s  %s\n",
-			4, marker, maxKey, d.Key, maxA, valA, valB)
+		_, _ = fmt.Fprintf(out, "%s  %-*s  %-*s  %s\n",
+			marker, maxKey, d.Key, maxA, valA, valB)
 	}
 
 	return nil
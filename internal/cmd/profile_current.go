@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+)
+
+// newProfileCurrentCmd creates the profile current subcommand.
+func newProfileCurrentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "current",
+		Short: "Show the effective profile and the env files it merges",
+		Long: `Print the profile commands use, where that choice comes from, and the
+env files that are merged for it, lowest precedence first.
+
+The profile is chosen in this order:
+  1. the --profile flag
+  2. the ENVREF_PROFILE environment variable
+  3. the branch_profiles entry matching the current git branch
+  4. active_profile in .envref.yaml
+
+Layers that do not exist are marked as missing; only env_file is required.
+
+Examples:
+  envref profile current                     # show the effective profile
+  envref profile current --profile staging   # show what --profile staging loads
+  envref profile current --format json       # machine-readable output`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			formatStr, _ := cmd.Flags().GetString("format")
+			return runProfileCurrent(cmd, profile, formatStr)
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "profile to show instead of the effective one")
+	cmd.Flags().String("format", "plain", "output format: plain, json")
+
+	return cmd
+}
+
+// profileCurrent is the output of profile current.
+type profileCurrent struct {
+	Profile string `json:"profile"`
+	// Source is where the profile comes from, or "none".
+	Source string `json:"source"`
+	// Inherits lists the profiles Profile inherits from, nearest first.
+	Inherits []string              `json:"inherits,omitempty"`
+	Layers   []profileCurrentLayer `json:"layers"`
+}
+
+// profileCurrentLayer is an env file merged for the profile.
+type profileCurrentLayer struct {
+	File     string `json:"file"`
+	Path     string `json:"path"`
+	Exists   bool   `json:"exists"`
+	Required bool   `json:"required"`
+}
+
+// runProfileCurrent implements the profile current command logic.
+func runProfileCurrent(cmd *cobra.Command, profileOverride, formatStr string) error {
+	format := OutputFormat(strings.ToLower(formatStr))
+	if format != FormatPlain && format != FormatJSON {
+		return fmt.Errorf("invalid format %q: must be one of %s, %s", formatStr, FormatPlain, FormatJSON)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+
+	cfg, projectDir, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	profile := cfg.EffectiveProfile(profileOverride)
+	current := profileCurrent{
+		Profile: profile,
+		Source:  profileSource(cfg, projectDir, profileOverride),
+	}
+	if chain := cfg.ProfileChain(profile); len(chain) > 1 {
+		current.Inherits = chain[1:]
+	}
+	for _, layer := range cfg.EnvLayers(profile) {
+		path := resolveFilePath(projectDir, layer)
+		current.Layers = append(current.Layers, profileCurrentLayer{
+			File:     layer,
+			Path:     path,
+			Exists:   fileExists(path),
+			Required: layer == cfg.EnvFile,
+		})
+	}
+
+	out := cmd.OutOrStdout()
+	if format == FormatJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(current)
+	}
+
+	w := output.NewWriter(cmd).ForStdout()
+	if profile == "" {
+		_, _ = fmt.Fprintln(out, "no profile")
+	} else {
+		_, _ = fmt.Fprintf(out, "%s (%s)\n", w.Bold(profile), current.Source)
+	}
+	if len(current.Inherits) > 0 {
+		_, _ = fmt.Fprintf(out, "inherits: %s\n", strings.Join(current.Inherits, " -> "))
+	}
+	_, _ = fmt.Fprintln(out, "layers, lowest precedence first:")
+	for _, l := range current.Layers {
+		var note string
+		switch {
+		case !l.Exists && l.Required:
+			note = "  " + w.Red("(missing, required)")
+		case !l.Exists:
+			note = "  (missing)"
+		}
+		_, _ = fmt.Fprintf(out, "  %s%s\n", l.File, note)
+	}
+	return nil
+}
+
+// profileSource describes where the effective profile comes from, in the
+// order config.Load and Config.EffectiveProfile apply them, or returns
+// "none" when no profile is active.
+func profileSource(cfg *config.Config, projectDir, profileOverride string) string {
+	switch {
+	case profileOverride != "":
+		return "--profile flag"
+	case os.Getenv(config.EnvProfile) != "":
+		return config.EnvProfile + " environment variable"
+	}
+	if branch := config.CurrentBranch(projectDir); len(cfg.BranchProfiles) > 0 {
+		if _, ok := cfg.BranchProfile(branch); ok {
+			if branch == "" {
+				return "branch_profiles, no branch checked out"
+			}
+			return fmt.Sprintf("branch_profiles, branch %s", branch)
+		}
+	}
+	if cfg.ActiveProfile != "" {
+		return "active_profile in " + config.FullFileName
+	}
+	return "none"
+}
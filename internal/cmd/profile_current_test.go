@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xcke/envref/internal/config"
+)
+
+func TestProfileCurrentCmd(t *testing.T) {
+	t.Setenv(config.EnvProfile, "")
+	dir := t.TempDir()
+	writeTestFile(t, dir, config.FullFileName, `project: myapp
+active_profile: preview
+profiles:
+  staging:
+    env_file: .env.staging
+  preview:
+    inherits: staging
+`)
+	writeTestFile(t, dir, ".env", "A=1\n")
+	writeTestFile(t, dir, ".env.preview", "A=2\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "profile", "current")
+	require.NoError(t, err)
+	assert.Equal(t, `preview (active_profile in .envref.yaml)
+inherits: staging
+layers, lowest precedence first:
+  .env
+  .env.staging  (missing)
+  .env.preview
+  .env.local  (missing)
+  .env.staging.local  (missing)
+  .env.preview.local  (missing)
+`, stdout)
+
+	stdout, _, err = execCmd(t, "profile", "current", "--profile", "staging")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "staging (--profile flag)\n")
+
+	t.Setenv(config.EnvProfile, "staging")
+	stdout, _, err = execCmd(t, "profile", "current", "--format", "json")
+	require.NoError(t, err)
+	var current profileCurrent
+	require.NoError(t, json.Unmarshal([]byte(stdout), &current))
+	assert.Equal(t, "staging", current.Profile)
+	assert.Equal(t, "ENVREF_PROFILE environment variable", current.Source)
+	require.Len(t, current.Layers, 4)
+	assert.Equal(t, profileCurrentLayer{File: ".env", Path: filepath.Join(dir, ".env"), Exists: true, Required: true}, current.Layers[0])
+	assert.Equal(t, profileCurrentLayer{File: ".env.staging", Path: filepath.Join(dir, ".env.staging")}, current.Layers[1])
+}
+
+func TestProfileCurrentCmd_Branch(t *testing.T) {
+	t.Setenv(config.EnvProfile, "")
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init", "-q", "-b", "main").CombinedOutput(); err != nil {
+		t.Skipf("git init: %v: %s", err, out)
+	}
+	writeTestFile(t, dir, config.FullFileName, "project: myapp\nbranch_profiles:\n  main: production\n")
+	writeTestFile(t, dir, ".env", "A=1\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "profile", "current")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "production (branch_profiles, branch main)\n")
+}
+
+func TestProfileCurrentCmd_NoProfile(t *testing.T) {
+	t.Setenv(config.EnvProfile, "")
+	dir := setupProject(t, "myapp", "A=1\n", "")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "profile", "current")
+	require.NoError(t, err)
+	assert.Equal(t, "no profile\nlayers, lowest precedence first:\n  .env\n  .env.local  (missing)\n", stdout)
+
+	_, _, err = execCmd(t, "profile", "current", "--format", "table")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid format "table"`)
+}
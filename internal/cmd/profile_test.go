@@ -3,6 +3,7 @@ package cmd
 import (
 	"encoding/json"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -855,6 +856,21 @@ profiles:
 	assert.Contains(t, stdout, "DB_HOST")
 }
 
+func TestProfileDiffCmd_TableFormatColor(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, config.FullFileName, "project: myapp\n")
+	writeTestFile(t, dir, ".env", "APP_NAME=myapp\n")
+	writeTestFile(t, dir, ".env.staging", "DB_HOST=staging-db\n")
+	writeTestFile(t, dir, ".env.production", "DB_HOST=prod-db\n")
+	chdir(t, dir)
+	t.Setenv("FORCE_COLOR", "1")
+
+	stdout, _, err := execCmd(t, "profile", "diff", "staging", "production", "--format", "table")
+	require.NoError(t, err)
+	// The colored marker keeps the columns aligned.
+	assert.Contains(t, stdout, "\033[33m~   \033[0m  DB_HOST  staging-db  prod-db\n")
+}
+
 func TestProfileDiffCmd_ConventionProfiles(t *testing.T) {
 	dir := t.TempDir()
 	cfgContent := `project: myapp
@@ -1203,3 +1219,139 @@ func TestProfileExportCmd_VisibleInHelp(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, stdout, "export")
 }
+
+func TestProfileUseCmd_SuggestsCloseProfile(t *testing.T) {
+	dir := setupProject(t, "myapp", "KEY=value\n", "")
+	writeTestFile(t, dir, ".env.staging", "KEY=staged\n")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "profile", "use", "stagign")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "did you mean staging?")
+}
+
+func TestResolveCmd_WarnsOnProfileTypo(t *testing.T) {
+	dir := setupProject(t, "myapp", "KEY=value\n", "")
+	writeTestFile(t, dir, ".env.staging", "KEY=staged\n")
+	chdir(t, dir)
+
+	stdout, stderr, err := execCmd(t, "resolve", "--profile", "stagign")
+	require.NoError(t, err)
+	assert.Equal(t, "KEY=value\n", stdout)
+	assert.Contains(t, stderr, `profile "stagign" is not in .envref.yaml and has no env file; did you mean staging?`)
+
+	_, stderr, err = execCmd(t, "resolve", "--profile", "ci")
+	require.NoError(t, err)
+	assert.NotContains(t, stderr, "did you mean")
+}
+
+func TestResolveCmd_InheritedProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, config.FullFileName, `project: myapp
+backends:
+  - name: secrets
+    type: memory
+profiles:
+  preview:
+    inherits: staging
+`)
+	writeTestFile(t, dir, ".env", "HOST=localhost\nLOG=debug\nREPLICAS=1\n")
+	writeTestFile(t, dir, ".env.staging", "HOST=staging.internal\nLOG=info\n")
+	writeTestFile(t, dir, ".env.preview", "HOST=preview.internal\n")
+	writeTestFile(t, dir, ".env.local", "REPLICAS=2\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "resolve", "--profile", "preview")
+	require.NoError(t, err)
+	assert.Equal(t, "HOST=preview.internal\nLOG=info\nREPLICAS=2\n", stdout)
+
+	stdout, _, err = execCmd(t, "profile", "list")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, ".env.preview (config, inherits staging, file)")
+
+	// A typo in the parent is reported like one in --profile.
+	writeTestFile(t, dir, config.FullFileName, `project: myapp
+profiles:
+  preview:
+    inherits: stagign
+`)
+	stdout, stderr, err := execCmd(t, "resolve", "--profile", "preview")
+	require.NoError(t, err)
+	assert.Equal(t, "HOST=preview.internal\nLOG=debug\nREPLICAS=2\n", stdout)
+	assert.Contains(t, stderr, `profile "stagign" is not in .envref.yaml and has no env file; did you mean staging?`)
+}
+
+func TestResolveCmd_BranchProfile(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init", "-q", "-b", "develop").CombinedOutput(); err != nil {
+		t.Skipf("git init: %v: %s", err, out)
+	}
+	writeTestFile(t, dir, config.FullFileName, `project: myapp
+branch_profiles:
+  main: production
+  develop: staging
+`)
+	writeTestFile(t, dir, ".env", "HOST=localhost\n")
+	writeTestFile(t, dir, ".env.staging", "HOST=staging.internal\n")
+	writeTestFile(t, dir, ".env.production", "HOST=prod.internal\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "resolve")
+	require.NoError(t, err)
+	assert.Equal(t, "HOST=staging.internal\n", stdout)
+
+	stdout, _, err = execCmd(t, "resolve", "--profile", "production")
+	require.NoError(t, err)
+	assert.Equal(t, "HOST=prod.internal\n", stdout)
+
+	_, stderr, err := execCmd(t, "profile", "use", "production")
+	require.NoError(t, err)
+	assert.Contains(t, stderr, `branch_profiles selects profile "staging" on branch "develop", which takes precedence over active_profile`)
+}
+
+func TestResolveCmd_ProfileLocalFile(t *testing.T) {
+	dir := setupProject(t, "myapp", "HOST=localhost\nPORT=8080\nDEBUG=false\n", "PORT=3000\nDEBUG=true\n")
+	writeTestFile(t, dir, ".env.staging", "HOST=staging.internal\nPORT=9000\n")
+	writeTestFile(t, dir, ".env.staging.local", "DEBUG=verbose\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "resolve", "--profile", "staging")
+	require.NoError(t, err)
+	assert.Equal(t, "HOST=staging.internal\nPORT=3000\nDEBUG=verbose\n", stdout)
+
+	stdout, _, err = execCmd(t, "resolve")
+	require.NoError(t, err)
+	assert.Equal(t, "HOST=localhost\nPORT=3000\nDEBUG=true\n", stdout, "without a profile its local file is not loaded")
+}
+
+func TestResolveCmd_CombinedProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "myapp")
+	writeTestFile(t, dir, ".env", "HOST=localhost\nREGION=local\nDB_PASS=ref://secrets/DB_PASS\nAPI_KEY=ref://secrets/API_KEY\n")
+	writeTestFile(t, dir, ".env.production", "HOST=prod.internal\nREGION=us\n")
+	writeTestFile(t, dir, ".env.eu", "REGION=eu\n")
+	writeTestFile(t, dir, ".env.eu.local", "HOST=eu.local\n")
+	chdir(t, dir)
+
+	for _, args := range [][]string{
+		{"secret", "set", "DB_PASS", "--value", "prod-pass", "--profile", "production"},
+		{"secret", "set", "DB_PASS", "--value", "eu-pass", "--profile", "eu"},
+		{"secret", "set", "API_KEY", "--value", "prod-key", "--profile", "production"},
+	} {
+		_, _, err := execCmd(t, args...)
+		require.NoError(t, err)
+	}
+
+	stdout, _, err := execCmd(t, "resolve", "--profile", "production,eu")
+	require.NoError(t, err)
+	assert.Equal(t, "HOST=eu.local\nREGION=eu\nDB_PASS=eu-pass\nAPI_KEY=prod-key\n", stdout)
+
+	stdout, _, err = execCmd(t, "resolve", "--profile", "eu,production")
+	require.NoError(t, err)
+	assert.Equal(t, "HOST=eu.local\nREGION=us\nDB_PASS=prod-pass\nAPI_KEY=prod-key\n", stdout)
+
+	// Secrets are stored for a single profile.
+	_, _, err = execCmd(t, "secret", "set", "DB_PASS", "--value", "x", "--profile", "production,eu")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "combines several profiles")
+}
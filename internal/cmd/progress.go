@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/resolve"
+)
+
+// resolveWithProgress resolves env for the project of cfg like
+// resolve.ResolveWithProfiles, showing a spinner with the lookups of each
+// backend on stderr while resolution takes longer than output.SpinnerDelay
+// (see output.Spinner). Values are read through the project's
+// last-known-good values (see openLastKnownGood).
+func resolveWithProgress(cmd *cobra.Command, env *envfile.Env, registry *backend.Registry, cfg *config.Config, profiles []string) (*resolve.Result, error) {
+	lkg, err := openLastKnownGood(cmd, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &resolveProgress{}
+	middleware := []backend.Middleware{backend.Progress(p.report)}
+	if lkg != nil {
+		middleware = append(middleware, lkg.middleware())
+	}
+	observed, err := observeRegistry(registry, func(b backend.Backend) backend.Backend {
+		return backend.Chain(b, middleware...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	spinner := output.NewWriter(cmd).Spinner(p.String)
+	spinner.Start()
+	result, err := resolve.ResolveWithProfiles(env, observed, cfg.Project, profiles)
+	spinner.Stop()
+	if lkg != nil && err == nil {
+		lkg.finish(cmd)
+	}
+	return result, err
+}
+
+// observeRegistry returns a registry with the backends of registry wrapped
+// in middleware, and the same namespaces, aliases, and access rule.
+// Closing it closes the backends of registry.
+func observeRegistry(registry *backend.Registry, middleware backend.Middleware) (*backend.Registry, error) {
+	observed := backend.NewRegistry()
+	for _, name := range registry.Names() {
+		if err := observed.Register(backend.Chain(registry.Backend(name), middleware)); err != nil {
+			return nil, err
+		}
+		if err := observed.SetNamespace(name, registry.Namespace(name)); err != nil {
+			return nil, err
+		}
+	}
+	for alias, targets := range registry.Aliases() {
+		if err := observed.SetAlias(alias, targets); err != nil {
+			return nil, err
+		}
+	}
+	observed.SetAccess(registry.Access())
+	return observed, nil
+}
+
+// resolveProgress counts the keys each backend was asked for and has
+// answered during a resolve.
+type resolveProgress struct {
+	mu    sync.Mutex
+	names []string
+	asked map[string]int
+	done  map[string]int
+}
+
+// report is the backend.Progress callback.
+func (p *resolveProgress) report(name string, keys int, done bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.asked == nil {
+		p.asked, p.done = make(map[string]int), make(map[string]int)
+	}
+	if done {
+		p.done[name] += keys
+		return
+	}
+	if _, ok := p.asked[name]; !ok {
+		p.names = append(p.names, name)
+	}
+	p.asked[name] += keys
+}
+
+// String describes the progress, e.g.
+// "resolving secrets: vault 3/4, keychain 2/2".
+func (p *resolveProgress) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	counts := make([]string, len(p.names))
+	for i, name := range p.names {
+		counts[i] = fmt.Sprintf("%s %d/%d", name, p.done[name], p.asked[name])
+	}
+	if len(counts) == 0 {
+		return "resolving secrets"
+	}
+	return "resolving secrets: " + strings.Join(counts, ", ")
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xcke/envref/internal/backend"
+)
+
+func TestResolveProgress(t *testing.T) {
+	p := &resolveProgress{}
+	assert.Equal(t, "resolving secrets", p.String())
+
+	p.report("vault", 3, false)
+	p.report("keychain", 1, false)
+	p.report("vault", 1, false)
+	p.report("vault", 3, true)
+	assert.Equal(t, "resolving secrets: vault 3/4, keychain 0/1", p.String())
+}
+
+func TestObserveRegistry(t *testing.T) {
+	registry := backend.NewRegistry()
+	for _, name := range []string{"primary", "fallback"} {
+		require.NoError(t, registry.Register(backend.NewMemoryBackend(name)))
+	}
+	require.NoError(t, registry.SetNamespace("fallback", "{project}-{key}"))
+	require.NoError(t, registry.SetAlias("shared", []string{"fallback"}))
+	require.NoError(t, registry.Backend("fallback").Set("myapp-api_key", "s3cret"))
+
+	p := &resolveProgress{}
+	observed, err := observeRegistry(registry, backend.Progress(p.report))
+	require.NoError(t, err)
+	assert.Equal(t, registry.Names(), observed.Names())
+	assert.Equal(t, "{project}-{key}", observed.Namespace("fallback"))
+	assert.Equal(t, registry.Aliases(), observed.Aliases())
+
+	ns, err := observed.Namespaced("fallback", "myapp", "")
+	require.NoError(t, err)
+	value, err := ns.Get("api_key")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", value)
+	assert.Equal(t, "resolving secrets: fallback 1/1", p.String())
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/remote"
+)
+
+// loadRemoteEnvFile downloads and parses the env file at rawURL with the
+// remote settings of cfg (nil for the defaults). Remote files are always
+// required: a file that cannot be fetched or verified is an error.
+func loadRemoteEnvFile(cmd *cobra.Command, rawURL string, cfg *config.Config) (*envfile.Env, []parser.Warning, error) {
+	f, err := remoteFetcher(cmd, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := f.Fetch(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return envfile.Read(bytes.NewReader(data))
+}
+
+// remoteFetcher returns a fetcher for remote env files configured by the
+// remote settings of cfg, caching in the user's cache directory and
+// warning on cmd when a stale copy is used.
+func remoteFetcher(cmd *cobra.Command, cfg *config.Config) (*remote.Fetcher, error) {
+	var settings config.RemoteConfig
+	if cfg != nil {
+		settings = cfg.Remote
+	}
+	w := output.NewWriter(cmd)
+	f, err := settings.Fetcher(func(format string, args ...any) { w.Warn(format, args...) })
+	if err != nil {
+		return nil, withExitCode(exitConfig, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xcke/envref/internal/config"
+)
+
+func TestResolveCmd_RemoteEnvFile(t *testing.T) {
+	const baseline = "LOG_LEVEL=info\nREGION=eu-west-1\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/service.env" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(baseline))
+	}))
+	defer srv.Close()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	sum := sha256.Sum256([]byte(baseline))
+	url := srv.URL + "/service.env#sha256=" + hex.EncodeToString(sum[:])
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, config.FullFileName, "project: app\nenv_files:\n  - "+url+"\n  - .env\n")
+	writeTestFile(t, dir, ".env", "LOG_LEVEL=debug\n")
+	chdir(t, dir)
+
+	stdout, stderr, err := execCmd(t, "resolve")
+	require.NoError(t, err, stderr)
+	assert.Equal(t, "LOG_LEVEL=debug\nREGION=eu-west-1\n", stdout)
+
+	// The cached copy is used when the server is gone.
+	srv.Close()
+	stdout, stderr, err = execCmd(t, "resolve")
+	require.NoError(t, err, stderr)
+	assert.Equal(t, "LOG_LEVEL=debug\nREGION=eu-west-1\n", stdout)
+}
+
+func TestResolveCmd_RemoteEnvFileChecksumMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("LOG_LEVEL=info\n"))
+	}))
+	defer srv.Close()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	url := srv.URL + "/service.env#sha256=9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	writeTestFile(t, dir, config.FullFileName, "project: app\nenv_file: "+url+"\n")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "resolve")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/ref"
+)
+
+// refPolicy enforces the require_refs patterns of a project config. A nil
+// *refPolicy enforces nothing.
+type refPolicy struct {
+	cfg        *config.Config
+	projectDir string
+}
+
+// newRefPolicy returns the policy of cfg, or nil if it declares no
+// patterns.
+func newRefPolicy(cfg *config.Config, projectDir string) *refPolicy {
+	if cfg == nil || len(cfg.RequireRefs) == 0 {
+		return nil
+	}
+	return &refPolicy{cfg: cfg, projectDir: projectDir}
+}
+
+// loadRefPolicy returns the policy of the project config found from the
+// working directory, or nil if there is no config.
+func loadRefPolicy() (*refPolicy, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, projectDir, err := config.Load(cwd)
+	if errors.Is(err, config.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	return newRefPolicy(cfg, projectDir), nil
+}
+
+// committed reports whether the env file at path is, or would be, committed
+// to git: it is not the project's local override file, and git does not
+// ignore it. Outside a git work tree only the local file is exempt.
+func (p *refPolicy) committed(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return true
+	}
+	if abs == filepath.Join(p.projectDir, p.cfg.LocalFile) {
+		return false
+	}
+	// check-ignore exits 0 for ignored paths, 1 for others, and 128
+	// outside a work tree.
+	err = exec.Command("git", "-C", filepath.Dir(abs), "check-ignore", "-q", abs).Run()
+	return err != nil
+}
+
+// violation returns the require_refs pattern that value breaks for key, if
+// any. Empty values, references in any configured scheme, and values that
+// interpolate other variables are allowed.
+func (p *refPolicy) violation(key, value string) (pattern string, violated bool) {
+	if p == nil || value == "" || strings.Contains(value, "${") {
+		return "", false
+	}
+	pattern, ok := p.cfg.RequiresRef(key)
+	if !ok || ref.IsRef(ref.Schemes(p.cfg.RefSchemes).Rewrite(value)) || ref.IsEncrypted(value) {
+		return "", false
+	}
+	return pattern, true
+}
+
+// checkValue returns an error if writing value for key to the env file at
+// path would break the policy.
+func (p *refPolicy) checkValue(path, key, value string) error {
+	pattern, violated := p.violation(key, value)
+	if !violated || !p.committed(path) {
+		return nil
+	}
+	return fmt.Errorf("%s matches require_refs pattern %q and must be a %s reference in %s; store it with \"envref secret set %s\", encrypt it with --encrypt, or write it to the local file with --local",
+		key, pattern, ref.Prefix, filepath.Base(path), key)
+}
+
+// checkFile returns an issue for every entry of the env file at path that
+// breaks the policy. Files that are not committed, or do not exist, have
+// none.
+func (p *refPolicy) checkFile(path string) ([]issue, error) {
+	if p == nil || !fileExists(path) || !p.committed(path) {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	entries, _, err := parser.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	var issues []issue
+	for _, entry := range entries {
+		if pattern, violated := p.violation(entry.Key, entry.Value); violated {
+			issues = append(issues, issue{
+				File:    path,
+				Line:    entry.Line,
+				Key:     entry.Key,
+				Message: fmt.Sprintf("%s has a plaintext value, but keys matching %q must be %s references", entry.Key, pattern, ref.Prefix),
+			})
+		}
+	}
+	return issues, nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/config"
+)
+
+// writeRequireRefsProject writes a project whose config requires *_TOKEN
+// and *_KEY values to be references.
+func writeRequireRefsProject(t *testing.T, dir string) {
+	t.Helper()
+	writeTestFile(t, dir, config.FullFileName, "project: app\nrequire_refs:\n  - \"*_TOKEN\"\n  - \"*_KEY\"\n")
+	writeTestFile(t, dir, ".gitignore", ".env.local\n")
+}
+
+func TestSetCmd_RequireRefs(t *testing.T) {
+	dir := t.TempDir()
+	writeRequireRefsProject(t, dir)
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "set", "GITHUB_TOKEN=ghp_plaintext")
+	if err == nil || !strings.Contains(err.Error(), `require_refs pattern "*_TOKEN"`) {
+		t.Fatalf("expected require_refs error, got %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"set", "GITHUB_TOKEN=ref://secrets/github_token"},
+		{"set", "GITHUB_TOKEN=ghp_plaintext", "--local"},
+		{"set", "DB_HOST=localhost"},
+		{"set", "API_KEY="},
+	} {
+		if _, _, err := execCmd(t, args...); err != nil {
+			t.Errorf("%v: %v", args, err)
+		}
+	}
+}
+
+func TestDoctorCmd_RequireRefs(t *testing.T) {
+	dir := t.TempDir()
+	writeRequireRefsProject(t, dir)
+	writeTestFile(t, dir, ".env", "DB_HOST=localhost\nAPI_KEY=sk-plaintext\nGITHUB_TOKEN=ref://secrets/github_token\n")
+	writeTestFile(t, dir, ".env.local", "STRIPE_KEY=sk-local-override\n")
+	chdir(t, dir)
+
+	_, stderr, err := execCmd(t, "lint", "--skip-backends")
+	if err == nil {
+		t.Fatal("expected issues")
+	}
+	if !strings.Contains(stderr, `line 2: API_KEY has a plaintext value, but keys matching "*_KEY"`) {
+		t.Errorf("expected API_KEY issue, got %q", stderr)
+	}
+	if strings.Contains(stderr, "STRIPE_KEY") || strings.Contains(stderr, "GITHUB_TOKEN") {
+		t.Errorf("only API_KEY should be reported, got %q", stderr)
+	}
+}
+
+func TestDoctorCmd_RequireRefs_GitIgnored(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	writeRequireRefsProject(t, dir)
+	writeTestFile(t, dir, ".gitignore", ".env\n.env.local\n")
+	writeTestFile(t, dir, ".env", "API_KEY=sk-plaintext\n")
+	chdir(t, dir)
+	if out, err := exec.Command("git", "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+
+	if _, stderr, err := execCmd(t, "doctor", "--skip-backends"); err != nil {
+		t.Fatalf("ignored .env should not be checked: %v\n%s", err, stderr)
+	}
+}
+
+func TestResolveCmd_RequireRefsWarning(t *testing.T) {
+	dir := t.TempDir()
+	writeRequireRefsProject(t, dir)
+	writeTestFile(t, dir, ".env", "API_KEY=sk-plaintext\n")
+	chdir(t, dir)
+
+	stdout, stderr, err := execCmd(t, "resolve")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if !strings.Contains(stdout, "API_KEY=sk-plaintext") {
+		t.Errorf("resolve should still output the value, got %q", stdout)
+	}
+	if !strings.Contains(stderr, `.env:1: API_KEY has a plaintext value`) {
+		t.Errorf("expected warning, got %q", stderr)
+	}
+}
@@ -13,22 +13,25 @@ import (
 	"github.com/xcke/envref/internal/config"
 	"github.com/xcke/envref/internal/envfile"
 	"github.com/xcke/envref/internal/output"
-	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/remote"
 	"github.com/xcke/envref/internal/resolve"
+	"github.com/xcke/envref/internal/schema"
 )
 
 // newResolveCmd creates the resolve subcommand.
 func newResolveCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "resolve",
+		Use:   "resolve [-]",
 		Short: "Resolve all references and output fully resolved environment",
 		Long: `Load .env and .env.local files, merge them, interpolate variables,
 resolve all ref:// secret references via configured backends, and output
 fully resolved KEY=VALUE pairs to stdout.
 
 When a profile is active (via --profile flag or active_profile in config),
-a profile-specific env file is loaded between .env and .env.local:
-  .env ← .env.<profile> ← .env.local
+a profile-specific env file is loaded between .env and .env.local, and its
+local override file last:
+  .env ← .env.<profile> ← .env.local ← .env.<profile>.local
 
 By default, output is in KEY=VALUE format (one per line). Use --direnv
 to output in direnv-compatible format (export KEY=VALUE), or use --format
@@ -37,19 +40,52 @@ to select from plain, json, shell, or table.
 Use --strict to suppress output entirely if any reference fails to resolve.
 This is useful in CI pipelines where partial output is unsafe.
 
+Use --terraform-json to output a flat JSON object of strings, the format
+Terraform's external data source reads. It implies --strict, since Terraform
+fails on any error anyway:
+
+  data "external" "env" {
+    program     = ["envref", "resolve", "--terraform-json"]
+    working_dir = path.module
+  }
+
+Use --strip-prefix and --prefix to rename keys on output for frameworks
+that only expose variables under a namespace: the prefix given to
+--strip-prefix is removed from the keys that start with it, then the one
+given to --prefix is prepended to every key. The prefix block of
+.envref.yaml sets defaults for both:
+
+  prefix:
+    strip: APP_
+    add: VITE_
+
 Use --watch to continuously monitor .env files for changes and re-resolve
 automatically. This is useful for development workflows where env files
 change frequently. The output is re-printed on each detected file change.
 
+Every secret read is also kept, encrypted, in a local last-known-good
+cache. When a backend cannot be reached (e.g., off VPN), its secrets are
+served from there with a warning instead of failing. Use --offline to not
+contact the backends at all and resolve from the cache only.
+
+Pass - to read the env definitions from stdin instead of the project's env
+files, so envref can resolve env content generated earlier in a pipeline.
+The project config, backends, and profile still apply.
+
 Examples:
   envref resolve                         # output KEY=VALUE pairs
   envref resolve --profile staging       # use staging profile
   envref resolve --direnv                # output export KEY=VALUE for direnv
   envref resolve --format json           # output as JSON array
   envref resolve --strict                # fail with no output if any ref fails
+  envref resolve --terraform-json        # output for a Terraform external data source
+  envref resolve --prefix VITE_          # output VITE_API_URL for API_URL
+  envref resolve --strip-prefix APP_     # output HOST for APP_HOST
   envref resolve --watch                 # re-resolve on file changes
+  envref resolve --offline               # use last-known-good values only
+  ./gen-env | envref resolve -           # resolve env content from stdin
   eval "$(envref resolve --direnv)"      # inject into current shell`,
-		Args: cobra.NoArgs,
+		Args: stdinArg,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			setVaultCmdContext(cmd)
 		},
@@ -57,15 +93,26 @@ Examples:
 			clearVaultCmdContext()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			fromStdin := len(args) == 1
 			direnv, _ := cmd.Flags().GetBool("direnv")
 			profile, _ := cmd.Flags().GetString("profile")
 			formatStr, _ := cmd.Flags().GetString("format")
 			strict, _ := cmd.Flags().GetBool("strict")
 			watch, _ := cmd.Flags().GetBool("watch")
+			if terraform, _ := cmd.Flags().GetBool("terraform-json"); terraform {
+				if direnv || watch || cmd.Flags().Changed("format") {
+					return fmt.Errorf("--terraform-json cannot be combined with --direnv, --format, or --watch")
+				}
+				formatStr = string(FormatTerraform)
+				strict = true
+			}
 			if watch {
+				if fromStdin {
+					return fmt.Errorf("--watch cannot be combined with reading stdin")
+				}
 				return runResolveWatch(cmd, direnv, profile, formatStr, strict)
 			}
-			return runResolve(cmd, direnv, profile, formatStr, strict)
+			return runResolve(cmd, direnv, profile, formatStr, strict, fromStdin)
 		},
 	}
 
@@ -73,20 +120,25 @@ Examples:
 	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
 	cmd.Flags().String("format", "plain", "output format: plain, json, shell, table")
 	cmd.Flags().Bool("strict", false, "fail with no output if any reference cannot be resolved")
+	cmd.Flags().Bool("terraform-json", false, "output a JSON object for Terraform's external data source (implies --strict)")
 	cmd.Flags().BoolP("watch", "w", false, "watch .env files for changes and re-resolve automatically")
+	cmd.Flags().String("prefix", "", "prepend a prefix to every output key (overrides prefix.add in config)")
+	cmd.Flags().String("strip-prefix", "", "remove a prefix from the output keys that have it (overrides prefix.strip in config)")
+	cmd.Flags().Bool("offline", false, "resolve secrets from last-known-good values only, without contacting backends")
 
 	return cmd
 }
 
-// runResolve implements the resolve command logic.
-func runResolve(cmd *cobra.Command, direnv bool, profileOverride, formatStr string, strict bool) error {
+// runResolve implements the resolve command logic. With fromStdin, the env
+// definitions are read from stdin instead of the project's env layers.
+func runResolve(cmd *cobra.Command, direnv bool, profileOverride, formatStr string, strict, fromStdin bool) error {
 	w := output.NewWriter(cmd)
 
 	// --direnv is a shorthand for --format shell.
 	if direnv {
 		formatStr = "shell"
 	}
-	format, err := parseFormat(formatStr)
+	format, err := parseResolveFormat(formatStr)
 	if err != nil {
 		return err
 	}
@@ -103,21 +155,20 @@ func runResolve(cmd *cobra.Command, direnv bool, profileOverride, formatStr stri
 	}
 
 	w.Debug("config loaded from %s/%s\n", projectDir, config.FullFileName)
+	applyPrefixFlags(cmd, cfg)
 
-	// Resolve file paths relative to the project root.
-	envPath := resolveFilePath(projectDir, cfg.EnvFile)
-	localPath := resolveFilePath(projectDir, cfg.LocalFile)
+	if err := runHook(cmd, hookPreResolve, cfg.Hooks, projectDir, nil); err != nil {
+		return err
+	}
 
-	// Determine the active profile and resolve its env file path.
-	var profilePath string
+	// Load and merge the env layers for the active profile.
 	profile := cfg.EffectiveProfile(profileOverride)
-	if profile != "" {
-		profilePath = resolveFilePath(projectDir, cfg.ProfileEnvFile(profile))
-		w.Verbose("using profile %q\n", profile)
+	var env *envfile.Env
+	if fromStdin {
+		env, err = loadEnvLayers(cmd, []string{stdinPath}, stdinPath, cfg)
+	} else {
+		env, err = loadProjectEnv(cmd, cfg, projectDir, profile)
 	}
-
-	// Load and merge env files.
-	env, err := loadAndMergeEnv(cmd, envPath, profilePath, localPath)
 	if err != nil {
 		return err
 	}
@@ -126,15 +177,19 @@ func runResolve(cmd *cobra.Command, direnv bool, profileOverride, formatStr stri
 
 	// If no refs (including embedded nested refs), just output without backend resolution.
 	if !env.HasAnyRefs() {
-		return outputEntries(cmd, envToEntries(env), format)
+		entries := envToEntries(env)
+		if err := runHook(cmd, hookPostResolve, cfg.Hooks, projectDir, entries); err != nil {
+			return err
+		}
+		return outputCheckedEntries(cmd, env, configSchema(cfg), cfg.Prefix, entries, format, strict)
 	}
 
 	// Build the backend registry.
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("ref:// references found but no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("ref:// references found but no backends configured in %s", config.FullFileName))
 	}
 
-	registry, err := buildRegistry(cfg)
+	registry, err := buildResolveRegistry(cfg)
 	if err != nil {
 		return fmt.Errorf("initializing backends: %w", err)
 	}
@@ -143,10 +198,11 @@ func runResolve(cmd *cobra.Command, direnv bool, profileOverride, formatStr stri
 	w.Debug("registered %d backend(s)\n", len(cfg.Backends))
 
 	// Resolve references (with profile-scoped fallback if profile is active).
-	result, err := resolve.ResolveWithProfile(env, registry, cfg.Project, profile)
+	result, err := resolveWithProgress(cmd, env, registry, cfg, cfg.ProfileChain(profile))
 	if err != nil {
 		return fmt.Errorf("resolving references: %w", err)
 	}
+	auditRefReads(cfg, projectDir, profile, env, result)
 
 	// Report resolution errors to stderr.
 	for _, keyErr := range result.Errors {
@@ -155,21 +211,34 @@ func runResolve(cmd *cobra.Command, direnv bool, profileOverride, formatStr stri
 
 	// In strict mode, suppress all output if any reference failed.
 	if strict && !result.Resolved() {
-		return fmt.Errorf("%d reference(s) could not be resolved (strict mode: no output produced)", len(result.Errors))
+		return withExitCode(unresolvedExitCode(result.Errors), fmt.Errorf("%d reference(s) could not be resolved (strict mode: no output produced)", len(result.Errors)))
+	}
+
+	if err := runHook(cmd, hookPostResolve, cfg.Hooks, projectDir, result.Entries); err != nil {
+		return err
 	}
 
 	// Output resolved entries.
-	if err := outputEntries(cmd, result.Entries, format); err != nil {
+	if err := outputCheckedEntries(cmd, env, configSchema(cfg), cfg.Prefix, result.Entries, format, strict); err != nil {
 		return err
 	}
 
 	if !result.Resolved() {
-		return fmt.Errorf("%d reference(s) could not be resolved", len(result.Errors))
+		return withExitCode(unresolvedExitCode(result.Errors), fmt.Errorf("%d reference(s) could not be resolved", len(result.Errors)))
 	}
 
 	return nil
 }
 
+// parseResolveFormat is parseFormat for resolve, which also accepts the
+// Terraform format set by --terraform-json.
+func parseResolveFormat(s string) (OutputFormat, error) {
+	if OutputFormat(s) == FormatTerraform {
+		return FormatTerraform, nil
+	}
+	return parseFormat(s)
+}
+
 // runResolveWatch implements the resolve --watch mode. It performs an initial
 // resolve, then watches the relevant .env files for changes and re-resolves
 // on each detected change. File system events are debounced to avoid redundant
@@ -195,17 +264,11 @@ func runResolveWatch(cmd *cobra.Command, direnv bool, profileOverride, formatStr
 		return fmt.Errorf("loading config: %w", err)
 	}
 
-	envPath := resolveFilePath(projectDir, cfg.EnvFile)
-	localPath := resolveFilePath(projectDir, cfg.LocalFile)
-
-	var profilePath string
+	applyPrefixFlags(cmd, cfg)
 	profile := cfg.EffectiveProfile(profileOverride)
-	if profile != "" {
-		profilePath = resolveFilePath(projectDir, cfg.ProfileEnvFile(profile))
-	}
 
 	// Perform the initial resolve.
-	if err := resolveAndOutput(cmd, cfg, envPath, profilePath, localPath, profile, format, strict); err != nil {
+	if err := resolveAndOutput(cmd, cfg, projectDir, profile, format, strict); err != nil {
 		// In watch mode, print the error but continue watching.
 		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: %s\n", err)
 	}
@@ -218,7 +281,7 @@ func runResolveWatch(cmd *cobra.Command, direnv bool, profileOverride, formatStr
 	defer func() { _ = watcher.Close() }()
 
 	// Watch the env files that exist.
-	watchPaths := collectWatchPaths(envPath, profilePath, localPath)
+	watchPaths := collectWatchPaths(projectEnvPaths(cfg, projectDir, profile)...)
 	for _, p := range watchPaths {
 		if err := watcher.Add(p); err != nil {
 			w.Verbose("cannot watch %s: %v\n", p, err)
@@ -279,7 +342,7 @@ func runResolveWatch(cmd *cobra.Command, direnv bool, profileOverride, formatStr
 				_ = watcher.Add(p)
 			}
 
-			if err := resolveAndOutput(cmd, cfg, envPath, profilePath, localPath, profile, format, strict); err != nil {
+			if err := resolveAndOutput(cmd, cfg, projectDir, profile, format, strict); err != nil {
 				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: %s\n", err)
 			}
 
@@ -292,47 +355,61 @@ func runResolveWatch(cmd *cobra.Command, direnv bool, profileOverride, formatStr
 	}
 }
 
-// resolveAndOutput runs the full resolve pipeline and outputs the result.
-// It is used by the watch loop to re-resolve on each file change.
-func resolveAndOutput(cmd *cobra.Command, cfg *config.Config, envPath, profilePath, localPath, profile string, format OutputFormat, strict bool) error {
-	env, err := loadAndMergeEnv(cmd, envPath, profilePath, localPath)
+// resolveAndOutput runs the full resolve pipeline, including hooks, and
+// outputs the result. It is used by the watch loop to re-resolve on each
+// file change.
+func resolveAndOutput(cmd *cobra.Command, cfg *config.Config, projectDir, profile string, format OutputFormat, strict bool) error {
+	if err := runHook(cmd, hookPreResolve, cfg.Hooks, projectDir, nil); err != nil {
+		return err
+	}
+
+	env, err := loadProjectEnv(cmd, cfg, projectDir, profile)
 	if err != nil {
 		return err
 	}
 
 	if !env.HasAnyRefs() {
-		return outputEntries(cmd, envToEntries(env), format)
+		entries := envToEntries(env)
+		if err := runHook(cmd, hookPostResolve, cfg.Hooks, projectDir, entries); err != nil {
+			return err
+		}
+		return outputCheckedEntries(cmd, env, configSchema(cfg), cfg.Prefix, entries, format, strict)
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("ref:// references found but no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("ref:// references found but no backends configured in %s", config.FullFileName))
 	}
 
-	registry, err := buildRegistry(cfg)
+	registry, err := buildResolveRegistry(cfg)
 	if err != nil {
 		return fmt.Errorf("initializing backends: %w", err)
 	}
 	defer registry.CloseAll()
 
-	result, err := resolve.ResolveWithProfile(env, registry, cfg.Project, profile)
+	result, err := resolveWithProgress(cmd, env, registry, cfg, cfg.ProfileChain(profile))
 	if err != nil {
 		return fmt.Errorf("resolving references: %w", err)
 	}
+	auditRefReads(cfg, projectDir, profile, env, result)
 
 	for _, keyErr := range result.Errors {
 		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: %s\n", keyErr.Error())
 	}
 
 	if strict && !result.Resolved() {
-		return fmt.Errorf("%d reference(s) could not be resolved (strict mode: no output produced)", len(result.Errors))
+		return withExitCode(unresolvedExitCode(result.Errors), fmt.Errorf("%d reference(s) could not be resolved (strict mode: no output produced)", len(result.Errors)))
 	}
 
-	if err := outputEntries(cmd, result.Entries, format); err != nil {
+	if err := runHook(cmd, hookPostResolve, cfg.Hooks, projectDir, result.Entries); err != nil {
+		return err
+	}
+
+	if err := outputCheckedEntries(cmd, env, configSchema(cfg), cfg.Prefix, result.Entries, format, strict); err != nil {
 		return err
 	}
 
 	if !result.Resolved() {
-		return fmt.Errorf("%d reference(s) could not be resolved", len(result.Errors))
+		return withExitCode(unresolvedExitCode(result.Errors), fmt.Errorf("%d reference(s) could not be resolved", len(result.Errors)))
 	}
 
 	return nil
@@ -355,61 +432,134 @@ func collectWatchPaths(paths ...string) []string {
 
 // resolveFilePath resolves a potentially relative file path against the project directory.
 func resolveFilePath(projectDir, filePath string) string {
-	if strings.HasPrefix(filePath, "/") {
+	if strings.HasPrefix(filePath, "/") || remote.IsURL(filePath) {
 		return filePath
 	}
 	return projectDir + "/" + filePath
 }
 
+// projectEnvPaths returns the env layers configured for profile as paths
+// relative to projectDir, lowest precedence first (see Config.EnvLayers).
+func projectEnvPaths(cfg *config.Config, projectDir, profile string) []string {
+	layers := cfg.EnvLayers(profile)
+	paths := make([]string, len(layers))
+	for i, layer := range layers {
+		paths[i] = resolveFilePath(projectDir, layer)
+	}
+	return paths
+}
+
+// loadProjectEnv loads and merges the env layers configured for profile.
+// The primary env_file must exist; every other layer is optional. Plaintext
+// values that break the require_refs policy are reported as warnings.
+func loadProjectEnv(cmd *cobra.Command, cfg *config.Config, projectDir, profile string) (*envfile.Env, error) {
+	w := output.NewWriter(cmd)
+	if profile != "" {
+		w.Verbose("using profile %q\n", profile)
+		// A profile without an env file may only scope secrets, so only
+		// a likely typo is worth a warning.
+		for _, name := range cfg.ProfileChain(profile) {
+			if hint := suggestProfile(cfg, projectDir, name); hint != "" {
+				w.Warn("profile %q is not in %s and has no env file%s\n", name, config.FullFileName, hint)
+			}
+		}
+	}
+	paths := projectEnvPaths(cfg, projectDir, profile)
+	env, err := loadEnvLayers(cmd, paths, resolveFilePath(projectDir, cfg.EnvFile), cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := newRefPolicy(cfg, projectDir)
+	for i, layer := range cfg.EnvLayers(profile) {
+		issues, _ := policy.checkFile(paths[i])
+		for _, iss := range issues {
+			w.Warn("%s:%d: %s\n", layer, iss.Line, iss.Message)
+		}
+	}
+	return env, nil
+}
+
 // loadAndMergeEnv loads the base env file, an optional profile-specific env
 // file, and the local override file, merges them in order (base ← profile ←
-// local), and interpolates variables.
+// local), rewrites the ref schemes of cfg, interpolates variables, and
+// applies its key aliases. A nil cfg has no schemes or aliases.
 //
 // The profilePath parameter is optional — pass an empty string to skip the
 // profile layer (backwards-compatible with the two-layer merge).
-func loadAndMergeEnv(cmd *cobra.Command, envPath, profilePath, localPath string) (*envfile.Env, error) {
-	w := output.NewWriter(cmd)
+func loadAndMergeEnv(cmd *cobra.Command, cfg *config.Config, envPath, profilePath, localPath string) (*envfile.Env, error) {
+	return loadEnvLayers(cmd, []string{envPath, profilePath, localPath}, envPath, cfg)
+}
 
-	w.Verbose("loading %s\n", envPath)
-	base, warnings, err := envfile.Load(envPath)
-	if err != nil {
-		return nil, fmt.Errorf("loading %s: %w", envPath, err)
-	}
-	printWarnings(cmd, envPath, warnings)
-	w.Debug("loaded %d entries from %s\n", base.Len(), envPath)
+// loadEnvLayers loads each env file in paths and merges them in order, later
+// files winning on conflicts, then rewrites the ref schemes and enc://
+// values of cfg, interpolates variables, and applies the key aliases of
+// cfg, warning about deprecated names that are still set. A nil cfg has no
+// schemes or aliases.
+// The file at required must exist; other missing files are skipped, as are
+// empty paths. A path of stdinPath reads the standard input of cmd.
+func loadEnvLayers(cmd *cobra.Command, paths []string, required string, cfg *config.Config) (*envfile.Env, error) {
+	w := output.NewWriter(cmd)
 
-	// Optional profile layer: .env.<profile> (e.g., .env.staging).
-	var profile *envfile.Env
-	if profilePath != "" {
-		w.Verbose("loading profile %s\n", profilePath)
-		var profileWarnings []parser.Warning
-		profile, profileWarnings, err = envfile.LoadOptional(profilePath)
+	merged := envfile.NewEnv()
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		name := envPathName(path)
+		w.Verbose("loading %s\n", name)
+		layer, warnings, err := loadEnvFile(cmd, path, path == required, cfg)
 		if err != nil {
-			return nil, fmt.Errorf("loading %s: %w", profilePath, err)
+			return nil, fmt.Errorf("loading %s: %w", name, err)
+		}
+		printWarnings(cmd, name, warnings)
+		w.Debug("loaded %d entries from %s\n", layer.Len(), name)
+		merged = envfile.Merge(merged, layer)
+	}
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+
+	// Rewrite configured alternative schemes (secret://, op://, ...) to
+	// ref:// before interpolation copies values between keys.
+	merged.ApplySchemes(ref.Schemes(cfg.RefSchemes))
+	merged.ApplyEncryption(cfg.Encryption.Key)
+	if cfg.Encryption.Key == "" {
+		for _, entry := range merged.All() {
+			if ref.IsEncrypted(entry.Value) {
+				w.Warn("%s is an %s value, but no encryption.key is set in %s\n", entry.Key, ref.EncPrefix, config.FullFileName)
+			}
 		}
-		printWarnings(cmd, profilePath, profileWarnings)
-	}
-
-	w.Verbose("loading %s\n", localPath)
-	local, localWarnings, err := envfile.LoadOptional(localPath)
-	if err != nil {
-		return nil, fmt.Errorf("loading %s: %w", localPath, err)
 	}
-	printWarnings(cmd, localPath, localWarnings)
-
-	// Merge: base ← profile ← local (later layers win on conflicts).
-	if profile != nil && profile.Len() > 0 {
-		merged := envfile.Merge(base, profile, local)
+	if cfg.InterpolateSystemEnv {
+		envfile.InterpolateWithEnviron(merged, os.Environ())
+	} else {
 		envfile.Interpolate(merged)
-		return merged, nil
 	}
 
-	merged := envfile.Merge(base, local)
-	envfile.Interpolate(merged)
+	for _, alias := range envfile.ApplyKeyAliases(merged, cfg.KeyAliases) {
+		key, _ := cfg.DeprecatedKey(alias)
+		w.Warn("%s is a deprecated name for %s; rename it in your env files\n", alias, key)
+	}
 
 	return merged, nil
 }
 
+// workingConfig returns the project config found from the working
+// directory, or nil without a loadable config; commands that need the
+// config report its errors themselves.
+func workingConfig() *config.Config {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	cfg, _, err := config.Load(cwd)
+	if err != nil {
+		return nil
+	}
+	return cfg
+}
+
 // envToEntries converts an Env to resolve.Entry slice for output.
 func envToEntries(env *envfile.Env) []resolve.Entry {
 	all := env.All()
@@ -433,16 +583,62 @@ func outputEntries(cmd *cobra.Command, entries []resolve.Entry, format OutputFor
 	return formatKVPairs(cmd.OutOrStdout(), pairs, format)
 }
 
-// shellQuote wraps a value in single quotes for safe shell usage.
-// Single quotes inside the value are escaped as '\'' (end quote, escaped quote, start quote).
-func shellQuote(s string) string {
-	if s == "" {
-		return "''"
+// outputCheckedEntries validates entries against the "# @type:" annotations
+// in env and the config schema s (if any), reports violations to stderr, and
+// writes the entries to stdout. In strict mode nothing is written when any
+// value fails validation.
+func outputCheckedEntries(cmd *cobra.Command, env *envfile.Env, s *schema.Schema, prefix config.PrefixConfig, entries []resolve.Entry, format OutputFormat, strict bool) error {
+	typeErrs := append(checkResolvedTypes(env, entries), checkSchema(s, env, entries)...)
+	for _, typeErr := range typeErrs {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: %s\n", typeErr)
+	}
+
+	if strict && len(typeErrs) > 0 {
+		return withExitCode(exitValidation, fmt.Errorf("%d value(s) failed type validation (strict mode: no output produced)", len(typeErrs)))
+	}
+
+	entries, err := prefixEntries(entries, prefix)
+	if err != nil {
+		return err
+	}
+	if err := outputEntries(cmd, entries, format); err != nil {
+		return err
+	}
+
+	if len(typeErrs) > 0 {
+		return withExitCode(exitValidation, fmt.Errorf("%d value(s) failed type validation", len(typeErrs)))
+	}
+	return nil
+}
+
+// applyPrefixFlags overrides the prefix settings of cfg with the --prefix
+// and --strip-prefix flags of cmd, when given.
+func applyPrefixFlags(cmd *cobra.Command, cfg *config.Config) {
+	if cmd.Flags().Changed("prefix") {
+		cfg.Prefix.Add, _ = cmd.Flags().GetString("prefix")
+	}
+	if cmd.Flags().Changed("strip-prefix") {
+		cfg.Prefix.Strip, _ = cmd.Flags().GetString("strip-prefix")
 	}
-	// If value contains no special characters, return as-is for readability.
-	if !strings.ContainsAny(s, " \t\n\r'\"\\$`!#&|;(){}[]<>?*~") {
-		return s
+}
+
+// prefixEntries returns entries with the keys renamed by prefix: Strip is
+// removed from the keys that start with it, then Add is prepended. It fails
+// if two keys end up with the same name.
+func prefixEntries(entries []resolve.Entry, prefix config.PrefixConfig) ([]resolve.Entry, error) {
+	if prefix.Add == "" && prefix.Strip == "" {
+		return entries, nil
+	}
+	renamed := make([]resolve.Entry, len(entries))
+	from := make(map[string]string, len(entries))
+	for i, entry := range entries {
+		key := prefix.Add + strings.TrimPrefix(entry.Key, prefix.Strip)
+		if prev, ok := from[key]; ok {
+			return nil, fmt.Errorf("keys %s and %s would both be output as %s", prev, entry.Key, key)
+		}
+		from[key] = entry.Key
+		renamed[i] = entry
+		renamed[i].Key = key
 	}
-	// Single-quote the value, escaping embedded single quotes.
-	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	return renamed, nil
 }
@@ -2,11 +2,14 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/xcke/envref/internal/config"
 	"github.com/xcke/envref/internal/resolve"
 )
 
@@ -231,3 +234,466 @@ func TestCollectWatchPaths(t *testing.T) {
 		}
 	})
 }
+
+func TestResolveCmd_TypeAnnotations(t *testing.T) {
+	t.Run("valid values pass", func(t *testing.T) {
+		dir := setupProject(t, "typed", "# @type: int\nPORT=8080\n", "")
+		chdir(t, dir)
+
+		stdout, _, err := execCmd(t, "resolve")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if stdout != "PORT=8080\n" {
+			t.Errorf("got %q", stdout)
+		}
+	})
+
+	t.Run("invalid override fails", func(t *testing.T) {
+		dir := setupProject(t, "typed", "# @type: int\nPORT=8080\n", "PORT=eighty\n")
+		chdir(t, dir)
+
+		stdout, stderr, err := execCmd(t, "resolve")
+		if err == nil {
+			t.Fatal("expected type validation error")
+		}
+		if !strings.Contains(stderr, "PORT: expected an integer") {
+			t.Errorf("stderr: got %q", stderr)
+		}
+		if stdout != "PORT=eighty\n" {
+			t.Errorf("stdout: got %q", stdout)
+		}
+	})
+
+	t.Run("strict suppresses output", func(t *testing.T) {
+		dir := setupProject(t, "typed", "# @type: bool\nDEBUG=maybe\n", "")
+		chdir(t, dir)
+
+		stdout, _, err := execCmd(t, "resolve", "--strict")
+		if err == nil {
+			t.Fatal("expected type validation error")
+		}
+		if stdout != "" {
+			t.Errorf("expected no output in strict mode, got %q", stdout)
+		}
+	})
+}
+
+func TestResolveCmd_ConfigSchema(t *testing.T) {
+	schemaCfg := "schema:\n  PORT: { type: int, required: true }\n  API_KEY: { ref: true }\n"
+
+	t.Run("valid values pass", func(t *testing.T) {
+		dir := setupProject(t, "schema", "PORT=8080\n", "")
+		appendTestFile(t, filepath.Join(dir, config.FullFileName), schemaCfg)
+		chdir(t, dir)
+
+		stdout, _, err := execCmd(t, "resolve")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if stdout != "PORT=8080\n" {
+			t.Errorf("got %q", stdout)
+		}
+	})
+
+	t.Run("violations are reported", func(t *testing.T) {
+		dir := setupProject(t, "schema", "API_KEY=sk-live\n", "")
+		appendTestFile(t, filepath.Join(dir, config.FullFileName), schemaCfg)
+		chdir(t, dir)
+
+		_, stderr, err := execCmd(t, "resolve")
+		if err == nil {
+			t.Fatal("expected schema validation error")
+		}
+		for _, want := range []string{"PORT: required key is missing", "API_KEY: must be a ref:// reference"} {
+			if !strings.Contains(stderr, want) {
+				t.Errorf("stderr missing %q: %q", want, stderr)
+			}
+		}
+	})
+}
+
+// appendTestFile appends content to an existing file.
+func appendTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("opening %s: %v", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("appending to %s: %v", path, err)
+	}
+}
+
+// allowHooks runs 'envref hooks allow' in the working directory.
+func allowHooks(t *testing.T) {
+	t.Helper()
+	if _, _, err := execCmd(t, "hooks", "allow"); err != nil {
+		t.Fatalf("hooks allow: %v", err)
+	}
+}
+
+func TestResolveCmd_Hooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use POSIX shell commands")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	t.Run("pre hook does not see resolved values", func(t *testing.T) {
+		dir := setupProject(t, "hooks", "GREETING=hello\n", "")
+		appendTestFile(t, filepath.Join(dir, config.FullFileName),
+			"hooks:\n  pre_resolve: echo \"pre:${GREETING:-unset}:$ENVREF_HOOK\" > pre.out\n  post_resolve: echo \"post:$GREETING\" > post.out\n")
+		chdir(t, dir)
+		allowHooks(t)
+
+		stdout, _, err := execCmd(t, "resolve")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if stdout != "GREETING=hello\n" {
+			t.Errorf("stdout: got %q", stdout)
+		}
+
+		pre, err := os.ReadFile(filepath.Join(dir, "pre.out"))
+		if err != nil {
+			t.Fatalf("pre hook did not run: %v", err)
+		}
+		if string(pre) != "pre:unset:pre_resolve\n" {
+			t.Errorf("pre hook output: got %q", string(pre))
+		}
+		post, err := os.ReadFile(filepath.Join(dir, "post.out"))
+		if err != nil {
+			t.Fatalf("post hook did not run: %v", err)
+		}
+		if string(post) != "post:hello\n" {
+			t.Errorf("post hook output: got %q", string(post))
+		}
+	})
+
+	t.Run("hook output goes to stderr", func(t *testing.T) {
+		dir := setupProject(t, "hooks", "A=1\n", "")
+		appendTestFile(t, filepath.Join(dir, config.FullFileName), "hooks:\n  post_resolve: echo notified\n")
+		chdir(t, dir)
+		allowHooks(t)
+
+		stdout, stderr, err := execCmd(t, "resolve")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if stdout != "A=1\n" {
+			t.Errorf("stdout: got %q", stdout)
+		}
+		if !strings.Contains(stderr, "notified") {
+			t.Errorf("stderr: got %q", stderr)
+		}
+	})
+
+	t.Run("failing pre hook aborts", func(t *testing.T) {
+		dir := setupProject(t, "hooks", "A=1\n", "")
+		appendTestFile(t, filepath.Join(dir, config.FullFileName), "hooks:\n  pre_resolve: exit 3\n")
+		chdir(t, dir)
+		allowHooks(t)
+
+		stdout, _, err := execCmd(t, "resolve")
+		if err == nil || !strings.Contains(err.Error(), "pre_resolve hook failed") {
+			t.Fatalf("expected pre_resolve hook error, got %v", err)
+		}
+		if stdout != "" {
+			t.Errorf("expected no output, got %q", stdout)
+		}
+	})
+
+	t.Run("hooks run only once allowed", func(t *testing.T) {
+		dir := setupProject(t, "hooks", "A=1\n", "")
+		cfgPath := filepath.Join(dir, config.FullFileName)
+		appendTestFile(t, cfgPath, "hooks:\n  pre_resolve: touch pre.out\n")
+		chdir(t, dir)
+		marker := filepath.Join(dir, "pre.out")
+
+		_, _, err := execCmd(t, "resolve")
+		if err == nil || !strings.Contains(err.Error(), "envref hooks allow") || exitCode(err) != exitConfig {
+			t.Fatalf("expected an untrusted hooks error, got %v", err)
+		}
+		if _, statErr := os.Stat(marker); statErr == nil {
+			t.Fatal("hook ran before it was allowed")
+		}
+
+		allowHooks(t)
+		if _, _, err := execCmd(t, "resolve"); err != nil {
+			t.Fatalf("resolve after allow: %v", err)
+		}
+		if _, statErr := os.Stat(marker); statErr != nil {
+			t.Fatalf("hook did not run after allow: %v", statErr)
+		}
+
+		// A changed hook must be allowed again.
+		_ = os.Remove(marker)
+		appendTestFile(t, cfgPath, "  post_resolve: touch post.out\n")
+		if _, _, err := execCmd(t, "resolve"); err == nil {
+			t.Fatal("expected changed hooks to need allowing again")
+		}
+		if _, statErr := os.Stat(marker); statErr == nil {
+			t.Fatal("changed hooks ran before they were allowed")
+		}
+
+		allowHooks(t)
+		if _, _, err := execCmd(t, "hooks", "deny"); err != nil {
+			t.Fatalf("hooks deny: %v", err)
+		}
+		if _, _, err := execCmd(t, "resolve"); err == nil {
+			t.Fatal("expected denied hooks to be refused")
+		}
+	})
+
+	t.Run("global hooks run without allow", func(t *testing.T) {
+		configHome := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", configHome)
+		if err := os.MkdirAll(filepath.Join(configHome, "envref"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeTestFile(t, filepath.Join(configHome, "envref"), config.GlobalFileName, "hooks:\n  pre_resolve: touch pre.out\n")
+		dir := setupProject(t, "hooks", "A=1\n", "")
+		chdir(t, dir)
+
+		if _, _, err := execCmd(t, "resolve"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "pre.out")); err != nil {
+			t.Fatalf("global hook did not run: %v", err)
+		}
+	})
+}
+
+func TestResolveCmd_RefSchemes(t *testing.T) {
+	dir := t.TempDir()
+	vaultPath := filepath.Join(dir, "test-vault.db")
+	cfgPath := writeVaultTestConfig(t, dir, "schemes", vaultPath)
+	appendTestFile(t, cfgPath, "ref_schemes:\n  secret: \"\"\n  op: vault\n")
+	writeTestFile(t, dir, ".env", "A=secret://vault/api_key\nB=op://api_key\nC=postgres://u:${secret://vault/api_key}@db\nD=https://example.com\n")
+	chdir(t, dir)
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "test-passphrase")
+
+	if _, _, err := execCmd(t, "vault", "init"); err != nil {
+		t.Fatalf("vault init: %v", err)
+	}
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "s3cret"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	stdout, stderr, err := execCmd(t, "resolve")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr %q)", err, stderr)
+	}
+	// "secret set" also appends api_key=ref://vault/api_key to .env.
+	want := "A=s3cret\nB=s3cret\nC=postgres://u:s3cret@db\nD=https://example.com\napi_key=s3cret\n"
+	if stdout != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+}
+
+func TestResolveCmd_EnvFilesLayering(t *testing.T) {
+	dir := setupProject(t, "layers", "A=env\nB=env\n", "C=local\n")
+	appendTestFile(t, filepath.Join(dir, config.FullFileName), "env_files:\n  - .env.defaults\n  - .env\n  - .env.{profile}\n  - .env.local\n")
+	writeTestFile(t, dir, ".env.defaults", "A=default\nB=default\nC=default\nD=default\n")
+	writeTestFile(t, dir, ".env.staging", "B=staging\n")
+	chdir(t, dir)
+
+	stdout, _, err := execCmd(t, "resolve")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "A=env\nB=env\nC=local\nD=default\n"; stdout != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+
+	stdout, _, err = execCmd(t, "resolve", "--profile", "staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "A=env\nB=staging\nC=local\nD=default\n"; stdout != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+
+	// The primary env_file is still required.
+	if err := os.Remove(filepath.Join(dir, ".env")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := execCmd(t, "resolve"); err == nil || !strings.Contains(err.Error(), ".env") {
+		t.Errorf("expected missing .env error, got %v", err)
+	}
+}
+
+func TestResolveCmd_TerraformJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "HOST=localhost\nAPI_KEY=ref://secrets/API_KEY\n")
+	chdir(t, dir)
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "sk-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	stdout, _, err := execCmd(t, "resolve", "--terraform-json")
+	if err != nil {
+		t.Fatalf("resolve --terraform-json: %v", err)
+	}
+	var got map[string]string
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("output is not a JSON object of strings: %v\n%s", err, stdout)
+	}
+	if len(got) != 2 || got["HOST"] != "localhost" || got["API_KEY"] != "sk-123" {
+		t.Errorf("got %v", got)
+	}
+
+	t.Run("implies strict", func(t *testing.T) {
+		writeTestFile(t, dir, ".env", "HOST=localhost\nMISSING=ref://secrets/MISSING\n")
+		stdout, _, err := execCmd(t, "resolve", "--terraform-json")
+		if err == nil {
+			t.Fatal("expected an error for an unresolved reference")
+		}
+		if stdout != "" {
+			t.Errorf("expected no output, got %q", stdout)
+		}
+	})
+
+	t.Run("rejects other output flags", func(t *testing.T) {
+		for _, flag := range []string{"--direnv", "--watch", "--format=json"} {
+			_, _, err := execCmd(t, "resolve", "--terraform-json", flag)
+			if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+				t.Errorf("%s: expected a conflict error, got %v", flag, err)
+			}
+		}
+	})
+}
+
+func TestResolveCmd_KeyAliases(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, config.FullFileName, "project: app\nkey_aliases:\n  DATABASE_URL: [DB_URL]\n  API_KEY: [OLD_API_KEY]\n")
+	writeTestFile(t, dir, ".env", "DATABASE_URL=postgres://db\nOLD_API_KEY=k\n")
+	chdir(t, dir)
+
+	stdout, stderr, err := execCmd(t, "resolve")
+	if err != nil {
+		t.Fatalf("resolve: %v\n%s", err, stderr)
+	}
+	want := "DATABASE_URL=postgres://db\nOLD_API_KEY=k\nAPI_KEY=k\nDB_URL=postgres://db\n"
+	if stdout != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+	if !strings.Contains(stderr, "OLD_API_KEY is a deprecated name for API_KEY") {
+		t.Errorf("expected a deprecation warning, got %q", stderr)
+	}
+	if strings.Contains(stderr, "DB_URL") {
+		t.Errorf("an alias that is not set should not be reported, got %q", stderr)
+	}
+}
+
+func TestResolveCmd_InterpolateSystemEnv(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".env", "CACHE_DIR=${ENVREF_TEST_HOME}/.cache\nPORT=8080\nADDR=:${PORT}\n")
+	chdir(t, dir)
+	t.Setenv("ENVREF_TEST_HOME", "/home/ada")
+	t.Setenv("PORT", "9999")
+
+	for _, tt := range []struct {
+		config, want string
+	}{
+		{"project: app\n", "CACHE_DIR=/.cache\nPORT=8080\nADDR=:8080\n"},
+		{"project: app\ninterpolate_system_env: true\n", "CACHE_DIR=/home/ada/.cache\nPORT=8080\nADDR=:8080\n"},
+	} {
+		writeTestFile(t, dir, config.FullFileName, tt.config)
+		stdout, stderr, err := execCmd(t, "resolve")
+		if err != nil {
+			t.Fatalf("resolve: %v\n%s", err, stderr)
+		}
+		if stdout != tt.want {
+			t.Errorf("with %q: got %q, want %q", tt.config, stdout, tt.want)
+		}
+	}
+}
+
+func TestResolveCmd_Prefix(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, config.FullFileName, "project: app\nprefix:\n  strip: APP_\n  add: VITE_\n")
+	writeTestFile(t, dir, ".env", "APP_API_URL=https://api\nMODE=dev\n")
+	chdir(t, dir)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"config", nil, "VITE_API_URL=https://api\nVITE_MODE=dev\n"},
+		{"flags override config", []string{"--prefix", "REACT_APP_", "--strip-prefix", ""}, "REACT_APP_APP_API_URL=https://api\nREACT_APP_MODE=dev\n"},
+		{"strip only", []string{"--prefix", ""}, "API_URL=https://api\nMODE=dev\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := execCmd(t, append([]string{"resolve"}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("resolve: %v\n%s", err, stderr)
+			}
+			if stdout != tt.want {
+				t.Errorf("got %q, want %q", stdout, tt.want)
+			}
+		})
+	}
+
+	t.Run("collision", func(t *testing.T) {
+		writeTestFile(t, dir, ".env", "APP_MODE=prod\nMODE=dev\n")
+		_, _, err := execCmd(t, "resolve", "--prefix", "")
+		if err == nil || !strings.Contains(err.Error(), "keys APP_MODE and MODE would both be output as MODE") {
+			t.Errorf("expected a collision error, got %v", err)
+		}
+	})
+}
+
+func TestResolveCmd_SelfRef(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "DB_URL=ref://secrets/db_url\nREAD_URL=ref://self/DB_URL\n")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "secret", "set", "db_url", "--value", "postgres://db", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	stdout, stderr, err := execCmd(t, "resolve")
+	if err != nil {
+		t.Fatalf("resolve: %v\n%s", err, stderr)
+	}
+	if want := "DB_URL=postgres://db\nREAD_URL=postgres://db\n"; stdout != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+
+	writeTestFile(t, dir, ".env", "READ_URL=ref://self/DB_URL\n")
+	stdout, stderr, err = execCmd(t, "resolve", "--strict")
+	if err == nil {
+		t.Fatal("expected an error for a reference to an undefined key")
+	}
+	if stdout != "" {
+		t.Errorf("expected no output in strict mode, got %q", stdout)
+	}
+	if !strings.Contains(stderr+err.Error(), "DB_URL is not defined") {
+		t.Errorf("expected the undefined key to be reported, got %q / %v", stderr, err)
+	}
+}
+
+func TestResolveCmd_Expand(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "# @expand:\nRDS=ref://secrets/rds\nRDS_HOST=localhost\n")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "secret", "set", "rds", "--value", `{"user": "app", "password": "s3cret", "host": "db"}`, "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	stdout, stderr, err := execCmd(t, "resolve")
+	if err != nil {
+		t.Fatalf("resolve: %v\n%s", err, stderr)
+	}
+	if want := "RDS_USER=app\nRDS_PASSWORD=s3cret\nRDS_HOST=localhost\n"; stdout != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+}
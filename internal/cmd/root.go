@@ -2,11 +2,12 @@
 package cmd
 
 import (
-	"errors"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/secret"
 )
 
 // version is set at build time via -ldflags.
@@ -27,10 +28,13 @@ from your OS keychain or other secret backends at runtime.`,
 
 	// Global verbosity flags (mutually exclusive by convention).
 	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "suppress informational output (errors only)")
-	rootCmd.PersistentFlags().Bool("verbose", false, "show additional detail")
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "show additional detail")
 	rootCmd.PersistentFlags().Bool("debug", false, "show debug information")
 	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose", "debug")
 
+	// Log format of the records the verbosity flags enable.
+	rootCmd.PersistentFlags().String("log-format", output.LogFormatText, "log format: text or json")
+
 	// Color control flag. Also respects NO_COLOR env var (https://no-color.org/).
 	rootCmd.PersistentFlags().Bool("no-color", false, "disable colorized output")
 
@@ -44,6 +48,7 @@ from your OS keychain or other secret backends at runtime.`,
 	rootCmd.AddCommand(newProfileCmd())
 	rootCmd.AddCommand(newValidateCmd())
 	rootCmd.AddCommand(newStatusCmd())
+	rootCmd.AddCommand(newRotateCmd())
 	rootCmd.AddCommand(newRunCmd())
 	rootCmd.AddCommand(newDoctorCmd())
 	rootCmd.AddCommand(newConfigCmd())
@@ -51,15 +56,77 @@ from your OS keychain or other secret backends at runtime.`,
 	rootCmd.AddCommand(newEditCmd())
 	rootCmd.AddCommand(newVaultCmd())
 	rootCmd.AddCommand(newAuditCmd())
+	rootCmd.AddCommand(newScanCmd())
 	rootCmd.AddCommand(newAuditLogCmd())
 	rootCmd.AddCommand(newSyncCmd())
 	rootCmd.AddCommand(newTeamCmd())
 	rootCmd.AddCommand(newBackendCmd())
+	rootCmd.AddCommand(newWhoamiCmd())
+	rootCmd.AddCommand(newPluginCmd())
 	rootCmd.AddCommand(newOnboardCmd())
+	rootCmd.AddCommand(newExampleCmd())
+	rootCmd.AddCommand(newFmtCmd())
+	rootCmd.AddCommand(newMergeCmd())
+	rootCmd.AddCommand(newGitMergeDriverCmd())
+	rootCmd.AddCommand(newWsCmd())
+	rootCmd.AddCommand(newAgentCmd())
+	rootCmd.AddCommand(newBenchCmd())
+	rootCmd.AddCommand(newCacheCmd())
+	rootCmd.AddCommand(newDirenvCmd())
+	rootCmd.AddCommand(newComposeCmd())
+	rootCmd.AddCommand(newK8sCmd())
+	rootCmd.AddCommand(newCICmd())
+	rootCmd.AddCommand(newDevcontainerCmd())
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newMCPCmd())
+	rootCmd.AddCommand(newPushCmd())
+	rootCmd.AddCommand(newPullCmd())
+	rootCmd.AddCommand(newGHCmd())
+	rootCmd.AddCommand(newAWSCmd())
+	rootCmd.AddCommand(newUICmd())
+	rootCmd.AddCommand(newExitCodesCmd())
+	rootCmd.AddCommand(newHooksCmd())
+
+	redactErrors(rootCmd)
+	colorErrors(rootCmd)
+	configureLogging(rootCmd)
+	markUsageErrors(rootCmd)
 
 	return rootCmd
 }
 
+// redactErrors wraps the RunE of cmd and all its subcommands so that the
+// errors they return, which cobra prints, have secret values masked (see
+// secret.Track).
+func redactErrors(cmd *cobra.Command) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return secret.RedactError(run(cmd, args))
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		redactErrors(sub)
+	}
+}
+
+// colorErrors wraps the RunE of cmd and all its subcommands so that the
+// "Error:" prefix cobra prints before the errors they return is red when
+// stderr is a terminal.
+func colorErrors(cmd *cobra.Command) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			err := run(cmd, args)
+			if err != nil {
+				cmd.SetErrPrefix(output.NewWriter(cmd).Red("Error:"))
+			}
+			return err
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		colorErrors(sub)
+	}
+}
+
 // newVersionCmd creates the version subcommand.
 func newVersionCmd() *cobra.Command {
 	return &cobra.Command{
@@ -71,13 +138,10 @@ func newVersionCmd() *cobra.Command {
 	}
 }
 
-// Execute runs the root command.
+// Execute runs the root command, and exits with the code for its error
+// (see exitCode).
 func Execute() {
 	if err := NewRootCmd().Execute(); err != nil {
-		var exitErr *exitError
-		if errors.As(err, &exitErr) {
-			os.Exit(exitErr.code)
-		}
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
@@ -2,8 +2,13 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/secret"
 )
 
 func TestNewRootCmd(t *testing.T) {
@@ -98,3 +103,49 @@ func TestHelpShowsVerbosityFlags(t *testing.T) {
 		}
 	}
 }
+
+func TestRedactErrors(t *testing.T) {
+	secret.Track("root-redact-secret")
+	sentinel := errors.New("sentinel")
+
+	root := &cobra.Command{Use: "root", SilenceUsage: true}
+	root.AddCommand(&cobra.Command{
+		Use: "fail",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("storing root-redact-secret: %w", sentinel)
+		},
+	})
+	redactErrors(root)
+
+	errBuf := new(bytes.Buffer)
+	root.SetErr(errBuf)
+	root.SetArgs([]string{"fail"})
+	err := root.Execute()
+	if !errors.Is(err, sentinel) {
+		t.Fatalf("expected wrapped sentinel, got %v", err)
+	}
+	if strings.Contains(err.Error(), "root-redact-secret") || strings.Contains(errBuf.String(), "root-redact-secret") {
+		t.Errorf("secret leaked: %v / %q", err, errBuf.String())
+	}
+	if !strings.Contains(errBuf.String(), "storing ***: sentinel") {
+		t.Errorf("expected redacted error, got %q", errBuf.String())
+	}
+}
+
+func TestColorErrors(t *testing.T) {
+	t.Setenv("FORCE_COLOR", "1")
+	chdir(t, t.TempDir())
+
+	_, stderr, err := execCmd(t, "resolve")
+	if err == nil {
+		t.Fatal("expected an error without a config")
+	}
+	if !strings.HasPrefix(stderr, "\033[31mError:\033[0m ") {
+		t.Errorf("expected red error prefix, got %q", stderr)
+	}
+
+	_, stderr, _ = execCmd(t, "resolve", "--no-color")
+	if !strings.HasPrefix(stderr, "Error: ") {
+		t.Errorf("expected plain error prefix with --no-color, got %q", stderr)
+	}
+}
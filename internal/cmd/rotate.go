@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/audit"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+)
+
+// newRotateCmd creates the rotate subcommand.
+func newRotateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Show secrets covered by rotation policies and rotate overdue ones",
+		Long: `Show when each secret covered by a rotation policy was last rotated,
+and rotate the ones that are overdue.
+
+Rotation policies live in the rotation: block of .envref.yaml and map secret
+key patterns to intervals in days ("90d"), weeks ("12w"), or as a Go
+duration ("720h"). An exact key name wins over patterns; otherwise the
+longest matching pattern applies:
+
+  rotation:
+    API_*: 90d
+    DB_PASSWORD: 30d
+
+A secret was last rotated when its value was last written. Backends that
+record metadata (1Password, AWS SSM, HashiCorp Vault, the encrypted vault)
+report this directly; for other backends the project's audit log is used.
+Secrets with no known write time are listed as unknown and are never due.
+
+With --due, only overdue secrets are listed, and for each you are asked
+whether to rotate it now with "envref secret rotate". --yes rotates all of
+them without asking. Without --yes, the command exits with code 1 if any
+secret is left overdue, so "envref rotate --due < /dev/null" works as a CI
+check.
+
+Examples:
+  envref rotate                      # rotation status of all covered secrets
+  envref rotate --due                # rotate overdue secrets interactively
+  envref rotate --due --yes          # rotate all overdue secrets
+  envref rotate --due --length 64    # custom generation for new values`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			due, _ := cmd.Flags().GetBool("due")
+			yes, _ := cmd.Flags().GetBool("yes")
+			backendName, _ := cmd.Flags().GetString("backend")
+			profile, _ := cmd.Flags().GetString("profile")
+			length, _ := cmd.Flags().GetInt("length")
+			charset, _ := cmd.Flags().GetString("charset")
+			keep, _ := cmd.Flags().GetInt("keep")
+			return runRotate(cmd, due, yes, backendName, profile, length, charset, keep)
+		},
+	}
+
+	cmd.Flags().Bool("due", false, "only show overdue secrets and offer to rotate them")
+	cmd.Flags().BoolP("yes", "y", false, "rotate all overdue secrets without asking (with --due)")
+	cmd.Flags().StringP("backend", "b", "", "backend to check (default: first configured)")
+	cmd.Flags().StringP("profile", "P", "", "profile scope of the secrets (e.g., staging, production)")
+	cmd.Flags().IntP("length", "l", 32, "length of generated secrets")
+	cmd.Flags().StringP("charset", "c", "alphanumeric", "character set: alphanumeric, ascii, hex, base64")
+	cmd.Flags().IntP("keep", "k", 1, "number of historical values to retain")
+
+	return cmd
+}
+
+// rotationStatus describes a secret covered by a rotation policy.
+type rotationStatus struct {
+	Key      string
+	Pattern  string
+	Interval time.Duration
+	// LastRotated is when the value was last written, or zero if unknown.
+	LastRotated time.Time
+}
+
+// age returns how long ago the secret was last rotated.
+func (s rotationStatus) age(now time.Time) time.Duration {
+	return now.Sub(s.LastRotated)
+}
+
+// due reports whether the secret is overdue for rotation. Secrets with an
+// unknown write time are never due.
+func (s rotationStatus) due(now time.Time) bool {
+	return !s.LastRotated.IsZero() && s.age(now) >= s.Interval
+}
+
+// runRotate implements the rotate command logic.
+func runRotate(cmd *cobra.Command, due, yes bool, backendName, profile string, length int, charset string, keep int) error {
+	if yes && !due {
+		return fmt.Errorf("--yes requires --due")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, configDir, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if len(cfg.Rotation) == 0 {
+		return fmt.Errorf("no rotation policies configured in %s", config.FullFileName)
+	}
+	if len(cfg.Backends) == 0 {
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
+	}
+	if backendName == "" {
+		backendName = cfg.Backends[0].Name
+	}
+	effectiveProfile := cfg.EffectiveProfile(profile)
+
+	registry, err := buildRegistry(cfg)
+	if err != nil {
+		return fmt.Errorf("initializing backends: %w", err)
+	}
+	statuses, err := checkRotations(registry, cfg, configDir, backendName, effectiveProfile)
+	// Rotating opens the backends again, so release them first.
+	registry.CloseAll()
+	if err != nil {
+		return err
+	}
+
+	w := output.NewWriter(cmd)
+	now := time.Now()
+	if due {
+		var overdue []rotationStatus
+		for _, s := range statuses {
+			if s.due(now) {
+				overdue = append(overdue, s)
+			}
+		}
+		statuses = overdue
+	}
+
+	if len(statuses) == 0 {
+		if due {
+			w.Info("no secrets are due for rotation\n")
+		} else {
+			w.Info("no secrets match the rotation policies\n")
+		}
+		return nil
+	}
+
+	printRotationTable(cmd, statuses, now)
+	if !due {
+		return nil
+	}
+
+	var (
+		scanner = bufio.NewScanner(cmd.InOrStdin())
+		skipped int
+	)
+	for _, s := range statuses {
+		if !yes {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Rotate %s (last rotated %s ago)? [y/N] ", s.Key, formatAge(s.age(now)))
+			answer := ""
+			if scanner.Scan() {
+				answer = strings.TrimSpace(strings.ToLower(scanner.Text()))
+			}
+			if answer != "y" && answer != "yes" {
+				skipped++
+				continue
+			}
+		}
+		if err := runSecretRotate(cmd, s.Key, length, charset, backendName, false, profile, keep); err != nil {
+			return fmt.Errorf("rotating %s: %w", s.Key, err)
+		}
+	}
+
+	if skipped > 0 {
+		return fmt.Errorf("%d secret(s) still due for rotation", skipped)
+	}
+	return nil
+}
+
+// checkRotations returns the rotation status of every secret in the named
+// backend, scoped to the project and profile, that a rotation policy
+// covers. Archived history entries are skipped. Results are sorted by key.
+func checkRotations(registry *backend.Registry, cfg *config.Config, configDir, backendName, profile string) ([]rotationStatus, error) {
+	if registry.Backend(backendName) == nil {
+		return nil, registry.Unregistered(backendName)
+	}
+	nsBackend, err := registry.Namespaced(backendName, cfg.Project, profile)
+	if err != nil {
+		return nil, fmt.Errorf("creating namespaced backend: %w", err)
+	}
+
+	keys, err := nsBackend.List()
+	if err != nil {
+		return nil, fmt.Errorf("listing secrets: %w", err)
+	}
+	sort.Strings(keys)
+
+	var (
+		statuses []rotationStatus
+		written  map[string]time.Time
+	)
+	for _, key := range keys {
+		if strings.Contains(key, historyKeySuffix) {
+			continue
+		}
+		interval, pattern, ok := cfg.RotationPolicy(key)
+		if !ok {
+			continue
+		}
+
+		s := rotationStatus{Key: key, Pattern: pattern, Interval: interval}
+		md, err := backend.GetMetadata(nsBackend, key)
+		switch {
+		case err == nil:
+			s.LastRotated = md.Updated
+		case !errors.Is(err, backend.ErrMetadataUnsupported):
+			return nil, fmt.Errorf("reading metadata for %s: %w", key, err)
+		}
+		if s.LastRotated.IsZero() {
+			if written == nil {
+				written = lastWrites(configDir, cfg.Project, backendName, profile)
+			}
+			s.LastRotated = written[key]
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// lastWrites returns, per key, when the audit log last recorded a new value
+// being written to the backend in the given project and profile. The audit
+// log is best-effort, so read errors yield an empty map.
+func lastWrites(configDir, project, backendName, profile string) map[string]time.Time {
+	written := make(map[string]time.Time)
+	entries, err := audit.NewLogger(filepath.Join(configDir, audit.DefaultFileName)).Read()
+	if err != nil {
+		return written
+	}
+	for _, e := range entries {
+		if e.Project != project || e.Backend != backendName || e.Profile != profile {
+			continue
+		}
+		switch e.Operation {
+		case audit.OpSet, audit.OpGenerate, audit.OpRotate, audit.OpCopy, audit.OpImport, audit.OpRollback:
+		default:
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, e.Timestamp)
+		if err != nil {
+			continue
+		}
+		if ts.After(written[e.Key]) {
+			written[e.Key] = ts
+		}
+	}
+	return written
+}
+
+// printRotationTable prints rotation statuses as an aligned table.
+func printRotationTable(cmd *cobra.Command, statuses []rotationStatus, now time.Time) {
+	w := output.NewWriter(cmd)
+
+	rows := make([][]string, 0, len(statuses))
+	for _, s := range statuses {
+		last, age, state := "unknown", "-", "unknown"
+		if !s.LastRotated.IsZero() {
+			last = formatMetadataTime(s.LastRotated)
+			age = formatAge(s.age(now))
+			state = "ok"
+			if s.due(now) {
+				state = "due"
+			}
+		}
+		rows = append(rows, []string{s.Key, formatAge(s.Interval), last, age, state})
+	}
+
+	header := []string{"KEY", "INTERVAL", "LAST ROTATED", "AGE", "STATUS"}
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i, col := range row {
+			widths[i] = max(widths[i], len(col))
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	for i, row := range append([][]string{header}, rows...) {
+		state := row[4]
+		if i > 0 && state == "due" {
+			state = w.Red(state)
+		}
+		_, _ = fmt.Fprintf(out, "%-*s  %-*s  %-*s  %-*s  %s\n", widths[0], row[0], widths[1], row[1], widths[2], row[2], widths[3], row[3], state)
+	}
+}
+
+// formatAge formats a duration in whole days, or hours below one day.
+func formatAge(d time.Duration) string {
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xcke/envref/internal/audit"
+)
+
+// setupRotationProject creates a project with a memory backend, rotation
+// policies, and audit log entries dating the secrets' last writes.
+func setupRotationProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	cfgPath := filepath.Join(dir, ".envref.yaml")
+	cfg, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, ".envref.yaml", string(cfg)+"rotation:\n  API_*: 90d\n  DB_PASSWORD: 30d\n")
+	writeTestFile(t, dir, "secrets.json", `{
+  "app/API_KEY": "old-api-key",
+  "app/API_UNTRACKED": "value",
+  "app/DB_PASSWORD": "hunter2",
+  "app/OTHER": "value"
+}`)
+	writeTestFile(t, dir, ".env", "API_KEY=ref://secrets/API_KEY\n")
+
+	now := time.Now().UTC()
+	logger := audit.NewLogger(filepath.Join(dir, audit.DefaultFileName))
+	for _, e := range []audit.Entry{
+		{Timestamp: now.AddDate(0, 0, -100).Format(time.RFC3339), Operation: audit.OpSet, Key: "API_KEY", Backend: "secrets", Project: "app"},
+		{Timestamp: now.AddDate(0, 0, -40).Format(time.RFC3339), Operation: audit.OpSet, Key: "DB_PASSWORD", Backend: "secrets", Project: "app"},
+		{Timestamp: now.AddDate(0, 0, -5).Format(time.RFC3339), Operation: audit.OpRotate, Key: "DB_PASSWORD", Backend: "secrets", Project: "app"},
+		{Timestamp: now.AddDate(0, 0, -1).Format(time.RFC3339), Operation: audit.OpSet, Key: "API_KEY", Backend: "secrets", Project: "other"},
+	} {
+		if err := logger.Log(e); err != nil {
+			t.Fatalf("writing audit entry: %v", err)
+		}
+	}
+
+	chdir(t, dir)
+	return dir
+}
+
+func TestRotateCmd_ListsStatus(t *testing.T) {
+	setupRotationProject(t)
+
+	stdout, _, err := execCmd(t, "rotate")
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header and 3 rows, got:\n%s", stdout)
+	}
+	for i, want := range [][]string{
+		{"KEY", "INTERVAL", "LAST", "ROTATED", "AGE", "STATUS"},
+		{"API_KEY", "90d", "100d", "due"},
+		{"API_UNTRACKED", "90d", "unknown", "-", "unknown"},
+		{"DB_PASSWORD", "30d", "5d", "ok"},
+	} {
+		fields := strings.Fields(lines[i])
+		if fields[0] != want[0] || fields[len(fields)-1] != want[len(want)-1] || !strings.Contains(lines[i], want[1]) || !strings.Contains(lines[i], want[2]) {
+			t.Errorf("line %d = %q, want fields %v", i, lines[i], want)
+		}
+	}
+	if strings.Contains(stdout, "OTHER") {
+		t.Errorf("keys without a policy should not be listed:\n%s", stdout)
+	}
+}
+
+func TestRotateCmd_DueDeclined(t *testing.T) {
+	setupRotationProject(t)
+
+	stdout, stderr, err := execCmdWithStdin(t, "n\n", "rotate", "--due")
+	if err == nil || !strings.Contains(err.Error(), "1 secret(s) still due for rotation") {
+		t.Fatalf("expected still-due error, got %v", err)
+	}
+	if !strings.Contains(stdout, "API_KEY") || strings.Contains(stdout, "DB_PASSWORD") {
+		t.Errorf("expected only API_KEY to be listed:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "Rotate API_KEY (last rotated 100d ago)? [y/N]") {
+		t.Errorf("expected confirmation prompt, got %q", stderr)
+	}
+}
+
+func TestRotateCmd_DueYes(t *testing.T) {
+	dir := setupRotationProject(t)
+
+	if _, _, err := execCmd(t, "rotate", "--due", "--yes"); err != nil {
+		t.Fatalf("rotate --due --yes: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "secrets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		t.Fatal(err)
+	}
+	if v := secrets["app/API_KEY"]; v == "old-api-key" || len(v) != 32 {
+		t.Errorf("API_KEY not rotated: %q", v)
+	}
+	if secrets["app/API_KEY.__history.1"] != "old-api-key" {
+		t.Errorf("old value not archived: %v", secrets)
+	}
+	if secrets["app/DB_PASSWORD"] != "hunter2" {
+		t.Errorf("DB_PASSWORD should not be rotated")
+	}
+
+	stdout, _, err := execCmd(t, "rotate", "--due")
+	if err != nil {
+		t.Fatalf("rotate --due after rotation: %v", err)
+	}
+	if !strings.Contains(stdout, "no secrets are due for rotation") {
+		t.Errorf("expected nothing due, got %q", stdout)
+	}
+}
+
+func TestRotateCmd_NoPolicies(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "rotate")
+	if err == nil || !strings.Contains(err.Error(), "no rotation policies configured") {
+		t.Errorf("expected no-policies error, got %v", err)
+	}
+}
+
+func TestStatusCmd_RotationDue(t *testing.T) {
+	setupRotationProject(t)
+
+	stdout, _, err := execCmd(t, "status")
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, want := range []string{"Rotation:", "API_KEY last rotated 100d ago (policy API_*: 90d)", "envref rotate --due", "1 issue(s) found"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("status output missing %q:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "DB_PASSWORD last rotated") {
+		t.Errorf("DB_PASSWORD is not due:\n%s", stdout)
+	}
+}
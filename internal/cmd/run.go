@@ -1,16 +1,25 @@
 package cmd
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/ref"
 	"github.com/xcke/envref/internal/resolve"
+	"github.com/xcke/envref/internal/secret"
 )
 
 // exitError wraps an exit code so the caller can propagate it.
@@ -25,25 +34,67 @@ func (e *exitError) Error() string {
 // newRunCmd creates the run subcommand.
 func newRunCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "run [flags] -- <command> [args...]",
+		Use:   "run [flags] -- <command> [args...] | run --procfile <file> [process...]",
 		Short: "Run a command with resolved environment variables",
 		Long: `Resolve all environment variables (including ref:// secret references)
 and execute the given command with those variables injected into its
 environment. This is an alternative to direnv for one-off commands.
 
 The resolved environment merges:
-  .env ← .env.<profile> ← .env.local
+  .env ← .env.<profile> ← .env.local ← .env.<profile>.local
 
 All resolved variables are added to the subprocess environment alongside
-the current process environment.
+the current process environment. With --isolate, the subprocess gets the
+resolved variables only, plus the few of the current environment that
+programs need to start (PATH, HOME, USER, LOGNAME, SHELL, TERM, TMPDIR,
+TZ, LANG, LC_*, and on Windows SYSTEMROOT, COMSPEC, PATHEXT, TEMP, TMP,
+USERPROFILE, and WINDIR), so that it cannot depend on variables that only
+exist on a developer's machine. --allow-env passes on more of them, by
+name or glob.
+
+A ref ending in ?encoding=base64file (e.g., ref://secrets/tls_cert?encoding=base64file)
+is decoded into a private temporary file instead, and the variable is set to
+the file's path. The files are removed when the command exits.
+
+Secrets of unreachable backends are served from their last-known-good
+values, as with resolve. Use --offline to resolve from those only.
+
+With --procfile, the processes of a Procfile ("name: command" per line)
+are started instead, as foreman does: the named ones, or all of them. When
+several run, their output is prefixed with their name. Each process gets
+PORT, counting up by 100 from the environment's PORT (or 5000) in Procfile
+order. When one process exits, or envref is interrupted, the others are
+stopped, and envref exits with the code of the first process to exit.
+
+With --watch, envref keeps running and restarts the command whenever the
+resolved environment changes, like nodemon for env edits: the env layers
+and .envref.yaml are watched, and after a change settles for --debounce,
+the environment is resolved again. If any value differs, the changed keys
+are listed (names only) and the command is stopped with --signal, killed
+if it has not exited after 10s, and started again. --poll also resolves
+the environment at that interval, to pick up rotated secrets. If the
+command exits by itself, envref waits for the next change to start it
+again.
 
 Examples:
   envref run -- node server.js
   envref run -- docker compose up
   envref run --profile staging -- ./deploy.sh
-  envref run --strict -- make test`,
+  envref run --strict -- make test
+  envref run --offline -- npm run dev
+  envref run --isolate -- make test
+  envref run --isolate --allow-env 'SSH_*' -- ./deploy.sh
+  envref run --watch -- node server.js
+  envref run --watch --signal INT --poll 5m -- ./worker
+  envref run --procfile Procfile              # every process
+  envref run --procfile Procfile web worker`,
 		// Cobra's built-in -- handling passes everything after -- as args.
-		Args: cobra.MinimumNArgs(1),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if procfile, _ := cmd.Flags().GetString("procfile"); procfile != "" {
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
 		PreRun: func(cmd *cobra.Command, args []string) {
 			setVaultCmdContext(cmd)
 		},
@@ -53,28 +104,87 @@ Examples:
 		RunE: func(cmd *cobra.Command, args []string) error {
 			profile, _ := cmd.Flags().GetString("profile")
 			strict, _ := cmd.Flags().GetBool("strict")
-			return runRun(cmd, args, profile, strict)
+			procfile, _ := cmd.Flags().GetString("procfile")
+			isolate, _ := cmd.Flags().GetBool("isolate")
+			allowEnv, _ := cmd.Flags().GetStringSlice("allow-env")
+			if len(allowEnv) > 0 && !isolate {
+				return fmt.Errorf("--allow-env requires --isolate")
+			}
+			for _, p := range allowEnv {
+				if _, err := path.Match(p, ""); err != nil {
+					return fmt.Errorf("--allow-env: invalid pattern %q: %w", p, err)
+				}
+			}
+			var allowed []string
+			if isolate {
+				allowed = append(slices.Clone(isolatedEnv), allowEnv...)
+			}
+			watch, _ := cmd.Flags().GetBool("watch")
+			if !watch {
+				for _, name := range []string{"signal", "debounce", "poll"} {
+					if cmd.Flags().Changed(name) {
+						return fmt.Errorf("--%s requires --watch", name)
+					}
+				}
+				return runRun(cmd, args, profile, strict, procfile, allowed)
+			}
+			if procfile != "" {
+				return fmt.Errorf("--watch cannot be combined with --procfile")
+			}
+			var opts watchOptions
+			sig, _ := cmd.Flags().GetString("signal")
+			opts.debounce, _ = cmd.Flags().GetDuration("debounce")
+			opts.poll, _ = cmd.Flags().GetDuration("poll")
+			var err error
+			if opts.signal, err = parseStopSignal(sig); err != nil {
+				return fmt.Errorf("--signal: %w", err)
+			}
+			if opts.debounce < 0 || opts.poll < 0 {
+				return fmt.Errorf("--debounce and --poll must not be negative")
+			}
+			return runWatch(cmd, args, profile, strict, allowed, opts)
 		},
 	}
 
 	cmd.Flags().StringP("profile", "P", "", "environment profile to use (e.g., staging, production)")
 	cmd.Flags().Bool("strict", false, "fail if any reference cannot be resolved")
+	cmd.Flags().String("procfile", "", "start the processes of this Procfile, or those named as arguments")
+	cmd.Flags().Bool("offline", false, "resolve secrets from last-known-good values only, without contacting backends")
+	cmd.Flags().Bool("isolate", false, "pass only the resolved variables and PATH, HOME, and the like to the command")
+	cmd.Flags().StringSlice("allow-env", nil, "with --isolate, also pass these variables of the current environment (globs, repeatable)")
+	cmd.Flags().Bool("watch", false, "restart the command when the resolved environment changes")
+	cmd.Flags().String("signal", "TERM", "with --watch, signal that stops the command before a restart")
+	cmd.Flags().Duration("debounce", 100*time.Millisecond, "with --watch, how long file changes must settle before a restart")
+	cmd.Flags().Duration("poll", 0, "with --watch, also resolve the environment at this interval, for rotated secrets (0: never)")
+	_ = cmd.RegisterFlagCompletionFunc("signal", cobra.FixedCompletions(stopSignalNames, cobra.ShellCompDirectiveNoFileComp))
 
 	return cmd
 }
 
-// runRun implements the run command logic.
-func runRun(cmd *cobra.Command, cmdArgs []string, profileOverride string, strict bool) error {
-	// Resolve environment variables using the same pipeline as "envref resolve".
-	entries, err := resolveEnvEntries(cmd, profileOverride, strict)
+// isolatedEnv lists the variables of the current environment that run
+// --isolate passes on: the ones programs need to start and find their
+// tools, and none that configure an app.
+var isolatedEnv = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "TMPDIR", "TZ", "LANG", "LC_*",
+	// Windows programs often fail to start without these.
+	"SYSTEMROOT", "COMSPEC", "PATHEXT", "TEMP", "TMP", "USERPROFILE", "WINDIR",
+}
+
+// runRun implements the run command logic. With a Procfile, cmdArgs are
+// the names of the processes to start. If allowed is not nil, only the
+// variables of the current environment that match its globs are passed
+// on, besides the resolved ones.
+func runRun(cmd *cobra.Command, cmdArgs []string, profileOverride string, strict bool, procfilePath string, allowed []string) error {
+	environ, _, fileDir, err := runEnviron(cmd, profileOverride, strict, allowed)
+	if fileDir != "" {
+		defer func() { _ = os.RemoveAll(fileDir) }()
+	}
 	if err != nil {
 		return err
 	}
 
-	// Build the subprocess environment: inherit current env + overlay resolved vars.
-	environ := os.Environ()
-	for _, entry := range entries {
-		environ = append(environ, entry.Key+"="+entry.Value)
+	if procfilePath != "" {
+		return runProcfile(cmd, procfilePath, cmdArgs, environ)
 	}
 
 	// Find the executable on PATH.
@@ -114,6 +224,94 @@ func runRun(cmd *cobra.Command, cmdArgs []string, profileOverride string, strict
 	return nil
 }
 
+// runEnviron resolves the environment of the command that run starts:
+// the current environment, or the variables of it matching allowed if that
+// is not nil, with the resolved variables added. base64file secrets are
+// written to files in fileDir, which the caller must remove once the
+// command has exited, even on error. values are the resolved variables,
+// with the contents of those files rather than their paths.
+func runEnviron(cmd *cobra.Command, profileOverride string, strict bool, allowed []string) (environ []string, values map[string]string, fileDir string, err error) {
+	// Resolve environment variables using the same pipeline as "envref resolve".
+	entries, err := resolveEnvEntries(cmd, profileOverride, strict)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	values = make(map[string]string, len(entries))
+	for _, entry := range entries {
+		values[entry.Key] = entry.Value
+	}
+
+	// Write base64file secrets to temporary files for the command's lifetime.
+	fileDir, err = writeSecretFiles(entries)
+	if err != nil {
+		return nil, nil, fileDir, err
+	}
+
+	// Build the subprocess environment: inherit current env + overlay resolved vars.
+	environ = os.Environ()
+	if allowed != nil {
+		environ = filterEnviron(environ, allowed)
+	}
+	for _, entry := range entries {
+		environ = append(environ, entry.Key+"="+entry.Value)
+	}
+	return environ, values, fileDir, nil
+}
+
+// filterEnviron returns the variables of environ whose names match one of
+// patterns, path.Match globs. Names are compared case-insensitively on
+// Windows, where they are. The result is never nil, which exec.Cmd would
+// take for the whole current environment.
+func filterEnviron(environ, patterns []string) []string {
+	kept := []string{}
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if runtime.GOOS == "windows" {
+			name = strings.ToUpper(name)
+		}
+		for _, p := range patterns {
+			if runtime.GOOS == "windows" {
+				p = strings.ToUpper(p)
+			}
+			if matched, _ := path.Match(p, name); matched {
+				kept = append(kept, kv)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// writeSecretFiles decodes the entries resolved through a ref with
+// encoding=base64file into files in a new private temporary directory and
+// sets each entry's value to its file's path. It returns the directory,
+// which the caller must remove, or "" if no entry needed a file.
+func writeSecretFiles(entries []resolve.Entry) (string, error) {
+	dir := ""
+	for i, entry := range entries {
+		if entry.Encoding != ref.EncodingBase64File {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(entry.Value)
+		if err != nil {
+			return dir, fmt.Errorf("decoding %s: %w", entry.Key, err)
+		}
+		if dir == "" {
+			if dir, err = os.MkdirTemp("", "envref-run-"); err != nil {
+				return "", fmt.Errorf("creating secret file directory: %w", err)
+			}
+		}
+		path := filepath.Join(dir, entry.Key)
+		err = os.WriteFile(path, data, 0o600)
+		secret.ClearBytes(data)
+		if err != nil {
+			return dir, fmt.Errorf("writing secret file for %s: %w", entry.Key, err)
+		}
+		entries[i].Value = path
+	}
+	return dir, nil
+}
+
 // resolveEnvEntries runs the full resolve pipeline and returns resolved entries.
 func resolveEnvEntries(cmd *cobra.Command, profileOverride string, strict bool) ([]resolve.Entry, error) {
 	// Load project config.
@@ -127,44 +325,43 @@ func resolveEnvEntries(cmd *cobra.Command, profileOverride string, strict bool)
 		return nil, fmt.Errorf("loading config: %w", err)
 	}
 
-	// Resolve file paths relative to the project root.
-	envPath := resolveFilePath(projectDir, cfg.EnvFile)
-	localPath := resolveFilePath(projectDir, cfg.LocalFile)
-
-	// Determine the active profile.
-	var profilePath string
-	profile := cfg.EffectiveProfile(profileOverride)
-	if profile != "" {
-		profilePath = resolveFilePath(projectDir, cfg.ProfileEnvFile(profile))
+	if err := runHook(cmd, hookPreResolve, cfg.Hooks, projectDir, nil); err != nil {
+		return nil, err
 	}
 
-	// Load and merge env files.
-	env, err := loadAndMergeEnv(cmd, envPath, profilePath, localPath)
+	// Load and merge the env layers for the active profile.
+	profile := cfg.EffectiveProfile(profileOverride)
+	env, err := loadProjectEnv(cmd, cfg, projectDir, profile)
 	if err != nil {
 		return nil, err
 	}
 
 	// If no refs (including embedded nested refs), convert directly.
 	if !env.HasAnyRefs() {
-		return envToEntries(env), nil
+		entries := envToEntries(env)
+		if err := runHook(cmd, hookPostResolve, cfg.Hooks, projectDir, entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
 	}
 
 	// Build the backend registry.
 	if len(cfg.Backends) == 0 {
-		return nil, fmt.Errorf("ref:// references found but no backends configured in %s", config.FullFileName)
+		return nil, withExitCode(exitConfig, fmt.Errorf("ref:// references found but no backends configured in %s", config.FullFileName))
 	}
 
-	registry, err := buildRegistry(cfg)
+	registry, err := buildResolveRegistry(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("initializing backends: %w", err)
 	}
 	defer registry.CloseAll()
 
 	// Resolve references.
-	result, err := resolve.Resolve(env, registry, cfg.Project)
+	result, err := resolveWithProgress(cmd, env, registry, cfg, nil)
 	if err != nil {
 		return nil, fmt.Errorf("resolving references: %w", err)
 	}
+	auditRefReads(cfg, projectDir, profile, env, result)
 
 	// Report resolution errors to stderr.
 	for _, keyErr := range result.Errors {
@@ -173,7 +370,11 @@ func resolveEnvEntries(cmd *cobra.Command, profileOverride string, strict bool)
 
 	// In strict mode, fail if any reference couldn't be resolved.
 	if strict && !result.Resolved() {
-		return nil, fmt.Errorf("%d reference(s) could not be resolved (strict mode)", len(result.Errors))
+		return nil, withExitCode(unresolvedExitCode(result.Errors), fmt.Errorf("%d reference(s) could not be resolved (strict mode)", len(result.Errors)))
+	}
+
+	if err := runHook(cmd, hookPostResolve, cfg.Hooks, projectDir, result.Entries); err != nil {
+		return nil, err
 	}
 
 	return result.Entries, nil
@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"errors"
+	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 // =============================================================================
@@ -278,6 +282,51 @@ func TestRunCmd_Interpolation(t *testing.T) {
 
 // --- exitError tests ---------------------------------------------------------
 
+func TestRunCmd_Isolate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on Windows: test uses /bin/sh")
+	}
+
+	dir := setupProject(t, "testproject", "MY_TEST_VAR=from_envref\n", "")
+	chdir(t, dir)
+	t.Setenv("ENVREF_TEST_LEAK", "leaked")
+	t.Setenv("ENVREF_TEST_ALLOWED", "allowed")
+
+	outFile := filepath.Join(dir, "out.txt")
+	scriptPath := filepath.Join(dir, "test_script.sh")
+	script := "#!/bin/sh\necho \"$MY_TEST_VAR,$ENVREF_TEST_LEAK,$ENVREF_TEST_ALLOWED,${PATH:+path}\" > " + outFile + "\n"
+	if err := os.WriteFile(scriptPath, []byte(script), 0o755); err != nil {
+		t.Fatalf("writing test script: %v", err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "from_envref,leaked,allowed,path"},
+		{[]string{"--isolate"}, "from_envref,,,path"},
+		{[]string{"--isolate", "--allow-env", "ENVREF_TEST_A*"}, "from_envref,,allowed,path"},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"run"}, tt.args...), "--", "/bin/sh", scriptPath)
+		if _, _, err := execCmd(t, args...); err != nil {
+			t.Fatalf("run %v: %v", tt.args, err)
+		}
+		data, err := os.ReadFile(outFile)
+		if err != nil {
+			t.Fatalf("reading output file: %v", err)
+		}
+		if got := strings.TrimSpace(string(data)); got != tt.want {
+			t.Errorf("run %v: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	_, _, err := execCmd(t, "run", "--allow-env", "HOME", "--", "/bin/sh", scriptPath)
+	if err == nil || !strings.Contains(err.Error(), "--allow-env requires --isolate") {
+		t.Errorf("expected an --allow-env error, got %v", err)
+	}
+}
+
 func TestExitError_Error(t *testing.T) {
 	tests := []struct {
 		code int
@@ -296,3 +345,112 @@ func TestExitError_Error(t *testing.T) {
 		}
 	}
 }
+
+func TestRunCmd_Base64FileRef(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on Windows: test uses /bin/sh")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "test-passphrase")
+	writeVaultTestConfig(t, dir, "testproject", filepath.Join(dir, "vault.db"))
+	chdir(t, dir)
+
+	data := "\x00binary\xff"
+	writeTestFile(t, dir, "cert.p12", data)
+	if _, _, err := execCmd(t, "secret", "set", "TLS_KEYSTORE", "--file", "cert.p12"); err != nil {
+		t.Fatalf("secret set --file: %v", err)
+	}
+
+	// The script copies the secret file and records its path.
+	outFile := filepath.Join(dir, "out.bin")
+	pathFile := filepath.Join(dir, "path.txt")
+	script := "cp \"$TLS_KEYSTORE\" " + outFile + " && echo \"$TLS_KEYSTORE\" > " + pathFile
+	if _, _, err := execCmd(t, "run", "--", "/bin/sh", "-c", script); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	got, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("reading copied secret file: %v", err)
+	}
+	if string(got) != data {
+		t.Errorf("secret file contents = %q, want %q", got, data)
+	}
+
+	path, err := os.ReadFile(pathFile)
+	if err != nil {
+		t.Fatalf("reading path file: %v", err)
+	}
+	if _, err := os.Stat(strings.TrimSpace(string(path))); !os.IsNotExist(err) {
+		t.Errorf("expected secret file to be removed after run, stat err: %v", err)
+	}
+}
+
+func TestRunCmd_Watch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on Windows: test uses /bin/sh")
+	}
+
+	dir := setupProject(t, "testproject", "MY_TEST_VAR=one\n", "")
+	chdir(t, dir)
+
+	outFile := filepath.Join(dir, "out.txt")
+	script := `echo "$MY_TEST_VAR" >> ` + outFile + `; exec sleep 30`
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	root := NewRootCmd()
+	root.SetOut(io.Discard)
+	root.SetErr(io.Discard)
+	root.SetArgs([]string{"run", "--watch", "--debounce", "20ms", "--", "/bin/sh", "-c", script})
+	done := make(chan error, 1)
+	go func() { done <- root.ExecuteContext(ctx) }()
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if data, _ := os.ReadFile(outFile); string(data) == want {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		data, _ := os.ReadFile(outFile)
+		t.Fatalf("output: got %q, want %q", data, want)
+	}
+
+	waitFor("one\n")
+	writeTestFile(t, dir, ".env", "MY_TEST_VAR=two\n")
+	waitFor("one\ntwo\n")
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("run --watch: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run --watch did not stop")
+	}
+}
+
+func TestRunCmd_WatchFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--signal", "INT"}, "--signal requires --watch"},
+		{[]string{"--poll", "1m"}, "--poll requires --watch"},
+		{[]string{"--watch", "--signal", "STOP"}, `unknown signal "STOP"`},
+		{[]string{"--watch", "--procfile", "Procfile"}, "--watch cannot be combined with --procfile"},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"run"}, tt.args...), "--", "true")
+		_, _, err := execCmd(t, args...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("run %v: expected %q, got %v", tt.args, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+)
+
+// stopSignalNames are the signals that run --signal accepts, without their
+// SIG prefix.
+var stopSignalNames = []string{"HUP", "INT", "QUIT", "TERM", "USR1", "USR2", "KILL"}
+
+// watchStopTimeout is how long run --watch gives the command to exit after
+// the stop signal before it is killed.
+const watchStopTimeout = 10 * time.Second
+
+// watchOptions configures run --watch.
+type watchOptions struct {
+	// signal stops the command before a restart, one of stopSignalNames.
+	signal string
+	// debounce is how long file changes must settle before the environment
+	// is resolved again.
+	debounce time.Duration
+	// poll is how often the environment is resolved again without a file
+	// change, to pick up rotated secrets, or 0 for never.
+	poll time.Duration
+}
+
+// parseStopSignal returns the name in stopSignalNames of the signal name,
+// given as TERM, SIGTERM, or sigterm.
+func parseStopSignal(name string) (string, error) {
+	n := strings.TrimPrefix(strings.ToUpper(name), "SIG")
+	if !slices.Contains(stopSignalNames, n) {
+		return "", fmt.Errorf("unknown signal %q (use one of: %s)", name, strings.Join(stopSignalNames, ", "))
+	}
+	return n, nil
+}
+
+// watchedChild is a command started by run --watch.
+type watchedChild struct {
+	child   *exec.Cmd
+	fileDir string
+	exited  chan error
+	running bool
+}
+
+// startWatchedChild starts binary with args and environ. fileDir holds its
+// secret files, which are removed once it has been stopped.
+func startWatchedChild(cmd *cobra.Command, binary string, args, environ []string, fileDir string) (*watchedChild, error) {
+	child := exec.Command(binary, args...)
+	child.SysProcAttr = processGroupAttr()
+	child.Env = environ
+	child.Stdin = os.Stdin
+	child.Stdout = cmd.OutOrStdout()
+	child.Stderr = cmd.ErrOrStderr()
+	if err := child.Start(); err != nil {
+		return nil, err
+	}
+	w := &watchedChild{child: child, fileDir: fileDir, exited: make(chan error, 1), running: true}
+	go func() {
+		w.exited <- child.Wait()
+	}()
+	return w, nil
+}
+
+// stop stops the command, if it is running, with the signal called name,
+// kills it if it has not exited after watchStopTimeout, and removes its
+// secret files.
+func (w *watchedChild) stop(name string) {
+	if w.running {
+		sendProcessGroup(w.child.Process, name)
+		select {
+		case <-w.exited:
+		case <-time.After(watchStopTimeout):
+			signalProcessGroup(w.child.Process, true)
+			<-w.exited
+		}
+		w.running = false
+	}
+	if w.fileDir != "" {
+		_ = os.RemoveAll(w.fileDir)
+	}
+}
+
+// runWatch runs cmdArgs as runRun does, and restarts it whenever the
+// resolved environment changes: after a change to an env layer or the
+// config file, and with opts.poll, after a secret rotation. The command is
+// also started again after a file change if it has exited by itself.
+func runWatch(cmd *cobra.Command, cmdArgs []string, profileOverride string, strict bool, allowed []string, opts watchOptions) error {
+	w := output.NewWriter(cmd)
+
+	binary, err := exec.LookPath(cmdArgs[0])
+	if err != nil {
+		return fmt.Errorf("command not found: %s", cmdArgs[0])
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, projectDir, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	profile := cfg.EffectiveProfile(profileOverride)
+
+	environ, values, fileDir, err := runEnviron(cmd, profileOverride, strict, allowed)
+	if err != nil {
+		if fileDir != "" {
+			_ = os.RemoveAll(fileDir)
+		}
+		return err
+	}
+	current, err := startWatchedChild(cmd, binary, cmdArgs[1:], environ, fileDir)
+	if err != nil {
+		_ = os.RemoveAll(fileDir)
+		return fmt.Errorf("running %s: %w", cmdArgs[0], err)
+	}
+	defer func() { current.stop(opts.signal) }()
+
+	// Set up file watcher on the env layers and the config file.
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("creating file watcher: %w", err)
+	}
+	defer func() { _ = watcher.Close() }()
+
+	paths := append(projectEnvPaths(cfg, projectDir, profile), filepath.Join(projectDir, config.FullFileName))
+	watchPaths := collectWatchPaths(paths...)
+	for _, p := range watchPaths {
+		if err := watcher.Add(p); err != nil {
+			w.Verbose("cannot watch %s: %v\n", p, err)
+		} else {
+			w.Verbose("watching %s\n", p)
+		}
+	}
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "watching %d file(s) for changes... (Ctrl+C to stop)\n", len(watchPaths))
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	var poll <-chan time.Time
+	if opts.poll > 0 {
+		ticker := time.NewTicker(opts.poll)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	// Debounce timer: coalesce rapid file changes.
+	var debounceTimer *time.Timer
+	debounceCh := make(chan struct{}, 1)
+
+	// reload resolves the environment again and restarts the command if
+	// it changed, or, after a file change, if the command has exited.
+	reload := func(fileChange bool) {
+		newEnviron, newValues, newDir, err := runEnviron(cmd, profileOverride, strict, allowed)
+		if err != nil {
+			if newDir != "" {
+				_ = os.RemoveAll(newDir)
+			}
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: %s\n", err)
+			return
+		}
+		changed := changedKeys(values, newValues)
+		if len(changed) == 0 && (current.running || !fileChange) {
+			if newDir != "" {
+				_ = os.RemoveAll(newDir)
+			}
+			w.Verbose("environment unchanged\n")
+			return
+		}
+		if len(changed) > 0 {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "environment changed (%s), restarting %s\n", strings.Join(changed, ", "), cmdArgs[0])
+		} else {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "restarting %s\n", cmdArgs[0])
+		}
+		current.stop(opts.signal)
+		values = newValues
+		next, err := startWatchedChild(cmd, binary, cmdArgs[1:], newEnviron, newDir)
+		if err != nil {
+			if newDir != "" {
+				_ = os.RemoveAll(newDir)
+			}
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: running %s: %s\n", cmdArgs[0], err)
+			current = &watchedChild{}
+			return
+		}
+		current = next
+	}
+
+	for {
+		var exited <-chan error
+		if current.running {
+			exited = current.exited
+		}
+		select {
+		case <-cmdContext(cmd).Done():
+			return nil
+
+		case <-sigCh:
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "\nstopping watch\n")
+			return nil
+
+		case err := <-exited:
+			current.running = false
+			status := "exited"
+			if err != nil {
+				status = err.Error()
+			}
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s %s; waiting for changes\n", cmdArgs[0], status)
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// Only react to writes and creates (covers most editors).
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			w.Debug("file changed: %s (%s)\n", event.Name, event.Op)
+
+			if debounceTimer != nil {
+				debounceTimer.Stop()
+			}
+			debounceTimer = time.AfterFunc(opts.debounce, func() {
+				select {
+				case debounceCh <- struct{}{}:
+				default:
+				}
+			})
+
+		case <-debounceCh:
+			// Re-add watch paths in case files were recreated.
+			for _, p := range watchPaths {
+				_ = watcher.Add(p)
+			}
+			reload(true)
+
+		case <-poll:
+			reload(false)
+
+		case watchErr, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.Warn("watch error: %v\n", watchErr)
+		}
+	}
+}
+
+// changedKeys returns the sorted keys whose values differ between old and
+// new, including keys only one of them has.
+func changedKeys(old, new map[string]string) []string {
+	var keys []string
+	for k, v := range new {
+		if ov, ok := old[k]; !ok || ov != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range old {
+		if _, ok := new[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
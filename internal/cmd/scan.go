@@ -0,0 +1,425 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/schema"
+)
+
+// scanRules describes the detection methods that produce findings, keyed by
+// auditFinding.Pattern. They become the rules of SARIF reports.
+var scanRules = []struct {
+	ID          string
+	Description string
+}{
+	{"pattern", "Value matches a known credential format"},
+	{"key-name", "Key name suggests a secret and the value is a non-trivial plaintext string"},
+	{"entropy", "High-entropy value that looks like a token or key"},
+	{"schema-ref", "The project schema requires a ref:// reference for this key"},
+}
+
+// scanSkipDirs are directories never descended into when looking for .env
+// files.
+var scanSkipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// newScanCmd creates the scan subcommand.
+func newScanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan [PATH...]",
+		Short: "Scan .env files and git history for plaintext secrets",
+		Long: `Scan every .env file under the given paths (default: the current
+directory) for plaintext secrets. A file is scanned if it is named .env or
+.env.<anything>, except JSON files such as .env.schema.json. The .git,
+node_modules, and vendor directories are skipped.
+
+Values are checked with the same detection methods as "envref audit":
+known credential formats, secret-looking key names with non-trivial values,
+and high-entropy strings. In addition, keys that the schema: block of
+.envref.yaml marks with ref: true are flagged when they hold a plaintext
+value.
+
+With --history, lines added to .env files in any commit of the git
+repository are scanned as well, so secrets that were committed and later
+removed are still found.
+
+Findings never include the secret values. Use --format json or
+--format sarif for machine-readable reports; SARIF reports can be uploaded
+to GitHub code scanning. The command exits with code 1 if anything is
+found.
+
+Examples:
+  envref scan                               # scan .env files under .
+  envref scan --history                     # also scan git history
+  envref scan --format sarif > envref.sarif # SARIF report for CI`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			history, _ := cmd.Flags().GetBool("history")
+			format, _ := cmd.Flags().GetString("format")
+			minEntropy, _ := cmd.Flags().GetFloat64("min-entropy")
+			return runScan(cmd, args, history, format, minEntropy)
+		},
+	}
+
+	cmd.Flags().Bool("history", false, "also scan lines added to .env files in git history")
+	cmd.Flags().String("format", "text", "output format: text, json, sarif")
+	cmd.Flags().Float64("min-entropy", 3.5, "minimum Shannon entropy to flag a value (bits per character)")
+
+	return cmd
+}
+
+// runScan implements the scan command logic.
+func runScan(cmd *cobra.Command, paths []string, history bool, format string, minEntropy float64) error {
+	switch format {
+	case "text", "json", "sarif":
+	default:
+		return fmt.Errorf("invalid format %q: must be one of text, json, sarif", format)
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	s, err := loadConfigSchema()
+	if err != nil {
+		return err
+	}
+
+	var findings []auditFinding
+	for _, root := range paths {
+		files, err := findEnvFiles(root)
+		if err != nil {
+			return err
+		}
+		for _, path := range files {
+			fileFindings, err := scanFile(path, s, minEntropy)
+			if err != nil {
+				output.NewWriter(cmd).Warn("skipping %s: %v\n", path, err)
+				continue
+			}
+			findings = append(findings, fileFindings...)
+		}
+	}
+
+	if history {
+		historyFindings, err := scanHistory(paths[0], s, minEntropy)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, historyFindings...)
+	}
+
+	w := output.NewWriter(cmd)
+	switch format {
+	case "json":
+		if err := writeScanJSON(cmd.OutOrStdout(), findings); err != nil {
+			return err
+		}
+	case "sarif":
+		if err := writeScanSARIF(cmd.OutOrStdout(), findings); err != nil {
+			return err
+		}
+	default:
+		if len(findings) == 0 {
+			if !w.IsQuiet() {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: no plaintext secrets detected\n", w.Green("OK"))
+			}
+			return nil
+		}
+		printAuditFindings(w, "Scan", findings)
+	}
+
+	if len(findings) > 0 {
+		return fmt.Errorf("%d potential secret(s) found", len(findings))
+	}
+	return nil
+}
+
+// isEnvFileName reports whether a file name is scanned: .env or
+// .env.<suffix>, excluding JSON files such as .env.schema.json.
+func isEnvFileName(name string) bool {
+	if name == ".env" {
+		return true
+	}
+	return strings.HasPrefix(name, ".env.") && !strings.HasSuffix(name, ".json")
+}
+
+// findEnvFiles returns the .env files under root, which may also be a
+// single file, in lexical order.
+func findEnvFiles(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return []string{root}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && scanSkipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && isEnvFileName(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// scanFile parses a .env file and checks each entry.
+func scanFile(path string, s *schema.Schema, minEntropy float64) ([]auditFinding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := parser.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing: %w", err)
+	}
+
+	var findings []auditFinding
+	for _, entry := range entries {
+		findings = append(findings, scanEntry(path, entry, s, minEntropy)...)
+	}
+	return findings, nil
+}
+
+// scanEntry checks a single entry: keys the schema requires to be refs are
+// flagged for any plaintext value, other keys go through auditEntry.
+func scanEntry(path string, entry parser.Entry, s *schema.Schema, minEntropy float64) []auditFinding {
+	if s != nil && s.Keys[entry.Key].Ref && entry.Value != "" && !ref.IsRef(s.Schemes.Rewrite(entry.Value)) && !ref.IsEncrypted(entry.Value) {
+		return []auditFinding{{
+			File:    path,
+			Line:    entry.Line,
+			Key:     entry.Key,
+			Reason:  "schema requires a ref:// reference, but the value is plaintext",
+			Pattern: "schema-ref",
+		}}
+	}
+	return auditEntry(path, entry, minEntropy)
+}
+
+// scanHistory checks every line added to a .env file in any commit of the
+// git repository containing dir. File paths are relative to the repository
+// root.
+func scanHistory(dir string, s *schema.Schema, minEntropy float64) ([]auditFinding, error) {
+	gitCmd := exec.Command("git", "-C", dir, "log", "--all", "-p", "--no-color", "--no-ext-diff",
+		"--unified=0", "--format=commit %H", "--", ":(glob)**/.env", ":(glob)**/.env.*")
+	var stderr bytes.Buffer
+	gitCmd.Stderr = &stderr
+	out, err := gitCmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("scanning git history: %s", strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("scanning git history: %w", err)
+	}
+	return scanDiff(bytes.NewReader(out), s, minEntropy)
+}
+
+// scanDiff checks the added lines of the output of `git log -p
+// --unified=0 --format="commit %H"`.
+func scanDiff(r io.Reader, s *schema.Schema, minEntropy float64) ([]auditFinding, error) {
+	var (
+		findings []auditFinding
+		commit   string
+		file     string
+		line     int
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "commit "):
+			commit = strings.TrimPrefix(text, "commit ")
+			file = ""
+		case strings.HasPrefix(text, "+++ "):
+			file = ""
+			if name, ok := strings.CutPrefix(text, "+++ b/"); ok && isEnvFileName(filepath.Base(name)) {
+				file = name
+			}
+		case strings.HasPrefix(text, "@@ "):
+			line = hunkStartLine(text)
+		case strings.HasPrefix(text, "+") && file != "":
+			entries, _, err := parser.Parse(strings.NewReader(text[1:]))
+			if err == nil {
+				for _, entry := range entries {
+					entry.Line = line
+					for _, f := range scanEntry(file, entry, s, minEntropy) {
+						f.Commit = commit
+						findings = append(findings, f)
+					}
+				}
+			}
+			line++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading git history: %w", err)
+	}
+	return findings, nil
+}
+
+// hunkStartLine returns the first new-file line number of a hunk header
+// such as "@@ -3,0 +4,2 @@".
+func hunkStartLine(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 3 {
+		return 0
+	}
+	start, _, _ := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+	n, _ := strconv.Atoi(start)
+	return n
+}
+
+// scanJSONFinding is the JSON representation of a finding.
+type scanJSONFinding struct {
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Key         string `json:"key"`
+	Rule        string `json:"rule"`
+	Description string `json:"description"`
+	Commit      string `json:"commit,omitempty"`
+}
+
+// writeScanJSON writes findings as a JSON array.
+func writeScanJSON(w io.Writer, findings []auditFinding) error {
+	out := make([]scanJSONFinding, len(findings))
+	for i, f := range findings {
+		out[i] = scanJSONFinding{
+			File:        filepath.ToSlash(f.File),
+			Line:        f.Line,
+			Key:         f.Key,
+			Rule:        f.Pattern,
+			Description: f.Reason,
+			Commit:      f.Commit,
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// SARIF 2.1.0 types, limited to the fields envref reports.
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID              string            `json:"ruleId"`
+		Level               string            `json:"level"`
+		Message             sarifMessage      `json:"message"`
+		Locations           []sarifLocation   `json:"locations"`
+		PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           sarifRegion           `json:"region"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+	}
+)
+
+// writeScanSARIF writes findings as a SARIF 2.1.0 log.
+func writeScanSARIF(w io.Writer, findings []auditFinding) error {
+	rules := make([]sarifRule, len(scanRules))
+	for i, r := range scanRules {
+		rules[i] = sarifRule{ID: r.ID, ShortDescription: sarifMessage{Text: r.Description}}
+	}
+
+	results := make([]sarifResult, len(findings))
+	for i, f := range findings {
+		msg := fmt.Sprintf("%s: %s", f.Key, f.Reason)
+		var fingerprints map[string]string
+		if f.Commit != "" {
+			msg += fmt.Sprintf(" (added in commit %.12s)", f.Commit)
+			fingerprints = map[string]string{"commitSha": f.Commit}
+		}
+		results[i] = sarifResult{
+			RuleID:  f.Pattern,
+			Level:   "error",
+			Message: sarifMessage{Text: msg},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(filepath.Clean(f.File))},
+				Region:           sarifRegion{StartLine: max(f.Line, 1)},
+			}}},
+			PartialFingerprints: fingerprints,
+		}
+	}
+
+	log := sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "envref",
+				Version:        version,
+				InformationURI: "https://github.com/xcke/envref",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
@@ -8,15 +8,23 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math/big"
 	"os"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/xcke/envref/internal/audit"
 	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/backend/factory"
 	"github.com/xcke/envref/internal/config"
 	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/secret"
+	"github.com/xcke/envref/internal/suggest"
 )
 
 // newSecretCmd creates the secret command group for managing secrets in backends.
@@ -44,8 +52,12 @@ Secrets are namespaced by project name from .envref.yaml.`,
 	cmd.AddCommand(newSecretListCmd())
 	cmd.AddCommand(newSecretGenerateCmd())
 	cmd.AddCommand(newSecretCopyCmd())
+	cmd.AddCommand(newSecretImportCmd())
 	cmd.AddCommand(newSecretRotateCmd())
 	cmd.AddCommand(newSecretShareCmd())
+	cmd.AddCommand(newSecretReceiveCmd())
+	cmd.AddCommand(newSecretVersionsCmd())
+	cmd.AddCommand(newSecretRollbackCmd())
 
 	return cmd
 }
@@ -53,7 +65,7 @@ Secrets are namespaced by project name from .envref.yaml.`,
 // newSecretGetCmd creates the secret get subcommand.
 func newSecretGetCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "get <KEY>",
+		Use:   "get [KEY]",
 		Short: "Retrieve a secret from a backend",
 		Long: `Retrieve and print a secret value from the configured backend for the current project.
 
@@ -63,15 +75,24 @@ Use --backend to specify a different backend.
 Use --profile to retrieve a profile-scoped secret (stored under <project>/<profile>/<key>).
 If no profile-scoped secret exists, falls back to the project-scoped secret.
 
+Without a KEY on a terminal, pick the secret from a list that narrows down
+as you type, as with fzf.
+
 Examples:
+  envref secret get                                      # pick the secret
   envref secret get API_KEY                              # get from default backend
   envref secret get DB_PASS --backend keychain           # get from specific backend
-  envref secret get API_KEY --profile staging            # get profile-scoped secret`,
-		Args: cobra.ExactArgs(1),
+  envref secret get API_KEY --profile staging            # get profile-scoped secret
+  envref secret get TLS_KEYSTORE > cert.p12              # binary secret`,
+		Args: keyArg,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			backendName, _ := cmd.Flags().GetString("backend")
 			profile, _ := cmd.Flags().GetString("profile")
-			return runSecretGet(cmd, args[0], backendName, profile)
+			var key string
+			if len(args) > 0 {
+				key = args[0]
+			}
+			return runSecretGet(cmd, key, backendName, profile)
 		},
 	}
 
@@ -81,10 +102,12 @@ Examples:
 	return cmd
 }
 
-// runSecretGet retrieves a secret from the configured backend.
+// runSecretGet retrieves a secret from the configured backend. An empty
+// key is picked interactively on a terminal.
 func runSecretGet(cmd *cobra.Command, key, backendName, profile string) error {
 	// Validate key.
-	if strings.TrimSpace(key) == "" {
+	pick := key == "" && canPickKey(cmd)
+	if !pick && strings.TrimSpace(key) == "" {
 		return fmt.Errorf("key must not be empty")
 	}
 
@@ -94,13 +117,13 @@ func runSecretGet(cmd *cobra.Command, key, backendName, profile string) error {
 		return fmt.Errorf("getting working directory: %w", err)
 	}
 
-	cfg, _, err := config.Load(cwd)
+	cfg, configDir, err := config.Load(cwd)
 	if err != nil {
 		return fmt.Errorf("loading config: %w", err)
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
 	}
 
 	// Determine target backend.
@@ -118,22 +141,33 @@ func runSecretGet(cmd *cobra.Command, key, backendName, profile string) error {
 	// Wrap the target backend with project namespace.
 	targetBackend := registry.Backend(backendName)
 	if targetBackend == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return registry.Unregistered(backendName)
 	}
 
 	// Resolve effective profile from flag or config.
 	effectiveProfile := cfg.EffectiveProfile(profile)
 
-	// If profile is active, try profile-scoped first, then fall back.
-	if effectiveProfile != "" {
-		profileBackend, pErr := backend.NewProfileNamespacedBackend(targetBackend, cfg.Project, effectiveProfile)
+	if pick {
+		keys, err := secretGetKeys(registry, backendName, cfg.Project, cfg.ProfileChain(effectiveProfile))
+		if err != nil {
+			return err
+		}
+		if key, err = pickKey(cmd, keys); err != nil {
+			return err
+		}
+	}
+
+	// If profile is active, try profile-scoped first, then the profiles it
+	// inherits from, then fall back.
+	for _, scope := range cfg.ProfileChain(effectiveProfile) {
+		profileBackend, pErr := registry.Namespaced(backendName, cfg.Project, scope)
 		if pErr != nil {
 			return fmt.Errorf("creating profile backend: %w", pErr)
 		}
 		value, pGetErr := profileBackend.Get(key)
 		if pGetErr == nil {
-			_, _ = fmt.Fprintln(cmd.OutOrStdout(), value)
-			return nil
+			auditRead(cfg, configDir, key, backendName, scope)
+			return printSecretValue(cmd, value)
 		}
 		// Only fall back on not-found; other errors are real failures.
 		if !errors.Is(pGetErr, backend.ErrNotFound) {
@@ -141,18 +175,71 @@ func runSecretGet(cmd *cobra.Command, key, backendName, profile string) error {
 		}
 	}
 
-	nsBackend, err := backend.NewNamespacedBackend(targetBackend, cfg.Project)
+	nsBackend, err := registry.Namespaced(backendName, cfg.Project, "")
 	if err != nil {
 		return fmt.Errorf("creating namespaced backend: %w", err)
 	}
 
 	// Retrieve the secret from project scope.
 	value, err := nsBackend.Get(key)
+	if errors.Is(err, backend.ErrNotFound) {
+		var hint string
+		if keys, listErr := secretGetKeys(registry, backendName, cfg.Project, cfg.ProfileChain(effectiveProfile)); listErr == nil {
+			hint = suggest.FormatSuggestion(suggest.Keys(key, keys))
+		}
+		return fmt.Errorf("retrieving secret: %w%s", err, hint)
+	}
 	if err != nil {
 		return fmt.Errorf("retrieving secret: %w", err)
 	}
 
-	_, _ = fmt.Fprintln(cmd.OutOrStdout(), value)
+	auditRead(cfg, configDir, key, backendName, "")
+	return printSecretValue(cmd, value)
+}
+
+// secretGetKeys returns the keys secret get can find in a backend: the
+// project-scoped ones and those scoped to each of profiles (see
+// config.Config.ProfileChain), which the project scope lists again under a
+// "<profile>/" prefix. History entries kept by secret rotate are left out.
+func secretGetKeys(registry *backend.Registry, backendName, project string, profiles []string) ([]string, error) {
+	scopes := append([]string{""}, profiles...)
+	var keys []string
+	for _, scope := range scopes {
+		ns, err := registry.Namespaced(backendName, project, scope)
+		if err != nil {
+			return nil, fmt.Errorf("creating namespaced backend: %w", err)
+		}
+		scoped, err := ns.List()
+		if err != nil {
+			return nil, fmt.Errorf("listing secrets: %w", err)
+		}
+		for _, k := range scoped {
+			if scope == "" && slices.ContainsFunc(profiles, func(p string) bool { return strings.HasPrefix(k, p+"/") }) {
+				continue
+			}
+			if !strings.Contains(k, historyKeySuffix) && !slices.Contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// printSecretValue writes a secret value to stdout followed by a newline.
+// Binary secrets are written as their raw bytes, without a newline, so they
+// can be redirected to a file.
+func printSecretValue(cmd *cobra.Command, value string) error {
+	if !backend.IsBinary(value) {
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), value)
+		return nil
+	}
+	data, err := backend.DecodeBinary(value)
+	if err != nil {
+		return err
+	}
+	defer secret.ClearBytes(data)
+	_, _ = cmd.OutOrStdout().Write(data)
 	return nil
 }
 
@@ -170,39 +257,57 @@ Use --backend to specify a different backend.
 
 Use --profile to list only profile-scoped secrets for the given profile.
 
+Use --long to also show when each secret was created and last updated, and
+by whom, for backends that record it (vault, 1password, aws-ssm,
+hashicorp-vault). Unknown fields are shown as "-".
+
+Use --format table to show each secret's scope (the project, or the
+profile), its backend, and the .env keys that reference it, in aligned
+columns. With --long, the table includes the times and author too.
+
 Examples:
   envref secret list                              # list from default backend
   envref secret list --backend keychain           # list from specific backend
-  envref secret list --profile staging            # list profile-scoped secrets`,
+  envref secret list --profile staging            # list profile-scoped secrets
+  envref secret list --long                       # include timestamps and author
+  envref secret list --format table               # include scope and references`,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			backendName, _ := cmd.Flags().GetString("backend")
 			profile, _ := cmd.Flags().GetString("profile")
-			return runSecretList(cmd, backendName, profile)
+			long, _ := cmd.Flags().GetBool("long")
+			formatStr, _ := cmd.Flags().GetString("format")
+			return runSecretList(cmd, backendName, profile, long, formatStr)
 		},
 	}
 
 	cmd.Flags().StringP("backend", "b", "", "backend to list secrets from (default: first configured)")
 	cmd.Flags().StringP("profile", "P", "", "profile scope to list secrets for (e.g., staging, production)")
+	cmd.Flags().BoolP("long", "l", false, "show created/updated times and who last wrote each secret")
+	cmd.Flags().String("format", "plain", "output format: plain, table")
 
 	return cmd
 }
 
 // runSecretList lists all secret keys for the current project from the configured backend.
-func runSecretList(cmd *cobra.Command, backendName, profile string) error {
+func runSecretList(cmd *cobra.Command, backendName, profile string, long bool, formatStr string) error {
+	if formatStr != string(FormatPlain) && formatStr != string(FormatTable) {
+		return fmt.Errorf("invalid format %q: must be one of %s, %s", formatStr, FormatPlain, FormatTable)
+	}
+
 	// Load project config.
 	cwd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("getting working directory: %w", err)
 	}
 
-	cfg, _, err := config.Load(cwd)
+	cfg, projectDir, err := config.Load(cwd)
 	if err != nil {
 		return fmt.Errorf("loading config: %w", err)
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
 	}
 
 	// Determine target backend.
@@ -220,17 +325,12 @@ func runSecretList(cmd *cobra.Command, backendName, profile string) error {
 	// Wrap the target backend with project namespace.
 	targetBackend := registry.Backend(backendName)
 	if targetBackend == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return registry.Unregistered(backendName)
 	}
 
 	// Build the appropriate namespaced backend.
 	effectiveProfile := cfg.EffectiveProfile(profile)
-	var nsBackend *backend.NamespacedBackend
-	if effectiveProfile != "" {
-		nsBackend, err = backend.NewProfileNamespacedBackend(targetBackend, cfg.Project, effectiveProfile)
-	} else {
-		nsBackend, err = backend.NewNamespacedBackend(targetBackend, cfg.Project)
-	}
+	nsBackend, err := registry.Namespaced(backendName, cfg.Project, effectiveProfile)
 	if err != nil {
 		return fmt.Errorf("creating namespaced backend: %w", err)
 	}
@@ -250,12 +350,123 @@ func runSecretList(cmd *cobra.Command, backendName, profile string) error {
 		return nil
 	}
 
+	if formatStr == string(FormatTable) {
+		return printSecretListTable(cmd, cfg, projectDir, backendName, effectiveProfile, nsBackend, keys, long)
+	}
+	if long {
+		return printSecretListLong(cmd, nsBackend, keys)
+	}
+
 	for _, key := range keys {
 		_, _ = fmt.Fprintln(cmd.OutOrStdout(), key)
 	}
 	return nil
 }
 
+// printSecretListLong prints keys with their metadata as an aligned table.
+// Metadata that the backend does not record is shown as "-".
+func printSecretListLong(cmd *cobra.Command, b backend.Backend, keys []string) error {
+	w := output.NewWriter(cmd)
+	sort.Strings(keys)
+
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		md, err := backend.GetMetadata(b, key)
+		if err != nil && !errors.Is(err, backend.ErrMetadataUnsupported) {
+			w.Warn("%s: %v\n", key, err)
+		}
+		rows = append(rows, []string{key, formatMetadataTime(md.Created), formatMetadataTime(md.Updated), orDash(md.UpdatedBy)})
+	}
+
+	header := []string{"KEY", "CREATED", "UPDATED", "UPDATED BY"}
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i, col := range row {
+			widths[i] = max(widths[i], len(col))
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	for _, row := range append([][]string{header}, rows...) {
+		_, _ = fmt.Fprintf(out, "%-*s  %-*s  %-*s  %s\n", widths[0], row[0], widths[1], row[1], widths[2], row[2], row[3])
+	}
+	return nil
+}
+
+// printSecretListTable prints keys as a table with their scope, backend,
+// and the keys of the project's environment that reference them, and with
+// long, their metadata.
+func printSecretListTable(cmd *cobra.Command, cfg *config.Config, projectDir, backendName, profile string, b backend.Backend, keys []string, long bool) error {
+	w := output.NewWriter(cmd)
+	sort.Strings(keys)
+
+	scope := "project"
+	if profile != "" {
+		scope = profile
+	}
+	// References are a convenience: the environment failing to load does
+	// not prevent listing.
+	referencedBy := make(map[string][]string)
+	if env, err := loadProjectEnv(cmd, cfg, projectDir, profile); err == nil {
+		for _, e := range env.Refs() {
+			if parsed, err := ref.Parse(e.Value); err == nil && refMayUse(cfg, parsed, backendName) {
+				referencedBy[parsed.Path] = append(referencedBy[parsed.Path], e.Key)
+			}
+		}
+	}
+
+	header := []string{"KEY", "SCOPE", "BACKEND", "REFERENCED BY"}
+	if long {
+		header = append(header, "CREATED", "UPDATED", "UPDATED BY")
+	}
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		row := []string{key, scope, backendName, orDash(strings.Join(referencedBy[key], ", "))}
+		if long {
+			md, err := backend.GetMetadata(b, key)
+			if err != nil && !errors.Is(err, backend.ErrMetadataUnsupported) {
+				w.Warn("%s: %v\n", key, err)
+			}
+			row = append(row, formatMetadataTime(md.Created), formatMetadataTime(md.Updated), orDash(md.UpdatedBy))
+		}
+		rows = append(rows, row)
+	}
+	return writeTable(cmd.OutOrStdout(), header, rows)
+}
+
+// refMayUse reports whether r may resolve from the backend named
+// backendName: it names that backend, an alias including it, or no
+// configured backend or alias, so that every backend is tried.
+func refMayUse(cfg *config.Config, r ref.Reference, backendName string) bool {
+	if r.Backend == backendName {
+		return true
+	}
+	if members, ok := cfg.Aliases[r.Backend]; ok {
+		return slices.Contains(members, backendName)
+	}
+	return !slices.ContainsFunc(cfg.Backends, func(bc config.BackendConfig) bool { return bc.Name == r.Backend })
+}
+
+// formatMetadataTime formats a secret metadata time in local time, or "-"
+// if it is unknown.
+func formatMetadataTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// orDash returns s, or "-" if s is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
 // newSecretDeleteCmd creates the secret delete subcommand.
 func newSecretDeleteCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -311,7 +522,7 @@ func runSecretDelete(cmd *cobra.Command, key, backendName string, force bool, pr
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
 	}
 
 	// Determine target backend.
@@ -329,17 +540,12 @@ func runSecretDelete(cmd *cobra.Command, key, backendName string, force bool, pr
 	// Wrap the target backend with project namespace.
 	targetBackend := registry.Backend(backendName)
 	if targetBackend == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return registry.Unregistered(backendName)
 	}
 
 	// Build the appropriate namespaced backend.
 	effectiveProfile := cfg.EffectiveProfile(profile)
-	var nsBackend *backend.NamespacedBackend
-	if effectiveProfile != "" {
-		nsBackend, err = backend.NewProfileNamespacedBackend(targetBackend, cfg.Project, effectiveProfile)
-	} else {
-		nsBackend, err = backend.NewNamespacedBackend(targetBackend, cfg.Project)
-	}
+	nsBackend, err := registry.Namespaced(backendName, cfg.Project, effectiveProfile)
 	if err != nil {
 		return fmt.Errorf("creating namespaced backend: %w", err)
 	}
@@ -368,7 +574,7 @@ func runSecretDelete(cmd *cobra.Command, key, backendName string, force bool, pr
 	}
 
 	// Log the operation to the audit log (best-effort).
-	_ = newAuditLogger(configDir).Log(audit.Entry{
+	_ = newAuditLogger(cfg, configDir).Log(audit.Entry{
 		Operation: audit.OpDelete,
 		Key:       key,
 		Backend:   backendName,
@@ -401,34 +607,63 @@ Use --backend to specify a different backend.
 Use --profile to store the secret in a profile-scoped namespace
 (<project>/<profile>/<key>), allowing different values per environment.
 
+Use --file to store the contents of a file, such as a TLS key or a keystore.
+The contents are stored base64-encoded, and the .env entry is written as
+ref://<backend>/<KEY>?encoding=base64file so that "envref run" recreates the
+file and sets the variable to its path.
+
+If a strength: policy is configured, the value must meet it: a minimum
+length, a minimum estimated entropy, and not being a known placeholder or
+leaked value such as "changeme". Use --no-verify to store it anyway.
+
 Examples:
   envref secret set API_KEY                              # prompt for value
   envref secret set API_KEY --value sk-123               # non-interactive
   envref secret set DB_PASS --backend keychain           # specific backend
-  envref secret set API_KEY --value sk-stg --profile staging  # profile-scoped`,
+  envref secret set API_KEY --value sk-stg --profile staging  # profile-scoped
+  envref secret set TLS_KEYSTORE --file cert.p12         # binary file
+  envref secret set DB_PASS --value dev --no-verify      # skip strength policy`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			value, _ := cmd.Flags().GetString("value")
+			file, _ := cmd.Flags().GetString("file")
 			backendName, _ := cmd.Flags().GetString("backend")
 			profile, _ := cmd.Flags().GetString("profile")
-			return runSecretSet(cmd, args[0], value, backendName, profile)
+			noVerify, _ := cmd.Flags().GetBool("no-verify")
+			return runSecretSet(cmd, args[0], value, file, backendName, profile, noVerify)
 		},
 	}
 
-	cmd.Flags().StringP("value", "v", "", "secret value (if omitted, prompts for input)")
+	cmd.Flags().String("value", "", "secret value (if omitted, prompts for input)")
+	cmd.Flags().StringP("file", "f", "", "store the contents of a file (binary safe)")
+	cmd.MarkFlagsMutuallyExclusive("value", "file")
 	cmd.Flags().StringP("backend", "b", "", "backend to store the secret in (default: first configured)")
 	cmd.Flags().StringP("profile", "P", "", "profile scope for the secret (e.g., staging, production)")
+	cmd.Flags().Bool("no-verify", false, "store the value even if it fails the strength policy")
 
 	return cmd
 }
 
-// runSecretSet stores a secret in the configured backend.
-func runSecretSet(cmd *cobra.Command, key, value, backendName, profile string) error {
+// runSecretSet stores a secret in the configured backend. If file is set,
+// its contents are stored as a binary secret instead of value. Unless
+// noVerify is set, a text value must meet the configured strength policy.
+func runSecretSet(cmd *cobra.Command, key, value, file, backendName, profile string, noVerify bool) error {
 	// Validate key.
 	if strings.TrimSpace(key) == "" {
 		return fmt.Errorf("key must not be empty")
 	}
 
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("reading secret file: %w", err)
+		}
+		if len(data) == 0 {
+			return fmt.Errorf("secret file %s is empty", file)
+		}
+		value = backend.EncodeBinary(data)
+	}
+
 	// Load project config.
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -441,7 +676,7 @@ func runSecretSet(cmd *cobra.Command, key, value, backendName, profile string) e
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
 	}
 
 	// Determine target backend.
@@ -459,17 +694,12 @@ func runSecretSet(cmd *cobra.Command, key, value, backendName, profile string) e
 	// Wrap the target backend with project namespace.
 	targetBackend := registry.Backend(backendName)
 	if targetBackend == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return registry.Unregistered(backendName)
 	}
 
 	// Build the appropriate namespaced backend.
 	effectiveProfile := cfg.EffectiveProfile(profile)
-	var nsBackend *backend.NamespacedBackend
-	if effectiveProfile != "" {
-		nsBackend, err = backend.NewProfileNamespacedBackend(targetBackend, cfg.Project, effectiveProfile)
-	} else {
-		nsBackend, err = backend.NewNamespacedBackend(targetBackend, cfg.Project)
-	}
+	nsBackend, err := registry.Namespaced(backendName, cfg.Project, effectiveProfile)
 	if err != nil {
 		return fmt.Errorf("creating namespaced backend: %w", err)
 	}
@@ -483,13 +713,19 @@ func runSecretSet(cmd *cobra.Command, key, value, backendName, profile string) e
 		value = prompted
 	}
 
+	if file == "" && !noVerify && cfg.Strength.Enabled() {
+		if problems := checkStrength(cfg.Strength, value); len(problems) > 0 {
+			return fmt.Errorf("value for %s fails the strength policy: %s; use --no-verify to store it anyway", key, strings.Join(problems, "; "))
+		}
+	}
+
 	// Store the secret.
 	if err := nsBackend.Set(key, value); err != nil {
 		return fmt.Errorf("storing secret: %w", err)
 	}
 
 	// Log the operation to the audit log (best-effort).
-	_ = newAuditLogger(configDir).Log(audit.Entry{
+	_ = newAuditLogger(cfg, configDir).Log(audit.Entry{
 		Operation: audit.OpSet,
 		Key:       key,
 		Backend:   backendName,
@@ -498,7 +734,11 @@ func runSecretSet(cmd *cobra.Command, key, value, backendName, profile string) e
 	})
 
 	// Update the .env file with a ref:// entry.
-	if err := syncEnvRef(cmd, cfg, configDir, key, backendName, effectiveProfile); err != nil {
+	envRef := ref.Reference{Backend: backendName, Path: key}
+	if file != "" {
+		envRef.Encoding = ref.EncodingBase64File
+	}
+	if err := syncEnvRefValue(cmd, cfg, configDir, key, envRef, effectiveProfile); err != nil {
 		output.NewWriter(cmd).Warn("could not update .env file: %v\n", err)
 	}
 
@@ -624,7 +864,7 @@ func runSecretGenerate(cmd *cobra.Command, key string, length int, charset, back
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
 	}
 
 	// Determine target backend.
@@ -642,17 +882,12 @@ func runSecretGenerate(cmd *cobra.Command, key string, length int, charset, back
 	// Wrap the target backend with project namespace.
 	targetBackend := registry.Backend(backendName)
 	if targetBackend == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return registry.Unregistered(backendName)
 	}
 
 	// Build the appropriate namespaced backend.
 	effectiveProfile := cfg.EffectiveProfile(profile)
-	var nsBackend *backend.NamespacedBackend
-	if effectiveProfile != "" {
-		nsBackend, err = backend.NewProfileNamespacedBackend(targetBackend, cfg.Project, effectiveProfile)
-	} else {
-		nsBackend, err = backend.NewNamespacedBackend(targetBackend, cfg.Project)
-	}
+	nsBackend, err := registry.Namespaced(backendName, cfg.Project, effectiveProfile)
 	if err != nil {
 		return fmt.Errorf("creating namespaced backend: %w", err)
 	}
@@ -663,7 +898,7 @@ func runSecretGenerate(cmd *cobra.Command, key string, length int, charset, back
 	}
 
 	// Log the operation to the audit log (best-effort).
-	_ = newAuditLogger(configDir).Log(audit.Entry{
+	_ = newAuditLogger(cfg, configDir).Log(audit.Entry{
 		Operation: audit.OpGenerate,
 		Key:       key,
 		Backend:   backendName,
@@ -717,7 +952,7 @@ func generateFromCharset(length int, chars string) (string, error) {
 		}
 		result[i] = chars[n.Int64()]
 	}
-	return string(result), nil
+	return secret.TakeString(result), nil
 }
 
 // generateHex generates a random hex-encoded string of exactly the given length.
@@ -725,6 +960,7 @@ func generateHex(length int) (string, error) {
 	// Each byte produces 2 hex characters.
 	numBytes := (length + 1) / 2
 	b := make([]byte, numBytes)
+	defer secret.ClearBytes(b)
 	if _, err := rand.Read(b); err != nil {
 		return "", fmt.Errorf("reading random bytes: %w", err)
 	}
@@ -737,6 +973,7 @@ func generateBase64(length int) (string, error) {
 	// Base64 encodes 3 bytes into 4 chars. Over-allocate to ensure enough output.
 	numBytes := (length*3)/4 + 3
 	b := make([]byte, numBytes)
+	defer secret.ClearBytes(b)
 	if _, err := rand.Read(b); err != nil {
 		return "", fmt.Errorf("reading random bytes: %w", err)
 	}
@@ -812,7 +1049,7 @@ func runSecretCopy(cmd *cobra.Command, key, fromProject, backendName, profile, f
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
 	}
 
 	// Determine target backend.
@@ -830,28 +1067,18 @@ func runSecretCopy(cmd *cobra.Command, key, fromProject, backendName, profile, f
 	// Get the raw backend.
 	targetBackend := registry.Backend(backendName)
 	if targetBackend == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return registry.Unregistered(backendName)
 	}
 
 	// Create namespaced backend for the source project (optionally profile-scoped).
-	var srcBackend *backend.NamespacedBackend
-	if fromProfile != "" {
-		srcBackend, err = backend.NewProfileNamespacedBackend(targetBackend, fromProject, fromProfile)
-	} else {
-		srcBackend, err = backend.NewNamespacedBackend(targetBackend, fromProject)
-	}
+	srcBackend, err := registry.Namespaced(backendName, fromProject, fromProfile)
 	if err != nil {
 		return fmt.Errorf("creating source namespace: %w", err)
 	}
 
 	// Create namespaced backend for the current (destination) project.
 	effectiveProfile := cfg.EffectiveProfile(profile)
-	var dstBackend *backend.NamespacedBackend
-	if effectiveProfile != "" {
-		dstBackend, err = backend.NewProfileNamespacedBackend(targetBackend, cfg.Project, effectiveProfile)
-	} else {
-		dstBackend, err = backend.NewNamespacedBackend(targetBackend, cfg.Project)
-	}
+	dstBackend, err := registry.Namespaced(backendName, cfg.Project, effectiveProfile)
 	if err != nil {
 		return fmt.Errorf("creating destination namespace: %w", err)
 	}
@@ -873,7 +1100,7 @@ func runSecretCopy(cmd *cobra.Command, key, fromProject, backendName, profile, f
 
 	// Log the operation to the audit log (best-effort).
 	detail := fmt.Sprintf("from %s", srcLabel)
-	_ = newAuditLogger(configDir).Log(audit.Entry{
+	_ = newAuditLogger(cfg, configDir).Log(audit.Entry{
 		Operation: audit.OpCopy,
 		Key:       key,
 		Backend:   backendName,
@@ -896,153 +1123,40 @@ func runSecretCopy(cmd *cobra.Command, key, fromProject, backendName, profile, f
 }
 
 // buildRegistry creates a backend registry from the config, instantiating
-// backends based on their type.
+// backends based on their type, wrapping them in their configured
+// middleware, and defining any configured key templates and aliases. Every
+// backend is also wrapped in backend.Redacting, so the secret values it
+// handles are masked in errors and logs. Backend calls are logged to the
+// default slog logger (see backend.Trace). Its errors make envref exit with
+// exitBackend.
 func buildRegistry(cfg *config.Config) (*backend.Registry, error) {
-	registry := backend.NewRegistry()
-
-	for _, bc := range cfg.Backends {
-		b, err := createBackend(bc)
+	registry, err := factory.Registry(cfg, func(bc config.BackendConfig) (backend.Backend, error) {
+		b, err := createChainedBackend(bc)
 		if err != nil {
-			return nil, fmt.Errorf("backend %q: %w", bc.Name, err)
-		}
-		if err := registry.Register(b); err != nil {
 			return nil, err
 		}
+		// Writes drop the old value from the agent's cache, if an agent
+		// is running.
+		return backend.Chain(b, backend.Trace(slog.Default()), agentInvalidating(bc)), nil
+	})
+	if err != nil {
+		return nil, withExitCode(exitBackend, err)
 	}
-
 	return registry, nil
 }
 
-// createBackend instantiates a backend based on its config type.
-func createBackend(bc config.BackendConfig) (backend.Backend, error) {
-	switch bc.EffectiveType() {
-	case "keychain":
-		return backend.NewKeychainBackend(), nil
-	case "vault":
-		return createVaultBackend(bc)
-	case "1password":
-		return createOnePasswordBackend(bc), nil
-	case "aws-ssm":
-		return createAWSSSMBackend(bc), nil
-	case "oci-vault":
-		return createOCIVaultBackend(bc), nil
-	case "hashicorp-vault":
-		return createHashiVaultBackend(bc), nil
-	case "plugin":
-		return createPluginBackend(bc)
-	default:
-		return nil, fmt.Errorf("unknown backend type %q", bc.EffectiveType())
-	}
-}
-
-// createOnePasswordBackend creates a OnePasswordBackend from the backend config.
-// Optional config keys: "vault" (default "Personal"), "account" (optional).
-func createOnePasswordBackend(bc config.BackendConfig) *backend.OnePasswordBackend {
-	vault := bc.Config["vault"]
-	if vault == "" {
-		vault = "Personal"
-	}
-
-	var opts []backend.OnePasswordOption
-	if account := bc.Config["account"]; account != "" {
-		opts = append(opts, backend.WithOnePasswordAccount(account))
-	}
-	if command := bc.Config["command"]; command != "" {
-		opts = append(opts, backend.WithOnePasswordCommand(command))
-	}
-	return backend.NewOnePasswordBackend(vault, opts...)
-}
-
-// createVaultBackend creates a VaultBackend from the backend config.
-// The passphrase is resolved in order: ENVREF_VAULT_PASSPHRASE env var,
-// config.passphrase from .envref.yaml, then interactive terminal prompt
-// (if a command context is available). Returns an error if no passphrase
-// can be obtained.
-func createVaultBackend(bc config.BackendConfig) (*backend.VaultBackend, error) {
-	return createVaultBackendWithContext(bc)
+// createChainedBackend instantiates the backend for bc wrapped in
+// backend.Redacting and its configured middleware. A vault backend may
+// prompt for its passphrase.
+func createChainedBackend(bc config.BackendConfig) (backend.Backend, error) {
+	return factory.Chained(bc, openVaultBackend)
 }
 
-// createPluginBackend creates a PluginBackend from the backend config.
-// If config.command is set, it is used as the plugin executable path.
-// Otherwise, the plugin is discovered by searching $PATH for
-// "envref-backend-<name>".
-func createPluginBackend(bc config.BackendConfig) (*backend.PluginBackend, error) {
-	command := bc.Config["command"]
-	if command == "" {
-		var err error
-		command, err = backend.DiscoverPlugin(bc.Name)
-		if err != nil {
-			return nil, err
-		}
-	}
-	return backend.NewPluginBackend(bc.Name, command), nil
-}
-
-// createAWSSSMBackend creates an AWSSSMBackend from the backend config.
-// Optional config keys: "prefix" (default "/envref"), "region" (optional),
-// "profile" (optional).
-func createAWSSSMBackend(bc config.BackendConfig) *backend.AWSSSMBackend {
-	prefix := bc.Config["prefix"]
-	if prefix == "" {
-		prefix = "/envref"
-	}
-
-	var opts []backend.AWSSSMOption
-	if region := bc.Config["region"]; region != "" {
-		opts = append(opts, backend.WithAWSSSMRegion(region))
-	}
-	if profile := bc.Config["profile"]; profile != "" {
-		opts = append(opts, backend.WithAWSSSMProfile(profile))
-	}
-	if command := bc.Config["command"]; command != "" {
-		opts = append(opts, backend.WithAWSSSMCommand(command))
-	}
-	return backend.NewAWSSSMBackend(prefix, opts...)
-}
-
-// createOCIVaultBackend creates an OCIVaultBackend from the backend config.
-// Required config keys: "vault_id", "compartment_id", "key_id".
-// Optional config keys: "profile" (optional).
-func createOCIVaultBackend(bc config.BackendConfig) *backend.OCIVaultBackend {
-	vaultID := bc.Config["vault_id"]
-	compartmentID := bc.Config["compartment_id"]
-	keyID := bc.Config["key_id"]
-
-	var opts []backend.OCIVaultOption
-	if profile := bc.Config["profile"]; profile != "" {
-		opts = append(opts, backend.WithOCIVaultProfile(profile))
-	}
-	if command := bc.Config["command"]; command != "" {
-		opts = append(opts, backend.WithOCIVaultCommand(command))
-	}
-	return backend.NewOCIVaultBackend(vaultID, compartmentID, keyID, opts...)
-}
-
-// createHashiVaultBackend creates a HashiVaultBackend from the backend config.
-// Optional config keys: "mount" (default "secret"), "prefix" (default "envref"),
-// "addr" (optional), "namespace" (optional), "token" (optional).
-func createHashiVaultBackend(bc config.BackendConfig) *backend.HashiVaultBackend {
-	mount := bc.Config["mount"]
-	if mount == "" {
-		mount = "secret"
-	}
-	prefix := bc.Config["prefix"]
-	if prefix == "" {
-		prefix = "envref"
-	}
-
-	var opts []backend.HashiVaultOption
-	if addr := bc.Config["addr"]; addr != "" {
-		opts = append(opts, backend.WithHashiVaultAddr(addr))
-	}
-	if namespace := bc.Config["namespace"]; namespace != "" {
-		opts = append(opts, backend.WithHashiVaultNamespace(namespace))
-	}
-	if token := bc.Config["token"]; token != "" {
-		opts = append(opts, backend.WithHashiVaultToken(token))
-	}
-	if command := bc.Config["command"]; command != "" {
-		opts = append(opts, backend.WithHashiVaultCommand(command))
+// openVaultBackend is the factory.VaultFunc of the commands.
+func openVaultBackend(bc config.BackendConfig) (backend.Backend, error) {
+	v, err := createVaultBackendWithContext(bc)
+	if err != nil {
+		return nil, err
 	}
-	return backend.NewHashiVaultBackend(mount, prefix, opts...)
+	return v, nil
 }
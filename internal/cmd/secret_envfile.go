@@ -16,6 +16,12 @@ import (
 // after a secret is stored in a backend. If the key already exists with a
 // non-ref value, it is left untouched to avoid overwriting manual overrides.
 func syncEnvRef(cmd *cobra.Command, cfg *config.Config, configDir, key, backendName, effectiveProfile string) error {
+	return syncEnvRefValue(cmd, cfg, configDir, key, ref.Reference{Backend: backendName, Path: key}, effectiveProfile)
+}
+
+// syncEnvRefValue works like syncEnvRef but writes the given reference,
+// e.g. one carrying an encoding parameter.
+func syncEnvRefValue(cmd *cobra.Command, cfg *config.Config, configDir, key string, r ref.Reference, effectiveProfile string) error {
 	if noEnvFlag(cmd) {
 		return nil
 	}
@@ -32,7 +38,7 @@ func syncEnvRef(cmd *cobra.Command, cfg *config.Config, configDir, key, backendN
 		return nil
 	}
 
-	refValue := ref.Prefix + backendName + "/" + key
+	refValue := r.String()
 	env.Set(parser.Entry{
 		Key:   key,
 		Value: refValue,
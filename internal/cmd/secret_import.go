@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/backend/factory"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+)
+
+// importSources are the stores that secret import reads from.
+var importSources = []string{"aws-ssm"}
+
+// newSecretImportCmd creates the secret import subcommand.
+func newSecretImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <PATH>",
+		Short: "Import the secrets under a path of another store",
+		Long: `Read every secret under PATH in an existing store and store them as
+secrets of the project in a backend, so that a service managed with
+another tool can move to envref. A ref:// entry is added to .env for each
+imported secret unless --no-env is given.
+
+With --from aws-ssm, PATH is an SSM Parameter Store path in the layout of
+chamber, such as /chamber/service or /service: each parameter directly
+under it is a secret, and nested paths are skipped. Names become variable
+names as 'chamber exec' makes them: upper-cased, with "-" replaced by "_".
+The AWS CLI's region and profile are taken from --region and
+--aws-profile, or else from the first aws-ssm backend in .envref.yaml.
+
+The changes are listed, by key name only, and applied after confirmation.
+Secrets that already exist with a different value are kept unless --force
+is given.
+
+Examples:
+  envref secret import --from aws-ssm /shop-api --dry-run
+  envref secret import --from aws-ssm /chamber/shop-api --backend vault -P production
+  envref secret import --from aws-ssm /shop-api --region eu-west-1 --yes --no-env`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, _ := cmd.Flags().GetString("from")
+			region, _ := cmd.Flags().GetString("region")
+			awsProfile, _ := cmd.Flags().GetString("aws-profile")
+			backendName, _ := cmd.Flags().GetString("backend")
+			profile, _ := cmd.Flags().GetString("profile")
+			force, _ := cmd.Flags().GetBool("force")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			yes, _ := cmd.Flags().GetBool("yes")
+			if from != "aws-ssm" {
+				return fmt.Errorf("unsupported source %q (supported: %s)", from, strings.Join(importSources, ", "))
+			}
+			return runSecretImportSSM(cmd, args[0], region, awsProfile, backendName, profile, force, dryRun, yes)
+		},
+	}
+
+	cmd.Flags().String("from", "", "store to import from: aws-ssm (required)")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.RegisterFlagCompletionFunc("from", cobra.FixedCompletions(importSources, cobra.ShellCompDirectiveNoFileComp))
+	cmd.Flags().String("region", "", "AWS region of the parameters (default: from the aws-ssm backend or the AWS CLI)")
+	cmd.Flags().String("aws-profile", "", "AWS CLI named profile (default: from the aws-ssm backend)")
+	cmd.Flags().StringP("backend", "b", "", "backend to import secrets into (default: first configured)")
+	cmd.Flags().StringP("profile", "P", "", "profile scope for secrets (e.g., staging, production)")
+	cmd.Flags().Bool("force", false, "overwrite secrets that have a different value")
+	cmd.Flags().Bool("dry-run", false, "show the changes without writing them")
+	cmd.Flags().BoolP("yes", "y", false, "apply the changes without asking")
+
+	return cmd
+}
+
+// runSecretImportSSM imports the parameters directly under path in AWS
+// SSM Parameter Store, in the layout of chamber.
+func runSecretImportSSM(cmd *cobra.Command, path, region, awsProfile, backendName, profile string, force, dryRun, yes bool) error {
+	path = "/" + strings.Trim(path, "/")
+	if path == "/" {
+		return fmt.Errorf("path must not be empty")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, _, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	// Reach AWS as the project's aws-ssm backend does, under path.
+	bc := config.BackendConfig{Name: "import", Type: "aws-ssm"}
+	for _, b := range cfg.Backends {
+		if b.EffectiveType() == "aws-ssm" {
+			bc = b
+			bc.Config = maps.Clone(b.Config)
+			break
+		}
+	}
+	if bc.Config == nil {
+		bc.Config = make(map[string]string)
+	}
+	bc.Config["prefix"] = path
+	if region != "" {
+		bc.Config["region"] = region
+	}
+	if awsProfile != "" {
+		bc.Config["profile"] = awsProfile
+	}
+	b, err := factory.New(bc, nil)
+	if err != nil {
+		return fmt.Errorf("initializing aws-ssm: %w", err)
+	}
+	ssm := b.(*backend.AWSSSMBackend)
+
+	names, err := ssm.List()
+	if err != nil {
+		return err
+	}
+	w := output.NewWriter(cmd)
+	var keys []string
+	for _, name := range names {
+		if strings.Contains(name, "/") {
+			w.Warn("skipping %s/%s: nested paths are not imported\n", path, name)
+			continue
+		}
+		keys = append(keys, name)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no parameters found under %s", path)
+	}
+
+	values, err := ssm.GetMany(keys)
+	if err != nil {
+		return err
+	}
+	vars := make(map[string]string, len(values))
+	for name, value := range values {
+		key := chamberEnvName(name)
+		if _, dup := vars[key]; dup {
+			return fmt.Errorf("parameters under %s map to the same variable %s", path, key)
+		}
+		vars[key] = value
+	}
+
+	source := "aws-ssm " + path
+	stored, target, err := importSecrets(cmd, vars, source, "import from "+source, profile, backendName, force, dryRun, yes, true)
+	if err != nil || stored == 0 {
+		return err
+	}
+	w.Info("imported %d secret(s) from %s into %s\n", stored, source, target.label())
+	return nil
+}
+
+// chamberEnvName returns the variable name of the chamber key name, as
+// 'chamber exec' sets it.
+func chamberEnvName(name string) string {
+	return strings.ReplaceAll(strings.ToUpper(name), "-", "_")
+}
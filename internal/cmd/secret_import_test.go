@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeFakeAWS writes an aws CLI to dir whose Parameter Store holds
+// /shop/api-key, /shop/db_url, and /shop/nested/token, logs its
+// arguments, and returns its path.
+func writeFakeAWS(t *testing.T, dir string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake aws is a shell script")
+	}
+	script := `#!/bin/sh
+echo "$*" >> "` + dir + `/aws.log"
+case "$2" in
+describe-parameters) echo '{"Parameters":[{"Name":"/shop/api-key"},{"Name":"/shop/db_url"},{"Name":"/shop/nested/token"}]}' ;;
+get-parameters) echo '{"Parameters":[{"Name":"/shop/api-key","Value":"sk-1"},{"Name":"/shop/db_url","Value":"postgres://db"}]}' ;;
+*) echo "unexpected aws call" >&2; exit 1 ;;
+esac
+`
+	path := filepath.Join(dir, "aws")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSecretImportCmd_AWSSSM(t *testing.T) {
+	dir := t.TempDir()
+	aws := writeFakeAWS(t, dir)
+	writeTestFile(t, dir, ".envref.yaml", "project: app\nbackends:\n"+
+		"  - name: secrets\n    type: memory\n    config:\n      path: "+filepath.Join(dir, "secrets.json")+"\n"+
+		"  - name: aws-ssm\n    type: aws-ssm\n    config:\n      command: "+aws+"\n      region: us-east-1\n")
+	writeTestFile(t, dir, ".env", "PORT=8080\n")
+	chdir(t, dir)
+
+	stdout, stderr, err := execCmd(t, "secret", "import", "--from", "aws-ssm", "/shop/", "--region", "eu-west-1", "--yes")
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	out := stdout + stderr
+	if !strings.Contains(out, "skipping /shop/nested/token: nested paths are not imported") ||
+		!strings.Contains(out, `imported 2 secret(s) from aws-ssm /shop into backend "secrets"`) {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if got, _, _ := execCmd(t, "secret", "get", "API_KEY"); strings.TrimSpace(got) != "sk-1" {
+		t.Errorf("API_KEY = %q", got)
+	}
+	env, err := os.ReadFile(filepath.Join(dir, ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(env), "API_KEY=ref://secrets/API_KEY") || !strings.Contains(string(env), "DB_URL=ref://secrets/DB_URL") {
+		t.Errorf("expected refs in .env:\n%s", env)
+	}
+	log, err := os.ReadFile(filepath.Join(dir, "aws.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(log), "Values=/shop/") || !strings.Contains(string(log), "--region eu-west-1") {
+		t.Errorf("unexpected aws calls:\n%s", log)
+	}
+
+	_, _, err = execCmd(t, "secret", "import", "--from", "chamber", "/shop")
+	if err == nil || !strings.Contains(err.Error(), `unsupported source "chamber" (supported: aws-ssm)`) {
+		t.Errorf("expected an unsupported source error, got %v", err)
+	}
+}
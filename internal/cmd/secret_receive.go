@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/audit"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/secret"
+)
+
+// pgpArmorHeader starts ASCII-armored GPG ciphertext.
+const pgpArmorHeader = "-----BEGIN PGP MESSAGE-----"
+
+// newSecretReceiveCmd creates the secret receive subcommand.
+func newSecretReceiveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "receive [FILE]",
+		Short: "Import a secret shared with 'envref secret share'",
+		Long: `Decrypt a bundle created by 'envref secret share' and store the secret in
+your own backend, under the key it was shared as (or --as).
+
+The bundle is read from FILE, or from stdin if FILE is omitted or "-".
+Age bundles are decrypted with the identity given by --identity or the
+AGE_IDENTITY environment variable; GPG bundles with the gpg binary and the
+secret keys in your keyring. Bundles past their expiry are refused.
+
+An existing secret is not overwritten unless --force is given.
+
+Examples:
+  envref secret receive shared-secret.age --identity key.txt
+  pbpaste | envref secret receive -i key.txt         # from the clipboard
+  envref secret receive shared-secret.asc            # GPG bundle
+  envref secret receive shared.age -i key.txt --as STRIPE_KEY
+  envref secret receive shared.age -i key.txt --profile staging --force`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file := stdinPath
+			if len(args) == 1 {
+				file = args[0]
+			}
+			identityFile, _ := cmd.Flags().GetString("identity")
+			as, _ := cmd.Flags().GetString("as")
+			backendName, _ := cmd.Flags().GetString("backend")
+			profile, _ := cmd.Flags().GetString("profile")
+			force, _ := cmd.Flags().GetBool("force")
+			return runSecretReceive(cmd, file, identityFile, as, backendName, profile, force)
+		},
+	}
+
+	cmd.Flags().StringP("identity", "i", "", "path to age identity (private key) file")
+	cmd.Flags().String("as", "", "store the secret under this key instead of the shared one")
+	cmd.Flags().StringP("backend", "b", "", "backend to store the secret in (default: first configured)")
+	cmd.Flags().StringP("profile", "P", "", "profile scope for the secret (e.g., staging, production)")
+	cmd.Flags().Bool("force", false, "overwrite the secret if it already exists")
+
+	return cmd
+}
+
+// runSecretReceive decrypts a share bundle and stores its secret in a
+// backend.
+func runSecretReceive(cmd *cobra.Command, file, identityFile, as, backendName, profile string, force bool) error {
+	var data []byte
+	var err error
+	if file == stdinPath {
+		data, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return fmt.Errorf("reading bundle: %w", err)
+	}
+
+	bundle, err := openShareBundle(data, identityFile)
+	if err != nil {
+		return err
+	}
+	if bundle.Expires != nil && time.Now().After(*bundle.Expires) {
+		return fmt.Errorf("shared secret %q expired at %s; ask the sender to share it again",
+			bundle.Key, bundle.Expires.Local().Format("2006-01-02 15:04"))
+	}
+
+	key := bundle.Key
+	if as != "" {
+		key = as
+	}
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("bundle has no key; use --as to name the secret")
+	}
+
+	// Load project config.
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+
+	cfg, configDir, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	if len(cfg.Backends) == 0 {
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
+	}
+
+	// Determine target backend.
+	if backendName == "" {
+		backendName = cfg.Backends[0].Name
+	}
+
+	registry, err := buildRegistry(cfg)
+	if err != nil {
+		return fmt.Errorf("initializing backends: %w", err)
+	}
+	defer registry.CloseAll()
+
+	if registry.Backend(backendName) == nil {
+		return registry.Unregistered(backendName)
+	}
+
+	effectiveProfile := cfg.EffectiveProfile(profile)
+	nsBackend, err := registry.Namespaced(backendName, cfg.Project, effectiveProfile)
+	if err != nil {
+		return fmt.Errorf("creating namespaced backend: %w", err)
+	}
+
+	if err := storeReceivedSecret(nsBackend, backendName, key, bundle.Value, force); err != nil {
+		return err
+	}
+
+	// Log the operation to the audit log (best-effort).
+	_ = newAuditLogger(cfg, configDir).Log(audit.Entry{
+		Operation: audit.OpImport,
+		Key:       key,
+		Backend:   backendName,
+		Project:   cfg.Project,
+		Profile:   effectiveProfile,
+		Detail:    "secret receive",
+	})
+
+	// Update the .env file with a ref:// entry.
+	if err := syncEnvRef(cmd, cfg, configDir, key, backendName, effectiveProfile); err != nil {
+		output.NewWriter(cmd).Warn("could not update .env file: %v\n", err)
+	}
+
+	output.NewWriter(cmd).Info("secret %q received into backend %q\n", key, backendName)
+	return nil
+}
+
+// storeReceivedSecret stores value under key in b, the backend called
+// backendName. Unless force is set, a key that exists is refused, and so is
+// one whose existence cannot be checked.
+func storeReceivedSecret(b backend.Backend, backendName, key, value string, force bool) error {
+	if !force {
+		_, err := b.Get(key)
+		switch {
+		case err == nil:
+			return fmt.Errorf("secret %q already exists in backend %q (use --force to overwrite)", key, backendName)
+		case !errors.Is(err, backend.ErrNotFound):
+			return fmt.Errorf("checking for an existing secret %q: %w", key, err)
+		}
+	}
+	if err := b.Set(key, value); err != nil {
+		return fmt.Errorf("storing secret: %w", err)
+	}
+	return nil
+}
+
+// openShareBundle decrypts data, an age or GPG bundle created by
+// 'envref secret share', and parses it.
+func openShareBundle(data []byte, identityFile string) (*shareBundle, error) {
+	var plaintext []byte
+	if bytes.Contains(data, []byte(pgpArmorHeader)) {
+		out, err := gpgDecrypt(data)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting bundle: %w", err)
+		}
+		plaintext = out
+	} else {
+		if identityFile == "" {
+			identityFile = os.Getenv("AGE_IDENTITY")
+		}
+		if identityFile == "" {
+			return nil, fmt.Errorf("identity file is required (use --identity or set AGE_IDENTITY)")
+		}
+		identities, err := parseIdentityFile(identityFile)
+		if err != nil {
+			return nil, err
+		}
+		out, err := decryptWithIdentities(string(data), identities)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting bundle: %w", err)
+		}
+		plaintext = []byte(out)
+	}
+	defer secret.ClearBytes(plaintext)
+
+	var bundle shareBundle
+	if err := json.Unmarshal(plaintext, &bundle); err != nil || bundle.Version == 0 {
+		return nil, fmt.Errorf("not a bundle from 'envref secret share' (output of --raw is decrypted with age or gpg instead)")
+	}
+	if bundle.Version != 1 {
+		return nil, fmt.Errorf("unsupported bundle version %d; upgrade envref", bundle.Version)
+	}
+	return &bundle, nil
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/xcke/envref/internal/backend"
+)
+
+// writeIdentity generates an age identity, writes it to a key file in dir,
+// and returns the file and the identity's public key.
+func writeIdentity(t *testing.T, dir, name string) (path, recipient string) {
+	t.Helper()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("generating identity: %v", err)
+	}
+	path = filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(identity.String()+"\n"), 0o600); err != nil {
+		t.Fatalf("writing identity: %v", err)
+	}
+	return path, identity.Recipient().String()
+}
+
+func TestSecretReceiveCmd_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+
+	aliceKey, alice := writeIdentity(t, dir, "alice.txt")
+	bobKey, bob := writeIdentity(t, dir, "bob.txt")
+
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "sk-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	bundle, stderr, err := execCmd(t, "secret", "share", "api_key", "--to", alice, "--to", bob)
+	if err != nil {
+		t.Fatalf("secret share: %v\n%s", err, stderr)
+	}
+	if strings.Contains(bundle, "sk-123") {
+		t.Fatal("bundle contains the plaintext value")
+	}
+
+	// Each recipient can receive the bundle.
+	for _, identity := range []string{aliceKey, bobKey} {
+		stdout, stderr, err := execCmdWithStdin(t, bundle, "secret", "receive", "-i", identity, "--as", "copy_"+filepath.Base(identity)[:3], "--no-env")
+		if err != nil {
+			t.Fatalf("secret receive with %s: %v\n%s", identity, err, stderr)
+		}
+		if !strings.Contains(stdout, "received") {
+			t.Errorf("unexpected output: %q", stdout)
+		}
+	}
+	stdout, _, err := execCmd(t, "secret", "get", "copy_bob")
+	if err != nil || strings.TrimSpace(stdout) != "sk-123" {
+		t.Errorf("received value: got %q (%v), want sk-123", stdout, err)
+	}
+
+	// The shared key name is kept, and existing secrets are not overwritten.
+	path := filepath.Join(dir, "shared.age")
+	writeTestFile(t, dir, "shared.age", bundle)
+	_, _, err = execCmd(t, "secret", "receive", path, "-i", aliceKey, "--no-env")
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected an already exists error, got %v", err)
+	}
+	if _, _, err := execCmd(t, "secret", "receive", path, "-i", aliceKey, "--force", "--no-env"); err != nil {
+		t.Fatalf("secret receive --force: %v", err)
+	}
+
+	// A stranger cannot decrypt the bundle.
+	eveKey, _ := writeIdentity(t, dir, "eve.txt")
+	if _, _, err := execCmd(t, "secret", "receive", path, "-i", eveKey, "--no-env"); err == nil {
+		t.Error("expected an error for an identity that is not a recipient")
+	}
+}
+
+func TestSecretReceiveCmd_Expired(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+
+	identityFile, recipient := writeIdentity(t, dir, "key.txt")
+	r, err := age.ParseX25519Recipient(recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := time.Now().Add(-time.Hour)
+	plaintext, err := json.Marshal(shareBundle{Version: 1, Key: "api_key", Value: "sk-123", Expires: &expired})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := encryptForRecipients(string(plaintext), []*age.X25519Recipient{r})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = execCmdWithStdin(t, bundle, "secret", "receive", "-i", identityFile, "--no-env")
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("expected an expiry error, got %v", err)
+	}
+	if _, _, err := execCmd(t, "secret", "get", "api_key"); err == nil {
+		t.Error("an expired secret was stored")
+	}
+}
+
+func TestSecretReceiveCmd_NotABundle(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+
+	identityFile, recipient := writeIdentity(t, dir, "key.txt")
+	r, err := age.ParseX25519Recipient(recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := encryptForRecipients("just a value", []*age.X25519Recipient{r})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = execCmdWithStdin(t, ciphertext, "secret", "receive", "-i", identityFile, "--no-env")
+	if err == nil || !strings.Contains(err.Error(), "not a bundle") {
+		t.Errorf("expected a not a bundle error, got %v", err)
+	}
+}
+
+func TestSecretReceiveCmd_GPG(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not available")
+	}
+	t.Setenv("GNUPGHOME", t.TempDir())
+	t.Cleanup(func() { _ = exec.Command("gpgconf", "--kill", "gpg-agent").Run() })
+	gen := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-generate-key", "Test <test@example.com>", "default", "default", "never")
+	if out, err := gen.CombinedOutput(); err != nil {
+		t.Skipf("generating gpg key: %v\n%s", err, out)
+	}
+
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "sk-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	bundle, stderr, err := execCmd(t, "secret", "share", "api_key", "--gpg", "test@example.com")
+	if err != nil {
+		t.Fatalf("secret share --gpg: %v\n%s", err, stderr)
+	}
+	if !strings.HasPrefix(bundle, pgpArmorHeader) {
+		t.Fatalf("expected a PGP message, got %q", bundle)
+	}
+
+	if _, stderr, err := execCmdWithStdin(t, bundle, "secret", "receive", "--as", "gpg_key", "--no-env"); err != nil {
+		t.Fatalf("secret receive: %v\n%s", err, stderr)
+	}
+	stdout, _, err := execCmd(t, "secret", "get", "gpg_key")
+	if err != nil || strings.TrimSpace(stdout) != "sk-123" {
+		t.Errorf("received value: got %q (%v), want sk-123", stdout, err)
+	}
+}
+
+// unreachableBackend is a backend whose reads fail.
+type unreachableBackend struct {
+	*backend.MemoryBackend
+}
+
+func (unreachableBackend) Get(string) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func TestStoreReceivedSecret(t *testing.T) {
+	mem := backend.NewMemoryBackend("secrets")
+	if err := storeReceivedSecret(mem, "secrets", "api_key", "sk-1", false); err != nil {
+		t.Fatalf("storing a new secret: %v", err)
+	}
+	err := storeReceivedSecret(mem, "secrets", "api_key", "sk-2", false)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected an already exists error, got %v", err)
+	}
+	if err := storeReceivedSecret(mem, "secrets", "api_key", "sk-2", true); err != nil {
+		t.Fatalf("storing with force: %v", err)
+	}
+
+	// A backend that cannot be read is not written without --force.
+	down := unreachableBackend{backend.NewMemoryBackend("secrets")}
+	_ = down.Set("api_key", "sk-old")
+	err = storeReceivedSecret(down, "secrets", "api_key", "sk-new", false)
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expected the read error, got %v", err)
+	}
+	if got, _ := down.MemoryBackend.Get("api_key"); got != "sk-old" {
+		t.Errorf("secret was overwritten: %q", got)
+	}
+}
@@ -108,7 +108,7 @@ func runSecretRotate(cmd *cobra.Command, key string, length int, charset, backen
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
 	}
 
 	// Determine target backend.
@@ -126,17 +126,12 @@ func runSecretRotate(cmd *cobra.Command, key string, length int, charset, backen
 	// Wrap the target backend with project namespace.
 	targetBackend := registry.Backend(backendName)
 	if targetBackend == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return registry.Unregistered(backendName)
 	}
 
 	// Build the appropriate namespaced backend.
 	effectiveProfile := cfg.EffectiveProfile(profile)
-	var nsBackend *backend.NamespacedBackend
-	if effectiveProfile != "" {
-		nsBackend, err = backend.NewProfileNamespacedBackend(targetBackend, cfg.Project, effectiveProfile)
-	} else {
-		nsBackend, err = backend.NewNamespacedBackend(targetBackend, cfg.Project)
-	}
+	nsBackend, err := registry.Namespaced(backendName, cfg.Project, effectiveProfile)
 	if err != nil {
 		return fmt.Errorf("creating namespaced backend: %w", err)
 	}
@@ -169,7 +164,7 @@ func runSecretRotate(cmd *cobra.Command, key string, length int, charset, backen
 	}
 
 	// Log the operation to the audit log (best-effort).
-	_ = newAuditLogger(configDir).Log(audit.Entry{
+	_ = newAuditLogger(cfg, configDir).Log(audit.Entry{
 		Operation: audit.OpRotate,
 		Key:       key,
 		Backend:   backendName,
@@ -2,75 +2,124 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"strings"
+	"time"
 
-	"filippo.io/age"
-	"filippo.io/age/armor"
 	"github.com/spf13/cobra"
 	"github.com/xcke/envref/internal/backend"
 	"github.com/xcke/envref/internal/config"
 	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/secret"
 )
 
+// defaultShareExpiry is how long a shared secret can be received for.
+const defaultShareExpiry = 7 * 24 * time.Hour
+
+// shareBundle is the plaintext of a shared secret, as encrypted by
+// 'envref secret share' and read by 'envref secret receive'.
+type shareBundle struct {
+	// Version marks the plaintext as a bundle; it is 1.
+	Version int        `json:"envref_share"`
+	Key     string     `json:"key"`
+	Value   string     `json:"value"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
 // newSecretShareCmd creates the secret share subcommand.
 func newSecretShareCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "share <KEY>",
-		Short: "Encrypt a secret for a specific recipient",
-		Long: `Encrypt a secret from the configured backend using a recipient's age public key.
+		Short: "Encrypt a secret for one or more recipients",
+		Long: `Encrypt a secret from the configured backend for one or more recipients.
+
+The secret is encrypted together with its key name and an expiry, and
+printed to stdout as ASCII-armored ciphertext. A recipient imports it into
+their own backend with 'envref secret receive', which refuses bundles past
+their expiry:
 
-The encrypted output is printed to stdout as ASCII-armored age ciphertext.
-The recipient can decrypt it using their corresponding private key:
+  envref secret receive shared-secret.age --identity key.txt
 
-  age --decrypt -i key.txt < shared-secret.age
+Recipients are age X25519 public keys, given with --to (repeatable) or
+--to-file (one key per line, repeatable), or GPG key IDs, fingerprints, or
+emails given with --gpg (repeatable), which encrypts with the gpg binary
+instead. Age and GPG recipients cannot be mixed in one bundle.
 
-Use --to to specify the recipient's age X25519 public key directly, or
---to-file to read it from a file. Exactly one of --to or --to-file is required.
+The bundle expires after --expires (default 168h, i.e. 7 days); use
+--expires 0 for a bundle that does not expire.
+
+Earlier versions encrypted the bare value, and 'age -d' or 'gpg -d' of a
+bundle now prints JSON with the key name, value, and expiry instead. Use
+--raw to encrypt the bare value as before, for recipients who decrypt it
+with age or gpg themselves; such output has no expiry and cannot be read
+by 'envref secret receive'.
 
 Examples:
   envref secret share API_KEY --to age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
-  envref secret share DB_PASS --to-file teammate.pub
+  envref secret share API_KEY --to age1... --to age1...   # several recipients
+  envref secret share DB_PASS --to-file teammates.pub
+  envref secret share DB_PASS --gpg alice@example.com --gpg bob@example.com
+  envref secret share API_KEY --to age1... --expires 1h
   envref secret share API_KEY --to age1... --backend keychain
   envref secret share API_KEY --to age1... --profile staging
-  envref secret share API_KEY --to age1... > shared-secret.age`,
+  envref secret share API_KEY --to age1... > shared-secret.age
+  envref secret share API_KEY --to age1... --raw | age -d -i key.txt`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			to, _ := cmd.Flags().GetString("to")
-			toFile, _ := cmd.Flags().GetString("to-file")
+			to, _ := cmd.Flags().GetStringArray("to")
+			toFiles, _ := cmd.Flags().GetStringArray("to-file")
+			gpgRecipients, _ := cmd.Flags().GetStringArray("gpg")
+			expires, _ := cmd.Flags().GetDuration("expires")
 			backendName, _ := cmd.Flags().GetString("backend")
 			profile, _ := cmd.Flags().GetString("profile")
-			return runSecretShare(cmd, args[0], to, toFile, backendName, profile)
+			raw, _ := cmd.Flags().GetBool("raw")
+			if raw {
+				if cmd.Flags().Changed("expires") {
+					return fmt.Errorf("--expires cannot be used with --raw")
+				}
+				expires = 0
+			}
+			return runSecretShare(cmd, args[0], to, toFiles, gpgRecipients, expires, backendName, profile, raw)
 		},
 	}
 
-	cmd.Flags().String("to", "", "recipient's age public key (age1...)")
-	cmd.Flags().String("to-file", "", "file containing the recipient's age public key")
+	cmd.Flags().StringArray("to", nil, "recipient's age public key (age1...) — repeatable")
+	cmd.Flags().StringArray("to-file", nil, "file containing age public keys (one per line) — repeatable")
+	cmd.Flags().StringArray("gpg", nil, "recipient's GPG key ID, fingerprint, or email — repeatable")
+	cmd.Flags().Duration("expires", defaultShareExpiry, "how long the bundle can be received for (0 for no expiry)")
 	cmd.Flags().StringP("backend", "b", "", "backend to retrieve the secret from (default: first configured)")
 	cmd.Flags().StringP("profile", "P", "", "profile scope for the secret (e.g., staging, production)")
+	cmd.Flags().Bool("raw", false, "encrypt the bare value, without key name or expiry, for 'age -d' or 'gpg -d'")
 
 	return cmd
 }
 
-// runSecretShare retrieves a secret from the backend and encrypts it for the
-// given recipient using age X25519 public key encryption.
-func runSecretShare(cmd *cobra.Command, key, to, toFile, backendName, profile string) error {
+// runSecretShare retrieves a secret from the backend and encrypts it, with
+// its key and expiry, for the given age or GPG recipients. With raw, the
+// value is encrypted alone.
+func runSecretShare(cmd *cobra.Command, key string, to, toFiles, gpgRecipients []string, expires time.Duration, backendName, profile string, raw bool) error {
 	// Validate key.
 	if strings.TrimSpace(key) == "" {
 		return fmt.Errorf("key must not be empty")
 	}
+	if expires < 0 {
+		return fmt.Errorf("--expires must not be negative")
+	}
 
-	// Resolve the recipient public key.
-	recipientKey, err := resolveRecipientKey(to, toFile)
+	// Parse the age recipients.
+	recipients, err := collectRecipients(to, toFiles)
 	if err != nil {
 		return err
 	}
-
-	// Parse the age recipient.
-	recipient, err := age.ParseX25519Recipient(recipientKey)
-	if err != nil {
-		return fmt.Errorf("invalid age public key: %w", err)
+	if len(recipients) > 0 && len(gpgRecipients) > 0 {
+		return fmt.Errorf("age recipients (--to, --to-file) and --gpg cannot be combined")
+	}
+	if len(recipients) == 0 && len(gpgRecipients) == 0 {
+		return fmt.Errorf("at least one recipient is required (use --to, --to-file, or --gpg)")
 	}
 
 	// Load project config.
@@ -85,7 +134,7 @@ func runSecretShare(cmd *cobra.Command, key, to, toFile, backendName, profile st
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
 	}
 
 	// Determine target backend.
@@ -103,20 +152,38 @@ func runSecretShare(cmd *cobra.Command, key, to, toFile, backendName, profile st
 	// Wrap the target backend with project namespace.
 	targetBackend := registry.Backend(backendName)
 	if targetBackend == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return registry.Unregistered(backendName)
 	}
 
 	// Resolve effective profile from flag or config.
 	effectiveProfile := cfg.EffectiveProfile(profile)
 
 	// Retrieve the secret value (profile-scoped first if applicable).
-	value, err := getSecretValue(targetBackend, cfg.Project, effectiveProfile, key)
+	value, err := getSecretValue(registry, backendName, cfg.Project, effectiveProfile, key)
 	if err != nil {
 		return fmt.Errorf("retrieving secret: %w", err)
 	}
 
-	// Encrypt the value for the recipient.
-	encrypted, err := encryptForRecipient(value, recipient)
+	bundle := shareBundle{Version: 1, Key: key, Value: value}
+	if expires > 0 {
+		at := time.Now().UTC().Add(expires).Truncate(time.Second)
+		bundle.Expires = &at
+	}
+	var plaintext []byte
+	if raw {
+		plaintext = []byte(value)
+	} else if plaintext, err = json.Marshal(bundle); err != nil {
+		return fmt.Errorf("encoding secret: %w", err)
+	}
+	defer secret.ClearBytes(plaintext)
+
+	// Encrypt the bundle for the recipients.
+	var encrypted string
+	if len(gpgRecipients) > 0 {
+		encrypted, err = gpgEncrypt(plaintext, gpgRecipients)
+	} else {
+		encrypted, err = encryptForRecipients(string(plaintext), recipients)
+	}
 	if err != nil {
 		return fmt.Errorf("encrypting secret: %w", err)
 	}
@@ -128,50 +195,19 @@ func runSecretShare(cmd *cobra.Command, key, to, toFile, backendName, profile st
 	if effectiveProfile != "" {
 		scopeLabel = fmt.Sprintf("backend %q (profile %q)", backendName, effectiveProfile)
 	}
-	output.NewWriter(cmd).Verbose("secret %q from %s encrypted for recipient %s\n", key, scopeLabel, truncateKey(recipientKey))
-
-	return nil
-}
-
-// resolveRecipientKey resolves the recipient's age public key from either the
-// --to flag or the --to-file flag. Exactly one must be provided.
-func resolveRecipientKey(to, toFile string) (string, error) {
-	if to == "" && toFile == "" {
-		return "", fmt.Errorf("either --to or --to-file is required")
-	}
-	if to != "" && toFile != "" {
-		return "", fmt.Errorf("--to and --to-file are mutually exclusive")
-	}
-
-	if to != "" {
-		return strings.TrimSpace(to), nil
-	}
-
-	data, err := os.ReadFile(toFile)
-	if err != nil {
-		return "", fmt.Errorf("reading public key file: %w", err)
-	}
-
-	key := strings.TrimSpace(string(data))
-	if key == "" {
-		return "", fmt.Errorf("public key file %q is empty", toFile)
+	w := output.NewWriter(cmd)
+	w.Verbose("secret %q from %s encrypted for %d recipient(s)\n", key, scopeLabel, len(recipients)+len(gpgRecipients))
+	if bundle.Expires != nil {
+		w.Verbose("expires %s\n", bundle.Expires.Local().Format(time.RFC3339))
 	}
 
-	// Take the first non-comment line.
-	for _, line := range strings.Split(key, "\n") {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "#") {
-			return line, nil
-		}
-	}
-
-	return "", fmt.Errorf("no public key found in %q", toFile)
+	return nil
 }
 
 // getSecretValue retrieves a secret from the backend, trying profile scope first.
-func getSecretValue(targetBackend backend.Backend, project, profile, key string) (string, error) {
+func getSecretValue(registry *backend.Registry, backendName, project, profile, key string) (string, error) {
 	if profile != "" {
-		profileBackend, err := backend.NewProfileNamespacedBackend(targetBackend, project, profile)
+		profileBackend, err := registry.Namespaced(backendName, project, profile)
 		if err != nil {
 			return "", fmt.Errorf("creating profile backend: %w", err)
 		}
@@ -184,7 +220,7 @@ func getSecretValue(targetBackend backend.Backend, project, profile, key string)
 		}
 	}
 
-	nsBackend, err := backend.NewNamespacedBackend(targetBackend, project)
+	nsBackend, err := registry.Namespaced(backendName, project, "")
 	if err != nil {
 		return "", fmt.Errorf("creating namespaced backend: %w", err)
 	}
@@ -197,30 +233,41 @@ func isNotFound(err error) bool {
 	return err != nil && (err == backend.ErrNotFound || err.Error() == "secret not found")
 }
 
-// encryptForRecipient encrypts plaintext using the recipient's age public key
+// gpgEncrypt encrypts plaintext for the GPG recipients with the gpg binary
 // and returns ASCII-armored ciphertext.
-func encryptForRecipient(plaintext string, recipient *age.X25519Recipient) (string, error) {
-	var buf bytes.Buffer
-	armorWriter := armor.NewWriter(&buf)
-
-	writer, err := age.Encrypt(armorWriter, recipient)
-	if err != nil {
-		return "", fmt.Errorf("creating encryption writer: %w", err)
+func gpgEncrypt(plaintext []byte, recipients []string) (string, error) {
+	args := []string{"--batch", "--armor", "--encrypt"}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
 	}
-
-	if _, err := writer.Write([]byte(plaintext)); err != nil {
-		return "", fmt.Errorf("writing plaintext: %w", err)
+	out, err := runGPG(plaintext, args...)
+	if err != nil {
+		return "", err
 	}
+	return string(out), nil
+}
 
-	if err := writer.Close(); err != nil {
-		return "", fmt.Errorf("closing encryption writer: %w", err)
-	}
+// gpgDecrypt decrypts ASCII-armored GPG ciphertext with the gpg binary,
+// using the secret keys in the user's keyring.
+func gpgDecrypt(ciphertext []byte) ([]byte, error) {
+	return runGPG(ciphertext, "--batch", "--quiet", "--decrypt")
+}
 
-	if err := armorWriter.Close(); err != nil {
-		return "", fmt.Errorf("closing armor writer: %w", err)
+// runGPG runs gpg with args and input on stdin, and returns its stdout.
+func runGPG(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("gpg", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("gpg: %s", strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("gpg: %w", err)
 	}
-
-	return buf.String(), nil
+	return stdout.Bytes(), nil
 }
 
 // truncateKey returns a shortened version of an age public key for display.
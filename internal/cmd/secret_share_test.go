@@ -6,10 +6,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"filippo.io/age"
-	"filippo.io/age/armor"
-	"io"
 )
 
 // --- Tests for secret share ---
@@ -50,12 +49,12 @@ func TestSecretShareCmd_NoRecipient(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error when no recipient specified, got nil")
 	}
-	if !contains(err.Error(), "--to or --to-file is required") {
+	if !contains(err.Error(), "at least one recipient is required") {
 		t.Errorf("expected recipient error, got: %v", err)
 	}
 }
 
-func TestSecretShareCmd_MutuallyExclusive(t *testing.T) {
+func TestSecretShareCmd_MixedRecipients(t *testing.T) {
 	dir := t.TempDir()
 	writeTestConfig(t, dir, "testproject")
 
@@ -79,14 +78,14 @@ func TestSecretShareCmd_MutuallyExclusive(t *testing.T) {
 	root := NewRootCmd()
 	root.SetOut(new(bytes.Buffer))
 	root.SetErr(new(bytes.Buffer))
-	root.SetArgs([]string{"secret", "share", "API_KEY", "--to", "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p", "--to-file", keyFile})
+	root.SetArgs([]string{"secret", "share", "API_KEY", "--to-file", keyFile, "--gpg", "alice@example.com"})
 
 	err = root.Execute()
 	if err == nil {
-		t.Fatal("expected error for mutually exclusive flags, got nil")
+		t.Fatal("expected error for mixed age and GPG recipients, got nil")
 	}
-	if !contains(err.Error(), "mutually exclusive") {
-		t.Errorf("expected mutually exclusive error, got: %v", err)
+	if !contains(err.Error(), "cannot be combined") {
+		t.Errorf("expected mixed recipients error, got: %v", err)
 	}
 }
 
@@ -261,7 +260,7 @@ func TestSecretShareCmd_ToFileNotFound(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for nonexistent key file, got nil")
 	}
-	if !contains(err.Error(), "reading public key file") {
+	if !contains(err.Error(), "reading key file") {
 		t.Errorf("expected file read error, got: %v", err)
 	}
 }
@@ -295,23 +294,73 @@ func TestSecretShareCmd_ToFileEmpty(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for empty key file, got nil")
 	}
-	if !contains(err.Error(), "is empty") {
+	if !contains(err.Error(), "at least one recipient is required") {
 		t.Errorf("expected empty file error, got: %v", err)
 	}
 }
 
+func TestTruncateKey(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p", "age1ql3z7hjy54pw..."},
+		{"short", "short"},
+		{"exactly-twenty-chars", "exactly-twenty-chars"},
+		{"exactly-twenty-one!xx", "exactly-twenty-o..."},
+	}
+	for _, tt := range tests {
+		got := truncateKey(tt.input)
+		if got != tt.want {
+			t.Errorf("truncateKey(%q): got %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	if isNotFound(nil) {
+		t.Error("nil should not be not-found")
+	}
+}
+
+func TestSecretShareCmd_Raw(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+	identityFile, recipient := writeIdentity(t, dir, "key.txt")
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "sk-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	stdout, _, err := execCmd(t, "secret", "share", "api_key", "--to", recipient, "--raw")
+	if err != nil {
+		t.Fatalf("secret share --raw: %v", err)
+	}
+	identities, err := parseIdentityFile(identityFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := decryptWithIdentities(stdout, identities)
+	if err != nil || value != "sk-123" {
+		t.Errorf("decrypted raw output: got %q (%v), want sk-123", value, err)
+	}
+
+	_, _, err = execCmd(t, "secret", "share", "api_key", "--to", recipient, "--raw", "--expires", "1h")
+	if err == nil || !contains(err.Error(), "--expires cannot be used with --raw") {
+		t.Errorf("expected a --raw/--expires error, got %v", err)
+	}
+}
+
 // --- Unit tests for encryption helpers ---
 
-func TestEncryptForRecipient(t *testing.T) {
-	// Generate a key pair for testing.
+func TestEncryptForRecipients_Share(t *testing.T) {
 	identity, err := age.GenerateX25519Identity()
 	if err != nil {
 		t.Fatalf("generating identity: %v", err)
 	}
-	recipient := identity.Recipient()
 
 	plaintext := "super-secret-value-123"
-	encrypted, err := encryptForRecipient(plaintext, recipient)
+	encrypted, err := encryptForRecipients(plaintext, []*age.X25519Recipient{identity.Recipient()})
 	if err != nil {
 		t.Fatalf("encrypting: %v", err)
 	}
@@ -324,74 +373,91 @@ func TestEncryptForRecipient(t *testing.T) {
 		t.Error("expected armor footer")
 	}
 
-	// Decrypt and verify the plaintext matches.
-	armorReader := armor.NewReader(strings.NewReader(encrypted))
-	reader, err := age.Decrypt(armorReader, identity)
+	decrypted, err := decryptWithIdentities(encrypted, []age.Identity{identity})
 	if err != nil {
 		t.Fatalf("decrypting: %v", err)
 	}
-	decrypted, err := io.ReadAll(reader)
-	if err != nil {
-		t.Fatalf("reading decrypted: %v", err)
-	}
-	if string(decrypted) != plaintext {
-		t.Errorf("decrypted value: got %q, want %q", string(decrypted), plaintext)
+	if decrypted != plaintext {
+		t.Errorf("decrypted value: got %q, want %q", decrypted, plaintext)
 	}
 }
 
-func TestEncryptForRecipient_EmptyPlaintext(t *testing.T) {
+func TestEncryptForRecipients_EmptyPlaintext(t *testing.T) {
 	identity, err := age.GenerateX25519Identity()
 	if err != nil {
 		t.Fatalf("generating identity: %v", err)
 	}
-	recipient := identity.Recipient()
 
-	encrypted, err := encryptForRecipient("", recipient)
+	encrypted, err := encryptForRecipients("", []*age.X25519Recipient{identity.Recipient()})
 	if err != nil {
 		t.Fatalf("encrypting empty plaintext: %v", err)
 	}
-
-	// Should still be valid armor.
 	if !strings.HasPrefix(encrypted, "-----BEGIN AGE ENCRYPTED FILE-----") {
 		t.Error("expected armor header for empty plaintext")
 	}
 
-	// Decrypt and verify empty.
-	armorReader := armor.NewReader(strings.NewReader(encrypted))
-	reader, err := age.Decrypt(armorReader, identity)
+	decrypted, err := decryptWithIdentities(encrypted, []age.Identity{identity})
 	if err != nil {
 		t.Fatalf("decrypting: %v", err)
 	}
-	decrypted, err := io.ReadAll(reader)
+	if decrypted != "" {
+		t.Errorf("expected empty decrypted value, got %q", decrypted)
+	}
+}
+
+func TestEncryptForRecipients_EachRecipientDecrypts(t *testing.T) {
+	var identities []*age.X25519Identity
+	var recipients []*age.X25519Recipient
+	for range 3 {
+		identity, err := age.GenerateX25519Identity()
+		if err != nil {
+			t.Fatalf("generating identity: %v", err)
+		}
+		identities = append(identities, identity)
+		recipients = append(recipients, identity.Recipient())
+	}
+
+	encrypted, err := encryptForRecipients("shared-value", recipients)
+	if err != nil {
+		t.Fatalf("encrypting: %v", err)
+	}
+	for i, identity := range identities {
+		decrypted, err := decryptWithIdentities(encrypted, []age.Identity{identity})
+		if err != nil || decrypted != "shared-value" {
+			t.Errorf("recipient %d: got %q (%v)", i, decrypted, err)
+		}
+	}
+
+	stranger, err := age.GenerateX25519Identity()
 	if err != nil {
-		t.Fatalf("reading decrypted: %v", err)
+		t.Fatalf("generating identity: %v", err)
 	}
-	if string(decrypted) != "" {
-		t.Errorf("expected empty decrypted value, got %q", string(decrypted))
+	if _, err := decryptWithIdentities(encrypted, []age.Identity{stranger}); err == nil {
+		t.Error("expected an error decrypting as a non-recipient")
 	}
 }
 
-func TestResolveRecipientKey_Direct(t *testing.T) {
-	key, err := resolveRecipientKey("age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p", "")
+func TestCollectRecipients_Direct(t *testing.T) {
+	recipients, err := collectRecipients([]string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"}, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if key != "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p" {
-		t.Errorf("got %q", key)
+	if len(recipients) != 1 || recipients[0].String() != "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p" {
+		t.Errorf("got %v", recipients)
 	}
 }
 
-func TestResolveRecipientKey_DirectWithWhitespace(t *testing.T) {
-	key, err := resolveRecipientKey("  age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p  \n", "")
+func TestCollectRecipients_DirectWithWhitespace(t *testing.T) {
+	recipients, err := collectRecipients([]string{"  age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p  \n"}, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if key != "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p" {
-		t.Errorf("got %q", key)
+	if len(recipients) != 1 || recipients[0].String() != "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p" {
+		t.Errorf("got %v", recipients)
 	}
 }
 
-func TestResolveRecipientKey_FromFile(t *testing.T) {
+func TestCollectRecipients_FromFileWithComments(t *testing.T) {
 	dir := t.TempDir()
 	keyFile := filepath.Join(dir, "recipient.pub")
 	content := "# teammate's key\nage1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p\n"
@@ -399,72 +465,135 @@ func TestResolveRecipientKey_FromFile(t *testing.T) {
 		t.Fatalf("writing file: %v", err)
 	}
 
-	key, err := resolveRecipientKey("", keyFile)
+	recipients, err := collectRecipients(nil, []string{keyFile})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if key != "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p" {
-		t.Errorf("got %q", key)
+	if len(recipients) != 1 || recipients[0].String() != "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p" {
+		t.Errorf("got %v", recipients)
 	}
 }
 
-func TestResolveRecipientKey_NeitherProvided(t *testing.T) {
-	_, err := resolveRecipientKey("", "")
-	if err == nil {
-		t.Fatal("expected error, got nil")
+func TestCollectRecipients_DirectAndFile(t *testing.T) {
+	// --to and --to-file used to be mutually exclusive; they now add up.
+	dir := t.TempDir()
+	_, other := writeIdentity(t, dir, "other.txt")
+	keyFile := filepath.Join(dir, "recipient.pub")
+	if err := os.WriteFile(keyFile, []byte(other+"\n"), 0o644); err != nil {
+		t.Fatalf("writing file: %v", err)
 	}
-	if !contains(err.Error(), "--to or --to-file is required") {
-		t.Errorf("unexpected error: %v", err)
+
+	recipients, err := collectRecipients([]string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"}, []string{keyFile})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recipients) != 2 {
+		t.Errorf("expected 2 recipients, got %d", len(recipients))
 	}
 }
 
-func TestResolveRecipientKey_BothProvided(t *testing.T) {
-	_, err := resolveRecipientKey("age1...", "some-file.pub")
-	if err == nil {
-		t.Fatal("expected error, got nil")
+func TestCollectRecipients_NeitherProvided(t *testing.T) {
+	recipients, err := collectRecipients(nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if !contains(err.Error(), "mutually exclusive") {
-		t.Errorf("unexpected error: %v", err)
+	if len(recipients) != 0 {
+		t.Errorf("expected no recipients, got %d", len(recipients))
 	}
 }
 
-func TestResolveRecipientKey_FileOnlyComments(t *testing.T) {
+func TestCollectRecipients_FileOnlyComments(t *testing.T) {
 	dir := t.TempDir()
 	keyFile := filepath.Join(dir, "comments.pub")
-	content := "# just a comment\n# another comment\n"
-	if err := os.WriteFile(keyFile, []byte(content), 0o644); err != nil {
+	if err := os.WriteFile(keyFile, []byte("# just a comment\n# another comment\n"), 0o644); err != nil {
 		t.Fatalf("writing file: %v", err)
 	}
 
-	_, err := resolveRecipientKey("", keyFile)
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	recipients, err := collectRecipients(nil, []string{keyFile})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if !contains(err.Error(), "no public key found") {
-		t.Errorf("unexpected error: %v", err)
+	if len(recipients) != 0 {
+		t.Errorf("expected no recipients, got %d", len(recipients))
 	}
 }
 
-func TestTruncateKey(t *testing.T) {
-	tests := []struct {
-		input string
-		want  string
-	}{
-		{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p", "age1ql3z7hjy54pw..."},
-		{"short", "short"},
-		{"exactly-twenty-chars", "exactly-twenty-chars"},
-		{"exactly-twenty-one!xx", "exactly-twenty-o..."},
+func TestCollectRecipients_BadRecipient(t *testing.T) {
+	_, err := collectRecipients([]string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p", "age1bogus"}, nil)
+	if err == nil || !contains(err.Error(), "invalid age public key") {
+		t.Errorf("expected an invalid key error, got %v", err)
 	}
-	for _, tt := range tests {
-		got := truncateKey(tt.input)
-		if got != tt.want {
-			t.Errorf("truncateKey(%q): got %q, want %q", tt.input, got, tt.want)
-		}
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "bad.pub")
+	if err := os.WriteFile(keyFile, []byte("ssh-ed25519 AAAA\n"), 0o644); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	_, err = collectRecipients(nil, []string{keyFile})
+	if err == nil || !contains(err.Error(), "invalid age public key in") {
+		t.Errorf("expected an invalid key error naming the file, got %v", err)
 	}
 }
 
-func TestIsNotFound(t *testing.T) {
-	if isNotFound(nil) {
-		t.Error("nil should not be not-found")
+// --- Tests for share bundle expiry ---
+
+func TestShareBundle_ExpiryRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+	identityFile, recipient := writeIdentity(t, dir, "key.txt")
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "sk-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	before := time.Now().UTC().Truncate(time.Second)
+	encrypted, _, err := execCmd(t, "secret", "share", "api_key", "--to", recipient, "--expires", "1h")
+	if err != nil {
+		t.Fatalf("secret share: %v", err)
+	}
+	bundle, err := openShareBundle([]byte(encrypted), identityFile)
+	if err != nil {
+		t.Fatalf("opening bundle: %v", err)
+	}
+	if bundle.Key != "api_key" || bundle.Value != "sk-123" {
+		t.Errorf("bundle: got key %q value %q", bundle.Key, bundle.Value)
+	}
+	if bundle.Expires == nil {
+		t.Fatal("expected an expiry")
+	}
+	if d := bundle.Expires.Sub(before); d < time.Hour || d > time.Hour+time.Minute {
+		t.Errorf("expiry %s is not an hour after %s", bundle.Expires, before)
+	}
+
+	encrypted, _, err = execCmd(t, "secret", "share", "api_key", "--to", recipient, "--expires", "0")
+	if err != nil {
+		t.Fatalf("secret share --expires 0: %v", err)
+	}
+	if bundle, err = openShareBundle([]byte(encrypted), identityFile); err != nil || bundle.Expires != nil {
+		t.Errorf("expected a bundle without expiry, got %+v (%v)", bundle, err)
+	}
+}
+
+func TestShareBundle_ExpiryRejected(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	chdir(t, dir)
+	identityFile, recipient := writeIdentity(t, dir, "key.txt")
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "sk-123", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	if _, _, err := execCmd(t, "secret", "share", "api_key", "--to", recipient, "--expires", "-1h"); err == nil || !contains(err.Error(), "must not be negative") {
+		t.Errorf("expected a negative expiry error, got %v", err)
+	}
+
+	encrypted, _, err := execCmd(t, "secret", "share", "api_key", "--to", recipient, "--expires", "1s")
+	if err != nil {
+		t.Fatalf("secret share: %v", err)
+	}
+	time.Sleep(2 * time.Second)
+	_, _, err = execCmdWithStdin(t, encrypted, "secret", "receive", "-i", identityFile, "--as", "late", "--no-env")
+	if err == nil || !contains(err.Error(), "expired") {
+		t.Errorf("expected an expiry error, got %v", err)
 	}
 }
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xcke/envref/internal/config"
+)
+
+// weakSecretValues are placeholders and commonly leaked passwords that are
+// rejected whenever a strength policy is configured. Entries are
+// lowercase.
+var weakSecretValues = []string{
+	"changeme", "change_me", "change-me", "changeit", "replaceme", "replace_me",
+	"placeholder", "example", "sample", "dummy", "test", "testing", "todo", "fixme",
+	"secret", "secret123", "mysecret", "supersecret", "your-secret-here", "your_secret_here",
+	"password", "password1", "password123", "passw0rd", "p@ssw0rd", "mypassword",
+	"admin", "admin123", "root", "toor", "default", "guest", "letmein", "welcome",
+	"qwerty", "abc123", "iloveyou", "monkey", "dragon",
+	"123456", "1234567", "12345678", "123456789", "1234567890", "000000", "111111",
+	"xxx", "xxxx", "xxxxxx", "xxxxxxxx",
+}
+
+// checkStrength returns the ways value fails policy, or nil if it passes.
+func checkStrength(policy config.StrengthConfig, value string) []string {
+	var problems []string
+
+	lower := strings.ToLower(strings.TrimSpace(value))
+	for _, denied := range append(weakSecretValues, policy.Deny...) {
+		if lower == strings.ToLower(denied) {
+			problems = append(problems, "known placeholder or leaked value")
+			break
+		}
+	}
+
+	length := utf8.RuneCountInString(value)
+	if policy.MinLength > 0 && length < policy.MinLength {
+		problems = append(problems, fmt.Sprintf("too short (%d characters, minimum %d)", length, policy.MinLength))
+	}
+	if policy.MinEntropy > 0 {
+		if bits := estimateEntropy(value); bits < policy.MinEntropy {
+			problems = append(problems, fmt.Sprintf("too predictable (about %.0f bits of entropy, minimum %.0f)", bits, policy.MinEntropy))
+		}
+	}
+	return problems
+}
+
+// estimateEntropy estimates the entropy of value in bits as its length
+// times its Shannon entropy per character. Repeated characters and short
+// alphabets lower the estimate.
+func estimateEntropy(value string) float64 {
+	return float64(utf8.RuneCountInString(value)) * shannonEntropy(value)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/config"
+)
+
+func TestCheckStrength(t *testing.T) {
+	tests := []struct {
+		policy config.StrengthConfig
+		value  string
+		want   []string
+	}{
+		{config.StrengthConfig{MinLength: 12, MinEntropy: 40}, "k7Qz9vLm2XpR4tWs", nil},
+		{config.StrengthConfig{MinLength: 12}, "ChangeMe", []string{"known placeholder", "too short (8 characters, minimum 12)"}},
+		{config.StrengthConfig{Deny: []string{"Acme2024!"}}, "acme2024!", []string{"known placeholder"}},
+		{config.StrengthConfig{MinEntropy: 40}, "aaaaaaaaaaaaaaaa", []string{"too predictable (about 0 bits"}},
+	}
+	for _, tt := range tests {
+		problems := checkStrength(tt.policy, tt.value)
+		if len(problems) != len(tt.want) {
+			t.Errorf("checkStrength(%q) = %q, want %d problem(s)", tt.value, problems, len(tt.want))
+			continue
+		}
+		for i, want := range tt.want {
+			if !strings.HasPrefix(problems[i], want) {
+				t.Errorf("checkStrength(%q)[%d] = %q, want prefix %q", tt.value, i, problems[i], want)
+			}
+		}
+	}
+}
+
+func TestSecretSetCmd_StrengthPolicy(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	cfg, err := os.ReadFile(filepath.Join(dir, config.FullFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, config.FullFileName, string(cfg)+"strength:\n  min_length: 12\n")
+	chdir(t, dir)
+
+	_, _, err = execCmd(t, "secret", "set", "DB_PASS", "--value", "changeme", "--no-env")
+	if err == nil || !strings.Contains(err.Error(), "fails the strength policy") || !strings.Contains(err.Error(), "--no-verify") {
+		t.Fatalf("expected strength policy error, got %v", err)
+	}
+	if _, _, err := execCmd(t, "secret", "get", "DB_PASS"); err == nil {
+		t.Fatal("rejected value must not be stored")
+	}
+
+	if _, _, err := execCmd(t, "secret", "set", "DB_PASS", "--value", "changeme", "--no-env", "--no-verify"); err != nil {
+		t.Fatalf("--no-verify: %v", err)
+	}
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "k7Qz9vLm2XpR4tWs", "--no-env"); err != nil {
+		t.Fatalf("strong value rejected: %v", err)
+	}
+}
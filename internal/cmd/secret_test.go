@@ -1220,3 +1220,181 @@ func searchSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestSecretCmd_BackendNamespace(t *testing.T) {
+	dir := t.TempDir()
+	vaultPath := filepath.Join(dir, "test-vault.db")
+	writeVaultTestConfig(t, dir, "nsproj", vaultPath)
+	chdir(t, dir)
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "test-passphrase")
+
+	if _, _, err := execCmd(t, "vault", "init"); err != nil {
+		t.Fatalf("vault init: %v", err)
+	}
+	// Stored with the default layout as nsproj/api_key.
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "default-layout"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	// Switch the backend to a flat layout; the old key is no longer visible.
+	cfgPath := writeVaultTestConfig(t, dir, "nsproj", vaultPath)
+	appendTestFile(t, cfgPath, "    namespace: \"{project}-{key}\"\n")
+
+	if _, _, err := execCmd(t, "secret", "get", "api_key"); err == nil {
+		t.Fatal("expected secret get to fail under the flat layout")
+	}
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "flat-layout"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	stdout, _, err := execCmd(t, "secret", "get", "api_key")
+	if err != nil {
+		t.Fatalf("secret get: %v", err)
+	}
+	if strings.TrimSpace(stdout) != "flat-layout" {
+		t.Errorf("got %q, want %q", stdout, "flat-layout")
+	}
+	stdout, _, err = execCmd(t, "secret", "list")
+	if err != nil {
+		t.Fatalf("secret list: %v", err)
+	}
+	if !strings.Contains(stdout, "api_key") || strings.Contains(stdout, "nsproj") {
+		t.Errorf("secret list = %q, want only api_key", stdout)
+	}
+}
+
+func TestSecretListCmd_Long(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "test-passphrase")
+	writeVaultTestConfig(t, dir, "testproject", filepath.Join(dir, "vault.db"))
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "sk-123"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	stdout, _, err := execCmd(t, "secret", "list", "--long")
+	if err != nil {
+		t.Fatalf("secret list --long: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected header and one row, got: %q", stdout)
+	}
+	if !strings.HasPrefix(lines[0], "KEY") || !strings.Contains(lines[0], "UPDATED BY") {
+		t.Errorf("unexpected header: %q", lines[0])
+	}
+	fields := strings.Fields(lines[1])
+	if fields[0] != "api_key" || fields[1] == "-" || len(fields) != 6 {
+		t.Errorf("expected api_key with created/updated times and author, got: %q", lines[1])
+	}
+}
+
+func TestSecretListCmd_Table(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "testproject")
+	writeTestFile(t, dir, ".env", "API_KEY=ref://secrets/api_key\nALT_KEY=ref://keychain/api_key\n")
+	chdir(t, dir)
+
+	for _, key := range []string{"api_key", "db_pass"} {
+		if _, _, err := execCmd(t, "secret", "set", key, "--value", "v-"+key, "--no-env"); err != nil {
+			t.Fatalf("secret set: %v", err)
+		}
+	}
+
+	stdout, _, err := execCmd(t, "secret", "list", "--format", "table")
+	if err != nil {
+		t.Fatalf("secret list --format table: %v", err)
+	}
+	// keychain is not configured, so ALT_KEY falls back to every backend.
+	expected := "KEY      SCOPE    BACKEND  REFERENCED BY\n" +
+		"-------  -------  -------  ----------------\n" +
+		"api_key  project  secrets  API_KEY, ALT_KEY\n" +
+		"db_pass  project  secrets  -\n"
+	if stdout != expected {
+		t.Errorf("expected %q, got %q", expected, stdout)
+	}
+	if strings.Contains(stdout, "v-api_key") {
+		t.Errorf("secret list must not print values: %q", stdout)
+	}
+
+	if _, _, err := execCmd(t, "secret", "list", "--format", "json"); err == nil || !strings.Contains(err.Error(), `invalid format "json"`) {
+		t.Errorf("expected invalid format error, got %v", err)
+	}
+}
+
+func TestSecretSetCmd_File(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "test-passphrase")
+	writeVaultTestConfig(t, dir, "testproject", filepath.Join(dir, "vault.db"))
+	chdir(t, dir)
+
+	data := []byte{0x30, 0x82, 0x00, 0xff, '\n'}
+	writeTestFile(t, dir, "cert.p12", string(data))
+
+	if _, _, err := execCmd(t, "secret", "set", "TLS_KEYSTORE", "--file", "cert.p12"); err != nil {
+		t.Fatalf("secret set --file: %v", err)
+	}
+
+	env, err := os.ReadFile(filepath.Join(dir, ".env"))
+	if err != nil {
+		t.Fatalf("reading .env: %v", err)
+	}
+	if !strings.Contains(string(env), "TLS_KEYSTORE=ref://vault/TLS_KEYSTORE?encoding=base64file") {
+		t.Errorf("expected base64file ref in .env, got: %q", env)
+	}
+
+	stdout, _, err := execCmd(t, "secret", "get", "TLS_KEYSTORE")
+	if err != nil {
+		t.Fatalf("secret get: %v", err)
+	}
+	if stdout != string(data) {
+		t.Errorf("expected raw file contents, got: %q", stdout)
+	}
+}
+
+func TestSecretSetCmd_FileAndValueExclusive(t *testing.T) {
+	_, _, err := execCmd(t, "secret", "set", "KEY", "--value", "v", "--file", "f")
+	if err == nil || !contains(err.Error(), "none of the others can be") {
+		t.Errorf("expected mutually exclusive error, got: %v", err)
+	}
+}
+
+func TestSecretCmd_BackendMiddleware(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "test-passphrase")
+	writeTestFile(t, dir, ".envref.yaml", "project: testproject\nbackends:\n  - name: vault\n    type: vault\n    config:\n      path: "+filepath.Join(dir, "vault.db")+"\n    middleware:\n      - type: cache\n      - type: rate-limit\n        rate: 100\n")
+	chdir(t, dir)
+
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "sk-123"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	stdout, _, err := execCmd(t, "secret", "get", "api_key")
+	if err != nil {
+		t.Fatalf("secret get: %v", err)
+	}
+	if strings.TrimSpace(stdout) != "sk-123" {
+		t.Errorf("expected sk-123 through middleware, got: %q", stdout)
+	}
+}
+
+func TestSecretCmd_SuggestsCloseNames(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "myapp")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "secret", "set", "api_key", "--value", "v", "--backend", "secrts")
+	if err == nil || !strings.Contains(err.Error(), `backend "secrts" is not registered; did you mean secrets?`) {
+		t.Errorf("secret set --backend secrts: got %v, want suggestion of secrets", err)
+	}
+
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "v"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	_, _, err = execCmd(t, "secret", "get", "api_kye")
+	if err == nil || !strings.Contains(err.Error(), "did you mean api_key?") {
+		t.Errorf("secret get api_kye: got %v, want suggestion of api_key", err)
+	}
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/audit"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+)
+
+// newSecretVersionsCmd creates the secret versions subcommand.
+func newSecretVersionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "versions <KEY>",
+		Short: "List the stored versions of a secret",
+		Long: `List the versions of a secret kept by a backend that versions its values
+(hashicorp-vault KV v2, aws-ssm). Values are never printed.
+
+Examples:
+  envref secret versions API_KEY                     # default backend
+  envref secret versions API_KEY --backend aws-ssm   # specific backend
+  envref secret versions API_KEY --profile staging   # profile-scoped`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backendName, _ := cmd.Flags().GetString("backend")
+			profile, _ := cmd.Flags().GetString("profile")
+			return runSecretVersions(cmd, args[0], backendName, profile)
+		},
+	}
+
+	cmd.Flags().StringP("backend", "b", "", "backend to read versions from (default: first configured)")
+	cmd.Flags().StringP("profile", "P", "", "profile scope for the secret (e.g., staging, production)")
+
+	return cmd
+}
+
+// newSecretRollbackCmd creates the secret rollback subcommand.
+func newSecretRollbackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback <KEY> --to <VERSION>",
+		Short: "Restore an earlier version of a secret",
+		Long: `Make an earlier version of a secret current again. The backend stores the
+old value as a new version, so a rollback can itself be rolled back.
+
+Use "envref secret versions KEY" to find the version number.
+
+Examples:
+  envref secret rollback API_KEY --to 3
+  envref secret rollback API_KEY --to 3 --backend hashicorp-vault --profile staging`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			to, _ := cmd.Flags().GetInt("to")
+			backendName, _ := cmd.Flags().GetString("backend")
+			profile, _ := cmd.Flags().GetString("profile")
+			return runSecretRollback(cmd, args[0], to, backendName, profile)
+		},
+	}
+
+	cmd.Flags().Int("to", 0, "version number to restore")
+	_ = cmd.MarkFlagRequired("to")
+	cmd.Flags().StringP("backend", "b", "", "backend holding the secret (default: first configured)")
+	cmd.Flags().StringP("profile", "P", "", "profile scope for the secret (e.g., staging, production)")
+
+	return cmd
+}
+
+// secretTarget is a project-namespaced backend selected by the --backend
+// and --profile flags of a secret subcommand.
+type secretTarget struct {
+	cfg         *config.Config
+	configDir   string
+	registry    *backend.Registry
+	backendName string
+	profile     string
+	ns          *backend.NamespacedBackend
+}
+
+// openSecretTarget loads the project config and returns the namespaced
+// backend for backendName (default: the first configured backend) and
+// profile. The caller must close t.registry.
+func openSecretTarget(backendName, profile string) (*secretTarget, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+
+	cfg, configDir, err := config.Load(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	if len(cfg.Backends) == 0 {
+		return nil, withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
+	}
+	if backendName == "" {
+		backendName = cfg.Backends[0].Name
+	}
+
+	registry, err := buildRegistry(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("initializing backends: %w", err)
+	}
+	if registry.Backend(backendName) == nil {
+		registry.CloseAll()
+		return nil, registry.Unregistered(backendName)
+	}
+
+	effectiveProfile := cfg.EffectiveProfile(profile)
+	ns, err := registry.Namespaced(backendName, cfg.Project, effectiveProfile)
+	if err != nil {
+		registry.CloseAll()
+		return nil, fmt.Errorf("creating namespaced backend: %w", err)
+	}
+
+	return &secretTarget{
+		cfg:         cfg,
+		configDir:   configDir,
+		registry:    registry,
+		backendName: backendName,
+		profile:     effectiveProfile,
+		ns:          ns,
+	}, nil
+}
+
+// versionError adds the backend name to the errors of version operations.
+func versionError(err error, key, backendName string) error {
+	switch {
+	case errors.Is(err, backend.ErrVersioningUnsupported):
+		return fmt.Errorf("backend %q does not keep secret versions", backendName)
+	case errors.Is(err, backend.ErrNotFound):
+		return fmt.Errorf("secret %q not found in backend %q", key, backendName)
+	default:
+		return err
+	}
+}
+
+// runSecretVersions prints the versions of key as a table.
+func runSecretVersions(cmd *cobra.Command, key, backendName, profile string) error {
+	t, err := openSecretTarget(backendName, profile)
+	if err != nil {
+		return err
+	}
+	defer t.registry.CloseAll()
+
+	versions, err := t.ns.ListVersions(key)
+	if err != nil {
+		return versionError(err, key, t.backendName)
+	}
+
+	rows := [][]string{{"VERSION", "CREATED", "CREATED BY", "STATUS"}}
+	for _, v := range versions {
+		status := ""
+		switch {
+		case v.Current:
+			status = "current"
+		case v.Deleted:
+			status = "deleted"
+		}
+		rows = append(rows, []string{strconv.Itoa(v.Number), formatMetadataTime(v.Created), orDash(v.CreatedBy), status})
+	}
+
+	widths := make([]int, 3)
+	for _, row := range rows {
+		for i := range widths {
+			widths[i] = max(widths[i], len(row[i]))
+		}
+	}
+	out := cmd.OutOrStdout()
+	for _, row := range rows {
+		_, _ = fmt.Fprintf(out, "%-*s  %-*s  %-*s  %s\n", widths[0], row[0], widths[1], row[1], widths[2], row[2], row[3])
+	}
+	return nil
+}
+
+// runSecretRollback restores version to of key and records it in the audit
+// log.
+func runSecretRollback(cmd *cobra.Command, key string, to int, backendName, profile string) error {
+	if to < 1 {
+		return fmt.Errorf("--to must be a version number of at least 1")
+	}
+
+	t, err := openSecretTarget(backendName, profile)
+	if err != nil {
+		return err
+	}
+	defer t.registry.CloseAll()
+
+	if err := t.ns.Rollback(key, to); err != nil {
+		if errors.Is(err, backend.ErrNotFound) {
+			return fmt.Errorf("version %d of secret %q not found in backend %q", to, key, t.backendName)
+		}
+		return versionError(err, key, t.backendName)
+	}
+
+	// Log the operation to the audit log (best-effort).
+	_ = newAuditLogger(t.cfg, t.configDir).Log(audit.Entry{
+		Operation: audit.OpRollback,
+		Key:       key,
+		Backend:   t.backendName,
+		Project:   t.cfg.Project,
+		Profile:   t.profile,
+		Detail:    fmt.Sprintf("to version %d", to),
+	})
+
+	output.NewWriter(cmd).Info("secret %q rolled back to version %d in backend %q\n", key, to, t.backendName)
+	return nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// buildVaultMockCmd compiles the mock HashiCorp vault CLI used by the
+// backend tests into a temporary directory and returns its path.
+func buildVaultMockCmd(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available, skipping hashicorp-vault tests")
+	}
+
+	binPath := filepath.Join(t.TempDir(), "vault")
+	if runtime.GOOS == "windows" {
+		binPath += ".exe"
+	}
+	src := filepath.Join("..", "backend", "testdata", "vault_mock.go")
+	cmd := exec.Command("go", "build", "-o", binPath, src)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("failed to build vault mock: %v", err)
+	}
+	return binPath
+}
+
+func TestSecretVersionsAndRollback(t *testing.T) {
+	vaultPath := buildVaultMockCmd(t)
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	writeTestFile(t, dir, ".envref.yaml", "project: testproject\nbackends:\n  - name: hashicorp-vault\n    type: hashicorp-vault\n    config:\n      command: "+vaultPath+"\n")
+	chdir(t, dir)
+
+	for _, v := range []string{"first", "second"} {
+		if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", v, "--no-env"); err != nil {
+			t.Fatalf("secret set: %v", err)
+		}
+	}
+
+	stdout, _, err := execCmd(t, "secret", "versions", "api_key")
+	if err != nil {
+		t.Fatalf("secret versions: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "VERSION") {
+		t.Fatalf("expected header and two versions, got: %q", stdout)
+	}
+	if !strings.HasPrefix(lines[2], "2") || !strings.HasSuffix(lines[2], "current") {
+		t.Errorf("expected version 2 to be current, got: %q", lines[2])
+	}
+
+	if _, _, err := execCmd(t, "secret", "rollback", "api_key", "--to", "1"); err != nil {
+		t.Fatalf("secret rollback: %v", err)
+	}
+	stdout, _, err = execCmd(t, "secret", "get", "api_key")
+	if err != nil {
+		t.Fatalf("secret get: %v", err)
+	}
+	if strings.TrimSpace(stdout) != "first" {
+		t.Errorf("expected value %q after rollback, got: %q", "first", stdout)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ".envref.audit.log"))
+	if err != nil {
+		t.Fatalf("reading audit log: %v", err)
+	}
+	if !strings.Contains(string(data), `"rollback"`) {
+		t.Errorf("expected rollback entry in audit log, got: %s", data)
+	}
+}
+
+func TestSecretVersions_Unsupported(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "test-passphrase")
+	writeVaultTestConfig(t, dir, "testproject", filepath.Join(dir, "vault.db"))
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "secret", "versions", "api_key")
+	if err == nil || !strings.Contains(err.Error(), "does not keep secret versions") {
+		t.Errorf("expected versioning unsupported error, got: %v", err)
+	}
+}
+
+func TestSecretRollback_InvalidVersion(t *testing.T) {
+	_, _, err := execCmd(t, "secret", "rollback", "api_key", "--to", "0")
+	if err == nil || !strings.Contains(err.Error(), "at least 1") {
+		t.Errorf("expected invalid version error, got: %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/jsonrpc"
+	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/suggest"
+)
+
+// newServeCmd creates the serve subcommand.
+func newServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve --stdio",
+		Short: "Serve envref operations over JSON-RPC for editor integrations",
+		Long: `Answer JSON-RPC 2.0 requests on stdin with responses on stdout, one
+message per line, so that an editor extension can keep one envref process
+per workspace instead of running a command on every keystroke. Requests
+apply to the project of the working directory the server was started in.
+Diagnostics go to stderr.
+
+Methods:
+  listKeys     {profile?}                      keys of the merged environment;
+                                               values of plain keys, refs of the others
+  resolveKey   {key, profile?}                 the resolved value of one key
+  setSecret    {key, value, backend?, profile?, noVerify?}
+                                               store a secret, as 'envref secret set'
+  lint         {file?, text?}                  the file checks of 'envref doctor', for
+                                               every env layer, one file, or unsaved text
+
+The server exits when stdin is closed.
+
+Example:
+  echo '{"jsonrpc":"2.0","id":1,"method":"listKeys"}' | envref serve --stdio`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stdio, _ := cmd.Flags().GetBool("stdio")
+			if !stdio {
+				return fmt.Errorf("--stdio is required (it is the only transport)")
+			}
+			return serveStdio(cmd, newRPCServer(cmd))
+		},
+	}
+
+	cmd.Flags().Bool("stdio", false, "serve on stdin and stdout")
+
+	return cmd
+}
+
+// serveStdio serves srv on the command's stdin and stdout.
+func serveStdio(cmd *cobra.Command, srv *jsonrpc.Server) error {
+	// Only responses may reach stdout; whatever the commands reused by the
+	// methods print goes to stderr instead.
+	out := cmd.OutOrStdout()
+	cmd.SetOut(cmd.ErrOrStderr())
+
+	return srv.Serve(cmdContext(cmd), cmd.InOrStdin(), out)
+}
+
+// rpcKey describes a key of the merged environment.
+type rpcKey struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+	Ref   string `json:"ref,omitempty"`
+}
+
+// rpcDiagnostic is a problem found by lint.
+type rpcDiagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+}
+
+// newRPCServer returns a JSON-RPC server whose methods run against cmd.
+func newRPCServer(cmd *cobra.Command) *jsonrpc.Server {
+	srv := jsonrpc.NewServer()
+
+	srv.Handle("listKeys", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p struct {
+			Profile string `json:"profile"`
+		}
+		if err := jsonrpc.DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		cfg, projectDir, err := loadServeConfig()
+		if err != nil {
+			return nil, err
+		}
+		env, err := loadProjectEnv(cmd, cfg, projectDir, cfg.EffectiveProfile(p.Profile))
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]rpcKey, 0, env.Len())
+		for _, e := range env.All() {
+			if e.IsRef {
+				keys = append(keys, rpcKey{Key: e.Key, Ref: e.Value})
+			} else {
+				keys = append(keys, rpcKey{Key: e.Key, Value: e.Value})
+			}
+		}
+		return keys, nil
+	})
+
+	srv.Handle("resolveKey", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p struct {
+			Key     string `json:"key"`
+			Profile string `json:"profile"`
+		}
+		if err := jsonrpc.DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if p.Key == "" {
+			return nil, jsonrpc.InvalidParams("key is required")
+		}
+		entries, err := resolveEnvEntries(cmd, p.Profile, false)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, len(entries))
+		for i, e := range entries {
+			if e.Key != p.Key {
+				names[i] = e.Key
+				continue
+			}
+			if !e.WasRef && ref.IsRef(e.Value) {
+				return nil, fmt.Errorf("%s: %s could not be resolved", p.Key, e.Value)
+			}
+			return rpcKey{Key: e.Key, Value: e.Value}, nil
+		}
+		return nil, fmt.Errorf("key %q not found%s", p.Key, suggest.FormatSuggestion(suggest.Keys(p.Key, names)))
+	})
+
+	srv.Handle("setSecret", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p struct {
+			Key      string `json:"key"`
+			Value    string `json:"value"`
+			Backend  string `json:"backend"`
+			Profile  string `json:"profile"`
+			NoVerify bool   `json:"noVerify"`
+		}
+		if err := jsonrpc.DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if p.Key == "" || p.Value == "" {
+			return nil, jsonrpc.InvalidParams("key and value are required")
+		}
+		if err := runSecretSet(cmd, p.Key, p.Value, "", p.Backend, p.Profile, p.NoVerify); err != nil {
+			return nil, err
+		}
+		return struct{}{}, nil
+	})
+
+	srv.Handle("lint", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p struct {
+			File string  `json:"file"`
+			Text *string `json:"text"`
+		}
+		if err := jsonrpc.DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		issues, err := lintForRPC(p.File, p.Text)
+		if err != nil {
+			return nil, err
+		}
+		diags := make([]rpcDiagnostic, len(issues))
+		for i, iss := range issues {
+			diags[i] = rpcDiagnostic(iss)
+		}
+		return diags, nil
+	})
+
+	return srv
+}
+
+// loadServeConfig loads the project of the working directory.
+func loadServeConfig() (*config.Config, string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, "", fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, projectDir, err := config.Load(cwd)
+	if err != nil {
+		return nil, "", fmt.Errorf("loading config: %w", err)
+	}
+	return cfg, projectDir, nil
+}
+
+// lintForRPC runs the doctor file checks on file, or on text as if it were
+// file's contents, or on every existing env layer if file is empty.
+func lintForRPC(file string, text *string) ([]issue, error) {
+	if text != nil {
+		if file == "" {
+			return nil, jsonrpc.InvalidParams("file is required with text")
+		}
+		return lintText(file, *text)
+	}
+
+	var paths []string
+	if file != "" {
+		paths = []string{file}
+	} else {
+		cfg, projectDir, err := loadServeConfig()
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range projectEnvPaths(cfg, projectDir, cfg.EffectiveProfile("")) {
+			if fileExists(path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	var issues []issue
+	for _, path := range paths {
+		fileIssues, err := checkEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, fileIssues...)
+	}
+	return append(issues, checkRequiredRefs(paths...)...), nil
+}
+
+// lintText checks text, the unsaved contents of file, through a temporary
+// copy, and reports the issues against file.
+func lintText(file, text string) ([]issue, error) {
+	tmp, err := os.CreateTemp("", "envref-lint-*"+filepath.Ext(file))
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.WriteString(text); err != nil {
+		_ = tmp.Close()
+		return nil, fmt.Errorf("writing temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("writing temporary file: %w", err)
+	}
+
+	issues, err := checkEnvFile(tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("checking %s: %w", file, err)
+	}
+	for i := range issues {
+		issues[i].File = file
+	}
+	return issues, nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// rpcResponse is a decoded JSON-RPC response line.
+type rpcResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// serveRequests sends requests to "envref serve --stdio" and returns the
+// responses, in order.
+func serveRequests(t *testing.T, requests ...string) []rpcResponse {
+	t.Helper()
+	stdout, stderr, err := execCmdWithStdin(t, strings.Join(requests, "\n")+"\n", "serve", "--stdio")
+	if err != nil {
+		t.Fatalf("serve: %v\n%s", err, stderr)
+	}
+	var responses []rpcResponse
+	for _, line := range strings.Split(strings.TrimSuffix(stdout, "\n"), "\n") {
+		var resp rpcResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("stdout line %q is not a response: %v", line, err)
+		}
+		responses = append(responses, resp)
+	}
+	if len(responses) != len(requests) {
+		t.Fatalf("got %d responses to %d requests:\n%s", len(responses), len(requests), stdout)
+	}
+	return responses
+}
+
+func TestServeCmd_Methods(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "app")
+	writeTestFile(t, dir, ".env", "PORT=3000\nAPI_KEY=ref://secrets/API_KEY\n")
+	chdir(t, dir)
+
+	resps := serveRequests(t,
+		`{"jsonrpc":"2.0","id":1,"method":"setSecret","params":{"key":"API_KEY","value":"sk-123"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"listKeys"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"resolveKey","params":{"key":"API_KEY"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resolveKey","params":{"key":"API_KY"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"lint","params":{"file":".env","text":"A=1\nA=2\nB=x \n"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"setSecret","params":{"key":"X"}}`,
+	)
+
+	if resps[0].Error != nil {
+		t.Fatalf("setSecret: %s", resps[0].Error.Message)
+	}
+	if got := string(resps[1].Result); got != `[{"key":"PORT","value":"3000"},{"key":"API_KEY","ref":"ref://secrets/API_KEY"}]` {
+		t.Errorf("listKeys: %s", got)
+	}
+	if got := string(resps[2].Result); got != `{"key":"API_KEY","value":"sk-123"}` {
+		t.Errorf("resolveKey: %s", got)
+	}
+	if resps[3].Error == nil || !strings.Contains(resps[3].Error.Message, `key "API_KY" not found`) ||
+		!strings.Contains(resps[3].Error.Message, "API_KEY") {
+		t.Errorf("resolveKey of a missing key: %+v", resps[3])
+	}
+
+	var diags []rpcDiagnostic
+	if err := json.Unmarshal(resps[4].Result, &diags); err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	if len(diags) != 2 || diags[0].File != ".env" || diags[0].Line != 2 || diags[1].Key != "B" {
+		t.Errorf("lint: %+v", diags)
+	}
+
+	if resps[5].Error == nil || resps[5].Error.Code != -32602 {
+		t.Errorf("setSecret without a value: %+v", resps[5])
+	}
+}
+
+func TestServeCmd_RequiresStdio(t *testing.T) {
+	_, _, err := execCmd(t, "serve")
+	if err == nil || !strings.Contains(err.Error(), "--stdio is required") {
+		t.Fatalf("expected --stdio error, got %v", err)
+	}
+}
@@ -2,55 +2,140 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
 	"github.com/xcke/envref/internal/envfile"
 	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/schema"
 )
 
 // newSetCmd creates the set subcommand.
 func newSetCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "set <KEY>=<VALUE>",
-		Short: "Set an environment variable in a .env file",
-		Long: `Set or update a single key-value pair in a .env file.
+		Use:   "set <KEY>=<VALUE>...",
+		Short: "Set environment variables in a .env file",
+		Long: `Set or update key-value pairs in a .env file.
 
-The argument must be in KEY=VALUE format. If the key already exists in the
+Each argument must be in KEY=VALUE format. If a key already exists in the
 target file, its value is updated in place. If the key is new, it is appended.
 
+With --stdin, the pairs are read from standard input in .env format
+instead, so a bootstrap script can set many keys at once:
+
+  ./gen-secrets | envref set --stdin --local
+
+All values are validated before any is written: if one is rejected, the
+file is left unchanged.
+
 By default, values are written to .env. Use --local to write to .env.local
-instead (for personal overrides that should not be committed).`,
-		Args: cobra.ExactArgs(1),
+instead (for personal overrides that should not be committed).
+
+If the key is annotated with a type comment in the target file or in .env
+(e.g., "# @type: int"), the value is validated before it is written. Rules
+from the schema: block of .envref.yaml are enforced the same way, including
+keys that must be ref:// references.
+
+Use --type to validate the values as a given type when neither declares
+one, e.g. "envref set --type url API_URL=https://api.example.com". It
+accepts the same types as "# @type:" annotations (url, int, bool, port,
+enum(a, b), ...).
+
+Keys matching a require_refs pattern in .envref.yaml (e.g., "*_TOKEN") only
+accept ref:// references in files committed to git. Plaintext values for
+them can go to .env.local with --local.
+
+With --encrypt, the values are written as enc:// values, encrypted with the
+project key named by encryption.key in .envref.yaml, so that small secrets
+can be committed with the .env file. They are checked against types and
+the schema before they are encrypted, and decrypted again by resolve and
+run.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if fromStdin, _ := cmd.Flags().GetBool("stdin"); fromStdin {
+				if len(args) > 0 {
+					return fmt.Errorf("--stdin cannot be combined with KEY=VALUE arguments")
+				}
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			file, _ := cmd.Flags().GetString("file")
 			localFile, _ := cmd.Flags().GetString("local-file")
 			useLocal, _ := cmd.Flags().GetBool("local")
+			fromStdin, _ := cmd.Flags().GetBool("stdin")
+			typ, _ := cmd.Flags().GetString("type")
+			encrypt, _ := cmd.Flags().GetBool("encrypt")
 
 			targetFile := file
 			if useLocal {
 				targetFile = localFile
 			}
 
-			return runSet(cmd, args[0], targetFile)
+			var pairs []kvPair
+			if fromStdin {
+				env, warnings, err := envfile.Read(cmd.InOrStdin())
+				if err != nil {
+					return fmt.Errorf("reading stdin: %w", err)
+				}
+				printWarnings(cmd, envPathName(stdinPath), warnings)
+				for _, entry := range env.All() {
+					pairs = append(pairs, kvPair{Key: entry.Key, Value: entry.Value})
+				}
+			} else {
+				for _, arg := range args {
+					key, value, err := parseKeyValue(arg)
+					if err != nil {
+						return err
+					}
+					pairs = append(pairs, kvPair{Key: key, Value: value})
+				}
+			}
+
+			if typ != "" {
+				rule, err := schema.RuleFromType(typ)
+				if err != nil {
+					return err
+				}
+				for _, p := range pairs {
+					if err := schema.ValidateValue(rule, p.Value); err != nil {
+						return fmt.Errorf("%s: %w", p.Key, err)
+					}
+				}
+			}
+
+			var encKey string
+			if encrypt {
+				var err error
+				if encKey, err = projectEncryptionKey(cmd); err != nil {
+					return err
+				}
+			}
+
+			return runSet(cmd, pairs, targetFile, file, encKey)
 		},
 	}
 
 	cmd.Flags().StringP("file", "f", ".env", "path to the .env file")
 	cmd.Flags().String("local-file", ".env.local", "path to the .env.local override file")
 	cmd.Flags().Bool("local", false, "write to .env.local instead of .env")
+	cmd.Flags().Bool("stdin", false, "read KEY=VALUE pairs in .env format from stdin")
+	cmd.Flags().String("type", "", "validate the values as this type (e.g., url, int, bool)")
+	cmd.Flags().Bool("encrypt", false, "write the values as enc:// values encrypted with the project key")
 
 	return cmd
 }
 
-// runSet parses the KEY=VALUE argument, loads the target file, updates the
-// entry, and writes the file back to disk.
-func runSet(cmd *cobra.Command, arg, targetPath string) error {
-	key, value, err := parseKeyValue(arg)
-	if err != nil {
-		return err
-	}
-
+// runSet loads the target file, updates the entries for pairs, and writes
+// the file back to disk. Annotations on an existing entry are preserved;
+// when a key is not in the target file, basePath is consulted for a type
+// annotation to validate against. Nothing is written unless every value
+// is valid. With encKey, values are validated and then written encrypted
+// with it as enc:// values.
+func runSet(cmd *cobra.Command, pairs []kvPair, targetPath, basePath, encKey string) error {
 	// Load existing file or start fresh if it doesn't exist.
 	env, warnings, err := envfile.LoadOptional(targetPath)
 	if err != nil {
@@ -58,24 +143,110 @@ func runSet(cmd *cobra.Command, arg, targetPath string) error {
 	}
 	printWarnings(cmd, targetPath, warnings)
 
-	// Create the entry.
-	entry := parser.Entry{
-		Key:   key,
-		Value: value,
-		Raw:   value,
-		IsRef: strings.HasPrefix(value, parser.RefPrefix),
+	var base *envfile.Env
+	if basePath != "" && basePath != targetPath {
+		base, _, _ = envfile.LoadOptional(basePath)
 	}
 
-	env.Set(entry)
+	// Values are checked against the config schema and the ref policy, if
+	// the project declares them.
+	cfgSchema, err := loadConfigSchema()
+	if err != nil {
+		return err
+	}
+	policy, err := loadRefPolicy()
+	if err != nil {
+		return err
+	}
+
+	written := make([]kvPair, len(pairs))
+	for i, p := range pairs {
+		// Create the entry.
+		entry := parser.Entry{
+			Key:   p.Key,
+			Value: p.Value,
+			Raw:   p.Value,
+			IsRef: strings.HasPrefix(p.Value, parser.RefPrefix),
+		}
+
+		// Validate against the key's type annotation, if any.
+		annotated, found := env.Get(p.Key)
+		if found {
+			entry.Annotations = annotated.Annotations
+		} else if base != nil {
+			annotated, found = base.Get(p.Key)
+		}
+		if found {
+			if err := checkTypedValue(annotated, p.Value); err != nil {
+				return err
+			}
+		}
+		if err := checkSchemaValue(cfgSchema, p.Key, p.Value); err != nil {
+			return err
+		}
+		if encKey != "" {
+			encrypted, err := ref.Encrypt(p.Value, encKey)
+			if err != nil {
+				return fmt.Errorf("encrypting %s: %w", p.Key, err)
+			}
+			entry.Value, entry.Raw, entry.IsRef = encrypted, encrypted, false
+		}
+		if err := policy.checkValue(targetPath, p.Key, entry.Value); err != nil {
+			return err
+		}
+
+		env.Set(entry)
+		written[i] = kvPair{Key: p.Key, Value: entry.Value}
+	}
 
 	if err := env.Write(targetPath); err != nil {
 		return fmt.Errorf("writing %s: %w", targetPath, err)
 	}
 
-	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s=%s\n", key, value)
+	for _, p := range written {
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s=%s\n", p.Key, p.Value)
+	}
 	return nil
 }
 
+// projectEncryptionKey reads the encryption key named by encryption.key in
+// the project config from its backend.
+func projectEncryptionKey(cmd *cobra.Command) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, projectDir, err := config.Load(cwd)
+	if err != nil {
+		return "", fmt.Errorf("loading config: %w", err)
+	}
+	if cfg.Encryption.Key == "" {
+		return "", withExitCode(exitConfig, fmt.Errorf("no encryption.key is set in %s; point it at a secret, e.g. \"envref secret generate envref_encryption_key --no-env\" and \"encryption: {key: ref://<backend>/envref_encryption_key}\"", config.FullFileName))
+	}
+	if len(cfg.Backends) == 0 {
+		return "", withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
+	}
+
+	registry, err := buildResolveRegistry(cfg)
+	if err != nil {
+		return "", fmt.Errorf("initializing backends: %w", err)
+	}
+	defer registry.CloseAll()
+
+	env := envfile.NewEnv()
+	env.Set(parser.Entry{Key: "encryption.key", Value: cfg.Encryption.Key, IsRef: true})
+	profile := cfg.EffectiveProfile("")
+	result, err := resolveWithProgress(cmd, env, registry, cfg, cfg.ProfileChain(profile))
+	if err != nil {
+		return "", fmt.Errorf("resolving encryption key: %w", err)
+	}
+	auditRefReads(cfg, projectDir, profile, env, result)
+	if !result.Resolved() {
+		return "", withExitCode(unresolvedExitCode(result.Errors), fmt.Errorf("reading encryption key %s: %w", cfg.Encryption.Key, result.Errors[0].Err))
+	}
+	return result.Entries[0].Value, nil
+}
+
 // parseKeyValue splits a KEY=VALUE argument. The key must not be empty.
 // The value may be empty (KEY=).
 func parseKeyValue(arg string) (string, string, error) {
@@ -6,6 +6,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/xcke/envref/internal/config"
@@ -60,16 +61,18 @@ type statusReport struct {
 	// File existence.
 	envFileExists     bool
 	localFileExists   bool
-	profileFileExists bool
 	exampleFileExists bool
 	configExists      bool
 
 	// File paths (relative).
 	envFilePath     string
 	localFilePath   string
-	profileFilePath string
 	exampleFilePath string
 
+	// profileFiles are the env files of the profiles combined in the
+	// active profile.
+	profileFiles []statusProfileFile
+
 	// Key counts.
 	totalKeys  int
 	configKeys int
@@ -82,6 +85,9 @@ type statusReport struct {
 	backendsOK     bool
 	backendNames   []string
 
+	// Secrets overdue for rotation in the first backend.
+	rotationDue []rotationStatus
+
 	// Validation results (vs .env.example).
 	missingKeys []string
 	extraKeys   []string
@@ -92,7 +98,7 @@ type statusReport struct {
 
 // runStatus implements the status command logic.
 func runStatus(cmd *cobra.Command, profileOverride string) error {
-	w := output.NewWriter(cmd)
+	w := output.NewWriter(cmd).ForStdout()
 
 	report, err := buildStatusReport(cmd, profileOverride)
 	if err != nil {
@@ -144,19 +150,18 @@ func buildStatusReport(cmd *cobra.Command, profileOverride string) (*statusRepor
 	report.envFilePath = cfg.EnvFile
 	report.localFilePath = cfg.LocalFile
 
-	var profilePath string
-	if profile != "" {
-		profileEnvFile := cfg.ProfileEnvFile(profile)
-		profilePath = resolveFilePath(projectDir, profileEnvFile)
-		report.profileFilePath = profileEnvFile
+	for _, name := range config.SplitProfiles(profile) {
+		profileEnvFile := cfg.ProfileEnvFile(name)
+		report.profileFiles = append(report.profileFiles, statusProfileFile{
+			profile: name,
+			path:    profileEnvFile,
+			exists:  fileExists(resolveFilePath(projectDir, profileEnvFile)),
+		})
 	}
 
 	// Check file existence.
 	report.envFileExists = fileExists(envPath)
 	report.localFileExists = fileExists(localPath)
-	if profilePath != "" {
-		report.profileFileExists = fileExists(profilePath)
-	}
 
 	examplePath := resolveFilePath(projectDir, ".env.example")
 	report.exampleFilePath = ".env.example"
@@ -168,7 +173,7 @@ func buildStatusReport(cmd *cobra.Command, profileOverride string) (*statusRepor
 		return report, nil
 	}
 
-	env, err := loadAndMergeEnv(cmd, envPath, profilePath, localPath)
+	env, err := loadProjectEnv(cmd, cfg, projectDir, profile)
 	if err != nil {
 		return nil, err
 	}
@@ -218,6 +223,13 @@ func buildStatusReport(cmd *cobra.Command, profileOverride string) (*statusRepor
 		}
 	}
 
+	// Check rotation policies against the first backend.
+	if len(cfg.Rotation) > 0 && len(cfg.Backends) > 0 {
+		if err := checkStatusRotation(report, cfg, projectDir, profile); err != nil {
+			report.hints = append(report.hints, fmt.Sprintf("Rotation check failed: %v", err))
+		}
+	}
+
 	// Check against .env.example if it exists.
 	if report.exampleFileExists {
 		example, _, exErr := envfile.Load(examplePath)
@@ -251,14 +263,22 @@ func buildStatusReport(cmd *cobra.Command, profileOverride string) (*statusRepor
 			parsed, parseErr := ref.Parse(keyErr.Ref)
 			if parseErr != nil {
 				report.hints = append(report.hints, fmt.Sprintf("Set missing secret: envref secret set %s", keyErr.Key))
+			} else if parsed.Backend == ref.SelfBackend {
+				report.hints = append(report.hints, fmt.Sprintf("Fix %s: %v", keyErr.Key, keyErr.Err))
 			} else {
 				report.hints = append(report.hints, fmt.Sprintf("Set missing secret: envref secret set %s  (backend: %s)", parsed.Path, parsed.Backend))
 			}
 		}
 	}
 
-	if profile != "" && !report.profileFileExists && report.profileFilePath != "" {
-		report.hints = append(report.hints, fmt.Sprintf("Profile %q is active but %s does not exist.", profile, report.profileFilePath))
+	if len(report.rotationDue) > 0 {
+		report.hints = append(report.hints, "Rotate overdue secrets: envref rotate --due")
+	}
+
+	for _, f := range report.profileFiles {
+		if !f.exists {
+			report.hints = append(report.hints, fmt.Sprintf("Profile %q is active but %s does not exist.", f.profile, f.path))
+		}
 	}
 
 	if !report.exampleFileExists {
@@ -268,6 +288,13 @@ func buildStatusReport(cmd *cobra.Command, profileOverride string) (*statusRepor
 	return report, nil
 }
 
+// statusProfileFile is the env file of an active profile.
+type statusProfileFile struct {
+	profile string
+	path    string
+	exists  bool
+}
+
 // printStatusReport formats and prints the status report.
 func printStatusReport(w *output.Writer, report *statusReport) {
 	out := w.Stdout()
@@ -296,8 +323,8 @@ func printStatusReport(w *output.Writer, report *statusReport) {
 	// Files section.
 	write("\n%s\n", w.Bold("Files:"))
 	write("  %s %s\n", statusIcon(w, report.envFileExists), report.envFilePath)
-	if report.profileFilePath != "" {
-		write("  %s %s\n", statusIcon(w, report.profileFileExists), report.profileFilePath)
+	for _, f := range report.profileFiles {
+		write("  %s %s\n", statusIcon(w, f.exists), f.path)
 	}
 	write("  %s %s\n", statusIcon(w, report.localFileExists), report.localFilePath)
 	write("  %s %s\n", statusIcon(w, report.exampleFileExists), report.exampleFilePath)
@@ -340,6 +367,15 @@ func printStatusReport(w *output.Writer, report *statusReport) {
 		}
 	}
 
+	// Rotation summary.
+	if len(report.rotationDue) > 0 {
+		write("\n%s\n", w.Bold("Rotation:"))
+		for _, s := range report.rotationDue {
+			write("  %s %s last rotated %s ago (policy %s: %s)\n", w.Red("[due]"), s.Key,
+				formatAge(s.age(time.Now())), s.Pattern, formatAge(s.Interval))
+		}
+	}
+
 	// Validation summary.
 	if report.exampleFileExists {
 		write("\n%s\n", w.Bold("Validation:"))
@@ -364,16 +400,39 @@ func printStatusReport(w *output.Writer, report *statusReport) {
 	}
 
 	// Overall status.
-	if len(report.unresolvedKeys) == 0 && len(report.missingKeys) == 0 {
+	if len(report.unresolvedKeys) == 0 && len(report.missingKeys) == 0 && len(report.rotationDue) == 0 {
 		write("\nStatus: %s\n", w.Green("OK"))
 	} else {
 		issues := 0
 		issues += len(report.unresolvedKeys)
 		issues += len(report.missingKeys)
+		issues += len(report.rotationDue)
 		write("\nStatus: %s\n", w.Red(fmt.Sprintf("%d issue(s) found", issues)))
 	}
 }
 
+// checkStatusRotation records the secrets in the first backend that are
+// overdue for rotation.
+func checkStatusRotation(report *statusReport, cfg *config.Config, projectDir, profile string) error {
+	registry, err := buildRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	defer registry.CloseAll()
+
+	statuses, err := checkRotations(registry, cfg, projectDir, cfg.Backends[0].Name, profile)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, s := range statuses {
+		if s.due(now) {
+			report.rotationDue = append(report.rotationDue, s)
+		}
+	}
+	return nil
+}
+
 // statusIcon returns a colored check or cross indicator for file existence.
 func statusIcon(w *output.Writer, exists bool) string {
 	if exists {
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/remote"
+)
+
+// stdinPath is the env file path that stands for standard input, as in
+// "envref resolve -" and "envref list --file -".
+const stdinPath = "-"
+
+// stdinArg is an Args validator for commands that take an optional "-" to
+// read env definitions from standard input.
+func stdinArg(cmd *cobra.Command, args []string) error {
+	if err := cobra.MaximumNArgs(1)(cmd, args); err != nil {
+		return err
+	}
+	if len(args) == 1 && args[0] != stdinPath {
+		return fmt.Errorf("unexpected argument %q (use %q to read env definitions from stdin)", args[0], stdinPath)
+	}
+	return nil
+}
+
+// envPathName returns path for messages, naming standard input for
+// stdinPath.
+func envPathName(path string) string {
+	if path == stdinPath {
+		return "stdin"
+	}
+	return path
+}
+
+// loadEnvFile loads the env file at path, or the env content on the
+// standard input of cmd if path is stdinPath. A missing file is an error
+// only if required is true. A path that is an http(s) URL is downloaded
+// with the remote settings of cfg, which may be nil.
+//
+// Standard input can be read only once, so the input of cmd is replaced by
+// the content read: loading stdinPath again, e.g. to find the layer a key
+// comes from, sees the same entries.
+func loadEnvFile(cmd *cobra.Command, path string, required bool, cfg *config.Config) (*envfile.Env, []parser.Warning, error) {
+	if remote.IsURL(path) {
+		return loadRemoteEnvFile(cmd, path, cfg)
+	}
+	if path != stdinPath {
+		if required {
+			return envfile.Load(path)
+		}
+		return envfile.LoadOptional(path)
+	}
+	data, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading stdin: %w", err)
+	}
+	cmd.SetIn(bytes.NewReader(data))
+	return envfile.Read(bytes.NewReader(data))
+}
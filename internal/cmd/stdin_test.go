@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCmd_Stdin(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "myapp")
+	writeTestFile(t, dir, ".env", "FROM_FILE=yes\n")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "secret", "set", "api_key", "--value", "s3cret")
+	require.NoError(t, err)
+
+	stdout, _, err := execCmdWithStdin(t, "HOST=db\nURL=postgres://${HOST}\nAPI_KEY=ref://secrets/api_key\n", "resolve", "-")
+	require.NoError(t, err)
+	assert.Equal(t, "HOST=db\nURL=postgres://db\nAPI_KEY=s3cret\n", stdout)
+}
+
+func TestResolveCmd_StdinParseWarnings(t *testing.T) {
+	dir := setupProject(t, "myapp", "", "")
+	chdir(t, dir)
+
+	stdout, stderr, err := execCmdWithStdin(t, "A=1\nA=2\n", "resolve", "-")
+	require.NoError(t, err)
+	assert.Equal(t, "A=2\n", stdout)
+	assert.Contains(t, stderr, "stdin: ")
+}
+
+func TestResolveCmd_StdinArgs(t *testing.T) {
+	dir := setupProject(t, "myapp", "A=1\n", "")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "resolve", ".env")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unexpected argument ".env"`)
+	assert.Equal(t, exitUsage, exitCode(err))
+
+	_, _, err = execCmdWithStdin(t, "A=1\n", "resolve", "-", "--watch")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--watch cannot be combined with reading stdin")
+}
+
+func TestListCmd_StdinFile(t *testing.T) {
+	dir := setupProject(t, "myapp", "", "PORT=3000\n")
+	chdir(t, dir)
+
+	stdin := "PORT=8080\nAPI_KEY=ref://secrets/api_key\n"
+	stdout, _, err := execCmdWithStdin(t, stdin, "list", "--file", "-")
+	require.NoError(t, err)
+	assert.Equal(t, "PORT=3000\nAPI_KEY=ref://***\n", stdout)
+
+	stdout, _, err = execCmdWithStdin(t, stdin, "list", "--file", "-", "--format", "table")
+	require.NoError(t, err)
+	assert.Regexp(t, `PORT\s+local\s+no\s+-\s+3000`, stdout)
+	assert.Regexp(t, `API_KEY\s+base\s+yes\s+secrets\s+ref://\*\*\*`, stdout)
+}
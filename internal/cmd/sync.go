@@ -14,9 +14,9 @@ import (
 	"filippo.io/age/armor"
 	"github.com/spf13/cobra"
 	"github.com/xcke/envref/internal/audit"
-	"github.com/xcke/envref/internal/backend"
 	"github.com/xcke/envref/internal/config"
 	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/secret"
 )
 
 // defaultSyncFile is the default name for the encrypted sync file.
@@ -170,7 +170,7 @@ func runSyncPush(cmd *cobra.Command, toKeys, toFiles []string, toTeam bool, file
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
 	}
 
 	// Determine target backend.
@@ -186,17 +186,12 @@ func runSyncPush(cmd *cobra.Command, toKeys, toFiles []string, toTeam bool, file
 
 	targetBackend := registry.Backend(backendName)
 	if targetBackend == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return registry.Unregistered(backendName)
 	}
 
 	// Create namespaced backend.
 	effectiveProfile := cfg.EffectiveProfile(profile)
-	var nsBackend backend.Backend
-	if effectiveProfile != "" {
-		nsBackend, err = backend.NewProfileNamespacedBackend(targetBackend, cfg.Project, effectiveProfile)
-	} else {
-		nsBackend, err = backend.NewNamespacedBackend(targetBackend, cfg.Project)
-	}
+	nsBackend, err := registry.Namespaced(backendName, cfg.Project, effectiveProfile)
 	if err != nil {
 		return fmt.Errorf("creating namespaced backend: %w", err)
 	}
@@ -288,7 +283,7 @@ func runSyncPull(cmd *cobra.Command, identityFile, file, backendName, profile st
 	}
 
 	if len(cfg.Backends) == 0 {
-		return fmt.Errorf("no backends configured in %s", config.FullFileName)
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
 	}
 
 	// Determine target backend.
@@ -304,17 +299,12 @@ func runSyncPull(cmd *cobra.Command, identityFile, file, backendName, profile st
 
 	targetBackend := registry.Backend(backendName)
 	if targetBackend == nil {
-		return fmt.Errorf("backend %q is not registered", backendName)
+		return registry.Unregistered(backendName)
 	}
 
 	// Create namespaced backend.
 	effectiveProfile := cfg.EffectiveProfile(profile)
-	var nsBackend backend.Backend
-	if effectiveProfile != "" {
-		nsBackend, err = backend.NewProfileNamespacedBackend(targetBackend, cfg.Project, effectiveProfile)
-	} else {
-		nsBackend, err = backend.NewNamespacedBackend(targetBackend, cfg.Project)
-	}
+	nsBackend, err := registry.Namespaced(backendName, cfg.Project, effectiveProfile)
 	if err != nil {
 		return fmt.Errorf("creating namespaced backend: %w", err)
 	}
@@ -373,7 +363,7 @@ func runSyncPull(cmd *cobra.Command, identityFile, file, backendName, profile st
 		}
 
 		// Log the operation to the audit log (best-effort).
-		_ = newAuditLogger(configDir).Log(audit.Entry{
+		_ = newAuditLogger(cfg, configDir).Log(audit.Entry{
 			Operation: audit.OpImport,
 			Key:       key,
 			Backend:   backendName,
@@ -448,7 +438,9 @@ func encryptForRecipients(plaintext string, recipients []*age.X25519Recipient) (
 		return "", fmt.Errorf("creating encryption writer: %w", err)
 	}
 
-	if _, err := writer.Write([]byte(plaintext)); err != nil {
+	data := []byte(plaintext)
+	defer secret.ClearBytes(data)
+	if _, err := writer.Write(data); err != nil {
 		return "", fmt.Errorf("writing plaintext: %w", err)
 	}
 
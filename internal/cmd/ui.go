@@ -0,0 +1,533 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/tui"
+)
+
+// newUICmd creates the ui command.
+func newUICmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ui",
+		Short: "Browse and edit the environment in a terminal UI",
+		Long: `Open a full-screen terminal UI over the project's environment.
+
+The UI lists every key of the merged env layers with the layer its value
+comes from, and shows for the selected key the value each layer sets,
+marking the one that wins. From there you can:
+
+  ↑/↓, k/j   select a key
+  e          edit a plain value in the layer it comes from
+  s          store a new value for the secret a ref:// value points to
+  r          rotate that secret to a generated value (see secret rotate)
+  p          show the next profile
+  u          make the shown profile the active one (see profile use)
+  q, Ctrl-C  quit
+
+Secrets are stored in the backend the reference names, scoped to the
+shown profile. Prompts for a vault passphrase appear outside the UI.
+
+Examples:
+  envref ui                   # browse the active profile
+  envref ui --profile staging # start on the staging profile`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			return runUI(cmd, profile)
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "environment profile to show first (e.g., staging, production)")
+	// The UI only stores secrets that a reference already points to, so
+	// storing them must not add references to .env files (see noEnvFlag).
+	cmd.Flags().Bool("no-env", true, "")
+	_ = cmd.Flags().MarkHidden("no-env")
+
+	return cmd
+}
+
+// runUI runs the terminal UI until the user quits.
+func runUI(cmd *cobra.Command, profile string) error {
+	in, ok := cmd.InOrStdin().(*os.File)
+	if !ok || !tui.IsTerminal(in) {
+		return withExitCode(exitUsage, fmt.Errorf("envref ui needs an interactive terminal"))
+	}
+
+	m, err := newUIModel(cmd, profile)
+	if err != nil {
+		return err
+	}
+
+	t, err := tui.Open(in, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = t.Close() }()
+	return m.run(t)
+}
+
+// uiTerm is the terminal the UI runs on; tests substitute a fake.
+type uiTerm interface {
+	tui.Screen
+	Suspend() error
+	Resume() error
+}
+
+// uiLayer is an env layer of the shown profile.
+type uiLayer struct {
+	// name is the file name as configured, e.g. ".env.local".
+	name string
+	path string
+}
+
+// uiDef is the definition of a key in one layer.
+type uiDef struct {
+	layer int
+	entry parser.Entry
+}
+
+// uiKey is a key of the merged environment with its definitions in layer
+// order; the last one wins.
+type uiKey struct {
+	key  string
+	defs []uiDef
+}
+
+// winner returns the definition that wins the merge.
+func (k uiKey) winner() uiDef {
+	return k.defs[len(k.defs)-1]
+}
+
+// uiPrompt is a line of input the UI is reading.
+type uiPrompt struct {
+	label string
+	value []rune
+	// masked hides the typed characters.
+	masked bool
+	// confirm asks a yes/no question answered by a single key.
+	confirm bool
+	submit  func(value string) error
+	// suspend leaves the UI while submit runs, so that it can prompt.
+	suspend bool
+}
+
+// uiModel is the state of the terminal UI.
+type uiModel struct {
+	cmd        *cobra.Command
+	cfg        *config.Config
+	projectDir string
+	// profiles are the profiles to cycle through, starting with "" for
+	// none.
+	profiles []string
+	profile  string
+	layers   []uiLayer
+	keys     []uiKey
+	cursor   int
+	top      int
+	prompt   *uiPrompt
+	status   string
+}
+
+// newUIModel loads the project and its environment for profile.
+func newUIModel(cmd *cobra.Command, profile string) (*uiModel, error) {
+	m := &uiModel{cmd: cmd}
+	if err := m.loadConfig(); err != nil {
+		return nil, err
+	}
+	m.profile = m.cfg.EffectiveProfile(profile)
+	if !slices.Contains(m.profiles, m.profile) {
+		m.profiles = append(m.profiles, m.profile)
+	}
+	if err := m.loadEnv(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// loadConfig loads the project config and its profiles.
+func (m *uiModel) loadConfig() error {
+	cfg, projectDir, err := loadServeConfig()
+	if err != nil {
+		return err
+	}
+	profiles, err := discoverProfiles(cfg, projectDir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	m.cfg, m.projectDir = cfg, projectDir
+	m.profiles = append([]string{""}, names...)
+	return nil
+}
+
+// loadEnv loads the env layers of the shown profile, keeping the selected
+// key selected.
+func (m *uiModel) loadEnv() error {
+	var selected string
+	if m.cursor < len(m.keys) {
+		selected = m.keys[m.cursor].key
+	}
+
+	names := m.cfg.EnvLayers(m.profile)
+	paths := projectEnvPaths(m.cfg, m.projectDir, m.profile)
+	layers := make([]uiLayer, len(paths))
+	index := make(map[string]int)
+	var keys []uiKey
+	for i, path := range paths {
+		layers[i] = uiLayer{name: names[i], path: path}
+		entries, err := parseEnvLayer(path)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			j, ok := index[e.Key]
+			if !ok {
+				j = len(keys)
+				index[e.Key] = j
+				keys = append(keys, uiKey{key: e.Key})
+			}
+			keys[j].defs = append(keys[j].defs, uiDef{layer: i, entry: e})
+		}
+	}
+
+	m.layers, m.keys = layers, keys
+	m.cursor = 0
+	if j, ok := index[selected]; ok {
+		m.cursor = j
+	}
+	return nil
+}
+
+// run draws the UI and handles key presses until the user quits.
+func (m *uiModel) run(t uiTerm) error {
+	for {
+		if err := t.Draw(m.render(t.Size())); err != nil {
+			return err
+		}
+		key, err := t.ReadKey()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if m.handle(t, key) {
+			return nil
+		}
+	}
+}
+
+// handle applies a key press and reports whether the UI should quit.
+func (m *uiModel) handle(t uiTerm, key tui.Key) bool {
+	if key.Code == tui.KeyCtrlC {
+		return true
+	}
+	if m.prompt != nil {
+		m.handlePrompt(t, key)
+		return false
+	}
+
+	switch {
+	case key.Code == tui.KeyUp || key.Rune == 'k':
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case key.Code == tui.KeyDown || key.Rune == 'j':
+		if m.cursor < len(m.keys)-1 {
+			m.cursor++
+		}
+	case key.Code == tui.KeyEsc || key.Rune == 'q':
+		return true
+	case key.Rune == 'e':
+		m.startEdit()
+	case key.Rune == 's':
+		m.startSecretSet()
+	case key.Rune == 'r':
+		m.startSecretRotate()
+	case key.Rune == 'p':
+		i := slices.Index(m.profiles, m.profile)
+		m.profile = m.profiles[(i+1)%len(m.profiles)]
+		m.status = "showing " + uiProfileName(m.profile)
+		if err := m.loadEnv(); err != nil {
+			m.status = "Error: " + err.Error()
+		}
+	case key.Rune == 'u':
+		m.act(t, false, func() error { return runProfileUse(m.cmd, m.profile) })
+	}
+	return false
+}
+
+// handlePrompt applies a key press to the prompt being read.
+func (m *uiModel) handlePrompt(t uiTerm, key tui.Key) {
+	p := m.prompt
+	if p.confirm {
+		m.prompt = nil
+		if key.Rune == 'y' || key.Rune == 'Y' {
+			m.act(t, p.suspend, func() error { return p.submit("") })
+		} else {
+			m.status = "cancelled"
+		}
+		return
+	}
+
+	switch key.Code {
+	case tui.KeyEnter:
+		m.prompt = nil
+		m.act(t, p.suspend, func() error { return p.submit(string(p.value)) })
+	case tui.KeyEsc:
+		m.prompt = nil
+		m.status = "cancelled"
+	case tui.KeyBackspace:
+		if len(p.value) > 0 {
+			p.value = p.value[:len(p.value)-1]
+		}
+	case tui.KeyCtrlU:
+		p.value = nil
+	case tui.KeyRune:
+		p.value = append(p.value, key.Rune)
+	}
+}
+
+// selected returns the selected key, or false if there are no keys.
+func (m *uiModel) selected() (uiKey, bool) {
+	if m.cursor >= len(m.keys) {
+		return uiKey{}, false
+	}
+	return m.keys[m.cursor], true
+}
+
+// startEdit prompts for a new plain value of the selected key, written to
+// the layer its value comes from.
+func (m *uiModel) startEdit() {
+	k, ok := m.selected()
+	if !ok {
+		return
+	}
+	def := k.winner()
+	if def.entry.IsRef {
+		m.status = k.key + " is a secret reference: press s to set or r to rotate the secret"
+		return
+	}
+	layer := m.layers[def.layer]
+	m.prompt = &uiPrompt{
+		label: fmt.Sprintf("%s in %s: ", k.key, layer.name),
+		value: []rune(def.entry.Value),
+		submit: func(value string) error {
+			return runSet(m.cmd, []kvPair{{Key: k.key, Value: value}}, layer.path, m.layers[0].path, "")
+		},
+	}
+}
+
+// secretTarget returns the secret that the selected key references and
+// the backend it is stored in, or false with a status message if the key
+// is not a reference.
+func (m *uiModel) secretTarget() (string, string, bool) {
+	k, ok := m.selected()
+	if !ok {
+		return "", "", false
+	}
+	entry := k.winner().entry
+	if !entry.IsRef {
+		m.status = k.key + " is a plain value: press e to edit it"
+		return "", "", false
+	}
+	r, err := ref.Parse(entry.Value)
+	if err != nil {
+		m.status = "Error: " + err.Error()
+		return "", "", false
+	}
+	if len(r.Transforms) > 0 {
+		m.status = k.key + " is derived from " + r.Secret().Raw + ": set that secret instead"
+		return "", "", false
+	}
+	// A reference to an unknown backend resolves through every backend;
+	// store into the default one, as secret set does.
+	backendName := ""
+	if slices.ContainsFunc(m.cfg.Backends, func(b config.BackendConfig) bool { return b.Name == r.Backend }) {
+		backendName = r.Backend
+	}
+	return r.Path, backendName, true
+}
+
+// startSecretSet prompts for a new value of the secret the selected key
+// references.
+func (m *uiModel) startSecretSet() {
+	path, backendName, ok := m.secretTarget()
+	if !ok {
+		return
+	}
+	m.prompt = &uiPrompt{
+		label:   fmt.Sprintf("New value for secret %s: ", path),
+		masked:  true,
+		suspend: true,
+		submit: func(value string) error {
+			if value == "" {
+				return fmt.Errorf("secret value must not be empty")
+			}
+			return runSecretSet(m.cmd, path, value, "", backendName, m.profile, false)
+		},
+	}
+}
+
+// startSecretRotate asks to confirm rotating the secret the selected key
+// references.
+func (m *uiModel) startSecretRotate() {
+	path, backendName, ok := m.secretTarget()
+	if !ok {
+		return
+	}
+	m.prompt = &uiPrompt{
+		label:   fmt.Sprintf("Rotate secret %s%s? (y/n) ", path, profileLabel(m.profile)),
+		confirm: true,
+		suspend: true,
+		submit: func(string) error {
+			return runSecretRotate(m.cmd, path, 32, "alphanumeric", backendName, false, m.profile, 1)
+		},
+	}
+}
+
+// act runs fn with the command's output captured, shows the last line of
+// it or fn's error in the status line, and reloads the environment. With
+// suspend, the UI leaves the screen while fn runs.
+func (m *uiModel) act(t uiTerm, suspend bool, fn func() error) {
+	var out bytes.Buffer
+	prev := m.cmd.OutOrStdout()
+	m.cmd.SetOut(&out)
+	if suspend {
+		_ = t.Suspend()
+	}
+	err := fn()
+	if suspend {
+		_ = t.Resume()
+	}
+	m.cmd.SetOut(prev)
+
+	if err != nil {
+		m.status = "Error: " + err.Error()
+	} else {
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		m.status = lines[len(lines)-1]
+	}
+	if err := m.loadConfig(); err != nil {
+		m.status = "Error: " + err.Error()
+		return
+	}
+	if !slices.Contains(m.profiles, m.profile) {
+		m.profiles = append(m.profiles, m.profile)
+	}
+	if err := m.loadEnv(); err != nil {
+		m.status = "Error: " + err.Error()
+	}
+}
+
+// uiHelp lists the keys of the UI.
+const uiHelp = "↑/↓ move  e edit  s set secret  r rotate  p profile  u use profile  q quit"
+
+// render returns the lines of the screen for a terminal of the given size.
+func (m *uiModel) render(width, height int) []string {
+	var lines []string
+	add := func(s string) { lines = append(lines, tui.Truncate(s, width)) }
+
+	active := m.cfg.EffectiveProfile("")
+	add(fmt.Sprintf("envref ui — project %s, %s (active: %s)", m.cfg.Project, uiProfileName(m.profile), uiProfileName(active)))
+	add("")
+
+	keyWidth, layerWidth := len("KEY"), len("SOURCE")
+	for _, k := range m.keys {
+		keyWidth = max(keyWidth, min(len(k.key), 32))
+	}
+	for _, l := range m.layers {
+		layerWidth = max(layerWidth, len(l.name))
+	}
+	row := func(key, source, value string) string {
+		return fmt.Sprintf("  %s  %s  %s", tui.Fit(key, keyWidth), tui.Fit(source, layerWidth), value)
+	}
+	add(row("KEY", "SOURCE", "VALUE"))
+
+	// The list takes the room left by the header, the detail pane of up
+	// to one line per layer, and the status and help lines.
+	listHeight := max(height-len(lines)-len(m.layers)-5, 1)
+	if m.cursor < m.top {
+		m.top = m.cursor
+	}
+	if m.cursor >= m.top+listHeight {
+		m.top = m.cursor - listHeight + 1
+	}
+	if len(m.keys) == 0 {
+		add("  (no keys)")
+	}
+	for i := m.top; i < len(m.keys) && i < m.top+listHeight; i++ {
+		k := m.keys[i]
+		def := k.winner()
+		line := row(k.key, m.layers[def.layer].name, displayValue(def.entry, false))
+		if i == m.cursor {
+			// The selected line is highlighted across the screen.
+			line = tui.Reverse(">" + tui.Fit(line, width)[1:])
+		} else {
+			line = tui.Truncate(line, width)
+		}
+		lines = append(lines, line)
+	}
+	add("")
+
+	if k, ok := m.selected(); ok {
+		add(k.key + " (later layers override earlier ones):")
+		for i, def := range k.defs {
+			value := def.entry.Value
+			if def.entry.IsRef {
+				value = "secret " + value
+			}
+			if i == len(k.defs)-1 {
+				value += "  ← used"
+			}
+			add(fmt.Sprintf("  %s  %s", tui.Fit(m.layers[def.layer].name, layerWidth), value))
+		}
+	}
+
+	// Keep the status and help lines at the bottom of the screen.
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+	add(m.status)
+	if p := m.prompt; p != nil {
+		value := string(p.value)
+		if p.masked {
+			value = strings.Repeat("*", len(p.value))
+		}
+		add(p.label + value)
+	} else {
+		add(uiHelp)
+	}
+	return lines
+}
+
+// uiProfileName names profile for the header and status line.
+func uiProfileName(profile string) string {
+	if profile == "" {
+		return "no profile"
+	}
+	return "profile " + profile
+}
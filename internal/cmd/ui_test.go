@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/tui"
+)
+
+// fakeUITerm replays scripted key presses and records the frames drawn.
+type fakeUITerm struct {
+	keys      []tui.Key
+	frames    [][]string
+	suspended int
+}
+
+func (f *fakeUITerm) ReadKey() (tui.Key, error) {
+	if len(f.keys) == 0 {
+		return tui.Key{}, io.EOF
+	}
+	k := f.keys[0]
+	f.keys = f.keys[1:]
+	return k, nil
+}
+
+func (f *fakeUITerm) Draw(lines []string) error {
+	f.frames = append(f.frames, lines)
+	return nil
+}
+
+func (f *fakeUITerm) Size() (int, int) { return 100, 30 }
+func (f *fakeUITerm) Suspend() error   { f.suspended++; return nil }
+func (f *fakeUITerm) Resume() error    { return nil }
+
+// lastFrame returns the last frame drawn as one string.
+func (f *fakeUITerm) lastFrame() string {
+	return strings.Join(f.frames[len(f.frames)-1], "\n")
+}
+
+// typed returns the key presses of typing s.
+func typed(s string) []tui.Key {
+	var keys []tui.Key
+	for _, r := range s {
+		keys = append(keys, tui.Key{Code: tui.KeyRune, Rune: r})
+	}
+	return keys
+}
+
+// runTestUI runs the UI of the project in the working directory with the
+// given key presses.
+func runTestUI(t *testing.T, keys ...tui.Key) (*fakeUITerm, *bytes.Buffer) {
+	t.Helper()
+	cmd := newUICmd()
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(new(bytes.Buffer))
+	m, err := newUIModel(cmd, "")
+	require.NoError(t, err)
+	term := &fakeUITerm{keys: keys}
+	require.NoError(t, m.run(term))
+	return term, out
+}
+
+var (
+	uiEnter = tui.Key{Code: tui.KeyEnter}
+	uiDown  = tui.Key{Code: tui.KeyDown}
+	uiClear = tui.Key{Code: tui.KeyCtrlU}
+)
+
+func TestUI_Browse(t *testing.T) {
+	dir := setupProject(t, "myapp", "PORT=8080\nAPI_KEY=ref://secrets/api_key\n", "PORT=3000\n")
+	chdir(t, dir)
+
+	term, _ := runTestUI(t)
+	frame := term.lastFrame()
+	assert.Contains(t, frame, "project myapp, no profile")
+	assert.Regexp(t, `PORT\s+\.env\.local\s+3000`, frame)
+	assert.Regexp(t, `API_KEY\s+\.env\s+ref://\*\*\*`, frame)
+	assert.Contains(t, frame, "PORT (later layers override earlier ones):")
+	assert.Regexp(t, `\.env\s+8080\n`, frame)
+	assert.Regexp(t, `\.env\.local\s+3000  ← used`, frame)
+	assert.Contains(t, frame, uiHelp)
+}
+
+func TestUI_SelectShowsLayers(t *testing.T) {
+	dir := setupProject(t, "myapp", "PORT=8080\nAPI_KEY=ref://secrets/api_key\n", "")
+	chdir(t, dir)
+
+	term, _ := runTestUI(t, uiDown)
+	assert.Contains(t, term.lastFrame(), "secret ref://secrets/api_key  ← used")
+}
+
+func TestUI_EditPlainValue(t *testing.T) {
+	dir := setupProject(t, "myapp", "PORT=8080\n", "PORT=3000\n")
+	chdir(t, dir)
+
+	keys := append([]tui.Key{{Code: tui.KeyRune, Rune: 'e'}, uiClear}, typed("9090")...)
+	term, out := runTestUI(t, append(keys, uiEnter)...)
+
+	assert.Contains(t, term.lastFrame(), "PORT=9090")
+	assert.Empty(t, out.String(), "command output goes to the status line")
+	local, err := os.ReadFile(filepath.Join(dir, ".env.local"))
+	require.NoError(t, err)
+	assert.Equal(t, "PORT=9090\n", string(local))
+	base, err := os.ReadFile(filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "PORT=8080\n", string(base))
+}
+
+func TestUI_EditCancelled(t *testing.T) {
+	dir := setupProject(t, "myapp", "PORT=8080\n", "")
+	chdir(t, dir)
+
+	term, _ := runTestUI(t, tui.Key{Code: tui.KeyRune, Rune: 'e'}, tui.Key{Code: tui.KeyRune, Rune: '1'}, tui.Key{Code: tui.KeyEsc})
+	assert.Contains(t, term.lastFrame(), "cancelled")
+	base, err := os.ReadFile(filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "PORT=8080\n", string(base))
+}
+
+func TestUI_EditRefuses(t *testing.T) {
+	dir := setupProject(t, "myapp", "API_KEY=ref://secrets/api_key\nPORT=8080\n", "")
+	chdir(t, dir)
+
+	term, _ := runTestUI(t, tui.Key{Code: tui.KeyRune, Rune: 'e'})
+	assert.Contains(t, term.lastFrame(), "API_KEY is a secret reference")
+
+	term, _ = runTestUI(t, uiDown, tui.Key{Code: tui.KeyRune, Rune: 's'})
+	assert.Contains(t, term.lastFrame(), "PORT is a plain value")
+}
+
+func TestUI_SetSecret(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "myapp")
+	writeTestFile(t, dir, ".env", "API_KEY=ref://secrets/api_key\n")
+	chdir(t, dir)
+
+	keys := append([]tui.Key{{Code: tui.KeyRune, Rune: 's'}}, typed("s3cret-value")...)
+	term, _ := runTestUI(t, append(keys, uiEnter)...)
+
+	assert.Equal(t, 1, term.suspended)
+	assert.NotContains(t, strings.Join(term.frames[len(term.frames)-2], "\n"), "s3cret-value", "input is masked")
+	assert.Contains(t, term.lastFrame(), `secret "api_key" stored in backend "secrets"`)
+
+	stdout, _, err := execCmd(t, "secret", "get", "api_key")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret-value\n", stdout)
+	env, err := os.ReadFile(filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "API_KEY=ref://secrets/api_key\n", string(env), "the reference is unchanged")
+}
+
+func TestUI_RotateSecret(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "myapp")
+	writeTestFile(t, dir, ".env", "API_KEY=ref://secrets/api_key\n")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "secret", "set", "api_key", "--value", "old-value")
+	require.NoError(t, err)
+
+	term, _ := runTestUI(t, tui.Key{Code: tui.KeyRune, Rune: 'r'}, tui.Key{Code: tui.KeyRune, Rune: 'n'})
+	assert.Contains(t, term.lastFrame(), "cancelled")
+
+	term, _ = runTestUI(t, tui.Key{Code: tui.KeyRune, Rune: 'r'}, tui.Key{Code: tui.KeyRune, Rune: 'y'})
+	assert.Contains(t, term.lastFrame(), "previous value archived")
+
+	stdout, _, err := execCmd(t, "secret", "get", "api_key")
+	require.NoError(t, err)
+	assert.Len(t, strings.TrimSpace(stdout), 32)
+	assert.NotEqual(t, "old-value\n", stdout)
+}
+
+func TestUI_SwitchAndUseProfile(t *testing.T) {
+	dir := setupProject(t, "myapp", "PORT=8080\n", "")
+	writeTestFile(t, dir, ".env.staging", "PORT=9000\n")
+	chdir(t, dir)
+
+	term, _ := runTestUI(t, tui.Key{Code: tui.KeyRune, Rune: 'p'})
+	frame := term.lastFrame()
+	assert.Contains(t, frame, "project myapp, profile staging (active: no profile)")
+	assert.Regexp(t, `PORT\s+\.env\.staging\s+9000`, frame)
+
+	// Cycling wraps around to no profile.
+	term, _ = runTestUI(t, tui.Key{Code: tui.KeyRune, Rune: 'p'}, tui.Key{Code: tui.KeyRune, Rune: 'p'})
+	assert.Contains(t, term.lastFrame(), "showing no profile")
+
+	term, _ = runTestUI(t, tui.Key{Code: tui.KeyRune, Rune: 'p'}, tui.Key{Code: tui.KeyRune, Rune: 'u'})
+	assert.Contains(t, term.lastFrame(), "(active: profile staging)")
+	cfg, _, err := config.Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "staging", cfg.ActiveProfile)
+}
+
+func TestUICmd_NeedsTerminal(t *testing.T) {
+	dir := setupProject(t, "myapp", "PORT=8080\n", "")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "ui")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "interactive terminal")
+	assert.Equal(t, exitUsage, exitCode(err))
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
 	"github.com/xcke/envref/internal/envfile"
 	"github.com/xcke/envref/internal/output"
 	"github.com/xcke/envref/internal/schema"
@@ -34,7 +35,15 @@ and success produces no output (exit code 0 = pass, 1 = fail).
 
 Use --schema to validate values against a .env.schema.json file with type
 constraints (string, number, boolean, url, enum, email, port), patterns, and
-required/optional declarations.
+required/optional declarations. Type errors do not include the offending
+value, which may be a secret; use --show-secrets to print it.
+
+When .envref.yaml has a schema: block, its rules are always enforced as
+well, including keys that must be ref:// references:
+
+  schema:
+    DB_PORT:  { type: int, required: true }
+    API_KEY:  { required: true, ref: true }
 
 Examples:
   envref validate                                # compare .env against .env.example
@@ -50,7 +59,8 @@ Examples:
 			exampleFile, _ := cmd.Flags().GetString("example")
 			schemaFile, _ := cmd.Flags().GetString("schema")
 			ci, _ := cmd.Flags().GetBool("ci")
-			return runValidate(cmd, envFile, profileFile, localFile, exampleFile, schemaFile, ci)
+			showSecrets, _ := cmd.Flags().GetBool("show-secrets")
+			return runValidate(cmd, envFile, profileFile, localFile, exampleFile, schemaFile, ci, showSecrets)
 		},
 	}
 
@@ -60,6 +70,7 @@ Examples:
 	cmd.Flags().StringP("example", "e", ".env.example", "path to the example/schema .env file")
 	cmd.Flags().StringP("schema", "s", "", "path to .env.schema.json for type validation")
 	cmd.Flags().Bool("ci", false, "CI mode: extra keys are errors, silent on success, exit code 1 on any failure")
+	cmd.Flags().Bool("show-secrets", false, "include offending values in type errors")
 
 	return cmd
 }
@@ -67,14 +78,20 @@ Examples:
 // runValidate compares the merged environment against the example schema file.
 // When ci is true, extra keys are treated as errors, output is compact, and
 // success produces no output (exit code 0 = pass, 1 = fail).
-// When schemaPath is non-empty, values are also validated against a JSON schema.
-func runValidate(cmd *cobra.Command, envPath, profilePath, localPath, examplePath, schemaPath string, ci bool) error {
+// When schemaPath is non-empty, values are also validated against a JSON schema,
+// and the schema: block of the project config is always enforced. Type errors
+// include the offending value only when showSecrets is true.
+func runValidate(cmd *cobra.Command, envPath, profilePath, localPath, examplePath, schemaPath string, ci, showSecrets bool) error {
 	out := cmd.OutOrStdout()
 	errOut := cmd.ErrOrStderr()
 	w := output.NewWriter(cmd)
 
 	// Load and merge the effective environment.
-	merged, err := loadAndMergeEnv(cmd, envPath, profilePath, localPath)
+	cfg, err := loadOptionalConfig()
+	if err != nil {
+		return err
+	}
+	merged, err := loadAndMergeEnv(cmd, cfg, envPath, profilePath, localPath)
 	if err != nil {
 		return err
 	}
@@ -82,6 +99,7 @@ func runValidate(cmd *cobra.Command, envPath, profilePath, localPath, examplePat
 	// Track overall validation state.
 	var missing, extra []string
 	var schemaErrors []schema.ValidationError
+	var schemaSources []string
 	exampleKeyCount := 0
 
 	// --- Example-based validation (key presence) ---
@@ -128,6 +146,22 @@ func runValidate(cmd *cobra.Command, envPath, profilePath, localPath, examplePat
 
 		result := s.Validate(valueMap)
 		schemaErrors = result.Errors
+		schemaSources = append(schemaSources, schemaPath)
+	}
+
+	// --- Config schema validation (schema: block in .envref.yaml) ---
+	if cfgSchema := configSchema(cfg); cfgSchema != nil {
+		valueMap := make(map[string]string, merged.Len())
+		for _, entry := range merged.All() {
+			valueMap[entry.Key] = entry.Value
+		}
+		schemaErrors = append(schemaErrors, cfgSchema.CheckRefs(valueMap).Errors...)
+		schemaErrors = append(schemaErrors, cfgSchema.Validate(valueMap).Errors...)
+		schemaSources = append(schemaSources, config.FullFileName)
+	}
+
+	if showSecrets {
+		schemaErrors = withValues(schemaErrors)
 	}
 
 	// --- Determine if everything is OK ---
@@ -137,8 +171,8 @@ func runValidate(cmd *cobra.Command, envPath, profilePath, localPath, examplePat
 	if !hasKeyErrors && !hasSchemaErrors {
 		if !ci && !w.IsQuiet() {
 			msg := fmt.Sprintf("%s: all %d keys match %s", w.Green("OK"), exampleKeyCount, examplePath)
-			if schemaPath != "" {
-				msg += fmt.Sprintf("; schema %s OK", schemaPath)
+			if len(schemaSources) > 0 {
+				msg += fmt.Sprintf("; schema %s OK", strings.Join(schemaSources, ", "))
 			}
 			_, _ = fmt.Fprintln(out, msg)
 		}
@@ -166,7 +200,7 @@ func runValidate(cmd *cobra.Command, envPath, profilePath, localPath, examplePat
 	}
 
 	if len(schemaErrors) > 0 {
-		_, _ = fmt.Fprintf(errOut, "%s (from %s):\n", w.Red("Type errors"), schemaPath)
+		_, _ = fmt.Fprintf(errOut, "%s (from %s):\n", w.Red("Type errors"), strings.Join(schemaSources, ", "))
 		for _, e := range schemaErrors {
 			_, _ = fmt.Fprintf(errOut, "  - %s: %s\n", e.Key, e.Message)
 		}
@@ -188,7 +222,7 @@ func runValidate(cmd *cobra.Command, envPath, profilePath, localPath, examplePat
 	// Missing keys and schema errors are hard failures; extra keys alone are a warning.
 	if len(missing) > 0 || len(schemaErrors) > 0 {
 		errorCount := len(missing) + len(schemaErrors)
-		return fmt.Errorf("%d validation error(s)", errorCount)
+		return withExitCode(exitValidation, fmt.Errorf("%d validation error(s)", errorCount))
 	}
 
 	return nil
@@ -218,7 +252,20 @@ func runValidateCI(w io.Writer, missing, extra []string, schemaErrors []schema.V
 	if len(schemaErrors) > 0 {
 		parts = append(parts, fmt.Sprintf("%d type error(s)", len(schemaErrors)))
 	}
-	return fmt.Errorf("validation failed: %d error(s) (%s)", total, strings.Join(parts, ", "))
+	return withExitCode(exitValidation, fmt.Errorf("validation failed: %d error(s) (%s)", total, strings.Join(parts, ", ")))
+}
+
+// withValues returns errs with each offending value appended to its
+// message.
+func withValues(errs []schema.ValidationError) []schema.ValidationError {
+	out := make([]schema.ValidationError, len(errs))
+	for i, e := range errs {
+		if e.Value != "" {
+			e.Message = fmt.Sprintf("%s, got %q", e.Message, e.Value)
+		}
+		out[i] = e
+	}
+	return out
 }
 
 // keySet converts a slice of strings to a set (map).
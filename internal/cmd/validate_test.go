@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/xcke/envref/internal/config"
 )
 
 func TestValidateCmd_AllKeysMatch(t *testing.T) {
@@ -658,3 +660,54 @@ func TestValidateCmd_SchemaMixedKeyAndTypeErrors(t *testing.T) {
 		t.Errorf("expected '1 type error' in summary, got %q", stderr)
 	}
 }
+
+func TestValidateCmd_ConfigSchema(t *testing.T) {
+	dir := setupProject(t, "schema", "PORT=eighty\nAPI_KEY=sk-live\nTOKEN=ref://keychain/token\n", "")
+	appendTestFile(t, filepath.Join(dir, config.FullFileName),
+		"schema:\n  PORT: { type: int }\n  API_KEY: { ref: true }\n  TOKEN: { ref: true, type: int }\n")
+	writeTestFile(t, dir, ".env.example", "PORT=\nAPI_KEY=\nTOKEN=\n")
+	chdir(t, dir)
+
+	_, stderr, err := execCmd(t, "validate")
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	if !strings.Contains(stderr, "PORT: expected an integer") {
+		t.Errorf("expected PORT type error, got %q", stderr)
+	}
+	if !strings.Contains(stderr, "API_KEY: must be a ref:// reference") {
+		t.Errorf("expected API_KEY ref error, got %q", stderr)
+	}
+	if strings.Contains(stderr, "TOKEN") {
+		t.Errorf("ref value should satisfy the rule, got %q", stderr)
+	}
+	if !strings.Contains(stderr, "(from "+config.FullFileName+")") {
+		t.Errorf("expected config file as source, got %q", stderr)
+	}
+}
+
+func TestValidateCmd_SchemaTypeErrorHidesValue(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".env.example", "DB_PORT=5432\n")
+	envPath := writeTestFile(t, dir, ".env", "DB_PORT=not-a-port\n")
+	schemaPath := writeTestFile(t, dir, ".env.schema.json", `{"keys": {"DB_PORT": {"type": "port"}}}`)
+	args := []string{"validate",
+		"--file", envPath,
+		"--local-file", filepath.Join(dir, ".env.local"),
+		"--example", filepath.Join(dir, ".env.example"),
+		"--schema", schemaPath,
+	}
+
+	_, stderr, err := execCmd(t, args...)
+	if err == nil || !strings.Contains(stderr, "DB_PORT: expected a port number") {
+		t.Fatalf("expected type error, got %v: %q", err, stderr)
+	}
+	if strings.Contains(stderr, "not-a-port") {
+		t.Errorf("type error must not include the value: %q", stderr)
+	}
+
+	_, stderr, _ = execCmd(t, append(args, "--show-secrets")...)
+	if !strings.Contains(stderr, `DB_PORT: expected a port number (1-65535), got "not-a-port"`) {
+		t.Errorf("expected value with --show-secrets, got %q", stderr)
+	}
+}
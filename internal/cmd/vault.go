@@ -1,12 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/backend/factory"
 	"github.com/xcke/envref/internal/config"
 	"github.com/xcke/envref/internal/output"
 	"github.com/xcke/envref/internal/secret"
@@ -22,8 +24,13 @@ func newVaultCmd() *cobra.Command {
 		Short: "Manage the local encrypted vault",
 		Long: `Manage the local encrypted vault backend used to store secrets.
 
-The vault stores secrets in a SQLite database with per-value age encryption.
-Use 'vault init' to set up the vault with a master passphrase on first use.`,
+The vault stores secrets in a SQLite database with per-value encryption under
+a key derived from the master passphrase (Argon2id by default).
+Use 'vault init' to set up the vault with a master passphrase on first use.
+
+A team can share one committed vault file: 'vault grant' gives members access
+with their age public keys, after which each opens the vault with their own
+age identity instead of the passphrase. 'vault revoke' removes members.`,
 	}
 
 	cmd.AddCommand(newVaultInitCmd())
@@ -31,6 +38,10 @@ Use 'vault init' to set up the vault with a master passphrase on first use.`,
 	cmd.AddCommand(newVaultUnlockCmd())
 	cmd.AddCommand(newVaultExportCmd())
 	cmd.AddCommand(newVaultImportCmd())
+	cmd.AddCommand(newVaultRekeyCmd())
+	cmd.AddCommand(newVaultGrantCmd())
+	cmd.AddCommand(newVaultRevokeCmd())
+	cmd.AddCommand(newVaultMembersCmd())
 
 	return cmd
 }
@@ -51,6 +62,10 @@ initialize non-interactively (e.g., in CI or scripting).
 The vault database is created at ~/.config/envref/vault.db by default, or at
 the path configured in .envref.yaml.
 
+Keys are derived from the passphrase with Argon2id (64 MiB, 3 passes, 4
+threads) unless the vault backend config sets "kdf: scrypt" or other
+Argon2id costs (argon2_time, argon2_memory in KiB, argon2_threads).
+
 Examples:
   envref vault init                                 # interactive passphrase prompt
   ENVREF_VAULT_PASSPHRASE=secret envref vault init  # non-interactive`,
@@ -73,7 +88,10 @@ func runVaultInit(cmd *cobra.Command) error {
 	if err == nil {
 		cfg, _, loadErr := config.Load(cwd)
 		if loadErr == nil {
-			bc = findVaultBackendConfig(cfg)
+			factory.ApplyMemoryConfig(cfg)
+			if bc, err = findVaultBackendConfig(cfg); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -91,9 +109,9 @@ func runVaultInit(cmd *cobra.Command) error {
 	}
 
 	// Create the vault backend with the passphrase.
-	var opts []backend.VaultOption
-	if path := bc.Config["path"]; path != "" {
-		opts = append(opts, backend.WithVaultPath(path))
+	opts, err := factory.VaultOptions(bc)
+	if err != nil {
+		return err
 	}
 
 	v, err := backend.NewVaultBackend(passphrase, opts...)
@@ -133,7 +151,8 @@ When the vault is locked, all get, set, delete, and list operations will be
 refused until the vault is unlocked with 'envref vault unlock'.
 
 Your passphrase is verified before locking to ensure only authorized users
-can lock the vault.
+can lock the vault. If the vault backend caches the passphrase in a session
+("session: keychain"), locking also ends the session.
 
 Examples:
   envref vault lock                                 # interactive passphrase prompt
@@ -151,17 +170,24 @@ Examples:
 func runVaultLock(cmd *cobra.Command) error {
 	out := output.NewWriter(cmd)
 
-	v, err := createVaultForCommand(cmd)
+	v, sess, err := createVaultForCommand(cmd)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = v.Close() }()
 
+	ended, err := sess.end()
+	if err != nil {
+		return err
+	}
 	if err := v.Lock(); err != nil {
 		return fmt.Errorf("locking vault: %w", err)
 	}
 
 	out.Info("vault locked at %s\n", v.DBPath())
+	if ended {
+		out.Info("vault session ended\n")
+	}
 	return nil
 }
 
@@ -173,7 +199,9 @@ func newVaultUnlockCmd() *cobra.Command {
 		Long: `Unlock the local encrypted vault to allow secret access again.
 
 The vault must have been previously locked with 'envref vault lock'. Your
-passphrase is verified before unlocking.
+passphrase is verified before unlocking. With "session: keychain" in the
+vault backend config, a passphrase typed here starts a session: later
+commands reuse it from the OS keychain until session_ttl passes.
 
 Examples:
   envref vault unlock                                 # interactive passphrase prompt
@@ -191,7 +219,7 @@ Examples:
 func runVaultUnlock(cmd *cobra.Command) error {
 	out := output.NewWriter(cmd)
 
-	v, err := createVaultForCommand(cmd)
+	v, sess, err := createVaultForCommand(cmd)
 	if err != nil {
 		return err
 	}
@@ -200,6 +228,7 @@ func runVaultUnlock(cmd *cobra.Command) error {
 	if err := v.Unlock(); err != nil {
 		return fmt.Errorf("unlocking vault: %w", err)
 	}
+	sess.start()
 
 	out.Info("vault unlocked at %s\n", v.DBPath())
 	return nil
@@ -239,7 +268,7 @@ Examples:
 func runVaultExport(cmd *cobra.Command) error {
 	out := output.NewWriter(cmd)
 
-	v, err := createVaultForCommand(cmd)
+	v, _, err := createVaultForCommand(cmd)
 	if err != nil {
 		return err
 	}
@@ -329,7 +358,7 @@ func runVaultImport(cmd *cobra.Command) error {
 		source = filePath
 	}
 
-	v, err := createVaultForCommand(cmd)
+	v, _, err := createVaultForCommand(cmd)
 	if err != nil {
 		return err
 	}
@@ -344,6 +373,95 @@ func runVaultImport(cmd *cobra.Command) error {
 	return nil
 }
 
+// newVaultRekeyCmd creates the vault rekey subcommand.
+func newVaultRekeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rekey",
+		Short: "Change the vault passphrase or key derivation",
+		Long: `Re-encrypt every secret in the vault under a new passphrase, and optionally
+a different key derivation function.
+
+The current passphrase is read from ENVREF_VAULT_PASSPHRASE, the config, or
+a prompt, and the new one from ENVREF_VAULT_NEW_PASSPHRASE or a prompt
+(entered twice). All values are rewritten in a single transaction, so an
+interrupted rekey leaves the vault unchanged.
+
+The new key derivation uses the vault backend config (kdf, argon2_time,
+argon2_memory, argon2_threads, scrypt_work_factor); --kdf overrides the
+algorithm. Use it to move a vault created with scrypt to Argon2id.
+
+Examples:
+  envref vault rekey                 # new passphrase, configured KDF
+  envref vault rekey --kdf argon2id  # also switch the KDF
+  ENVREF_VAULT_PASSPHRASE=old ENVREF_VAULT_NEW_PASSPHRASE=new envref vault rekey`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kdf, _ := cmd.Flags().GetString("kdf")
+			return runVaultRekey(cmd, kdf)
+		},
+	}
+
+	cmd.Flags().String("kdf", "", "key derivation function: argon2id or scrypt (default: from config, else argon2id)")
+
+	return cmd
+}
+
+// runVaultRekey re-encrypts the vault under a new passphrase and KDF.
+func runVaultRekey(cmd *cobra.Command, algorithm string) error {
+	out := output.NewWriter(cmd)
+
+	var bc config.BackendConfig
+	if cwd, err := os.Getwd(); err == nil {
+		if cfg, _, loadErr := config.Load(cwd); loadErr == nil {
+			if bc, err = findVaultBackendConfig(cfg); err != nil {
+				return err
+			}
+		}
+	}
+	kdf, err := factory.VaultKDF(bc, algorithm)
+	if err != nil {
+		return err
+	}
+
+	v, sess, err := createVaultForCommand(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = v.Close() }()
+
+	if shared, err := v.Shared(); err != nil {
+		return err
+	} else if shared {
+		return fmt.Errorf("the vault is shared with age recipients and has no passphrase; use 'envref vault grant' and 'envref vault revoke' to change its members")
+	}
+
+	newPassphrase := os.Getenv("ENVREF_VAULT_NEW_PASSPHRASE")
+	if newPassphrase == "" {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Choose the new vault passphrase.")
+		if newPassphrase, err = promptVaultPassphrase(cmd, true); err != nil {
+			return err
+		}
+	}
+
+	if err := v.Rekey(newPassphrase, kdf); err != nil {
+		return fmt.Errorf("rekeying vault: %w", err)
+	}
+	// The session holds the old passphrase.
+	if _, err := sess.end(); err != nil {
+		out.Warn("%v\n", err)
+	}
+
+	current, err := v.KDF()
+	if err != nil {
+		return err
+	}
+	out.Info("vault rekeyed at %s using %s\n", v.DBPath(), current)
+	if bc.Config["passphrase"] != "" {
+		out.Warn("update the vault passphrase in %s; it still holds the old one\n", config.FullFileName)
+	}
+	return nil
+}
+
 // readAll reads all data from the command's stdin.
 func readAll(cmd *cobra.Command) ([]byte, error) {
 	return io.ReadAll(cmd.InOrStdin())
@@ -352,39 +470,59 @@ func readAll(cmd *cobra.Command) ([]byte, error) {
 // createVaultForCommand creates a VaultBackend for vault management commands
 // (lock, unlock). It loads config, resolves the passphrase, and creates the
 // backend without verifying the passphrase (lock/unlock verify internally).
-func createVaultForCommand(cmd *cobra.Command) (*backend.VaultBackend, error) {
+// It also returns the vault's session, which is nil if sessions are not
+// enabled.
+func createVaultForCommand(cmd *cobra.Command) (*backend.VaultBackend, *vaultSession, error) {
 	var bc config.BackendConfig
 	cwd, err := os.Getwd()
 	if err == nil {
 		cfg, _, loadErr := config.Load(cwd)
 		if loadErr == nil {
-			bc = findVaultBackendConfig(cfg)
+			factory.ApplyMemoryConfig(cfg)
+			if bc, err = findVaultBackendConfig(cfg); err != nil {
+				return nil, nil, err
+			}
 		}
 	}
 
-	passphrase := os.Getenv("ENVREF_VAULT_PASSPHRASE")
-	if passphrase == "" {
-		passphrase = bc.Config["passphrase"]
+	opts, err := factory.VaultOptions(bc)
+	if err != nil {
+		return nil, nil, err
 	}
-	if passphrase == "" {
-		prompted, promptErr := promptVaultPassphraseForAccess(cmd)
-		if promptErr != nil {
-			return nil, promptErr
+
+	// A shared vault is opened with an age identity, never a passphrase.
+	shared, err := factory.VaultShared(bc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if shared {
+		if ids, err := factory.VaultIdentities(bc); err != nil {
+			return nil, nil, err
+		} else if len(ids) == 0 {
+			return nil, nil, backend.ErrVaultIdentityRequired
 		}
-		passphrase = prompted
+		v, err := backend.NewVaultBackend("", opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating vault: %w", err)
+		}
+		return v, nil, nil
 	}
 
-	var opts []backend.VaultOption
-	if path := bc.Config["path"]; path != "" {
-		opts = append(opts, backend.WithVaultPath(path))
+	sess, err := newVaultSession(bc)
+	if err != nil {
+		return nil, nil, err
+	}
+	passphrase, _, err := resolveVaultPassphrase(cmd, bc, sess)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	v, err := backend.NewVaultBackend(passphrase, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("creating vault: %w", err)
+		return nil, nil, fmt.Errorf("creating vault: %w", err)
 	}
 
-	return v, nil
+	return v, sess, nil
 }
 
 // promptVaultPassphrase prompts the user to enter a vault passphrase from
@@ -428,13 +566,9 @@ func promptVaultPassphrase(cmd *cobra.Command, confirm bool) (string, error) {
 		secret.ClearBytes(confirmBytes)
 	}
 
-	// Convert to string for the return value, then clear the byte slice.
 	// The string copy is necessary because NewVaultBackend accepts a string,
 	// but clearing the original bytes reduces duplicate copies in memory.
-	passphrase := string(passBytes)
-	secret.ClearBytes(passBytes)
-
-	return passphrase, nil
+	return secret.TakeString(passBytes), nil
 }
 
 // getTerminalFd returns the file descriptor for stdin and whether it is a
@@ -450,17 +584,23 @@ func getTerminalFd(cmd *cobra.Command) (int, bool) {
 }
 
 // findVaultBackendConfig returns the BackendConfig for the vault backend
-// from the config, or a zero-value BackendConfig if none is found.
-func findVaultBackendConfig(cfg *config.Config) config.BackendConfig {
+// from the config, with encrypted values decrypted, or a zero-value
+// BackendConfig if none is found.
+func findVaultBackendConfig(cfg *config.Config) (config.BackendConfig, error) {
 	if cfg == nil {
-		return config.BackendConfig{}
+		return config.BackendConfig{}, nil
 	}
 	for _, bc := range cfg.Backends {
-		if bc.EffectiveType() == "vault" {
-			return bc
+		if bc.EffectiveType() != "vault" {
+			continue
 		}
+		decrypted, err := factory.DecryptConfig(bc)
+		if err != nil {
+			return config.BackendConfig{}, fmt.Errorf("backend %q: %w", bc.Name, err)
+		}
+		return decrypted, nil
 	}
-	return config.BackendConfig{}
+	return config.BackendConfig{}, nil
 }
 
 // promptVaultPassphraseForAccess prompts for the vault passphrase (without
@@ -471,29 +611,40 @@ func promptVaultPassphraseForAccess(cmd *cobra.Command) (string, error) {
 }
 
 // createVaultBackendInteractive creates a VaultBackend, prompting for the
-// passphrase interactively if not provided via env var or config.
+// passphrase interactively if not provided via env var, config, or an
+// unexpired vault session. A passphrase typed at the prompt starts a session
+// if the backend has "session: keychain" configured.
 // The cmd parameter is used for terminal I/O; pass nil to disable interactive
 // prompting.
 func createVaultBackendInteractive(bc config.BackendConfig, cmd *cobra.Command) (*backend.VaultBackend, error) {
-	// Resolve passphrase: env var > config > interactive prompt.
-	passphrase := os.Getenv("ENVREF_VAULT_PASSPHRASE")
-	if passphrase == "" {
-		passphrase = bc.Config["passphrase"]
-	}
-	if passphrase == "" && cmd != nil {
-		prompted, err := promptVaultPassphraseForAccess(cmd)
+	// A shared vault is opened with an age identity: there is nothing to
+	// prompt for.
+	if shared, err := factory.VaultShared(bc); err != nil {
+		return nil, err
+	} else if shared {
+		b, err := factory.Vault(bc)
 		if err != nil {
-			return nil, fmt.Errorf("vault passphrase: %w", err)
+			return nil, err
 		}
-		passphrase = prompted
+		return b.(*backend.VaultBackend), nil
+	}
+
+	sess, err := newVaultSession(bc)
+	if err != nil {
+		return nil, err
+	}
+	// Resolve passphrase: env var > config > session > interactive prompt.
+	passphrase, fromSession, err := resolveVaultPassphrase(cmd, bc, sess)
+	if err != nil {
+		return nil, fmt.Errorf("vault passphrase: %w", err)
 	}
 	if passphrase == "" {
 		return nil, fmt.Errorf("vault passphrase required: set ENVREF_VAULT_PASSPHRASE or config.passphrase in %s", config.FullFileName)
 	}
 
-	var opts []backend.VaultOption
-	if path := bc.Config["path"]; path != "" {
-		opts = append(opts, backend.WithVaultPath(path))
+	opts, err := factory.VaultOptions(bc)
+	if err != nil {
+		return nil, err
 	}
 
 	v, err := backend.NewVaultBackend(passphrase, opts...)
@@ -510,8 +661,17 @@ func createVaultBackendInteractive(bc config.BackendConfig, cmd *cobra.Command)
 	if initialized {
 		if verifyErr := v.VerifyPassphrase(); verifyErr != nil {
 			_ = v.Close()
+			if fromSession && errors.Is(verifyErr, backend.ErrWrongPassphrase) {
+				// The vault was rekeyed since the session started: end
+				// it and ask for the passphrase again.
+				if _, err := sess.end(); err != nil {
+					return nil, err
+				}
+				return createVaultBackendInteractive(bc, cmd)
+			}
 			return nil, verifyErr
 		}
+		sess.start()
 	}
 
 	return v, nil
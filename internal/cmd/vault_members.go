@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/backend/factory"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/suggest"
+)
+
+// newVaultGrantCmd creates the vault grant subcommand.
+func newVaultGrantCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grant <member>...",
+		Short: "Share the vault with team members",
+		Long: `Give team members access to the vault, so a team can commit one vault file
+and each member opens it with their own age identity.
+
+Each member's age public key comes from the team roster in .envref.yaml, or
+from --key when granting a single member. Granting a member again replaces
+their key.
+
+The first grant turns a passphrase vault into a shared vault: every value is
+re-encrypted with a new data key, wrapped for each member's public key, and
+the passphrase no longer opens it. Your own key must be among the members
+granted, and your age identity file must be set in ENVREF_VAULT_IDENTITY or
+the vault backend's config.identity, so that you keep access.
+
+Examples:
+  envref vault grant alice bob                  # keys from the team roster
+  envref vault grant carol --key age1...        # a key not in the roster`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, _ := cmd.Flags().GetString("key")
+			return runVaultGrant(cmd, args, key)
+		},
+	}
+
+	cmd.Flags().String("key", "", "age public key of the member (only with a single member)")
+
+	return cmd
+}
+
+// runVaultGrant grants the named members access to the vault.
+func runVaultGrant(cmd *cobra.Command, names []string, key string) error {
+	out := output.NewWriter(cmd)
+
+	if key != "" && len(names) != 1 {
+		return fmt.Errorf("--key can only be used when granting a single member")
+	}
+	cfg := loadVaultTeamConfig()
+
+	members := make([]backend.VaultMember, 0, len(names))
+	for _, name := range names {
+		recipient := key
+		if recipient == "" {
+			m := cfg.TeamMemberByName(name)
+			if m == nil {
+				all := make([]string, 0, len(cfg.Team))
+				for _, t := range cfg.Team {
+					all = append(all, t.Name)
+				}
+				return fmt.Errorf("%q is not in the team roster of %s; add it or pass --key%s",
+					name, config.FullFileName, suggest.FormatSuggestion(suggest.Keys(name, all)))
+			}
+			if m.PublicKey == "" {
+				return fmt.Errorf("team member %q has no public_key in %s", name, config.FullFileName)
+			}
+			recipient = m.PublicKey
+		}
+		members = append(members, backend.VaultMember{Name: name, Recipient: recipient})
+	}
+
+	v, sess, err := createVaultForCommand(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = v.Close() }()
+
+	shared, err := v.Shared()
+	if err != nil {
+		return err
+	}
+	if err := v.Grant(members...); err != nil {
+		return err
+	}
+
+	out.Info("granted %s access to the vault at %s\n", strings.Join(names, ", "), v.DBPath())
+	if !shared {
+		out.Info("the vault is now shared: open it with your age identity (ENVREF_VAULT_IDENTITY or config.identity); the passphrase no longer opens it\n")
+		// The session holds the passphrase, which no longer opens the vault.
+		if _, err := sess.end(); err != nil {
+			out.Warn("%v\n", err)
+		}
+	}
+	return nil
+}
+
+// newVaultRevokeCmd creates the vault revoke subcommand.
+func newVaultRevokeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "revoke <member>...",
+		Short: "Remove team members from a shared vault",
+		Long: `Remove members from a shared vault.
+
+The vault's data key is replaced and every value re-encrypted, so a revoked
+member cannot open values written after the revocation even with a copy of
+the old key. Values they could read before should be rotated in any case.
+At least one member must keep access.
+
+Examples:
+  envref vault revoke bob`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVaultRevoke(cmd, args)
+		},
+	}
+
+	return cmd
+}
+
+// runVaultRevoke removes the named members from the vault.
+func runVaultRevoke(cmd *cobra.Command, names []string) error {
+	out := output.NewWriter(cmd)
+
+	v, _, err := createVaultForCommand(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = v.Close() }()
+
+	members, err := v.Members()
+	if err != nil {
+		return err
+	}
+	own := ownVaultRecipients()
+	if err := v.Revoke(names...); err != nil {
+		return err
+	}
+
+	out.Info("revoked %s from the vault at %s\n", strings.Join(names, ", "), v.DBPath())
+	for _, m := range members {
+		if slices.Contains(names, m.Name) && slices.Contains(own, m.Recipient) {
+			out.Warn("you revoked your own access (%s); ask a remaining member to grant it again\n", m.Name)
+		}
+	}
+	return nil
+}
+
+// newVaultMembersCmd creates the vault members subcommand.
+func newVaultMembersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "members",
+		Short: "List the members of a shared vault",
+		Long: `List the members of a shared vault and their age public keys. Members
+whose key belongs to your age identity are marked.
+
+Examples:
+  envref vault members
+  envref vault members --format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			return runVaultMembers(cmd, format)
+		},
+	}
+
+	cmd.Flags().String("format", "plain", "output format: plain, json")
+
+	return cmd
+}
+
+// vaultMemberEntry is a vault member as printed by vault members.
+type vaultMemberEntry struct {
+	Name      string `json:"name"`
+	Recipient string `json:"recipient"`
+	You       bool   `json:"you,omitempty"`
+}
+
+// runVaultMembers prints the members of the vault.
+func runVaultMembers(cmd *cobra.Command, formatStr string) error {
+	format, err := parseFormat(formatStr)
+	if err != nil {
+		return err
+	}
+	if format != FormatPlain && format != FormatJSON {
+		return fmt.Errorf("unsupported format %q for vault members (use plain or json)", formatStr)
+	}
+	out := output.NewWriter(cmd)
+
+	v, _, err := createVaultForCommand(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = v.Close() }()
+
+	members, err := v.Members()
+	if err != nil {
+		return err
+	}
+	own := ownVaultRecipients()
+	entries := make([]vaultMemberEntry, 0, len(members))
+	for _, m := range members {
+		entries = append(entries, vaultMemberEntry{Name: m.Name, Recipient: m.Recipient, You: slices.Contains(own, m.Recipient)})
+	}
+
+	if format == FormatJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	if len(entries) == 0 {
+		out.Info("the vault at %s is not shared; add members with 'envref vault grant'\n", v.DBPath())
+		return nil
+	}
+	width := 0
+	for _, e := range entries {
+		width = max(width, len(e.Name))
+	}
+	for _, e := range entries {
+		line := fmt.Sprintf("%-*s  %s", width, e.Name, e.Recipient)
+		if e.You {
+			line += " " + out.Green("(you)")
+		}
+		_, _ = fmt.Fprintln(out.Stdout(), line)
+	}
+	return nil
+}
+
+// loadVaultTeamConfig loads the project config for its team roster. A
+// missing or invalid config yields an empty one.
+func loadVaultTeamConfig() *config.Config {
+	if cwd, err := os.Getwd(); err == nil {
+		if cfg, _, err := config.Load(cwd); err == nil {
+			return cfg
+		}
+	}
+	return &config.Config{}
+}
+
+// ownVaultRecipients returns the age public keys of the vault identity
+// configured for the current project, if any.
+func ownVaultRecipients() []string {
+	cfg := loadVaultTeamConfig()
+	bc, err := findVaultBackendConfig(cfg)
+	if err != nil {
+		return nil
+	}
+	ids, err := factory.VaultIdentities(bc)
+	if err != nil {
+		return nil
+	}
+	var recipients []string
+	for _, id := range ids {
+		if x, ok := id.(*age.X25519Identity); ok {
+			recipients = append(recipients, x.Recipient().String())
+		}
+	}
+	return recipients
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// writeAgeIdentity generates an age identity, writes it to dir/name.key,
+// and returns the file path and public key.
+func writeAgeIdentity(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity: %v", err)
+	}
+	path := writeTestFile(t, dir, name+".key", id.String()+"\n")
+	return path, id.Recipient().String()
+}
+
+func TestVaultGrantRevokeCmd(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	aliceKey, alicePub := writeAgeIdentity(t, t.TempDir(), "alice")
+	bobKey, bobPub := writeAgeIdentity(t, t.TempDir(), "bob")
+	vaultPath := filepath.Join(dir, "vault.db")
+	writeTestFile(t, dir, ".envref.yaml", "project: testproject\nteam:\n  - name: alice\n    public_key: "+alicePub+
+		"\n  - name: bob\n    public_key: "+bobPub+
+		"\nbackends:\n  - name: vault\n    type: vault\n    config:\n      path: "+vaultPath+
+		"\n      argon2_time: \"1\"\n      argon2_memory: \"1024\"\n      argon2_threads: \"1\"\n")
+	chdir(t, dir)
+
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "pass")
+	if _, _, err := execCmd(t, "vault", "init"); err != nil {
+		t.Fatalf("vault init: %v", err)
+	}
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "s3cret", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	// Without an identity, the caller would lose access.
+	if _, _, err := execCmd(t, "vault", "grant", "bob"); err == nil || !strings.Contains(err.Error(), "grant your own public key") {
+		t.Fatalf("vault grant without identity: got %v", err)
+	}
+	if _, _, err := execCmd(t, "vault", "grant", "carol"); err == nil || !strings.Contains(err.Error(), "not in the team roster") {
+		t.Errorf("vault grant unknown member: got %v", err)
+	}
+
+	t.Setenv("ENVREF_VAULT_IDENTITY", aliceKey)
+	stdout, _, err := execCmd(t, "vault", "grant", "alice", "bob")
+	if err != nil {
+		t.Fatalf("vault grant: %v", err)
+	}
+	if !strings.Contains(stdout, "granted alice, bob") || !strings.Contains(stdout, "the vault is now shared") {
+		t.Errorf("unexpected grant output: %q", stdout)
+	}
+
+	// Bob opens the vault with his identity; the passphrase is not needed.
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "")
+	t.Setenv("ENVREF_VAULT_IDENTITY", bobKey)
+	stdout, _, err = execCmd(t, "secret", "get", "api_key")
+	if err != nil || stdout != "s3cret\n" {
+		t.Fatalf("secret get as bob: got %q, %v", stdout, err)
+	}
+
+	stdout, _, err = execCmd(t, "vault", "members", "--format", "json")
+	if err != nil {
+		t.Fatalf("vault members: %v", err)
+	}
+	var members []vaultMemberEntry
+	if err := json.Unmarshal([]byte(stdout), &members); err != nil {
+		t.Fatalf("parsing JSON: %v\n%s", err, stdout)
+	}
+	if len(members) != 2 || members[0].You || !members[1].You || members[1].Recipient != bobPub {
+		t.Errorf("unexpected members: %+v", members)
+	}
+
+	if _, _, err := execCmd(t, "vault", "rekey"); err == nil || !strings.Contains(err.Error(), "shared with age recipients") {
+		t.Errorf("vault rekey on shared vault: got %v", err)
+	}
+
+	t.Setenv("ENVREF_VAULT_IDENTITY", aliceKey)
+	stdout, _, err = execCmd(t, "vault", "revoke", "bob")
+	if err != nil || !strings.Contains(stdout, "revoked bob") {
+		t.Fatalf("vault revoke: got %q, %v", stdout, err)
+	}
+
+	t.Setenv("ENVREF_VAULT_IDENTITY", bobKey)
+	if _, _, err := execCmd(t, "secret", "get", "api_key"); err == nil || !strings.Contains(err.Error(), "none of your age identities is a member") {
+		t.Errorf("secret get as revoked member: got %v", err)
+	}
+	t.Setenv("ENVREF_VAULT_IDENTITY", "")
+	if _, _, err := execCmd(t, "secret", "get", "api_key"); err == nil || !strings.Contains(err.Error(), "ENVREF_VAULT_IDENTITY") {
+		t.Errorf("secret get without identity: got %v", err)
+	}
+}
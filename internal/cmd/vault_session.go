@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/backend/factory"
+	"github.com/xcke/envref/internal/config"
+)
+
+// vaultSessionItemPrefix prefixes the keychain item holding a vault
+// session; the rest of the name is the absolute path of the vault database.
+const vaultSessionItemPrefix = "session:vault:"
+
+// vaultSession caches the vault passphrase in the OS keychain for
+// session_ttl after it is typed at a prompt, so that later commands do not
+// prompt again. It is enabled with "session: keychain" in the vault
+// backend config and ended by 'envref vault lock'.
+type vaultSession struct {
+	item string
+	ttl  time.Duration
+
+	// entered is the passphrase typed at the prompt, which start caches
+	// once it has been verified.
+	entered string
+}
+
+// newVaultSession returns the session of the vault backend config, or nil
+// if sessions are not enabled. The methods of a nil session do nothing.
+func newVaultSession(bc config.BackendConfig) (*vaultSession, error) {
+	mode, ttl, err := factory.SessionConfig(bc)
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case "":
+		return nil, nil
+	case backend.SessionCacheKeychain:
+	default:
+		return nil, fmt.Errorf("%s: vault sessions are cached in the keychain; set session to %q", bc.Name, backend.SessionCacheKeychain)
+	}
+	if ttl == 0 {
+		ttl = backend.DefaultSessionTTL
+	}
+
+	path, err := factory.VaultPath(bc)
+	if err != nil {
+		return nil, err
+	}
+	return &vaultSession{item: vaultSessionItemPrefix + path, ttl: ttl}, nil
+}
+
+// passphrase returns the passphrase cached by an unexpired session, or "".
+func (s *vaultSession) passphrase() string {
+	if s == nil {
+		return ""
+	}
+	return backend.CachedSession(s.item)
+}
+
+// start caches the passphrase typed at the prompt, if any. Keychain errors
+// are not fatal: the user is then prompted again next time.
+func (s *vaultSession) start() {
+	if s == nil || s.entered == "" {
+		return
+	}
+	_ = backend.CacheSession(s.item, s.entered, s.ttl)
+}
+
+// end removes the cached passphrase. It reports whether a session existed.
+func (s *vaultSession) end() (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	if err := backend.DeleteKeychainItem(s.item); err != nil {
+		if errors.Is(err, backend.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("ending vault session: %w", err)
+	}
+	return true, nil
+}
+
+// resolveVaultPassphrase returns the vault passphrase from, in order,
+// ENVREF_VAULT_PASSPHRASE, config.passphrase, the session, and an
+// interactive prompt (skipped if cmd is nil). fromSession reports whether
+// the session supplied it. An empty passphrase is not an error.
+func resolveVaultPassphrase(cmd *cobra.Command, bc config.BackendConfig, sess *vaultSession) (passphrase string, fromSession bool, err error) {
+	if passphrase = os.Getenv("ENVREF_VAULT_PASSPHRASE"); passphrase != "" {
+		return passphrase, false, nil
+	}
+	if passphrase = bc.Config["passphrase"]; passphrase != "" {
+		return passphrase, false, nil
+	}
+	if passphrase = sess.passphrase(); passphrase != "" {
+		return passphrase, true, nil
+	}
+	if cmd == nil {
+		return "", false, nil
+	}
+	if passphrase, err = promptVaultPassphraseForAccess(cmd); err != nil {
+		return "", false, err
+	}
+	if sess != nil {
+		sess.entered = passphrase
+	}
+	return passphrase, false, nil
+}
@@ -6,9 +6,13 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/zalando/go-keyring"
 )
 
 // writeVaultTestConfig writes a .envref.yaml with a vault backend to the
@@ -804,3 +808,150 @@ func TestVaultCmd_HelpShowsExportImport(t *testing.T) {
 		t.Errorf("expected 'Import secrets' in help output, got: %q", got)
 	}
 }
+
+func TestVaultRekeyCmd(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	vaultPath := filepath.Join(dir, "vault.db")
+	writeTestFile(t, dir, ".envref.yaml", "project: testproject\nbackends:\n  - name: vault\n    type: vault\n    config:\n      path: "+vaultPath+
+		"\n      kdf: scrypt\n      argon2_time: \"1\"\n      argon2_memory: \"1024\"\n      argon2_threads: \"1\"\n")
+	chdir(t, dir)
+
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "old-pass")
+	if _, _, err := execCmd(t, "vault", "init"); err != nil {
+		t.Fatalf("vault init: %v", err)
+	}
+	if _, _, err := execCmd(t, "secret", "set", "api_key", "--value", "s3cret", "--no-env"); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+
+	t.Setenv("ENVREF_VAULT_NEW_PASSPHRASE", "new-pass")
+	stdout, _, err := execCmd(t, "vault", "rekey", "--kdf", "argon2id")
+	if err != nil {
+		t.Fatalf("vault rekey: %v", err)
+	}
+	if !contains(stdout, "vault rekeyed") || !contains(stdout, "argon2id (time 1, memory 1024 KiB, threads 1)") {
+		t.Errorf("unexpected rekey output: %q", stdout)
+	}
+
+	if _, _, err := execCmd(t, "secret", "get", "api_key"); err == nil {
+		t.Error("secret get with old passphrase: expected error, got nil")
+	}
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "new-pass")
+	stdout, _, err = execCmd(t, "secret", "get", "api_key")
+	if err != nil {
+		t.Fatalf("secret get with new passphrase: %v", err)
+	}
+	if stdout != "s3cret\n" {
+		t.Errorf("secret get: got %q, want %q", stdout, "s3cret\n")
+	}
+}
+
+func TestVaultInitCmd_InvalidKDFConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".envref.yaml", "project: testproject\nbackends:\n  - name: vault\n    type: vault\n    config:\n      path: "+
+		filepath.Join(dir, "vault.db")+"\n      kdf: pbkdf2\n")
+	chdir(t, dir)
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "pass")
+
+	_, _, err := execCmd(t, "vault", "init")
+	if err == nil || !contains(err.Error(), `unknown KDF "pbkdf2"`) {
+		t.Errorf("expected unknown KDF error, got: %v", err)
+	}
+}
+
+func TestVaultSession_ReusedAndEndedByLock(t *testing.T) {
+	keyring.MockInit()
+
+	dir := t.TempDir()
+	vaultPath := filepath.Join(dir, "test-vault.db")
+	path := writeVaultTestConfig(t, dir, "testproject", vaultPath)
+	cfg, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, ".envref.yaml", string(cfg)+"      session: keychain\n      session_ttl: 5m\n")
+	chdir(t, dir)
+
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "test-passphrase")
+	if _, _, err := execCmd(t, "vault", "init"); err != nil {
+		t.Fatalf("vault init: %v", err)
+	}
+
+	// Without a session or passphrase, a non-interactive command fails.
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "")
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "sk-123", "--backend", "vault", "--no-env"); err == nil {
+		t.Fatal("expected an error without a passphrase")
+	}
+
+	// A session supplies the passphrase.
+	item := vaultSessionItemPrefix + vaultPath
+	if err := backend.CacheSession(item, "test-passphrase", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "sk-123", "--backend", "vault", "--no-env"); err != nil {
+		t.Fatalf("secret set with session: %v", err)
+	}
+
+	stdout, _, err := execCmd(t, "vault", "lock")
+	if err != nil {
+		t.Fatalf("vault lock: %v", err)
+	}
+	if !strings.Contains(stdout, "vault session ended") {
+		t.Errorf("expected the session to end, got %q", stdout)
+	}
+	if got := backend.CachedSession(item); got != "" {
+		t.Errorf("session still cached: %q", got)
+	}
+}
+
+func TestVaultSession_StalePassphraseDropped(t *testing.T) {
+	keyring.MockInit()
+
+	dir := t.TempDir()
+	vaultPath := filepath.Join(dir, "test-vault.db")
+	path := writeVaultTestConfig(t, dir, "testproject", vaultPath)
+	cfg, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, ".envref.yaml", string(cfg)+"      session: keychain\n")
+	chdir(t, dir)
+
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "test-passphrase")
+	if _, _, err := execCmd(t, "vault", "init"); err != nil {
+		t.Fatalf("vault init: %v", err)
+	}
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "")
+
+	item := vaultSessionItemPrefix + vaultPath
+	if err := backend.CacheSession(item, "old-passphrase", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := execCmd(t, "secret", "set", "API_KEY", "--value", "sk-123", "--backend", "vault", "--no-env"); err == nil {
+		t.Fatal("expected an error with a stale session and no terminal")
+	}
+	if got := backend.CachedSession(item); got != "" {
+		t.Errorf("stale session still cached: %q", got)
+	}
+}
+
+func TestNewVaultSession(t *testing.T) {
+	sess, err := newVaultSession(config.BackendConfig{Name: "vault"})
+	if err != nil || sess != nil {
+		t.Fatalf("expected no session, got %v, %v", sess, err)
+	}
+
+	_, err = newVaultSession(config.BackendConfig{Name: "vault", Config: map[string]string{"session": "process"}})
+	if err == nil || !strings.Contains(err.Error(), "keychain") {
+		t.Errorf("expected process mode to be rejected, got %v", err)
+	}
+
+	sess, err = newVaultSession(config.BackendConfig{Name: "vault", Config: map[string]string{"session": "keychain"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.ttl != backend.DefaultSessionTTL {
+		t.Errorf("ttl = %v, want %v", sess.ttl, backend.DefaultSessionTTL)
+	}
+}
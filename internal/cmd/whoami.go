@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/backend/factory"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/suggest"
+)
+
+// newWhoamiCmd creates the whoami subcommand.
+func newWhoamiCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "whoami [backend...]",
+		Short: "Show the identity each backend acts as",
+		Long: `Show the identity each configured backend acts as, which is the first
+thing to check when a backend denies access:
+
+  aws-ssm          the IAM caller (aws sts get-caller-identity)
+  hashicorp-vault  the token's display name and policies (vault token lookup)
+  1password        the signed-in account (op whoami)
+
+Other backends, such as the OS keychain and the local vault, act as the OS
+user, shown as user@host. With a team roster in .envref.yaml, the team
+member and roles each identity maps to are shown too, as used by the acl
+block.
+
+With names, only those backends are shown. Exits with an error if any
+backend cannot tell its identity, e.g. because its CLI is not signed in.
+
+Examples:
+  envref whoami                 # every configured backend
+  envref whoami vault           # one backend
+  envref whoami --format json   # machine-readable output`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			return runWhoami(cmd, args, format)
+		},
+	}
+
+	cmd.Flags().String("format", "plain", "output format: plain, json")
+
+	return cmd
+}
+
+// backendIdentity is the identity of one configured backend.
+type backendIdentity struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Identity string            `json:"identity,omitempty"`
+	OSUser   bool              `json:"os_user,omitempty"`
+	Details  map[string]string `json:"details,omitempty"`
+	Member   string            `json:"team_member,omitempty"`
+	Roles    []string          `json:"roles,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// runWhoami looks up and prints the identities of the selected backends.
+func runWhoami(cmd *cobra.Command, names []string, formatStr string) error {
+	format, err := parseFormat(formatStr)
+	if err != nil {
+		return err
+	}
+	if format != FormatPlain && format != FormatJSON {
+		return fmt.Errorf("unsupported format %q for whoami (use plain or json)", formatStr)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	cfg, _, err := config.Load(cwd)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	selected := cfg.Backends
+	if len(names) > 0 {
+		byName := make(map[string]config.BackendConfig, len(cfg.Backends))
+		all := make([]string, 0, len(cfg.Backends))
+		for _, bc := range cfg.Backends {
+			byName[bc.Name] = bc
+			all = append(all, bc.Name)
+		}
+		selected = nil
+		for _, name := range names {
+			bc, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("unknown backend %q%s", name, suggest.FormatSuggestion(suggest.Keys(name, all)))
+			}
+			selected = append(selected, bc)
+		}
+	}
+	if len(selected) == 0 {
+		return withExitCode(exitConfig, fmt.Errorf("no backends configured in %s", config.FullFileName))
+	}
+
+	results := lookupIdentities(cfg, selected)
+
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+
+	if format == FormatJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		printIdentities(output.NewWriter(cmd), results, len(cfg.Team) > 0)
+	}
+
+	if failed > 0 {
+		return withExitCode(exitBackend, fmt.Errorf("%d of %d backend(s) could not tell their identity", failed, len(results)))
+	}
+	return nil
+}
+
+// lookupIdentities creates each backend and asks it for its identity. The
+// local vault is never opened, since it acts as the OS user anyway and
+// would prompt for its passphrase.
+func lookupIdentities(cfg *config.Config, backends []config.BackendConfig) []backendIdentity {
+	noVault := func(config.BackendConfig) (backend.Backend, error) {
+		return nil, backend.ErrIdentityUnsupported
+	}
+	results := make([]backendIdentity, 0, len(backends))
+	for _, bc := range backends {
+		r := backendIdentity{Name: bc.Name, Type: bc.EffectiveType()}
+		var ids []string
+		b, err := factory.New(bc, noVault)
+		var id backend.Identity
+		if err == nil {
+			id, err = backend.GetIdentity(b)
+			if c, ok := b.(io.Closer); ok {
+				_ = c.Close()
+			}
+		}
+		switch {
+		case err == nil && id.Name != "":
+			r.Identity, r.Details = id.Name, id.Details
+			ids = []string{id.Name}
+		case err == nil || errors.Is(err, backend.ErrIdentityUnsupported):
+			ids, err = factory.OSIdentities()
+			if err == nil {
+				r.Identity, r.OSUser = ids[0], true
+			}
+		}
+		if err != nil {
+			r.Error = err.Error()
+		} else if m := cfg.TeamMemberByIdentity(ids...); m != nil {
+			r.Member, r.Roles = m.Name, m.Roles
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// printIdentities prints one line per backend with its details indented
+// below. With a team, the team member of each identity is shown as well.
+func printIdentities(w *output.Writer, results []backendIdentity, team bool) {
+	nameWidth, typeWidth := 0, 0
+	for _, r := range results {
+		nameWidth = max(nameWidth, len(r.Name))
+		typeWidth = max(typeWidth, len(r.Type))
+	}
+	indent := strings.Repeat(" ", nameWidth+typeWidth+4)
+	for _, r := range results {
+		if r.Error != "" {
+			_, _ = fmt.Fprintf(w.Stdout(), "%-*s  %-*s  %s %s\n", nameWidth, r.Name, typeWidth, r.Type, w.Red("[!!]"), r.Error)
+			continue
+		}
+		identity := r.Identity
+		if r.OSUser {
+			identity += " (OS user)"
+		}
+		_, _ = fmt.Fprintf(w.Stdout(), "%-*s  %-*s  %s\n", nameWidth, r.Name, typeWidth, r.Type, identity)
+
+		keys := make([]string, 0, len(r.Details))
+		for k, v := range r.Details {
+			if v != "" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			_, _ = fmt.Fprintf(w.Stdout(), "%s%s: %s\n", indent, k, r.Details[k])
+		}
+		switch {
+		case !team:
+		case r.Member == "":
+			_, _ = fmt.Fprintf(w.Stdout(), "%steam member: %s\n", indent, w.Yellow("none"))
+		case len(r.Roles) > 0:
+			_, _ = fmt.Fprintf(w.Stdout(), "%steam member: %s (roles: %s)\n", indent, r.Member, strings.Join(r.Roles, ", "))
+		default:
+			_, _ = fmt.Fprintf(w.Stdout(), "%steam member: %s\n", indent, r.Member)
+		}
+	}
+}
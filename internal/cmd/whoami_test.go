@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/backend/factory"
+	"github.com/xcke/envref/internal/config"
+)
+
+func TestWhoamiCmd(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "whoami-app")
+	chdir(t, dir)
+
+	ids, err := factory.OSIdentities()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+
+	stdout, _, err := execCmd(t, "whoami")
+	if err != nil {
+		t.Fatalf("whoami: %v", err)
+	}
+	if !strings.Contains(stdout, "secrets  memory  "+ids[0]+" (OS user)") {
+		t.Errorf("expected the OS user for the memory backend, got %q", stdout)
+	}
+	if strings.Contains(stdout, "team member") {
+		t.Errorf("expected no team member line without a team, got %q", stdout)
+	}
+
+	// With a roster, the identity is mapped to its team member.
+	base, err := os.ReadFile(filepath.Join(dir, config.FullFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeACLTestConfig(t, dir, string(base), "admin")
+	stdout, _, err = execCmd(t, "whoami", "secrets")
+	if err != nil {
+		t.Fatalf("whoami secrets: %v", err)
+	}
+	if !strings.Contains(stdout, "team member: alice (roles: admin)") {
+		t.Errorf("expected the team member, got %q", stdout)
+	}
+
+	stdout, _, err = execCmd(t, "whoami", "--format", "json")
+	if err != nil {
+		t.Fatalf("whoami --format json: %v", err)
+	}
+	var results []backendIdentity
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("parsing JSON: %v\n%s", err, stdout)
+	}
+	if len(results) != 1 || results[0].Identity != ids[0] || !results[0].OSUser || results[0].Member != "alice" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestWhoamiCmd_UnknownBackend(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryTestConfig(t, dir, "whoami-app")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "whoami", "secret")
+	if err == nil || !strings.Contains(err.Error(), `unknown backend "secret"`) {
+		t.Errorf("expected an unknown backend error, got %v", err)
+	}
+}
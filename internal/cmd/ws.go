@@ -0,0 +1,447 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/output"
+	"github.com/xcke/envref/internal/resolve"
+	"github.com/xcke/envref/internal/suggest"
+)
+
+// newWsCmd creates the ws (workspace) command group.
+func newWsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "ws",
+		Aliases: []string{"workspace"},
+		Short:   "Operate on every project of a monorepo workspace",
+		Long: `Manage the member projects of a monorepo workspace from anywhere inside it.
+
+A workspace root is an .envref.yaml that lists its members:
+
+  workspace:
+    members:
+      - path: services/api
+        project: api
+      - path: services/web
+
+A member with its own .envref.yaml is loaded like any project, with the
+declared project name taking precedence. A member without one inherits the
+root config (backends, aliases, profiles, ...) and must declare a project
+name, which namespaces its secrets. Env files are read from the member
+directory.
+
+Subcommands accept member names to limit the operation; by default every
+member is included.`,
+	}
+
+	cmd.AddCommand(newWsListCmd())
+	cmd.AddCommand(newWsResolveCmd())
+	cmd.AddCommand(newWsStatusCmd())
+
+	return cmd
+}
+
+// newWsListCmd creates the ws list subcommand.
+func newWsListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List workspace members",
+		Long: `List the members of the workspace with their path, project name, and
+whether they have their own .envref.yaml or inherit the root config.
+A member that fails to load is listed with its error, and the command
+exits with code 1.
+
+Examples:
+  envref ws list
+  envref ws list --format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			formatStr, _ := cmd.Flags().GetString("format")
+			return runWsList(cmd, formatStr)
+		},
+	}
+
+	cmd.Flags().String("format", "plain", "output format: plain, json")
+
+	return cmd
+}
+
+// newWsResolveCmd creates the ws resolve subcommand.
+func newWsResolveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resolve [member...]",
+		Short: "Resolve the environment of each workspace member",
+		Long: `Resolve the environment of each workspace member, as 'envref resolve' would
+from the member directory, and print the results grouped by member.
+
+In plain, shell, and table formats each member's output is preceded by a
+"# <member> (<path>)" comment line; the json format prints an array of
+{"member", "path", "entries"} objects. A member that fails to load or has
+unresolved references is reported on stderr, and the command exits with
+code 1 after processing the remaining members.
+
+Examples:
+  envref ws resolve                      # every member
+  envref ws resolve api web              # selected members only
+  envref ws resolve --profile staging --format json`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			formatStr, _ := cmd.Flags().GetString("format")
+			strict, _ := cmd.Flags().GetBool("strict")
+			return runWsResolve(cmd, args, profile, formatStr, strict)
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use for every member")
+	cmd.Flags().String("format", "plain", "output format: plain, json, shell, table")
+	cmd.Flags().Bool("strict", false, "fail with no output if any member cannot be fully resolved")
+
+	return cmd
+}
+
+// newWsStatusCmd creates the ws status subcommand.
+func newWsStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [member...]",
+		Short: "Show a one-line status for each workspace member",
+		Long: `Show, for each workspace member, the number of keys and secret references
+and how many references resolve. Run 'envref status' in a member directory
+for the full report.
+
+Examples:
+  envref ws status
+  envref ws status --profile production`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setVaultCmdContext(cmd)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			clearVaultCmdContext()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			return runWsStatus(cmd, args, profile)
+		},
+	}
+
+	cmd.Flags().StringP("profile", "P", "", "environment profile to use for every member")
+
+	return cmd
+}
+
+// workspace is a loaded workspace root and the members selected for a
+// command.
+type workspace struct {
+	root    *config.Config
+	rootDir string
+	members []config.WorkspaceMember
+}
+
+// loadWorkspace finds the workspace containing the working directory and
+// selects the named members, or all members if names is empty.
+func loadWorkspace(names []string) (*workspace, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+	root, rootDir, err := config.FindWorkspace(cwd)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &workspace{root: root, rootDir: rootDir, members: root.Workspace.Members}
+	if len(names) == 0 {
+		return ws, nil
+	}
+
+	byName := make(map[string]config.WorkspaceMember, len(root.Workspace.Members))
+	all := make([]string, 0, len(root.Workspace.Members))
+	for _, m := range root.Workspace.Members {
+		byName[m.Name()] = m
+		all = append(all, m.Name())
+	}
+	ws.members = nil
+	for _, name := range names {
+		m, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown workspace member %q%s", name, suggest.FormatSuggestion(suggest.Keys(name, all)))
+		}
+		ws.members = append(ws.members, m)
+	}
+	return ws, nil
+}
+
+// wsListEntry is a workspace member in ws list output.
+type wsListEntry struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Project string `json:"project,omitempty"`
+	Config  string `json:"config"`
+	Error   string `json:"error,omitempty"`
+}
+
+// runWsList prints the workspace members.
+func runWsList(cmd *cobra.Command, formatStr string) error {
+	format, err := parseFormat(formatStr)
+	if err != nil {
+		return err
+	}
+	ws, err := loadWorkspace(nil)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]wsListEntry, 0, len(ws.members))
+	failed := 0
+	for _, m := range ws.members {
+		entry := wsListEntry{Name: m.Name(), Path: m.Path, Config: "inherited"}
+		if _, statErr := os.Stat(filepath.Join(ws.rootDir, m.Path, config.FullFileName)); statErr == nil {
+			entry.Config = filepath.ToSlash(filepath.Join(m.Path, config.FullFileName))
+		}
+		cfg, _, loadErr := config.LoadMember(ws.root, ws.rootDir, m)
+		if loadErr != nil {
+			entry.Error = loadErr.Error()
+			failed++
+		} else {
+			entry.Project = cfg.Project
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := writeWsList(cmd.OutOrStdout(), format, entries); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d member(s) failed to load", failed, len(ws.members))
+	}
+	return nil
+}
+
+// writeWsList writes the ws list entries to out in format.
+func writeWsList(out io.Writer, format OutputFormat, entries []wsListEntry) error {
+	if format == FormatJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	nameWidth, pathWidth := len("NAME"), len("PATH")
+	for _, e := range entries {
+		nameWidth = max(nameWidth, len(e.Name))
+		pathWidth = max(pathWidth, len(e.Path))
+	}
+	_, _ = fmt.Fprintf(out, "%-*s  %-*s  %s\n", nameWidth, "NAME", pathWidth, "PATH", "CONFIG")
+	for _, e := range entries {
+		configCol := e.Config
+		if e.Error != "" {
+			configCol += " (error: " + e.Error + ")"
+		}
+		_, _ = fmt.Fprintf(out, "%-*s  %-*s  %s\n", nameWidth, e.Name, pathWidth, e.Path, configCol)
+	}
+	return nil
+}
+
+// wsResolved is one member's resolved environment in ws resolve output.
+type wsResolved struct {
+	Member  string   `json:"member"`
+	Path    string   `json:"path"`
+	Entries []kvPair `json:"entries"`
+}
+
+// runWsResolve resolves each selected member and prints the results.
+func runWsResolve(cmd *cobra.Command, names []string, profileOverride, formatStr string, strict bool) error {
+	format, err := parseFormat(formatStr)
+	if err != nil {
+		return err
+	}
+	ws, err := loadWorkspace(names)
+	if err != nil {
+		return err
+	}
+
+	var results []wsResolved
+	failed := 0
+	for _, m := range ws.members {
+		entries, err := resolveWsMember(cmd, ws, m, profileOverride)
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: %s: %s\n", m.Name(), err)
+			failed++
+			if entries == nil {
+				continue
+			}
+		}
+		pairs := make([]kvPair, len(entries))
+		for i, e := range entries {
+			pairs[i] = kvPair{Key: e.Key, Value: e.Value}
+		}
+		results = append(results, wsResolved{Member: m.Name(), Path: m.Path, Entries: pairs})
+	}
+
+	if strict && failed > 0 {
+		return fmt.Errorf("%d of %d member(s) failed (strict mode: no output produced)", failed, len(ws.members))
+	}
+
+	out := cmd.OutOrStdout()
+	if format == FormatJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for i, r := range results {
+			if i > 0 {
+				_, _ = fmt.Fprintln(out)
+			}
+			_, _ = fmt.Fprintf(out, "# %s (%s)\n", r.Member, r.Path)
+			if err := formatKVPairs(out, r.Entries, format); err != nil {
+				return err
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d member(s) failed", failed, len(ws.members))
+	}
+	return nil
+}
+
+// resolveWsMember runs the resolve pipeline for one member, including its
+// hooks. When some references cannot be resolved it returns the partial
+// entries together with an error.
+func resolveWsMember(cmd *cobra.Command, ws *workspace, m config.WorkspaceMember, profileOverride string) ([]resolve.Entry, error) {
+	cfg, dir, err := config.LoadMember(ws.root, ws.rootDir, m)
+	if err != nil {
+		return nil, err
+	}
+	if err := runHook(cmd, hookPreResolve, cfg.Hooks, dir, nil); err != nil {
+		return nil, err
+	}
+
+	profile := cfg.EffectiveProfile(profileOverride)
+	env, err := loadProjectEnv(cmd, cfg, dir, profile)
+	if err != nil {
+		return nil, err
+	}
+	result, err := resolveMemberEnv(cmd, cfg, dir, env, profile)
+	if err != nil {
+		return nil, err
+	}
+	for _, keyErr := range result.Errors {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: %s: %s\n", m.Name(), keyErr.Error())
+	}
+
+	if err := runHook(cmd, hookPostResolve, cfg.Hooks, dir, result.Entries); err != nil {
+		return nil, err
+	}
+	if !result.Resolved() {
+		return result.Entries, withExitCode(unresolvedExitCode(result.Errors), fmt.Errorf("%d reference(s) could not be resolved", len(result.Errors)))
+	}
+	return result.Entries, nil
+}
+
+// resolveMemberEnv resolves the references in env with the backends of the
+// member in dir. An env without references is returned as-is.
+func resolveMemberEnv(cmd *cobra.Command, cfg *config.Config, dir string, env *envfile.Env, profile string) (*resolve.Result, error) {
+	if !env.HasAnyRefs() {
+		return &resolve.Result{Entries: envToEntries(env)}, nil
+	}
+	if len(cfg.Backends) == 0 {
+		return nil, withExitCode(exitConfig, fmt.Errorf("ref:// references found but no backends configured"))
+	}
+
+	registry, err := buildRegistry(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("initializing backends: %w", err)
+	}
+	defer registry.CloseAll()
+
+	result, err := resolveWithProgress(cmd, env, registry, cfg, cfg.ProfileChain(profile))
+	if err != nil {
+		return nil, fmt.Errorf("resolving references: %w", err)
+	}
+	auditRefReads(cfg, dir, profile, env, result)
+	return result, nil
+}
+
+// runWsStatus prints a one-line summary per selected member.
+func runWsStatus(cmd *cobra.Command, names []string, profileOverride string) error {
+	w := output.NewWriter(cmd)
+	ws, err := loadWorkspace(names)
+	if err != nil {
+		return err
+	}
+
+	nameWidth := 0
+	for _, m := range ws.members {
+		nameWidth = max(nameWidth, len(m.Name()))
+	}
+
+	failed := 0
+	for _, m := range ws.members {
+		summary, ok := wsMemberStatus(cmd, ws, m, profileOverride)
+		if !ok {
+			failed++
+		}
+		icon := w.Green("[ok]")
+		if !ok {
+			icon = w.Red("[!!]")
+		}
+		_, _ = fmt.Fprintf(w.Stdout(), "%s %-*s  %s\n", icon, nameWidth, m.Name(), summary)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d member(s) have problems", failed, len(ws.members))
+	}
+	return nil
+}
+
+// wsMemberStatus returns a summary of one member's environment and whether
+// it loads and resolves completely. Hooks are not run.
+func wsMemberStatus(cmd *cobra.Command, ws *workspace, m config.WorkspaceMember, profileOverride string) (string, bool) {
+	cfg, dir, err := config.LoadMember(ws.root, ws.rootDir, m)
+	if err != nil {
+		return err.Error(), false
+	}
+	profile := cfg.EffectiveProfile(profileOverride)
+	env, err := loadProjectEnv(cmd, cfg, dir, profile)
+	if err != nil {
+		return err.Error(), false
+	}
+
+	refs := len(env.Refs())
+	parts := []string{fmt.Sprintf("%d keys", env.Len())}
+	if profile != "" {
+		parts = append([]string{"profile " + profile}, parts...)
+	}
+	if refs == 0 {
+		return strings.Join(parts, ", "), true
+	}
+
+	result, err := resolveMemberEnv(cmd, cfg, dir, env, profile)
+	if err != nil {
+		return strings.Join(append(parts, err.Error()), ", "), false
+	}
+	parts = append(parts, fmt.Sprintf("%d/%d secrets resolved", refs-len(result.Errors), refs))
+	if !result.Resolved() {
+		missing := make([]string, len(result.Errors))
+		for i, keyErr := range result.Errors {
+			missing[i] = keyErr.Key
+		}
+		parts = append(parts, "missing: "+strings.Join(missing, ", "))
+	}
+	return strings.Join(parts, ", "), result.Resolved()
+}
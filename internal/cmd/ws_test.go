@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/config"
+)
+
+// setupWorkspaceProject creates a workspace root with two members: "api",
+// which has its own config, and "web", which inherits the root config.
+func setupWorkspaceProject(t *testing.T) string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	writeTestFile(t, root, config.FullFileName, `project: mono
+workspace:
+  members:
+    - path: services/api
+      project: api
+    - path: apps/web
+      project: web
+`)
+	api := filepath.Join(root, "services", "api")
+	web := filepath.Join(root, "apps", "web")
+	for _, dir := range []string{api, web} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestFile(t, api, config.FullFileName, "project: api-service\n")
+	writeTestFile(t, api, ".env", "PORT=8080\nNAME=api\n")
+	writeTestFile(t, web, ".env", "PORT=3000\nURL=http://localhost:${PORT}\n")
+	return root
+}
+
+func TestWsListCmd(t *testing.T) {
+	root := setupWorkspaceProject(t)
+	chdir(t, filepath.Join(root, "apps", "web"))
+
+	stdout, _, err := execCmd(t, "ws", "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header + 2 members, got %q", stdout)
+	}
+	if !strings.Contains(lines[1], "api") || !strings.Contains(lines[1], "services/api/.envref.yaml") {
+		t.Errorf("unexpected api line: %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "web") || !strings.Contains(lines[2], "inherited") {
+		t.Errorf("unexpected web line: %q", lines[2])
+	}
+}
+
+func TestWsListCmd_JSON(t *testing.T) {
+	root := setupWorkspaceProject(t)
+	chdir(t, root)
+
+	stdout, _, err := execCmd(t, "ws", "list", "--format", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var entries []wsListEntry
+	if err := json.Unmarshal([]byte(stdout), &entries); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout)
+	}
+	if len(entries) != 2 || entries[0].Project != "api" || entries[1].Project != "web" {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
+
+func TestWsListCmd_MemberExtendsRoot(t *testing.T) {
+	root := setupWorkspaceProject(t)
+	api := filepath.Join(root, "services", "api")
+	writeTestFile(t, api, config.FullFileName, "project: api-service\nextends: ../../.envref.yaml\n")
+	chdir(t, api)
+
+	stdout, _, err := execCmd(t, "ws", "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, stdout)
+	}
+	if strings.Contains(stdout, "error") {
+		t.Errorf("expected the members of the real root, got %q", stdout)
+	}
+}
+
+func TestWsListCmd_MemberError(t *testing.T) {
+	root := setupWorkspaceProject(t)
+	if err := os.RemoveAll(filepath.Join(root, "apps", "web")); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, root)
+
+	stdout, _, err := execCmd(t, "ws", "list")
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 member(s) failed to load") {
+		t.Fatalf("expected a member failure, got %v", err)
+	}
+	if !strings.Contains(stdout, "apps/web") || !strings.Contains(stdout, "(error: ") {
+		t.Errorf("expected the failing member to be listed, got %q", stdout)
+	}
+}
+
+func TestWsCmd_NoWorkspace(t *testing.T) {
+	dir := setupProject(t, "solo", "A=1\n", "")
+	chdir(t, dir)
+
+	_, _, err := execCmd(t, "ws", "list")
+	if err == nil || !strings.Contains(err.Error(), "no workspace found") {
+		t.Fatalf("expected no workspace error, got %v", err)
+	}
+}
+
+func TestWsResolveCmd(t *testing.T) {
+	root := setupWorkspaceProject(t)
+	chdir(t, root)
+
+	stdout, _, err := execCmd(t, "ws", "resolve")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "# api (services/api)\nPORT=8080\nNAME=api\n\n# web (apps/web)\nPORT=3000\nURL=http://localhost:3000\n"
+	if stdout != want {
+		t.Errorf("got:\n%s\nwant:\n%s", stdout, want)
+	}
+}
+
+func TestWsResolveCmd_SelectedMemberJSON(t *testing.T) {
+	root := setupWorkspaceProject(t)
+	chdir(t, root)
+
+	stdout, _, err := execCmd(t, "ws", "resolve", "web", "--format", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var results []wsResolved
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout)
+	}
+	if len(results) != 1 || results[0].Member != "web" || len(results[0].Entries) != 2 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestWsResolveCmd_UnknownMember(t *testing.T) {
+	root := setupWorkspaceProject(t)
+	chdir(t, root)
+
+	_, _, err := execCmd(t, "ws", "resolve", "wbe")
+	if err == nil || !strings.Contains(err.Error(), `unknown workspace member "wbe"`) {
+		t.Fatalf("expected unknown member error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "web") {
+		t.Errorf("expected suggestion, got %v", err)
+	}
+}
+
+func TestWsResolveCmd_MemberFailure(t *testing.T) {
+	root := setupWorkspaceProject(t)
+	writeTestFile(t, filepath.Join(root, "apps", "web"), ".env", "TOKEN=ref://secrets/token\n")
+	chdir(t, root)
+
+	stdout, stderr, err := execCmd(t, "ws", "resolve")
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 member(s) failed") {
+		t.Fatalf("expected member failure, got %v", err)
+	}
+	if !strings.Contains(stderr, "error: web:") {
+		t.Errorf("expected web error on stderr, got %q", stderr)
+	}
+	if !strings.Contains(stdout, "PORT=8080") {
+		t.Errorf("expected api output despite web failure, got %q", stdout)
+	}
+}
+
+func TestWsStatusCmd(t *testing.T) {
+	root := setupWorkspaceProject(t)
+	chdir(t, root)
+
+	stdout, _, err := execCmd(t, "ws", "status")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stdout, "[ok] api  2 keys") || !strings.Contains(stdout, "[ok] web  2 keys") {
+		t.Errorf("unexpected status output:\n%s", stdout)
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ACLRule returns the team members and roles allowed to read and write the
+// secret key in the given profile scope ("" for the project scope), and
+// the acl pattern they come from. A pattern matches the key or, for a
+// profile-scoped secret, "<profile>/<key>", so that "production/*" covers
+// every secret of the production profile. Patterns are path.Match globs
+// compared case-insensitively; an exact name takes precedence over globs,
+// and among globs the longest (most specific) pattern wins. ok is false if
+// no pattern matches, in which case anyone may access the secret.
+func (c *Config) ACLRule(profile, key string) (allowed []string, pattern string, ok bool) {
+	names := []string{key}
+	if profile != "" {
+		names = []string{profile + "/" + key, key}
+	}
+	patterns := sortedPatterns(c.ACL)
+	for _, name := range names {
+		for _, p := range patterns {
+			if strings.EqualFold(p, name) {
+				return c.ACL[p], p, true
+			}
+		}
+		for _, p := range patterns {
+			if matched, _ := path.Match(strings.ToUpper(p), strings.ToUpper(name)); matched {
+				return c.ACL[p], p, true
+			}
+		}
+	}
+	return nil, "", false
+}
+
+// TeamMemberByIdentity returns the team member known by one of identities,
+// or nil if there is none. A member is known by their name and by each of
+// their identities, compared case-insensitively.
+func (c *Config) TeamMemberByIdentity(identities ...string) *TeamMember {
+	for _, id := range identities {
+		if id == "" {
+			continue
+		}
+		for i := range c.Team {
+			m := &c.Team[i]
+			if m.Name == id || slices.ContainsFunc(m.Identities, func(s string) bool { return strings.EqualFold(s, id) }) {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// AllowedBy reports whether allowed, the entry of an acl pattern, names the
+// member or one of their roles.
+func (m *TeamMember) AllowedBy(allowed []string) bool {
+	for _, a := range allowed {
+		if a == m.Name || slices.Contains(m.Roles, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateACL returns the problems with the acl block and the access
+// control fields of team members.
+func (c *Config) validateACL() []string {
+	var errs []string
+	owner := make(map[string]string)
+	for i, m := range c.Team {
+		for _, role := range m.Roles {
+			if strings.TrimSpace(role) == "" {
+				errs = append(errs, fmt.Sprintf("team[%d]: roles must not be empty", i))
+			}
+		}
+		for _, id := range m.Identities {
+			key := strings.ToLower(id)
+			switch prev, seen := owner[key]; {
+			case strings.TrimSpace(id) == "":
+				errs = append(errs, fmt.Sprintf("team[%d]: identities must not be empty", i))
+			case seen && prev != m.Name:
+				errs = append(errs, fmt.Sprintf("team[%d]: identity %q is also an identity of %s", i, id, prev))
+			default:
+				owner[key] = m.Name
+			}
+		}
+	}
+
+	for _, pattern := range sortedPatterns(c.ACL) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("acl: invalid key pattern %q", pattern))
+		}
+		if len(c.ACL[pattern]) == 0 {
+			errs = append(errs, fmt.Sprintf("acl: %s: list the team members or roles allowed", pattern))
+		}
+		for _, a := range c.ACL[pattern] {
+			if strings.TrimSpace(a) == "" {
+				errs = append(errs, fmt.Sprintf("acl: %s: entries must not be empty", pattern))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConfig_ACLRule(t *testing.T) {
+	cfg := &Config{ACL: map[string][]string{
+		"PROD_*":       {"admin"},
+		"PROD_API_*":   {"admin", "api"},
+		"PROD_API_KEY": {"alice"},
+		"production/*": {"ops"},
+	}}
+
+	tests := []struct {
+		profile, key string
+		wantPattern  string
+		wantOK       bool
+	}{
+		{"", "PROD_API_KEY", "PROD_API_KEY", true},
+		{"", "prod_api_key", "PROD_API_KEY", true},
+		{"", "PROD_API_TOKEN", "PROD_API_*", true},
+		{"", "PROD_DB", "PROD_*", true},
+		{"staging", "PROD_DB", "PROD_*", true},
+		{"production", "DB_PASS", "production/*", true},
+		{"production", "PROD_API_KEY", "production/*", true},
+		{"", "STAGING_DB", "", false},
+		{"staging", "DB_PASS", "", false},
+	}
+	for _, tt := range tests {
+		_, pattern, ok := cfg.ACLRule(tt.profile, tt.key)
+		if ok != tt.wantOK || pattern != tt.wantPattern {
+			t.Errorf("ACLRule(%q, %q) = %q, %v; want %q, %v", tt.profile, tt.key, pattern, ok, tt.wantPattern, tt.wantOK)
+		}
+	}
+
+	if _, _, ok := (&Config{}).ACLRule("", "PROD_DB"); ok {
+		t.Error("ACLRule without an acl block should not match")
+	}
+}
+
+func TestConfig_TeamMemberByIdentity(t *testing.T) {
+	cfg := &Config{Team: []TeamMember{
+		{Name: "alice", Roles: []string{"admin"}, Identities: []string{"arn:aws:iam::123456789012:user/alice", "alice@laptop"}},
+		{Name: "bob", Roles: []string{"contributor"}},
+	}}
+
+	tests := []struct {
+		ids  []string
+		want string
+	}{
+		{[]string{"arn:aws:iam::123456789012:user/alice"}, "alice"},
+		{[]string{"ALICE@LAPTOP"}, "alice"},
+		{[]string{"bob@desktop", "bob"}, "bob"},
+		{[]string{"", "carol"}, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if m := cfg.TeamMemberByIdentity(tt.ids...); m != nil {
+			got = m.Name
+		}
+		if got != tt.want {
+			t.Errorf("TeamMemberByIdentity(%q) = %q, want %q", tt.ids, got, tt.want)
+		}
+	}
+
+	alice, bob := &cfg.Team[0], &cfg.Team[1]
+	if !alice.AllowedBy([]string{"admin"}) || !bob.AllowedBy([]string{"admin", "bob"}) {
+		t.Error("AllowedBy should allow members by role and by name")
+	}
+	if bob.AllowedBy([]string{"admin"}) {
+		t.Error("AllowedBy should not allow a member without the role")
+	}
+}
+
+func TestLoadFile_ACL(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, `project: app
+team:
+  - name: alice
+    public_key: age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+    roles: [admin]
+    identities: [alice@laptop]
+acl:
+  PROD_*: [admin]
+  Stripe_Key: [alice]
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(cfg.ACL["PROD_*"]) != 1 || len(cfg.ACL["Stripe_Key"]) != 1 {
+		t.Errorf("acl = %v, want case-preserving keys", cfg.ACL)
+	}
+	if m := cfg.TeamMemberByIdentity("alice@laptop"); m == nil || len(m.Roles) != 1 || m.Roles[0] != "admin" {
+		t.Errorf("team member = %+v, want alice with role admin", m)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestValidate_ACL(t *testing.T) {
+	cfg := &Config{
+		Project: "app",
+		Team: []TeamMember{
+			{Name: "alice", PublicKey: "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p", Roles: []string{"admin"}, Identities: []string{"shared"}},
+			{Name: "bob", PublicKey: "age1lggyhqrw2nlhcxprm67z43rta597azn8gknawjehu9d9dl0jq3yqqvfafg", Identities: []string{"SHARED"}},
+		},
+		ACL: map[string][]string{
+			"PROD_*": {"admin", " "},
+			"[":      {"alice"},
+			"DB_*":   {},
+		},
+	}
+
+	err := cfg.Validate()
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		`identity "SHARED" is also an identity of alice`,
+		"PROD_*: entries must not be empty",
+		`invalid key pattern "["`,
+		"DB_*: list the team members or roles allowed",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q does not mention %q", msg, want)
+		}
+	}
+}
+
+func TestMergeConfigs_ACL(t *testing.T) {
+	global := &Config{ACL: map[string][]string{"*": {"admin"}}}
+
+	merged := mergeConfigs(global, &Config{})
+	if len(merged.ACL["*"]) != 1 {
+		t.Errorf("acl = %v, want the global acl", merged.ACL)
+	}
+
+	merged = mergeConfigs(global, &Config{ACL: map[string][]string{"PROD_*": {"ops"}}})
+	if _, ok := merged.ACL["*"]; ok || len(merged.ACL["PROD_*"]) != 1 {
+		t.Errorf("acl = %v, want the project acl only", merged.ACL)
+	}
+}
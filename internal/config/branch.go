@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// branchLookup finds the profile BranchProfiles selects for the branch
+// checked out in dir the first time it is needed, and remembers it.
+type branchLookup struct {
+	dir     string
+	once    sync.Once
+	profile string
+	ok      bool
+}
+
+// CurrentBranch returns the git branch checked out in dir, or "" if dir is
+// not in a git work tree, HEAD is detached, or git is not installed.
+func CurrentBranch(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "symbolic-ref", "--quiet", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// BranchProfile returns the profile BranchProfiles selects for branch. A
+// key naming the branch exactly wins; otherwise the longest glob pattern
+// matching it (see path.Match) does, with "*" matching any branch, even
+// one with a slash or none at all (a detached HEAD).
+func (c *Config) BranchProfile(branch string) (string, bool) {
+	if branch != "" {
+		if profile, ok := c.BranchProfiles[branch]; ok {
+			return profile, true
+		}
+	}
+	for _, pattern := range sortedBranchPatterns(c.BranchProfiles) {
+		if pattern == "*" {
+			return c.BranchProfiles[pattern], true
+		}
+		if matched, _ := path.Match(pattern, branch); matched && branch != "" {
+			return c.BranchProfiles[pattern], true
+		}
+	}
+	return "", false
+}
+
+// applyBranchProfile makes EffectiveProfile prefer the profile
+// BranchProfiles selects for the git branch checked out in dir. git only
+// runs once EffectiveProfile needs the branch, so not at all when
+// ENVREF_PROFILE or a --profile flag names the profile.
+func applyBranchProfile(cfg *Config, dir string) {
+	if len(cfg.BranchProfiles) == 0 || os.Getenv(EnvProfile) != "" {
+		return
+	}
+	cfg.branch = &branchLookup{dir: dir}
+}
+
+// branchProfile returns the profile selected by the checked out branch,
+// looking the branch up on first use.
+func (c *Config) branchProfile() (string, bool) {
+	if c.branch == nil {
+		return "", false
+	}
+	c.branch.once.Do(func() {
+		c.branch.profile, c.branch.ok = c.BranchProfile(CurrentBranch(c.branch.dir))
+	})
+	return c.branch.profile, c.branch.ok
+}
+
+// sortedBranchPatterns returns the keys of branchProfiles, longest first
+// so that more specific patterns are tried before broader ones, and in
+// lexical order among keys of the same length.
+func sortedBranchPatterns(branchProfiles map[string]string) []string {
+	patterns := make([]string, 0, len(branchProfiles))
+	for pattern := range branchProfiles {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	return patterns
+}
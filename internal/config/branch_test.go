@@ -0,0 +1,169 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// gitInit creates a git repository in dir with branch checked out.
+func gitInit(t *testing.T, dir, branch string) {
+	t.Helper()
+	if out, err := exec.Command("git", "-C", dir, "init", "-q", "-b", branch).CombinedOutput(); err != nil {
+		t.Skipf("git init: %v: %s", err, out)
+	}
+}
+
+func TestConfig_BranchProfile(t *testing.T) {
+	cfg := Config{BranchProfiles: map[string]string{
+		"main":        "production",
+		"release/*":   "staging",
+		"release/1.x": "legacy",
+		"*":           "development",
+	}}
+
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"main", "production"},
+		{"release/2.0", "staging"},
+		{"release/1.x", "legacy"},
+		{"feature/login", "development"},
+		{"", "development"},
+	}
+	for _, tt := range tests {
+		if got, ok := cfg.BranchProfile(tt.branch); !ok || got != tt.want {
+			t.Errorf("BranchProfile(%q) = %q, %v; want %q", tt.branch, got, ok, tt.want)
+		}
+	}
+
+	delete(cfg.BranchProfiles, "*")
+	if got, ok := cfg.BranchProfile("feature/login"); ok {
+		t.Errorf("BranchProfile(feature/login) without a catch-all = %q, want no match", got)
+	}
+}
+
+func TestLoad_BranchProfiles(t *testing.T) {
+	t.Setenv("ENVREF_CONFIG_DIR", t.TempDir())
+	t.Setenv(EnvProfile, "")
+	dir := t.TempDir()
+	gitInit(t, dir, "develop")
+	writeFile(t, dir, FullFileName, `project: myapp
+active_profile: local
+branch_profiles:
+  main: production
+  develop: staging
+`)
+
+	cfg, _, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.EffectiveProfile(""); got != "staging" {
+		t.Errorf("EffectiveProfile() = %q, want staging from the develop branch", got)
+	}
+	if got := cfg.EffectiveProfile("qa"); got != "qa" {
+		t.Errorf("EffectiveProfile(qa) = %q, want the flag to win", got)
+	}
+
+	t.Setenv(EnvProfile, "ci")
+	if cfg, _, err = Load(dir); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.EffectiveProfile(""); got != "ci" {
+		t.Errorf("EffectiveProfile() = %q, want ci from %s", got, EnvProfile)
+	}
+
+	t.Setenv(EnvProfile, "")
+	if err := exec.Command("git", "-C", dir, "checkout", "-q", "-b", "feature/x").Run(); err != nil {
+		t.Fatalf("git checkout: %v", err)
+	}
+	if cfg, _, err = Load(dir); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.EffectiveProfile(""); got != "local" {
+		t.Errorf("EffectiveProfile() = %q, want active_profile on an unmapped branch", got)
+	}
+}
+
+func TestLoad_BranchProfilesLookupOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake git is a shell script")
+	}
+	t.Setenv("ENVREF_CONFIG_DIR", t.TempDir())
+	t.Setenv(EnvProfile, "")
+	dir := t.TempDir()
+	writeFile(t, dir, FullFileName, "project: myapp\nbranch_profiles:\n  main: production\n")
+
+	// A fake git that records each call and reports the main branch.
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	writeFile(t, bin, "git", "#!/bin/sh\necho call >> "+calls+"\necho main\n")
+	if err := os.Chmod(filepath.Join(bin, "git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	countCalls := func() int {
+		data, _ := os.ReadFile(calls)
+		return strings.Count(string(data), "call")
+	}
+
+	cfg, _, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.EffectiveProfile("qa"); got != "qa" || countCalls() != 0 {
+		t.Errorf("EffectiveProfile(qa) = %q after %d git calls, want qa without git", got, countCalls())
+	}
+	for range 2 {
+		if got := cfg.EffectiveProfile(""); got != "production" {
+			t.Errorf("EffectiveProfile() = %q, want production", got)
+		}
+	}
+	if n := countCalls(); n != 1 {
+		t.Errorf("git ran %d times, want once", n)
+	}
+
+	t.Setenv(EnvProfile, "ci")
+	if cfg, _, err = Load(dir); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.EffectiveProfile(""); got != "ci" || countCalls() != 1 {
+		t.Errorf("EffectiveProfile() = %q after %d git calls, want ci without git", got, countCalls())
+	}
+}
+
+func TestValidate_BranchProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		branches map[string]string
+		want     string
+	}{
+		{name: "valid", branches: map[string]string{"main": "production", "*": "development"}},
+		{name: "bad pattern", branches: map[string]string{"release/[": "staging"}, want: `branch_profiles: invalid branch pattern "release/["`},
+		{name: "empty profile", branches: map[string]string{"main": ""}, want: `branch_profiles: branch "main" must name a profile`},
+		{name: "undefined profile", branches: map[string]string{"main": "prodution"}, want: `branch "main" selects profile "prodution", which is not defined in profiles; did you mean production?`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Defaults()
+			cfg.Project = "myapp"
+			cfg.Profiles = map[string]ProfileConfig{"production": {}, "development": {}}
+			cfg.BranchProfiles = tt.branches
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// ComposeConfig declares the Docker Compose services that "envref compose"
+// writes env files for, and the keys each one receives.
+type ComposeConfig struct {
+	// Services lists the services, in the order their files are written.
+	Services []ComposeService `mapstructure:"services" yaml:"services"`
+}
+
+// ComposeService is a Docker Compose service and the keys of the resolved
+// environment it receives.
+type ComposeService struct {
+	// Name is the service name, as in compose.yaml.
+	Name string `mapstructure:"name" yaml:"name"`
+
+	// Keys lists the key patterns (path.Match globs such as "API_*") the
+	// service receives. An empty list means every key.
+	Keys []string `mapstructure:"keys" yaml:"keys"`
+}
+
+// composeServiceName matches the service names Docker Compose accepts.
+var composeServiceName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Service returns the service named name.
+func (c ComposeConfig) Service(name string) (ComposeService, bool) {
+	for _, s := range c.Services {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return ComposeService{}, false
+}
+
+// Names returns the service names, in declaration order.
+func (c ComposeConfig) Names() []string {
+	names := make([]string, len(c.Services))
+	for i, s := range c.Services {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// Includes reports whether the service receives key.
+func (s ComposeService) Includes(key string) bool {
+	if len(s.Keys) == 0 {
+		return true
+	}
+	for _, p := range s.Keys {
+		if matched, _ := path.Match(p, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// validate returns problems with the service list: missing, invalid, or
+// duplicate names and invalid key patterns.
+func (c ComposeConfig) validate() []string {
+	var errs []string
+	seen := make(map[string]bool, len(c.Services))
+	for i, s := range c.Services {
+		switch {
+		case s.Name == "":
+			errs = append(errs, fmt.Sprintf("compose.services[%d]: name is required", i))
+		case !composeServiceName.MatchString(s.Name):
+			errs = append(errs, fmt.Sprintf("compose.services[%d]: invalid service name %q", i, s.Name))
+		case seen[s.Name]:
+			errs = append(errs, fmt.Sprintf("compose.services[%d]: duplicate service %q", i, s.Name))
+		}
+		seen[s.Name] = true
+		for j, p := range s.Keys {
+			if p == "" {
+				errs = append(errs, fmt.Sprintf("compose.services[%d].keys[%d]: pattern must not be empty", i, j))
+			} else if _, err := path.Match(p, ""); err != nil {
+				errs = append(errs, fmt.Sprintf("compose.services[%d].keys[%d]: invalid key pattern %q", i, j, p))
+			}
+		}
+	}
+	return errs
+}
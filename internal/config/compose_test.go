@@ -0,0 +1,88 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad_Compose(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, dir, FullFileName, `project: app
+compose:
+  services:
+    - name: api
+      keys: [DATABASE_URL, "API_*"]
+    - name: Worker
+`)
+
+	cfg, _, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.Compose.Names(); strings.Join(got, ",") != "api,Worker" {
+		t.Fatalf("Names() = %v, want [api Worker]", got)
+	}
+	api, ok := cfg.Compose.Service("api")
+	if !ok {
+		t.Fatal("Service(api) not found")
+	}
+	for key, want := range map[string]bool{"DATABASE_URL": true, "API_KEY": true, "WEB_PORT": false} {
+		if got := api.Includes(key); got != want {
+			t.Errorf("api.Includes(%q) = %v, want %v", key, got, want)
+		}
+	}
+	worker, _ := cfg.Compose.Service("Worker")
+	if !worker.Includes("ANYTHING") {
+		t.Error("a service without keys should receive every key")
+	}
+	if _, ok := cfg.Compose.Service("web"); ok {
+		t.Error("Service(web) should not be found")
+	}
+}
+
+func TestLoad_ComposeNotInheritedThroughExtends(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, dir, "base.yaml", "project: base\ncompose:\n  services:\n    - name: api\n")
+	writeFile(t, dir, FullFileName, "project: app\nextends: "+filepath.Join(dir, "base.yaml")+"\n")
+
+	cfg, _, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Compose.Services) != 0 {
+		t.Errorf("compose should not be inherited, got %v", cfg.Compose.Services)
+	}
+}
+
+func TestValidate_Compose(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []ComposeService
+		wantErr  string
+	}{
+		{"valid", []ComposeService{{Name: "api", Keys: []string{"API_*"}}, {Name: "web.v2"}}, ""},
+		{"missing name", []ComposeService{{Keys: []string{"A"}}}, "compose.services[0]: name is required"},
+		{"invalid name", []ComposeService{{Name: "my api"}}, `compose.services[0]: invalid service name "my api"`},
+		{"duplicate", []ComposeService{{Name: "api"}, {Name: "api"}}, `compose.services[1]: duplicate service "api"`},
+		{"empty pattern", []ComposeService{{Name: "api", Keys: []string{""}}}, "compose.services[0].keys[0]: pattern must not be empty"},
+		{"bad pattern", []ComposeService{{Name: "api", Keys: []string{"API_["}}}, `compose.services[0].keys[0]: invalid key pattern "API_["`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Project: "app", EnvFile: ".env", LocalFile: ".env.local", Compose: ComposeConfig{Services: tt.services}}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -8,14 +8,26 @@
 package config
 
 import (
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/remote"
+	"github.com/xcke/envref/internal/schema"
+	"github.com/xcke/envref/internal/suggest"
+	"go.yaml.in/yaml/v3"
 )
 
 // KnownBackendTypes lists the backend types that envref recognizes. This is
@@ -27,6 +39,7 @@ var KnownBackendTypes = []string{
 	"aws-ssm",
 	"oci-vault",
 	"hashicorp-vault",
+	"memory",
 }
 
 // ValidationError is returned when the config is syntactically valid YAML but
@@ -98,7 +111,7 @@ func loadGlobalConfig() (*Config, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil, nil
 	}
-	cfg, err := loadFile(path)
+	cfg, err := loadFileWithExtends(path, nil)
 	if err != nil {
 		return nil, fmt.Errorf("loading global config: %w", err)
 	}
@@ -141,6 +154,13 @@ func mergeConfigs(global, project *Config) *Config {
 	if merged.ActiveProfile == "" {
 		merged.ActiveProfile = global.ActiveProfile
 	}
+	if len(merged.EnvFiles) == 0 && len(global.EnvFiles) > 0 {
+		merged.EnvFiles = append([]string(nil), global.EnvFiles...)
+	}
+	if merged.Layering == "" {
+		merged.Layering = global.Layering
+	}
+	merged.InterpolateSystemEnv = merged.InterpolateSystemEnv || global.InterpolateSystemEnv
 
 	// Backends: project replaces entirely if present, otherwise inherit global.
 	if len(merged.Backends) == 0 && len(global.Backends) > 0 {
@@ -156,6 +176,149 @@ func mergeConfigs(global, project *Config) *Config {
 		}
 	}
 
+	// Aliases: project replaces entirely if present, otherwise inherit global.
+	if len(merged.Aliases) == 0 && len(global.Aliases) > 0 {
+		merged.Aliases = make(map[string][]string, len(global.Aliases))
+		for k, v := range global.Aliases {
+			merged.Aliases[k] = v
+		}
+	}
+
+	// Schema: project replaces entirely if present, otherwise inherit global.
+	if len(merged.Schema) == 0 && len(global.Schema) > 0 {
+		merged.Schema = make(map[string]schema.Rule, len(global.Schema))
+		for k, v := range global.Schema {
+			merged.Schema[k] = v
+		}
+	}
+
+	// Rotation: project replaces entirely if present, otherwise inherit global.
+	if len(merged.Rotation) == 0 && len(global.Rotation) > 0 {
+		merged.Rotation = make(map[string]string, len(global.Rotation))
+		for k, v := range global.Rotation {
+			merged.Rotation[k] = v
+		}
+	}
+
+	// ACL: project replaces entirely if present, otherwise inherit global.
+	if len(merged.ACL) == 0 && len(global.ACL) > 0 {
+		merged.ACL = make(map[string][]string, len(global.ACL))
+		for k, v := range global.ACL {
+			merged.ACL[k] = v
+		}
+	}
+
+	// KeyAliases: project replaces entirely if present, otherwise inherit global.
+	if len(merged.KeyAliases) == 0 && len(global.KeyAliases) > 0 {
+		merged.KeyAliases = make(map[string][]string, len(global.KeyAliases))
+		for k, v := range global.KeyAliases {
+			merged.KeyAliases[k] = v
+		}
+	}
+
+	// BranchProfiles: project replaces entirely if present, otherwise inherit global.
+	if len(merged.BranchProfiles) == 0 && len(global.BranchProfiles) > 0 {
+		merged.BranchProfiles = make(map[string]string, len(global.BranchProfiles))
+		for k, v := range global.BranchProfiles {
+			merged.BranchProfiles[k] = v
+		}
+	}
+
+	// RefSchemes: project replaces entirely if present, otherwise inherit global.
+	if len(merged.RefSchemes) == 0 && len(global.RefSchemes) > 0 {
+		merged.RefSchemes = make(map[string]string, len(global.RefSchemes))
+		for k, v := range global.RefSchemes {
+			merged.RefSchemes[k] = v
+		}
+	}
+
+	// Hooks: each hook is inherited unless the project sets it.
+	if merged.Hooks.PreResolve == "" {
+		merged.Hooks.PreResolve = global.Hooks.PreResolve
+	}
+	if merged.Hooks.PostResolve == "" {
+		merged.Hooks.PostResolve = global.Hooks.PostResolve
+	}
+
+	// Prefix: each part is inherited unless the project sets it.
+	if merged.Prefix.Add == "" {
+		merged.Prefix.Add = global.Prefix.Add
+	}
+	if merged.Prefix.Strip == "" {
+		merged.Prefix.Strip = global.Prefix.Strip
+	}
+
+	// Remote: the cache TTL is inherited unless the project sets it, and
+	// the public keys unless the project lists its own.
+	if merged.Remote.CacheTTL == "" {
+		merged.Remote.CacheTTL = global.Remote.CacheTTL
+	}
+	if len(merged.Remote.PublicKeys) == 0 && len(global.Remote.PublicKeys) > 0 {
+		merged.Remote.PublicKeys = append([]string(nil), global.Remote.PublicKeys...)
+	}
+
+	// Audit: signing and read logging are enabled if either config enables
+	// them, and global sinks are kept in front of the project's own.
+	merged.Audit.Sign = merged.Audit.Sign || global.Audit.Sign
+	merged.Audit.Reads = merged.Audit.Reads || global.Audit.Reads
+	if len(global.Audit.Sinks) > 0 {
+		merged.Audit.Sinks = append(append([]AuditSinkConfig(nil), global.Audit.Sinks...), merged.Audit.Sinks...)
+	}
+
+	// Offline: last-known-good values are disabled if either config
+	// disables them.
+	merged.Offline.Disabled = merged.Offline.Disabled || global.Offline.Disabled
+
+	// Memory: locking is enabled if either config enables it.
+	merged.Memory.Lock = merged.Memory.Lock || global.Memory.Lock
+
+	// Encryption: the key is inherited unless the project sets it.
+	if merged.Encryption.Key == "" {
+		merged.Encryption.Key = global.Encryption.Key
+	}
+
+	// Strength: each threshold is inherited unless the project sets it,
+	// and both deny-lists apply.
+	if merged.Strength.MinLength == 0 {
+		merged.Strength.MinLength = global.Strength.MinLength
+	}
+	if merged.Strength.MinEntropy == 0 {
+		merged.Strength.MinEntropy = global.Strength.MinEntropy
+	}
+	if len(global.Strength.Deny) > 0 {
+		merged.Strength.Deny = append(append([]string(nil), global.Strength.Deny...), merged.Strength.Deny...)
+	}
+
+	// Kubernetes: each field is inherited unless the project sets it.
+	if merged.Kubernetes.Context == "" {
+		merged.Kubernetes.Context = global.Kubernetes.Context
+	}
+	if merged.Kubernetes.Namespace == "" {
+		merged.Kubernetes.Namespace = global.Kubernetes.Namespace
+	}
+	if merged.Kubernetes.Secret == "" {
+		merged.Kubernetes.Secret = global.Kubernetes.Secret
+	}
+	if len(merged.Kubernetes.Labels) == 0 && len(global.Kubernetes.Labels) > 0 {
+		merged.Kubernetes.Labels = append([]string(nil), global.Kubernetes.Labels...)
+	}
+
+	// GitHub: the secrets and environments are inherited unless the
+	// project lists its own. The repo is not, so that a global repo never
+	// overrides the project's git remote.
+	if len(merged.GitHub.Secrets) == 0 && len(global.GitHub.Secrets) > 0 {
+		merged.GitHub.Secrets = append([]string(nil), global.GitHub.Secrets...)
+	}
+	if len(merged.GitHub.Environments) == 0 && len(global.GitHub.Environments) > 0 {
+		merged.GitHub.Environments = make([]GitHubEnvironment, len(global.GitHub.Environments))
+		copy(merged.GitHub.Environments, global.GitHub.Environments)
+	}
+
+	// RequireRefs: global patterns always apply; a project can add its own.
+	if len(global.RequireRefs) > 0 {
+		merged.RequireRefs = append(append([]string(nil), global.RequireRefs...), merged.RequireRefs...)
+	}
+
 	// Team: project replaces entirely if present, otherwise inherit global.
 	if len(merged.Team) == 0 && len(global.Team) > 0 {
 		merged.Team = make([]TeamMember, len(global.Team))
@@ -167,6 +330,11 @@ func mergeConfigs(global, project *Config) *Config {
 
 // Config represents the complete .envref.yaml configuration.
 type Config struct {
+	// Extends is the path to a base config file whose values this file
+	// inherits, merged with the same rules as global and project config.
+	// Relative paths are resolved against the directory of this file.
+	Extends string `mapstructure:"extends" yaml:"extends"`
+
 	// Project is the project name, used as a namespace for secrets.
 	Project string `mapstructure:"project" yaml:"project"`
 
@@ -176,22 +344,135 @@ type Config struct {
 	// LocalFile is the path to the local override file (default ".env.local").
 	LocalFile string `mapstructure:"local_file" yaml:"local_file"`
 
+	// EnvFiles optionally replaces the fixed env_file ← profile ← local_file
+	// layering with an explicit list, lowest precedence first. An entry
+	// containing "{profile}" is the profile layer: it is skipped when no
+	// profile is active and otherwise names the profile's env file.
+	EnvFiles []string `mapstructure:"env_files" yaml:"env_files"`
+
+	// Layering selects how the env layers follow the profile. With
+	// LayeringCRA, the layers follow the mode of Create React App and
+	// Next.js when no profile is active (see EnvLayers).
+	Layering string `mapstructure:"layering" yaml:"layering"`
+
+	// InterpolateSystemEnv lets ${VAR} in env files read the environment
+	// of the envref process for variables that no env file defines.
+	InterpolateSystemEnv bool `mapstructure:"interpolate_system_env" yaml:"interpolate_system_env"`
+
 	// ActiveProfile is the name of the currently active profile (e.g., "staging").
 	// When set, the resolve pipeline loads .env ← .env.<profile> ← .env.local.
 	// Can be overridden at runtime with the --profile flag.
 	ActiveProfile string `mapstructure:"active_profile" yaml:"active_profile"`
 
+	// BranchProfiles maps git branch names to the profile to use on them,
+	// taking precedence over ActiveProfile (see BranchProfile). The
+	// --profile flag and ENVREF_PROFILE still win.
+	BranchProfiles map[string]string `mapstructure:"branch_profiles" yaml:"branch_profiles"`
+
+	// branch selects the profile from the checked out branch; set by Load
+	// when BranchProfiles applies.
+	branch *branchLookup
+
 	// Backends defines the ordered list of secret backends to try when
 	// resolving ref:// references. Backends are tried in order; the first
 	// one that returns a value wins.
 	Backends []BackendConfig `mapstructure:"backends" yaml:"backends"`
 
+	// Aliases maps a ref:// backend name to an explicit, ordered list of
+	// backends. With "secrets: [vault, keychain]", ref://secrets/key tries
+	// vault and then keychain, instead of every backend in Backends order.
+	Aliases map[string][]string `mapstructure:"aliases" yaml:"aliases"`
+
 	// Profiles defines named environment profiles (e.g., development, staging).
 	Profiles map[string]ProfileConfig `mapstructure:"profiles" yaml:"profiles"`
 
 	// Team defines team members with their age public keys for secret sharing.
 	// Each member has a name (identifier) and an age X25519 public key.
 	Team []TeamMember `mapstructure:"team" yaml:"team"`
+
+	// RefSchemes maps additional URI schemes to the backend they resolve
+	// through (empty for "same as ref://"), e.g. {"secret": "", "op":
+	// "1password"}. See ref.Schemes.
+	RefSchemes map[string]string `mapstructure:"ref_schemes" yaml:"ref_schemes"`
+
+	// Hooks declares shell commands to run around reference resolution.
+	Hooks HooksConfig `mapstructure:"hooks" yaml:"hooks"`
+
+	// Prefix renames keys in the output of "envref resolve", for
+	// frameworks that only expose variables under a namespace.
+	Prefix PrefixConfig `mapstructure:"prefix" yaml:"prefix"`
+
+	// Remote configures the env files that env_file and env_files load
+	// from http:// and https:// URLs.
+	Remote RemoteConfig `mapstructure:"remote" yaml:"remote"`
+
+	// Offline configures the last-known-good values that resolution falls
+	// back to when a backend cannot be reached.
+	Offline OfflineConfig `mapstructure:"offline" yaml:"offline"`
+
+	// Audit configures the secret operations audit log.
+	Audit AuditConfig `mapstructure:"audit" yaml:"audit"`
+
+	// Memory configures how secret values are held in process memory.
+	Memory MemoryConfig `mapstructure:"memory" yaml:"memory"`
+
+	// Encryption configures enc:// values, secrets encrypted inline in env
+	// files with a project key kept in a backend.
+	Encryption EncryptionConfig `mapstructure:"encryption" yaml:"encryption"`
+
+	// Strength is the policy that "secret set" checks new secret values
+	// against.
+	Strength StrengthConfig `mapstructure:"strength" yaml:"strength"`
+
+	// RequireRefs lists key patterns (e.g., "*_TOKEN") whose values in
+	// committed env files must be ref:// references rather than
+	// plaintext. See RequiresRef.
+	RequireRefs []string `mapstructure:"require_refs" yaml:"require_refs"`
+
+	// Workspace makes this config the root of a monorepo workspace whose
+	// member projects can be managed together with "envref ws". It is
+	// never inherited from the global config or through extends.
+	Workspace WorkspaceConfig `mapstructure:"workspace" yaml:"workspace"`
+
+	// Compose declares the Docker Compose services that "envref compose"
+	// writes env files for. Like Workspace, it is never inherited from the
+	// global config or through extends.
+	Compose ComposeConfig `mapstructure:"compose" yaml:"compose"`
+
+	// Kubernetes configures the Secret that "envref k8s sync" writes.
+	Kubernetes KubernetesConfig `mapstructure:"kubernetes" yaml:"kubernetes"`
+
+	// GitHub maps keys to the GitHub Actions secrets that "envref gh sync"
+	// writes. Its secrets and environments are inherited from the global
+	// config and through extends; its repo never is.
+	GitHub GitHubConfig `mapstructure:"github" yaml:"github"`
+
+	// OS holds per-operating-system overrides keyed by GOOS name (e.g.,
+	// "darwin", "windows"). The section for the running system is merged
+	// over the rest of the file when it is loaded.
+	OS map[string]Config `mapstructure:"os" yaml:"os"`
+
+	// Schema declares validation rules per key: whether it is required, its
+	// type, and whether it must be a ref:// reference. It is read separately
+	// from the rest of the file because Viper lowercases map keys.
+	Schema map[string]schema.Rule `mapstructure:"-" yaml:"schema"`
+
+	// Rotation maps secret key patterns to the interval after which the
+	// secret is due for rotation (e.g., {"API_*": "90d"}). Like Schema, it
+	// is read separately to preserve the case of key names. See
+	// RotationPolicy.
+	Rotation map[string]string `mapstructure:"-" yaml:"rotation"`
+
+	// KeyAliases maps a key to the deprecated names it was renamed from
+	// (e.g., {"DATABASE_URL": ["DB_URL"]}), so that consumers still reading
+	// an old name keep working during a rename. Like Schema, it is read
+	// separately to preserve the case of key names. See DeprecatedKey.
+	KeyAliases map[string][]string `mapstructure:"-" yaml:"key_aliases"`
+
+	// ACL maps secret key patterns to the team members and roles allowed
+	// to read and write them (e.g., {"PROD_*": ["admin"]}). Like Schema, it
+	// is read separately to preserve the case of key names. See ACLRule.
+	ACL map[string][]string `mapstructure:"-" yaml:"acl"`
 }
 
 // BackendConfig describes a single secret backend.
@@ -203,8 +484,315 @@ type BackendConfig struct {
 	// "1password", "aws-ssm"). If empty, defaults to the value of Name.
 	Type string `mapstructure:"type" yaml:"type"`
 
+	// Namespace is the key template used to scope secrets in this backend,
+	// using the {project}, {profile}, and {key} placeholders (e.g.,
+	// "{project}-{key}"). If empty, keys are stored as "<project>/<key>" or
+	// "<project>/<profile>/<key>".
+	Namespace string `mapstructure:"namespace" yaml:"namespace"`
+
 	// Config holds backend-specific configuration key-value pairs.
 	Config map[string]string `mapstructure:"config" yaml:"config"`
+
+	// Middleware lists decorators applied around the backend, outermost
+	// first (e.g., a cache in front of a rate limiter).
+	Middleware []MiddlewareConfig `mapstructure:"middleware" yaml:"middleware"`
+
+	// Encrypted lists the Config keys whose values are tagged !encrypted in
+	// the file and still hold ciphertext. See Decrypt.
+	Encrypted []string `mapstructure:"-" yaml:"-"`
+}
+
+// MiddlewareConfig configures one decorator applied around a backend.
+type MiddlewareConfig struct {
+	// Type is the middleware type; see KnownMiddlewareTypes.
+	Type string `mapstructure:"type" yaml:"type"`
+
+	// TTL is how long the "cache" middleware keeps values, as a Go
+	// duration (e.g., "5m"). Defaults to DefaultCacheTTL.
+	TTL string `mapstructure:"ttl" yaml:"ttl"`
+
+	// Rate is the maximum number of backend calls per second for the
+	// "rate-limit" middleware.
+	Rate float64 `mapstructure:"rate" yaml:"rate"`
+}
+
+// KnownMiddlewareTypes lists the middleware types that can be configured
+// on a backend.
+var KnownMiddlewareTypes = []string{"logging", "metrics", "cache", "rate-limit"}
+
+// DefaultCacheTTL is the TTL of the "cache" middleware when none is set.
+const DefaultCacheTTL = 5 * time.Minute
+
+// CacheTTL returns the parsed TTL of a "cache" middleware, or
+// DefaultCacheTTL if none is set.
+func (m MiddlewareConfig) CacheTTL() (time.Duration, error) {
+	if m.TTL == "" {
+		return DefaultCacheTTL, nil
+	}
+	ttl, err := time.ParseDuration(m.TTL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ttl %q: %w", m.TTL, err)
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("ttl must be positive, got %q", m.TTL)
+	}
+	return ttl, nil
+}
+
+// validate returns the problems with the middleware entry.
+func (m MiddlewareConfig) validate() []string {
+	switch m.Type {
+	case "logging", "metrics":
+	case "cache":
+		if _, err := m.CacheTTL(); err != nil {
+			return []string{err.Error()}
+		}
+	case "rate-limit":
+		if m.Rate <= 0 {
+			return []string{"rate must be a positive number of calls per second"}
+		}
+	case "":
+		return []string{"type is required"}
+	default:
+		return []string{fmt.Sprintf("unknown middleware type %q (known types: %s)", m.Type, strings.Join(KnownMiddlewareTypes, ", "))}
+	}
+	return nil
+}
+
+// HooksConfig declares shell commands run before and after resolution.
+// Commands run through the system shell with the project root as working
+// directory.
+type HooksConfig struct {
+	// PreResolve runs before any secret is resolved (e.g., "vault login").
+	// It sees only the caller's environment, never resolved values.
+	PreResolve string `mapstructure:"pre_resolve" yaml:"pre_resolve"`
+
+	// PostResolve runs after resolution, with the resolved
+	// variables added to its environment (e.g., to notify a dev proxy).
+	PostResolve string `mapstructure:"post_resolve" yaml:"post_resolve"`
+}
+
+// PrefixConfig renames output keys: Strip is removed from the keys that
+// start with it, then Add is prepended to every key. With Strip "APP_" and
+// Add "VITE_", APP_API_URL is output as VITE_API_URL.
+type PrefixConfig struct {
+	// Add is prepended to every key (e.g., "VITE_", "REACT_APP_").
+	Add string `mapstructure:"add" yaml:"add"`
+
+	// Strip is removed from the keys that start with it.
+	Strip string `mapstructure:"strip" yaml:"strip"`
+}
+
+// RemoteConfig configures remote env files. A remote file can be pinned to
+// its SHA-256 digest with a "#sha256=<hex>" URL fragment.
+type RemoteConfig struct {
+	// CacheTTL is how long a downloaded file is used before it is fetched
+	// again, as a Go duration (e.g., "1h"). Defaults to
+	// DefaultRemoteCacheTTL. The cached copy is also used when the server
+	// cannot be reached.
+	CacheTTL string `mapstructure:"cache_ttl" yaml:"cache_ttl"`
+
+	// PublicKeys lists base64-encoded Ed25519 public keys. When set, every
+	// remote file must be signed by one of them, with the signature served
+	// at the file's URL followed by ".sig".
+	PublicKeys []string `mapstructure:"public_keys" yaml:"public_keys"`
+}
+
+// DefaultRemoteCacheTTL is how long remote env files are cached when no
+// cache_ttl is set.
+const DefaultRemoteCacheTTL = time.Hour
+
+// TTL returns the parsed cache TTL, or DefaultRemoteCacheTTL if none is
+// set.
+func (r RemoteConfig) TTL() (time.Duration, error) {
+	if r.CacheTTL == "" {
+		return DefaultRemoteCacheTTL, nil
+	}
+	ttl, err := time.ParseDuration(r.CacheTTL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cache_ttl %q: %w", r.CacheTTL, err)
+	}
+	if ttl < 0 {
+		return 0, fmt.Errorf("cache_ttl must not be negative, got %q", r.CacheTTL)
+	}
+	return ttl, nil
+}
+
+// Fetcher returns a fetcher for remote env files with these settings,
+// caching in remote.DefaultCacheDir. warn, which may be nil, is called
+// when a stale copy is used.
+func (r RemoteConfig) Fetcher(warn func(format string, args ...any)) (*remote.Fetcher, error) {
+	ttl, err := r.TTL()
+	if err != nil {
+		return nil, fmt.Errorf("remote: %w", err)
+	}
+	keys := make([]ed25519.PublicKey, 0, len(r.PublicKeys))
+	for _, s := range r.PublicKeys {
+		key, err := remote.ParsePublicKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("remote.public_keys: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	// Without a cache directory every load downloads the file.
+	cacheDir, _ := remote.DefaultCacheDir()
+	return &remote.Fetcher{
+		CacheDir:   cacheDir,
+		TTL:        ttl,
+		PublicKeys: keys,
+		Warn:       warn,
+	}, nil
+}
+
+// validate returns the problems with the remote settings.
+func (r RemoteConfig) validate() []string {
+	var errs []string
+	if _, err := r.TTL(); err != nil {
+		errs = append(errs, "remote: "+err.Error())
+	}
+	for i, key := range r.PublicKeys {
+		if _, err := remote.ParsePublicKey(key); err != nil {
+			errs = append(errs, fmt.Sprintf("remote.public_keys[%d]: %v", i, err))
+		}
+	}
+	return errs
+}
+
+// OfflineConfig configures the last-known-good values of secrets. Each
+// value resolved is kept in an encrypted file in the user's cache
+// directory, under a key held in the OS keychain, and served when its
+// backend cannot be reached or with "resolve --offline".
+type OfflineConfig struct {
+	// Disabled stops envref from keeping last-known-good values, for
+	// projects whose secrets must never be written to disk. Unreachable
+	// backends then fail resolution.
+	Disabled bool `mapstructure:"disabled" yaml:"disabled"`
+}
+
+// AuditConfig configures the .envref.audit.log file.
+type AuditConfig struct {
+	// Sign HMAC-signs every audit entry with a per-project key kept in the
+	// OS keychain, so that "envref audit verify" can detect a rewritten
+	// log on machines that hold the key.
+	Sign bool `mapstructure:"sign" yaml:"sign"`
+
+	// Reads also logs secret reads: "envref secret get" and the references
+	// fetched when resolving an environment. Read entries record the key
+	// and backend, never the value. Reads are not logged by default, as
+	// every resolve would add entries.
+	Reads bool `mapstructure:"reads" yaml:"reads"`
+
+	// Sinks lists remote destinations that receive a copy of every entry
+	// in addition to the local file. Sinks from the global config always
+	// apply; a project can add its own.
+	Sinks []AuditSinkConfig `mapstructure:"sinks" yaml:"sinks"`
+}
+
+// MemoryConfig configures the handling of secret values in memory.
+type MemoryConfig struct {
+	// Lock pins passphrases, derived keys, and other sensitive buffers in
+	// physical memory with mlock(2), so that they are never written to
+	// swap. Locking is best-effort and has no effect on Windows.
+	Lock bool `mapstructure:"lock" yaml:"lock"`
+}
+
+// EncryptionConfig configures enc:// values: small secrets kept encrypted
+// in committed env files and decrypted during resolution with a project
+// key. Only the key needs to be shared through a backend.
+type EncryptionConfig struct {
+	// Key is the ref:// URI of the secret used as the encryption key
+	// (e.g., ref://keychain/envref_encryption_key). enc:// values are left
+	// as is when it is not set.
+	Key string `mapstructure:"key" yaml:"key"`
+}
+
+// validate returns the problems with the encryption settings.
+func (e EncryptionConfig) validate() []string {
+	if e.Key == "" {
+		return nil
+	}
+	parsed, err := ref.Parse(e.Key)
+	switch {
+	case err != nil:
+		return []string{"encryption.key: " + err.Error()}
+	case parsed.Backend == ref.SelfBackend:
+		return []string{"encryption.key: must name a secret in a backend, not ref://self"}
+	case parsed.Encoding != "":
+		return []string{"encryption.key: must not have an encoding"}
+	}
+	return nil
+}
+
+// StrengthConfig is a policy for new secret values. Checks are off unless
+// at least one field is set.
+type StrengthConfig struct {
+	// MinLength is the minimum length of a value, in characters.
+	MinLength int `mapstructure:"min_length" yaml:"min_length"`
+
+	// MinEntropy is the minimum estimated entropy of a whole value, in
+	// bits: its length times the Shannon entropy per character.
+	MinEntropy float64 `mapstructure:"min_entropy" yaml:"min_entropy"`
+
+	// Deny lists values that are always rejected, compared
+	// case-insensitively, in addition to envref's built-in list of
+	// placeholders and commonly leaked passwords.
+	Deny []string `mapstructure:"deny" yaml:"deny"`
+}
+
+// Enabled reports whether any strength check is configured.
+func (s StrengthConfig) Enabled() bool {
+	return s.MinLength > 0 || s.MinEntropy > 0 || len(s.Deny) > 0
+}
+
+// AuditSinkConfig describes one remote audit destination.
+type AuditSinkConfig struct {
+	// Type is the sink type; see KnownAuditSinkTypes.
+	Type string `mapstructure:"type" yaml:"type"`
+
+	// Address is the syslog server as "udp://host:port", "tcp://host:port",
+	// or "unix:///path/to/socket". If empty, the local syslog daemon is used.
+	Address string `mapstructure:"address" yaml:"address"`
+
+	// URL is the webhook URL, or the OTLP/HTTP logs endpoint for "otlp"
+	// (default: from OTEL_EXPORTER_OTLP_LOGS_ENDPOINT or
+	// OTEL_EXPORTER_OTLP_ENDPOINT, else http://localhost:4318/v1/logs).
+	URL string `mapstructure:"url" yaml:"url"`
+
+	// Headers are extra HTTP headers for "webhook" and "otlp" sinks, e.g.
+	// an Authorization token.
+	Headers map[string]string `mapstructure:"headers" yaml:"headers"`
+}
+
+// KnownAuditSinkTypes lists the audit sink types that can be configured.
+var KnownAuditSinkTypes = []string{"syslog", "webhook", "otlp"}
+
+// validate returns the problems with the sink entry.
+func (s AuditSinkConfig) validate() []string {
+	switch s.Type {
+	case "syslog":
+		if s.Address == "" {
+			return nil
+		}
+		scheme, rest, ok := strings.Cut(s.Address, "://")
+		if !ok || rest == "" || (scheme != "udp" && scheme != "tcp" && scheme != "unix") {
+			return []string{fmt.Sprintf("invalid syslog address %q (use udp://host:port, tcp://host:port, or unix:///path)", s.Address)}
+		}
+	case "webhook":
+		if s.URL == "" {
+			return []string{"url is required"}
+		}
+		fallthrough
+	case "otlp":
+		if s.URL != "" && !strings.HasPrefix(s.URL, "http://") && !strings.HasPrefix(s.URL, "https://") {
+			return []string{fmt.Sprintf("url %q must be an http:// or https:// URL", s.URL)}
+		}
+	case "":
+		return []string{"type is required"}
+	default:
+		return []string{fmt.Sprintf("unknown audit sink type %q (known types: %s)", s.Type, strings.Join(KnownAuditSinkTypes, ", "))}
+	}
+	return nil
 }
 
 // ProfileConfig describes a named environment profile.
@@ -212,6 +800,11 @@ type ProfileConfig struct {
 	// EnvFile is the path to the profile-specific .env file
 	// (e.g., ".env.staging"). If empty, defaults to ".env.<profile-name>".
 	EnvFile string `mapstructure:"env_file" yaml:"env_file"`
+
+	// Inherits names another profile this one builds on. Its env file and
+	// profile-scoped secrets are layered below this profile's, so only the
+	// differences need to be declared.
+	Inherits string `mapstructure:"inherits" yaml:"inherits,omitempty"`
 }
 
 // TeamMember represents a team member with an age public key for secret sharing.
@@ -221,6 +814,15 @@ type TeamMember struct {
 
 	// PublicKey is the member's age X25519 public key (age1...).
 	PublicKey string `mapstructure:"public_key" yaml:"public_key"`
+
+	// Roles name the groups the member belongs to, for acl entries
+	// (e.g., "admin", "contributor").
+	Roles []string `mapstructure:"roles" yaml:"roles,omitempty"`
+
+	// Identities are the identities the member is known by to backends
+	// (e.g., an IAM ARN, a Vault token display name, or a 1Password email)
+	// and to the OS ("alice" or "alice@laptop"). See TeamMemberByIdentity.
+	Identities []string `mapstructure:"identities" yaml:"identities,omitempty"`
 }
 
 // TeamMemberByName returns the team member with the given name, or nil if not found.
@@ -269,6 +871,131 @@ func (c *Config) ProfileEnvFile(profile string) string {
 	return ".env." + profile
 }
 
+// ProfileSeparator separates the profiles of a combined profile such as
+// "production,eu", which layers profiles of orthogonal dimensions (here
+// stage and region) in the order given.
+const ProfileSeparator = ","
+
+// SplitProfiles returns the profiles combined in profile (see
+// ProfileSeparator), ignoring blanks around them and empty ones.
+func SplitProfiles(profile string) []string {
+	var parts []string
+	for _, p := range strings.Split(profile, ProfileSeparator) {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// ProfileChain returns profile followed by the profiles it inherits from,
+// nearest first: with staging inheriting from base, the chain of staging is
+// [staging base]. It stops at a profile already in the chain, so an
+// inheritance cycle (reported by Validate) cannot loop. An empty profile
+// has an empty chain.
+//
+// A combined profile chains its parts from the last, which wins, to the
+// first: the chain of "production,eu" is [eu production]. A profile that
+// several parts inherit from is kept at its lowest precedence, below all
+// of them.
+func (c *Config) ProfileChain(profile string) []string {
+	parts := SplitProfiles(profile)
+	var all []string
+	for i := len(parts) - 1; i >= 0; i-- {
+		var chain []string
+		for p := parts[i]; p != "" && !slices.Contains(chain, p); p = c.Profiles[p].Inherits {
+			chain = append(chain, p)
+		}
+		all = append(all, chain...)
+	}
+
+	var chain []string
+	for i, p := range all {
+		if !slices.Contains(all[i+1:], p) {
+			chain = append(chain, p)
+		}
+	}
+	return chain
+}
+
+// ProfileLocalFile returns the local override file of a profile, its env
+// file with a ".local" suffix (e.g., ".env.staging.local"). Like LocalFile,
+// it is meant to stay out of version control.
+func (c *Config) ProfileLocalFile(profile string) string {
+	return c.ProfileEnvFile(profile) + ".local"
+}
+
+// LayeringCRA is the Layering of Create React App and Next.js projects:
+// without a profile, the env files of the mode named by NODE_ENV, or
+// development, are loaded (.env.development and .env.development.local),
+// and in the test mode .env.local is not, so that tests give everyone the
+// same results.
+const LayeringCRA = "cra"
+
+// craTestMode is the mode in which LayeringCRA skips LocalFile.
+const craTestMode = "test"
+
+// CRAMode returns the mode of LayeringCRA: NODE_ENV, or development when
+// it is not set.
+func CRAMode() string {
+	if mode := os.Getenv("NODE_ENV"); mode != "" {
+		return mode
+	}
+	return "development"
+}
+
+// EnvLayers returns the env files to load for profile, lowest precedence
+// first. Without EnvFiles this is EnvFile, the env files of the profile
+// chain (see ProfileChain) from the furthest ancestor to profile itself,
+// LocalFile, and the local files of the chain (see ProfileLocalFile) in
+// the same order, as in Create React App. With EnvFiles, each "{profile}"
+// entry is replaced by those of the chain, each the profile's custom
+// env_file if it defines one, or the entry with the profile name
+// substituted, and dropped when profile is empty.
+//
+// With LayeringCRA, an empty profile is the mode of CRAMode, and LocalFile
+// is skipped in the test mode.
+func (c *Config) EnvLayers(profile string) []string {
+	if profile == "" && c.Layering == LayeringCRA {
+		profile = CRAMode()
+	}
+	chain := c.ProfileChain(profile)
+	slices.Reverse(chain)
+
+	if len(c.EnvFiles) == 0 {
+		layers := []string{c.EnvFile}
+		for _, name := range chain {
+			layers = append(layers, c.ProfileEnvFile(name))
+		}
+		if c.Layering != LayeringCRA || profile != craTestMode {
+			layers = append(layers, c.LocalFile)
+		}
+		for _, name := range chain {
+			// A profile env file served from a URL has no local file.
+			if local := c.ProfileLocalFile(name); !remote.IsURL(local) {
+				layers = append(layers, local)
+			}
+		}
+		return layers
+	}
+
+	layers := make([]string, 0, len(c.EnvFiles)+len(chain))
+	for _, f := range c.EnvFiles {
+		if !strings.Contains(f, "{profile}") {
+			layers = append(layers, f)
+			continue
+		}
+		for _, name := range chain {
+			if p, ok := c.Profiles[name]; ok && p.EnvFile != "" {
+				layers = append(layers, p.EnvFile)
+			} else {
+				layers = append(layers, strings.ReplaceAll(f, "{profile}", name))
+			}
+		}
+	}
+	return layers
+}
+
 // HasProfile reports whether the given profile name is defined in the
 // Profiles map. An empty profile name always returns false.
 func (c *Config) HasProfile(profile string) bool {
@@ -280,12 +1007,16 @@ func (c *Config) HasProfile(profile string) bool {
 }
 
 // EffectiveProfile returns the profile to use, preferring the override
-// (e.g., from --profile flag) over the config's ActiveProfile.
+// (e.g., from --profile flag), then the profile BranchProfiles selects
+// for the checked out branch, then the config's ActiveProfile.
 // Returns empty string if no profile is active.
 func (c *Config) EffectiveProfile(override string) string {
 	if override != "" {
 		return override
 	}
+	if profile, ok := c.branchProfile(); ok {
+		return profile
+	}
 	return c.ActiveProfile
 }
 
@@ -294,9 +1025,11 @@ func (c *Config) EffectiveProfile(override string) string {
 func (c *Config) Validate() error {
 	var errs []string
 
-	// Project name checks.
+	// Project name checks. A workspace root need not be a project itself.
 	if c.Project == "" {
-		errs = append(errs, "project name is required")
+		if len(c.Workspace.Members) == 0 {
+			errs = append(errs, "project name is required")
+		}
 	} else if strings.TrimSpace(c.Project) != c.Project {
 		errs = append(errs, "project name must not have leading or trailing whitespace")
 	} else if strings.ContainsAny(c.Project, "/\\") {
@@ -306,6 +1039,10 @@ func (c *Config) Validate() error {
 	// File path checks.
 	if c.EnvFile == "" {
 		errs = append(errs, "env_file must not be empty")
+	} else if remote.IsURL(c.EnvFile) {
+		if _, err := remote.Parse(c.EnvFile); err != nil {
+			errs = append(errs, "env_file: "+err.Error())
+		}
 	} else if filepath.IsAbs(c.EnvFile) {
 		errs = append(errs, "env_file must be a relative path, got absolute path")
 	}
@@ -316,6 +1053,28 @@ func (c *Config) Validate() error {
 		errs = append(errs, "local_file must be a relative path, got absolute path")
 	}
 
+	seenEnvFiles := make(map[string]bool, len(c.EnvFiles))
+	for i, f := range c.EnvFiles {
+		switch {
+		case f == "":
+			errs = append(errs, fmt.Sprintf("env_files[%d]: must not be empty", i))
+		case remote.IsURL(f):
+			if _, err := remote.Parse(f); err != nil {
+				errs = append(errs, fmt.Sprintf("env_files[%d]: %v", i, err))
+			} else if seenEnvFiles[f] {
+				errs = append(errs, fmt.Sprintf("env_files[%d]: duplicate file %q", i, f))
+			}
+		case filepath.IsAbs(f):
+			errs = append(errs, fmt.Sprintf("env_files[%d]: must be a relative path, got absolute path", i))
+		case seenEnvFiles[f]:
+			errs = append(errs, fmt.Sprintf("env_files[%d]: duplicate file %q", i, f))
+		}
+		seenEnvFiles[f] = true
+	}
+	if c.Layering != "" && c.Layering != LayeringCRA {
+		errs = append(errs, fmt.Sprintf("layering: unknown layering %q (supported: %s)", c.Layering, LayeringCRA))
+	}
+
 	// Validate backends.
 	seenBackends := make(map[string]bool)
 	for i, b := range c.Backends {
@@ -323,10 +1082,105 @@ func (c *Config) Validate() error {
 			errs = append(errs, fmt.Sprintf("backends[%d]: name is required", i))
 			continue
 		}
+		if b.Name == ref.SelfBackend {
+			errs = append(errs, fmt.Sprintf("backends[%d]: name %q is reserved for references to other variables", i, b.Name))
+		}
 		if seenBackends[b.Name] {
 			errs = append(errs, fmt.Sprintf("backends[%d]: duplicate backend name %q", i, b.Name))
 		}
 		seenBackends[b.Name] = true
+		if b.Namespace != "" {
+			if err := backend.ValidateNamespace(b.Namespace); err != nil {
+				errs = append(errs, fmt.Sprintf("backends[%d]: %v", i, err))
+			}
+		}
+		for j, m := range b.Middleware {
+			for _, problem := range m.validate() {
+				errs = append(errs, fmt.Sprintf("backends[%d].middleware[%d]: %s", i, j, problem))
+			}
+		}
+	}
+
+	errs = append(errs, c.Workspace.validate()...)
+	errs = append(errs, c.Compose.validate()...)
+	errs = append(errs, c.Kubernetes.validate()...)
+	errs = append(errs, c.GitHub.validate()...)
+	errs = append(errs, c.validateOS()...)
+
+	// Validate aliases.
+	for _, name := range sortedAliasNames(c.Aliases) {
+		targets := c.Aliases[name]
+		if name == "" {
+			errs = append(errs, "aliases: empty alias name is not allowed")
+			continue
+		}
+		if name == ref.SelfBackend {
+			errs = append(errs, fmt.Sprintf("aliases: alias name %q is reserved for references to other variables", name))
+		}
+		if seenBackends[name] {
+			errs = append(errs, fmt.Sprintf("aliases: alias %q shadows a backend of the same name", name))
+		}
+		if len(targets) == 0 {
+			errs = append(errs, fmt.Sprintf("aliases: alias %q must list at least one backend", name))
+		}
+		for _, target := range targets {
+			if !seenBackends[target] {
+				errs = append(errs, fmt.Sprintf("aliases: alias %q references unknown backend %q%s", name, target, suggestBackend(c.Backends, target)))
+			}
+		}
+	}
+
+	// Validate ref schemes.
+	schemeNames := make([]string, 0, len(c.RefSchemes))
+	for name := range c.RefSchemes {
+		schemeNames = append(schemeNames, name)
+	}
+	sort.Strings(schemeNames)
+	for _, name := range schemeNames {
+		target := c.RefSchemes[name]
+		if !schemeNamePattern.MatchString(name) {
+			errs = append(errs, fmt.Sprintf("ref_schemes: invalid scheme name %q", name))
+		} else if name == "ref" {
+			errs = append(errs, "ref_schemes: \"ref\" is always recognized and cannot be remapped")
+		}
+		if _, isAlias := c.Aliases[target]; target != "" && !seenBackends[target] && !isAlias {
+			errs = append(errs, fmt.Sprintf("ref_schemes: scheme %q references unknown backend %q%s", name, target, suggestBackend(c.Backends, target)))
+		}
+	}
+
+	// Validate schema rules.
+	if _, err := schema.New(c.Schema); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	errs = append(errs, c.validateRotation()...)
+	errs = append(errs, c.validateACL()...)
+	errs = append(errs, c.validateKeyAliases()...)
+
+	// Validate the output prefixes.
+	if c.Prefix.Add != "" && !keyPrefixPattern.MatchString(c.Prefix.Add) {
+		errs = append(errs, fmt.Sprintf("prefix.add: %q is not a valid variable name prefix", c.Prefix.Add))
+	}
+	if c.Prefix.Strip != "" && !keyPrefixPattern.MatchString(c.Prefix.Strip) {
+		errs = append(errs, fmt.Sprintf("prefix.strip: %q is not a valid variable name prefix", c.Prefix.Strip))
+	}
+	errs = append(errs, c.Remote.validate()...)
+	errs = append(errs, c.Encryption.validate()...)
+	errs = append(errs, c.validateRequireRefs()...)
+
+	// Validate audit sinks.
+	for i, sink := range c.Audit.Sinks {
+		for _, problem := range sink.validate() {
+			errs = append(errs, fmt.Sprintf("audit.sinks[%d]: %s", i, problem))
+		}
+	}
+
+	// Validate the strength policy.
+	if c.Strength.MinLength < 0 {
+		errs = append(errs, "strength.min_length must not be negative")
+	}
+	if c.Strength.MinEntropy < 0 {
+		errs = append(errs, "strength.min_entropy must not be negative")
 	}
 
 	// Validate profiles.
@@ -335,13 +1189,43 @@ func (c *Config) Validate() error {
 			errs = append(errs, "profiles: empty profile name is not allowed")
 		} else if strings.TrimSpace(name) != name {
 			errs = append(errs, fmt.Sprintf("profiles: profile name %q must not have leading or trailing whitespace", name))
+		} else if strings.Contains(name, ProfileSeparator) {
+			errs = append(errs, fmt.Sprintf("profiles: profile name %q must not contain %q, which combines profiles", name, ProfileSeparator))
+		}
+	}
+
+	// Validate profile inheritance has no cycles. A parent need not be in
+	// profiles: like any profile, it may be found by its .env.<name> file.
+	for _, name := range sortedProfileNames(c.Profiles) {
+		if c.Profiles[name].Inherits == "" {
+			continue
+		}
+		chain := c.ProfileChain(name)
+		if last := c.Profiles[chain[len(chain)-1]].Inherits; slices.Contains(chain, last) {
+			errs = append(errs, fmt.Sprintf("profiles: profile %q inherits from itself (%s -> %s)", name, strings.Join(chain, " -> "), last))
+		}
+	}
+
+	// Validate branch_profiles patterns and the profiles they select.
+	for _, pattern := range sortedBranchPatterns(c.BranchProfiles) {
+		profile := c.BranchProfiles[pattern]
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("branch_profiles: invalid branch pattern %q", pattern))
+		}
+		if profile == "" {
+			errs = append(errs, fmt.Sprintf("branch_profiles: branch %q must name a profile", pattern))
+		} else if name, ok := c.undefinedProfile(profile); ok {
+			errs = append(errs, fmt.Sprintf("branch_profiles: branch %q selects profile %q, which is not defined in profiles%s", pattern, name, suggest.FormatSuggestion(suggest.Keys(name, sortedProfileNames(c.Profiles)))))
 		}
 	}
 
 	// Validate active_profile references an existing profile (if set and profiles are defined).
-	if c.ActiveProfile != "" && len(c.Profiles) > 0 {
-		if _, ok := c.Profiles[c.ActiveProfile]; !ok {
-			errs = append(errs, fmt.Sprintf("active_profile %q is not defined in profiles", c.ActiveProfile))
+	if name, ok := c.undefinedProfile(c.ActiveProfile); ok {
+		hint := suggest.FormatSuggestion(suggest.Keys(name, sortedProfileNames(c.Profiles)))
+		if name == c.ActiveProfile {
+			errs = append(errs, fmt.Sprintf("active_profile %q is not defined in profiles%s", name, hint))
+		} else {
+			errs = append(errs, fmt.Sprintf("active_profile %q: profile %q is not defined in profiles%s", c.ActiveProfile, name, hint))
 		}
 	}
 
@@ -376,6 +1260,58 @@ func (c *Config) Validate() error {
 	return &ValidationError{Problems: errs}
 }
 
+// schemeNamePattern matches a valid URI scheme name (RFC 3986), lowercase.
+var schemeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
+// keyPrefixPattern matches the prefixes that keep a variable name valid.
+var keyPrefixPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// sortedAliasNames returns the alias names in sorted order so validation
+// messages are deterministic.
+func sortedAliasNames(aliases map[string][]string) []string {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// undefinedProfile returns the first of the profiles combined in profile
+// that is not in Profiles, if any. Without profiles, any profile is
+// allowed, since convention-based profiles need no config.
+func (c *Config) undefinedProfile(profile string) (string, bool) {
+	if len(c.Profiles) == 0 {
+		return "", false
+	}
+	for _, p := range SplitProfiles(profile) {
+		if _, ok := c.Profiles[p]; !ok {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// sortedProfileNames returns the names of profiles in sorted order.
+func sortedProfileNames(profiles map[string]ProfileConfig) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// suggestBackend returns a "did you mean" hint naming the backends close
+// to name, or "" if there are none.
+func suggestBackend(backends []BackendConfig, name string) string {
+	names := make([]string, len(backends))
+	for i, b := range backends {
+		names[i] = b.Name
+	}
+	return suggest.FormatSuggestion(suggest.Keys(name, names))
+}
+
 // Warnings returns non-fatal issues with the config, such as unknown backend
 // types. Unlike Validate, these do not prevent the config from being used.
 func (c *Config) Warnings() []string {
@@ -387,6 +1323,9 @@ func (c *Config) Warnings() []string {
 				i, btype, strings.Join(KnownBackendTypes, ", ")))
 		}
 	}
+	if len(c.EnvFiles) > 0 && !containsString(c.EnvFiles, c.EnvFile) {
+		warnings = append(warnings, fmt.Sprintf("env_file %q is not listed in env_files and will not be loaded", c.EnvFile))
+	}
 	return warnings
 }
 
@@ -407,42 +1346,166 @@ func isKnownBackendType(t string) bool {
 //
 // If a global config file exists at ~/.config/envref/config.yaml (or the
 // path determined by GlobalConfigPath), it is loaded first as a base. The
-// project-level config then overrides global values.
+// project-level config, including any files it extends, then overrides
+// global values.
+//
+// Environment variable overrides (see EnvOverrides) are applied last, so
+// they take precedence over both config files.
 //
-// If no project-level config file is found, Load returns ErrNotFound.
+// If no project-level config file is found, Load returns ErrNotFound. Its
+// other errors are *LoadError.
 func Load(startDir string) (*Config, string, error) {
 	configDir, err := findConfigDir(startDir)
 	if err != nil {
 		return nil, "", err
 	}
 
-	projectCfg, err := loadFile(filepath.Join(configDir, FullFileName))
+	projectCfg, err := loadFileWithExtends(filepath.Join(configDir, FullFileName), nil)
 	if err != nil {
-		return nil, "", err
+		return nil, "", &LoadError{Err: err}
 	}
 
 	globalCfg, err := loadGlobalConfig()
 	if err != nil {
-		return nil, "", err
+		return nil, "", &LoadError{Err: err}
 	}
 
 	cfg := mergeConfigs(globalCfg, projectCfg)
+	applyBranchProfile(cfg, configDir)
+	applyEnvOverrides(cfg)
 
 	if err := cfg.Validate(); err != nil {
-		return nil, "", err
+		return nil, "", &LoadError{Err: err}
 	}
 
 	return cfg, configDir, nil
 }
 
-// LoadFile reads a config from a specific file path.
+// LoadError is returned when the config cannot be found, read, parsed, or
+// validated. Its message is that of Err, which errors.Is and errors.As see
+// through it.
+type LoadError struct {
+	Err error
+}
+
+// Error returns the message of the underlying error.
+func (e *LoadError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// Environment variables that override values from .envref.yaml. They let CI
+// pipelines redirect envref without editing committed configuration.
+const (
+	// EnvProject overrides the project name (and thus the secret namespace).
+	EnvProject = "ENVREF_PROJECT"
+
+	// EnvProfile overrides active_profile. The --profile flag still wins.
+	EnvProfile = "ENVREF_PROFILE"
+
+	// EnvEnvFile overrides env_file.
+	EnvEnvFile = "ENVREF_ENV_FILE"
+
+	// EnvBackend restricts resolution to a single backend. If it names a
+	// configured backend, only that backend is used; otherwise a backend
+	// of that name (and type) is used with no extra configuration.
+	EnvBackend = "ENVREF_BACKEND"
+)
+
+// EnvOverrides lists the environment variables that override config values,
+// in the order they are applied.
+var EnvOverrides = []string{EnvProject, EnvProfile, EnvEnvFile, EnvBackend}
+
+// applyEnvOverrides replaces config values with those set through the
+// ENVREF_* environment variables. Empty variables are ignored.
+func applyEnvOverrides(cfg *Config) {
+	if v := os.Getenv(EnvProject); v != "" {
+		cfg.Project = v
+	}
+	if v := os.Getenv(EnvProfile); v != "" {
+		cfg.ActiveProfile = v
+	}
+	if v := os.Getenv(EnvEnvFile); v != "" {
+		cfg.EnvFile = v
+	}
+	if v := os.Getenv(EnvBackend); v != "" {
+		selected := BackendConfig{Name: v}
+		for _, b := range cfg.Backends {
+			if b.Name == v {
+				selected = b
+				break
+			}
+		}
+		cfg.Backends = []BackendConfig{selected}
+		// Aliases can only point at the selected backend now.
+		for name := range cfg.Aliases {
+			cfg.Aliases[name] = []string{v}
+		}
+	}
+}
+
+// LoadFile reads a config from a specific file path, following extends.
+// Errors are *LoadError.
 func LoadFile(path string) (*Config, error) {
-	return loadFile(path)
+	cfg, err := loadFileWithExtends(path, nil)
+	if err != nil {
+		return nil, &LoadError{Err: err}
+	}
+	return cfg, nil
+}
+
+// loadFileWithExtends loads the config at path and, if it declares extends,
+// the chain of base files it inherits from, merged so that each file
+// overrides the one it extends. chain holds the files already visited and is
+// used to detect cycles.
+func loadFileWithExtends(path string, chain []string) (*Config, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolving config path %s: %w", path, err)
+	}
+	for _, visited := range chain {
+		if visited == abs {
+			return nil, fmt.Errorf("config extends cycle: %s -> %s", strings.Join(chain, " -> "), abs)
+		}
+	}
+
+	cfg, err := loadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Extends == "" {
+		return cfg, nil
+	}
+
+	basePath := cfg.Extends
+	if !filepath.IsAbs(basePath) {
+		basePath = filepath.Join(filepath.Dir(abs), basePath)
+	}
+	base, err := loadFileWithExtends(basePath, append(chain, abs))
+	if err != nil {
+		return nil, fmt.Errorf("loading %s (extended by %s): %w", cfg.Extends, abs, err)
+	}
+	return mergeConfigs(base, cfg), nil
 }
 
 // ErrNotFound is returned when no .envref.yaml is found in the directory tree.
 var ErrNotFound = errors.New("no .envref.yaml found")
 
+// FindFile returns the path of the nearest .envref.yaml, searching from
+// startDir upward. Unlike Load it does not parse or validate the file.
+// Returns ErrNotFound if none is found.
+func FindFile(startDir string) (string, error) {
+	dir, err := findConfigDir(startDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FullFileName), nil
+}
+
 // findConfigDir walks from startDir up to the filesystem root looking for
 // a .envref.yaml file. Returns the directory containing the file, or
 // ErrNotFound if none is found.
@@ -511,7 +1574,8 @@ func SetActiveProfile(path, profile string) error {
 	}
 
 	content := strings.Join(lines, "\n")
-	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {		return fmt.Errorf("writing config %s: %w", path, err)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("writing config %s: %w", path, err)
 	}
 	return nil
 }
@@ -592,7 +1656,8 @@ func AddProfile(path, profile, envFile string) error {
 	}
 
 	content := strings.Join(lines, "\n")
-	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {		return fmt.Errorf("writing config %s: %w", path, err)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("writing config %s: %w", path, err)
 	}
 	return nil
 }
@@ -680,7 +1745,8 @@ func AddTeamMember(path, name, publicKey string) error {
 	}
 
 	content := strings.Join(lines, "\n")
-	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {		return fmt.Errorf("writing config %s: %w", path, err)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("writing config %s: %w", path, err)
 	}
 	return nil
 }
@@ -779,7 +1845,8 @@ func RemoveTeamMember(path, name string) error {
 	}
 
 	content := strings.Join(newLines, "\n")
-	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {		return fmt.Errorf("writing config %s: %w", path, err)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("writing config %s: %w", path, err)
 	}
 	return nil
 }
@@ -827,5 +1894,55 @@ func loadFile(path string) (*Config, error) {
 		return nil, fmt.Errorf("parsing config %s: %w", path, err)
 	}
 
-	return cfg, nil
+	blocks, err := loadKeyBlocks(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Schema = blocks.Schema
+	cfg.Rotation = blocks.Rotation
+	cfg.KeyAliases = blocks.KeyAliases
+	cfg.ACL = blocks.ACL
+
+	encrypted, err := loadEncrypted(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, keys := range encrypted[""] {
+		if i < len(cfg.Backends) {
+			cfg.Backends[i].Encrypted = keys
+		}
+	}
+	for name, section := range cfg.OS {
+		for i, keys := range encrypted[name] {
+			if i < len(section.Backends) {
+				section.Backends[i].Encrypted = keys
+			}
+		}
+	}
+
+	return cfg.applyOS(runtime.GOOS), nil
+}
+
+// keyBlocks holds the config blocks whose map keys are environment variable
+// names or patterns.
+type keyBlocks struct {
+	Schema     map[string]schema.Rule `yaml:"schema"`
+	Rotation   map[string]string      `yaml:"rotation"`
+	KeyAliases map[string][]string    `yaml:"key_aliases"`
+	ACL        map[string][]string    `yaml:"acl"`
+}
+
+// loadKeyBlocks reads the schema:, rotation:, key_aliases:, and acl:
+// blocks of a config file with the YAML decoder directly, preserving the
+// case of environment variable names.
+func loadKeyBlocks(path string) (keyBlocks, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return keyBlocks{}, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	var doc keyBlocks
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return keyBlocks{}, fmt.Errorf("parsing schema in config %s: %w", path, err)
+	}
+	return doc, nil
 }
@@ -5,7 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/xcke/envref/internal/schema"
 )
 
 // writeFile is a test helper that creates a file with the given content.
@@ -693,6 +697,35 @@ func TestConfig_Validate_ActiveProfile(t *testing.T) {
 			wantErr: true,
 			errMsg:  "active_profile \"staging\" is not defined in profiles",
 		},
+		{
+			name: "active_profile combines defined profiles",
+			config: Config{
+				Project:       "myapp",
+				EnvFile:       ".env",
+				LocalFile:     ".env.local",
+				ActiveProfile: "production,eu",
+				Profiles: map[string]ProfileConfig{
+					"production": {EnvFile: ".env.production"},
+					"eu":         {EnvFile: ".env.eu"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "active_profile combines an undefined profile",
+			config: Config{
+				Project:       "myapp",
+				EnvFile:       ".env",
+				LocalFile:     ".env.local",
+				ActiveProfile: "production,us",
+				Profiles: map[string]ProfileConfig{
+					"production": {EnvFile: ".env.production"},
+					"eu":         {EnvFile: ".env.eu"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "active_profile \"production,us\": profile \"us\" is not defined in profiles",
+		},
 		{
 			name: "active_profile without any profiles defined is allowed",
 			config: Config{
@@ -2191,3 +2224,852 @@ func containsAt(s, substr string) bool {
 	}
 	return false
 }
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	t.Setenv("ENVREF_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, dir, FullFileName, `project: myapp
+env_file: .env
+active_profile: development
+backends:
+  - name: keychain
+  - name: ssm
+    type: aws-ssm
+    config:
+      region: us-east-1
+profiles:
+  development: {}
+  ci: {}
+`)
+
+	t.Run("no overrides", func(t *testing.T) {
+		cfg, _, err := Load(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Project != "myapp" || cfg.ActiveProfile != "development" || len(cfg.Backends) != 2 {
+			t.Errorf("unexpected config without overrides: %+v", cfg)
+		}
+	})
+
+	t.Run("all overrides", func(t *testing.T) {
+		t.Setenv(EnvProject, "ci-project")
+		t.Setenv(EnvProfile, "ci")
+		t.Setenv(EnvEnvFile, ".env.ci")
+		t.Setenv(EnvBackend, "ssm")
+
+		cfg, _, err := Load(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Project != "ci-project" {
+			t.Errorf("Project = %q, want %q", cfg.Project, "ci-project")
+		}
+		if cfg.ActiveProfile != "ci" {
+			t.Errorf("ActiveProfile = %q, want %q", cfg.ActiveProfile, "ci")
+		}
+		if cfg.EnvFile != ".env.ci" {
+			t.Errorf("EnvFile = %q, want %q", cfg.EnvFile, ".env.ci")
+		}
+		if len(cfg.Backends) != 1 {
+			t.Fatalf("len(Backends) = %d, want 1", len(cfg.Backends))
+		}
+		if cfg.Backends[0].Name != "ssm" || cfg.Backends[0].Config["region"] != "us-east-1" {
+			t.Errorf("Backends[0] = %+v, want configured ssm backend", cfg.Backends[0])
+		}
+	})
+
+	t.Run("unconfigured backend", func(t *testing.T) {
+		t.Setenv(EnvBackend, "vault")
+
+		cfg, _, err := Load(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cfg.Backends) != 1 || cfg.Backends[0].EffectiveType() != "vault" {
+			t.Errorf("Backends = %+v, want single vault backend", cfg.Backends)
+		}
+	})
+
+	t.Run("invalid override is rejected", func(t *testing.T) {
+		t.Setenv(EnvProject, " padded ")
+
+		_, _, err := Load(dir)
+		var ve *ValidationError
+		if !errors.As(err, &ve) {
+			t.Fatalf("expected ValidationError, got %v", err)
+		}
+	})
+}
+
+func TestValidate_Aliases(t *testing.T) {
+	base := func() Config {
+		return Config{
+			Project:   "myapp",
+			EnvFile:   ".env",
+			LocalFile: ".env.local",
+			Backends:  []BackendConfig{{Name: "vault"}, {Name: "keychain"}},
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		cfg := base()
+		cfg.Aliases = map[string][]string{"secrets": {"vault", "keychain"}}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		aliases map[string][]string
+		want    string
+	}{
+		{name: "unknown backend", aliases: map[string][]string{"secrets": {"vault", "op"}}, want: `alias "secrets" references unknown backend "op"`},
+		{name: "backend typo", aliases: map[string][]string{"secrets": {"vualt"}}, want: `alias "secrets" references unknown backend "vualt"; did you mean vault?`},
+		{name: "empty list", aliases: map[string][]string{"secrets": {}}, want: `alias "secrets" must list at least one backend`},
+		{name: "shadows backend", aliases: map[string][]string{"vault": {"keychain"}}, want: `alias "vault" shadows a backend`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			cfg.Aliases = tt.aliases
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFile_Aliases(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, `project: myapp
+backends:
+  - name: vault
+  - name: keychain
+aliases:
+  secrets: [vault, keychain]
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := cfg.Aliases["secrets"]
+	if len(got) != 2 || got[0] != "vault" || got[1] != "keychain" {
+		t.Errorf("Aliases[secrets] = %v, want [vault keychain]", got)
+	}
+}
+
+func TestLoad_BackendOverrideRetargetsAliases(t *testing.T) {
+	t.Setenv("ENVREF_CONFIG_DIR", t.TempDir())
+	t.Setenv(EnvBackend, "keychain")
+	dir := t.TempDir()
+	writeFile(t, dir, FullFileName, `project: myapp
+backends:
+  - name: vault
+  - name: keychain
+aliases:
+  secrets: [vault, keychain]
+`)
+
+	cfg, _, err := Load(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Aliases["secrets"]; len(got) != 1 || got[0] != "keychain" {
+		t.Errorf("Aliases[secrets] = %v, want [keychain]", got)
+	}
+}
+
+func TestLoadFile_Schema(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, `project: myapp
+schema:
+  DB_PORT:
+    type: int
+    required: true
+  LOG_LEVEL:
+    type: enum
+    values: [debug, info]
+  API_KEY:
+    ref: true
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Schema) != 3 {
+		t.Fatalf("len(Schema) = %d, want 3: %v", len(cfg.Schema), cfg.Schema)
+	}
+	// Keys keep their case even though Viper lowercases other map keys.
+	port, ok := cfg.Schema["DB_PORT"]
+	if !ok || port.Type != "int" || !port.Required {
+		t.Errorf("Schema[DB_PORT] = %+v, %v", port, ok)
+	}
+	if got := cfg.Schema["LOG_LEVEL"].Values; len(got) != 2 {
+		t.Errorf("Schema[LOG_LEVEL].Values = %v", got)
+	}
+	if !cfg.Schema["API_KEY"].Ref {
+		t.Error("Schema[API_KEY].Ref = false, want true")
+	}
+}
+
+func TestValidate_Schema(t *testing.T) {
+	cfg := Defaults()
+	cfg.Project = "myapp"
+	cfg.Schema = map[string]schema.Rule{"MODE": {Type: "enum"}}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `key "MODE" has type "enum" but no values`) {
+		t.Errorf("Validate() = %v, want enum error", err)
+	}
+}
+
+func TestLoad_Extends(t *testing.T) {
+	t.Setenv("ENVREF_CONFIG_DIR", t.TempDir())
+	root := t.TempDir()
+	writeFile(t, root, ".envref.base.yaml", `env_file: .env.shared
+active_profile: dev
+backends:
+  - name: keychain
+  - name: vault
+profiles:
+  dev:
+    env_file: .env.dev
+  prod:
+    env_file: .env.production
+aliases:
+  secrets: [vault, keychain]
+`)
+	service := filepath.Join(root, "services", "api")
+	if err := os.MkdirAll(service, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFile(t, service, FullFileName, `extends: ../../.envref.base.yaml
+project: api
+active_profile: prod
+`)
+
+	cfg, dir, err := Load(service)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dir != service {
+		t.Errorf("root = %q, want %q", dir, service)
+	}
+	if cfg.Project != "api" {
+		t.Errorf("Project = %q, want %q", cfg.Project, "api")
+	}
+	if cfg.ActiveProfile != "prod" {
+		t.Errorf("ActiveProfile = %q, want %q (project overrides base)", cfg.ActiveProfile, "prod")
+	}
+	if cfg.EnvFile != ".env.shared" {
+		t.Errorf("EnvFile = %q, want %q (inherited)", cfg.EnvFile, ".env.shared")
+	}
+	if len(cfg.Backends) != 2 || len(cfg.Profiles) != 2 || len(cfg.Aliases) != 1 {
+		t.Errorf("expected backends, profiles, and aliases to be inherited, got %+v", cfg)
+	}
+}
+
+func TestLoad_ExtendsChainAndOverride(t *testing.T) {
+	t.Setenv("ENVREF_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, dir, "root.yaml", "backends:\n  - name: keychain\n")
+	writeFile(t, dir, "middle.yaml", "extends: root.yaml\nbackends:\n  - name: vault\n")
+	writeFile(t, dir, FullFileName, "extends: middle.yaml\nproject: app\n")
+
+	cfg, _, err := Load(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The closest file that sets backends replaces them entirely.
+	if len(cfg.Backends) != 1 || cfg.Backends[0].Name != "vault" {
+		t.Errorf("Backends = %+v, want [vault]", cfg.Backends)
+	}
+}
+
+func TestLoad_ExtendsErrors(t *testing.T) {
+	t.Setenv("ENVREF_CONFIG_DIR", t.TempDir())
+
+	t.Run("missing base", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, FullFileName, "extends: nope.yaml\nproject: app\n")
+		_, _, err := Load(dir)
+		if err == nil || !strings.Contains(err.Error(), "nope.yaml") {
+			t.Errorf("expected error naming the missing base, got %v", err)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "a.yaml", "extends: "+FullFileName+"\n")
+		writeFile(t, dir, FullFileName, "extends: a.yaml\nproject: app\n")
+		_, _, err := Load(dir)
+		if err == nil || !strings.Contains(err.Error(), "cycle") {
+			t.Errorf("expected cycle error, got %v", err)
+		}
+	})
+}
+
+func TestMergeConfigs_Hooks(t *testing.T) {
+	global := &Config{Hooks: HooksConfig{PreResolve: "vault login", PostResolve: "notify global"}}
+	project := &Config{Project: "app", Hooks: HooksConfig{PostResolve: "notify project"}}
+
+	merged := mergeConfigs(global, project)
+	if merged.Hooks.PreResolve != "vault login" {
+		t.Errorf("PreResolve = %q, want inherited %q", merged.Hooks.PreResolve, "vault login")
+	}
+	if merged.Hooks.PostResolve != "notify project" {
+		t.Errorf("PostResolve = %q, want %q", merged.Hooks.PostResolve, "notify project")
+	}
+}
+
+func TestMergeConfigs_Prefix(t *testing.T) {
+	global := &Config{Prefix: PrefixConfig{Add: "VITE_", Strip: "APP_"}}
+	project := &Config{Project: "app", Prefix: PrefixConfig{Add: "REACT_APP_"}}
+
+	merged := mergeConfigs(global, project)
+	if merged.Prefix.Add != "REACT_APP_" || merged.Prefix.Strip != "APP_" {
+		t.Errorf("Prefix = %+v, want add from project and strip inherited", merged.Prefix)
+	}
+}
+
+func TestValidate_Prefix(t *testing.T) {
+	cfg := Defaults()
+	cfg.Project = "myapp"
+	cfg.Prefix = PrefixConfig{Add: "VITE_", Strip: "APP_"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.Prefix = PrefixConfig{Add: "my-app.", Strip: "1X"}
+	err := cfg.Validate()
+	for _, want := range []string{`prefix.add: "my-app."`, `prefix.strip: "1X"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want error containing %q", err, want)
+		}
+	}
+}
+
+func TestValidate_Encryption(t *testing.T) {
+	cfg := Defaults()
+	cfg.Project = "myapp"
+	cfg.Encryption.Key = "ref://keychain/envref_encryption_key"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for value, want := range map[string]string{
+		"keychain/enc_key":                          "encryption.key: not a ref:// URI",
+		"ref://self/ENC_KEY":                        "encryption.key: must name a secret in a backend",
+		"ref://secrets/enc_key?encoding=base64file": "encryption.key: must not have an encoding",
+	} {
+		cfg.Encryption.Key = value
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() with key %q = %v, want error containing %q", value, err, want)
+		}
+	}
+}
+
+func TestMergeConfigs_Remote(t *testing.T) {
+	global := &Config{Remote: RemoteConfig{CacheTTL: "24h", PublicKeys: []string{"global-key"}}}
+	project := &Config{Project: "app", Remote: RemoteConfig{PublicKeys: []string{"project-key"}}}
+
+	merged := mergeConfigs(global, project)
+	if merged.Remote.CacheTTL != "24h" {
+		t.Errorf("Remote.CacheTTL = %q, want inherited 24h", merged.Remote.CacheTTL)
+	}
+	if len(merged.Remote.PublicKeys) != 1 || merged.Remote.PublicKeys[0] != "project-key" {
+		t.Errorf("Remote.PublicKeys = %v, want the project's keys", merged.Remote.PublicKeys)
+	}
+}
+
+func TestValidate_Remote(t *testing.T) {
+	const key = "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="
+	const sum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	cfg := Defaults()
+	cfg.Project = "myapp"
+	cfg.EnvFile = "https://config.internal/service.env#sha256=" + sum
+	cfg.EnvFiles = []string{"https://config.internal/base.env", ".env"}
+	cfg.Remote = RemoteConfig{CacheTTL: "30m", PublicKeys: []string{key}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.EnvFile = "https://config.internal/service.env#sha256=abc"
+	cfg.EnvFiles = []string{"https:///base.env"}
+	cfg.Remote = RemoteConfig{CacheTTL: "soon", PublicKeys: []string{"not-a-key"}}
+	err := cfg.Validate()
+	for _, want := range []string{
+		"env_file: invalid URL",
+		"env_files[0]: invalid URL",
+		`remote: invalid cache_ttl "soon"`,
+		"remote.public_keys[0]: invalid public key",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want error containing %q", err, want)
+		}
+	}
+}
+
+func TestValidate_SelfBackendReserved(t *testing.T) {
+	cfg := Defaults()
+	cfg.Project = "myapp"
+	cfg.Backends = []BackendConfig{{Name: "self"}, {Name: "vault"}}
+	cfg.Aliases = map[string][]string{"self": {"vault"}}
+	err := cfg.Validate()
+	for _, want := range []string{`backends[0]: name "self" is reserved`, `aliases: alias name "self" is reserved`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want error containing %q", err, want)
+		}
+	}
+}
+
+func TestValidate_RefSchemes(t *testing.T) {
+	base := func() Config {
+		cfg := Defaults()
+		cfg.Project = "myapp"
+		cfg.Backends = []BackendConfig{{Name: "vault"}}
+		cfg.Aliases = map[string][]string{"secrets": {"vault"}}
+		return cfg
+	}
+
+	cfg := base()
+	cfg.RefSchemes = map[string]string{"secret": "", "op": "vault", "sec": "secrets"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		schemes map[string]string
+		want    string
+	}{
+		{map[string]string{"ref": ""}, `"ref" is always recognized`},
+		{map[string]string{"Bad_Name": ""}, `invalid scheme name "Bad_Name"`},
+		{map[string]string{"op": "1password"}, `scheme "op" references unknown backend "1password"`},
+	}
+	for _, tt := range tests {
+		cfg := base()
+		cfg.RefSchemes = tt.schemes
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%v) = %v, want error containing %q", tt.schemes, err, tt.want)
+		}
+	}
+}
+
+func TestValidate_BackendNamespace(t *testing.T) {
+	cfg := Defaults()
+	cfg.Project = "myapp"
+	cfg.Backends = []BackendConfig{
+		{Name: "keychain"},
+		{Name: "ssm", Type: "aws-ssm", Namespace: "/{project}/{profile}/{key}"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.Backends[1].Namespace = "{project}/{stage}/{key}"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "backends[1]") || !strings.Contains(err.Error(), "unknown placeholder {stage}") {
+		t.Errorf("Validate = %v, want unknown placeholder error for backends[1]", err)
+	}
+
+	cfg.Backends[1].Namespace = "{project}"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "must contain {key} exactly once") {
+		t.Errorf("Validate = %v, want missing {key} error", err)
+	}
+}
+
+func TestLoad_BackendNamespace(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, FullFileName, `project: myapp
+backends:
+  - name: gcp
+    type: plugin
+    namespace: "{project}-{key}"
+`)
+
+	cfg, _, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.Backends[0].Namespace; got != "{project}-{key}" {
+		t.Errorf("Namespace = %q, want %q", got, "{project}-{key}")
+	}
+}
+
+func TestValidate_BackendMiddleware(t *testing.T) {
+	cfg := Defaults()
+	cfg.Project = "myapp"
+	cfg.Backends = []BackendConfig{{
+		Name: "ssm",
+		Type: "aws-ssm",
+		Middleware: []MiddlewareConfig{
+			{Type: "logging"},
+			{Type: "cache", TTL: "30s"},
+			{Type: "rate-limit", Rate: 5},
+		},
+	}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		mw   MiddlewareConfig
+		want string
+	}{
+		{"unknown type", MiddlewareConfig{Type: "retry"}, `unknown middleware type "retry"`},
+		{"missing type", MiddlewareConfig{}, "type is required"},
+		{"invalid ttl", MiddlewareConfig{Type: "cache", TTL: "soon"}, `invalid ttl "soon"`},
+		{"zero rate", MiddlewareConfig{Type: "rate-limit"}, "rate must be a positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Backends[0].Middleware = []MiddlewareConfig{{Type: "logging"}, tt.mw}
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), "backends[0].middleware[1]: "+tt.want) {
+				t.Errorf("Validate = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_BackendMiddleware(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, FullFileName, `project: myapp
+backends:
+  - name: ssm
+    type: aws-ssm
+    middleware:
+      - type: cache
+        ttl: 1m
+      - type: rate-limit
+        rate: 2.5
+`)
+
+	cfg, _, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	mw := cfg.Backends[0].Middleware
+	if len(mw) != 2 || mw[0].Type != "cache" || mw[1].Rate != 2.5 {
+		t.Fatalf("Middleware = %+v", mw)
+	}
+	if ttl, err := mw[0].CacheTTL(); err != nil || ttl != time.Minute {
+		t.Errorf("CacheTTL = %s, %v; want 1m", ttl, err)
+	}
+	if ttl, _ := (MiddlewareConfig{Type: "cache"}).CacheTTL(); ttl != DefaultCacheTTL {
+		t.Errorf("default CacheTTL = %s, want %s", ttl, DefaultCacheTTL)
+	}
+}
+
+func TestConfig_EnvLayers(t *testing.T) {
+	legacy := Defaults()
+	layered := Defaults()
+	layered.EnvFiles = []string{".env.defaults", ".env", ".env.{profile}", ".env.local"}
+	layered.Profiles = map[string]ProfileConfig{"prod": {EnvFile: "deploy/.env.production"}, "prod-eu": {Inherits: "prod"}}
+	inherited := Defaults()
+	inherited.Profiles = map[string]ProfileConfig{"staging": {}, "preview": {Inherits: "staging"}, "pr": {Inherits: "preview"}}
+
+	tests := []struct {
+		name    string
+		config  Config
+		profile string
+		want    []string
+	}{
+		{"legacy without profile", legacy, "", []string{".env", ".env.local"}},
+		{"legacy with profile", legacy, "staging", []string{".env", ".env.staging", ".env.local", ".env.staging.local"}},
+		{"layered without profile", layered, "", []string{".env.defaults", ".env", ".env.local"}},
+		{"layered with profile", layered, "staging", []string{".env.defaults", ".env", ".env.staging", ".env.local"}},
+		{"layered with custom profile file", layered, "prod", []string{".env.defaults", ".env", "deploy/.env.production", ".env.local"}},
+		{"layered with inherited profile", layered, "prod-eu", []string{".env.defaults", ".env", "deploy/.env.production", ".env.prod-eu", ".env.local"}},
+		{"legacy with inherited profiles", inherited, "pr", []string{".env", ".env.staging", ".env.preview", ".env.pr", ".env.local", ".env.staging.local", ".env.preview.local", ".env.pr.local"}},
+		{"legacy with combined profiles", legacy, "production,eu", []string{".env", ".env.production", ".env.eu", ".env.local", ".env.production.local", ".env.eu.local"}},
+		{"layered with combined profiles", layered, "prod,eu", []string{".env.defaults", ".env", "deploy/.env.production", ".env.eu", ".env.local"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.EnvLayers(tt.profile)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("EnvLayers(%q) = %v, want %v", tt.profile, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_EnvLayers_CRA(t *testing.T) {
+	cfg := Defaults()
+	cfg.Layering = LayeringCRA
+
+	t.Setenv("NODE_ENV", "")
+	want := []string{".env", ".env.development", ".env.local", ".env.development.local"}
+	if got := cfg.EnvLayers(""); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("EnvLayers(\"\") = %v, want %v", got, want)
+	}
+	want = []string{".env", ".env.staging", ".env.local", ".env.staging.local"}
+	if got := cfg.EnvLayers("staging"); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("EnvLayers(staging) = %v, want %v", got, want)
+	}
+
+	t.Setenv("NODE_ENV", "production")
+	want = []string{".env", ".env.production", ".env.local", ".env.production.local"}
+	if got := cfg.EnvLayers(""); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("EnvLayers(\"\") with NODE_ENV=production = %v, want %v", got, want)
+	}
+
+	// Tests do not load .env.local.
+	t.Setenv("NODE_ENV", "test")
+	want = []string{".env", ".env.test", ".env.test.local"}
+	if got := cfg.EnvLayers(""); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("EnvLayers(\"\") with NODE_ENV=test = %v, want %v", got, want)
+	}
+
+	cfg.Project = "app"
+	cfg.Layering = "vite"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `layering: unknown layering "vite" (supported: cra)`) {
+		t.Errorf("Validate() = %v, want an unknown layering error", err)
+	}
+}
+
+func TestConfig_ProfileChain(t *testing.T) {
+	cfg := Defaults()
+	cfg.Profiles = map[string]ProfileConfig{
+		"staging": {},
+		"preview": {Inherits: "staging"},
+		"a":       {Inherits: "b"},
+		"b":       {Inherits: "a"},
+		"eu":      {Inherits: "staging"},
+	}
+
+	tests := []struct {
+		profile string
+		want    []string
+	}{
+		{"", nil},
+		{"staging", []string{"staging"}},
+		{"preview", []string{"preview", "staging"}},
+		{"undeclared", []string{"undeclared"}},
+		{"a", []string{"a", "b"}},
+		{"production,eu", []string{"eu", "staging", "production"}},
+		{" production , eu ", []string{"eu", "staging", "production"}},
+		{"preview,eu", []string{"eu", "preview", "staging"}},
+		{"staging,staging", []string{"staging"}},
+	}
+	for _, tt := range tests {
+		if got := cfg.ProfileChain(tt.profile); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ProfileChain(%q) = %v, want %v", tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestValidate_ProfileInherits(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles map[string]ProfileConfig
+		want     string
+	}{
+		{name: "valid", profiles: map[string]ProfileConfig{"staging": {}, "preview": {Inherits: "staging"}}},
+		{name: "parent by convention", profiles: map[string]ProfileConfig{"preview": {Inherits: "staging"}}},
+		{name: "self", profiles: map[string]ProfileConfig{"staging": {Inherits: "staging"}}, want: `profile "staging" inherits from itself (staging -> staging)`},
+		{name: "cycle", profiles: map[string]ProfileConfig{"a": {Inherits: "b"}, "b": {Inherits: "a"}}, want: `profile "a" inherits from itself (a -> b -> a)`},
+		{name: "separator in name", profiles: map[string]ProfileConfig{"production,eu": {}}, want: `profile name "production,eu" must not contain ","`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Defaults()
+			cfg.Project = "myapp"
+			cfg.Profiles = tt.profiles
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_EnvFiles(t *testing.T) {
+	cfg := Defaults()
+	cfg.Project = "myapp"
+	cfg.EnvFiles = []string{".env.defaults", ".env", "", "/etc/app.env", ".env"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{
+		"env_files[2]: must not be empty",
+		"env_files[3]: must be a relative path",
+		`env_files[4]: duplicate file ".env"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestWarnings_EnvFileNotLayered(t *testing.T) {
+	cfg := Defaults()
+	cfg.Project = "myapp"
+	cfg.EnvFiles = []string{".env.defaults", ".env.local"}
+
+	warnings := cfg.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `env_file ".env" is not listed in env_files`) {
+		t.Errorf("Warnings = %v", warnings)
+	}
+
+	cfg.EnvFiles = append(cfg.EnvFiles, ".env")
+	if warnings := cfg.Warnings(); len(warnings) != 0 {
+		t.Errorf("Warnings = %v, want none", warnings)
+	}
+}
+
+func TestMergeConfigs_Audit(t *testing.T) {
+	global := &Config{Audit: AuditConfig{Reads: true, Sinks: []AuditSinkConfig{{Type: "syslog"}}}}
+	project := &Config{Project: "app", Audit: AuditConfig{Sign: true, Sinks: []AuditSinkConfig{{Type: "webhook", URL: "https://hooks.example.com"}}}}
+
+	merged := mergeConfigs(global, project)
+	if !merged.Audit.Sign {
+		t.Error("Sign should be kept from the project config")
+	}
+	if !merged.Audit.Reads {
+		t.Error("Reads should be enabled by the global config")
+	}
+	if len(merged.Audit.Sinks) != 2 || merged.Audit.Sinks[0].Type != "syslog" || merged.Audit.Sinks[1].Type != "webhook" {
+		t.Errorf("Sinks = %+v, want global syslog then project webhook", merged.Audit.Sinks)
+	}
+	if len(project.Audit.Sinks) != 1 {
+		t.Error("merging must not modify the project config")
+	}
+}
+
+func TestValidate_AuditSinks(t *testing.T) {
+	cfg := Defaults()
+	cfg.Project = "myapp"
+	cfg.Audit.Sinks = []AuditSinkConfig{
+		{Type: "syslog"},
+		{Type: "syslog", Address: "udp://logs.example.com:514"},
+		{Type: "webhook", URL: "https://hooks.example.com/audit"},
+		{Type: "otlp"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		sink AuditSinkConfig
+		want string
+	}{
+		{AuditSinkConfig{}, "audit.sinks[0]: type is required"},
+		{AuditSinkConfig{Type: "kafka"}, `unknown audit sink type "kafka"`},
+		{AuditSinkConfig{Type: "syslog", Address: "logs.example.com:514"}, "invalid syslog address"},
+		{AuditSinkConfig{Type: "webhook"}, "url is required"},
+		{AuditSinkConfig{Type: "otlp", URL: "collector:4318"}, "must be an http:// or https:// URL"},
+	}
+	for _, tt := range tests {
+		cfg.Audit.Sinks = []AuditSinkConfig{tt.sink}
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%+v) = %v, want error containing %q", tt.sink, err, tt.want)
+		}
+	}
+}
+
+func TestMergeConfigs_MemoryLock(t *testing.T) {
+	global := &Config{Memory: MemoryConfig{Lock: true}}
+	project := &Config{Project: "app"}
+
+	if merged := mergeConfigs(global, project); !merged.Memory.Lock {
+		t.Error("Lock should be inherited from the global config")
+	}
+	if merged := mergeConfigs(&Config{}, project); merged.Memory.Lock {
+		t.Error("Lock should be off unless a config enables it")
+	}
+}
+
+func TestMergeConfigs_Strength(t *testing.T) {
+	global := &Config{Strength: StrengthConfig{MinLength: 16, MinEntropy: 64, Deny: []string{"acme"}}}
+	project := &Config{Project: "app", Strength: StrengthConfig{MinLength: 24, Deny: []string{"app-dev"}}}
+
+	merged := mergeConfigs(global, project)
+	if merged.Strength.MinLength != 24 || merged.Strength.MinEntropy != 64 {
+		t.Errorf("thresholds = %+v, want project min_length and global min_entropy", merged.Strength)
+	}
+	if strings.Join(merged.Strength.Deny, ",") != "acme,app-dev" {
+		t.Errorf("Deny = %v, want both lists", merged.Strength.Deny)
+	}
+}
+
+func TestMergeConfigs_GitHub(t *testing.T) {
+	global := &Config{GitHub: GitHubConfig{
+		Repo:         "acme/base",
+		Secrets:      []string{"NPM_TOKEN"},
+		Environments: []GitHubEnvironment{{Name: "production", Profile: "prod", Secrets: []string{"DEPLOY_KEY"}}},
+	}}
+
+	merged := mergeConfigs(global, &Config{Project: "app"})
+	if merged.GitHub.Repo != "" {
+		t.Errorf("Repo = %q, should not be inherited", merged.GitHub.Repo)
+	}
+	if strings.Join(merged.GitHub.Secrets, ",") != "NPM_TOKEN" || len(merged.GitHub.Environments) != 1 {
+		t.Errorf("GitHub = %+v, want the global secrets and environments", merged.GitHub)
+	}
+
+	project := &Config{Project: "app", GitHub: GitHubConfig{Repo: "acme/app", Secrets: []string{"API_KEY"}}}
+	merged = mergeConfigs(global, project)
+	if merged.GitHub.Repo != "acme/app" || strings.Join(merged.GitHub.Secrets, ",") != "API_KEY" {
+		t.Errorf("GitHub = %+v, want the project repo and secrets", merged.GitHub)
+	}
+	if len(merged.GitHub.Environments) != 1 || merged.GitHub.Environments[0].Name != "production" {
+		t.Errorf("Environments = %v, want the global environments", merged.GitHub.Environments)
+	}
+}
+
+func TestMergeConfigs_WorkspaceAndCompose(t *testing.T) {
+	global := &Config{
+		Workspace: WorkspaceConfig{Members: []WorkspaceMember{{Path: "services/api"}}},
+		Compose:   ComposeConfig{Services: []ComposeService{{Name: "api"}}},
+	}
+	merged := mergeConfigs(global, &Config{Project: "app"})
+	if len(merged.Workspace.Members) != 0 || len(merged.Compose.Services) != 0 {
+		t.Errorf("workspace and compose should not be inherited, got %+v, %+v", merged.Workspace, merged.Compose)
+	}
+}
+
+func TestRequiresRef(t *testing.T) {
+	cfg := &Config{RequireRefs: []string{"*_TOKEN", "DB_PASSWORD"}}
+	if pattern, ok := cfg.RequiresRef("GITHUB_TOKEN"); !ok || pattern != "*_TOKEN" {
+		t.Errorf("RequiresRef(GITHUB_TOKEN) = %q, %v", pattern, ok)
+	}
+	if _, ok := cfg.RequiresRef("DB_PASSWORD"); !ok {
+		t.Error("exact key should match")
+	}
+	if _, ok := cfg.RequiresRef("TOKEN_TTL"); ok {
+		t.Error("TOKEN_TTL should not match *_TOKEN")
+	}
+
+	merged := mergeConfigs(&Config{RequireRefs: []string{"*_SECRET"}}, cfg)
+	if strings.Join(merged.RequireRefs, ",") != "*_SECRET,*_TOKEN,DB_PASSWORD" {
+		t.Errorf("merged RequireRefs = %v", merged.RequireRefs)
+	}
+
+	invalid := Defaults()
+	invalid.Project = "myapp"
+	invalid.RequireRefs = []string{"[", ""}
+	err := invalid.Validate()
+	if err == nil || !strings.Contains(err.Error(), `require_refs[0]: invalid key pattern "["`) || !strings.Contains(err.Error(), "require_refs[1]") {
+		t.Errorf("expected require_refs errors, got %v", err)
+	}
+}
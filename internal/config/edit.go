@@ -0,0 +1,300 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ErrKeyNotFound is returned by GetValue when the key path does not exist
+// in the config file.
+var ErrKeyNotFound = errors.New("key not found")
+
+// keySegment is one step of a key path: a mapping key or a sequence index.
+type keySegment struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// parseKeyPath splits a key path such as "backends[0].config.region" into
+// its segments.
+func parseKeyPath(keyPath string) ([]keySegment, error) {
+	if keyPath == "" {
+		return nil, fmt.Errorf("key path must not be empty")
+	}
+	var segs []keySegment
+	for _, part := range strings.Split(keyPath, ".") {
+		name := part
+		var indexes []int
+		for strings.HasSuffix(name, "]") {
+			open := strings.LastIndex(name, "[")
+			if open < 0 {
+				return nil, fmt.Errorf("invalid key path %q: unmatched ]", keyPath)
+			}
+			n, err := strconv.Atoi(name[open+1 : len(name)-1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid key path %q: bad index %q", keyPath, name[open:])
+			}
+			indexes = append([]int{n}, indexes...)
+			name = name[:open]
+		}
+		if strings.ContainsAny(name, "[]") {
+			return nil, fmt.Errorf("invalid key path %q", keyPath)
+		}
+		if name == "" && (len(segs) > 0 || len(indexes) == 0) {
+			return nil, fmt.Errorf("invalid key path %q: empty key", keyPath)
+		}
+		if name != "" {
+			segs = append(segs, keySegment{key: name})
+		}
+		for _, n := range indexes {
+			segs = append(segs, keySegment{index: n, isIdx: true})
+		}
+	}
+	return segs, nil
+}
+
+// GetValue returns the value at keyPath (e.g., "backends[0].config.region")
+// in the config file at path. Scalars are returned as-is; mappings and
+// sequences are returned as YAML. Returns ErrKeyNotFound if any part of the
+// path does not exist.
+func GetValue(path, keyPath string) (string, error) {
+	segs, err := parseKeyPath(keyPath)
+	if err != nil {
+		return "", err
+	}
+	doc, err := readYAMLDocument(path)
+	if err != nil {
+		return "", err
+	}
+	if len(doc.Content) == 0 {
+		return "", fmt.Errorf("%s: %w", keyPath, ErrKeyNotFound)
+	}
+
+	node := doc.Content[0]
+	for _, seg := range segs {
+		node = childNode(node, seg)
+		if node == nil {
+			return "", fmt.Errorf("%s: %w", keyPath, ErrKeyNotFound)
+		}
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		if node.Tag == "!!null" {
+			return "", nil
+		}
+		return node.Value, nil
+	}
+	out, err := encodeYAML(node)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// SetValue sets keyPath to value in the config file at path, creating the
+// file and any missing intermediate mappings. A sequence index may equal
+// the sequence length to append an element.
+//
+// The value is parsed as YAML, so "true" and "8080" keep their types and
+// "[vault, keychain]" sets a list; anything that does not parse is stored as
+// a string. Comments and key order in the file are preserved. The edited
+// config is checked against the JSON Schema before it is written, so typos
+// in field names are rejected; problems already present in the file are
+// left for "config validate" to report.
+func SetValue(path, keyPath, value string) error {
+	segs, err := parseKeyPath(keyPath)
+	if err != nil {
+		return err
+	}
+	return setNode(path, keyPath, segs, valueNode(value))
+}
+
+// SetEncryptedValue sets keyPath to an !encrypted ciphertext (see
+// EncryptValue) in the config file at path. keyPath must address a backend
+// config value, such as "backends[0].config.token".
+func SetEncryptedValue(path, keyPath, ciphertext string) error {
+	segs, err := parseKeyPath(keyPath)
+	if err != nil {
+		return err
+	}
+	if len(segs) != 4 || segs[0].key != "backends" || !segs[1].isIdx || segs[2].key != "config" || segs[3].isIdx {
+		return fmt.Errorf("setting %s: %s is only supported for backends[N].config.<key>", keyPath, EncryptedTag)
+	}
+	return setNode(path, keyPath, segs, &yaml.Node{Kind: yaml.ScalarNode, Tag: EncryptedTag, Value: ciphertext})
+}
+
+// setNode replaces the node at segs in the config file at path with
+// newValue, as described in SetValue.
+func setNode(path, keyPath string, segs []keySegment, newValue *yaml.Node) error {
+	doc, err := readYAMLDocument(path)
+	if errors.Is(err, os.ErrNotExist) {
+		doc = &yaml.Node{Kind: yaml.DocumentNode}
+	} else if err != nil {
+		return err
+	}
+	// Problems already in the file are not caused by this edit.
+	existing := make(map[string]bool)
+	if before, err := encodeYAML(doc); err == nil {
+		if errs, err := CheckYAML(before); err == nil {
+			for _, e := range errs {
+				existing[e.Path+"\x00"+e.Message] = true
+			}
+		}
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	node := doc.Content[0]
+	for i, seg := range segs {
+		next := childNode(node, seg)
+		if next == nil {
+			var following keySegment
+			if i+1 < len(segs) {
+				following = segs[i+1]
+			}
+			next, err = addChild(node, seg, following, keyPath)
+			if err != nil {
+				return err
+			}
+		}
+		node = next
+	}
+
+	// Replace the target in place, keeping any comments attached to it.
+	newValue.HeadComment = node.HeadComment
+	newValue.LineComment = node.LineComment
+	newValue.FootComment = node.FootComment
+	*node = *newValue
+
+	data, err := encodeYAML(doc)
+	if err != nil {
+		return err
+	}
+	schemaErrs, err := CheckYAML(data)
+	if err != nil {
+		return err
+	}
+	for _, e := range schemaErrs {
+		if !existing[e.Path+"\x00"+e.Message] {
+			return fmt.Errorf("setting %s: %s", keyPath, e.Message)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing config %s: %w", path, err)
+	}
+	return nil
+}
+
+// readYAMLDocument parses the YAML file at path into a document node.
+// A missing file returns an error wrapping os.ErrNotExist.
+func readYAMLDocument(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return &doc, nil
+}
+
+// childNode returns the child of node selected by seg, or nil if it does
+// not exist or node has the wrong kind.
+func childNode(node *yaml.Node, seg keySegment) *yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if seg.isIdx {
+		if node.Kind != yaml.SequenceNode || seg.index >= len(node.Content) {
+			return nil
+		}
+		return node.Content[seg.index]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == seg.key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// addChild creates the child of node selected by seg. The new child is a
+// mapping, or a sequence when next is an index. A null node (e.g., an
+// empty "profiles:") is turned into the collection seg needs.
+func addChild(node *yaml.Node, seg, next keySegment, keyPath string) (*yaml.Node, error) {
+	child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if next.isIdx {
+		child = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		node.Kind, node.Tag, node.Value = yaml.MappingNode, "!!map", ""
+		if seg.isIdx {
+			node.Kind, node.Tag = yaml.SequenceNode, "!!seq"
+		}
+	}
+
+	if seg.isIdx {
+		if node.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("setting %s: cannot use index %d on a non-list value", keyPath, seg.index)
+		}
+		if seg.index != len(node.Content) {
+			return nil, fmt.Errorf("setting %s: index %d out of range (list has %d item(s))", keyPath, seg.index, len(node.Content))
+		}
+		node.Content = append(node.Content, child)
+		return child, nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("setting %s: cannot set %q on a non-mapping value", keyPath, seg.key)
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: seg.key}
+	node.Content = append(node.Content, key, child)
+	return child, nil
+}
+
+// valueNode parses value as a YAML scalar or flow collection, falling back
+// to a plain string.
+func valueNode(value string) *yaml.Node {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err == nil && len(doc.Content) == 1 {
+		n := doc.Content[0]
+		if n.Kind == yaml.ScalarNode || n.Style&yaml.FlowStyle != 0 {
+			n.Line, n.Column = 0, 0
+			return n
+		}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// encodeYAML marshals node with the two-space indentation used by envref
+// config files.
+func encodeYAML(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
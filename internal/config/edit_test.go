@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyPath(t *testing.T) {
+	segs, err := parseKeyPath("backends[0].config.region")
+	if err != nil {
+		t.Fatalf("parseKeyPath: %v", err)
+	}
+	want := []keySegment{{key: "backends"}, {index: 0, isIdx: true}, {key: "config"}, {key: "region"}}
+	if len(segs) != len(want) {
+		t.Fatalf("got %v, want %v", segs, want)
+	}
+	for i := range want {
+		if segs[i] != want[i] {
+			t.Errorf("segment %d: got %+v, want %+v", i, segs[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"", "a..b", "a[x]", "a[-1]", "a]", "a[0]b", ".a"} {
+		if _, err := parseKeyPath(bad); err == nil {
+			t.Errorf("parseKeyPath(%q): expected error", bad)
+		}
+	}
+}
+
+func TestGetValue(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, `project: myapp
+backends:
+  - name: ssm
+    config:
+      region: eu-west-1
+profiles:
+`)
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"project", "myapp"},
+		{"backends[0].config.region", "eu-west-1"},
+		{"backends[0].config", "region: eu-west-1"},
+		{"profiles", ""},
+	}
+	for _, tt := range tests {
+		got, err := GetValue(path, tt.key)
+		if err != nil {
+			t.Errorf("GetValue(%q): %v", tt.key, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("GetValue(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	for _, missing := range []string{"env_file", "backends[1]", "project.name"} {
+		if _, err := GetValue(path, missing); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("GetValue(%q) = %v, want ErrKeyNotFound", missing, err)
+		}
+	}
+}
+
+func TestSetValue_PreservesComments(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, `# Project settings
+project: myapp # used as the secret namespace
+
+backends:
+  # Primary store.
+  - name: ssm
+    config:
+      region: eu-west-1 # default region
+`)
+
+	if err := SetValue(path, "backends[0].config.region", "us-east-1"); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		"# Project settings\n",
+		"project: myapp # used as the secret namespace\n",
+		"# Primary store.\n",
+		"region: us-east-1 # default region\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestSetValue_CreatesKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, "project: myapp\nprofiles:\n")
+
+	steps := []struct{ key, value string }{
+		{"active_profile", "staging"},
+		{"profiles.staging.env_file", ".env.stg"},
+		{"backends[0].name", "vault"},
+		{"backends[1].name", "keychain"},
+		{"aliases.secrets", "[vault, keychain]"},
+		{"schema.PORT.required", "true"},
+	}
+	for _, s := range steps {
+		if err := SetValue(path, s.key, s.value); err != nil {
+			t.Fatalf("SetValue(%q, %q): %v", s.key, s.value, err)
+		}
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if cfg.ActiveProfile != "staging" {
+		t.Errorf("ActiveProfile = %q", cfg.ActiveProfile)
+	}
+	if cfg.Profiles["staging"].EnvFile != ".env.stg" {
+		t.Errorf("Profiles = %v", cfg.Profiles)
+	}
+	if len(cfg.Backends) != 2 || cfg.Backends[1].Name != "keychain" {
+		t.Errorf("Backends = %v", cfg.Backends)
+	}
+	if got := cfg.Aliases["secrets"]; len(got) != 2 || got[0] != "vault" {
+		t.Errorf("Aliases = %v", cfg.Aliases)
+	}
+	if !cfg.Schema["PORT"].Required {
+		t.Errorf("Schema = %v", cfg.Schema)
+	}
+}
+
+func TestSetValue_NewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "envref", GlobalFileName)
+	if err := SetValue(path, "backends[0].name", "keychain"); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+	got, err := GetValue(path, "backends[0].name")
+	if err != nil || got != "keychain" {
+		t.Errorf("GetValue = %q, %v", got, err)
+	}
+}
+
+func TestSetValue_Errors(t *testing.T) {
+	dir := t.TempDir()
+	original := "project: myapp\nbackends:\n  - name: vault\n"
+	path := writeFile(t, dir, FullFileName, original)
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"projct", `unknown field "projct"; did you mean project?`},
+		{"backends[0].nmae", `unknown field "nmae"`},
+		{"backends[5].name", "index 5 out of range (list has 1 item(s))"},
+		{"project.name", `cannot set "name" on a non-mapping value`},
+		{"project[0]", "cannot use index 0 on a non-list value"},
+	}
+	for _, tt := range tests {
+		err := SetValue(path, tt.key, "x")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SetValue(%q) = %v, want error containing %q", tt.key, err, tt.want)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != original {
+		t.Errorf("file changed after failed edits:\n%s", data)
+	}
+}
+
+func TestSetValue_IgnoresExistingProblems(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, "project: myapp\nactiv_profile: dev\n")
+
+	if err := SetValue(path, "env_file", ".env.app"); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/secret"
+	"go.yaml.in/yaml/v3"
+)
+
+// EncryptedTag marks a backend config value in .envref.yaml as encrypted:
+//
+//	backends:
+//	  - name: hcv
+//	    type: hashicorp-vault
+//	    config:
+//	      token: !encrypted YWdlLWVuY3J5cHRpb24ub3Jn...
+//
+// The value is an age ciphertext, base64-encoded, encrypted with the config
+// passphrase (see Passphrase). Encrypted values are only supported under
+// backends[].config.
+const EncryptedTag = "!encrypted"
+
+// PassphraseKeychainItem is the OS keychain item holding the config
+// passphrase when ENVREF_VAULT_PASSPHRASE is not set.
+const PassphraseKeychainItem = "__envref_config_passphrase__"
+
+// ErrNoPassphrase is returned by Passphrase when neither
+// ENVREF_VAULT_PASSPHRASE nor the keychain item is set.
+var ErrNoPassphrase = errors.New("no passphrase for encrypted config values: set ENVREF_VAULT_PASSPHRASE or store it with 'envref config set --encrypt --remember'")
+
+// Passphrase returns the passphrase for encrypted config values: the vault
+// passphrase from ENVREF_VAULT_PASSPHRASE, or else the one stored in the OS
+// keychain under PassphraseKeychainItem.
+func Passphrase() (string, error) {
+	if p := os.Getenv("ENVREF_VAULT_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	p, err := backend.KeychainItem(PassphraseKeychainItem)
+	if errors.Is(err, backend.ErrNotFound) {
+		return "", ErrNoPassphrase
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w (%v)", ErrNoPassphrase, err)
+	}
+	return p, nil
+}
+
+// EncryptValue encrypts plaintext with passphrase and returns the base64
+// ciphertext stored after an !encrypted tag.
+func EncryptValue(plaintext, passphrase string) (string, error) {
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return "", fmt.Errorf("creating age recipient: %w", err)
+	}
+	// Config values are decrypted on every backend load, so use the same
+	// reduced work factor as vault values.
+	recipient.SetWorkFactor(15)
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return "", fmt.Errorf("creating age writer: %w", err)
+	}
+	if _, err := io.WriteString(w, plaintext); err != nil {
+		return "", fmt.Errorf("writing plaintext: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("closing age writer: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecryptValue decrypts a ciphertext produced by EncryptValue.
+func DecryptValue(ciphertext, passphrase string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(ciphertext))
+	if err != nil {
+		return "", fmt.Errorf("decoding encrypted value: %w", err)
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return "", fmt.Errorf("creating age identity: %w", err)
+	}
+	r, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		return "", fmt.Errorf("decrypting: %w", err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("reading plaintext: %w", err)
+	}
+	return secret.TakeString(plaintext), nil
+}
+
+// IsEncrypted reports whether the config value for key is still an
+// encrypted ciphertext.
+func (b BackendConfig) IsEncrypted(key string) bool {
+	for _, k := range b.Encrypted {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Decrypt returns a copy of b with its encrypted config values replaced by
+// their plaintext. It is a no-op when b has no encrypted values.
+func (b BackendConfig) Decrypt(passphrase string) (BackendConfig, error) {
+	if len(b.Encrypted) == 0 {
+		return b, nil
+	}
+	out := b
+	out.Config = make(map[string]string, len(b.Config))
+	for k, v := range b.Config {
+		out.Config[k] = v
+	}
+	for _, k := range b.Encrypted {
+		plaintext, err := DecryptValue(b.Config[k], passphrase)
+		if err != nil {
+			return BackendConfig{}, fmt.Errorf("config.%s: %w", k, err)
+		}
+		out.Config[k] = plaintext
+	}
+	out.Encrypted = nil
+	return out, nil
+}
+
+// loadEncrypted returns the config keys of the file at path tagged
+// !encrypted, by backend index. The keys for the top-level backends are under
+// "", and those of an os: section under the system name. Keys are lowercased
+// to match the Viper-decoded Config map. The tag anywhere else is an error,
+// since the value would otherwise be used as-is.
+func loadEncrypted(path string) (map[string]map[int][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	encrypted := make(map[string]map[int][]string)
+	allowed := make(map[*yaml.Node]bool)
+	if len(doc.Content) > 0 {
+		root := doc.Content[0]
+		encrypted[""] = encryptedBackendKeys(root, allowed)
+		if sections := childNode(root, keySegment{key: "os"}); sections != nil && sections.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(sections.Content); i += 2 {
+				encrypted[sections.Content[i].Value] = encryptedBackendKeys(sections.Content[i+1], allowed)
+			}
+		}
+	}
+
+	var misplaced *yaml.Node
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if misplaced != nil {
+			return
+		}
+		if n.Tag == EncryptedTag && !allowed[n] {
+			misplaced = n
+			return
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(&doc)
+	if misplaced != nil {
+		return nil, fmt.Errorf("config %s: line %d: %s is only supported for backends[].config values", path, misplaced.Line, EncryptedTag)
+	}
+	return encrypted, nil
+}
+
+// encryptedBackendKeys returns the !encrypted config keys of the backends
+// list in node, by backend index, and marks their value nodes in allowed.
+func encryptedBackendKeys(node *yaml.Node, allowed map[*yaml.Node]bool) map[int][]string {
+	keys := make(map[int][]string)
+	backends := childNode(node, keySegment{key: "backends"})
+	if backends == nil || backends.Kind != yaml.SequenceNode {
+		return keys
+	}
+	for i, b := range backends.Content {
+		cfg := childNode(b, keySegment{key: "config"})
+		if cfg == nil || cfg.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(cfg.Content); j += 2 {
+			if v := cfg.Content[j+1]; v.Tag == EncryptedTag {
+				allowed[v] = true
+				keys[i] = append(keys[i], strings.ToLower(cfg.Content[j].Value))
+			}
+		}
+		sort.Strings(keys[i])
+	}
+	return keys
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptValue_RoundTrip(t *testing.T) {
+	ciphertext, err := EncryptValue("hvs.token", "correct horse")
+	if err != nil {
+		t.Fatalf("EncryptValue: %v", err)
+	}
+	if strings.Contains(ciphertext, "hvs.token") || strings.ContainsAny(ciphertext, "\n ") {
+		t.Fatalf("ciphertext should be a single opaque line, got %q", ciphertext)
+	}
+
+	plaintext, err := DecryptValue(ciphertext, "correct horse")
+	if err != nil {
+		t.Fatalf("DecryptValue: %v", err)
+	}
+	if plaintext != "hvs.token" {
+		t.Errorf("plaintext = %q, want hvs.token", plaintext)
+	}
+
+	if _, err := DecryptValue(ciphertext, "wrong"); err == nil {
+		t.Error("expected error with wrong passphrase")
+	}
+}
+
+func TestPassphrase_FromEnv(t *testing.T) {
+	t.Setenv("ENVREF_VAULT_PASSPHRASE", "from-env")
+	p, err := Passphrase()
+	if err != nil || p != "from-env" {
+		t.Fatalf("Passphrase() = %q, %v", p, err)
+	}
+}
+
+func TestLoadFile_EncryptedBackendConfig(t *testing.T) {
+	ciphertext, err := EncryptValue("s3cret", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, `project: app
+backends:
+  - name: keychain
+  - name: hcv
+    type: hashicorp-vault
+    config:
+      addr: https://vault.example.com
+      Token: !encrypted `+ciphertext+`
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	hcv := cfg.Backends[1]
+	if len(cfg.Backends[0].Encrypted) != 0 {
+		t.Errorf("keychain should have no encrypted values, got %v", cfg.Backends[0].Encrypted)
+	}
+	if !hcv.IsEncrypted("token") || hcv.IsEncrypted("addr") {
+		t.Fatalf("Encrypted = %v, want [token]", hcv.Encrypted)
+	}
+	if hcv.Config["token"] != ciphertext {
+		t.Errorf("token should hold the ciphertext until decrypted")
+	}
+
+	decrypted, err := hcv.Decrypt("pw")
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if decrypted.Config["token"] != "s3cret" || decrypted.Config["addr"] != "https://vault.example.com" {
+		t.Errorf("decrypted config = %v", decrypted.Config)
+	}
+	if len(decrypted.Encrypted) != 0 {
+		t.Errorf("decrypted config should have no encrypted values")
+	}
+	if hcv.Config["token"] != ciphertext {
+		t.Error("Decrypt must not modify the original config")
+	}
+
+	if _, err := hcv.Decrypt("wrong"); err == nil || !strings.Contains(err.Error(), "config.token") {
+		t.Errorf("expected config.token error, got %v", err)
+	}
+}
+
+func TestLoadFile_EncryptedTagOutsideBackendConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, "project: !encrypted abc\n")
+
+	_, err := LoadFile(path)
+	if err == nil || !strings.Contains(err.Error(), "only supported for backends[].config") {
+		t.Fatalf("expected misplaced tag error, got %v", err)
+	}
+}
+
+func TestSetEncryptedValue(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FullFileName)
+	writeFile(t, dir, FullFileName, "project: app\nbackends:\n  - name: hcv\n    type: hashicorp-vault\n")
+
+	if err := SetEncryptedValue(path, "backends[0].config.token", "Y2lwaGVy"); err != nil {
+		t.Fatalf("SetEncryptedValue: %v", err)
+	}
+	got, err := GetValue(path, "backends[0].config.token")
+	if err != nil || got != "Y2lwaGVy" {
+		t.Fatalf("GetValue = %q, %v", got, err)
+	}
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if !cfg.Backends[0].IsEncrypted("token") {
+		t.Error("expected token to be marked encrypted")
+	}
+
+	err = SetEncryptedValue(path, "project", "Y2lwaGVy")
+	if err == nil || !strings.Contains(err.Error(), "only supported for backends[N].config.<key>") {
+		t.Errorf("expected path restriction error, got %v", err)
+	}
+}
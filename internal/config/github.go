@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// GitHubConfig maps keys of the resolved environment to the GitHub Actions
+// secrets that "envref gh sync" writes.
+type GitHubConfig struct {
+	// Repo is the repository, as owner/name. Empty means the repository
+	// of the origin remote.
+	Repo string `mapstructure:"repo" yaml:"repo"`
+
+	// Secrets lists the repository secrets, each as "NAME" or
+	// "NAME=KEY". See ParseGitHubSecret.
+	Secrets []string `mapstructure:"secrets" yaml:"secrets"`
+
+	// Environments lists the deployment environments whose secrets are
+	// written.
+	Environments []GitHubEnvironment `mapstructure:"environments" yaml:"environments"`
+}
+
+// GitHubEnvironment is a GitHub deployment environment and the secrets it
+// receives.
+type GitHubEnvironment struct {
+	// Name is the environment name, as in the repository settings.
+	Name string `mapstructure:"name" yaml:"name"`
+
+	// Profile is the profile the environment's secrets are resolved with;
+	// the active profile if empty.
+	Profile string `mapstructure:"profile" yaml:"profile"`
+
+	// Secrets lists the environment's secrets, as in GitHubConfig.Secrets.
+	Secrets []string `mapstructure:"secrets" yaml:"secrets"`
+}
+
+// GitHubSecret maps a key of the resolved environment to a secret.
+type GitHubSecret struct {
+	// Name is the secret name.
+	Name string
+	// Key is the key whose value the secret holds.
+	Key string
+}
+
+// githubSecretName matches the names GitHub accepts for secrets.
+var githubSecretName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// githubRepo matches an owner/name repository.
+var githubRepo = regexp.MustCompile(`^[A-Za-z0-9-]+/[A-Za-z0-9._-]+$`)
+
+// ParseGitHubSecret parses a secret mapping: "NAME" writes key NAME as
+// secret NAME, and "NAME=KEY" writes key KEY as secret NAME.
+func ParseGitHubSecret(s string) (GitHubSecret, error) {
+	name, key, ok := strings.Cut(s, "=")
+	if !ok {
+		key = name
+	}
+	if !githubSecretName.MatchString(name) {
+		return GitHubSecret{}, fmt.Errorf("invalid secret name %q (letters, digits, and underscores, not starting with a digit)", name)
+	}
+	if strings.HasPrefix(strings.ToUpper(name), "GITHUB_") {
+		return GitHubSecret{}, fmt.Errorf("secret name %q must not start with GITHUB_", name)
+	}
+	if key == "" {
+		return GitHubSecret{}, fmt.Errorf("secret %q: key must not be empty", name)
+	}
+	return GitHubSecret{Name: name, Key: key}, nil
+}
+
+// ParseGitHubSecrets parses a list of secret mappings.
+func ParseGitHubSecrets(list []string) ([]GitHubSecret, error) {
+	secrets := make([]GitHubSecret, len(list))
+	for i, s := range list {
+		secret, err := ParseGitHubSecret(s)
+		if err != nil {
+			return nil, err
+		}
+		secrets[i] = secret
+	}
+	return secrets, nil
+}
+
+// validate returns problems with the github section: an invalid repo,
+// invalid or duplicate secrets, and missing or duplicate environments.
+func (g GitHubConfig) validate() []string {
+	var errs []string
+	if g.Repo != "" && !githubRepo.MatchString(g.Repo) {
+		errs = append(errs, fmt.Sprintf("github.repo: invalid repository %q (expected owner/name)", g.Repo))
+	}
+	errs = append(errs, validateGitHubSecrets("github.secrets", g.Secrets)...)
+
+	seen := make(map[string]bool, len(g.Environments))
+	for i, env := range g.Environments {
+		field := fmt.Sprintf("github.environments[%d]", i)
+		switch name := strings.ToLower(env.Name); {
+		case env.Name == "":
+			errs = append(errs, field+": name is required")
+		case seen[name]:
+			errs = append(errs, fmt.Sprintf("%s: duplicate environment %q", field, env.Name))
+		default:
+			seen[name] = true
+		}
+		errs = append(errs, validateGitHubSecrets(field+".secrets", env.Secrets)...)
+	}
+	return errs
+}
+
+// validateGitHubSecrets returns problems with the secret mappings of field.
+// Secret names are case-insensitive on GitHub.
+func validateGitHubSecrets(field string, list []string) []string {
+	var errs []string
+	seen := make(map[string]bool, len(list))
+	for i, s := range list {
+		secret, err := ParseGitHubSecret(s)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s[%d]: %v", field, i, err))
+			continue
+		}
+		name := strings.ToUpper(secret.Name)
+		if seen[name] {
+			errs = append(errs, fmt.Sprintf("%s[%d]: duplicate secret %q", field, i, secret.Name))
+		}
+		seen[name] = true
+	}
+	return errs
+}
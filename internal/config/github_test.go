@@ -0,0 +1,77 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGitHubSecret(t *testing.T) {
+	for in, want := range map[string]GitHubSecret{
+		"API_KEY":              {Name: "API_KEY", Key: "API_KEY"},
+		"PROD_DB=DATABASE_URL": {Name: "PROD_DB", Key: "DATABASE_URL"},
+	} {
+		got, err := ParseGitHubSecret(in)
+		if err != nil || got != want {
+			t.Errorf("ParseGitHubSecret(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+
+	for _, bad := range []string{"", "1KEY", "MY-KEY", "GITHUB_TOKEN", "NAME="} {
+		if _, err := ParseGitHubSecret(bad); err == nil {
+			t.Errorf("ParseGitHubSecret(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestLoad_GitHub(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, dir, FullFileName, `project: app
+github:
+  repo: acme/shop
+  secrets: [NPM_TOKEN]
+  environments:
+    - name: Production
+      profile: prod
+      secrets: ["DB=DATABASE_URL"]
+`)
+
+	cfg, _, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	gh := cfg.GitHub
+	if gh.Repo != "acme/shop" || strings.Join(gh.Secrets, ",") != "NPM_TOKEN" || len(gh.Environments) != 1 {
+		t.Fatalf("github = %+v", gh)
+	}
+	if env := gh.Environments[0]; env.Name != "Production" || env.Profile != "prod" || strings.Join(env.Secrets, ",") != "DB=DATABASE_URL" {
+		t.Errorf("environment = %+v", env)
+	}
+}
+
+func TestValidate_GitHub(t *testing.T) {
+	cfg := &Config{Project: "app", EnvFile: ".env", LocalFile: ".env.local", GitHub: GitHubConfig{
+		Repo:    "shop",
+		Secrets: []string{"A", "a", "GITHUB_X"},
+		Environments: []GitHubEnvironment{
+			{Name: "prod", Secrets: []string{"A"}},
+			{Name: "Prod"},
+			{},
+		},
+	}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{
+		`github.repo: invalid repository "shop"`,
+		`github.secrets[1]: duplicate secret "a"`,
+		`github.secrets[2]: secret name "GITHUB_X" must not start with GITHUB_`,
+		`github.environments[1]: duplicate environment "Prod"`,
+		`github.environments[2]: name is required`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%v", want, err)
+		}
+	}
+}
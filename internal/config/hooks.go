@@ -0,0 +1,97 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hooksTrustDirName is the directory, under GlobalConfigDir, that records
+// the project hooks the user has allowed to run.
+const hooksTrustDirName = "trusted-hooks"
+
+// Hash returns a digest of the hook commands. Any change to a command
+// changes the digest.
+func (h HooksConfig) Hash() string {
+	sum := sha256.Sum256([]byte(h.PreResolve + "\x00" + h.PostResolve))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsEmpty reports whether no hook is configured.
+func (h HooksConfig) IsEmpty() bool {
+	return h.PreResolve == "" && h.PostResolve == ""
+}
+
+// hooksTrustPath returns the file recording the trusted hooks of the
+// project in projectDir.
+func hooksTrustPath(projectDir string) (string, error) {
+	dir := GlobalConfigDir()
+	if dir == "" {
+		return "", fmt.Errorf("cannot determine the global config directory")
+	}
+	abs, err := filepath.Abs(projectDir)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, hooksTrustDirName, hex.EncodeToString(sum[:])), nil
+}
+
+// TrustHooks records hooks as allowed to run for the project in projectDir,
+// until they change.
+func TrustHooks(projectDir string, hooks HooksConfig) error {
+	path, err := hooksTrustPath(projectDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(hooks.Hash()+"\n"), 0o600)
+}
+
+// UntrustHooks removes the record of the allowed hooks of the project in
+// projectDir. It is not an error if there is none.
+func UntrustHooks(projectDir string) error {
+	path, err := hooksTrustPath(projectDir)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// HooksTrusted reports whether hooks may run for the project in projectDir.
+// Hooks that all come from the global config may always run; others only
+// once TrustHooks has recorded these exact commands for the project, so
+// that a pulled change to a committed .envref.yaml never runs unreviewed.
+func HooksTrusted(projectDir string, hooks HooksConfig) (bool, error) {
+	global, err := loadGlobalConfig()
+	if err != nil {
+		return false, err
+	}
+	if global != nil &&
+		(hooks.PreResolve == "" || hooks.PreResolve == global.Hooks.PreResolve) &&
+		(hooks.PostResolve == "" || hooks.PostResolve == global.Hooks.PostResolve) {
+		return true, nil
+	}
+
+	path, err := hooksTrustPath(projectDir)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(data)) == hooks.Hash(), nil
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestHooksTrusted(t *testing.T) {
+	t.Setenv("ENVREF_CONFIG_DIR", t.TempDir())
+	projectDir := t.TempDir()
+	hooks := HooksConfig{PreResolve: "vault login"}
+
+	if ok, err := HooksTrusted(projectDir, hooks); err != nil || ok {
+		t.Fatalf("HooksTrusted before TrustHooks = %v, %v; want false", ok, err)
+	}
+	if err := TrustHooks(projectDir, hooks); err != nil {
+		t.Fatalf("TrustHooks: %v", err)
+	}
+	if ok, err := HooksTrusted(projectDir, hooks); err != nil || !ok {
+		t.Fatalf("HooksTrusted after TrustHooks = %v, %v; want true", ok, err)
+	}
+
+	changed := HooksConfig{PreResolve: "vault login", PostResolve: "curl evil.example"}
+	if ok, _ := HooksTrusted(projectDir, changed); ok {
+		t.Error("changed hooks should not be trusted")
+	}
+	if ok, _ := HooksTrusted(t.TempDir(), hooks); ok {
+		t.Error("hooks should only be trusted for the project they were allowed in")
+	}
+
+	if err := UntrustHooks(projectDir); err != nil {
+		t.Fatalf("UntrustHooks: %v", err)
+	}
+	if ok, _ := HooksTrusted(projectDir, hooks); ok {
+		t.Error("hooks should not be trusted after UntrustHooks")
+	}
+	if err := UntrustHooks(projectDir); err != nil {
+		t.Errorf("UntrustHooks without a record: %v", err)
+	}
+}
+
+func TestHooksTrusted_Global(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ENVREF_CONFIG_DIR", dir)
+	writeFile(t, dir, GlobalFileName, "hooks:\n  pre_resolve: vault login\n")
+
+	if ok, err := HooksTrusted(t.TempDir(), HooksConfig{PreResolve: "vault login"}); err != nil || !ok {
+		t.Errorf("global hooks should be trusted, got %v, %v", ok, err)
+	}
+	if ok, _ := HooksTrusted(t.TempDir(), HooksConfig{PreResolve: "vault login", PostResolve: "make notify"}); ok {
+		t.Error("a project hook next to global ones should not be trusted")
+	}
+}
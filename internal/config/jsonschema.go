@@ -0,0 +1,269 @@
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/xcke/envref/internal/suggest"
+	"go.yaml.in/yaml/v3"
+)
+
+// jsonSchemaData is the published JSON Schema for .envref.yaml. Editors
+// can use it for completion; CheckFile uses it to report unknown fields and
+// type mismatches that Viper silently ignores.
+//
+//go:embed envref.schema.json
+var jsonSchemaData []byte
+
+// JSONSchema returns the JSON Schema describing .envref.yaml.
+func JSONSchema() []byte {
+	out := make([]byte, len(jsonSchemaData))
+	copy(out, jsonSchemaData)
+	return out
+}
+
+// SchemaError is a single violation of the config JSON Schema, located at a
+// line and column of the YAML source.
+type SchemaError struct {
+	// Line is the 1-based line of the offending node.
+	Line int
+	// Column is the 1-based column of the offending node.
+	Column int
+	// Path is the dotted path to the offending node (e.g., "backends[0].name").
+	Path string
+	// Message describes the problem.
+	Message string
+}
+
+// Error returns the error formatted as "line:column: path: message".
+func (e SchemaError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Path, e.Message)
+}
+
+// jsonSchema is the subset of JSON Schema used by envref.schema.json:
+// type, properties, additionalProperties, required, items, enum, and $ref
+// to a schema under definitions.
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties *additionalProperties  `json:"additionalProperties"`
+	Required             []string               `json:"required"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []string               `json:"enum"`
+	Ref                  string                 `json:"$ref"`
+	Definitions          map[string]*jsonSchema `json:"definitions"`
+}
+
+// definitionsPrefix is the only $ref form envref.schema.json uses.
+const definitionsPrefix = "#/definitions/"
+
+// resolveRef returns the schema s refers to, or s itself if it has no $ref.
+func (s *jsonSchema) resolveRef(root *jsonSchema) *jsonSchema {
+	if s.Ref == "" || !strings.HasPrefix(s.Ref, definitionsPrefix) {
+		return s
+	}
+	if def, ok := root.Definitions[strings.TrimPrefix(s.Ref, definitionsPrefix)]; ok {
+		return def
+	}
+	return s
+}
+
+// schemaTypes holds the "type" keyword, which may be a string or a list.
+type schemaTypes []string
+
+// UnmarshalJSON accepts either a single type name or a list of names.
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// additionalProperties holds the "additionalProperties" keyword, which is
+// either a boolean or a schema for the extra values.
+type additionalProperties struct {
+	allowed bool
+	schema  *jsonSchema
+}
+
+// UnmarshalJSON accepts either a boolean or a schema object.
+func (a *additionalProperties) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		a.allowed = allowed
+		return nil
+	}
+	a.allowed = true
+	return json.Unmarshal(data, &a.schema)
+}
+
+// CheckFile reads the config file at path and checks it against the JSON
+// Schema. It returns one SchemaError per violation, sorted by position, or
+// an error if the file cannot be read or is not valid YAML.
+func CheckFile(path string) ([]SchemaError, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	return CheckYAML(data)
+}
+
+// CheckYAML checks YAML config data against the JSON Schema.
+func CheckYAML(data []byte) ([]SchemaError, error) {
+	var root jsonSchema
+	if err := json.Unmarshal(jsonSchemaData, &root); err != nil {
+		return nil, fmt.Errorf("parsing config JSON schema: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config YAML: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		// An empty file has no fields to check.
+		return nil, nil
+	}
+
+	var errs []SchemaError
+	checkNode(&root, &root, doc.Content[0], "", &errs)
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+	return errs, nil
+}
+
+// checkNode validates node against s, appending violations to errs. root
+// holds the definitions that $ref points to.
+func checkNode(root, s *jsonSchema, node *yaml.Node, path string, errs *[]SchemaError) {
+	s = s.resolveRef(root)
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	report := func(n *yaml.Node, p, format string, args ...interface{}) {
+		*errs = append(*errs, SchemaError{Line: n.Line, Column: n.Column, Path: p, Message: fmt.Sprintf(format, args...)})
+	}
+
+	actual := nodeType(node)
+	if len(s.Type) > 0 && !typeMatches(s.Type, actual) {
+		report(node, path, "expected %s, got %s", strings.Join(s.Type, " or "), actual)
+		return
+	}
+
+	if len(s.Enum) > 0 && node.Kind == yaml.ScalarNode && !containsString(s.Enum, node.Value) {
+		report(node, path, "invalid value %q (allowed: %s)", node.Value, strings.Join(s.Enum, ", "))
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		seen := make(map[string]bool, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			key := keyNode.Value
+			seen[key] = true
+			childPath := joinPath(path, key)
+
+			if prop, ok := s.Properties[key]; ok {
+				checkNode(root, prop, valueNode, childPath, errs)
+				continue
+			}
+			if s.AdditionalProperties == nil || s.AdditionalProperties.allowed {
+				if s.AdditionalProperties != nil && s.AdditionalProperties.schema != nil {
+					checkNode(root, s.AdditionalProperties.schema, valueNode, childPath, errs)
+				}
+				continue
+			}
+			report(keyNode, path, "unknown field %q%s", key, suggest.FormatSuggestion(suggest.Keys(key, propertyNames(s))))
+		}
+		for _, req := range s.Required {
+			if !seen[req] {
+				report(node, path, "missing required field %q", req)
+			}
+		}
+
+	case yaml.SequenceNode:
+		if s.Items == nil {
+			return
+		}
+		for i, item := range node.Content {
+			checkNode(root, s.Items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+// nodeType returns the JSON Schema type name for a YAML node.
+func nodeType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.Tag {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	default:
+		return "string"
+	}
+}
+
+// typeMatches reports whether actual satisfies one of the allowed types.
+// An integer satisfies "number", as in JSON Schema.
+func typeMatches(allowed []string, actual string) bool {
+	for _, t := range allowed {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// propertyNames returns the sorted property names declared by s.
+func propertyNames(s *jsonSchema) []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// joinPath appends key to a dotted path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchema_IsValidJSON(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(JSONSchema(), &doc); err != nil {
+		t.Fatalf("JSONSchema is not valid JSON: %v", err)
+	}
+}
+
+func TestJSONSchema_CoversConfigFields(t *testing.T) {
+	var root jsonSchema
+	if err := json.Unmarshal(JSONSchema(), &root); err != nil {
+		t.Fatalf("parsing schema: %v", err)
+	}
+
+	typ := reflect.TypeOf(Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		tag := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if _, ok := root.Properties[tag]; !ok {
+			t.Errorf("Config field %s (yaml %q) is missing from envref.schema.json", typ.Field(i).Name, tag)
+		}
+	}
+}
+
+func TestCheckYAML_Valid(t *testing.T) {
+	data := `project: myapp
+env_file: .env
+local_file: .env.local
+active_profile: staging
+backends:
+  - name: keychain
+  - name: vault
+    type: hashicorp-vault
+    config:
+      addr: https://vault.example.com
+      port: 8200
+      tls: true
+aliases:
+  secrets: [vault, keychain]
+profiles:
+  staging:
+    env_file: .env.staging
+  production:
+team:
+  - name: alice
+    public_key: age1abc
+schema:
+  PORT: { type: port, required: true }
+  API_KEY: { ref: true }
+`
+	errs, err := CheckYAML([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
+
+func TestCheckYAML_Empty(t *testing.T) {
+	errs, err := CheckYAML(nil)
+	if err != nil || len(errs) != 0 {
+		t.Errorf("CheckYAML(empty) = %v, %v; want no errors", errs, err)
+	}
+}
+
+func TestCheckYAML_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "unknown top-level field with suggestion",
+			data: "project: myapp\nactiv_profile: dev\n",
+			want: `2:1: unknown field "activ_profile"; did you mean active_profile?`,
+		},
+		{
+			name: "unknown backend field",
+			data: "project: myapp\nbackends:\n  - name: keychain\n    typ: keychain\n",
+			want: `4:5: backends[0]: unknown field "typ"; did you mean type?`,
+		},
+		{
+			name: "missing required field",
+			data: "project: myapp\nteam:\n  - name: alice\n",
+			want: `3:5: team[0]: missing required field "public_key"`,
+		},
+		{
+			name: "wrong type",
+			data: "project: myapp\nbackends:\n  name: keychain\n",
+			want: `3:3: backends: expected array, got object`,
+		},
+		{
+			name: "scalar instead of string",
+			data: "project: [a, b]\n",
+			want: `1:10: project: expected string, got array`,
+		},
+		{
+			name: "invalid enum value",
+			data: "project: myapp\nschema:\n  PORT:\n    type: integer\n",
+			want: `4:11: schema.PORT.type: invalid value "integer"`,
+		},
+		{
+			name: "unknown schema rule field",
+			data: "project: myapp\nschema:\n  PORT:\n    requird: true\n",
+			want: `4:5: schema.PORT: unknown field "requird"; did you mean required?`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := CheckYAML([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(errs) != 1 {
+				t.Fatalf("expected 1 error, got %v", errs)
+			}
+			if got := errs[0].Error(); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("error = %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckYAML_InvalidYAML(t *testing.T) {
+	if _, err := CheckYAML([]byte("project: [unclosed\n")); err == nil {
+		t.Error("expected error for invalid YAML")
+	}
+}
+
+func TestCheckYAML_SortedByPosition(t *testing.T) {
+	data := "zzz: 1\nproject: myapp\naaa: 2\n"
+	errs, err := CheckYAML([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(errs) != 2 || errs[0].Line != 1 || errs[1].Line != 3 {
+		t.Errorf("expected errors on lines 1 and 3, got %v", errs)
+	}
+}
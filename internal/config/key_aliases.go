@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DeprecatedKey reports whether name is a deprecated alias in key_aliases,
+// and returns the key it was renamed to.
+func (c *Config) DeprecatedKey(name string) (key string, ok bool) {
+	for key, aliases := range c.KeyAliases {
+		for _, alias := range aliases {
+			if alias == name {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// validateKeyAliases returns the problems with the key_aliases block: each
+// alias must be non-empty and belong to a single key that is not itself an
+// alias.
+func (c *Config) validateKeyAliases() []string {
+	keys := make([]string, 0, len(c.KeyAliases))
+	for key := range c.KeyAliases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []string
+	owner := make(map[string]string)
+	for _, key := range keys {
+		for i, alias := range c.KeyAliases[key] {
+			switch prev, seen := owner[alias]; {
+			case alias == "":
+				errs = append(errs, fmt.Sprintf("key_aliases: %s[%d]: alias must not be empty", key, i))
+			case alias == key:
+				errs = append(errs, fmt.Sprintf("key_aliases: %s: a key cannot be its own alias", key))
+			case seen:
+				errs = append(errs, fmt.Sprintf("key_aliases: %s is an alias of both %s and %s", alias, prev, key))
+			default:
+				owner[alias] = key
+			}
+		}
+	}
+	for _, key := range keys {
+		if other, ok := owner[key]; ok {
+			errs = append(errs, fmt.Sprintf("key_aliases: %s is both a key and an alias of %s", key, other))
+		}
+	}
+	return errs
+}
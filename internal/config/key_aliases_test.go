@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConfig_DeprecatedKey(t *testing.T) {
+	cfg := &Config{KeyAliases: map[string][]string{
+		"DATABASE_URL": {"DB_URL", "PG_URL"},
+	}}
+
+	if key, ok := cfg.DeprecatedKey("PG_URL"); !ok || key != "DATABASE_URL" {
+		t.Errorf("DeprecatedKey(PG_URL) = %q, %v; want DATABASE_URL, true", key, ok)
+	}
+	if _, ok := cfg.DeprecatedKey("DATABASE_URL"); ok {
+		t.Error("a key should not be reported as deprecated")
+	}
+}
+
+func TestLoadFile_KeyAliases(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, `project: app
+key_aliases:
+  DATABASE_URL: [DB_URL]
+  Api_Key: [apiKey]
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if got := cfg.KeyAliases["Api_Key"]; len(got) != 1 || got[0] != "apiKey" {
+		t.Errorf("key_aliases = %v, want case-preserving names", cfg.KeyAliases)
+	}
+	if got := cfg.KeyAliases["DATABASE_URL"]; len(got) != 1 || got[0] != "DB_URL" {
+		t.Errorf("key_aliases = %v", cfg.KeyAliases)
+	}
+}
+
+func TestValidate_KeyAliases(t *testing.T) {
+	cfg := &Config{Project: "app", KeyAliases: map[string][]string{
+		"A": {"A", ""},
+		"B": {"OLD"},
+		"C": {"OLD", "B"},
+	}}
+
+	err := cfg.Validate()
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"A: a key cannot be its own alias",
+		"A[1]: alias must not be empty",
+		"OLD is an alias of both B and C",
+		"B is both a key and an alias of C",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q does not mention %q", msg, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KubernetesConfig configures "envref k8s sync", which writes the resolved
+// environment to a Kubernetes Secret. Flags override each field.
+type KubernetesConfig struct {
+	// Context is the kubeconfig context to use; empty means the current
+	// context.
+	Context string `mapstructure:"context" yaml:"context"`
+
+	// Namespace is the namespace of the Secret; empty means the context's
+	// namespace.
+	Namespace string `mapstructure:"namespace" yaml:"namespace"`
+
+	// Secret is the name of the Secret; empty means the project name.
+	Secret string `mapstructure:"secret" yaml:"secret"`
+
+	// Labels lists "key=value" labels set on the Secret.
+	Labels []string `mapstructure:"labels" yaml:"labels"`
+}
+
+// ParseLabels parses "key=value" labels into a map. It rejects labels
+// without "=" or with an empty key.
+func ParseLabels(labels []string) (map[string]string, error) {
+	m := make(map[string]string, len(labels))
+	for _, l := range labels {
+		key, value, ok := strings.Cut(l, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid label %q (expected key=value)", l)
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+// validate returns problems with the kubernetes section.
+func (k KubernetesConfig) validate() []string {
+	var errs []string
+	for i, l := range k.Labels {
+		if _, err := ParseLabels([]string{l}); err != nil {
+			errs = append(errs, fmt.Sprintf("kubernetes.labels[%d]: %v", i, err))
+		}
+	}
+	return errs
+}
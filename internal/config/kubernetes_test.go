@@ -0,0 +1,55 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	got, err := ParseLabels([]string{"app=api", "tier=", "app.kubernetes.io/part-of=shop"})
+	if err != nil {
+		t.Fatalf("ParseLabels: %v", err)
+	}
+	if len(got) != 3 || got["app"] != "api" || got["tier"] != "" || got["app.kubernetes.io/part-of"] != "shop" {
+		t.Errorf("got %v", got)
+	}
+
+	for _, bad := range []string{"app", "=api"} {
+		if _, err := ParseLabels([]string{bad}); err == nil {
+			t.Errorf("ParseLabels(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestLoad_Kubernetes(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, dir, FullFileName, `project: app
+kubernetes:
+  namespace: shop
+  labels: ["app=api"]
+os:
+  linux:
+    backends:
+      - name: vault
+  darwin:
+    backends:
+      - name: keychain
+`)
+
+	cfg, _, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Kubernetes.Namespace != "shop" || strings.Join(cfg.Kubernetes.Labels, ",") != "app=api" {
+		t.Errorf("kubernetes = %+v", cfg.Kubernetes)
+	}
+}
+
+func TestValidate_KubernetesLabels(t *testing.T) {
+	cfg := &Config{Project: "app", EnvFile: ".env", LocalFile: ".env.local", Kubernetes: KubernetesConfig{Labels: []string{"app=api", "broken"}}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `kubernetes.labels[1]: invalid label "broken"`) {
+		t.Fatalf("expected a label error, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KnownOS lists the operating system names accepted as keys of the os:
+// section. They match Go's runtime.GOOS values.
+var KnownOS = []string{"darwin", "linux", "windows", "freebsd", "openbsd", "netbsd"}
+
+// applyOS returns c with the os: section for goos merged over it, so one
+// committed file can, for example, use the keychain on macOS and the vault
+// elsewhere:
+//
+//	backends:
+//	  - name: vault
+//	os:
+//	  darwin:
+//	    backends:
+//	      - name: keychain
+//
+// The section is merged with the same rules as a project over the global
+// config: set scalars override, and lists and maps replace. The os: section
+// itself is kept so Validate can check the sections for other systems.
+func (c *Config) applyOS(goos string) *Config {
+	section, ok := c.OS[goos]
+	if !ok {
+		return c
+	}
+	// Unset file paths in the section must not clear the file's own.
+	if section.EnvFile == "" {
+		section.EnvFile = ".env"
+	}
+	if section.LocalFile == "" {
+		section.LocalFile = ".env.local"
+	}
+
+	merged := mergeConfigs(c, &section)
+	merged.Extends = c.Extends
+	merged.Workspace = c.Workspace
+	merged.Compose = c.Compose
+	merged.GitHub = c.GitHub
+	merged.Schema = c.Schema
+	merged.OS = c.OS
+	return merged
+}
+
+// validateOS returns problems with the os: section: unknown system names and
+// fields that cannot vary per system.
+func (c *Config) validateOS() []string {
+	var errs []string
+	names := make([]string, 0, len(c.OS))
+	for name := range c.OS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !containsString(KnownOS, name) {
+			errs = append(errs, fmt.Sprintf("os.%s: unknown operating system (known: %s)", name, strings.Join(KnownOS, ", ")))
+			continue
+		}
+		section := c.OS[name]
+		var fixed []string
+		if section.Project != "" {
+			fixed = append(fixed, "project")
+		}
+		if section.Extends != "" {
+			fixed = append(fixed, "extends")
+		}
+		if len(section.Team) > 0 {
+			fixed = append(fixed, "team")
+		}
+		if len(section.Workspace.Members) > 0 {
+			fixed = append(fixed, "workspace")
+		}
+		if len(section.Compose.Services) > 0 {
+			fixed = append(fixed, "compose")
+		}
+		if section.GitHub.Repo != "" || len(section.GitHub.Secrets) > 0 || len(section.GitHub.Environments) > 0 {
+			fixed = append(fixed, "github")
+		}
+		if len(section.OS) > 0 {
+			fixed = append(fixed, "os")
+		}
+		for _, field := range fixed {
+			errs = append(errs, fmt.Sprintf("os.%s: %s cannot be set per operating system", name, field))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLoadFile_OSOverride(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, `project: app
+env_file: .env.shared
+backends:
+  - name: vault
+aliases:
+  secrets: [vault]
+os:
+  `+runtime.GOOS+`:
+    active_profile: native
+    backends:
+      - name: keychain
+    aliases:
+      secrets: [keychain]
+  plan9:
+    backends:
+      - name: other
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(cfg.Backends) != 1 || cfg.Backends[0].Name != "keychain" {
+		t.Errorf("backends = %v, want [keychain]", cfg.Backends)
+	}
+	if got := cfg.Aliases["secrets"]; len(got) != 1 || got[0] != "keychain" {
+		t.Errorf("aliases.secrets = %v, want [keychain]", got)
+	}
+	if cfg.ActiveProfile != "native" {
+		t.Errorf("active_profile = %q, want native", cfg.ActiveProfile)
+	}
+	if cfg.Project != "app" || cfg.EnvFile != ".env.shared" || cfg.LocalFile != ".env.local" {
+		t.Errorf("unset fields must keep the file's values, got project=%q env_file=%q local_file=%q", cfg.Project, cfg.EnvFile, cfg.LocalFile)
+	}
+	if len(cfg.OS) != 2 {
+		t.Errorf("os sections should be kept for validation, got %d", len(cfg.OS))
+	}
+}
+
+func TestLoadFile_OSOverrideOtherSystem(t *testing.T) {
+	other := "windows"
+	if runtime.GOOS == other {
+		other = "linux"
+	}
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, "project: app\nbackends:\n  - name: vault\nos:\n  "+other+":\n    backends:\n      - name: keychain\n")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(cfg.Backends) != 1 || cfg.Backends[0].Name != "vault" {
+		t.Errorf("backends = %v, want [vault]", cfg.Backends)
+	}
+}
+
+func TestLoadFile_OSOverrideEncrypted(t *testing.T) {
+	ciphertext, err := EncryptValue("tok", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, "project: app\nos:\n  "+runtime.GOOS+":\n    backends:\n      - name: hcv\n        type: hashicorp-vault\n        config:\n          token: !encrypted "+ciphertext+"\n")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(cfg.Backends) != 1 || !cfg.Backends[0].IsEncrypted("token") {
+		t.Fatalf("expected encrypted token in OS backend, got %+v", cfg.Backends)
+	}
+}
+
+func TestValidate_OS(t *testing.T) {
+	tests := []struct {
+		name    string
+		os      map[string]Config
+		wantErr string
+	}{
+		{"valid", map[string]Config{"darwin": {Backends: []BackendConfig{{Name: "keychain"}}}}, ""},
+		{"unknown os", map[string]Config{"macos": {}}, "os.macos: unknown operating system"},
+		{"project", map[string]Config{"linux": {Project: "other"}}, "os.linux: project cannot be set per operating system"},
+		{"nested os", map[string]Config{"linux": {OS: map[string]Config{"darwin": {}}}}, "os.linux: os cannot be set"},
+		{"compose", map[string]Config{"linux": {Compose: ComposeConfig{Services: []ComposeService{{Name: "api"}}}}}, "os.linux: compose cannot be set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Project: "app", EnvFile: ".env", LocalFile: ".env.local", OS: tt.os}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckYAML_OSSection(t *testing.T) {
+	errs, err := CheckYAML([]byte("project: app\nos:\n  darwin:\n    backends:\n      - nme: keychain\n    project: x\n"))
+	if err != nil {
+		t.Fatalf("CheckYAML: %v", err)
+	}
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	joined := strings.Join(msgs, "\n")
+	if !strings.Contains(joined, `os.darwin.backends[0]: unknown field "nme"`) {
+		t.Errorf("expected backend field error via $ref, got:\n%s", joined)
+	}
+	if !strings.Contains(joined, `os.darwin: unknown field "project"`) {
+		t.Errorf("expected project rejected in os section, got:\n%s", joined)
+	}
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// RequiresRef reports whether the require_refs policy says key must hold a
+// ref:// reference in committed env files, and the first pattern that
+// matches it. Patterns are path.Match globs matched against the key name.
+func (c *Config) RequiresRef(key string) (pattern string, ok bool) {
+	for _, p := range c.RequireRefs {
+		if matched, _ := path.Match(p, key); matched {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// validateRequireRefs returns the problems with the require_refs list.
+func (c *Config) validateRequireRefs() []string {
+	var errs []string
+	for i, p := range c.RequireRefs {
+		if p == "" {
+			errs = append(errs, fmt.Sprintf("require_refs[%d]: pattern must not be empty", i))
+		} else if _, err := path.Match(p, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("require_refs[%d]: invalid key pattern %q", i, p))
+		}
+	}
+	return errs
+}
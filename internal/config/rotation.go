@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseRotationInterval parses a rotation interval: a number of days
+// ("90d"), a number of weeks ("12w"), or a Go duration ("720h").
+func ParseRotationInterval(s string) (time.Duration, error) {
+	var d time.Duration
+	switch {
+	case strings.HasSuffix(s, "d"), strings.HasSuffix(s, "w"):
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid rotation interval %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+		if strings.HasSuffix(s, "w") {
+			d *= 7
+		}
+	default:
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid rotation interval %q (use e.g. 90d, 12w, or 720h)", s)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("rotation interval must be positive, got %q", s)
+	}
+	return d, nil
+}
+
+// RotationPolicy returns the rotation interval that applies to a secret key,
+// and the pattern it came from. Patterns are path.Match globs matched
+// against the key name. An exact key name takes precedence over globs; among
+// globs the longest (most specific) pattern wins. ok is false if no pattern
+// matches or the matching interval is invalid.
+func (c *Config) RotationPolicy(key string) (interval time.Duration, pattern string, ok bool) {
+	if _, exact := c.Rotation[key]; exact {
+		pattern = key
+	} else {
+		for _, p := range sortedPatterns(c.Rotation) {
+			if matched, _ := path.Match(p, key); matched {
+				pattern = p
+				break
+			}
+		}
+	}
+	if pattern == "" {
+		return 0, "", false
+	}
+	interval, err := ParseRotationInterval(c.Rotation[pattern])
+	if err != nil {
+		return 0, "", false
+	}
+	return interval, pattern, true
+}
+
+// validateRotation returns the problems with the rotation block.
+func (c *Config) validateRotation() []string {
+	var errs []string
+	for _, pattern := range sortedPatterns(c.Rotation) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("rotation: invalid key pattern %q", pattern))
+		}
+		if _, err := ParseRotationInterval(c.Rotation[pattern]); err != nil {
+			errs = append(errs, fmt.Sprintf("rotation: %s: %v", pattern, err))
+		}
+	}
+	return errs
+}
+
+// sortedPatterns returns the key patterns of a rotation or acl block,
+// longest first and then alphabetically, so that more specific patterns are
+// tried first.
+func sortedPatterns[V any](block map[string]V) []string {
+	patterns := make([]string, 0, len(block))
+	for p := range block {
+		patterns = append(patterns, p)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	return patterns
+}
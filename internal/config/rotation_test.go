@@ -0,0 +1,101 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRotationInterval(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"90d", 90 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"720h", 720 * time.Hour, false},
+		{"90", 0, true},
+		{"d", 0, true},
+		{"0d", 0, true},
+		{"-5h", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRotationInterval(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRotationInterval(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRotationInterval(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_RotationPolicy(t *testing.T) {
+	cfg := &Config{Rotation: map[string]string{
+		"*":            "365d",
+		"API_*":        "90d",
+		"API_INTERNAL": "30d",
+		"STRIPE_*":     "bogus",
+	}}
+
+	tests := []struct {
+		key         string
+		wantPattern string
+		want        time.Duration
+		wantOK      bool
+	}{
+		{"API_INTERNAL", "API_INTERNAL", 30 * 24 * time.Hour, true},
+		{"API_KEY", "API_*", 90 * 24 * time.Hour, true},
+		{"DB_PASSWORD", "*", 365 * 24 * time.Hour, true},
+		{"STRIPE_KEY", "", 0, false},
+	}
+	for _, tt := range tests {
+		got, pattern, ok := cfg.RotationPolicy(tt.key)
+		if ok != tt.wantOK || pattern != tt.wantPattern || got != tt.want {
+			t.Errorf("RotationPolicy(%q) = %v, %q, %v; want %v, %q, %v", tt.key, got, pattern, ok, tt.want, tt.wantPattern, tt.wantOK)
+		}
+	}
+
+	if _, _, ok := (&Config{}).RotationPolicy("API_KEY"); ok {
+		t.Error("RotationPolicy without a rotation block should not match")
+	}
+}
+
+func TestLoadFile_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, FullFileName, `project: app
+rotation:
+  API_*: 90d
+  Db_Password: 12w
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if cfg.Rotation["API_*"] != "90d" || cfg.Rotation["Db_Password"] != "12w" {
+		t.Errorf("rotation = %v, want case-preserving keys", cfg.Rotation)
+	}
+}
+
+func TestValidate_Rotation(t *testing.T) {
+	cfg := &Config{Project: "app", Rotation: map[string]string{
+		"API_*": "soon",
+		"[":     "90d",
+	}}
+
+	err := cfg.Validate()
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{`invalid rotation interval "soon"`, `invalid key pattern "["`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q does not mention %q", msg, want)
+		}
+	}
+}
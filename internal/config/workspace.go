@@ -0,0 +1,148 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WorkspaceConfig declares the member projects of a monorepo workspace.
+type WorkspaceConfig struct {
+	// Members lists the projects in the workspace, in display order.
+	Members []WorkspaceMember `mapstructure:"members" yaml:"members"`
+}
+
+// WorkspaceMember is a project directory within a workspace.
+type WorkspaceMember struct {
+	// Path is the member directory, relative to the workspace root.
+	Path string `mapstructure:"path" yaml:"path"`
+
+	// Project is the member's project name. It overrides the project of the
+	// member's own .envref.yaml, and is required for members without one.
+	Project string `mapstructure:"project" yaml:"project"`
+}
+
+// Name returns the label used for the member: its project name if
+// declared, otherwise its path.
+func (m WorkspaceMember) Name() string {
+	if m.Project != "" {
+		return m.Project
+	}
+	return filepath.ToSlash(filepath.Clean(m.Path))
+}
+
+// ErrNoWorkspace is returned by FindWorkspace when no config declaring
+// workspace members is found.
+var ErrNoWorkspace = errors.New("no workspace found (no .envref.yaml with workspace.members)")
+
+// validate returns problems with the member list: missing, absolute, or
+// escaping paths, and duplicate paths or names.
+func (w WorkspaceConfig) validate() []string {
+	var errs []string
+	paths := make(map[string]bool, len(w.Members))
+	names := make(map[string]bool, len(w.Members))
+	for i, m := range w.Members {
+		clean := filepath.Clean(m.Path)
+		switch {
+		case m.Path == "":
+			errs = append(errs, fmt.Sprintf("workspace.members[%d]: path is required", i))
+			continue
+		case filepath.IsAbs(m.Path):
+			errs = append(errs, fmt.Sprintf("workspace.members[%d]: path must be relative, got %q", i, m.Path))
+		case clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)):
+			errs = append(errs, fmt.Sprintf("workspace.members[%d]: path %q is outside the workspace root", i, m.Path))
+		case paths[clean]:
+			errs = append(errs, fmt.Sprintf("workspace.members[%d]: duplicate path %q", i, m.Path))
+		}
+		paths[clean] = true
+
+		if m.Project != "" && strings.ContainsAny(m.Project, "/\\") {
+			errs = append(errs, fmt.Sprintf("workspace.members[%d]: project name must not contain path separators (/ or \\)", i))
+		}
+		if names[m.Name()] {
+			errs = append(errs, fmt.Sprintf("workspace.members[%d]: duplicate member name %q", i, m.Name()))
+		}
+		names[m.Name()] = true
+	}
+	return errs
+}
+
+// FindWorkspace searches from startDir upward for a .envref.yaml declaring
+// workspace members in its own workspace block, skipping member configs
+// along the way. It returns the loaded root config and the workspace root
+// directory, or ErrNoWorkspace.
+func FindWorkspace(startDir string) (*Config, string, error) {
+	dir := startDir
+	for {
+		configDir, err := findConfigDir(dir)
+		if errors.Is(err, ErrNotFound) {
+			return nil, "", ErrNoWorkspace
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		cfg, err := loadFile(filepath.Join(configDir, FullFileName))
+		if err != nil {
+			return nil, "", err
+		}
+		if len(cfg.Workspace.Members) > 0 {
+			root, rootDir, err := Load(configDir)
+			if err != nil {
+				return nil, "", err
+			}
+			return root, rootDir, nil
+		}
+
+		parent := filepath.Dir(configDir)
+		if parent == configDir {
+			return nil, "", ErrNoWorkspace
+		}
+		dir = parent
+	}
+}
+
+// LoadMember loads the effective config of a workspace member and returns
+// it with the member's directory. A member with its own .envref.yaml is
+// loaded like a project (global config, extends, and environment
+// overrides apply); a member without one inherits the root config. In both
+// cases the member's declared project name takes precedence.
+func LoadMember(root *Config, rootDir string, m WorkspaceMember) (*Config, string, error) {
+	dir := filepath.Join(rootDir, m.Path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("workspace member %s: %w", m.Name(), err)
+	}
+	if !info.IsDir() {
+		return nil, "", fmt.Errorf("workspace member %s: %s is not a directory", m.Name(), dir)
+	}
+
+	var cfg *Config
+	if _, statErr := os.Stat(filepath.Join(dir, FullFileName)); statErr == nil {
+		memberCfg, err := loadFileWithExtends(filepath.Join(dir, FullFileName), nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("workspace member %s: %w", m.Name(), err)
+		}
+		globalCfg, err := loadGlobalConfig()
+		if err != nil {
+			return nil, "", err
+		}
+		cfg = mergeConfigs(globalCfg, memberCfg)
+		applyBranchProfile(cfg, dir)
+		applyEnvOverrides(cfg)
+	} else {
+		inherited := *root
+		cfg = &inherited
+	}
+	cfg.Workspace = WorkspaceConfig{}
+	if m.Project != "" {
+		cfg.Project = m.Project
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, "", fmt.Errorf("workspace member %s: %w", m.Name(), err)
+	}
+	return cfg, dir, nil
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupWorkspace writes a workspace root with an "api" member that has its
+// own config and a "web" member that inherits the root config.
+func setupWorkspace(t *testing.T) string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	writeFile(t, root, FullFileName, `project: mono
+backends:
+  - name: keychain
+workspace:
+  members:
+    - path: services/api
+    - path: services/web
+      project: web
+`)
+	api := filepath.Join(root, "services", "api")
+	web := filepath.Join(root, "services", "web")
+	for _, dir := range []string{api, web} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, api, FullFileName, "project: api\nenv_file: .env.api\n")
+	return root
+}
+
+func TestFindWorkspace_FromMemberDir(t *testing.T) {
+	root := setupWorkspace(t)
+
+	cfg, rootDir, err := FindWorkspace(filepath.Join(root, "services", "api"))
+	if err != nil {
+		t.Fatalf("FindWorkspace: %v", err)
+	}
+	if rootDir != root {
+		t.Errorf("rootDir = %q, want %q", rootDir, root)
+	}
+	if len(cfg.Workspace.Members) != 2 {
+		t.Fatalf("members = %d, want 2", len(cfg.Workspace.Members))
+	}
+	if got := cfg.Workspace.Members[0].Name(); got != "services/api" {
+		t.Errorf("Members[0].Name() = %q, want services/api", got)
+	}
+	if got := cfg.Workspace.Members[1].Name(); got != "web" {
+		t.Errorf("Members[1].Name() = %q, want web", got)
+	}
+}
+
+func TestFindWorkspace_MemberExtendsRoot(t *testing.T) {
+	root := setupWorkspace(t)
+	api := filepath.Join(root, "services", "api")
+	writeFile(t, api, FullFileName, "project: api\nextends: ../../"+FullFileName+"\n")
+
+	_, rootDir, err := FindWorkspace(api)
+	if err != nil {
+		t.Fatalf("FindWorkspace: %v", err)
+	}
+	if rootDir != root {
+		t.Errorf("rootDir = %q, want %q", rootDir, root)
+	}
+}
+
+func TestFindWorkspace_NotFound(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, dir, FullFileName, "project: solo\n")
+
+	_, _, err := FindWorkspace(dir)
+	if !errors.Is(err, ErrNoWorkspace) {
+		t.Fatalf("expected ErrNoWorkspace, got %v", err)
+	}
+}
+
+func TestLoadMember(t *testing.T) {
+	root := setupWorkspace(t)
+	rootCfg, rootDir, err := FindWorkspace(root)
+	if err != nil {
+		t.Fatalf("FindWorkspace: %v", err)
+	}
+
+	t.Run("own config", func(t *testing.T) {
+		cfg, dir, err := LoadMember(rootCfg, rootDir, rootCfg.Workspace.Members[0])
+		if err != nil {
+			t.Fatalf("LoadMember: %v", err)
+		}
+		if cfg.Project != "api" || cfg.EnvFile != ".env.api" {
+			t.Errorf("got project=%q env_file=%q", cfg.Project, cfg.EnvFile)
+		}
+		if len(cfg.Backends) != 0 {
+			t.Errorf("member with own config should not inherit root backends, got %v", cfg.Backends)
+		}
+		if dir != filepath.Join(root, "services", "api") {
+			t.Errorf("dir = %q", dir)
+		}
+	})
+
+	t.Run("inherits root config", func(t *testing.T) {
+		cfg, _, err := LoadMember(rootCfg, rootDir, rootCfg.Workspace.Members[1])
+		if err != nil {
+			t.Fatalf("LoadMember: %v", err)
+		}
+		if cfg.Project != "web" {
+			t.Errorf("project = %q, want web", cfg.Project)
+		}
+		if len(cfg.Backends) != 1 || cfg.Backends[0].Name != "keychain" {
+			t.Errorf("expected inherited keychain backend, got %v", cfg.Backends)
+		}
+		if len(cfg.Workspace.Members) != 0 {
+			t.Error("member config should not carry workspace members")
+		}
+		if rootCfg.Project != "mono" {
+			t.Errorf("root config modified: project = %q", rootCfg.Project)
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, _, err := LoadMember(rootCfg, rootDir, WorkspaceMember{Path: "services/gone", Project: "gone"})
+		if err == nil || !strings.Contains(err.Error(), "workspace member gone") {
+			t.Fatalf("expected member error, got %v", err)
+		}
+	})
+}
+
+func TestValidate_Workspace(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []WorkspaceMember
+		wantErr string
+	}{
+		{"valid", []WorkspaceMember{{Path: "a"}, {Path: "b", Project: "bee"}}, ""},
+		{"missing path", []WorkspaceMember{{Project: "x"}}, "path is required"},
+		{"absolute path", []WorkspaceMember{{Path: absTestPath("/srv/app")}}, "must be relative"},
+		{"escapes root", []WorkspaceMember{{Path: "../other"}}, "outside the workspace root"},
+		{"duplicate path", []WorkspaceMember{{Path: "a"}, {Path: "./a", Project: "other"}}, "duplicate path"},
+		{"duplicate name", []WorkspaceMember{{Path: "a", Project: "app"}, {Path: "b", Project: "app"}}, "duplicate member name"},
+		{"separator in project", []WorkspaceMember{{Path: "a", Project: "x/y"}}, "path separators"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{EnvFile: ".env", LocalFile: ".env.local", Workspace: WorkspaceConfig{Members: tt.members}}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
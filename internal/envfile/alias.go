@@ -0,0 +1,47 @@
+package envfile
+
+import "sort"
+
+// ApplyKeyAliases carries key renames through env. aliases maps each key to
+// the deprecated names it was renamed from. A key that is not set takes the
+// entry of the first of its aliases that is, and every alias that is not
+// set takes the entry of its key, so that consumers reading either name see
+// the same value. Names that are set explicitly are left as they are.
+//
+// The aliases that are set in env are returned, in key order, so that
+// callers can report their use as deprecated.
+func ApplyKeyAliases(env *Env, aliases map[string][]string) []string {
+	keys := make([]string, 0, len(aliases))
+	for key := range aliases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var deprecated []string
+	for _, key := range keys {
+		entry, ok := env.Get(key)
+		for _, alias := range aliases[key] {
+			old, set := env.Get(alias)
+			if !set {
+				continue
+			}
+			deprecated = append(deprecated, alias)
+			if !ok {
+				entry, ok = old, true
+				entry.Key = key
+				env.Set(entry)
+			}
+		}
+		if !ok {
+			continue
+		}
+		for _, alias := range aliases[key] {
+			if _, set := env.Get(alias); !set {
+				copied := entry
+				copied.Key = alias
+				env.Set(copied)
+			}
+		}
+	}
+	return deprecated
+}
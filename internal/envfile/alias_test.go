@@ -0,0 +1,62 @@
+package envfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyKeyAliases(t *testing.T) {
+	aliases := map[string][]string{
+		"DATABASE_URL": {"DB_URL", "PG_URL"},
+		"API_KEY":      {"OLD_API_KEY"},
+	}
+	tests := []struct {
+		name           string
+		input          string
+		want           map[string]string
+		wantDeprecated []string
+	}{
+		{
+			name:  "key set, aliases filled in",
+			input: "DATABASE_URL=postgres://db\n",
+			want:  map[string]string{"DATABASE_URL": "postgres://db", "DB_URL": "postgres://db", "PG_URL": "postgres://db"},
+		},
+		{
+			name:           "only a deprecated name set",
+			input:          "PG_URL=postgres://old\n",
+			want:           map[string]string{"DATABASE_URL": "postgres://old", "DB_URL": "postgres://old", "PG_URL": "postgres://old"},
+			wantDeprecated: []string{"PG_URL"},
+		},
+		{
+			name:           "explicit names kept",
+			input:          "DATABASE_URL=new\nDB_URL=old\nOLD_API_KEY=k\n",
+			want:           map[string]string{"DATABASE_URL": "new", "DB_URL": "old", "PG_URL": "new", "API_KEY": "k", "OLD_API_KEY": "k"},
+			wantDeprecated: []string{"OLD_API_KEY", "DB_URL"},
+		},
+		{
+			name:  "unrelated keys untouched",
+			input: "HOST=localhost\n",
+			want:  map[string]string{"HOST": "localhost"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, _, err := Read(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			deprecated := ApplyKeyAliases(env, aliases)
+			if !reflect.DeepEqual(deprecated, tt.wantDeprecated) {
+				t.Errorf("deprecated = %v, want %v", deprecated, tt.wantDeprecated)
+			}
+			got := make(map[string]string)
+			for _, e := range env.All() {
+				got[e.Key] = e.Value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("env = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
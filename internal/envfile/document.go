@@ -0,0 +1,104 @@
+// Package envfile — line-level view of .env files.
+package envfile
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xcke/envref/internal/parser"
+)
+
+// block is a top-level element of a .env file: a blank line, lines that
+// belong to no entry (detached comments, lines the parser ignores), or an
+// entry with the comment lines directly above it. Lines are kept as they
+// appear in the file, without line endings.
+type block struct {
+	blank bool
+	// lines are the block's lines, or the comment lines above entry.
+	lines []string
+	entry *parser.Entry
+	// entryLines are the lines of entry itself.
+	entryLines []string
+	// export is set when the entry has an "export " prefix.
+	export bool
+}
+
+// parseBlocks splits data, the contents of a .env file, into blocks. The
+// input must be UTF-8. Parse errors are returned as *parser.ParseError.
+func parseBlocks(data []byte) ([]block, error) {
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%w: only UTF-8 files can be rewritten", parser.ErrUnsupportedEncoding)
+	}
+	entries, _, err := parser.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	starts := make(map[int]int, len(entries))
+	for i, e := range entries {
+		starts[e.Line] = i
+	}
+
+	text := strings.TrimPrefix(string(data), "\xEF\xBB\xBF")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		lines = nil
+	}
+
+	var blocks []block
+	var pending []string // comment lines not yet attached to an entry
+	flush := func() {
+		if len(pending) > 0 {
+			blocks = append(blocks, block{lines: pending})
+			pending = nil
+		}
+	}
+	for n := 1; n <= len(lines); n++ {
+		trimmed := strings.TrimSpace(lines[n-1])
+		if i, ok := starts[n]; ok {
+			end := entries[i].EndLine
+			blocks = append(blocks, block{
+				lines:      pending,
+				entry:      &entries[i],
+				entryLines: lines[n-1 : end],
+				export:     strings.HasPrefix(trimmed, "export "),
+			})
+			pending = nil
+			n = end
+			continue
+		}
+		switch {
+		case trimmed == "":
+			flush()
+			blocks = append(blocks, block{blank: true})
+		case trimmed[0] == '#':
+			pending = append(pending, lines[n-1])
+		default:
+			flush()
+			blocks = append(blocks, block{lines: []string{lines[n-1]}})
+		}
+	}
+	flush()
+	return blocks, nil
+}
+
+// writeBlocks returns blocks as file contents, with each line ending in a
+// newline.
+func writeBlocks(blocks []block) []byte {
+	var b strings.Builder
+	for _, blk := range blocks {
+		if blk.blank {
+			b.WriteByte('\n')
+			continue
+		}
+		for _, line := range blk.lines {
+			b.WriteString(line + "\n")
+		}
+		for _, line := range blk.entryLines {
+			b.WriteString(line + "\n")
+		}
+	}
+	return []byte(b.String())
+}
@@ -4,6 +4,7 @@ package envfile
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -152,6 +153,53 @@ func (e *Env) HasAnyRefs() bool {
 	return false
 }
 
+// ApplySchemes rewrites values that use one of the given alternative URI
+// schemes (e.g., secret:// or op://) into canonical ref:// form, so the rest
+// of the pipeline treats them as references. It should run before
+// Interpolate so that ${scheme://...} interpolations are preserved.
+// Heredoc values are literal and left unchanged.
+func (e *Env) ApplySchemes(schemes ref.Schemes) {
+	if len(schemes) == 0 {
+		return
+	}
+	for _, key := range e.order {
+		entry := e.entries[key]
+		if entry.Quote == parser.QuoteHeredoc {
+			continue
+		}
+		rewritten := schemes.Rewrite(entry.Value)
+		if rewritten == entry.Value {
+			continue
+		}
+		entry.Value = rewritten
+		entry.IsRef = ref.IsRef(rewritten)
+		e.Set(entry)
+	}
+}
+
+// ApplyEncryption rewrites enc:// values into references that decrypt them
+// with the key stored at keyRef (see ref.EncryptedRef), so that they are
+// resolved like any other reference. It does nothing if keyRef is empty.
+// Heredoc values are literal and left unchanged.
+func (e *Env) ApplyEncryption(keyRef string) {
+	if keyRef == "" {
+		return
+	}
+	for _, key := range e.order {
+		entry := e.entries[key]
+		if entry.Quote == parser.QuoteHeredoc {
+			continue
+		}
+		rewritten, ok := ref.EncryptedRef(keyRef, entry.Value)
+		if !ok {
+			continue
+		}
+		entry.Value = rewritten
+		entry.IsRef = true
+		e.Set(entry)
+	}
+}
+
 // Load reads a .env file from disk and returns an Env with all entries.
 // Returns an error if the file cannot be opened or parsed.
 // Parse warnings (e.g., duplicate keys) are returned as the second value.
@@ -161,7 +209,7 @@ func Load(path string) (*Env, []parser.Warning, error) {
 		return nil, nil, fmt.Errorf("opening %s: %w", path, err)
 	}
 
-	entries, warnings, parseErr := parser.Parse(f)
+	env, warnings, parseErr := Read(f)
 	closeErr := f.Close()
 	if parseErr != nil {
 		return nil, warnings, fmt.Errorf("parsing %s: %w", path, parseErr)
@@ -169,6 +217,16 @@ func Load(path string) (*Env, []parser.Warning, error) {
 	if closeErr != nil {
 		return nil, warnings, fmt.Errorf("closing %s: %w", path, closeErr)
 	}
+	return env, warnings, nil
+}
+
+// Read parses .env content from r and returns an Env with all entries, as
+// Load does for a file. Parse warnings are returned as the second value.
+func Read(r io.Reader) (*Env, []parser.Warning, error) {
+	entries, warnings, err := parser.Parse(r)
+	if err != nil {
+		return nil, warnings, err
+	}
 
 	env := newEnvSized(len(entries))
 	for _, entry := range entries {
@@ -204,10 +262,21 @@ func Merge(base *Env, overlays ...*Env) *Env {
 		result.Set(base.entries[key])
 	}
 
-	// Apply overlays in order.
+	// Apply overlays in order. An overriding entry without annotations or a
+	// comment keeps those of the entry it replaces, so documentation and
+	// types declared in .env still apply to values overridden in .env.local.
 	for _, overlay := range overlays {
 		for _, key := range overlay.order {
-			result.Set(overlay.entries[key])
+			entry := overlay.entries[key]
+			if prev, ok := result.entries[key]; ok {
+				if entry.Annotations == nil {
+					entry.Annotations = prev.Annotations
+				}
+				if entry.Comment == "" {
+					entry.Comment = prev.Comment
+				}
+			}
+			result.Set(entry)
 		}
 	}
 
@@ -217,17 +286,72 @@ func Merge(base *Env, overlays ...*Env) *Env {
 // Write serializes the Env to a .env formatted file at the given path.
 // Entries are written in insertion order, one per line, as KEY=VALUE.
 // Values that contain spaces, quotes, or newlines are double-quoted with
-// appropriate escaping.
+// appropriate escaping. Entries that were parsed from a heredoc block are
+// written back as heredocs so large multiline values stay readable.
+// Leading comments and annotations ("# @name: value") are written above
+// their entry, and inline comments are kept at the end of the line.
 func (e *Env) Write(path string) error {
+	return os.WriteFile(path, e.Bytes(), 0o644)
+}
+
+// Bytes returns the Env serialized in .env format, exactly as Write would
+// write it to disk.
+func (e *Env) Bytes() []byte {
 	var b strings.Builder
 	for _, key := range e.order {
 		entry := e.entries[key]
+		if entry.Comment != "" {
+			for _, line := range strings.Split(entry.Comment, "\n") {
+				b.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+			}
+		}
+		for _, a := range entry.Annotations {
+			b.WriteString("# @" + a.Name + ": " + a.Value + "\n")
+		}
+		if entry.Quote == parser.QuoteHeredoc {
+			b.WriteString(formatHeredoc(key, entry.Value))
+			continue
+		}
 		b.WriteString(key)
 		b.WriteByte('=')
 		b.WriteString(formatValue(entry.Value))
+		if entry.InlineComment != "" {
+			b.WriteString(" # " + entry.InlineComment)
+		}
 		b.WriteByte('\n')
 	}
-	return os.WriteFile(path, []byte(b.String()), 0o644)}
+	return []byte(b.String())
+}
+
+// formatHeredoc returns a KEY<<DELIM heredoc block for the value. The
+// delimiter defaults to EOF and is suffixed with a counter if any line of
+// the value would otherwise terminate the block early.
+func formatHeredoc(key, value string) string {
+	lines := strings.Split(value, "\n")
+	delim := "EOF"
+	for n := 1; containsLine(lines, delim); n++ {
+		delim = fmt.Sprintf("EOF_%d", n)
+	}
+
+	var b strings.Builder
+	b.WriteString(key + "<<" + delim + "\n")
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(delim + "\n")
+	return b.String()
+}
+
+// containsLine reports whether any line, ignoring surrounding whitespace,
+// equals target.
+func containsLine(lines []string, target string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) == target {
+			return true
+		}
+	}
+	return false
+}
 
 // formatValue returns the value formatted for a .env file.
 // Simple values are returned as-is. Values containing spaces, newlines,
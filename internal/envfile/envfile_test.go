@@ -1,11 +1,14 @@
 package envfile
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/ref"
 )
 
 // writeFile is a test helper that creates a file with the given content.
@@ -119,6 +122,29 @@ func TestLoad(t *testing.T) {
 	})
 }
 
+func TestRead(t *testing.T) {
+	env, warnings, err := Read(strings.NewReader("FOO=bar\nFOO=baz\nURL=ref://secrets/url\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected 1 duplicate key warning, got %v", warnings)
+	}
+	if got := env.Keys(); len(got) != 2 || got[0] != "FOO" || got[1] != "URL" {
+		t.Errorf("Keys() = %v, want [FOO URL]", got)
+	}
+	if entry, _ := env.Get("FOO"); entry.Value != "baz" {
+		t.Errorf("FOO: got %q, want %q", entry.Value, "baz")
+	}
+	if entry, _ := env.Get("URL"); !entry.IsRef {
+		t.Error("URL: expected a reference")
+	}
+
+	if _, _, err := Read(strings.NewReader("FOO='unterminated")); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
 func TestLoadOptional(t *testing.T) {
 	dir := t.TempDir()
 
@@ -850,6 +876,117 @@ func TestWrite(t *testing.T) {
 	})
 }
 
+func TestWriteHeredoc(t *testing.T) {
+	t.Run("writes heredoc entries as heredocs", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, ".env")
+
+		env := NewEnv()
+		env.Set(parser.Entry{Key: "CERT", Value: "line1\nline2", Quote: parser.QuoteHeredoc})
+		env.Set(parser.Entry{Key: "NEXT", Value: "value"})
+
+		if err := env.Write(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading file: %v", err)
+		}
+		want := "CERT<<EOF\nline1\nline2\nEOF\nNEXT=value\n"
+		if string(content) != want {
+			t.Errorf("got %q, want %q", string(content), want)
+		}
+	})
+
+	t.Run("picks a delimiter not present in the value", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, ".env")
+
+		env := NewEnv()
+		env.Set(parser.Entry{Key: "DOC", Value: "a\nEOF\nb", Quote: parser.QuoteHeredoc})
+
+		if err := env.Write(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		loaded, _, err := Load(path)
+		if err != nil {
+			t.Fatalf("load: %v", err)
+		}
+		got, ok := loaded.Get("DOC")
+		if !ok {
+			t.Fatal("DOC missing after roundtrip")
+		}
+		if got.Value != "a\nEOF\nb" {
+			t.Errorf("got %q, want %q", got.Value, "a\nEOF\nb")
+		}
+	})
+}
+
+func TestWriteComments(t *testing.T) {
+	dir := t.TempDir()
+	input := "# Database settings\n#\n# host only\nDB_HOST=localhost # local dev\n# @type: int\nDB_PORT=5432\n"
+	path := writeFile(t, dir, ".env", input)
+
+	env, _, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	out := filepath.Join(dir, ".env.out")
+	if err := env.Write(out); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+	if string(content) != input {
+		t.Errorf("got %q, want %q", string(content), input)
+	}
+}
+
+func TestMergeKeepsBaseDocumentation(t *testing.T) {
+	base := NewEnv()
+	base.Set(parser.Entry{
+		Key:         "PORT",
+		Value:       "80",
+		Comment:     "Listen port",
+		Annotations: []parser.Annotation{{Name: "type", Value: "int"}},
+	})
+	overlay := NewEnv()
+	overlay.Set(parser.Entry{Key: "PORT", Value: "8080"})
+
+	merged := Merge(base, overlay)
+	got, _ := merged.Get("PORT")
+	if got.Value != "8080" {
+		t.Errorf("Value: got %q, want %q", got.Value, "8080")
+	}
+	if got.Comment != "Listen port" {
+		t.Errorf("Comment: got %q, want %q", got.Comment, "Listen port")
+	}
+	if typ, _ := got.Annotation("type"); typ != "int" {
+		t.Errorf("type annotation: got %q, want %q", typ, "int")
+	}
+}
+
+func TestInterpolateSkipsHeredoc(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, ".env", "HOST=localhost\nBLOB<<EOF\n${HOST}\nEOF\n")
+
+	env, _, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	Interpolate(env)
+
+	got, _ := env.Get("BLOB")
+	if got.Value != "${HOST}" {
+		t.Errorf("got %q, want %q", got.Value, "${HOST}")
+	}
+}
+
 func TestLoadReturnsWarningsForDuplicateKeys(t *testing.T) {
 	dir := t.TempDir()
 	path := writeFile(t, dir, ".env", "FOO=first\nBAR=middle\nFOO=second\n")
@@ -914,3 +1051,71 @@ func TestLoadHandlesCRLF(t *testing.T) {
 		t.Errorf("BAZ: got %q, want %q", entry.Value, "qux")
 	}
 }
+
+func TestLoadUnsupportedEncodingNamesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, ".env", "F\x00O\x00O\x00=\x001\x00")
+
+	_, _, err := Load(path)
+	if !errors.Is(err, parser.ErrUnsupportedEncoding) {
+		t.Fatalf("expected ErrUnsupportedEncoding, got %v", err)
+	}
+	if !strings.Contains(err.Error(), path) {
+		t.Errorf("error should name the file, got %q", err.Error())
+	}
+}
+
+func TestApplySchemes(t *testing.T) {
+	env := NewEnv()
+	env.Set(parser.Entry{Key: "A", Value: "secret://keychain/a"})
+	env.Set(parser.Entry{Key: "B", Value: "plain"})
+	env.Set(parser.Entry{Key: "C", Value: "secret://keychain/c", Quote: parser.QuoteHeredoc})
+	env.Set(parser.Entry{Key: "D", Value: "x-${secret://keychain/d}"})
+
+	if env.HasRefs() {
+		t.Fatal("expected no refs before ApplySchemes")
+	}
+	env.ApplySchemes(ref.Schemes{"secret": ""})
+
+	a, _ := env.Get("A")
+	if a.Value != "ref://keychain/a" || !a.IsRef {
+		t.Errorf("A = %+v, want rewritten ref", a)
+	}
+	if c, _ := env.Get("C"); c.Value != "secret://keychain/c" || c.IsRef {
+		t.Errorf("heredoc C should be unchanged, got %+v", c)
+	}
+	if d, _ := env.Get("D"); d.Value != "x-${ref://keychain/d}" || d.IsRef {
+		t.Errorf("D = %+v, want embedded rewrite without IsRef", d)
+	}
+	if len(env.Refs()) != 1 {
+		t.Errorf("Refs() = %v, want only A", env.Refs())
+	}
+
+	Interpolate(env)
+	if d, _ := env.Get("D"); d.Value != "x-ref://keychain/d" {
+		t.Errorf("after Interpolate D = %q", d.Value)
+	}
+}
+
+func TestApplyEncryption(t *testing.T) {
+	env := NewEnv()
+	env.Set(parser.Entry{Key: "A", Value: "enc://Y2lwaGVy"})
+	env.Set(parser.Entry{Key: "B", Value: "plain"})
+	env.Set(parser.Entry{Key: "C", Value: "enc://Y2lwaGVy", Quote: parser.QuoteHeredoc})
+
+	env.ApplyEncryption("")
+	if a, _ := env.Get("A"); a.IsRef {
+		t.Fatalf("A rewritten without a key: %+v", a)
+	}
+
+	env.ApplyEncryption("ref://keychain/enc_key")
+	if a, _ := env.Get("A"); a.Value != "ref://keychain/enc_key?decrypt=Y2lwaGVy" || !a.IsRef {
+		t.Errorf("A = %+v, want a decrypting ref", a)
+	}
+	if b, _ := env.Get("B"); b.Value != "plain" || b.IsRef {
+		t.Errorf("B should be unchanged, got %+v", b)
+	}
+	if c, _ := env.Get("C"); c.Value != "enc://Y2lwaGVy" || c.IsRef {
+		t.Errorf("heredoc C should be unchanged, got %+v", c)
+	}
+}
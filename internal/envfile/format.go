@@ -0,0 +1,118 @@
+// Package envfile — formatting of .env files.
+package envfile
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/xcke/envref/internal/parser"
+)
+
+// FormatOptions controls how Format normalizes a file.
+type FormatOptions struct {
+	// Sort orders the entries of each section by key. A section is a run
+	// of entries not separated by a blank line or by a comment that is not
+	// attached to an entry. Entries keep their comments and annotations.
+	Sort bool
+}
+
+// Format returns data, the contents of a .env file, in a normalized layout
+// that parses to the same values:
+//
+//   - entries are written as KEY=VALUE, without spaces around the "=", and
+//     keep their "export " prefix and inline comment
+//   - values are left unquoted when possible; other values are
+//     double-quoted, or single-quoted when they must not be interpolated
+//   - heredoc values stay heredocs
+//   - comments and annotations keep their place, with surrounding
+//     whitespace removed
+//   - runs of blank lines are collapsed, and leading and trailing blank
+//     lines are removed
+//
+// The input must be UTF-8. Parse errors are returned as *parser.ParseError.
+func Format(data []byte, opts FormatOptions) ([]byte, error) {
+	blocks, err := parseBlocks(data)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Sort {
+		sortSections(blocks)
+	}
+
+	var b strings.Builder
+	blank := false
+	for _, blk := range blocks {
+		if blk.blank {
+			blank = b.Len() > 0
+			continue
+		}
+		if blank {
+			b.WriteByte('\n')
+			blank = false
+		}
+		for _, line := range blk.lines {
+			b.WriteString(strings.TrimSpace(line) + "\n")
+		}
+		if blk.entry != nil {
+			if blk.export {
+				b.WriteString("export ")
+			}
+			b.WriteString(formatEntry(*blk.entry))
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// sortSections sorts each run of consecutive entry blocks by key. Sorting
+// is stable, so duplicate keys keep their order and the last still wins.
+func sortSections(blocks []block) {
+	for start := 0; start < len(blocks); {
+		if blocks[start].entry == nil {
+			start++
+			continue
+		}
+		end := start
+		for end < len(blocks) && blocks[end].entry != nil {
+			end++
+		}
+		section := blocks[start:end]
+		sort.SliceStable(section, func(i, j int) bool {
+			return section[i].entry.Key < section[j].entry.Key
+		})
+		start = end
+	}
+}
+
+// formatEntry returns the lines of an entry, ending in a newline.
+func formatEntry(e parser.Entry) string {
+	if e.Quote == parser.QuoteHeredoc {
+		return formatHeredoc(e.Key, e.Value)
+	}
+	line := e.Key + "=" + formatEntryValue(e.Value, e.Quote)
+	if e.InlineComment != "" {
+		line += " # " + e.InlineComment
+	}
+	return line + "\n"
+}
+
+// formatEntryValue quotes value only as needed. Values that are not
+// interpolated (single- and backtick-quoted) stay literal: they are
+// single-quoted if they contain a "$", or written with "$$" escapes in
+// double quotes if single quotes cannot hold them.
+func formatEntryValue(value string, quote parser.QuoteStyle) string {
+	literal := isLiteral(quote)
+	special := " \t\n\r\"'`#\\"
+	if literal {
+		special += "$"
+	}
+	if !strings.ContainsAny(value, special) {
+		return value
+	}
+	if !literal {
+		return formatValue(value)
+	}
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	return formatValue(strings.ReplaceAll(value, "$", "$$"))
+}
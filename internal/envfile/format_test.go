@@ -0,0 +1,136 @@
+package envfile
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/xcke/envref/internal/parser"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  FormatOptions
+		want  string
+	}{
+		{
+			name:  "spacing around equals",
+			input: "FOO = bar\n  BAZ=qux  \n",
+			want:  "FOO=bar\nBAZ=qux\n",
+		},
+		{
+			name:  "unnecessary quotes removed",
+			input: "FOO=\"bar\"\nBAZ='qux'\nURL=\"http://${HOST}/api\"\n",
+			want:  "FOO=bar\nBAZ=qux\nURL=http://${HOST}/api\n",
+		},
+		{
+			name:  "values that need quotes",
+			input: "GREETING=hello world\nHASH=a#b\nMULTI=\"a\nb\"\n",
+			want:  "GREETING=\"hello world\"\nHASH=\"a#b\"\nMULTI=\"a\\nb\"\n",
+		},
+		{
+			name:  "literal values stay literal",
+			input: "PASS='pa$$word'\nTICK=`it's $5`\n",
+			want:  "PASS='pa$$word'\nTICK=\"it's $$5\"\n",
+		},
+		{
+			name:  "comments, annotations, and inline comments kept",
+			input: "  # Database\n# @type: int\nPORT = 5432   # default port\n",
+			want:  "# Database\n# @type: int\nPORT=5432 # default port\n",
+		},
+		{
+			name:  "blank lines collapsed and trimmed",
+			input: "\n\nFOO=1\n\n\n\nBAR=2\n\n",
+			want:  "FOO=1\n\nBAR=2\n",
+		},
+		{
+			name:  "export prefix kept",
+			input: "export  FOO=\"bar\"\n",
+			want:  "export FOO=bar\n",
+		},
+		{
+			name:  "heredoc kept",
+			input: "CERT<<EOF\nline 1\nline 2\nEOF\nNEXT=1\n",
+			want:  "CERT<<EOF\nline 1\nline 2\nEOF\nNEXT=1\n",
+		},
+		{
+			name:  "CRLF and missing final newline",
+			input: "FOO=1\r\nBAR=2",
+			want:  "FOO=1\nBAR=2\n",
+		},
+		{
+			name:  "not sorted by default",
+			input: "B=2\nA=1\n",
+			want:  "B=2\nA=1\n",
+		},
+		{
+			name:  "sorted within sections",
+			input: "# Server\nPORT=80\n# the host\nHOST=localhost\n\nZ=1\nA=2\n# Detached\n\nC=3\nB=4\n",
+			opts:  FormatOptions{Sort: true},
+			want:  "# the host\nHOST=localhost\n# Server\nPORT=80\n\nA=2\nZ=1\n# Detached\n\nB=4\nC=3\n",
+		},
+		{
+			name:  "sorting keeps duplicates in order",
+			input: "B=1\nA=1\nB=2\n",
+			opts:  FormatOptions{Sort: true},
+			want:  "A=1\nB=1\nB=2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format([]byte(tt.input), tt.opts)
+			if err != nil {
+				t.Fatalf("Format: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Format:\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+
+			again, err := Format(got, tt.opts)
+			if err != nil {
+				t.Fatalf("Format of formatted output: %v", err)
+			}
+			if !bytes.Equal(again, got) {
+				t.Errorf("Format is not idempotent:\nfirst:\n%s\nsecond:\n%s", got, again)
+			}
+		})
+	}
+}
+
+func TestFormat_PreservesValues(t *testing.T) {
+	input := "HOST=localhost\nURL=\"http://${HOST}/x y\"\nLIT='${HOST} $$'\nTICK=`a'b $HOST`\nESC=\"tab\\there \\\"q\\\" back\\\\slash\"\nWIN=C:\\path\n"
+	got, err := Format([]byte(input), FormatOptions{})
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	load := func(data []byte) *Env {
+		t.Helper()
+		env, _, err := Read(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		Interpolate(env)
+		return env
+	}
+	before, after := load([]byte(input)), load(got)
+	for _, key := range before.Keys() {
+		b, _ := before.Get(key)
+		a, _ := after.Get(key)
+		if a.Value != b.Value {
+			t.Errorf("%s: got %q after formatting, want %q\nformatted:\n%s", key, a.Value, b.Value, got)
+		}
+	}
+}
+
+func TestFormat_Errors(t *testing.T) {
+	var parseErr *parser.ParseError
+	if _, err := Format([]byte("FOO=\"unterminated\n"), FormatOptions{}); !errors.As(err, &parseErr) {
+		t.Errorf("expected a ParseError, got %v", err)
+	}
+	if _, err := Format([]byte{0xFF, 0xFE, 'F', 0}, FormatOptions{}); !errors.Is(err, parser.ErrUnsupportedEncoding) {
+		t.Errorf("expected ErrUnsupportedEncoding, got %v", err)
+	}
+}
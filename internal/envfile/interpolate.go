@@ -12,21 +12,37 @@ import (
 // available to later ones, order-dependent). Undefined variables expand to
 // an empty string.
 //
-// Single-quoted and backtick-quoted values are treated as literals and are
-// not interpolated (consistent with shell behavior). Double-quoted and
+// Single-quoted, backtick-quoted, and heredoc values are treated as literals
+// and are not interpolated (consistent with shell behavior). Double-quoted and
 // unquoted values are interpolated.
 //
 // The Env is modified in place. A new Env is not created.
 func Interpolate(env *Env) {
+	InterpolateWithEnviron(env, nil)
+}
+
+// InterpolateWithEnviron works like Interpolate, but variables that env
+// does not define are also looked up in environ, a list of "KEY=value"
+// strings such as os.Environ returns. Keys defined in env always take
+// precedence: a key that env defines is never read from environ, even
+// where it is referenced before its definition (and so expands to an
+// empty string).
+func InterpolateWithEnviron(env *Env, environ []string) {
 	// Build a lookup map that grows as we process entries in order.
 	// This means later entries can reference earlier ones.
-	resolved := make(map[string]string, env.Len())
+	resolved := make(map[string]string, env.Len()+len(environ))
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if _, defined := env.entries[key]; ok && !defined {
+			resolved[key] = value
+		}
+	}
 
 	for _, key := range env.order {
 		entry := env.entries[key]
 
-		// Single-quoted and backtick-quoted values are literal — skip.
-		if entry.Quote == parser.QuoteSingle || entry.Quote == parser.QuoteBacktick {
+		// Single-quoted, backtick-quoted, and heredoc values are literal — skip.
+		if entry.Quote == parser.QuoteSingle || entry.Quote == parser.QuoteBacktick || entry.Quote == parser.QuoteHeredoc {
 			resolved[key] = entry.Value
 			continue
 		}
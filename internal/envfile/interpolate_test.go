@@ -1,6 +1,7 @@
 package envfile
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/xcke/envref/internal/parser"
@@ -322,3 +323,31 @@ LITERAL='${DB_HOST} not expanded'
 		t.Errorf("LITERAL: got %q, want %q", entry.Value, wantLiteral)
 	}
 }
+
+func TestInterpolateWithEnviron(t *testing.T) {
+	env, _, err := Read(strings.NewReader(`CACHE=${HOME}/.cache
+EARLY=${PORT}
+PORT=8080
+USER_PORT=${USER}:${PORT}
+LITERAL='${HOME}'
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	InterpolateWithEnviron(env, []string{"HOME=/home/ada", "PORT=9999", "USER=ada", "MALFORMED"})
+
+	for key, want := range map[string]string{
+		"CACHE":     "/home/ada/.cache",
+		"EARLY":     "", // PORT is defined in the file, so the process's is never used
+		"USER_PORT": "ada:8080",
+		"LITERAL":   "${HOME}",
+	} {
+		if entry, _ := env.Get(key); entry.Value != want {
+			t.Errorf("%s: got %q, want %q", key, entry.Value, want)
+		}
+	}
+	if _, ok := env.Get("HOME"); ok {
+		t.Error("HOME was added to the env")
+	}
+}
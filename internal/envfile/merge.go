@@ -0,0 +1,295 @@
+// Package envfile — key-wise merging of .env files.
+package envfile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xcke/envref/internal/parser"
+)
+
+// Conflict is a key that two files being merged changed in different ways.
+type Conflict struct {
+	Key string
+	// Ours and Theirs are the key's entries in the two files, or nil where
+	// the key was deleted.
+	Ours, Theirs *parser.Entry
+}
+
+// Side names how a conflict is resolved.
+type Side int
+
+const (
+	// KeepOurs keeps the entry of the file merged into.
+	KeepOurs Side = iota
+	// KeepTheirs takes the entry of the incoming file.
+	KeepTheirs
+	// MarkConflict writes both entries between git-style conflict markers,
+	// for a person to resolve later.
+	MarkConflict
+)
+
+// Lines written around both sides of a conflict resolved with MarkConflict.
+const (
+	markerOurs   = "<<<<<<< ours"
+	markerSep    = "======="
+	markerTheirs = ">>>>>>> theirs"
+)
+
+// Resolver decides how to resolve a conflict.
+type Resolver func(Conflict) (Side, error)
+
+// ConflictError is returned by MergeFiles and ThreeWayMerge when conflicts
+// are left unresolved.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	keys := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		keys[i] = c.Key
+	}
+	return fmt.Sprintf("%d conflicting key(s): %s", len(keys), strings.Join(keys, ", "))
+}
+
+// MergeResult describes the changes a merge made to the file merged into.
+type MergeResult struct {
+	// Data is the merged file.
+	Data []byte
+	// Added, Updated, and Removed list the keys whose entry was taken from
+	// the incoming file, and Conflicts the keys written between conflict
+	// markers.
+	Added, Updated, Removed, Conflicts []string
+}
+
+// MergeFiles merges incoming into ours key by key, as for .env files
+// written by several people. The merged file is ours with its layout,
+// comments, and key order, plus:
+//
+//   - keys only in incoming, with the comments above them, inserted after
+//     the key that precedes them in incoming (or before the first key)
+//   - for keys set to different values in both files, the entry chosen by
+//     resolve; a key's comments are those of ours either way
+//
+// Keys only in ours are kept. A nil resolve leaves conflicts unresolved,
+// and MergeFiles returns a *ConflictError listing them. Both inputs must be
+// UTF-8; parse errors are returned as *parser.ParseError.
+func MergeFiles(ours, incoming []byte, resolve Resolver) (*MergeResult, error) {
+	return ThreeWayMerge(nil, ours, incoming, resolve)
+}
+
+// ThreeWayMerge merges into ours the changes theirs made to their common
+// ancestor, key by key, the way git merges lines. A key that only theirs
+// added, changed, or deleted takes the entry of theirs. A key that only
+// ours changed, or that both changed the same way, keeps the entry of
+// ours. A key that both changed in different ways is a conflict, resolved
+// with resolve as in MergeFiles, which ThreeWayMerge is with an empty
+// ancestor.
+func ThreeWayMerge(ancestor, ours, theirs []byte, resolve Resolver) (*MergeResult, error) {
+	baseDoc, err := parseDocument(ancestor)
+	if err != nil {
+		return nil, err
+	}
+	ourDoc, err := parseDocument(ours)
+	if err != nil {
+		return nil, err
+	}
+	theirDoc, err := parseDocument(theirs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decide each key, in the order of ours and then of theirs.
+	keys := entryKeys(ourDoc.blocks)
+	for _, key := range entryKeys(theirDoc.blocks) {
+		if ourDoc.entry(key) == nil {
+			keys = append(keys, key)
+		}
+	}
+	sides := make(map[string]Side)
+	var unresolved []Conflict
+	for _, key := range keys {
+		o, a, b := baseDoc.entry(key), ourDoc.entry(key), theirDoc.entry(key)
+		switch {
+		case sameValue(a, b), sameValue(b, o):
+			continue
+		case sameValue(a, o):
+			sides[key] = KeepTheirs
+			continue
+		}
+		conflict := Conflict{Key: key, Ours: a, Theirs: b}
+		if resolve == nil {
+			unresolved = append(unresolved, conflict)
+			continue
+		}
+		side, err := resolve(conflict)
+		if err != nil {
+			return nil, err
+		}
+		sides[key] = side
+	}
+	if len(unresolved) > 0 {
+		return nil, &ConflictError{Conflicts: unresolved}
+	}
+
+	// Apply the decisions to the keys of ours in place.
+	result := &MergeResult{}
+	var merged []block
+	for i, blk := range ourDoc.blocks {
+		side, decided := sides[blockKey(blk)]
+		if !decided || ourDoc.index[blk.entry.Key] != i {
+			merged = append(merged, blk)
+			continue
+		}
+		key := blk.entry.Key
+		theirLines := theirDoc.entryLines(key)
+		switch {
+		case side == KeepTheirs && theirLines == nil:
+			result.Removed = append(result.Removed, key)
+			continue
+		case side == KeepTheirs:
+			blk.entry, blk.entryLines = theirDoc.entry(key), theirLines
+			result.Updated = append(result.Updated, key)
+		case side == MarkConflict:
+			blk.entryLines = conflictLines(blk.entryLines, theirLines)
+			result.Conflicts = append(result.Conflicts, key)
+		}
+		merged = append(merged, blk)
+	}
+
+	// Insert the keys only in theirs after the key preceding them there.
+	var after string // the last key of theirs that is in merged
+	for i, blk := range theirDoc.blocks {
+		if blk.entry == nil || theirDoc.index[blk.entry.Key] != i {
+			continue
+		}
+		key := blk.entry.Key
+		if ourDoc.entry(key) == nil {
+			switch side, decided := sides[key]; {
+			case !decided || side == KeepOurs:
+				continue
+			case side == KeepTheirs:
+				result.Added = append(result.Added, key)
+			case side == MarkConflict:
+				blk.entryLines = conflictLines(nil, blk.entryLines)
+				result.Conflicts = append(result.Conflicts, key)
+			}
+			at := insertionPoint(merged, after)
+			merged = append(merged[:at], append([]block{blk}, merged[at:]...)...)
+		}
+		after = key
+	}
+
+	result.Data = writeBlocks(merged)
+	return result, nil
+}
+
+// document is a parsed .env file with the block of each key's last entry,
+// which wins as when loading the file.
+type document struct {
+	blocks []block
+	index  map[string]int
+}
+
+// parseDocument parses data into a document.
+func parseDocument(data []byte) (document, error) {
+	blocks, err := parseBlocks(data)
+	if err != nil {
+		return document{}, err
+	}
+	return document{blocks: blocks, index: lastEntries(blocks)}, nil
+}
+
+// entry returns the last entry of key, or nil if key is not set.
+func (d document) entry(key string) *parser.Entry {
+	if i, ok := d.index[key]; ok {
+		return d.blocks[i].entry
+	}
+	return nil
+}
+
+// entryLines returns the lines of the last entry of key, or nil if key is
+// not set.
+func (d document) entryLines(key string) []string {
+	if i, ok := d.index[key]; ok {
+		return d.blocks[i].entryLines
+	}
+	return nil
+}
+
+// blockKey returns the key of blk's entry, or "" if blk has none.
+func blockKey(blk block) string {
+	if blk.entry == nil {
+		return ""
+	}
+	return blk.entry.Key
+}
+
+// conflictLines returns the lines of both sides of a conflict between
+// conflict markers.
+func conflictLines(ours, theirs []string) []string {
+	lines := append([]string{markerOurs}, ours...)
+	lines = append(lines, markerSep)
+	lines = append(lines, theirs...)
+	return append(lines, markerTheirs)
+}
+
+// lastEntries maps each key to the index of its last entry block.
+func lastEntries(blocks []block) map[string]int {
+	index := make(map[string]int)
+	for i, blk := range blocks {
+		if blk.entry != nil {
+			index[blk.entry.Key] = i
+		}
+	}
+	return index
+}
+
+// entryKeys returns the keys of blocks in order of their last entry.
+func entryKeys(blocks []block) []string {
+	index := lastEntries(blocks)
+	var keys []string
+	for i, blk := range blocks {
+		if blk.entry != nil && index[blk.entry.Key] == i {
+			keys = append(keys, blk.entry.Key)
+		}
+	}
+	return keys
+}
+
+// sameValue reports whether two entries of a key, nil where the key is not
+// set, set the same value: the same text, interpolated in both or in
+// neither if it contains a "$".
+func sameValue(a, b *parser.Entry) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if a.Value != b.Value {
+		return false
+	}
+	return isLiteral(a.Quote) == isLiteral(b.Quote) || !strings.Contains(a.Value, "$")
+}
+
+// isLiteral reports whether values quoted with quote are not interpolated.
+func isLiteral(quote parser.QuoteStyle) bool {
+	return quote == parser.QuoteSingle || quote == parser.QuoteBacktick || quote == parser.QuoteHeredoc
+}
+
+// insertionPoint returns where to insert a new entry block that follows
+// the key after: right after the last entry of after, or before the first
+// entry when after is empty or missing. Without entries, blocks are
+// appended.
+func insertionPoint(blocks []block, after string) int {
+	if after != "" {
+		if i, ok := lastEntries(blocks)[after]; ok {
+			return i + 1
+		}
+	}
+	for i, blk := range blocks {
+		if blk.entry != nil {
+			return i
+		}
+	}
+	return len(blocks)
+}
@@ -894,3 +894,150 @@ func TestMergeResolvedRefs(t *testing.T) {
 		assert.Equal(t, "prod/db/password", ref.Path)
 	})
 }
+
+func TestMergeFiles(t *testing.T) {
+	ours := "# App\nAPP=myapp\n\n# Database\nDB_HOST=localhost # dev\nDB_PORT=5432\nLOCAL_ONLY=1\n"
+	theirs := "# Header\nFIRST=1\nAPP=myapp\n\nDB_HOST=db.internal\n# the pool size\nDB_POOL=10\nDB_PORT=5432\nLAST=1\n"
+
+	t.Run("theirs", func(t *testing.T) {
+		var conflicts []Conflict
+		result, err := MergeFiles([]byte(ours), []byte(theirs), func(c Conflict) (Side, error) {
+			conflicts = append(conflicts, c)
+			return KeepTheirs, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "# Header\nFIRST=1\n# App\nAPP=myapp\n\n# Database\nDB_HOST=db.internal\n# the pool size\nDB_POOL=10\nDB_PORT=5432\nLAST=1\nLOCAL_ONLY=1\n", string(result.Data))
+		assert.Equal(t, []string{"FIRST", "DB_POOL", "LAST"}, result.Added)
+		assert.Equal(t, []string{"DB_HOST"}, result.Updated)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, "DB_HOST", conflicts[0].Key)
+		assert.Equal(t, "localhost", conflicts[0].Ours.Value)
+		assert.Equal(t, "db.internal", conflicts[0].Theirs.Value)
+	})
+
+	t.Run("ours", func(t *testing.T) {
+		result, err := MergeFiles([]byte(ours), []byte(theirs), func(Conflict) (Side, error) { return KeepOurs, nil })
+		require.NoError(t, err)
+		assert.Contains(t, string(result.Data), "DB_HOST=localhost # dev\n")
+		assert.Empty(t, result.Updated)
+	})
+
+	t.Run("unresolved", func(t *testing.T) {
+		_, err := MergeFiles([]byte(ours), []byte(theirs), nil)
+		var conflictErr *ConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, "1 conflicting key(s): DB_HOST", err.Error())
+	})
+
+	t.Run("resolver error", func(t *testing.T) {
+		_, err := MergeFiles([]byte(ours), []byte(theirs), func(Conflict) (Side, error) { return 0, fmt.Errorf("cancelled") })
+		assert.EqualError(t, err, "cancelled")
+	})
+}
+
+func TestMergeFiles_SameValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		ours     string
+		theirs   string
+		conflict bool
+	}{
+		{"different quoting", "A=\"x y\"\n", "A='x y'\n", false},
+		{"interpolated and literal", "A=$B\n", "A='$B'\n", true},
+		{"duplicate key uses last value", "A=1\nA=2\n", "A=2\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MergeFiles([]byte(tt.ours), []byte(tt.theirs), nil)
+			if tt.conflict {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMergeFiles_Empty(t *testing.T) {
+	result, err := MergeFiles(nil, []byte("# note\nA=1\n\nB=2\n"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "# note\nA=1\nB=2\n", string(result.Data))
+
+	result, err = MergeFiles([]byte("A=1\n"), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "A=1\n", string(result.Data))
+}
+
+func TestThreeWayMerge(t *testing.T) {
+	ancestor := "# Server\nHOST=localhost\nPORT=80\nDEBUG=false\nOLD=1\n"
+	ours := "# Server\nHOST=localhost\nPORT=8080\nDEBUG=false\nOLD=1\nMINE=a\n"
+	theirs := "# Server\nHOST=example.com\nPORT=80\nDEBUG=false\n# Theirs\nTHEIRS=b\n"
+
+	result, err := ThreeWayMerge([]byte(ancestor), []byte(ours), []byte(theirs), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "# Server\nHOST=example.com\nPORT=8080\nDEBUG=false\n# Theirs\nTHEIRS=b\nMINE=a\n", string(result.Data))
+	assert.Equal(t, []string{"THEIRS"}, result.Added)
+	assert.Equal(t, []string{"HOST"}, result.Updated)
+	assert.Equal(t, []string{"OLD"}, result.Removed)
+	assert.Empty(t, result.Conflicts)
+}
+
+func TestThreeWayMerge_Conflicts(t *testing.T) {
+	ancestor := "A=1\nB=1\nC=1\n"
+	ours := "A=2\nB=1\nC=2\n"
+	theirs := "A=3\nC=1\nD=4\n"
+
+	t.Run("unresolved", func(t *testing.T) {
+		_, err := ThreeWayMerge([]byte(ancestor), []byte(ours), []byte(theirs), nil)
+		var conflictErr *ConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.Len(t, conflictErr.Conflicts, 1)
+		assert.Equal(t, "A", conflictErr.Conflicts[0].Key)
+	})
+
+	t.Run("both changed the same way", func(t *testing.T) {
+		result, err := ThreeWayMerge([]byte(ancestor), []byte("A=2\n"), []byte("A=2\n"), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "A=2\n", string(result.Data))
+	})
+
+	t.Run("deleted on one side and changed on the other", func(t *testing.T) {
+		_, err := ThreeWayMerge([]byte(ancestor), []byte("A=1\nB=1\nC=2\n"), []byte("A=1\nB=1\n"), nil)
+		var conflictErr *ConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.Len(t, conflictErr.Conflicts, 1)
+		c := conflictErr.Conflicts[0]
+		assert.Equal(t, "C", c.Key)
+		require.NotNil(t, c.Ours)
+		assert.Equal(t, "2", c.Ours.Value)
+		assert.Nil(t, c.Theirs)
+	})
+
+	t.Run("marked", func(t *testing.T) {
+		mark := func(Conflict) (Side, error) { return MarkConflict, nil }
+		result, err := ThreeWayMerge([]byte(ancestor), []byte(ours), []byte(theirs), mark)
+		require.NoError(t, err)
+		assert.Equal(t, "<<<<<<< ours\nA=2\n=======\nA=3\n>>>>>>> theirs\nC=2\nD=4\n", string(result.Data))
+		assert.Equal(t, []string{"A"}, result.Conflicts)
+		assert.Equal(t, []string{"D"}, result.Added)
+		assert.Equal(t, []string{"B"}, result.Removed)
+	})
+
+	t.Run("marked deletion", func(t *testing.T) {
+		mark := func(Conflict) (Side, error) { return MarkConflict, nil }
+		result, err := ThreeWayMerge([]byte("A=1\n"), []byte("A=2\n"), nil, mark)
+		require.NoError(t, err)
+		assert.Equal(t, "<<<<<<< ours\nA=2\n=======\n>>>>>>> theirs\n", string(result.Data))
+	})
+
+	t.Run("added on both sides", func(t *testing.T) {
+		mark := func(Conflict) (Side, error) { return MarkConflict, nil }
+		result, err := ThreeWayMerge(nil, []byte("X=0\n"), []byte("X=0\nN=2\n"), mark)
+		require.NoError(t, err)
+		assert.Equal(t, "X=0\nN=2\n", string(result.Data))
+
+		result, err = ThreeWayMerge(nil, []byte("X=0\nN=1\n"), []byte("X=0\nN=2\n"), mark)
+		require.NoError(t, err)
+		assert.Equal(t, "X=0\n<<<<<<< ours\nN=1\n=======\nN=2\n>>>>>>> theirs\n", string(result.Data))
+	})
+}
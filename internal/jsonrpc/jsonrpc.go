@@ -0,0 +1,175 @@
+// Package jsonrpc implements a JSON-RPC 2.0 server over a stream, with one
+// message per line, as used by editor integrations and the Model Context
+// Protocol. Requests are answered in order, one at a time; batches are not
+// supported.
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Version is the JSON-RPC version of every message.
+const Version = "2.0"
+
+// Error codes defined by JSON-RPC 2.0.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	// CodeServerError is used for errors returned by a method.
+	CodeServerError = -32000
+)
+
+// Error is a JSON-RPC error. A Handler may return one to choose the code.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// InvalidParams returns an error for parameters a method cannot use.
+func InvalidParams(format string, args ...any) *Error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// Request is a request or, without an ID, a notification.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response answers a Request. Exactly one of Result and Error is set.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Handler answers a method call. params is null when the request has none.
+type Handler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Server dispatches requests to the handlers of their methods.
+type Server struct {
+	mu      sync.Mutex
+	methods map[string]Handler
+}
+
+// NewServer returns a server with no methods.
+func NewServer() *Server {
+	return &Server{methods: make(map[string]Handler)}
+}
+
+// Handle registers h for method, replacing any earlier handler.
+func (s *Server) Handle(method string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[method] = h
+}
+
+// Serve reads requests from r and writes responses to w until r ends or
+// ctx is done. It returns nil when r ends.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if resp := s.dispatch(ctx, line); resp != nil {
+				if err := enc.Encode(resp); err != nil {
+					return fmt.Errorf("writing response: %w", err)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading request: %w", err)
+		}
+	}
+}
+
+// dispatch answers one message. It returns nil for a notification.
+func (s *Server) dispatch(ctx context.Context, line []byte) *Response {
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(nil, &Error{Code: CodeParseError, Message: "parse error: " + err.Error()})
+	}
+	if req.JSONRPC != Version || req.Method == "" {
+		return errorResponse(req.ID, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+
+	s.mu.Lock()
+	h, ok := s.methods[req.Method]
+	s.mu.Unlock()
+
+	var result any
+	var err error
+	if ok {
+		params := req.Params
+		if len(params) == 0 {
+			params = json.RawMessage("null")
+		}
+		result, err = h(ctx, params)
+	} else {
+		err = &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+	}
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeServerError, Message: err.Error()}
+		}
+		return errorResponse(req.ID, rpcErr)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(req.ID, &Error{Code: CodeInternalError, Message: "encoding result: " + err.Error()})
+	}
+	return &Response{JSONRPC: Version, ID: req.ID, Result: data}
+}
+
+// errorResponse returns a response carrying err. A nil id is sent as null.
+func errorResponse(id json.RawMessage, err *Error) *Response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &Response{JSONRPC: Version, ID: id, Error: err}
+}
+
+// DecodeParams decodes params into v. Unknown fields are ignored, and
+// missing params leave v unchanged.
+func DecodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return InvalidParams("invalid params: %v", err)
+	}
+	return nil
+}
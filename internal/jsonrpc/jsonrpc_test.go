@@ -0,0 +1,69 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve runs a server over input and returns its response lines.
+func serve(t *testing.T, s *Server, input string) []string {
+	t.Helper()
+	var out strings.Builder
+	require.NoError(t, s.Serve(context.Background(), strings.NewReader(input), &out))
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func TestServer_Dispatch(t *testing.T) {
+	s := NewServer()
+	s.Handle("add", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p struct{ A, B int }
+		if err := DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return p.A + p.B, nil
+	})
+	s.Handle("fail", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return nil, errors.New("boom")
+	})
+	s.Handle("nothing", func(ctx context.Context, params json.RawMessage) (any, error) {
+		assert.Equal(t, "null", string(params))
+		return nil, nil
+	})
+
+	lines := serve(t, s, strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"add","params":{"A":2,"B":3}}`,
+		`{"jsonrpc":"2.0","method":"add","params":{"A":1,"B":1}}`,
+		``,
+		`{"jsonrpc":"2.0","id":"x","method":"fail"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"missing"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"add","params":[1]}`,
+		`{"jsonrpc":"2.0","id":4,"method":"nothing"}`,
+		`{"jsonrpc":"1.0","id":5,"method":"add"}`,
+		`not json`,
+	}, "\n"))
+
+	assert.Equal(t, []string{
+		`{"jsonrpc":"2.0","id":1,"result":5}`,
+		`{"jsonrpc":"2.0","id":"x","error":{"code":-32000,"message":"boom"}}`,
+		`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"method \"missing\" not found"}}`,
+		`{"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"invalid params: json: cannot unmarshal array into Go value of type struct { A int; B int }"}}`,
+		`{"jsonrpc":"2.0","id":4,"result":null}`,
+		`{"jsonrpc":"2.0","id":5,"error":{"code":-32600,"message":"invalid request"}}`,
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error: invalid character 'o' in literal null (expecting 'u')"}}`,
+	}, lines)
+}
+
+func TestServer_LastLineWithoutNewline(t *testing.T) {
+	s := NewServer()
+	s.Handle("ping", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return "pong", nil
+	})
+	lines := serve(t, s, `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	assert.Equal(t, []string{`{"jsonrpc":"2.0","id":1,"result":"pong"}`}, lines)
+}
@@ -0,0 +1,179 @@
+// Package offline keeps an encrypted copy of the last value read of each
+// secret, so that references can still be resolved when the backends that
+// hold them cannot be reached (e.g., off VPN).
+//
+// Values are stored per project in a single file, encrypted with
+// AES-256-GCM under a key the caller keeps elsewhere (envref keeps it in
+// the OS keychain).
+package offline
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// KeySize is the size of the encryption key, in bytes.
+const KeySize = 32
+
+// additionalData binds the ciphertext to its purpose.
+var additionalData = []byte("envref offline cache v1")
+
+// ErrCorrupt is returned by Open for a file that cannot be decrypted with
+// the given key or does not hold a cache.
+var ErrCorrupt = errors.New("offline cache cannot be decrypted")
+
+// Store holds the last value read of each secret, by backend name and
+// storage key. It implements backend.KnownValues. The zero value is not
+// usable; create stores with New or Open.
+type Store struct {
+	path string
+	key  []byte
+
+	mu     sync.Mutex
+	values map[string]record
+	dirty  bool
+}
+
+// record is a stored value and when it was read.
+type record struct {
+	Value string    `json:"value"`
+	At    time.Time `json:"at"`
+}
+
+// DefaultPath returns the file the values of project are kept in: under
+// envref/offline in the user's cache directory.
+func DefaultPath(project string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "envref", "offline", project+".cache"), nil
+}
+
+// New returns an empty store that saves to path, encrypted with key.
+func New(path string, key []byte) *Store {
+	return &Store{path: path, key: key, values: make(map[string]record)}
+}
+
+// Open loads the store saved at path. A missing file is an empty store.
+func Open(path string, key []byte) (*Store, error) {
+	s := New(path, key)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrCorrupt
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	if err := json.Unmarshal(plain, &s.values); err != nil {
+		return nil, ErrCorrupt
+	}
+	return s, nil
+}
+
+// storeKey returns the key of the value of key in backend.
+func storeKey(backend, key string) string {
+	return backend + "\x00" + key
+}
+
+// Lookup returns the last value read of key from backend, and when it was
+// read.
+func (s *Store) Lookup(backend, key string) (string, time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.values[storeKey(backend, key)]
+	return r.Value, r.At, ok
+}
+
+// Remember records value as the last value read of key from backend, read
+// now.
+func (s *Store) Remember(backend, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[storeKey(backend, key)] = record{Value: value, At: time.Now().UTC()}
+	s.dirty = true
+}
+
+// Len returns the number of values in the store.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.values)
+}
+
+// Save writes the store to its file if values were recorded since it was
+// opened. The file is replaced atomically and readable by the owner only.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+
+	plain, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data := gcm.Seal(nonce, nonce, plain, additionalData)
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".offline-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// newGCM returns the AES-GCM cipher for key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("offline cache key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
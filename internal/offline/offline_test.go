@@ -0,0 +1,61 @@
+package offline
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestStore_SaveAndOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "offline", "app.cache")
+
+	s, err := Open(path, testKey(1))
+	require.NoError(t, err)
+	assert.Equal(t, 0, s.Len())
+
+	s.Remember("vault", "app/db_pass", "s3cret")
+	require.NoError(t, s.Save())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cret")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	s, err = Open(path, testKey(1))
+	require.NoError(t, err)
+	value, at, ok := s.Lookup("vault", "app/db_pass")
+	assert.True(t, ok)
+	assert.Equal(t, "s3cret", value)
+	assert.False(t, at.IsZero())
+
+	_, _, ok = s.Lookup("keychain", "app/db_pass")
+	assert.False(t, ok)
+}
+
+func TestOpen_WrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.cache")
+	s := New(path, testKey(1))
+	s.Remember("vault", "k", "v")
+	require.NoError(t, s.Save())
+
+	_, err := Open(path, testKey(2))
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
+
+func TestSave_OnlyWhenChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.cache")
+	require.NoError(t, New(path, testKey(1)).Save())
+
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "an unchanged store is not written")
+}
@@ -36,7 +36,8 @@ func isTerminal(w io.Writer) bool {
 // Color is enabled when all of the following are true:
 //   - The --no-color flag is not set
 //   - The NO_COLOR environment variable is not set (https://no-color.org/)
-//   - The writer is connected to a terminal
+//   - The writer is connected to a terminal, or FORCE_COLOR is set to a
+//     value other than "0" (e.g., when piping into less -R)
 func colorEnabled(w io.Writer, noColorFlag bool) bool {
 	if noColorFlag {
 		return false
@@ -44,6 +45,9 @@ func colorEnabled(w io.Writer, noColorFlag bool) bool {
 	if _, ok := os.LookupEnv("NO_COLOR"); ok {
 		return false
 	}
+	if force := os.Getenv("FORCE_COLOR"); force != "" && force != "0" {
+		return true
+	}
 	return isTerminal(w)
 }
 
@@ -101,6 +101,40 @@ func TestWriter_ColorHelpers_Disabled(t *testing.T) {
 	}
 }
 
+func TestColorEnabled_ForceColor(t *testing.T) {
+	var buf bytes.Buffer
+	t.Setenv("FORCE_COLOR", "1")
+	if !colorEnabled(&buf, false) {
+		t.Error("expected FORCE_COLOR to enable color for a non-terminal writer")
+	}
+	if colorEnabled(&buf, true) {
+		t.Error("expected --no-color to win over FORCE_COLOR")
+	}
+
+	t.Setenv("FORCE_COLOR", "0")
+	if colorEnabled(&buf, false) {
+		t.Error("expected FORCE_COLOR=0 to leave color disabled")
+	}
+}
+
+func TestWriter_ForStdout(t *testing.T) {
+	// Color follows stdout, not stderr: a terminal stderr does not color
+	// output piped from stdout.
+	w := &Writer{out: new(bytes.Buffer), errOut: new(bytes.Buffer), color: true}
+	if w.ForStdout().ColorEnabled() {
+		t.Error("expected color disabled for a non-terminal stdout")
+	}
+
+	t.Setenv("FORCE_COLOR", "1")
+	if !w.ForStdout().ColorEnabled() {
+		t.Error("expected FORCE_COLOR to enable stdout color")
+	}
+	w.noColor = true
+	if w.ForStdout().ColorEnabled() {
+		t.Error("expected --no-color to disable stdout color")
+	}
+}
+
 func TestWriter_ColorHelpers_Enabled(t *testing.T) {
 	// When color is enabled, helpers should wrap text with ANSI codes.
 	w := &Writer{color: true}
@@ -0,0 +1,75 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/secret"
+)
+
+// Log formats accepted by the --log-format flag.
+const (
+	// LogFormatText writes records as key=value pairs.
+	LogFormatText = "text"
+	// LogFormatJSON writes records as JSON objects, one per line.
+	LogFormatJSON = "json"
+)
+
+// LogLevel returns the lowest level logged at verbosity v: errors only
+// with --quiet, warnings by default, info with --verbose, and debug with
+// --debug.
+func LogLevel(v Verbosity) slog.Level {
+	switch {
+	case v <= VerbosityQuiet:
+		return slog.LevelError
+	case v == VerbosityNormal:
+		return slog.LevelWarn
+	case v == VerbosityVerbose:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
+
+// NewLogger creates a logger that writes to the command's stderr, at the
+// level of its verbosity flags (see LogLevel) and in the format of its
+// --log-format flag. Attribute values have tracked secret values masked.
+func NewLogger(cmd *cobra.Command) (*slog.Logger, error) {
+	format, _ := cmd.Flags().GetString("log-format")
+	return newLogger(cmd.ErrOrStderr(), format, LogLevel(FromCmd(cmd)))
+}
+
+// newLogger creates a logger writing records of at least level to w in
+// format.
+func newLogger(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case LogFormatText, "":
+		// Timestamps only clutter logs read in a terminal.
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return redactAttr(groups, a)
+		}
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case LogFormatJSON:
+		opts.ReplaceAttr = redactAttr
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q (want %s or %s)", format, LogFormatText, LogFormatJSON)
+	}
+}
+
+// redactAttr masks tracked secret values in string and error attributes.
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	switch v := a.Value.Any().(type) {
+	case string:
+		a.Value = slog.StringValue(secret.Redact(v))
+	case error:
+		a.Value = slog.StringValue(secret.Redact(v.Error()))
+	}
+	return a
+}
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/xcke/envref/internal/secret"
+)
+
+func TestLogLevel(t *testing.T) {
+	tests := []struct {
+		v    Verbosity
+		want slog.Level
+	}{
+		{VerbosityQuiet, slog.LevelError},
+		{VerbosityNormal, slog.LevelWarn},
+		{VerbosityVerbose, slog.LevelInfo},
+		{VerbosityDebug, slog.LevelDebug},
+	}
+	for _, tt := range tests {
+		if got := LogLevel(tt.v); got != tt.want {
+			t.Errorf("LogLevel(%d) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestNewLogger_Text(t *testing.T) {
+	cmd, _, stderr := newTestCmd("-v")
+	logger, err := NewLogger(cmd)
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	logger.Info("resolved", "key", "API_KEY")
+	logger.Debug("hidden")
+
+	got := stderr.String()
+	if got != "level=INFO msg=resolved key=API_KEY\n" {
+		t.Errorf("unexpected log output: %q", got)
+	}
+}
+
+func TestNewLogger_JSON(t *testing.T) {
+	cmd, _, stderr := newTestCmd("--debug", "--log-format", "json")
+	logger, err := NewLogger(cmd)
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	logger.Debug("backend call", "backend", "vault")
+
+	var record map[string]any
+	if err := json.Unmarshal(stderr.Bytes(), &record); err != nil {
+		t.Fatalf("log output is not JSON: %v: %q", err, stderr.String())
+	}
+	if record["level"] != "DEBUG" || record["msg"] != "backend call" || record["backend"] != "vault" {
+		t.Errorf("unexpected record: %v", record)
+	}
+	if _, ok := record["time"]; !ok {
+		t.Error("expected JSON records to have a time")
+	}
+}
+
+func TestNewLogger_DefaultLevel(t *testing.T) {
+	cmd, _, stderr := newTestCmd()
+	logger, err := NewLogger(cmd)
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown")
+
+	if got := stderr.String(); got != "level=WARN msg=shown\n" {
+		t.Errorf("unexpected log output: %q", got)
+	}
+}
+
+func TestNewLogger_InvalidFormat(t *testing.T) {
+	cmd, _, _ := newTestCmd("--log-format", "xml")
+	if _, err := NewLogger(cmd); err == nil || !strings.Contains(err.Error(), `invalid log format "xml"`) {
+		t.Errorf("expected invalid format error, got %v", err)
+	}
+}
+
+func TestNewLogger_Redacts(t *testing.T) {
+	secret.Track("log-redact-secret")
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, LogFormatText, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("newLogger: %v", err)
+	}
+	logger.Info("failed", "value", "got log-redact-secret", "error", errors.New("bad log-redact-secret"))
+
+	if got := buf.String(); strings.Contains(got, "log-redact-secret") {
+		t.Errorf("log must not contain secret values: %q", got)
+	}
+}
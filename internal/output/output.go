@@ -2,7 +2,8 @@
 //
 // It reads --quiet, --verbose, and --debug persistent flags from the cobra
 // command tree and provides helper methods to conditionally print messages
-// based on the active verbosity level.
+// based on the active verbosity level. Messages have tracked secret values
+// masked (see secret.Redact).
 package output
 
 import (
@@ -10,6 +11,7 @@ import (
 	"io"
 
 	"github.com/spf13/cobra"
+	"github.com/xcke/envref/internal/secret"
 )
 
 // Verbosity represents the output verbosity level.
@@ -56,6 +58,7 @@ type Writer struct {
 	errOut    io.Writer
 	verbosity Verbosity
 	color     bool
+	noColor   bool
 }
 
 // NewWriter creates a Writer from a cobra command. It reads the verbosity
@@ -70,9 +73,19 @@ func NewWriter(cmd *cobra.Command) *Writer {
 		errOut:    errW,
 		verbosity: FromCmd(cmd),
 		color:     colorEnabled(errW, noColor),
+		noColor:   noColor,
 	}
 }
 
+// ForStdout returns a copy of w whose color helpers are enabled when stdout,
+// rather than stderr, is a terminal. Commands that color the data they
+// print to stdout use it, so that piped output has no escape codes.
+func (w *Writer) ForStdout() *Writer {
+	c := *w
+	c.color = colorEnabled(w.out, w.noColor)
+	return &c
+}
+
 // Verbosity returns the active verbosity level.
 func (w *Writer) Verbosity() Verbosity {
 	return w.verbosity
@@ -96,21 +109,21 @@ func (w *Writer) IsDebug() bool {
 // Info prints an informational message to stdout. Suppressed in quiet mode.
 func (w *Writer) Info(format string, args ...interface{}) {
 	if w.verbosity >= VerbosityNormal {
-		_, _ = fmt.Fprintf(w.out, format, args...)
+		_, _ = fmt.Fprint(w.out, secret.Redact(fmt.Sprintf(format, args...)))
 	}
 }
 
 // Verbose prints a message to stderr only when --verbose or --debug is active.
 func (w *Writer) Verbose(format string, args ...interface{}) {
 	if w.verbosity >= VerbosityVerbose {
-		_, _ = fmt.Fprintf(w.errOut, format, args...)
+		_, _ = fmt.Fprint(w.errOut, secret.Redact(fmt.Sprintf(format, args...)))
 	}
 }
 
 // Debug prints a message to stderr only when --debug is active.
 func (w *Writer) Debug(format string, args ...interface{}) {
 	if w.verbosity >= VerbosityDebug {
-		msg := fmt.Sprintf(format, args...)
+		msg := secret.Redact(fmt.Sprintf(format, args...))
 		_, _ = fmt.Fprintf(w.errOut, "%s %s", w.debugPrefix(), msg)
 	}
 }
@@ -118,14 +131,14 @@ func (w *Writer) Debug(format string, args ...interface{}) {
 // Warn prints a warning to stderr. Shown at all verbosity levels except quiet.
 func (w *Writer) Warn(format string, args ...interface{}) {
 	if w.verbosity >= VerbosityNormal {
-		msg := fmt.Sprintf(format, args...)
+		msg := secret.Redact(fmt.Sprintf(format, args...))
 		_, _ = fmt.Fprintf(w.errOut, "%s %s", w.warnPrefix(), msg)
 	}
 }
 
 // Error prints an error to stderr. Always shown regardless of verbosity.
 func (w *Writer) Error(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
+	msg := secret.Redact(fmt.Sprintf(format, args...))
 	_, _ = fmt.Fprintf(w.errOut, "%s %s", w.errorPrefix(), msg)
 }
 
@@ -15,9 +15,10 @@ func newTestCmd(args ...string) (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
 
 	root := &cobra.Command{Use: "test"}
 	root.PersistentFlags().BoolP("quiet", "q", false, "suppress informational output")
-	root.PersistentFlags().Bool("verbose", false, "show additional detail")
+	root.PersistentFlags().BoolP("verbose", "v", false, "show additional detail")
 	root.PersistentFlags().Bool("debug", false, "show debug information")
 	root.PersistentFlags().Bool("no-color", false, "disable colorized output")
+	root.PersistentFlags().String("log-format", "text", "log format: text or json")
 
 	child := &cobra.Command{
 		Use:  "sub",
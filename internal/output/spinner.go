@@ -0,0 +1,101 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/xcke/envref/internal/secret"
+)
+
+// Spinner timing: the spinner appears once an operation has run for
+// SpinnerDelay, and redraws every spinnerInterval.
+const (
+	SpinnerDelay    = 300 * time.Millisecond
+	spinnerInterval = 100 * time.Millisecond
+)
+
+// spinnerFrames are the frames of the spinner animation.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Spinner animates a status line on stderr while a slow operation runs, so
+// that users can tell envref is waiting rather than hung. It draws nothing
+// until the operation has run for its delay, and nothing at all unless
+// stderr is a terminal and the verbosity is normal: quiet output must stay
+// quiet, and verbose log lines would break the line up.
+type Spinner struct {
+	out      io.Writer
+	delay    time.Duration
+	interval time.Duration
+	text     func() string
+	enabled  bool
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// Spinner returns a spinner showing the status text returns, redrawn at
+// every frame. Start it before the operation and Stop it after.
+func (w *Writer) Spinner(text func() string) *Spinner {
+	s := newSpinner(w.errOut, SpinnerDelay, spinnerInterval, text)
+	s.enabled = w.verbosity == VerbosityNormal && isTerminal(w.errOut)
+	return s
+}
+
+// newSpinner creates an enabled spinner writing to out.
+func newSpinner(out io.Writer, delay, interval time.Duration, text func() string) *Spinner {
+	return &Spinner{
+		out:      out,
+		delay:    delay,
+		interval: interval,
+		text:     text,
+		enabled:  true,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start starts the spinner in the background.
+func (s *Spinner) Start() {
+	if !s.enabled {
+		close(s.done)
+		return
+	}
+	go s.run()
+}
+
+// run draws frames from the delay until stopped, then clears the line.
+func (s *Spinner) run() {
+	defer close(s.done)
+
+	timer := time.NewTimer(s.delay)
+	defer timer.Stop()
+	select {
+	case <-s.stop:
+		return
+	case <-timer.C:
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for frame := 0; ; frame++ {
+		line := spinnerFrames[frame%len(spinnerFrames)] + " " + secret.Redact(s.text())
+		_, _ = fmt.Fprint(s.out, "\r\033[K"+line)
+		select {
+		case <-s.stop:
+			_, _ = fmt.Fprint(s.out, "\r\033[K")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop stops the spinner and clears its line. It returns once the line is
+// cleared, so that output written after it starts on a clean line. Stop may
+// be called more than once.
+func (s *Spinner) Stop() {
+	s.once.Do(func() { close(s.stop) })
+	<-s.done
+}
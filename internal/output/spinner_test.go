@@ -0,0 +1,67 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for the spinner's goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSpinner_DrawsAfterDelay(t *testing.T) {
+	var out syncBuffer
+	s := newSpinner(&out, 10*time.Millisecond, time.Millisecond, func() string { return "waiting on vault" })
+	s.Start()
+	time.Sleep(50 * time.Millisecond)
+	s.Stop()
+
+	got := out.String()
+	if !strings.Contains(got, "⠋ waiting on vault") {
+		t.Errorf("expected spinner frame, got %q", got)
+	}
+	if !strings.HasSuffix(got, "\r\033[K") {
+		t.Errorf("expected the line to be cleared on stop, got %q", got)
+	}
+}
+
+func TestSpinner_QuickOperationDrawsNothing(t *testing.T) {
+	var out syncBuffer
+	s := newSpinner(&out, time.Hour, time.Millisecond, func() string { return "waiting" })
+	s.Start()
+	s.Stop()
+	s.Stop()
+	if got := out.String(); got != "" {
+		t.Errorf("expected no output, got %q", got)
+	}
+}
+
+func TestWriter_Spinner_NotATerminal(t *testing.T) {
+	cmd, _, stderr := newTestCmd()
+	s := NewWriter(cmd).Spinner(func() string { return "waiting" })
+	if s.enabled {
+		t.Fatal("spinner must be disabled when stderr is not a terminal")
+	}
+	s.Start()
+	s.Stop()
+	if stderr.Len() != 0 {
+		t.Errorf("expected no output, got %q", stderr.String())
+	}
+}
@@ -4,10 +4,13 @@ package parser
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"unicode"
+	"unicode/utf16"
 )
 
 // RefPrefix is the URI scheme prefix for secret references in .env values.
@@ -16,6 +19,18 @@ const RefPrefix = "ref://"
 // bom is the UTF-8 Byte Order Mark sequence.
 const bom = "\xEF\xBB\xBF"
 
+// Byte Order Marks for encodings other than UTF-8.
+var (
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+	bomUTF32LE = []byte{0xFF, 0xFE, 0x00, 0x00}
+	bomUTF32BE = []byte{0x00, 0x00, 0xFE, 0xFF}
+)
+
+// ErrUnsupportedEncoding is returned when the input is in a text encoding
+// the parser cannot read (e.g., UTF-32, or UTF-16 without a byte order mark).
+var ErrUnsupportedEncoding = errors.New("unsupported encoding")
+
 // QuoteStyle indicates how a value was quoted in the .env file.
 type QuoteStyle int
 
@@ -28,6 +43,9 @@ const (
 	QuoteDouble
 	// QuoteBacktick means the value was wrapped in backticks (literal, no interpolation).
 	QuoteBacktick
+	// QuoteHeredoc means the value was written as a KEY<<DELIM heredoc block
+	// (literal, no interpolation).
+	QuoteHeredoc
 )
 
 // Entry represents a single key-value pair parsed from a .env file.
@@ -38,11 +56,58 @@ type Entry struct {
 	Raw string
 	// Line is the 1-based line number where this entry starts.
 	Line int
+	// EndLine is the 1-based line number where this entry ends. It is
+	// greater than Line for multiline quoted values and heredocs.
+	EndLine int
 	// IsRef is true when the parsed value starts with "ref://",
 	// indicating it is an unresolved secret reference.
 	IsRef bool
 	// Quote indicates how the value was quoted in the source file.
 	Quote QuoteStyle
+	// Annotations holds structured "# @name: value" comments that appeared
+	// directly above the entry, in source order. Nil when there are none.
+	Annotations []Annotation
+	// Comment is the block of ordinary comment lines directly above the
+	// entry (no blank line in between), with the leading "#" and one space
+	// stripped from each line and lines joined by "\n". Annotation lines are
+	// excluded.
+	Comment string
+	// InlineComment is the text of a trailing "# comment" on the entry's
+	// line, without the "#" and surrounding whitespace.
+	InlineComment string
+}
+
+// Annotation is a structured comment of the form "# @name: value" attached
+// to the entry that follows it (e.g., "# @type: int").
+type Annotation struct {
+	// Name is the lower-cased annotation name without the @ (e.g., "type").
+	Name string
+	// Value is the trimmed text after the colon.
+	Value string
+}
+
+// Well-known annotation names.
+const (
+	// AnnotationType declares the expected value type (e.g., int, bool, url).
+	AnnotationType = "type"
+	// AnnotationDescription documents the purpose of the key.
+	AnnotationDescription = "description"
+	// AnnotationExpand expands a ref to a JSON object into one variable
+	// per field, named with the annotation's value as prefix (the entry's
+	// key and "_" when empty).
+	AnnotationExpand = "expand"
+)
+
+// Annotation returns the value of the named annotation and whether it was
+// present. If the annotation appears more than once, the last value wins.
+func (e Entry) Annotation(name string) (string, bool) {
+	value, found := "", false
+	for _, a := range e.Annotations {
+		if a.Name == name {
+			value, found = a.Value, true
+		}
+	}
+	return value, found
 }
 
 // Warning represents a non-fatal issue detected during parsing.
@@ -72,19 +137,32 @@ func (e *ParseError) Error() string {
 //   - Double-quoted values (with escape processing: \n, \t, \\, \")
 //   - Backtick-quoted values (literal, no escape processing)
 //   - Multiline values inside double quotes
+//   - Heredoc blocks (KEY<<EOF ... EOF) for large multiline values
 //   - Comments (lines starting with #, and inline comments for unquoted values)
+//   - Annotation comments (# @type: int) attached to the following entry
 //   - Empty lines (skipped)
 //   - Whitespace trimming for unquoted values
 //   - UTF-8 BOM stripping (first line)
+//   - UTF-16 LE/BE input with a BOM (transcoded to UTF-8)
 //   - CRLF line ending normalization
 //   - Duplicate key detection (last wins, with warning)
+//   - Warnings for suspicious values (see checkValue) and keys that differ
+//     only by case
 func Parse(r io.Reader) ([]Entry, []Warning, error) {
+	r, err := decodeInput(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var entries []Entry
 	var warnings []Warning
-	seen := make(map[string]int) // key -> line number of first occurrence
+	seen := make(map[string]int)      // key -> line number of first occurrence
+	folded := make(map[string]string) // lower-cased key -> first spelling seen
 	scanner := bufio.NewScanner(r)
 	lineNum := 0
 	firstLine := true
+	var pending []Annotation // annotations waiting for the next entry
+	var comments []string    // comment lines waiting for the next entry
 
 	for scanner.Scan() {
 		lineNum++
@@ -99,9 +177,21 @@ func Parse(r io.Reader) ([]Entry, []Warning, error) {
 		// Strip trailing carriage return (handles CRLF line endings).
 		line = strings.TrimRight(line, "\r")
 
-		// Skip empty lines and comments.
+		// Skip empty lines and comments. A blank line detaches any pending
+		// comments and annotations; comment lines are collected for the next
+		// entry.
 		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || trimmed[0] == '#' {
+		if trimmed == "" {
+			pending = nil
+			comments = nil
+			continue
+		}
+		if trimmed[0] == '#' {
+			if a, ok := parseAnnotation(trimmed); ok {
+				pending = append(pending, a)
+			} else {
+				comments = append(comments, commentText(trimmed))
+			}
 			continue
 		}
 
@@ -111,26 +201,38 @@ func Parse(r io.Reader) ([]Entry, []Warning, error) {
 			trimmed = strings.TrimSpace(trimmed)
 		}
 
-		// Find the = separator.
-		eqIdx := strings.IndexByte(trimmed, '=')
-		if eqIdx < 0 {
-			// Lines without = are ignored (not an error, matches dotenv behavior).
-			continue
-		}
+		var key, value, raw string
+		var quote QuoteStyle
+		startLine := lineNum
 
-		key := strings.TrimSpace(trimmed[:eqIdx])
-		if key == "" {
-			continue
-		}
+		if hdKey, delim, ok := parseHeredocHeader(trimmed); ok {
+			// Heredoc block: KEY<<DELIM followed by body lines up to DELIM.
+			var err error
+			key = hdKey
+			quote = QuoteHeredoc
+			value, raw, lineNum, err = parseHeredoc(trimmed, delim, scanner, lineNum)
+			if err != nil {
+				return entries, warnings, &ParseError{Line: startLine, Message: err.Error()}
+			}
+		} else {
+			// Find the = separator.
+			eqIdx := strings.IndexByte(trimmed, '=')
+			if eqIdx < 0 {
+				// Lines without = are ignored (not an error, matches dotenv behavior).
+				continue
+			}
 
-		rawValue := trimmed[eqIdx+1:]
-		startLine := lineNum
+			key = strings.TrimSpace(trimmed[:eqIdx])
+			if key == "" {
+				continue
+			}
 
-		value, raw, newLineNum, quote, err := parseValue(rawValue, scanner, lineNum)
-		if err != nil {
-			return entries, warnings, &ParseError{Line: startLine, Message: err.Error()}
+			var err error
+			value, raw, lineNum, quote, err = parseValue(trimmed[eqIdx+1:], scanner, lineNum)
+			if err != nil {
+				return entries, warnings, &ParseError{Line: startLine, Message: err.Error()}
+			}
 		}
-		lineNum = newLineNum
 
 		// Check for duplicate keys.
 		if prevLine, exists := seen[key]; exists {
@@ -141,14 +243,33 @@ func Parse(r io.Reader) ([]Entry, []Warning, error) {
 		}
 		seen[key] = startLine
 
+		// Check for keys that differ only by case.
+		lower := strings.ToLower(key)
+		if prevKey, exists := folded[lower]; exists && prevKey != key {
+			warnings = append(warnings, Warning{
+				Line:    startLine,
+				Message: fmt.Sprintf("key %q differs only by case from %q (defined on line %d)", key, prevKey, seen[prevKey]),
+			})
+		} else if !exists {
+			folded[lower] = key
+		}
+
+		warnings = append(warnings, checkValue(key, value, quote, startLine)...)
+
 		entries = append(entries, Entry{
-			Key:   key,
-			Value: value,
-			Raw:   raw,
-			Line:  startLine,
-			IsRef: strings.HasPrefix(value, RefPrefix),
-			Quote: quote,
+			Key:           key,
+			Value:         value,
+			Raw:           raw,
+			Line:          startLine,
+			EndLine:       lineNum,
+			IsRef:         strings.HasPrefix(value, RefPrefix),
+			Quote:         quote,
+			Annotations:   pending,
+			Comment:       strings.Join(comments, "\n"),
+			InlineComment: inlineComment(raw, quote),
 		})
+		pending = nil
+		comments = nil
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -158,6 +279,106 @@ func Parse(r io.Reader) ([]Entry, []Warning, error) {
 	return entries, warnings, nil
 }
 
+// checkValue returns warnings for values that are likely mistakes:
+// trailing whitespace inside quotes, unterminated ${ interpolations in
+// values that are interpolated, and mistyped ref:// prefixes.
+func checkValue(key, value string, quote QuoteStyle, line int) []Warning {
+	var warnings []Warning
+	warn := func(format string, args ...interface{}) {
+		warnings = append(warnings, Warning{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if (quote == QuoteSingle || quote == QuoteDouble || quote == QuoteBacktick) &&
+		value != strings.TrimRightFunc(value, unicode.IsSpace) {
+		warn("value for %q has trailing whitespace inside quotes", key)
+	}
+
+	if (quote == QuoteNone || quote == QuoteDouble) && hasUnbalancedInterpolation(value) {
+		warn("value for %q has an unterminated ${ interpolation (missing closing brace)", key)
+	}
+
+	if !strings.HasPrefix(value, RefPrefix) && looksLikeRefTypo(value) {
+		warn("value for %q looks like a mistyped reference (expected %s<backend>/<path>)", key, RefPrefix)
+	}
+
+	return warnings
+}
+
+// hasUnbalancedInterpolation reports whether s contains a "${" with no
+// matching "}". A "$$" escape is skipped, matching interpolation rules.
+func hasUnbalancedInterpolation(s string) bool {
+	for i := 0; i < len(s)-1; i++ {
+		if s[i] != '$' {
+			continue
+		}
+		if s[i+1] == '$' {
+			i++
+			continue
+		}
+		if s[i+1] == '{' {
+			closeIdx := strings.IndexByte(s[i+2:], '}')
+			if closeIdx < 0 {
+				return true
+			}
+			i += 2 + closeIdx
+		}
+	}
+	return false
+}
+
+// looksLikeRefTypo reports whether value starts with something close to the
+// ref:// prefix: a wrong number of slashes, a missing colon, or the wrong case.
+func looksLikeRefTypo(value string) bool {
+	lower := strings.ToLower(value)
+	return strings.HasPrefix(lower, "ref:/") ||
+		strings.HasPrefix(lower, "ref//") ||
+		strings.HasPrefix(lower, "ref:\\")
+}
+
+// decodeInput inspects the start of the input for a byte order mark. UTF-16
+// input (LE or BE, with BOM) is transcoded to UTF-8. UTF-32 input and
+// UTF-16 without a BOM (detected by NUL bytes in the first characters) are
+// rejected with ErrUnsupportedEncoding. Other input is returned unchanged.
+func decodeInput(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(4)
+
+	switch {
+	case bytes.HasPrefix(head, bomUTF32LE), bytes.HasPrefix(head, bomUTF32BE):
+		return nil, fmt.Errorf("%w: UTF-32 is not supported, save the file as UTF-8", ErrUnsupportedEncoding)
+	case bytes.HasPrefix(head, bomUTF16LE):
+		return decodeUTF16(br, false)
+	case bytes.HasPrefix(head, bomUTF16BE):
+		return decodeUTF16(br, true)
+	case len(head) >= 2 && bytes.IndexByte(head, 0x00) >= 0:
+		return nil, fmt.Errorf("%w: input contains NUL bytes (UTF-16 without a byte order mark?), save the file as UTF-8", ErrUnsupportedEncoding)
+	}
+	return br, nil
+}
+
+// decodeUTF16 reads the remaining input as UTF-16 (skipping the 2-byte BOM)
+// and returns a reader over the equivalent UTF-8 text.
+func decodeUTF16(r io.Reader, bigEndian bool) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading input: %w", err)
+	}
+	data = data[2:]
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("%w: truncated UTF-16 input (odd number of bytes)", ErrUnsupportedEncoding)
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return strings.NewReader(string(utf16.Decode(units))), nil
+}
+
 // parseValue handles the value portion of a KEY=VALUE pair.
 // It returns the processed value, the raw value, the updated line number, the quote style, and any error.
 func parseValue(rawValue string, scanner *bufio.Scanner, lineNum int) (string, string, int, QuoteStyle, error) {
@@ -309,6 +530,128 @@ func parseBacktickQuoted(raw string, scanner *bufio.Scanner, lineNum int) (strin
 	}
 }
 
+// commentText strips the leading "#" and a single following space from a
+// comment line. Trailing whitespace is removed.
+func commentText(comment string) string {
+	text := strings.TrimPrefix(comment, "#")
+	text = strings.TrimPrefix(text, " ")
+	return strings.TrimRightFunc(text, unicode.IsSpace)
+}
+
+// inlineComment extracts the trailing "# comment" from a raw value. For
+// quoted values, only text after the closing quote is considered; for
+// unquoted values, the same " #" rule as stripInlineComment applies.
+// Heredoc values never carry an inline comment.
+func inlineComment(raw string, quote QuoteStyle) string {
+	var rest string
+	trimmed := strings.TrimLeftFunc(raw, unicode.IsSpace)
+	switch quote {
+	case QuoteNone:
+		stripped := stripInlineComment(raw)
+		rest = raw[len(stripped):]
+	case QuoteSingle:
+		if idx := strings.IndexByte(trimmed[1:], '\''); idx >= 0 {
+			rest = trimmed[idx+2:]
+		}
+	case QuoteDouble:
+		if idx, _ := findClosingDoubleQuote(trimmed[1:]); idx >= 0 {
+			rest = trimmed[idx+2:]
+		}
+	case QuoteBacktick:
+		if idx := strings.IndexByte(trimmed[1:], '`'); idx >= 0 {
+			rest = trimmed[idx+2:]
+		}
+	}
+
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "#") {
+		return ""
+	}
+	return strings.TrimSpace(rest[1:])
+}
+
+// parseAnnotation parses a comment line of the form "# @name: value".
+// Returns false if the comment is not an annotation.
+func parseAnnotation(comment string) (Annotation, bool) {
+	body := strings.TrimSpace(strings.TrimPrefix(comment, "#"))
+	if !strings.HasPrefix(body, "@") {
+		return Annotation{}, false
+	}
+	colonIdx := strings.IndexByte(body, ':')
+	if colonIdx < 0 {
+		return Annotation{}, false
+	}
+	name := strings.ToLower(strings.TrimRightFunc(body[1:colonIdx], unicode.IsSpace))
+	if !isIdentifier(name) {
+		return Annotation{}, false
+	}
+	return Annotation{Name: name, Value: strings.TrimSpace(body[colonIdx+1:])}, true
+}
+
+// parseHeredocHeader checks whether line opens a heredoc block of the form
+// KEY<<DELIM. The << must appear before any = sign, the key must not contain
+// whitespace, and the delimiter must be a valid identifier. Returns the key,
+// the delimiter, and true when the line is a heredoc header.
+func parseHeredocHeader(line string) (string, string, bool) {
+	idx := strings.Index(line, "<<")
+	if idx <= 0 {
+		return "", "", false
+	}
+	if eqIdx := strings.IndexByte(line, '='); eqIdx >= 0 && eqIdx < idx {
+		return "", "", false
+	}
+
+	key := strings.TrimSpace(line[:idx])
+	delim := strings.TrimSpace(line[idx+2:])
+	if key == "" || strings.IndexFunc(key, unicode.IsSpace) >= 0 || !isIdentifier(delim) {
+		return "", "", false
+	}
+	return key, delim, true
+}
+
+// parseHeredoc collects the body of a heredoc block. Every line after the
+// header up to (but excluding) a line consisting solely of the delimiter is
+// part of the value, verbatim — no escape processing or comment stripping.
+// Lines are joined with \n and the value has no trailing newline.
+func parseHeredoc(header, delim string, scanner *bufio.Scanner, lineNum int) (string, string, int, error) {
+	var fullRaw strings.Builder
+	fullRaw.WriteString(header)
+	var body []string
+
+	for {
+		if !scanner.Scan() {
+			return "", fullRaw.String(), lineNum, fmt.Errorf("unterminated heredoc (expected closing %q)", delim)
+		}
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		fullRaw.WriteByte('\n')
+		fullRaw.WriteString(line)
+
+		if strings.TrimSpace(line) == delim {
+			return strings.Join(body, "\n"), fullRaw.String(), lineNum, nil
+		}
+		body = append(body, line)
+	}
+}
+
+// isIdentifier reports whether s is a non-empty identifier made of ASCII
+// letters, digits, and underscores, not starting with a digit.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z'):
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // parseUnquoted processes an unquoted value: trims whitespace and strips inline comments.
 func parseUnquoted(raw string) string {
 	// Inline comments: strip everything after an unquoted #.
@@ -1,9 +1,12 @@
 package parser
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"unicode/utf16"
 )
 
 func TestParse(t *testing.T) {
@@ -1078,6 +1081,9 @@ func TestParseLineNumbers(t *testing.T) {
 	if got[1].Line != 4 {
 		t.Errorf("MULTI line: got %d, want 4", got[1].Line)
 	}
+	if got[1].EndLine != 6 {
+		t.Errorf("MULTI end line: got %d, want 6", got[1].EndLine)
+	}
 	// MULTI spans lines 4-6, so AFTER is on line 7.
 	if got[2].Line != 7 {
 		t.Errorf("AFTER line: got %d, want 7", got[2].Line)
@@ -1345,3 +1351,465 @@ export NODE_ENV=production
 		}
 	}
 }
+
+// TestParseHeredoc tests KEY<<DELIM heredoc blocks.
+func TestParseHeredoc(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantKey   string
+		wantValue string
+		wantLine  int
+	}{
+		{
+			name:      "basic heredoc",
+			input:     "CERT<<EOF\nline1\nline2\nEOF",
+			wantKey:   "CERT",
+			wantValue: "line1\nline2",
+			wantLine:  1,
+		},
+		{
+			name:      "custom delimiter",
+			input:     "JSON<<END_JSON\n{\"a\": \"b\"}\nEND_JSON\n",
+			wantKey:   "JSON",
+			wantValue: `{"a": "b"}`,
+			wantLine:  1,
+		},
+		{
+			name:      "export prefix",
+			input:     "export CERT<<EOF\nabc\nEOF",
+			wantKey:   "CERT",
+			wantValue: "abc",
+			wantLine:  1,
+		},
+		{
+			name:      "body is literal",
+			input:     "RAW<<EOF\n# not a comment\n\"quoted\" \\n $HOME\nEOF",
+			wantKey:   "RAW",
+			wantValue: "# not a comment\n\"quoted\" \\n $HOME",
+			wantLine:  1,
+		},
+		{
+			name:      "empty body",
+			input:     "EMPTY<<EOF\nEOF",
+			wantKey:   "EMPTY",
+			wantValue: "",
+			wantLine:  1,
+		},
+		{
+			name:      "preserves blank lines and indentation",
+			input:     "BLOCK<<EOF\n  indented\n\nafter blank\nEOF",
+			wantKey:   "BLOCK",
+			wantValue: "  indented\n\nafter blank",
+			wantLine:  1,
+		},
+		{
+			name:      "CRLF line endings",
+			input:     "CERT<<EOF\r\nline1\r\nline2\r\nEOF\r\n",
+			wantKey:   "CERT",
+			wantValue: "line1\nline2",
+			wantLine:  1,
+		},
+		{
+			name:      "heredoc after other entries",
+			input:     "A=1\n\nCERT<<EOF\nx\nEOF",
+			wantKey:   "CERT",
+			wantValue: "x",
+			wantLine:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) == 0 {
+				t.Fatal("expected at least 1 entry, got 0")
+			}
+			last := got[len(got)-1]
+			if last.Key != tt.wantKey {
+				t.Errorf("Key: got %q, want %q", last.Key, tt.wantKey)
+			}
+			if last.Value != tt.wantValue {
+				t.Errorf("Value: got %q, want %q", last.Value, tt.wantValue)
+			}
+			if last.Line != tt.wantLine {
+				t.Errorf("Line: got %d, want %d", last.Line, tt.wantLine)
+			}
+			if last.Quote != QuoteHeredoc {
+				t.Errorf("Quote: got %d, want %d", last.Quote, QuoteHeredoc)
+			}
+		})
+	}
+}
+
+// TestParseHeredocFollowedByEntries verifies that parsing continues after
+// the closing delimiter with correct line numbers.
+func TestParseHeredocFollowedByEntries(t *testing.T) {
+	input := "CERT<<EOF\na\nb\nEOF\nNEXT=value\n"
+	got, _, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %+v", len(got), got)
+	}
+	if got[0].EndLine != 4 {
+		t.Errorf("CERT end line: got %d, want 4", got[0].EndLine)
+	}
+	if got[1].Key != "NEXT" || got[1].Value != "value" || got[1].Line != 5 {
+		t.Errorf("entry[1]: got {%q, %q, line %d}, want {\"NEXT\", \"value\", line 5}", got[1].Key, got[1].Value, got[1].Line)
+	}
+}
+
+// TestParseHeredocUnterminated verifies that a missing closing delimiter is
+// reported as a ParseError on the header line.
+func TestParseHeredocUnterminated(t *testing.T) {
+	_, _, err := Parse(strings.NewReader("A=1\nCERT<<EOF\nline1\nline2"))
+	if err == nil {
+		t.Fatal("expected error for unterminated heredoc")
+	}
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected *ParseError, got %T", err)
+	}
+	if pe.Line != 2 {
+		t.Errorf("expected error on line 2, got line %d", pe.Line)
+	}
+	if !strings.Contains(pe.Message, "heredoc") {
+		t.Errorf("expected heredoc in message, got %q", pe.Message)
+	}
+}
+
+// TestParseHeredocNotTriggered verifies that << inside ordinary values and
+// malformed headers do not start a heredoc.
+func TestParseHeredocNotTriggered(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantKey   string
+		wantValue string
+	}{
+		{
+			name:      "<< after equals sign",
+			input:     "CMD=cat <<EOF",
+			wantKey:   "CMD",
+			wantValue: "cat <<EOF",
+		},
+		{
+			name:      "invalid delimiter",
+			input:     "A<<B=C",
+			wantKey:   "A<<B",
+			wantValue: "C",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("expected 1 entry, got %d", len(got))
+			}
+			if got[0].Key != tt.wantKey || got[0].Value != tt.wantValue {
+				t.Errorf("got {%q, %q}, want {%q, %q}", got[0].Key, got[0].Value, tt.wantKey, tt.wantValue)
+			}
+			if got[0].Quote == QuoteHeredoc {
+				t.Error("Quote: unexpected QuoteHeredoc")
+			}
+		})
+	}
+}
+
+// TestParseAnnotations tests "# @name: value" comments attached to entries.
+func TestParseAnnotations(t *testing.T) {
+	input := strings.Join([]string{
+		"# @type: int",
+		"# @description: HTTP listen port",
+		"PORT=8080",
+		"# plain comment",
+		"HOST=localhost",
+		"# @type: bool",
+		"",
+		"DEBUG=true",
+		"# @Type: url",
+		"# free text between annotations",
+		"# @description: Public URL",
+		"export URL=https://example.com",
+		"# @ bad: ignored",
+		"# @nocolon",
+		"OTHER=x",
+	}, "\n")
+
+	got, _, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(got))
+	}
+
+	if typ, ok := got[0].Annotation(AnnotationType); !ok || typ != "int" {
+		t.Errorf("PORT type: got %q (%v), want \"int\"", typ, ok)
+	}
+	if desc, _ := got[0].Annotation(AnnotationDescription); desc != "HTTP listen port" {
+		t.Errorf("PORT description: got %q, want %q", desc, "HTTP listen port")
+	}
+	if got[1].Annotations != nil {
+		t.Errorf("HOST: expected no annotations, got %+v", got[1].Annotations)
+	}
+	if got[2].Annotations != nil {
+		t.Errorf("DEBUG: blank line should detach annotations, got %+v", got[2].Annotations)
+	}
+	if typ, _ := got[3].Annotation(AnnotationType); typ != "url" {
+		t.Errorf("URL type: got %q, want \"url\"", typ)
+	}
+	if desc, _ := got[3].Annotation(AnnotationDescription); desc != "Public URL" {
+		t.Errorf("URL description: got %q, want %q", desc, "Public URL")
+	}
+	if got[4].Annotations != nil {
+		t.Errorf("OTHER: expected no annotations, got %+v", got[4].Annotations)
+	}
+}
+
+func TestEntryAnnotationLastWins(t *testing.T) {
+	e := Entry{Annotations: []Annotation{{Name: "type", Value: "int"}, {Name: "type", Value: "port"}}}
+	if typ, _ := e.Annotation("type"); typ != "port" {
+		t.Errorf("got %q, want %q", typ, "port")
+	}
+	if _, ok := e.Annotation("description"); ok {
+		t.Error("expected missing annotation to report false")
+	}
+}
+
+// TestParseComments tests leading comment blocks and inline comments
+// attached to entries.
+func TestParseComments(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantComment string
+		wantInline  string
+	}{
+		{
+			name:        "single leading comment",
+			input:       "# Database host\nDB_HOST=localhost",
+			wantComment: "Database host",
+		},
+		{
+			name:        "multi-line leading block",
+			input:       "# line one\n#\n#   indented\nKEY=v",
+			wantComment: "line one\n\n  indented",
+		},
+		{
+			name:  "blank line detaches comment",
+			input: "# header\n\nKEY=v",
+		},
+		{
+			name:        "annotations excluded from comment",
+			input:       "# The port\n# @type: int\nPORT=80",
+			wantComment: "The port",
+		},
+		{
+			name:       "inline comment on unquoted value",
+			input:      "PORT=80 # listen port",
+			wantInline: "listen port",
+		},
+		{
+			name:  "hash without space is part of value",
+			input: "COLOR=#fff",
+		},
+		{
+			name:       "inline comment after double quotes",
+			input:      `MSG="a # not comment" # real comment`,
+			wantInline: "real comment",
+		},
+		{
+			name:       "inline comment after single quotes",
+			input:      `MSG='x' #note`,
+			wantInline: "note",
+		},
+		{
+			name:       "inline comment after backticks",
+			input:      "MSG=`x` # tick",
+			wantInline: "tick",
+		},
+		{
+			name:       "inline comment after multiline double quotes",
+			input:      "MSG=\"a\nb\" # end",
+			wantInline: "end",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("expected 1 entry, got %d", len(got))
+			}
+			if got[0].Comment != tt.wantComment {
+				t.Errorf("Comment: got %q, want %q", got[0].Comment, tt.wantComment)
+			}
+			if got[0].InlineComment != tt.wantInline {
+				t.Errorf("InlineComment: got %q, want %q", got[0].InlineComment, tt.wantInline)
+			}
+		})
+	}
+}
+
+// TestParseCommentsResetBetweenEntries verifies that a comment block only
+// attaches to the entry directly below it.
+func TestParseCommentsResetBetweenEntries(t *testing.T) {
+	got, _, err := Parse(strings.NewReader("# first\nA=1\nB=2\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got[0].Comment != "first" {
+		t.Errorf("A comment: got %q, want %q", got[0].Comment, "first")
+	}
+	if got[1].Comment != "" {
+		t.Errorf("B comment: got %q, want empty", got[1].Comment)
+	}
+}
+
+// encodeUTF16 encodes s as UTF-16 with a byte order mark.
+func encodeUTF16(s string, bigEndian bool) []byte {
+	var out []byte
+	if bigEndian {
+		out = append(out, 0xFE, 0xFF)
+	} else {
+		out = append(out, 0xFF, 0xFE)
+	}
+	for _, u := range utf16.Encode([]rune(s)) {
+		if bigEndian {
+			out = append(out, byte(u>>8), byte(u))
+		} else {
+			out = append(out, byte(u), byte(u>>8))
+		}
+	}
+	return out
+}
+
+// TestParseUTF16 verifies that UTF-16 input with a BOM is transcoded.
+func TestParseUTF16(t *testing.T) {
+	input := "FOO=bar\r\nGREETING=\"héllo 世界\"\r\n"
+	for _, bigEndian := range []bool{false, true} {
+		t.Run(fmt.Sprintf("bigEndian=%v", bigEndian), func(t *testing.T) {
+			got, _, err := Parse(bytes.NewReader(encodeUTF16(input, bigEndian)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("expected 2 entries, got %d: %+v", len(got), got)
+			}
+			if got[0].Key != "FOO" || got[0].Value != "bar" {
+				t.Errorf("entry[0]: got {%q, %q}", got[0].Key, got[0].Value)
+			}
+			if got[1].Key != "GREETING" || got[1].Value != "héllo 世界" {
+				t.Errorf("entry[1]: got {%q, %q}", got[1].Key, got[1].Value)
+			}
+		})
+	}
+}
+
+// TestParseUnsupportedEncoding verifies that unreadable encodings fail with
+// ErrUnsupportedEncoding instead of producing garbage keys.
+func TestParseUnsupportedEncoding(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "UTF-32 LE", input: []byte{0xFF, 0xFE, 0x00, 0x00, 'A', 0, 0, 0}},
+		{name: "UTF-32 BE", input: []byte{0x00, 0x00, 0xFE, 0xFF, 0, 0, 0, 'A'}},
+		{name: "UTF-16 LE without BOM", input: []byte{'A', 0, '=', 0, '1', 0}},
+		{name: "truncated UTF-16", input: []byte{0xFF, 0xFE, 'A', 0, '='}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse(bytes.NewReader(tt.input))
+			if !errors.Is(err, ErrUnsupportedEncoding) {
+				t.Errorf("expected ErrUnsupportedEncoding, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseSuspiciousValueWarnings(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string // substring of the expected warning; empty means none
+	}{
+		{name: "trailing space in double quotes", input: `A="value "`, want: "trailing whitespace inside quotes"},
+		{name: "trailing tab in single quotes", input: "A='value\t'", want: "trailing whitespace inside quotes"},
+		{name: "quoted value without trailing space", input: `A=" value"`},
+		{name: "unterminated interpolation", input: `A=${HOST:3000`, want: "unterminated ${"},
+		{name: "unterminated interpolation in double quotes", input: `A="${HOST"`, want: "unterminated ${"},
+		{name: "unterminated in single quotes is literal", input: `A='${HOST'`},
+		{name: "balanced interpolation", input: `A=${HOST}:${PORT}`},
+		{name: "escaped dollar", input: `A=$${HOST`},
+		{name: "ref single slash", input: `A=ref:/keychain/key`, want: "mistyped reference"},
+		{name: "ref missing colon", input: `A=ref//keychain/key`, want: "mistyped reference"},
+		{name: "ref upper case", input: `A=REF://keychain/key`, want: "mistyped reference"},
+		{name: "valid ref", input: `A=ref://keychain/key`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, warnings, err := Parse(strings.NewReader(tt.input + "\n"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.want == "" {
+				if len(warnings) != 0 {
+					t.Errorf("expected no warnings, got %v", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 {
+				t.Fatalf("expected 1 warning, got %v", warnings)
+			}
+			if !strings.Contains(warnings[0].Message, tt.want) {
+				t.Errorf("warning = %q, want substring %q", warnings[0].Message, tt.want)
+			}
+			if warnings[0].Line != 1 {
+				t.Errorf("warning line = %d, want 1", warnings[0].Line)
+			}
+		})
+	}
+}
+
+func TestParseCaseInsensitiveDuplicateWarning(t *testing.T) {
+	input := "API_KEY=one\nOTHER=x\nApi_Key=two\nAPI_KEY=three\n"
+	entries, warnings, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+
+	var caseWarnings []Warning
+	for _, w := range warnings {
+		if strings.Contains(w.Message, "differs only by case") {
+			caseWarnings = append(caseWarnings, w)
+		}
+	}
+	if len(caseWarnings) != 1 {
+		t.Fatalf("expected 1 case warning, got %v", warnings)
+	}
+	if caseWarnings[0].Line != 3 {
+		t.Errorf("warning line = %d, want 3", caseWarnings[0].Line)
+	}
+	if !strings.Contains(caseWarnings[0].Message, `"API_KEY"`) || !strings.Contains(caseWarnings[0].Message, "line 1") {
+		t.Errorf("unexpected message: %q", caseWarnings[0].Message)
+	}
+}
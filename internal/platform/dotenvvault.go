@@ -0,0 +1,340 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/xcke/envref/internal/parser"
+)
+
+// dotenvKeysFile is the file, next to a .env.vault, that holds the
+// DOTENV_KEY of each environment.
+const dotenvKeysFile = ".env.keys"
+
+// Headers of the files that dotenv-vault writes.
+const (
+	dotenvVaultHeader = `#/-------------------.env.vault---------------------/
+#/         cloud-agnostic vaulting standard         /
+#/   [how it works](https://dotenv.org/env-vault)   /
+#/--------------------------------------------------/
+`
+	dotenvKeysHeader = `#/!!!!!!!!!!!!!!!!!!!.env.keys!!!!!!!!!!!!!!!!!!!!!!/
+#/   DOTENV_KEYs. DO NOT commit to source control   /
+#/   [how it works](https://dotenv.org/env-keys)    /
+#/--------------------------------------------------/
+`
+)
+
+// DotenvVault is one environment of a .env.vault file, the format of
+// dotenv-vault: each environment's .env content is encrypted with
+// AES-256-GCM under its own key and stored as DOTENV_VAULT_<ENVIRONMENT>.
+//
+// The key is a DOTENV_KEY URI, such as
+// dotenv://:key_<hex>@dotenv.local/vault/.env.vault?environment=production,
+// read from the DOTENV_KEY environment variable (a comma-separated list)
+// or from DOTENV_KEY_<ENVIRONMENT> in the .env.keys file next to the
+// vault.
+type DotenvVault struct {
+	path        string
+	environment string
+}
+
+// NewDotenvVault returns environment of the .env.vault file at path.
+func NewDotenvVault(path, environment string) *DotenvVault {
+	return &DotenvVault{path: path, environment: environment}
+}
+
+// openDotenvVault opens the vault file at path.
+func openDotenvVault(path, environment string) (Platform, error) {
+	return NewDotenvVault(path, environment), nil
+}
+
+// Describe implements Platform.
+func (d *DotenvVault) Describe() string {
+	return fmt.Sprintf("dotenv-vault environment %s of %s", d.environment, d.path)
+}
+
+// Readable implements Platform.
+func (d *DotenvVault) Readable() bool {
+	return true
+}
+
+// Vars implements Platform. A missing file or environment has no vars.
+func (d *DotenvVault) Vars(_ context.Context) (map[string]string, error) {
+	ciphertext, err := d.ciphertext()
+	if err != nil || ciphertext == "" {
+		return map[string]string{}, err
+	}
+	key, err := d.key()
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, fmt.Errorf("dotenv-vault: no key for environment %s (set DOTENV_KEY or add %s to %s)",
+			d.environment, d.keyName(), d.keysPath())
+	}
+	plaintext, err := decryptDotenvVault(ciphertext, key)
+	if err != nil {
+		return nil, fmt.Errorf("dotenv-vault: environment %s: %w", d.environment, err)
+	}
+	entries, _, err := parser.Parse(strings.NewReader(plaintext))
+	if err != nil {
+		return nil, fmt.Errorf("dotenv-vault: environment %s: %w", d.environment, err)
+	}
+	vars := make(map[string]string, len(entries))
+	for _, e := range entries {
+		vars[e.Key] = e.Value
+	}
+	return vars, nil
+}
+
+// Apply implements Platform. The environment is encrypted again as a
+// whole. A new environment gets a new key, which is added to the
+// .env.keys file.
+func (d *DotenvVault) Apply(ctx context.Context, set map[string]string, unset []string) error {
+	vars, err := d.Vars(ctx)
+	if err != nil {
+		return err
+	}
+	maps.Copy(vars, set)
+	for _, key := range unset {
+		delete(vars, key)
+	}
+
+	key, err := d.key()
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		if key, err = d.newKey(); err != nil {
+			return err
+		}
+	}
+
+	var plaintext strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		fmt.Fprintf(&plaintext, "%s=%s\n", k, dotenvQuote(vars[k]))
+	}
+	ciphertext, err := encryptDotenvVault(plaintext.String(), key)
+	if err != nil {
+		return fmt.Errorf("dotenv-vault: %w", err)
+	}
+	if err := upsertDotenvVar(d.path, d.vaultName(), ciphertext, d.environment, dotenvVaultHeader, 0o644); err != nil {
+		return fmt.Errorf("dotenv-vault: writing %s: %w", d.path, err)
+	}
+	return nil
+}
+
+// vaultName is the variable of the environment in the vault file.
+func (d *DotenvVault) vaultName() string {
+	return "DOTENV_VAULT_" + strings.ToUpper(d.environment)
+}
+
+// keyName is the variable of the environment's key in the .env.keys file.
+func (d *DotenvVault) keyName() string {
+	return "DOTENV_KEY_" + strings.ToUpper(d.environment)
+}
+
+// keysPath is the .env.keys file next to the vault file.
+func (d *DotenvVault) keysPath() string {
+	return filepath.Join(filepath.Dir(d.path), dotenvKeysFile)
+}
+
+// ciphertext returns the encrypted environment from the vault file, or ""
+// if the file or the environment does not exist.
+func (d *DotenvVault) ciphertext() (string, error) {
+	vars, err := readDotenvFile(d.path)
+	if err != nil {
+		return "", fmt.Errorf("dotenv-vault: reading %s: %w", d.path, err)
+	}
+	return vars[d.vaultName()], nil
+}
+
+// key returns the DOTENV_KEY URI of the environment from the DOTENV_KEY
+// environment variable or the .env.keys file, or "" if neither has one.
+func (d *DotenvVault) key() (string, error) {
+	for uri := range strings.SplitSeq(os.Getenv("DOTENV_KEY"), ",") {
+		uri = strings.TrimSpace(uri)
+		if uri == "" {
+			continue
+		}
+		_, environment, err := parseDotenvKey(uri)
+		if err != nil {
+			return "", fmt.Errorf("dotenv-vault: DOTENV_KEY: %w", err)
+		}
+		if environment == d.environment {
+			return uri, nil
+		}
+	}
+	keys, err := readDotenvFile(d.keysPath())
+	if err != nil {
+		return "", fmt.Errorf("dotenv-vault: reading %s: %w", d.keysPath(), err)
+	}
+	return keys[d.keyName()], nil
+}
+
+// newKey generates a key for the environment and adds it to the .env.keys
+// file, before anything is encrypted with it.
+func (d *DotenvVault) newKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return "", fmt.Errorf("dotenv-vault: generating key: %w", err)
+	}
+	uri := (&url.URL{
+		Scheme:   "dotenv",
+		User:     url.UserPassword("", "key_"+hex.EncodeToString(secret)),
+		Host:     "dotenv.local",
+		Path:     "/vault/.env.vault",
+		RawQuery: url.Values{"environment": {d.environment}}.Encode(),
+	}).String()
+	if err := upsertDotenvVar(d.keysPath(), d.keyName(), uri, d.environment, dotenvKeysHeader, 0o600); err != nil {
+		return "", fmt.Errorf("dotenv-vault: writing %s: %w", d.keysPath(), err)
+	}
+	return uri, nil
+}
+
+// parseDotenvKey returns the AES key and the environment of a DOTENV_KEY
+// URI.
+func parseDotenvKey(uri string) ([]byte, string, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "dotenv" {
+		return nil, "", errors.New("invalid key: expected dotenv://:key_<hex>@dotenv.org/vault/.env.vault?environment=<name>")
+	}
+	password, _ := u.User.Password()
+	environment := u.Query().Get("environment")
+	if password == "" || environment == "" {
+		return nil, "", errors.New("invalid key: missing key or environment")
+	}
+	// As in dotenv, the key is the last 64 hex digits of the password.
+	secret, err := hex.DecodeString(password[max(0, len(password)-64):])
+	if err != nil || len(secret) != 32 {
+		return nil, "", errors.New("invalid key: expected 64 hex digits")
+	}
+	return secret, environment, nil
+}
+
+// decryptDotenvVault decrypts ciphertext, the base64 of a 12-byte nonce
+// and the AES-256-GCM sealed text, with the DOTENV_KEY URI key.
+func decryptDotenvVault(ciphertext, key string) (string, error) {
+	aead, err := dotenvVaultCipher(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("ciphertext is not valid base64: %w", err)
+	}
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return "", errors.New("ciphertext is truncated")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt with this key")
+	}
+	return string(plaintext), nil
+}
+
+// encryptDotenvVault encrypts plaintext as decryptDotenvVault reads it.
+func encryptDotenvVault(plaintext, key string) (string, error) {
+	aead, err := dotenvVaultCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("generating nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+// dotenvVaultCipher returns the AES-256-GCM cipher of a DOTENV_KEY URI.
+func dotenvVaultCipher(key string) (cipher.AEAD, error) {
+	secret, _, err := parseDotenvKey(key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// dotenvQuote quotes value so that both dotenv and envref read it back
+// literally: in single quotes, or in backticks if it holds a single quote
+// or a newline. Values that hold a backtick too are double-quoted with
+// escapes, which dotenv reads back only if they hold no double quote.
+func dotenvQuote(value string) string {
+	switch {
+	case !strings.ContainsAny(value, "'\n\r"):
+		return "'" + value + "'"
+	case !strings.Contains(value, "`"):
+		return "`" + value + "`"
+	default:
+		r := strings.NewReplacer(`"`, `\"`, "\n", `\n`, "\r", `\r`)
+		return `"` + r.Replace(value) + `"`
+	}
+}
+
+// readDotenvFile returns the variables of the dotenv file at path, or
+// none if it does not exist.
+func readDotenvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // user-specified vault file
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := parser.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string, len(entries))
+	for _, e := range entries {
+		vars[e.Key] = e.Value
+	}
+	return vars, nil
+}
+
+// upsertDotenvVar sets name to value, double-quoted, in the dotenv file at
+// path, replacing its line or appending it under a "# environment"
+// comment. A new file starts with header and is created with perm.
+func upsertDotenvVar(path, name, value, environment, header string, perm os.FileMode) error {
+	data, err := os.ReadFile(path) //nolint:gosec // user-specified vault file
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		data = []byte(header)
+	case err != nil:
+		return err
+	}
+
+	line := fmt.Sprintf("%s=%q", name, value)
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	replaced := false
+	for i, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), name+"=") {
+			lines[i] = line
+			replaced = true
+		}
+	}
+	if !replaced {
+		lines = append(lines, "", "# "+environment, line)
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), perm)
+}
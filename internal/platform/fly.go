@@ -0,0 +1,113 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Fly is the secrets of a Fly.io app, read and written with flyctl. Fly
+// never reveals secret values, so only their names can be read.
+type Fly struct {
+	app     string
+	command string
+}
+
+// NewFly returns the secrets of app, managed with the flyctl executable
+// at command.
+func NewFly(app, command string) *Fly {
+	return &Fly{app: app, command: command}
+}
+
+// openFly finds flyctl, which is also installed as fly, in PATH.
+func openFly(app, _ string) (Platform, error) {
+	for _, name := range []string{"flyctl", "fly"} {
+		if command, err := exec.LookPath(name); err == nil {
+			return NewFly(app, command), nil
+		}
+	}
+	return nil, fmt.Errorf("flyctl not found in PATH (install it from https://fly.io/docs/flyctl/install/)")
+}
+
+// Describe implements Platform.
+func (f *Fly) Describe() string {
+	return "fly app " + f.app
+}
+
+// Readable implements Platform.
+func (f *Fly) Readable() bool {
+	return false
+}
+
+// Vars implements Platform. The values are empty.
+func (f *Fly) Vars(ctx context.Context) (map[string]string, error) {
+	out, err := f.run(ctx, nil, "secrets", "list", "--app", f.app, "--json")
+	if err != nil {
+		return nil, fmt.Errorf("fly: listing secrets: %w", err)
+	}
+	var secrets []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(out, &secrets); err != nil {
+		return nil, fmt.Errorf("fly: listing secrets: %w", err)
+	}
+	vars := make(map[string]string, len(secrets))
+	for _, s := range secrets {
+		vars[s.Name] = ""
+	}
+	return vars, nil
+}
+
+// Apply implements Platform. The values are sent on stdin, never as
+// arguments. Fly deploys the app once, after the last change.
+func (f *Fly) Apply(ctx context.Context, set map[string]string, unset []string) error {
+	if len(set) > 0 {
+		var stdin bytes.Buffer
+		for _, key := range slices.Sorted(maps.Keys(set)) {
+			value := set[key]
+			if strings.Contains(value, "\n") {
+				value = `"""` + value + `"""`
+			}
+			fmt.Fprintf(&stdin, "%s=%s\n", key, value)
+		}
+		args := []string{"secrets", "import", "--app", f.app}
+		if len(unset) > 0 {
+			// Unset deploys the app with the staged secrets.
+			args = append(args, "--stage")
+		}
+		if _, err := f.run(ctx, stdin.Bytes(), args...); err != nil {
+			return fmt.Errorf("fly: setting secrets: %w", err)
+		}
+	}
+	if len(unset) > 0 {
+		args := append([]string{"secrets", "unset", "--app", f.app}, unset...)
+		if _, err := f.run(ctx, nil, args...); err != nil {
+			return fmt.Errorf("fly: removing secrets: %w", err)
+		}
+	}
+	return nil
+}
+
+// run runs flyctl with args and stdin, and returns its stdout. On failure
+// the error holds flyctl's stderr.
+func (f *Fly) run(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	c := exec.CommandContext(ctx, f.command, args...) //nolint:gosec // flyctl from PATH
+	c.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); errors.As(err, &exitErr) && msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
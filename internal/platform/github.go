@@ -0,0 +1,131 @@
+package platform
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// GitHubAPI is the GitHub REST API.
+const GitHubAPI = "https://api.github.com"
+
+// githubPageSize is the number of secrets requested per page.
+const githubPageSize = 100
+
+// GitHubSecrets is the GitHub Actions secrets of a repository, or of one of
+// its deployment environments. GitHub never reveals secret values: they
+// are written encrypted to the repository's public key.
+type GitHubSecrets struct {
+	repo        string
+	environment string
+	api         *apiClient
+}
+
+// NewGitHubSecrets returns the secrets of repo (owner/name), or of its
+// environment if that is not empty, accessed through the API at apiURL
+// (GitHubAPI, or a GitHub Enterprise Server's) with token.
+func NewGitHubSecrets(apiURL, token, repo, environment string) *GitHubSecrets {
+	return &GitHubSecrets{repo: repo, environment: environment, api: &apiClient{
+		baseURL: strings.TrimSuffix(apiURL, "/"),
+		headers: map[string]string{
+			"Accept":               "application/vnd.github+json",
+			"Authorization":        "Bearer " + token,
+			"X-GitHub-Api-Version": "2022-11-28",
+		},
+		client: &http.Client{Timeout: httpTimeout},
+	}}
+}
+
+// Describe implements Platform.
+func (g *GitHubSecrets) Describe() string {
+	if g.environment == "" {
+		return "github repository " + g.repo
+	}
+	return fmt.Sprintf("github environment %s of %s", g.environment, g.repo)
+}
+
+// Readable implements Platform.
+func (g *GitHubSecrets) Readable() bool {
+	return false
+}
+
+// Vars implements Platform. It returns the secret names, with empty
+// values.
+func (g *GitHubSecrets) Vars(ctx context.Context) (map[string]string, error) {
+	vars := make(map[string]string)
+	for page := 1; ; page++ {
+		var list struct {
+			TotalCount int `json:"total_count"`
+			Secrets    []struct {
+				Name string `json:"name"`
+			} `json:"secrets"`
+		}
+		query := url.Values{"per_page": {strconv.Itoa(githubPageSize)}, "page": {strconv.Itoa(page)}}
+		if err := g.api.do(ctx, http.MethodGet, g.path("")+"?"+query.Encode(), nil, &list); err != nil {
+			return nil, fmt.Errorf("github: %w", err)
+		}
+		for _, s := range list.Secrets {
+			vars[s.Name] = ""
+		}
+		if len(list.Secrets) < githubPageSize || len(vars) >= list.TotalCount {
+			return vars, nil
+		}
+	}
+}
+
+// Apply implements Platform. Values are sealed to the public key of the
+// repository or environment before they are sent.
+func (g *GitHubSecrets) Apply(ctx context.Context, set map[string]string, unset []string) error {
+	if len(set) > 0 {
+		var pub struct {
+			KeyID string `json:"key_id"`
+			Key   string `json:"key"`
+		}
+		if err := g.api.do(ctx, http.MethodGet, g.path("/public-key"), nil, &pub); err != nil {
+			return fmt.Errorf("github: reading public key: %w", err)
+		}
+		raw, err := base64.StdEncoding.DecodeString(pub.Key)
+		if err != nil || len(raw) != 32 {
+			return fmt.Errorf("github: invalid public key %q", pub.Key)
+		}
+		var key [32]byte
+		copy(key[:], raw)
+
+		for _, name := range slices.Sorted(maps.Keys(set)) {
+			sealed, err := box.SealAnonymous(nil, []byte(set[name]), &key, rand.Reader)
+			if err != nil {
+				return fmt.Errorf("github: encrypting %s: %w", name, err)
+			}
+			body := map[string]string{
+				"encrypted_value": base64.StdEncoding.EncodeToString(sealed),
+				"key_id":          pub.KeyID,
+			}
+			if err := g.api.do(ctx, http.MethodPut, g.path("/"+url.PathEscape(name)), body, nil); err != nil {
+				return fmt.Errorf("github: setting %s: %w", name, err)
+			}
+		}
+	}
+	for _, name := range unset {
+		if err := g.api.do(ctx, http.MethodDelete, g.path("/"+url.PathEscape(name)), nil, nil); err != nil {
+			return fmt.Errorf("github: removing %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// path returns the API path of the secrets, followed by suffix.
+func (g *GitHubSecrets) path(suffix string) string {
+	if g.environment == "" {
+		return "/repos/" + g.repo + "/actions/secrets" + suffix
+	}
+	return "/repos/" + g.repo + "/environments/" + url.PathEscape(g.environment) + "/secrets" + suffix
+}
@@ -0,0 +1,89 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// herokuAPI is the Heroku Platform API.
+const herokuAPI = "https://api.heroku.com"
+
+// Heroku is the config vars of a Heroku app, read and written through the
+// Platform API.
+type Heroku struct {
+	app string
+	api *apiClient
+}
+
+// NewHeroku returns the config vars of app, accessed with token.
+func NewHeroku(app, token string) *Heroku {
+	return &Heroku{app: app, api: &apiClient{
+		baseURL: herokuAPI,
+		headers: map[string]string{
+			"Accept":        "application/vnd.heroku+json; version=3",
+			"Authorization": "Bearer " + token,
+		},
+		client: &http.Client{Timeout: httpTimeout},
+	}}
+}
+
+// openHeroku reads the token from HEROKU_API_KEY, or from the Heroku CLI
+// if it is logged in.
+func openHeroku(app, _ string) (Platform, error) {
+	token := os.Getenv("HEROKU_API_KEY")
+	if token == "" {
+		if out, err := exec.Command("heroku", "auth:token").Output(); err == nil {
+			token = strings.TrimSpace(string(out))
+		}
+	}
+	if token == "" {
+		return nil, fmt.Errorf("heroku: set HEROKU_API_KEY or log in with 'heroku login'")
+	}
+	return NewHeroku(app, token), nil
+}
+
+// Describe implements Platform.
+func (h *Heroku) Describe() string {
+	return "heroku app " + h.app
+}
+
+// Readable implements Platform.
+func (h *Heroku) Readable() bool {
+	return true
+}
+
+// Vars implements Platform.
+func (h *Heroku) Vars(ctx context.Context) (map[string]string, error) {
+	vars := map[string]string{}
+	if err := h.api.do(ctx, http.MethodGet, h.path(), nil, &vars); err != nil {
+		return nil, fmt.Errorf("heroku: %w", err)
+	}
+	return vars, nil
+}
+
+// Apply implements Platform. All changes are made in one request, which
+// creates one release.
+func (h *Heroku) Apply(ctx context.Context, set map[string]string, unset []string) error {
+	// A null value removes the var.
+	body := make(map[string]*string, len(set)+len(unset))
+	for k, v := range set {
+		body[k] = &v
+	}
+	for _, k := range unset {
+		body[k] = nil
+	}
+	if err := h.api.do(ctx, http.MethodPatch, h.path(), body, nil); err != nil {
+		return fmt.Errorf("heroku: %w", err)
+	}
+	return nil
+}
+
+// path returns the API path of the app's config vars.
+func (h *Heroku) path() string {
+	return "/apps/" + url.PathEscape(h.app) + "/config-vars"
+}
@@ -0,0 +1,165 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+)
+
+// netlifyAPI is the Netlify API.
+const netlifyAPI = "https://api.netlify.com/api/v1"
+
+// Netlify is the environment variables of a Netlify site for one deploy
+// context (production, deploy-preview, branch-deploy, or dev), read and
+// written through the API.
+type Netlify struct {
+	site    string
+	context string
+	account string
+	api     *apiClient
+}
+
+// netlifyEnv is an environment variable of a Netlify site, with a value per
+// deploy context.
+type netlifyEnv struct {
+	Key    string `json:"key"`
+	Values []struct {
+		ID      string `json:"id"`
+		Value   string `json:"value"`
+		Context string `json:"context"`
+	} `json:"values"`
+}
+
+// NewNetlify returns the variables of site (its ID) for deployContext,
+// accessed with token.
+func NewNetlify(site, deployContext, token string) *Netlify {
+	return &Netlify{site: site, context: deployContext, api: &apiClient{
+		baseURL: netlifyAPI,
+		headers: map[string]string{"Authorization": "Bearer " + token},
+		client:  &http.Client{Timeout: httpTimeout},
+	}}
+}
+
+// openNetlify reads the token from NETLIFY_AUTH_TOKEN, as the Netlify CLI
+// does.
+func openNetlify(site, deployContext string) (Platform, error) {
+	token := os.Getenv("NETLIFY_AUTH_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("netlify: set NETLIFY_AUTH_TOKEN to a personal access token")
+	}
+	return NewNetlify(site, deployContext, token), nil
+}
+
+// Describe implements Platform.
+func (n *Netlify) Describe() string {
+	return fmt.Sprintf("netlify site %s (%s)", n.site, n.context)
+}
+
+// Readable implements Platform.
+func (n *Netlify) Readable() bool {
+	return true
+}
+
+// Vars implements Platform. A variable with a value for all contexts and
+// none for this one has that value.
+func (n *Netlify) Vars(ctx context.Context) (map[string]string, error) {
+	envs, err := n.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string, len(envs))
+	for _, e := range envs {
+		if i := n.valueIndex(e); i >= 0 {
+			vars[e.Key] = e.Values[i].Value
+		}
+	}
+	return vars, nil
+}
+
+// Apply implements Platform. A value that applies to all contexts cannot
+// be removed for this context alone.
+func (n *Netlify) Apply(ctx context.Context, set map[string]string, unset []string) error {
+	envs, err := n.list(ctx)
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]netlifyEnv, len(envs))
+	for _, e := range envs {
+		byKey[e.Key] = e
+	}
+	query := url.Values{"site_id": {n.site}}
+
+	for _, key := range slices.Sorted(maps.Keys(set)) {
+		value := map[string]string{"context": n.context, "value": set[key]}
+		if _, ok := byKey[key]; ok {
+			err = n.api.do(ctx, http.MethodPatch, n.path("/"+url.PathEscape(key), query), value, nil)
+		} else {
+			body := []map[string]any{{"key": key, "values": []map[string]string{value}}}
+			err = n.api.do(ctx, http.MethodPost, n.path("", query), body, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("netlify: setting %s: %w", key, err)
+		}
+	}
+	for _, key := range unset {
+		e, ok := byKey[key]
+		if !ok {
+			continue
+		}
+		i := n.valueIndex(e)
+		if i < 0 {
+			continue
+		}
+		if e.Values[i].Context == "all" {
+			return fmt.Errorf("netlify: %s has one value for all deploy contexts; remove it in Netlify", key)
+		}
+		p := "/" + url.PathEscape(key) + "/value/" + url.PathEscape(e.Values[i].ID)
+		if err := n.api.do(ctx, http.MethodDelete, n.path(p, query), nil, nil); err != nil {
+			return fmt.Errorf("netlify: removing %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// list returns the variables of the site.
+func (n *Netlify) list(ctx context.Context) ([]netlifyEnv, error) {
+	if n.account == "" {
+		var site struct {
+			AccountID string `json:"account_id"`
+		}
+		if err := n.api.do(ctx, http.MethodGet, "/sites/"+url.PathEscape(n.site), nil, &site); err != nil {
+			return nil, fmt.Errorf("netlify: %w", err)
+		}
+		n.account = site.AccountID
+	}
+	var envs []netlifyEnv
+	if err := n.api.do(ctx, http.MethodGet, n.path("", url.Values{"site_id": {n.site}}), nil, &envs); err != nil {
+		return nil, fmt.Errorf("netlify: %w", err)
+	}
+	return envs, nil
+}
+
+// valueIndex returns the index of the value of e for the context, or of
+// its value for all contexts, or -1.
+func (n *Netlify) valueIndex(e netlifyEnv) int {
+	all := -1
+	for i, v := range e.Values {
+		switch v.Context {
+		case n.context:
+			return i
+		case "all":
+			all = i
+		}
+	}
+	return all
+}
+
+// path returns the API path of the account's variables, followed by
+// suffix and query.
+func (n *Netlify) path(suffix string, query url.Values) string {
+	return "/accounts/" + url.PathEscape(n.account) + "/env" + suffix + "?" + query.Encode()
+}
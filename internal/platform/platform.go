@@ -0,0 +1,261 @@
+// Package platform reads and writes the config vars of an app on a hosting
+// platform, such as Heroku, so that "envref push" and "envref pull" can
+// keep them in sync with a project's environment.
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// httpTimeout bounds each API request.
+const httpTimeout = 30 * time.Second
+
+// Platform is the config vars of one app on a hosting platform.
+type Platform interface {
+	// Describe names the app, such as "heroku app shop-api".
+	Describe() string
+
+	// Readable reports whether Vars returns values. Platforms that keep
+	// secrets write-only return their names with empty values.
+	Readable() bool
+
+	// Vars returns the app's config vars.
+	Vars(ctx context.Context) (map[string]string, error)
+
+	// Apply sets the vars of set and removes the ones named in unset.
+	Apply(ctx context.Context, set map[string]string, unset []string) error
+}
+
+// platformSpec describes a supported platform.
+type platformSpec struct {
+	// appEnv is the environment variable naming the app when none is
+	// given, as the platform's own CLI reads it.
+	appEnv string
+	// defaultApp is the app when none is given and appEnv is not set, for
+	// platforms whose app is a local file.
+	defaultApp string
+	// environments maps the generic environment names (see
+	// ProfileEnvironment) to the platform's own, for platforms whose vars
+	// are set per environment. It is nil for the others.
+	environments map[string]string
+	open         func(app, environment string) (Platform, error)
+}
+
+var platforms = map[string]platformSpec{
+	"heroku": {appEnv: "HEROKU_APP", open: openHeroku},
+	"fly":    {appEnv: "FLY_APP", open: openFly},
+	"render": {appEnv: "RENDER_SERVICE_ID", open: openRender},
+	"vercel": {
+		appEnv:       "VERCEL_PROJECT_ID",
+		environments: map[string]string{"production": "production", "preview": "preview", "development": "development"},
+		open:         openVercel,
+	},
+	"dotenv-vault": {
+		defaultApp:   ".env.vault",
+		environments: map[string]string{"production": "production", "preview": "staging", "development": "development", "ci": "ci"},
+		open:         openDotenvVault,
+	},
+	"netlify": {
+		appEnv:       "NETLIFY_SITE_ID",
+		environments: map[string]string{"production": "production", "preview": "deploy-preview", "development": "dev"},
+		open:         openNetlify,
+	},
+}
+
+// Names returns the names of the supported platforms, sorted.
+func Names() []string {
+	return slices.Sorted(maps.Keys(platforms))
+}
+
+// New returns the platform called name for app. An empty app is read from
+// the platform's environment variable, such as HEROKU_APP, or is the
+// platform's default, such as .env.vault for dotenv-vault. Credentials are
+// read from the environment too.
+//
+// Platforms that set vars per environment, such as Vercel, need
+// environment: either a generic name (production, preview, development) or
+// the platform's own (such as deploy-preview on Netlify). The others take
+// none.
+func New(name, app, environment string) (Platform, error) {
+	spec, ok := platforms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown platform %q (supported: %s)", name, strings.Join(Names(), ", "))
+	}
+	if app == "" && spec.appEnv != "" {
+		app = os.Getenv(spec.appEnv)
+	}
+	if app == "" {
+		app = spec.defaultApp
+	}
+	if app == "" {
+		return nil, fmt.Errorf("no %s app given (use --app or set %s)", name, spec.appEnv)
+	}
+
+	switch {
+	case spec.environments == nil && environment != "":
+		return nil, fmt.Errorf("%s has no environments", name)
+	case spec.environments != nil && environment == "":
+		return nil, fmt.Errorf("%s sets vars per environment: use --environment or a profile", name)
+	case spec.environments != nil:
+		native, ok := spec.environments[environment]
+		if !ok && !slices.Contains(slices.Collect(maps.Values(spec.environments)), environment) {
+			names := slices.Sorted(maps.Values(spec.environments))
+			return nil, fmt.Errorf("unknown %s environment %q (use one of: %s)", name, environment, strings.Join(names, ", "))
+		}
+		if ok {
+			environment = native
+		}
+	}
+	return spec.open(app, environment)
+}
+
+// HasEnvironments reports whether the platform called name sets vars per
+// environment.
+func HasEnvironments(name string) bool {
+	return platforms[name].environments != nil
+}
+
+// ProfileEnvironment returns the generic environment that profile deploys
+// to: production for the production and prod profiles, development for
+// development, dev, and local, and preview for any other profile. It
+// returns "" for no profile.
+func ProfileEnvironment(profile string) string {
+	switch profile {
+	case "":
+		return ""
+	case "production", "prod":
+		return "production"
+	case "development", "dev", "local":
+		return "development"
+	default:
+		return "preview"
+	}
+}
+
+// Changes is the difference between an app's config vars and the vars
+// that should replace them. Key lists are sorted.
+type Changes struct {
+	// Added are keys the app does not have.
+	Added []string
+	// Updated are keys whose value differs, or cannot be compared because
+	// the platform is not readable.
+	Updated []string
+	// Removed are keys of the app to remove.
+	Removed []string
+	// Kept are keys of the app that are not in the new vars but are kept.
+	Kept []string
+	// Unchanged counts the keys whose value is the same.
+	Unchanged int
+}
+
+// Diff compares the current vars of an app with desired. If prune is
+// set, keys missing from desired are removed; otherwise they are kept.
+// If readable is false, values cannot be compared and every key that
+// exists is updated.
+func Diff(current, desired map[string]string, readable, prune bool) Changes {
+	var c Changes
+	for _, key := range slices.Sorted(maps.Keys(desired)) {
+		switch prev, ok := current[key]; {
+		case !ok:
+			c.Added = append(c.Added, key)
+		case !readable || prev != desired[key]:
+			c.Updated = append(c.Updated, key)
+		default:
+			c.Unchanged++
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(current)) {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		if prune {
+			c.Removed = append(c.Removed, key)
+		} else {
+			c.Kept = append(c.Kept, key)
+		}
+	}
+	return c
+}
+
+// Empty reports whether there is nothing to apply.
+func (c Changes) Empty() bool {
+	return len(c.Added)+len(c.Updated)+len(c.Removed) == 0
+}
+
+// Set returns the vars of desired that are added or updated.
+func (c Changes) Set(desired map[string]string) map[string]string {
+	set := make(map[string]string, len(c.Added)+len(c.Updated))
+	for _, key := range slices.Concat(c.Added, c.Updated) {
+		set[key] = desired[key]
+	}
+	return set
+}
+
+// apiClient sends JSON requests to a platform API.
+type apiClient struct {
+	baseURL string
+	headers map[string]string
+	client  *http.Client
+}
+
+// do sends body, if it is not nil, as JSON to path and decodes the
+// response into out, if it is not nil. A non-2xx response is an error
+// holding the API's message.
+func (c *apiClient) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", "envref")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return fmt.Errorf("%s %s: reading response: %w", method, path, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, apiErr.Message)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s %s: decoding response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package platform
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func TestDiff(t *testing.T) {
+	current := map[string]string{"A": "1", "B": "old", "C": "3"}
+	desired := map[string]string{"A": "1", "B": "new", "D": "4"}
+
+	c := Diff(current, desired, true, false)
+	assert.Equal(t, Changes{Added: []string{"D"}, Updated: []string{"B"}, Kept: []string{"C"}, Unchanged: 1}, c)
+	assert.Equal(t, map[string]string{"B": "new", "D": "4"}, c.Set(desired))
+	assert.False(t, c.Empty())
+
+	c = Diff(current, desired, false, true)
+	assert.Equal(t, Changes{Added: []string{"D"}, Updated: []string{"A", "B"}, Removed: []string{"C"}}, c)
+
+	assert.True(t, Diff(current, current, true, true).Empty())
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("HEROKU_APP", "")
+	_, err := New("heroku", "", "")
+	assert.ErrorContains(t, err, "set HEROKU_APP")
+
+	_, err = New("dokku", "app", "")
+	assert.ErrorContains(t, err, `unknown platform "dokku" (supported: dotenv-vault, fly, heroku, netlify, render, vercel)`)
+
+	_, err = New("heroku", "app", "production")
+	assert.ErrorContains(t, err, "heroku has no environments")
+
+	t.Setenv("RENDER_SERVICE_ID", "srv-1")
+	t.Setenv("RENDER_API_KEY", "key")
+	p, err := New("render", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "render service srv-1", p.Describe())
+}
+
+func TestHeroku(t *testing.T) {
+	var patched map[string]*string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/apps/shop/config-vars", r.URL.Path)
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/vnd.heroku+json; version=3", r.Header.Get("Accept"))
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = fmt.Fprint(w, `{"A":"1","OLD":"x"}`)
+		case http.MethodPatch:
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&patched))
+			_, _ = fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+
+	h := NewHeroku("shop", "tok")
+	h.api.baseURL = srv.URL
+	vars, err := h.Vars(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "OLD": "x"}, vars)
+
+	require.NoError(t, h.Apply(context.Background(), map[string]string{"B": "2"}, []string{"OLD"}))
+	require.Len(t, patched, 2)
+	require.NotNil(t, patched["B"])
+	assert.Equal(t, "2", *patched["B"])
+	assert.Nil(t, patched["OLD"])
+}
+
+func TestHeroku_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprint(w, `{"id":"not_found","message":"Couldn't find that app."}`)
+	}))
+	defer srv.Close()
+
+	h := NewHeroku("nope", "tok")
+	h.api.baseURL = srv.URL
+	_, err := h.Vars(context.Background())
+	assert.ErrorContains(t, err, "404 Not Found: Couldn't find that app.")
+}
+
+func TestRender(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if r.Method != http.MethodGet {
+			_, _ = fmt.Fprint(w, `{}`)
+			return
+		}
+		// Two pages: a full one, then the rest.
+		var items []string
+		start, end := 0, renderPageSize
+		if r.URL.Query().Get("cursor") == "c99" {
+			start, end = renderPageSize, renderPageSize+2
+		}
+		for i := start; i < end; i++ {
+			items = append(items, fmt.Sprintf(`{"envVar":{"key":"K%d","value":"v%d"},"cursor":"c%d"}`, i, i, i))
+		}
+		_, _ = fmt.Fprint(w, "["+strings.Join(items, ",")+"]")
+	}))
+	defer srv.Close()
+
+	p := NewRender("srv-1", "key")
+	p.api.baseURL = srv.URL
+	vars, err := p.Vars(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, vars, renderPageSize+2)
+	assert.Equal(t, "v101", vars["K101"])
+
+	calls = nil
+	require.NoError(t, p.Apply(context.Background(), map[string]string{"B": "2", "A": "1"}, []string{"OLD"}))
+	assert.Equal(t, []string{
+		"PUT /services/srv-1/env-vars/A",
+		"PUT /services/srv-1/env-vars/B",
+		"DELETE /services/srv-1/env-vars/OLD",
+	}, calls)
+}
+
+func TestProfileEnvironment(t *testing.T) {
+	for profile, want := range map[string]string{"": "", "prod": "production", "dev": "development", "staging": "preview"} {
+		assert.Equal(t, want, ProfileEnvironment(profile), profile)
+	}
+}
+
+// recorder records the requests of a fake API, as method, path, and body,
+// and answers GET requests with the body for the path.
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+	gets  map[string]string
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if r.Method == http.MethodGet {
+		_, _ = fmt.Fprint(w, rec.gets[r.URL.Path])
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	rec.calls = append(rec.calls, strings.TrimSpace(r.Method+" "+r.URL.RequestURI()+" "+string(body)))
+	_, _ = fmt.Fprint(w, `{}`)
+}
+
+func TestVercel(t *testing.T) {
+	rec := &recorder{gets: map[string]string{"/v9/projects/web/env": `{"envs":[
+		{"id":"a","key":"A","value":"1","target":["production"]},
+		{"id":"s","key":"S","value":"s","target":["production","preview"]},
+		{"id":"b","key":"A","value":"branch","target":["production"],"gitBranch":"main"},
+		{"id":"p","key":"P","value":"p","target":["preview"]}]}`}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	v := NewVercel("web", "production", "tok", "team_1")
+	v.api.baseURL = srv.URL
+	assert.Equal(t, "vercel project web (production)", v.Describe())
+	vars, err := v.Vars(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "S": "s"}, vars)
+
+	require.NoError(t, v.Apply(context.Background(), map[string]string{"A": "2", "N": "n"}, []string{"S"}))
+	assert.Equal(t, []string{
+		`PATCH /v9/projects/web/env/a?teamId=team_1 {"value":"2"}`,
+		`POST /v10/projects/web/env?teamId=team_1 {"key":"N","target":["production"],"type":"encrypted","value":"n"}`,
+		`PATCH /v9/projects/web/env/s?teamId=team_1 {"target":["preview"]}`,
+	}, rec.calls)
+}
+
+func TestNetlify(t *testing.T) {
+	rec := &recorder{gets: map[string]string{
+		"/sites/site-1": `{"account_id":"acct"}`,
+		"/accounts/acct/env": `[
+			{"key":"A","values":[{"id":"a1","value":"prod","context":"production"},{"id":"a2","value":"dev","context":"dev"}]},
+			{"key":"ALL","values":[{"id":"l1","value":"every","context":"all"}]},
+			{"key":"D","values":[{"id":"d1","value":"dev","context":"dev"}]}]`,
+	}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n := NewNetlify("site-1", "production", "tok")
+	n.api.baseURL = srv.URL
+	vars, err := n.Vars(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "prod", "ALL": "every"}, vars)
+
+	require.NoError(t, n.Apply(context.Background(), map[string]string{"A": "new", "N": "n"}, []string{"A"}))
+	assert.Equal(t, []string{
+		`PATCH /accounts/acct/env/A?site_id=site-1 {"context":"production","value":"new"}`,
+		`POST /accounts/acct/env?site_id=site-1 [{"key":"N","values":[{"context":"production","value":"n"}]}]`,
+		`DELETE /accounts/acct/env/A/value/a1?site_id=site-1`,
+	}, rec.calls)
+
+	err = n.Apply(context.Background(), nil, []string{"ALL"})
+	assert.ErrorContains(t, err, "ALL has one value for all deploy contexts")
+}
+
+func TestGitHubSecrets(t *testing.T) {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	var mu sync.Mutex
+	var calls []string
+	sealed := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		switch {
+		case r.URL.Path == "/repos/acme/shop/environments/prod/secrets/public-key":
+			_, _ = fmt.Fprintf(w, `{"key_id":"k1","key":%q}`, base64.StdEncoding.EncodeToString(pub[:]))
+		case r.Method == http.MethodGet:
+			// Two pages: a full one, then the rest.
+			var items []string
+			start, end := 0, githubPageSize
+			if r.URL.Query().Get("page") == "2" {
+				start, end = githubPageSize, githubPageSize+1
+			}
+			for i := start; i < end; i++ {
+				items = append(items, fmt.Sprintf(`{"name":"S%d"}`, i))
+			}
+			_, _ = fmt.Fprintf(w, `{"total_count":%d,"secrets":[%s]}`, githubPageSize+1, strings.Join(items, ","))
+		case r.Method == http.MethodPut:
+			var body struct {
+				EncryptedValue string `json:"encrypted_value"`
+				KeyID          string `json:"key_id"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "k1", body.KeyID)
+			data, err := base64.StdEncoding.DecodeString(body.EncryptedValue)
+			require.NoError(t, err)
+			plain, ok := box.OpenAnonymous(nil, data, pub, priv)
+			require.True(t, ok, "value cannot be opened")
+			sealed[path.Base(r.URL.Path)] = string(plain)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	g := NewGitHubSecrets(srv.URL, "tok", "acme/shop", "prod")
+	assert.Equal(t, "github environment prod of acme/shop", g.Describe())
+	vars, err := g.Vars(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, vars, githubPageSize+1)
+	assert.Contains(t, vars, "S100")
+
+	calls = nil
+	require.NoError(t, g.Apply(context.Background(), map[string]string{"B": "2", "A": "1"}, []string{"OLD"}))
+	assert.Equal(t, []string{
+		"GET /repos/acme/shop/environments/prod/secrets/public-key",
+		"PUT /repos/acme/shop/environments/prod/secrets/A",
+		"PUT /repos/acme/shop/environments/prod/secrets/B",
+		"DELETE /repos/acme/shop/environments/prod/secrets/OLD",
+	}, calls)
+	assert.Equal(t, map[string]string{"A": "1", "B": "2"}, sealed)
+	assert.Equal(t, "github repository acme/shop", NewGitHubSecrets(GitHubAPI, "tok", "acme/shop", "").Describe())
+}
+
+func TestDotenvVault(t *testing.T) {
+	t.Setenv("DOTENV_KEY", "")
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env.vault")
+	ctx := context.Background()
+
+	prod := NewDotenvVault(path, "production")
+	vars, err := prod.Vars(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, vars)
+
+	set := map[string]string{"API_KEY": "abc", "QUOTED": `it's "x"`, "PEM": "line1\nline2", "GONE": "1"}
+	require.NoError(t, prod.Apply(ctx, set, nil))
+	require.NoError(t, prod.Apply(ctx, map[string]string{"API_KEY": "def"}, []string{"GONE"}))
+	require.NoError(t, NewDotenvVault(path, "development").Apply(ctx, map[string]string{"DEBUG": "1"}, nil))
+
+	vars, err = prod.Vars(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"API_KEY": "def", "QUOTED": `it's "x"`, "PEM": "line1\nline2"}, vars)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "DOTENV_VAULT_PRODUCTION=")
+	assert.Contains(t, string(data), "DOTENV_VAULT_DEVELOPMENT=")
+	assert.NotContains(t, string(data), "def")
+	keys, err := readDotenvFile(filepath.Join(dir, ".env.keys"))
+	require.NoError(t, err)
+	require.Contains(t, keys, "DOTENV_KEY_PRODUCTION")
+	assert.NotEqual(t, keys["DOTENV_KEY_PRODUCTION"], keys["DOTENV_KEY_DEVELOPMENT"])
+
+	// DOTENV_KEY takes precedence over .env.keys.
+	require.NoError(t, os.Remove(filepath.Join(dir, ".env.keys")))
+	_, err = prod.Vars(ctx)
+	assert.ErrorContains(t, err, "no key for environment production")
+	t.Setenv("DOTENV_KEY", keys["DOTENV_KEY_DEVELOPMENT"]+", "+keys["DOTENV_KEY_PRODUCTION"])
+	vars, err = prod.Vars(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "def", vars["API_KEY"])
+
+	other := strings.Replace(keys["DOTENV_KEY_DEVELOPMENT"], "environment=development", "environment=production", 1)
+	t.Setenv("DOTENV_KEY", other)
+	_, err = prod.Vars(ctx)
+	assert.ErrorContains(t, err, "cannot decrypt with this key")
+
+	t.Setenv("DOTENV_KEY", "dotenv://:key_1234@dotenv.org/vault/.env.vault?environment=production")
+	_, err = prod.Vars(ctx)
+	assert.ErrorContains(t, err, "expected 64 hex digits")
+}
+
+func TestDotenvVault_Compatible(t *testing.T) {
+	// A vault and key from dotenv's own tests.
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env.vault")
+	require.NoError(t, os.WriteFile(path, []byte(dotenvVaultHeader+
+		`DOTENV_VAULT_DEVELOPMENT="s7NYXa809k/bVSPwIAmJhPJmEGTtU0hG58hOZy7I0ix6y5HP8LsHBsZCYC/gw5DDFy5DgOcyd18R"`+"\n"), 0o644))
+	t.Setenv("DOTENV_KEY", "dotenv://:key_ddcaa26504cd70a6fef9801901c3981538563a1767c297cb8416e8a38c62fe00@dotenv.local/vault/.env.vault?environment=development")
+
+	vars, err := NewDotenvVault(path, "development").Vars(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ALPHA": "zeta"}, vars)
+}
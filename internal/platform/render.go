@@ -0,0 +1,113 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// renderAPI is the Render API.
+const renderAPI = "https://api.render.com/v1"
+
+// renderPageSize is the number of env vars Render returns per page, at
+// most.
+const renderPageSize = 100
+
+// Render is the environment variables of a Render service, read and
+// written through the Render API.
+type Render struct {
+	service string
+	api     *apiClient
+}
+
+// NewRender returns the environment variables of service (a service ID,
+// such as srv-abc123), accessed with the API key.
+func NewRender(service, apiKey string) *Render {
+	return &Render{service: service, api: &apiClient{
+		baseURL: renderAPI,
+		headers: map[string]string{
+			"Accept":        "application/json",
+			"Authorization": "Bearer " + apiKey,
+		},
+		client: &http.Client{Timeout: httpTimeout},
+	}}
+}
+
+// openRender reads the API key from RENDER_API_KEY.
+func openRender(service, _ string) (Platform, error) {
+	apiKey := os.Getenv("RENDER_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("render: set RENDER_API_KEY to an API key from the Render dashboard")
+	}
+	return NewRender(service, apiKey), nil
+}
+
+// Describe implements Platform.
+func (r *Render) Describe() string {
+	return "render service " + r.service
+}
+
+// Readable implements Platform.
+func (r *Render) Readable() bool {
+	return true
+}
+
+// Vars implements Platform.
+func (r *Render) Vars(ctx context.Context) (map[string]string, error) {
+	vars := map[string]string{}
+	cursor := ""
+	for {
+		query := url.Values{"limit": {strconv.Itoa(renderPageSize)}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		var page []struct {
+			EnvVar struct {
+				Key   string `json:"key"`
+				Value string `json:"value"`
+			} `json:"envVar"`
+			Cursor string `json:"cursor"`
+		}
+		if err := r.api.do(ctx, http.MethodGet, r.path("")+"?"+query.Encode(), nil, &page); err != nil {
+			return nil, fmt.Errorf("render: %w", err)
+		}
+		for _, item := range page {
+			vars[item.EnvVar.Key] = item.EnvVar.Value
+		}
+		if len(page) < renderPageSize {
+			return vars, nil
+		}
+		cursor = page[len(page)-1].Cursor
+	}
+}
+
+// Apply implements Platform. Each var is written with its own request,
+// so that the vars it does not name are left alone.
+func (r *Render) Apply(ctx context.Context, set map[string]string, unset []string) error {
+	for _, key := range slices.Sorted(maps.Keys(set)) {
+		body := map[string]string{"value": set[key]}
+		if err := r.api.do(ctx, http.MethodPut, r.path(key), body, nil); err != nil {
+			return fmt.Errorf("render: setting %s: %w", key, err)
+		}
+	}
+	for _, key := range unset {
+		if err := r.api.do(ctx, http.MethodDelete, r.path(key), nil, nil); err != nil {
+			return fmt.Errorf("render: removing %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// path returns the API path of the service's env vars, or of one of them.
+func (r *Render) path(key string) string {
+	p := "/services/" + url.PathEscape(r.service) + "/env-vars"
+	if key != "" {
+		p += "/" + url.PathEscape(key)
+	}
+	return p
+}
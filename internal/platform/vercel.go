@@ -0,0 +1,168 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+)
+
+// vercelAPI is the Vercel REST API.
+const vercelAPI = "https://api.vercel.com"
+
+// Vercel is the environment variables of a Vercel project for one
+// environment (production, preview, or development), read and written
+// through the REST API. Variables scoped to a Git branch are left alone.
+type Vercel struct {
+	project     string
+	environment string
+	teamID      string
+	api         *apiClient
+}
+
+// vercelEnv is an environment variable of a Vercel project.
+type vercelEnv struct {
+	ID        string   `json:"id"`
+	Key       string   `json:"key"`
+	Value     string   `json:"value"`
+	Target    []string `json:"target"`
+	GitBranch string   `json:"gitBranch"`
+}
+
+// NewVercel returns the variables of project (its ID or name) for
+// environment, accessed with token. teamID is the team that owns the
+// project, or "" for a personal account.
+func NewVercel(project, environment, token, teamID string) *Vercel {
+	return &Vercel{project: project, environment: environment, teamID: teamID, api: &apiClient{
+		baseURL: vercelAPI,
+		headers: map[string]string{"Authorization": "Bearer " + token},
+		client:  &http.Client{Timeout: httpTimeout},
+	}}
+}
+
+// openVercel reads the token from VERCEL_TOKEN and the team from
+// VERCEL_ORG_ID, as the Vercel CLI does.
+func openVercel(project, environment string) (Platform, error) {
+	token := os.Getenv("VERCEL_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("vercel: set VERCEL_TOKEN to a token from the Vercel account settings")
+	}
+	return NewVercel(project, environment, token, os.Getenv("VERCEL_ORG_ID")), nil
+}
+
+// Describe implements Platform.
+func (v *Vercel) Describe() string {
+	return fmt.Sprintf("vercel project %s (%s)", v.project, v.environment)
+}
+
+// Readable implements Platform. Values of sensitive variables read back
+// empty.
+func (v *Vercel) Readable() bool {
+	return true
+}
+
+// Vars implements Platform.
+func (v *Vercel) Vars(ctx context.Context) (map[string]string, error) {
+	envs, err := v.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string, len(envs))
+	for key, e := range envs {
+		vars[key] = e.Value
+	}
+	return vars, nil
+}
+
+// Apply implements Platform. A variable shared with other environments is
+// split: the environment is taken out of its targets, and a variable of its
+// own is created for it.
+func (v *Vercel) Apply(ctx context.Context, set map[string]string, unset []string) error {
+	envs, err := v.list(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range slices.Sorted(maps.Keys(set)) {
+		e, ok := envs[key]
+		switch {
+		case ok && len(e.Target) == 1:
+			err = v.api.do(ctx, http.MethodPatch, v.path("/v9", e.ID, nil), map[string]string{"value": set[key]}, nil)
+		case ok:
+			if err = v.untarget(ctx, e); err == nil {
+				err = v.create(ctx, key, set[key])
+			}
+		default:
+			err = v.create(ctx, key, set[key])
+		}
+		if err != nil {
+			return fmt.Errorf("vercel: setting %s: %w", key, err)
+		}
+	}
+	for _, key := range unset {
+		e, ok := envs[key]
+		switch {
+		case !ok:
+			continue
+		case len(e.Target) == 1:
+			err = v.api.do(ctx, http.MethodDelete, v.path("/v9", e.ID, nil), nil, nil)
+		default:
+			err = v.untarget(ctx, e)
+		}
+		if err != nil {
+			return fmt.Errorf("vercel: removing %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// list returns the variables of the environment that are not scoped to a
+// Git branch, by key.
+func (v *Vercel) list(ctx context.Context) (map[string]vercelEnv, error) {
+	var resp struct {
+		Envs []vercelEnv `json:"envs"`
+	}
+	if err := v.api.do(ctx, http.MethodGet, v.path("/v9", "", url.Values{"decrypt": {"true"}}), nil, &resp); err != nil {
+		return nil, fmt.Errorf("vercel: %w", err)
+	}
+	envs := make(map[string]vercelEnv, len(resp.Envs))
+	for _, e := range resp.Envs {
+		if e.GitBranch == "" && slices.Contains(e.Target, v.environment) {
+			envs[e.Key] = e
+		}
+	}
+	return envs, nil
+}
+
+// create creates an encrypted variable for the environment.
+func (v *Vercel) create(ctx context.Context, key, value string) error {
+	body := map[string]any{"key": key, "value": value, "type": "encrypted", "target": []string{v.environment}}
+	return v.api.do(ctx, http.MethodPost, v.path("/v10", "", nil), body, nil)
+}
+
+// untarget takes the environment out of the targets of e.
+func (v *Vercel) untarget(ctx context.Context, e vercelEnv) error {
+	target := slices.DeleteFunc(slices.Clone(e.Target), func(t string) bool { return t == v.environment })
+	return v.api.do(ctx, http.MethodPatch, v.path("/v9", e.ID, nil), map[string]any{"target": target}, nil)
+}
+
+// path returns the API path, under version, of the project's variables or
+// of the one with id, with the team added to query.
+func (v *Vercel) path(version, id string, query url.Values) string {
+	p := version + "/projects/" + url.PathEscape(v.project) + "/env"
+	if id != "" {
+		p += "/" + url.PathEscape(id)
+	}
+	if v.teamID != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("teamId", v.teamID)
+	}
+	if len(query) > 0 {
+		p += "?" + query.Encode()
+	}
+	return p
+}
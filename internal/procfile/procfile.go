@@ -0,0 +1,71 @@
+// Package procfile parses Procfiles, which declare the processes of an
+// app one per line as "name: command", as Heroku and foreman read them.
+package procfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Process is a process declared in a Procfile.
+type Process struct {
+	// Name is the process type, such as "web".
+	Name string
+	// Command is the shell command that starts the process.
+	Command string
+}
+
+// processLine matches a process declaration.
+var processLine = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.*)$`)
+
+// Parse reads the processes of a Procfile, in order. Blank lines and lines
+// starting with # are ignored.
+func Parse(r io.Reader) ([]Process, error) {
+	var procs []Process
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := processLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: expected \"name: command\"", lineNum)
+		}
+		name, command := m[1], strings.TrimSpace(m[2])
+		if command == "" {
+			return nil, fmt.Errorf("line %d: process %q has no command", lineNum, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("line %d: duplicate process %q", lineNum, name)
+		}
+		seen[name] = true
+		procs = append(procs, Process{Name: name, Command: command})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(procs) == 0 {
+		return nil, fmt.Errorf("no processes declared")
+	}
+	return procs, nil
+}
+
+// ParseFile reads the processes of the Procfile at path.
+func ParseFile(path string) ([]Process, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	procs, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return procs, nil
+}
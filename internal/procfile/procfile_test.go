@@ -0,0 +1,38 @@
+package procfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	procs, err := Parse(strings.NewReader(`# app processes
+web: bundle exec puma -p $PORT
+
+worker:bin/worker --queue=default
+release:  ./migrate.sh   
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []Process{
+		{Name: "web", Command: "bundle exec puma -p $PORT"},
+		{Name: "worker", Command: "bin/worker --queue=default"},
+		{Name: "release", Command: "./migrate.sh"},
+	}, procs)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"web bundle exec puma\n": `line 1: expected "name: command"`,
+		"web: a\nweb: b\n":       `line 2: duplicate process "web"`,
+		"web:\n":                 `line 1: process "web" has no command`,
+		"# nothing here\n\n":     "no processes declared",
+		"my.web: a\n":            `line 1: expected "name: command"`,
+	}
+	for input, want := range tests {
+		_, err := Parse(strings.NewReader(input))
+		assert.ErrorContains(t, err, want, input)
+	}
+}
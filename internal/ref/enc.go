@@ -0,0 +1,95 @@
+package ref
+
+import (
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// EncPrefix is the URI scheme prefix of values encrypted inline with the
+// project's encryption key, as in API_TOKEN=enc://<ciphertext>. The key is
+// a secret in a backend; EncryptedRef turns an enc:// value into a
+// reference to it that decrypts the value.
+const EncPrefix = "enc://"
+
+// MinEncryptionKeyLength is the minimum length of an encryption key, in
+// bytes, so that a short passphrase is not used as one.
+const MinEncryptionKeyLength = 32
+
+// encInfo binds keys derived for enc:// values to that use.
+const encInfo = "envref enc:// v1"
+
+// IsEncrypted reports whether value is an enc:// value.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncPrefix)
+}
+
+// Encrypt encrypts plaintext with key and returns it as an enc:// value.
+func Encrypt(plaintext, key string) (string, error) {
+	if len(key) < MinEncryptionKeyLength {
+		return "", fmt.Errorf("encryption key is too short: %d bytes, need at least %d", len(key), MinEncryptionKeyLength)
+	}
+	aead, err := encCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("generating nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return EncPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// EncryptedRef returns the reference that decrypts the enc:// value with
+// the key stored at keyRef, a ref:// URI. It returns false if value is not
+// an enc:// value or keyRef is not a valid URI.
+func EncryptedRef(keyRef, value string) (string, bool) {
+	if !IsEncrypted(value) {
+		return "", false
+	}
+	key, err := Parse(keyRef)
+	if err != nil {
+		return "", false
+	}
+	key.Transforms = append(key.Transforms, Transform{Name: TransformDecrypt, Arg: value[len(EncPrefix):]})
+	return key.String(), true
+}
+
+// decrypt decrypts ciphertext, the base64 text of an enc:// value, with key.
+func decrypt(ciphertext, key string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("enc:// value is not valid base64: %w", err)
+	}
+	aead, err := encCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return "", errors.New("enc:// value is truncated")
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", errors.New("enc:// value cannot be decrypted with this key")
+	}
+	return string(plaintext), nil
+}
+
+// encCipher derives the XChaCha20-Poly1305 cipher of key.
+func encCipher(key string) (cipher.AEAD, error) {
+	derived, err := hkdf.Key(sha256.New, []byte(key), nil, encInfo, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	return chacha20poly1305.NewX(derived)
+}
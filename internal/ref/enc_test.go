@@ -0,0 +1,71 @@
+package ref
+
+import (
+	"strings"
+	"testing"
+)
+
+const testEncKey = "0123456789abcdef0123456789abcdef"
+
+func TestEncryptDecrypt(t *testing.T) {
+	enc, err := Encrypt("s3cret value", testEncKey)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !IsEncrypted(enc) {
+		t.Fatalf("Encrypt = %q, want an enc:// value", enc)
+	}
+	if again, _ := Encrypt("s3cret value", testEncKey); again == enc {
+		t.Error("Encrypt is deterministic, want a random nonce")
+	}
+
+	uri, ok := EncryptedRef("ref://keychain/enc_key", enc)
+	if !ok {
+		t.Fatalf("EncryptedRef(%q) = false", enc)
+	}
+	parsed, err := Parse(uri)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", uri, err)
+	}
+	if parsed.Secret().Raw != "ref://keychain/enc_key" {
+		t.Errorf("secret = %q, want the key", parsed.Secret().Raw)
+	}
+	got, err := parsed.ApplyTransforms(testEncKey)
+	if err != nil || got != "s3cret value" {
+		t.Errorf("ApplyTransforms = %q, %v", got, err)
+	}
+
+	if _, err := parsed.ApplyTransforms(strings.ToUpper(testEncKey)); err == nil || !strings.Contains(err.Error(), "cannot be decrypted") {
+		t.Errorf("decrypt with the wrong key: got %v", err)
+	}
+	tampered := Transform{Name: TransformDecrypt, Arg: "AAAA"}
+	if _, err := tampered.Apply(testEncKey); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("decrypt truncated value: got %v", err)
+	}
+}
+
+func TestEncryptShortKey(t *testing.T) {
+	if _, err := Encrypt("value", "short"); err == nil || !strings.Contains(err.Error(), "too short") {
+		t.Errorf("Encrypt with a short key: got %v", err)
+	}
+}
+
+func TestEncryptedRef(t *testing.T) {
+	tests := []struct {
+		keyRef string
+		value  string
+		want   string
+		ok     bool
+	}{
+		{"ref://secrets/enc_key", "enc://abc", "ref://secrets/enc_key?decrypt=abc", true},
+		{"ref://vault/keys?json=.enc", "enc://abc", "ref://vault/keys?json=.enc&decrypt=abc", true},
+		{"ref://secrets/enc_key", "plain", "", false},
+		{"not-a-ref", "enc://abc", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := EncryptedRef(tt.keyRef, tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("EncryptedRef(%q, %q) = %q, %v, want %q, %v", tt.keyRef, tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -10,6 +10,15 @@
 //	ref://secrets/api_key        → backend "secrets", path "api_key"
 //	ref://keychain/db_pass       → backend "keychain", path "db_pass"
 //	ref://ssm/prod/db/password   → backend "ssm", path "prod/db/password"
+//	ref://self/DB_HOST           → the resolved value of the DB_HOST variable
+//
+// A ref may end in parameters that transform the secret before it is used,
+// applied in order, and an encoding parameter that controls how it is
+// exposed:
+//
+//	ref://secrets/tls_cert?encoding=base64file
+//	ref://vault/db_creds?json=.password
+//	ref://vault/bundle?json=.tls.cert&b64decode
 package ref
 
 import (
@@ -20,6 +29,18 @@ import (
 // Prefix is the URI scheme prefix for secret references.
 const Prefix = "ref://"
 
+// encodingParam names the encoding parameter of a ref:// URI.
+const encodingParam = "encoding"
+
+// EncodingBase64File marks a secret holding base64 data (or a binary secret)
+// that `envref run` decodes into a temporary file; the variable is set to
+// the file's path.
+const EncodingBase64File = "base64file"
+
+// SelfBackend is the reserved backend name of refs to other variables of
+// the same environment: ref://self/DB_HOST resolves to the value of DB_HOST.
+const SelfBackend = "self"
+
 // Reference represents a parsed ref:// URI pointing to a secret in a backend.
 type Reference struct {
 	// Raw is the original ref:// string as it appeared in the .env file.
@@ -28,11 +49,36 @@ type Reference struct {
 	Backend string
 	// Path is the key or path within the backend (e.g. "api_key", "prod/db/password").
 	Path string
+	// Transforms are applied to the secret in order before it is used.
+	Transforms []Transform
+	// Encoding is the ref's encoding parameter (e.g. EncodingBase64File), or
+	// empty when the secret is used as is.
+	Encoding string
 }
 
 // String returns the canonical ref:// URI for this reference.
 func (r Reference) String() string {
-	return Prefix + r.Backend + "/" + r.Path
+	s := Prefix + r.Backend + "/" + r.Path
+	var params []string
+	for _, t := range r.Transforms {
+		params = append(params, t.String())
+	}
+	if r.Encoding != "" {
+		params = append(params, encodingParam+"="+r.Encoding)
+	}
+	if len(params) > 0 {
+		s += "?" + strings.Join(params, "&")
+	}
+	return s
+}
+
+// Secret returns the reference to the stored secret itself, without
+// transforms or encoding. References that differ only in their parameters
+// read the same secret.
+func (r Reference) Secret() Reference {
+	secret := Reference{Backend: r.Backend, Path: r.Path}
+	secret.Raw = secret.String()
+	return secret
 }
 
 // IsRef reports whether the given value is a ref:// reference.
@@ -62,6 +108,29 @@ func Parse(value string) (Reference, error) {
 	backend := rest[:slashIdx]
 	path := rest[slashIdx+1:]
 
+	// Split off trailing parameters. A query without any known parameter
+	// is part of the path.
+	var transforms []Transform
+	var encoding string
+	if idx := strings.LastIndexByte(path, '?'); idx >= 0 && hasKnownParam(path[idx+1:]) {
+		for _, param := range strings.Split(path[idx+1:], "&") {
+			name, arg, _ := strings.Cut(param, "=")
+			if name == encodingParam {
+				if arg != EncodingBase64File {
+					return Reference{}, fmt.Errorf("ref:// URI has unknown encoding %q: %q (supported: %s)", arg, value, EncodingBase64File)
+				}
+				encoding = arg
+				continue
+			}
+			t, err := parseTransform(param)
+			if err != nil {
+				return Reference{}, fmt.Errorf("ref:// URI %q: %w", value, err)
+			}
+			transforms = append(transforms, t)
+		}
+		path = path[:idx]
+	}
+
 	if backend == "" {
 		return Reference{}, fmt.Errorf("ref:// URI has empty backend: %q", value)
 	}
@@ -70,12 +139,26 @@ func Parse(value string) (Reference, error) {
 	}
 
 	return Reference{
-		Raw:     value,
-		Backend: backend,
-		Path:    path,
+		Raw:        value,
+		Backend:    backend,
+		Path:       path,
+		Transforms: transforms,
+		Encoding:   encoding,
 	}, nil
 }
 
+// hasKnownParam reports whether query, the text after the last "?" of a
+// ref:// URI, has a parameter that envref recognizes.
+func hasKnownParam(query string) bool {
+	for _, param := range strings.Split(query, "&") {
+		name, _, _ := strings.Cut(param, "=")
+		if name == encodingParam || isTransformName(name) {
+			return true
+		}
+	}
+	return false
+}
+
 // ContainsRef reports whether s contains an embedded ref:// URI.
 // Unlike IsRef, this checks for ref:// anywhere in the string.
 func ContainsRef(s string) bool {
@@ -112,6 +195,17 @@ func FindAll(s string) []Embedded {
 		for end < len(s) && isRefChar(s[end]) {
 			end++
 		}
+		// Take a query along if it has parameters envref recognizes, so
+		// that ${ref://vault/creds?json=.user} keeps its transform.
+		if end < len(s) && s[end] == '?' {
+			qend := end + 1
+			for qend < len(s) && (isRefChar(s[qend]) || s[qend] == '=' || s[qend] == '&') {
+				qend++
+			}
+			if hasKnownParam(s[end+1 : qend]) {
+				end = qend
+			}
+		}
 		raw := s[start:end]
 		parsed, err := Parse(raw)
 		if err == nil {
@@ -136,3 +230,63 @@ func isRefChar(c byte) bool {
 		(c >= '0' && c <= '9') ||
 		c == '/' || c == '_' || c == '-' || c == '.'
 }
+
+// Schemes maps additional URI schemes to the backend they resolve through,
+// so files written for other tools' conventions (secret://, op://) can be
+// used without rewriting every line.
+//
+// An empty backend makes the scheme an alias for ref://: the first path
+// segment names the backend. Otherwise the whole remainder is a path in
+// that backend; with {"op": "1password"}, op://Personal/db/password is
+// read as ref://1password/Personal/db/password.
+type Schemes map[string]string
+
+// Rewrite returns value with references in any of the schemes converted to
+// canonical ref:// form. A value that starts with a scheme is rewritten as a
+// whole; elsewhere only ${scheme://...} interpolations are rewritten, so
+// ordinary URLs in values are left alone.
+func (s Schemes) Rewrite(value string) string {
+	if len(s) == 0 {
+		return value
+	}
+	if scheme, backend, ok := s.match(value); ok {
+		return canonicalPrefix(backend) + value[len(scheme)+len("://"):]
+	}
+
+	var b strings.Builder
+	i := 0
+	for {
+		idx := strings.Index(value[i:], "${")
+		if idx < 0 {
+			b.WriteString(value[i:])
+			break
+		}
+		start := i + idx + 2
+		b.WriteString(value[i:start])
+		i = start
+		if scheme, backend, ok := s.match(value[start:]); ok {
+			b.WriteString(canonicalPrefix(backend))
+			i = start + len(scheme) + len("://")
+		}
+	}
+	return b.String()
+}
+
+// match reports which scheme, if any, value starts with.
+func (s Schemes) match(value string) (scheme, backend string, ok bool) {
+	sep := strings.Index(value, "://")
+	if sep <= 0 {
+		return "", "", false
+	}
+	scheme = value[:sep]
+	backend, ok = s[scheme]
+	return scheme, backend, ok
+}
+
+// canonicalPrefix returns the ref:// prefix for a scheme mapped to backend.
+func canonicalPrefix(backend string) string {
+	if backend == "" {
+		return Prefix
+	}
+	return Prefix + backend + "/"
+}
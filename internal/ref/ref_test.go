@@ -1,6 +1,8 @@
 package ref
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -34,11 +36,12 @@ func TestIsRef(t *testing.T) {
 
 func TestParse(t *testing.T) {
 	tests := []struct {
-		name        string
-		input       string
-		wantBackend string
-		wantPath    string
-		wantErr     bool
+		name         string
+		input        string
+		wantBackend  string
+		wantPath     string
+		wantEncoding string
+		wantErr      bool
 	}{
 		{
 			name:        "secrets backend",
@@ -70,6 +73,29 @@ func TestParse(t *testing.T) {
 			wantBackend: "1password",
 			wantPath:    "my-vault/api-key",
 		},
+		{
+			name:         "base64file encoding",
+			input:        "ref://secrets/tls/cert?encoding=base64file",
+			wantBackend:  "secrets",
+			wantPath:     "tls/cert",
+			wantEncoding: EncodingBase64File,
+		},
+		{
+			name:        "question mark in path",
+			input:       "ref://secrets/what?now",
+			wantBackend: "secrets",
+			wantPath:    "what?now",
+		},
+		{
+			name:    "unknown encoding",
+			input:   "ref://secrets/cert?encoding=hex",
+			wantErr: true,
+		},
+		{
+			name:    "encoding with empty path",
+			input:   "ref://secrets/?encoding=base64file",
+			wantErr: true,
+		},
 		{
 			name:    "not a ref URI",
 			input:   "plaintext-value",
@@ -121,6 +147,9 @@ func TestParse(t *testing.T) {
 			if got.Path != tt.wantPath {
 				t.Errorf("Path: got %q, want %q", got.Path, tt.wantPath)
 			}
+			if got.Encoding != tt.wantEncoding {
+				t.Errorf("Encoding: got %q, want %q", got.Encoding, tt.wantEncoding)
+			}
 			if got.Raw != tt.input {
 				t.Errorf("Raw: got %q, want %q", got.Raw, tt.input)
 			}
@@ -154,6 +183,66 @@ func TestReferenceStringNestedPath(t *testing.T) {
 	}
 }
 
+func TestReferenceStringEncoding(t *testing.T) {
+	ref := Reference{
+		Backend:  "secrets",
+		Path:     "cert",
+		Encoding: EncodingBase64File,
+	}
+	got := ref.String()
+	want := "ref://secrets/cert?encoding=base64file"
+	if got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParseTransforms(t *testing.T) {
+	tests := []struct {
+		input          string
+		wantPath       string
+		wantTransforms []Transform
+		wantEncoding   string
+		wantErr        string
+	}{
+		{input: "ref://vault/db?json=.password", wantPath: "db", wantTransforms: []Transform{{Name: TransformJSON, Arg: ".password"}}},
+		{input: "ref://vault/tls_cert?b64decode", wantPath: "tls_cert", wantTransforms: []Transform{{Name: TransformB64Decode}}},
+		{
+			input:          "ref://vault/bundle?json=.tls.cert&b64decode&encoding=base64file",
+			wantPath:       "bundle",
+			wantTransforms: []Transform{{Name: TransformJSON, Arg: ".tls.cert"}, {Name: TransformB64Decode}},
+			wantEncoding:   EncodingBase64File,
+		},
+		{input: "ref://vault/what?now&then", wantPath: "what?now&then"},
+		{input: "ref://vault/a?b?json=.x", wantPath: "a?b", wantTransforms: []Transform{{Name: TransformJSON, Arg: ".x"}}},
+		{input: "ref://vault/db?json=password", wantErr: `field path starting with "."`},
+		{input: "ref://vault/db?json=.a&bogus", wantErr: `unknown parameter "bogus"`},
+		{input: "ref://vault/db?b64decode=1", wantErr: "b64decode takes no argument"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want it to contain %q", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.input, err)
+			}
+			if got.Path != tt.wantPath || got.Encoding != tt.wantEncoding || !reflect.DeepEqual(got.Transforms, tt.wantTransforms) {
+				t.Errorf("Parse(%q) = path %q, transforms %v, encoding %q; want %q, %v, %q", tt.input, got.Path, got.Transforms, got.Encoding, tt.wantPath, tt.wantTransforms, tt.wantEncoding)
+			}
+			if s := got.String(); s != tt.input {
+				t.Errorf("String() = %q, want %q", s, tt.input)
+			}
+			if secret := got.Secret(); secret.Raw != "ref://vault/"+tt.wantPath || secret.Transforms != nil {
+				t.Errorf("Secret() = %+v", secret)
+			}
+		})
+	}
+}
+
 func TestContainsRef(t *testing.T) {
 	tests := []struct {
 		input string
@@ -262,6 +351,26 @@ func TestFindAll(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "ref with transforms",
+			input: "postgres://ref://vault/db?json=.user@host",
+			want: []Embedded{
+				{
+					Ref:   Reference{Raw: "ref://vault/db?json=.user", Backend: "vault", Path: "db"},
+					Start: 11, End: 36,
+				},
+			},
+		},
+		{
+			name:  "unrelated query left out",
+			input: "https://host/cb?next=ref://secrets/token?page=2",
+			want: []Embedded{
+				{
+					Ref:   Reference{Raw: "ref://secrets/token", Backend: "secrets", Path: "token"},
+					Start: 21, End: 40,
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -290,3 +399,32 @@ func TestFindAll(t *testing.T) {
 		})
 	}
 }
+
+func TestSchemesRewrite(t *testing.T) {
+	schemes := Schemes{"secret": "", "op": "1password"}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"secret://keychain/api_key", "ref://keychain/api_key"},
+		{"op://Personal/db/password", "ref://1password/Personal/db/password"},
+		{"ref://keychain/api_key", "ref://keychain/api_key"},
+		{"postgres://u:${secret://vault/pass}@db", "postgres://u:${ref://vault/pass}@db"},
+		{"${op://a/b}-${secret://kc/c}", "${ref://1password/a/b}-${ref://kc/c}"},
+		{"https://example.com/${HOME}", "https://example.com/${HOME}"},
+		{"see secret://kc/x", "see secret://kc/x"},
+		{"secrets://kc/x", "secrets://kc/x"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := schemes.Rewrite(tt.input); got != tt.want {
+			t.Errorf("Rewrite(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	var none Schemes
+	if got := none.Rewrite("secret://kc/x"); got != "secret://kc/x" {
+		t.Errorf("nil Schemes rewrote value: %q", got)
+	}
+}
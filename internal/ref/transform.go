@@ -0,0 +1,172 @@
+package ref
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Transform names, as written in the query of a ref:// URI.
+const (
+	// TransformB64Decode decodes a base64 secret.
+	TransformB64Decode = "b64decode"
+	// TransformJSON selects a field of a JSON secret by its path, such as
+	// ".password" or ".hosts.0.name".
+	TransformJSON = "json"
+	// TransformDecrypt decrypts the ciphertext of an enc:// value, its
+	// argument, with the secret as the key. See EncryptedRef.
+	TransformDecrypt = "decrypt"
+)
+
+// Transform is a step applied to a secret after it is fetched, so that
+// one stored secret, such as a JSON credential, can feed several
+// variables.
+type Transform struct {
+	// Name is the transform name (e.g. TransformJSON).
+	Name string
+	// Arg is the transform's argument, empty for transforms without one.
+	Arg string
+}
+
+// String returns the transform as it is written in a ref:// URI.
+func (t Transform) String() string {
+	if t.Arg == "" {
+		return t.Name
+	}
+	return t.Name + "=" + t.Arg
+}
+
+// isTransformName reports whether name is a known transform.
+func isTransformName(name string) bool {
+	return name == TransformB64Decode || name == TransformJSON || name == TransformDecrypt
+}
+
+// parseTransform parses a "name" or "name=arg" query parameter.
+func parseTransform(param string) (Transform, error) {
+	name, arg, hasArg := strings.Cut(param, "=")
+	switch name {
+	case TransformB64Decode:
+		if hasArg {
+			return Transform{}, fmt.Errorf("%s takes no argument", name)
+		}
+	case TransformJSON:
+		if !strings.HasPrefix(arg, ".") {
+			return Transform{}, fmt.Errorf("%s needs a field path starting with \".\" (e.g. json=.password), got %q", name, arg)
+		}
+	case TransformDecrypt:
+		if arg == "" {
+			return Transform{}, fmt.Errorf("%s needs the ciphertext of an %s value", name, EncPrefix)
+		}
+	default:
+		return Transform{}, fmt.Errorf("unknown parameter %q (supported: %s, %s, %s, %s)", name, TransformB64Decode, TransformJSON, TransformDecrypt, encodingParam)
+	}
+	return Transform{Name: name, Arg: arg}, nil
+}
+
+// Apply returns value transformed by t.
+func (t Transform) Apply(value string) (string, error) {
+	switch t.Name {
+	case TransformB64Decode:
+		return b64decode(value)
+	case TransformJSON:
+		return jsonField(value, t.Arg)
+	case TransformDecrypt:
+		return decrypt(t.Arg, value)
+	}
+	return "", fmt.Errorf("unknown transform %q", t.Name)
+}
+
+// ApplyTransforms returns value with the transforms of r applied in order.
+func (r Reference) ApplyTransforms(value string) (string, error) {
+	for _, t := range r.Transforms {
+		var err error
+		if value, err = t.Apply(value); err != nil {
+			// The ciphertext is too long to repeat in the message.
+			label := t.String()
+			if t.Name == TransformDecrypt {
+				label = t.Name
+			}
+			return "", fmt.Errorf("%s: %w", label, err)
+		}
+	}
+	return value, nil
+}
+
+// b64decode decodes standard or URL-safe base64, padded or not. The result
+// must fit in an environment variable, which cannot hold NUL bytes.
+func b64decode(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	var data []byte
+	var err error
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err = enc.DecodeString(value); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("secret is not valid base64: %w", err)
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", fmt.Errorf("decoded secret contains NUL bytes (use encoding=%s for binary data)", EncodingBase64File)
+	}
+	return string(data), nil
+}
+
+// jsonField returns the field at path in the JSON document value. Strings
+// are returned as is, other values as compact JSON.
+func jsonField(value, path string) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return "", fmt.Errorf("secret is not valid JSON: %w", err)
+	}
+
+	current := doc
+	if path != "." {
+		for _, field := range strings.Split(path[1:], ".") {
+			switch node := current.(type) {
+			case map[string]any:
+				next, ok := node[field]
+				if !ok {
+					return "", fmt.Errorf("no field %q", field)
+				}
+				current = next
+			case []any:
+				i, err := strconv.Atoi(field)
+				if err != nil || i < 0 || i >= len(node) {
+					return "", fmt.Errorf("no element %q in an array of %d", field, len(node))
+				}
+				current = node[i]
+			default:
+				return "", fmt.Errorf("cannot select %q in a JSON %s", field, jsonKind(current))
+			}
+		}
+	}
+
+	if s, ok := current.(string); ok {
+		return s, nil
+	}
+	out, err := json.Marshal(current)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// jsonKind names the JSON type of a decoded scalar for error messages.
+func jsonKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	default:
+		return "string"
+	}
+}
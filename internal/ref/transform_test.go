@@ -0,0 +1,49 @@
+package ref
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransformApply(t *testing.T) {
+	doc := `{"user": "app", "port": 5432, "debug": true, "hosts": [{"name": "a"}, {"name": "b"}], "tls": {"ca": null}}`
+	tests := []struct {
+		transform Transform
+		value     string
+		want      string
+		wantErr   string
+	}{
+		{Transform{Name: TransformJSON, Arg: ".user"}, doc, "app", ""},
+		{Transform{Name: TransformJSON, Arg: ".port"}, doc, "5432", ""},
+		{Transform{Name: TransformJSON, Arg: ".debug"}, doc, "true", ""},
+		{Transform{Name: TransformJSON, Arg: ".hosts.1.name"}, doc, "b", ""},
+		{Transform{Name: TransformJSON, Arg: ".hosts.0"}, doc, `{"name":"a"}`, ""},
+		{Transform{Name: TransformJSON, Arg: ".hosts.2"}, doc, "", `no element "2" in an array of 2`},
+		{Transform{Name: TransformJSON, Arg: ".user.name"}, doc, "", `cannot select "name" in a JSON string`},
+		{Transform{Name: TransformJSON, Arg: ".tls.ca.pem"}, doc, "", `cannot select "pem" in a JSON null`},
+		{Transform{Name: TransformJSON, Arg: ".missing"}, doc, "", `no field "missing"`},
+		{Transform{Name: TransformJSON, Arg: ".user"}, "plain", "", "not valid JSON"},
+		{Transform{Name: TransformB64Decode}, "aGVsbG8gd29ybGQ=", "hello world", ""},
+		{Transform{Name: TransformB64Decode}, "aGVsbG8gd29ybGQ\n", "hello world", ""},
+		{Transform{Name: TransformB64Decode}, "-_8", "\xfb\xff", ""},
+		{Transform{Name: TransformB64Decode}, "AAE=", "", "NUL bytes"},
+		{Transform{Name: TransformB64Decode}, "not base64!", "", "not valid base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.transform.String()+" "+tt.value, func(t *testing.T) {
+			got, err := tt.transform.Apply(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Apply error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Apply = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+// Package remote fetches env files served over HTTP(S), so that a baseline
+// of non-secret configuration can be distributed from a central location:
+//
+//	env_file: https://config.internal/service.env#sha256=9f86d08...
+//
+// Downloads are cached on disk for a configurable time and, when the server
+// cannot be reached, the last copy is used. A file can be pinned to a
+// SHA-256 digest with a "#sha256=<hex>" fragment, and required to carry an
+// Ed25519 signature, served next to it with a ".sig" suffix. Plain http://
+// files must be pinned or signed.
+package remote
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SignatureSuffix is appended to the URL of an env file to fetch its
+// signature: the base64-encoded Ed25519 signature of SignedMessage.
+const SignatureSuffix = ".sig"
+
+// fetchTimeout bounds a single download.
+const fetchTimeout = 10 * time.Second
+
+// maxSize bounds the size of a downloaded file.
+const maxSize = 1 << 20
+
+// IsURL reports whether path names a remote env file rather than a local
+// one: an http:// or https:// URL.
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://")
+}
+
+// SignedMessage returns what the signature of the file at rawURL signs: the
+// URL without its fragment, a newline, and the content. Signing the URL
+// keeps a file signed for one location from being served at another.
+func SignedMessage(rawURL string, data []byte) []byte {
+	u, _, _ := strings.Cut(rawURL, "#")
+	msg := make([]byte, 0, len(u)+1+len(data))
+	msg = append(msg, u...)
+	msg = append(msg, '\n')
+	return append(msg, data...)
+}
+
+// Source is a parsed remote env file location.
+type Source struct {
+	// URL is the location of the file, without the checksum fragment.
+	URL string
+	// SHA256 is the pinned hex digest of the file, or empty when the file
+	// is not pinned.
+	SHA256 string
+}
+
+// Parse parses a remote env file URL, with an optional "#sha256=<hex>"
+// fragment pinning its content.
+func Parse(rawURL string) (Source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Source{}, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Source{}, fmt.Errorf("invalid URL %q: scheme must be http or https", rawURL)
+	}
+	if u.Host == "" {
+		return Source{}, fmt.Errorf("invalid URL %q: missing host", rawURL)
+	}
+
+	var src Source
+	if u.Fragment != "" {
+		digest, ok := strings.CutPrefix(u.Fragment, "sha256=")
+		if !ok {
+			return Source{}, fmt.Errorf("invalid URL %q: the fragment must be sha256=<hex digest>", rawURL)
+		}
+		if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+			return Source{}, fmt.Errorf("invalid URL %q: sha256 must be 64 hex digits", rawURL)
+		}
+		src.SHA256 = strings.ToLower(digest)
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	src.URL = u.String()
+	return src, nil
+}
+
+// ParsePublicKey parses a base64-encoded Ed25519 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key %q: expected a base64-encoded Ed25519 key", s)
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// Fetcher downloads, verifies, and caches remote env files.
+type Fetcher struct {
+	// CacheDir holds the downloaded files. No cache is kept when empty.
+	CacheDir string
+	// TTL is how long a cached file is used before it is downloaded again.
+	TTL time.Duration
+	// PublicKeys, when not empty, require every file to be signed by one
+	// of them.
+	PublicKeys []ed25519.PublicKey
+	// Client performs the downloads. A client with a timeout is used when
+	// nil.
+	Client *http.Client
+	// Warn, if set, is called when a stale cached copy is used because the
+	// file could not be downloaded.
+	Warn func(format string, args ...any)
+}
+
+// DefaultCacheDir returns the directory remote env files are cached in:
+// envref/remote in the user's cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "envref", "remote"), nil
+}
+
+// Fetch returns the content of the env file at rawURL. A cached copy
+// younger than the TTL is used as is; otherwise the file is downloaded,
+// falling back to the cached copy if that fails. Every copy is checked
+// against the pinned digest and the public keys before it is returned. A
+// plain http:// file is refused unless it is pinned or signatures are
+// required.
+func (f *Fetcher) Fetch(rawURL string) ([]byte, error) {
+	src, err := Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(src.URL, "http://") && src.SHA256 == "" && len(f.PublicKeys) == 0 {
+		return nil, fmt.Errorf("%s: plain http:// files must be pinned with #sha256=<hex> or signed (remote.public_keys); use https://", src.URL)
+	}
+
+	cached, cachedSig, age, cacheErr := f.readCache(src.URL)
+	if cacheErr == nil && age < f.TTL {
+		if err := f.verify(src, cached, cachedSig); err == nil {
+			return cached, nil
+		}
+	}
+
+	data, sig, fetchErr := f.download(src.URL)
+	if fetchErr != nil {
+		if cacheErr != nil {
+			return nil, fetchErr
+		}
+		if err := f.verify(src, cached, cachedSig); err != nil {
+			return nil, fmt.Errorf("%w (cached copy: %v)", fetchErr, err)
+		}
+		if f.Warn != nil {
+			f.Warn("%v; using the copy cached %s ago\n", fetchErr, age.Round(time.Second))
+		}
+		return cached, nil
+	}
+
+	if err := f.verify(src, data, sig); err != nil {
+		return nil, err
+	}
+	f.writeCache(src.URL, data, sig)
+	return data, nil
+}
+
+// download fetches the file at u and, when signatures are required, its
+// signature.
+func (f *Fetcher) download(u string) (data, sig []byte, err error) {
+	data, err = f.get(u)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(f.PublicKeys) > 0 {
+		if sig, err = f.get(u + SignatureSuffix); err != nil {
+			return nil, nil, err
+		}
+	}
+	return data, sig, nil
+}
+
+// get returns the body of a successful GET request for u.
+func (f *Fetcher) get(u string) ([]byte, error) {
+	client := f.Client
+	if client == nil {
+		client = &http.Client{Timeout: fetchTimeout}
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", u, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", u, err)
+	}
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("fetching %s: larger than %d bytes", u, maxSize)
+	}
+	return data, nil
+}
+
+// verify checks data against the digest pinned by src and, when public keys
+// are configured, its signature sig.
+func (f *Fetcher) verify(src Source, data, sig []byte) error {
+	if src.SHA256 != "" {
+		sum := sha256.Sum256(data)
+		got := hex.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(got), []byte(src.SHA256)) != 1 {
+			return fmt.Errorf("%s: checksum mismatch: got sha256=%s, want sha256=%s", src.URL, got, src.SHA256)
+		}
+	}
+	if len(f.PublicKeys) == 0 {
+		return nil
+	}
+	if len(sig) == 0 {
+		return fmt.Errorf("%s: no signature", src.URL)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || len(raw) != ed25519.SignatureSize {
+		return fmt.Errorf("%s: malformed signature", src.URL)
+	}
+	for _, key := range f.PublicKeys {
+		if ed25519.Verify(key, SignedMessage(src.URL, data), raw) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: signature does not match any configured public key", src.URL)
+}
+
+// cachePath returns the path the file at u is cached under.
+func (f *Fetcher) cachePath(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return filepath.Join(f.CacheDir, hex.EncodeToString(sum[:16])+".env")
+}
+
+// readCache returns the cached copy of the file at u, its signature (if
+// any), and its age.
+func (f *Fetcher) readCache(u string) (data, sig []byte, age time.Duration, err error) {
+	if f.CacheDir == "" {
+		return nil, nil, 0, os.ErrNotExist
+	}
+	path := f.cachePath(u)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, nil, 0, err
+	}
+	sig, _ = os.ReadFile(path + SignatureSuffix)
+	return data, sig, time.Since(info.ModTime()), nil
+}
+
+// writeCache caches data and its signature as the copy of the file at u.
+// Caching is best-effort: failures only cost a download next time.
+func (f *Fetcher) writeCache(u string, data, sig []byte) {
+	if f.CacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(f.CacheDir, 0o700); err != nil {
+		return
+	}
+	path := f.cachePath(u)
+	if sig != nil {
+		_ = os.WriteFile(path+SignatureSuffix, sig, 0o600)
+	} else {
+		_ = os.Remove(path + SignatureSuffix)
+	}
+	_ = os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,192 @@
+package remote
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve starts an HTTPS server for files, keyed by path, counting
+// requests. Fetchers reach it with its Client.
+func serve(t *testing.T, files map[string]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParse(t *testing.T) {
+	sum := digest("A=1\n")
+
+	src, err := Parse("https://config.internal/service.env#sha256=" + strings.ToUpper(sum))
+	require.NoError(t, err)
+	assert.Equal(t, Source{URL: "https://config.internal/service.env", SHA256: sum}, src)
+
+	for _, bad := range []string{
+		"ftp://config.internal/service.env",
+		"https:///service.env",
+		"https://config.internal/service.env#md5=abc",
+		"https://config.internal/service.env#sha256=abc",
+	} {
+		_, err := Parse(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	assert.True(t, IsURL("https://config.internal/.env"))
+	assert.True(t, IsURL("http://localhost:8080/.env"))
+	assert.False(t, IsURL(".env"))
+	assert.False(t, IsURL("/etc/https.env"))
+}
+
+func TestFetch_Checksum(t *testing.T) {
+	srv, _ := serve(t, map[string]string{"/service.env": "A=1\n"})
+	f := &Fetcher{Client: srv.Client()}
+
+	data, err := f.Fetch(srv.URL + "/service.env#sha256=" + digest("A=1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "A=1\n", string(data))
+
+	_, err = f.Fetch(srv.URL + "/service.env#sha256=" + digest("A=2\n"))
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	_, err = f.Fetch(srv.URL + "/missing.env")
+	assert.ErrorContains(t, err, "404 Not Found")
+}
+
+func TestFetch_Signature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	files := map[string]string{
+		"/signed.env":   "A=1\n",
+		"/tampered.env": "A=2\n",
+		"/moved.env":    "A=1\n",
+		"/unsigned.env": "A=1\n",
+	}
+	srv, _ := serve(t, files)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, SignedMessage(srv.URL+"/signed.env", []byte("A=1\n"))))
+	files["/signed.env.sig"] = sig + "\n"
+	files["/tampered.env.sig"] = sig
+	// A valid signature for another URL must not be accepted.
+	files["/moved.env.sig"] = sig
+
+	f := &Fetcher{PublicKeys: []ed25519.PublicKey{otherPub, pub}, Client: srv.Client()}
+	data, err := f.Fetch(srv.URL + "/signed.env")
+	require.NoError(t, err)
+	assert.Equal(t, "A=1\n", string(data))
+
+	_, err = f.Fetch(srv.URL + "/tampered.env")
+	assert.ErrorContains(t, err, "signature does not match")
+
+	_, err = f.Fetch(srv.URL + "/moved.env")
+	assert.ErrorContains(t, err, "signature does not match")
+
+	_, err = f.Fetch(srv.URL + "/unsigned.env")
+	assert.ErrorContains(t, err, "unsigned.env.sig: 404 Not Found")
+
+	f.PublicKeys = []ed25519.PublicKey{otherPub}
+	_, err = f.Fetch(srv.URL + "/signed.env")
+	assert.ErrorContains(t, err, "signature does not match")
+}
+
+func TestFetch_Cache(t *testing.T) {
+	files := map[string]string{"/service.env": "A=1\n"}
+	srv, hits := serve(t, files)
+	dir := t.TempDir()
+	f := &Fetcher{CacheDir: dir, TTL: time.Hour, Client: srv.Client()}
+
+	for i := 0; i < 2; i++ {
+		data, err := f.Fetch(srv.URL + "/service.env")
+		require.NoError(t, err)
+		assert.Equal(t, "A=1\n", string(data))
+	}
+	assert.Equal(t, int32(1), hits.Load(), "a fresh copy is served from the cache")
+
+	// An expired copy is downloaded again.
+	files["/service.env"] = "A=2\n"
+	f.TTL = 0
+	data, err := f.Fetch(srv.URL + "/service.env")
+	require.NoError(t, err)
+	assert.Equal(t, "A=2\n", string(data))
+	assert.Equal(t, int32(2), hits.Load())
+}
+
+func TestFetch_StaleCacheWhenUnreachable(t *testing.T) {
+	srv, _ := serve(t, map[string]string{"/service.env": "A=1\n"})
+	dir := t.TempDir()
+	var warnings []string
+	f := &Fetcher{CacheDir: dir, Client: srv.Client(), Warn: func(format string, args ...any) {
+		warnings = append(warnings, format)
+	}}
+
+	url := srv.URL + "/service.env"
+	_, err := f.Fetch(url)
+	require.NoError(t, err)
+	srv.Close()
+
+	data, err := f.Fetch(url)
+	require.NoError(t, err)
+	assert.Equal(t, "A=1\n", string(data))
+	assert.Len(t, warnings, 1)
+
+	// The stale copy must still match the pinned digest.
+	_, err = f.Fetch(url + "#sha256=" + digest("A=2\n"))
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	// Without a cached copy the download error is returned.
+	require.NoError(t, os.RemoveAll(dir))
+	_, err = f.Fetch(url)
+	assert.ErrorContains(t, err, "fetching "+url)
+}
+
+func TestFetch_PlainHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("A=1\n"))
+	}))
+	t.Cleanup(srv.Close)
+	url := srv.URL + "/service.env"
+
+	// Unpinned and unsigned, the content could be replaced on the way.
+	f := &Fetcher{}
+	_, err := f.Fetch(url)
+	assert.ErrorContains(t, err, "plain http://")
+
+	data, err := f.Fetch(url + "#sha256=" + digest("A=1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "A=1\n", string(data))
+
+	// With required signatures, the signature check is the error instead.
+	pub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	f.PublicKeys = []ed25519.PublicKey{pub}
+	_, err = f.Fetch(url)
+	assert.ErrorContains(t, err, "malformed signature")
+}
@@ -0,0 +1,84 @@
+package resolve
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/xcke/envref/internal/ref"
+)
+
+// expandKeys splits value, a JSON object, into one entry per field. Each
+// entry is named prefix followed by the field name in upper case, with
+// characters that cannot appear in a variable name replaced by "_". String
+// fields are used as is, other values as compact JSON. Fields keep the
+// order of the object.
+func expandKeys(prefix, value string) ([]Entry, error) {
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("@expand: secret is not a JSON object")
+	}
+
+	var entries []Entry
+	from := make(map[string]string)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("@expand: secret is not valid JSON: %w", err)
+		}
+		field := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("@expand: secret is not valid JSON: %w", err)
+		}
+
+		key := prefix + expandedName(field)
+		if prev, ok := from[key]; ok {
+			return nil, fmt.Errorf("@expand: fields %q and %q would both be named %s", prev, field, key)
+		}
+		from[key] = field
+
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, raw); err != nil {
+				return nil, fmt.Errorf("@expand: secret is not valid JSON: %w", err)
+			}
+			s = compact.String()
+		}
+		entries = append(entries, Entry{Key: key, Value: s, WasRef: true})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("@expand: secret is not valid JSON: %w", err)
+	}
+	return entries, nil
+}
+
+// expandedName returns the variable name part for a JSON field name.
+func expandedName(field string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, field)
+}
+
+// expandEntry returns the entries that the resolved value of parsed, the
+// ref of key, expands into under an "@expand: prefix" annotation. An empty
+// prefix stands for key followed by "_".
+func expandEntry(key, prefix, value string, parsed ref.Reference) ([]Entry, error) {
+	if parsed.Encoding != "" {
+		return nil, fmt.Errorf("@expand cannot be combined with encoding=%s", parsed.Encoding)
+	}
+	if prefix == "" {
+		prefix = key + "_"
+	}
+	return expandKeys(prefix, value)
+}
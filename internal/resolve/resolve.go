@@ -6,13 +6,20 @@
 package resolve
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/xcke/envref/internal/backend"
 	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/parser"
 	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/secret"
+	"github.com/xcke/envref/internal/suggest"
 )
 
 // Result holds the output of a resolution pass.
@@ -31,6 +38,26 @@ type Entry struct {
 	Value string
 	// WasRef indicates whether this entry was a ref:// reference that was resolved.
 	WasRef bool
+	// Encoding is the encoding parameter of the entry's ref:// URI, if any.
+	// For ref.EncodingBase64File, Value holds the base64 data that
+	// `envref run` writes to a file.
+	Encoding string
+	// Origins lists where each ref:// URI in the value was found: one for
+	// a reference, one per embedded reference, and those of the variable
+	// copied by a ref://self reference.
+	Origins []Origin
+}
+
+// Origin is where a ref:// URI was resolved.
+type Origin struct {
+	// Ref is the ref:// URI.
+	Ref string
+	// Backend is the name of the backend that served the secret, which
+	// differs from the URI's backend for aliases and fallback refs.
+	Backend string
+	// Profile is the profile whose namespace held the secret, or "" for
+	// the project namespace.
+	Profile string
 }
 
 // KeyErr records a resolution failure for a specific key.
@@ -61,7 +88,8 @@ func (r *Result) Resolved() bool {
 // The project parameter is used to namespace secret lookups via NamespacedBackend.
 //
 // Non-ref entries are passed through unchanged. Resolution errors are collected
-// per-key rather than failing the entire operation.
+// per-key rather than failing the entire operation. Each lookup is logged at
+// info level to the default slog logger, with its duration.
 func Resolve(env *envfile.Env, registry *backend.Registry, project string) (*Result, error) {
 	return ResolveWithProfile(env, registry, project, "")
 }
@@ -70,10 +98,24 @@ func Resolve(env *envfile.Env, registry *backend.Registry, project string) (*Res
 // When profile is non-empty, each ref:// lookup first tries the profile-scoped
 // namespace (<project>/<profile>/<key>), then falls back to the project-scoped
 // namespace (<project>/<key>). This allows profile-specific secret overrides
-// while maintaining project-wide defaults.
+// while maintaining project-wide defaults. Backends with a key template
+// (see Registry.SetNamespace) use that layout instead.
 //
 // When profile is empty, behavior is identical to Resolve.
 func ResolveWithProfile(env *envfile.Env, registry *backend.Registry, project, profile string) (*Result, error) {
+	var profiles []string
+	if profile != "" {
+		profiles = []string{profile}
+	}
+	return ResolveWithProfiles(env, registry, project, profiles)
+}
+
+// ResolveWithProfiles works like ResolveWithProfile for a profile that
+// inherits from others: profiles lists the active profile followed by the
+// profiles it inherits from, nearest first (see config.Config.ProfileChain).
+// Each ref:// lookup tries their scopes in that order before falling back
+// to the project scope.
+func ResolveWithProfiles(env *envfile.Env, registry *backend.Registry, project string, profiles []string) (*Result, error) {
 	if env == nil {
 		return nil, fmt.Errorf("env must not be nil")
 	}
@@ -84,15 +126,19 @@ func ResolveWithProfile(env *envfile.Env, registry *backend.Registry, project, p
 		return nil, fmt.Errorf("project name must not be empty")
 	}
 
+	// Keys each backend may be asked for, so that backends supporting
+	// batch reads can fetch them all in one call.
+	wanted := refKeysByBackend(env, registry)
+
 	// Build project-scoped namespaced wrappers for each backend.
 	backends := registry.BackendsIter()
-	nsBackends := make(map[string]*backend.NamespacedBackend, len(backends))
+	nsBackends := make(map[string]backend.Backend, len(backends))
 	for _, b := range backends {
-		ns, err := backend.NewNamespacedBackend(b, project)
+		ns, err := registry.Namespaced(b.Name(), project, "")
 		if err != nil {
 			return nil, fmt.Errorf("wrapping backend %q: %w", b.Name(), err)
 		}
-		nsBackends[b.Name()] = ns
+		nsBackends[b.Name()] = prefetch(ns, wanted[b.Name()])
 	}
 
 	// Build a project-scoped namespaced registry for fallback resolution.
@@ -102,33 +148,43 @@ func ResolveWithProfile(env *envfile.Env, registry *backend.Registry, project, p
 			return nil, fmt.Errorf("registering namespaced backend %q: %w", name, err)
 		}
 	}
+	aliases := registry.Aliases()
+	if err := copyAliases(nsRegistry, aliases); err != nil {
+		return nil, err
+	}
 
-	// Build profile-scoped namespaced wrappers if a profile is active.
-	var profileBackends map[string]*backend.NamespacedBackend
-	var profileRegistry *backend.Registry
-	if profile != "" {
-		profileBackends = make(map[string]*backend.NamespacedBackend, len(backends))
+	// Build profile-scoped namespaced wrappers for each active profile, to
+	// try in order before the project scope.
+	scopes := make([]scope, 0, len(profiles)+1)
+	for _, profile := range profiles {
+		profileBackends := make(map[string]backend.Backend, len(backends))
 		for _, b := range backends {
-			ns, err := backend.NewProfileNamespacedBackend(b, project, profile)
+			ns, err := registry.Namespaced(b.Name(), project, profile)
 			if err != nil {
 				return nil, fmt.Errorf("wrapping backend %q for profile %q: %w", b.Name(), profile, err)
 			}
-			profileBackends[b.Name()] = ns
+			profileBackends[b.Name()] = prefetch(ns, wanted[b.Name()])
 		}
 
-		profileRegistry = backend.NewRegistry()
+		profileRegistry := backend.NewRegistry()
 		for _, name := range registry.Names() {
 			if err := profileRegistry.Register(profileBackends[name]); err != nil {
 				return nil, fmt.Errorf("registering profile backend %q: %w", name, err)
 			}
 		}
+		if err := copyAliases(profileRegistry, aliases); err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope{profile, profileBackends, profileRegistry})
 	}
+	scopes = append(scopes, scope{"", nsBackends, nsRegistry})
 
 	// Cache resolved values to avoid duplicate backend hits when multiple
 	// env vars reference the same secret (keyed by raw ref:// URI).
 	type cachedResult struct {
-		value string
-		err   error
+		value  string
+		origin Origin
+		err    error
 	}
 	cache := make(map[string]cachedResult)
 
@@ -136,6 +192,10 @@ func ResolveWithProfile(env *envfile.Env, registry *backend.Registry, project, p
 	result := &Result{
 		Entries: make([]Entry, 0, len(allEntries)),
 	}
+	// ref://self entries by index in result.Entries.
+	selfRefs := make(map[int]ref.Reference)
+	// Keys added by @expand annotations.
+	expandedKeys := make(map[string]bool)
 	for _, envEntry := range allEntries {
 		if !envEntry.IsRef {
 			result.Entries = append(result.Entries, Entry{
@@ -162,24 +222,40 @@ func ResolveWithProfile(env *envfile.Env, registry *backend.Registry, project, p
 			continue
 		}
 
-		// Check the cache before hitting backends.
-		cached, ok := cache[envEntry.Value]
-		if !ok {
-			var value string
-			var resolveErr error
-
-			// If a profile is active, try profile-scoped first.
-			if profileBackends != nil {
-				value, resolveErr = resolveRef(parsed, profileBackends, profileRegistry)
-			}
-
-			// Fall back to project-scoped if no profile or profile lookup failed with not-found.
-			if profileBackends == nil || isNotFoundError(resolveErr) {
-				value, resolveErr = resolveRef(parsed, nsBackends, nsRegistry)
-			}
+		// Refs to other variables are resolved once all others are.
+		if _, expand := envEntry.Annotation(parser.AnnotationExpand); expand && parsed.Backend == ref.SelfBackend {
+			result.Errors = append(result.Errors, KeyErr{
+				Key: envEntry.Key,
+				Ref: envEntry.Value,
+				Err: errors.New("@expand cannot be used with ref://self"),
+			})
+			result.Entries = append(result.Entries, Entry{
+				Key:    envEntry.Key,
+				Value:  envEntry.Value,
+				WasRef: true,
+			})
+			continue
+		}
+		if parsed.Backend == ref.SelfBackend {
+			selfRefs[len(result.Entries)] = parsed
+			result.Entries = append(result.Entries, Entry{
+				Key:    envEntry.Key,
+				Value:  envEntry.Value,
+				WasRef: true,
+			})
+			continue
+		}
 
-			cached = cachedResult{value: value, err: resolveErr}
-			cache[envEntry.Value] = cached
+		// Check the cache before hitting backends. Refs that differ only in
+		// their parameters share the fetched secret.
+		secretURI := parsed.Secret().Raw
+		cached, ok := cache[secretURI]
+		if !ok {
+			start := time.Now()
+			value, origin, resolveErr := resolveInScopes(parsed, scopes)
+			cached = cachedResult{value: value, origin: origin, err: resolveErr}
+			cache[secretURI] = cached
+			logRef(envEntry.Key, envEntry.Value, start, resolveErr)
 		}
 
 		if cached.err != nil {
@@ -197,10 +273,55 @@ func ResolveWithProfile(env *envfile.Env, registry *backend.Registry, project, p
 			continue
 		}
 
+		value, err := refValue(cached.value, parsed)
+		if err != nil {
+			result.Errors = append(result.Errors, KeyErr{
+				Key: envEntry.Key,
+				Ref: envEntry.Value,
+				Err: err,
+			})
+			result.Entries = append(result.Entries, Entry{
+				Key:    envEntry.Key,
+				Value:  envEntry.Value,
+				WasRef: true,
+			})
+			continue
+		}
+
+		if prefix, ok := envEntry.Annotation(parser.AnnotationExpand); ok {
+			expanded, err := expandEntry(envEntry.Key, prefix, value, parsed)
+			if err != nil {
+				result.Errors = append(result.Errors, KeyErr{
+					Key: envEntry.Key,
+					Ref: envEntry.Value,
+					Err: err,
+				})
+				result.Entries = append(result.Entries, Entry{
+					Key:    envEntry.Key,
+					Value:  envEntry.Value,
+					WasRef: true,
+				})
+				continue
+			}
+			// Variables set in the env files take precedence over fields,
+			// as does the first ref to expand into a name.
+			for _, e := range expanded {
+				if _, ok := env.Get(e.Key); ok || expandedKeys[e.Key] {
+					continue
+				}
+				expandedKeys[e.Key] = true
+				e.Origins = []Origin{originOf(cached.origin, envEntry.Value)}
+				result.Entries = append(result.Entries, e)
+			}
+			continue
+		}
+
 		result.Entries = append(result.Entries, Entry{
-			Key:    envEntry.Key,
-			Value:  cached.value,
-			WasRef: true,
+			Key:      envEntry.Key,
+			Value:    value,
+			WasRef:   true,
+			Encoding: parsed.Encoding,
+			Origins:  []Origin{originOf(cached.origin, envEntry.Value)},
 		})
 	}
 
@@ -224,49 +345,209 @@ func ResolveWithProfile(env *envfile.Env, registry *backend.Registry, project, p
 		// Resolve each embedded ref and build the substituted value.
 		value := result.Entries[i].Value
 		hasError := false
+		var origins []Origin
 		// Process in reverse order so byte offsets remain valid after substitution.
 		for j := len(embedded) - 1; j >= 0; j-- {
 			emb := embedded[j]
 			rawURI := emb.Ref.Raw
+			secretURI := emb.Ref.Secret().Raw
 
-			cached, ok := cache[rawURI]
-			if !ok {
-				var resolved string
-				var resolveErr error
-
-				if profileBackends != nil {
-					resolved, resolveErr = resolveRef(emb.Ref, profileBackends, profileRegistry)
-				}
-				if profileBackends == nil || isNotFoundError(resolveErr) {
-					resolved, resolveErr = resolveRef(emb.Ref, nsBackends, nsRegistry)
-				}
+			if emb.Ref.Backend == ref.SelfBackend {
+				result.Errors = append(result.Errors, KeyErr{
+					Key: result.Entries[i].Key,
+					Ref: rawURI,
+					Err: errEmbeddedSelf,
+				})
+				hasError = true
+				continue
+			}
 
-				cached = cachedResult{value: resolved, err: resolveErr}
-				cache[rawURI] = cached
+			cached, ok := cache[secretURI]
+			if !ok {
+				start := time.Now()
+				resolved, origin, resolveErr := resolveInScopes(emb.Ref, scopes)
+				cached = cachedResult{value: resolved, origin: origin, err: resolveErr}
+				cache[secretURI] = cached
+				logRef(result.Entries[i].Key, rawURI, start, resolveErr)
 			}
 
-			if cached.err != nil {
+			resolveErr := cached.err
+			var resolved string
+			if resolveErr == nil {
+				// Embedded refs are substituted as text, so an encoding
+				// does not apply.
+				inline := emb.Ref
+				inline.Encoding = ""
+				resolved, resolveErr = refValue(cached.value, inline)
+			}
+			if resolveErr != nil {
 				result.Errors = append(result.Errors, KeyErr{
 					Key: result.Entries[i].Key,
 					Ref: rawURI,
-					Err: cached.err,
+					Err: resolveErr,
 				})
 				hasError = true
 				continue
 			}
 
-			value = value[:emb.Start] + cached.value + value[emb.End:]
+			value = value[:emb.Start] + resolved + value[emb.End:]
+			origins = append(origins, originOf(cached.origin, rawURI))
 		}
 
 		if !hasError || value != result.Entries[i].Value {
+			slices.Reverse(origins)
 			result.Entries[i].Value = value
 			result.Entries[i].WasRef = true
+			result.Entries[i].Origins = origins
 		}
 	}
 
+	resolveSelfRefs(result, selfRefs)
+
 	return result, nil
 }
 
+// errEmbeddedSelf reports a ref://self URI inside a larger value.
+var errEmbeddedSelf = errors.New("ref://self can only be used as a whole value; use ${KEY} to embed another variable")
+
+// resolveSelfRefs resolves the ref://self entries of result, given by their
+// index in result.Entries, to the values of the variables they name. Chains
+// of self refs are followed; a ref to a variable that is not defined, did
+// not resolve, or is part of a cycle is recorded as an error.
+func resolveSelfRefs(result *Result, selfRefs map[int]ref.Reference) {
+	if len(selfRefs) == 0 {
+		return
+	}
+
+	index := make(map[string]int, len(result.Entries))
+	keys := make([]string, 0, len(result.Entries))
+	for i, e := range result.Entries {
+		index[e.Key] = i
+		keys = append(keys, e.Key)
+	}
+	failed := make(map[string]bool, len(result.Errors))
+	for _, e := range result.Errors {
+		failed[e.Key] = true
+	}
+
+	visiting := make(map[int]bool)
+	done := make(map[int]error)
+	var visit func(i int) error
+	visit = func(i int) error {
+		key := result.Entries[i].Key
+		parsed, ok := selfRefs[i]
+		if !ok {
+			if failed[key] {
+				return fmt.Errorf("%s could not be resolved", key)
+			}
+			return nil
+		}
+		if err, ok := done[i]; ok {
+			return err
+		}
+		if visiting[i] {
+			return fmt.Errorf("reference cycle through %s", key)
+		}
+		visiting[i] = true
+
+		var err error
+		j, ok := index[parsed.Path]
+		if !ok {
+			err = fmt.Errorf("%s is not defined%s", parsed.Path, suggest.FormatSuggestion(suggest.Keys(parsed.Path, keys)))
+		} else if err = visit(j); err == nil {
+			var value string
+			value, err = refValue(result.Entries[j].Value, parsed)
+			if err == nil {
+				result.Entries[i].Value = value
+				result.Entries[i].Encoding = parsed.Encoding
+				result.Entries[i].Origins = result.Entries[j].Origins
+			}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, KeyErr{
+				Key: key,
+				Ref: parsed.Raw,
+				Err: err,
+			})
+		}
+
+		visiting[i] = false
+		done[i] = err
+		if err != nil {
+			return fmt.Errorf("%s could not be resolved", key)
+		}
+		return nil
+	}
+
+	for i := range result.Entries {
+		if _, ok := selfRefs[i]; ok {
+			_ = visit(i)
+		}
+	}
+}
+
+// logRef logs the lookup of ref for key, started at start, to the default
+// slog logger. Values are never logged.
+func logRef(key, ref string, start time.Time, err error) {
+	attrs := []any{"key", key, "ref", ref, "duration", time.Since(start).Round(time.Microsecond)}
+	if err != nil {
+		slog.Info("reference not resolved", append(attrs, "error", err)...)
+		return
+	}
+	slog.Info("reference resolved", attrs...)
+}
+
+// refValue returns the value exposed for a stored secret resolved through
+// parsed. Binary secrets (see backend.BinaryPrefix) are exposed as their
+// base64 data, since environment variables cannot hold arbitrary bytes. The
+// ref's transforms are then applied in order. With ref.EncodingBase64File
+// the value must be valid base64, as it is decoded into a file.
+func refValue(stored string, parsed ref.Reference) (string, error) {
+	value, err := parsed.ApplyTransforms(strings.TrimPrefix(stored, backend.BinaryPrefix))
+	if err != nil {
+		return "", err
+	}
+	if parsed.Encoding == ref.EncodingBase64File {
+		data, err := base64.StdEncoding.DecodeString(value)
+		secret.ClearBytes(data)
+		if err != nil {
+			return "", fmt.Errorf("encoding=%s: secret is not valid base64: %w", parsed.Encoding, err)
+		}
+	}
+	return value, nil
+}
+
+// copyAliases defines each alias on the namespaced registry dst.
+func copyAliases(dst *backend.Registry, aliases map[string][]string) error {
+	for name, targets := range aliases {
+		if err := dst.SetAlias(name, targets); err != nil {
+			return fmt.Errorf("registering alias %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ErrBackendUnavailable matches, with errors.Is, the error of a KeyErr whose
+// backend failed, as opposed to not having the secret.
+var ErrBackendUnavailable = errors.New("backend unavailable")
+
+// unavailableError marks a backend failure. Its message is that of err.
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string { return e.err.Error() }
+
+func (e *unavailableError) Unwrap() error { return e.err }
+
+// Is reports whether target is ErrBackendUnavailable.
+func (e *unavailableError) Is(target error) bool { return target == ErrBackendUnavailable }
+
+// unavailable marks err as a backend failure.
+func unavailable(err error) error {
+	return &unavailableError{err: err}
+}
+
 // isNotFoundError returns true if the error indicates a secret was not found.
 func isNotFoundError(err error) bool {
 	if err == nil {
@@ -276,30 +557,164 @@ func isNotFoundError(err error) bool {
 		strings.Contains(err.Error(), "not found")
 }
 
+// scope is a set of namespaced backends that lookups of one profile, or of
+// the project, go through.
+type scope struct {
+	profile  string
+	backends map[string]backend.Backend
+	registry *backend.Registry
+}
+
+// resolveInScopes looks up a parsed reference in each scope in turn,
+// moving on to the next only when the reference is not found. The origin
+// of a found reference has no Ref; see originOf.
+func resolveInScopes(parsed ref.Reference, scopes []scope) (string, Origin, error) {
+	for i, s := range scopes {
+		value, source, err := resolveRef(parsed, s.backends, s.registry)
+		if err == nil {
+			return value, Origin{Backend: source, Profile: s.profile}, nil
+		}
+		if !isNotFoundError(err) || i == len(scopes)-1 {
+			return "", Origin{}, err
+		}
+	}
+	return "", Origin{}, nil
+}
+
+// originOf returns origin for the ref:// URI uri.
+func originOf(origin Origin, uri string) Origin {
+	origin.Ref = uri
+	return origin
+}
+
 // resolveRef looks up a parsed reference in the backends. If the ref specifies
 // a backend name that matches a registered backend, it queries that backend
-// directly. Otherwise, it uses the registry's fallback chain with the ref path
-// as the key.
-func resolveRef(parsed ref.Reference, nsBackends map[string]*backend.NamespacedBackend, nsRegistry *backend.Registry) (string, error) {
+// directly. If it names an alias, the alias's backends are tried in order.
+// Otherwise, it uses the registry's fallback chain with the ref path as the key.
+// It also returns the name of the backend that had the secret.
+func resolveRef(parsed ref.Reference, nsBackends map[string]backend.Backend, nsRegistry *backend.Registry) (string, string, error) {
 	// If the ref backend name matches a registered backend, query it directly.
 	if ns, ok := nsBackends[parsed.Backend]; ok {
 		value, err := ns.Get(parsed.Path)
 		if err != nil {
 			if errors.Is(err, backend.ErrNotFound) {
-				return "", fmt.Errorf("secret %q not found in backend %q", parsed.Path, parsed.Backend)
+				return "", "", fmt.Errorf("secret %q not found in backend %q", parsed.Path, parsed.Backend)
 			}
-			return "", fmt.Errorf("backend %q: %w", parsed.Backend, err)
+			return "", "", unavailable(fmt.Errorf("backend %q: %w", parsed.Backend, err))
 		}
-		return value, nil
+		return value, parsed.Backend, nil
+	}
+
+	// Aliases resolve through their explicit backend list only.
+	if targets, ok := nsRegistry.Alias(parsed.Backend); ok {
+		value, source, err := nsRegistry.LookupVia(parsed.Backend, parsed.Path)
+		if err != nil {
+			if errors.Is(err, backend.ErrNotFound) {
+				return "", "", fmt.Errorf("secret %q not found in alias %q (%s)", parsed.Path, parsed.Backend, strings.Join(targets, ", "))
+			}
+			return "", "", unavailable(err)
+		}
+		return value, source, nil
 	}
 
 	// For generic backend names (like "secrets"), try the fallback chain.
-	value, err := nsRegistry.Get(parsed.Path)
+	value, source, err := nsRegistry.Lookup(parsed.Path)
 	if err != nil {
 		if errors.Is(err, backend.ErrNotFound) {
-			return "", fmt.Errorf("secret %q not found in any backend", parsed.Path)
+			// A ref backend close to a registered one is most likely a typo.
+			if hint := suggest.FormatSuggestion(suggest.Keys(parsed.Backend, nsRegistry.Names())); hint != "" {
+				return "", "", fmt.Errorf("secret %q not found in any backend (ref backend %q is not registered%s)", parsed.Path, parsed.Backend, hint)
+			}
+			return "", "", fmt.Errorf("secret %q not found in any backend", parsed.Path)
 		}
-		return "", err
+		return "", "", unavailable(err)
 	}
-	return value, nil
+	return value, source, nil
+}
+
+// refKeysByBackend returns, per backend name, the deduplicated ref paths
+// that resolving env may look up in that backend: the named backend, the
+// members of a named alias, or every backend for a fallback ref.
+func refKeysByBackend(env *envfile.Env, registry *backend.Registry) map[string][]string {
+	var refs []ref.Reference
+	for _, entry := range env.All() {
+		if entry.IsRef {
+			if parsed, err := ref.Parse(entry.Value); err == nil {
+				refs = append(refs, parsed)
+			}
+			continue
+		}
+		if ref.ContainsRef(entry.Value) {
+			for _, emb := range ref.FindAll(entry.Value) {
+				refs = append(refs, emb.Ref)
+			}
+		}
+	}
+
+	keys := make(map[string][]string)
+	seen := make(map[string]bool)
+	add := func(name, path string) {
+		if registry.Backend(name) == nil || seen[name+"\x00"+path] {
+			return
+		}
+		seen[name+"\x00"+path] = true
+		keys[name] = append(keys[name], path)
+	}
+	for _, r := range refs {
+		if r.Backend == ref.SelfBackend {
+			continue
+		}
+		switch targets, isAlias := registry.Alias(r.Backend); {
+		case registry.Backend(r.Backend) != nil:
+			add(r.Backend, r.Path)
+		case isAlias:
+			for _, name := range targets {
+				add(name, r.Path)
+			}
+		default:
+			for _, name := range registry.Names() {
+				add(name, r.Path)
+			}
+		}
+	}
+	return keys
+}
+
+// prefetchedBackend serves Get from values fetched ahead of time with
+// GetMany, and passes keys that were not requested to the wrapped backend.
+type prefetchedBackend struct {
+	backend.Backend
+	values  map[string]string
+	fetched map[string]bool
+}
+
+// Get returns the prefetched value for key, ErrNotFound if key was fetched
+// but does not exist, or the wrapped backend's value otherwise.
+func (p *prefetchedBackend) Get(key string) (string, error) {
+	if value, ok := p.values[key]; ok {
+		return value, nil
+	}
+	if p.fetched[key] {
+		return "", backend.ErrNotFound
+	}
+	return p.Backend.Get(key)
+}
+
+// prefetch returns ns with keys fetched in a single GetMany call, if its
+// backend supports batch reads. Otherwise, or if the batch call fails, ns
+// is returned unchanged so that each lookup (and its error) goes through
+// Get as usual.
+func prefetch(ns *backend.NamespacedBackend, keys []string) backend.Backend {
+	if len(keys) < 2 || !ns.Batched() {
+		return ns
+	}
+	values, err := ns.GetMany(keys)
+	if err != nil {
+		return ns
+	}
+	fetched := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		fetched[key] = true
+	}
+	return &prefetchedBackend{Backend: ns, values: values, fetched: fetched}
 }
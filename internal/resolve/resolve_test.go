@@ -10,6 +10,7 @@ import (
 	"github.com/xcke/envref/internal/backend"
 	"github.com/xcke/envref/internal/envfile"
 	"github.com/xcke/envref/internal/parser"
+	"github.com/xcke/envref/internal/ref"
 	"github.com/xcke/envref/internal/resolve"
 )
 
@@ -76,6 +77,32 @@ func (c *countingBackend) Get(key string) (string, error) {
 	return c.mockBackend.Get(key)
 }
 
+// batchBackend is a countingBackend that implements backend.BatchGetter and
+// counts GetMany calls. If batchErr is set, GetMany fails with it.
+type batchBackend struct {
+	*countingBackend
+	batchCalls int
+	batchErr   error
+}
+
+func newBatchBackend(name string, secrets map[string]string) *batchBackend {
+	return &batchBackend{countingBackend: newCountingBackend(name, secrets)}
+}
+
+func (b *batchBackend) GetMany(keys []string) (map[string]string, error) {
+	b.batchCalls++
+	if b.batchErr != nil {
+		return nil, b.batchErr
+	}
+	values := make(map[string]string)
+	for _, k := range keys {
+		if v, ok := b.secrets[k]; ok {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
 // errorBackend always returns an error on Get (simulates connection failures).
 type errorBackend struct {
 	name string
@@ -313,6 +340,94 @@ func TestResolve_FallbackChainFirstBackendWins(t *testing.T) {
 	assert.Equal(t, "from-primary", result.Entries[0].Value)
 }
 
+func TestResolve_AliasUsesExplicitOrder(t *testing.T) {
+	// The alias lists keychain before vault and skips "other" entirely,
+	// regardless of registration order.
+	env := buildEnv(
+		parser.Entry{Key: "KEY", Value: "ref://secrets/shared", IsRef: true},
+		parser.Entry{Key: "ONLY_OTHER", Value: "ref://secrets/other_only", IsRef: true},
+	)
+	reg := buildRegistry(
+		newMockBackend("other", map[string]string{"app/shared": "from-other", "app/other_only": "x"}),
+		newMockBackend("vault", map[string]string{"app/shared": "from-vault"}),
+		newMockBackend("keychain", map[string]string{"app/shared": "from-keychain"}),
+	)
+	require.NoError(t, reg.SetAlias("secrets", []string{"keychain", "vault"}))
+
+	result, err := resolve.Resolve(env, reg, "app")
+	require.NoError(t, err)
+
+	assert.Equal(t, "from-keychain", result.Entries[0].Value)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "ONLY_OTHER", result.Errors[0].Key)
+	assert.Contains(t, result.Errors[0].Err.Error(), `not found in alias "secrets" (keychain, vault)`)
+}
+
+func TestResolve_AliasWithProfile(t *testing.T) {
+	env := buildEnv(
+		parser.Entry{Key: "KEY", Value: "ref://secrets/key", IsRef: true},
+	)
+	reg := buildRegistry(
+		newMockBackend("vault", map[string]string{
+			"app/key":         "project-value",
+			"app/staging/key": "staging-value",
+		}),
+	)
+	require.NoError(t, reg.SetAlias("secrets", []string{"vault"}))
+
+	result, err := resolve.ResolveWithProfile(env, reg, "app", "staging")
+	require.NoError(t, err)
+	assert.Equal(t, "staging-value", result.Entries[0].Value)
+}
+
+func TestResolve_Origins(t *testing.T) {
+	env := buildEnv(
+		parser.Entry{Key: "DIRECT", Value: "ref://vault/token", IsRef: true},
+		parser.Entry{Key: "ALIASED", Value: "ref://secrets/key", IsRef: true},
+		parser.Entry{Key: "FALLBACK", Value: "ref://any/key", IsRef: true},
+		parser.Entry{Key: "URL", Value: "https://ref://vault/user:ref://keychain/token@host", IsRef: false},
+		parser.Entry{Key: "COPY", Value: "ref://self/ALIASED", IsRef: true},
+	)
+	reg := buildRegistry(
+		newMockBackend("vault", map[string]string{"app/staging/token": "t", "app/user": "u"}),
+		newMockBackend("keychain", map[string]string{"app/key": "k", "app/token": "p"}),
+	)
+	require.NoError(t, reg.SetAlias("secrets", []string{"vault", "keychain"}))
+
+	result, err := resolve.ResolveWithProfile(env, reg, "app", "staging")
+	require.NoError(t, err)
+	require.True(t, result.Resolved())
+
+	assert.Equal(t, []resolve.Origin{{Ref: "ref://vault/token", Backend: "vault", Profile: "staging"}}, result.Entries[0].Origins)
+	assert.Equal(t, []resolve.Origin{{Ref: "ref://secrets/key", Backend: "keychain"}}, result.Entries[1].Origins)
+	assert.Equal(t, []resolve.Origin{{Ref: "ref://any/key", Backend: "keychain"}}, result.Entries[2].Origins)
+	assert.Equal(t, []resolve.Origin{
+		{Ref: "ref://vault/user", Backend: "vault"},
+		{Ref: "ref://keychain/token", Backend: "keychain"},
+	}, result.Entries[3].Origins)
+	assert.Equal(t, result.Entries[1].Origins, result.Entries[4].Origins)
+}
+
+func TestResolve_BackendNamespaceTemplate(t *testing.T) {
+	env := buildEnv(
+		parser.Entry{Key: "API_KEY", Value: "ref://ssm/api_key", IsRef: true},
+		parser.Entry{Key: "DB_PASS", Value: "ref://secrets/db_pass", IsRef: true},
+	)
+	reg := buildRegistry(
+		newMockBackend("ssm", map[string]string{
+			"/app/staging/api_key": "staging-key",
+			"/app/db_pass":         "project-pass",
+		}),
+	)
+	require.NoError(t, reg.SetNamespace("ssm", "/{project}/{profile}/{key}"))
+
+	result, err := resolve.ResolveWithProfile(env, reg, "app", "staging")
+	require.NoError(t, err)
+	require.True(t, result.Resolved())
+	assert.Equal(t, "staging-key", result.Entries[0].Value)
+	assert.Equal(t, "project-pass", result.Entries[1].Value)
+}
+
 func TestResolve_FallbackChainThreeBackends(t *testing.T) {
 	// Secret found only in the third backend.
 	env := buildEnv(
@@ -350,6 +465,21 @@ func TestResolve_FallbackChainNotFoundInAny(t *testing.T) {
 	assert.Contains(t, result.Errors[0].Err.Error(), "not found in any backend")
 }
 
+func TestResolve_FallbackChainSuggestsBackend(t *testing.T) {
+	env := buildEnv(
+		parser.Entry{Key: "GHOST", Value: "ref://fisrt/ghost_key", IsRef: true},
+	)
+	reg := buildRegistry(
+		newMockBackend("first", map[string]string{}),
+	)
+
+	result, err := resolve.Resolve(env, reg, "proj")
+	require.NoError(t, err)
+
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Err.Error(), `ref backend "fisrt" is not registered; did you mean first?`)
+}
+
 // ---------------------------------------------------------------------------
 // Missing Secret / Not Found Tests
 // ---------------------------------------------------------------------------
@@ -667,6 +797,8 @@ func TestResolve_BackendConnectionError_DirectMatch(t *testing.T) {
 	assert.Len(t, result.Errors, 1)
 	assert.Equal(t, "SECRET", result.Errors[0].Key)
 	assert.Contains(t, result.Errors[0].Err.Error(), "broken")
+	assert.ErrorIs(t, result.Errors[0].Err, resolve.ErrBackendUnavailable)
+	assert.ErrorIs(t, result.Errors[0].Err, connErr)
 }
 
 func TestResolve_BackendConnectionError_Fallback(t *testing.T) {
@@ -757,6 +889,214 @@ func TestResolve_EmptySecretValue(t *testing.T) {
 	assert.True(t, result.Entries[0].WasRef)
 }
 
+func TestResolve_BinarySecret(t *testing.T) {
+	// Binary secrets are exposed as their base64 data, both as plain refs
+	// and with encoding=base64file.
+	stored := backend.EncodeBinary([]byte{0x00, 0xff, 0x10})
+	env := buildEnv(
+		parser.Entry{Key: "CERT_B64", Value: "ref://keychain/cert", IsRef: true},
+		parser.Entry{Key: "CERT_FILE", Value: "ref://keychain/cert?encoding=base64file", IsRef: true},
+	)
+	reg := buildRegistry(newMockBackend("keychain", map[string]string{
+		"proj/cert": stored,
+	}))
+
+	result, err := resolve.Resolve(env, reg, "proj")
+	require.NoError(t, err)
+
+	assert.True(t, result.Resolved())
+	assert.Equal(t, "AP8Q", result.Entries[0].Value)
+	assert.Empty(t, result.Entries[0].Encoding)
+	assert.Equal(t, "AP8Q", result.Entries[1].Value)
+	assert.Equal(t, ref.EncodingBase64File, result.Entries[1].Encoding)
+}
+
+func TestResolve_Base64FileInvalidData(t *testing.T) {
+	env := buildEnv(
+		parser.Entry{Key: "CERT_FILE", Value: "ref://keychain/cert?encoding=base64file", IsRef: true},
+	)
+	reg := buildRegistry(newMockBackend("keychain", map[string]string{
+		"proj/cert": "not base64!",
+	}))
+
+	result, err := resolve.Resolve(env, reg, "proj")
+	require.NoError(t, err)
+
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Err.Error(), "not valid base64")
+	assert.Equal(t, "ref://keychain/cert?encoding=base64file", result.Entries[0].Value)
+}
+
+func TestResolve_Transforms(t *testing.T) {
+	// One JSON secret feeds several variables and is fetched once.
+	env := buildEnv(
+		parser.Entry{Key: "DB_USER", Value: "ref://vault/db?json=.user", IsRef: true},
+		parser.Entry{Key: "DB_PASS", Value: "ref://vault/db?json=.password", IsRef: true},
+		parser.Entry{Key: "DB_PORT", Value: "ref://vault/db?json=.port", IsRef: true},
+		parser.Entry{Key: "TLS_CERT", Value: "ref://vault/db?json=.tls.cert&b64decode", IsRef: true},
+		parser.Entry{Key: "DSN", Value: "postgres://ref://vault/db?json=.user@db"},
+	)
+	vault := newCountingBackend("vault", map[string]string{
+		"proj/db": `{"user": "app", "password": "s3cret", "port": 5432, "tls": {"cert": "LS0tQ0VSVC0tLQ=="}}`,
+	})
+	reg := buildRegistry(vault)
+
+	result, err := resolve.Resolve(env, reg, "proj")
+	require.NoError(t, err)
+
+	require.True(t, result.Resolved(), "errors: %v", result.Errors)
+	got := make(map[string]string)
+	for _, e := range result.Entries {
+		got[e.Key] = e.Value
+	}
+	assert.Equal(t, map[string]string{
+		"DB_USER":  "app",
+		"DB_PASS":  "s3cret",
+		"DB_PORT":  "5432",
+		"TLS_CERT": "---CERT---",
+		"DSN":      "postgres://app@db",
+	}, got)
+	assert.Equal(t, 1, vault.getCounts["proj/db"])
+}
+
+func TestResolve_TransformErrors(t *testing.T) {
+	env := buildEnv(
+		parser.Entry{Key: "MISSING", Value: "ref://vault/db?json=.nope", IsRef: true},
+		parser.Entry{Key: "NOT_JSON", Value: "ref://vault/plain?json=.user", IsRef: true},
+		parser.Entry{Key: "NOT_B64", Value: "ref://vault/plain?b64decode", IsRef: true},
+	)
+	reg := buildRegistry(newMockBackend("vault", map[string]string{
+		"proj/db":    `{"user": "app"}`,
+		"proj/plain": "not json!",
+	}))
+
+	result, err := resolve.Resolve(env, reg, "proj")
+	require.NoError(t, err)
+
+	require.Len(t, result.Errors, 3)
+	assert.Contains(t, result.Errors[0].Err.Error(), `json=.nope: no field "nope"`)
+	assert.Contains(t, result.Errors[1].Err.Error(), "not valid JSON")
+	assert.Contains(t, result.Errors[2].Err.Error(), "b64decode: secret is not valid base64")
+	assert.Equal(t, "ref://vault/db?json=.nope", result.Entries[0].Value)
+}
+
+func TestResolve_SelfRefs(t *testing.T) {
+	env := buildEnv(
+		parser.Entry{Key: "READ_URL", Value: "ref://self/DB_URL", IsRef: true},
+		parser.Entry{Key: "DB_URL", Value: "ref://vault/db_url", IsRef: true},
+		parser.Entry{Key: "HOST", Value: "localhost"},
+		parser.Entry{Key: "API_HOST", Value: "ref://self/HOST", IsRef: true},
+		parser.Entry{Key: "REPLICA_URL", Value: "ref://self/READ_URL", IsRef: true},
+		parser.Entry{Key: "DB_USER", Value: "ref://self/CREDS?json=.user", IsRef: true},
+		parser.Entry{Key: "CREDS", Value: "ref://vault/creds", IsRef: true},
+	)
+	reg := buildRegistry(newMockBackend("vault", map[string]string{
+		"proj/db_url": "postgres://db/app",
+		"proj/creds":  `{"user": "app"}`,
+	}))
+
+	result, err := resolve.Resolve(env, reg, "proj")
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+
+	values := make(map[string]string)
+	for _, e := range result.Entries {
+		values[e.Key] = e.Value
+	}
+	assert.Equal(t, "postgres://db/app", values["READ_URL"])
+	assert.Equal(t, "localhost", values["API_HOST"])
+	assert.Equal(t, "postgres://db/app", values["REPLICA_URL"])
+	assert.Equal(t, "app", values["DB_USER"])
+	assert.True(t, result.Entries[0].WasRef)
+}
+
+func TestResolve_SelfRefErrors(t *testing.T) {
+	env := buildEnv(
+		parser.Entry{Key: "UNDEFINED", Value: "ref://self/DB_HOTS", IsRef: true},
+		parser.Entry{Key: "DB_HOST", Value: "localhost"},
+		parser.Entry{Key: "A", Value: "ref://self/B", IsRef: true},
+		parser.Entry{Key: "B", Value: "ref://self/A", IsRef: true},
+		parser.Entry{Key: "MISSING", Value: "ref://vault/missing", IsRef: true},
+		parser.Entry{Key: "COPY", Value: "ref://self/MISSING", IsRef: true},
+		parser.Entry{Key: "URL", Value: "http://${ref://self/DB_HOST}/"},
+	)
+	reg := buildRegistry(newMockBackend("vault", map[string]string{}))
+
+	result, err := resolve.Resolve(env, reg, "proj")
+	require.NoError(t, err)
+
+	errs := make(map[string]string)
+	for _, e := range result.Errors {
+		errs[e.Key] = e.Err.Error()
+	}
+	assert.Len(t, errs, 6)
+	assert.Equal(t, "DB_HOTS is not defined; did you mean DB_HOST?", errs["UNDEFINED"])
+	assert.Equal(t, "B could not be resolved", errs["A"])
+	assert.Equal(t, "reference cycle through A", errs["B"])
+	assert.Equal(t, "MISSING could not be resolved", errs["COPY"])
+	assert.Contains(t, errs["URL"], "ref://self can only be used as a whole value")
+	assert.Equal(t, "ref://self/DB_HOTS", result.Entries[0].Value)
+}
+
+func TestResolve_Expand(t *testing.T) {
+	expand := func(prefix string) []parser.Annotation {
+		return []parser.Annotation{{Name: parser.AnnotationExpand, Value: prefix}}
+	}
+	env := buildEnv(
+		parser.Entry{Key: "RDS", Value: "ref://aws/rds-creds", IsRef: true, Annotations: expand("")},
+		parser.Entry{Key: "RDS_HOST", Value: "localhost"},
+		parser.Entry{Key: "CACHE", Value: "ref://aws/bundle?json=.cache", IsRef: true, Annotations: expand("REDIS_")},
+		parser.Entry{Key: "HOST_COPY", Value: "ref://self/REDIS_HOST", IsRef: true},
+	)
+	reg := buildRegistry(newMockBackend("aws", map[string]string{
+		"proj/rds-creds": `{"user": "app", "password": "s3cret", "host": "db.internal", "port": 5432}`,
+		"proj/bundle":    `{"cache": {"host": "redis", "tls-enabled": true, "nodes": [1, 2]}}`,
+	}))
+
+	result, err := resolve.Resolve(env, reg, "proj")
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+
+	var keys []string
+	values := make(map[string]string)
+	for _, e := range result.Entries {
+		keys = append(keys, e.Key)
+		values[e.Key] = e.Value
+		assert.NotEqual(t, "RDS", e.Key)
+	}
+	assert.Equal(t, []string{"RDS_USER", "RDS_PASSWORD", "RDS_PORT", "RDS_HOST", "REDIS_HOST", "REDIS_NODES", "REDIS_TLS_ENABLED", "HOST_COPY"}, keys)
+	assert.Equal(t, "app", values["RDS_USER"])
+	assert.Equal(t, "5432", values["RDS_PORT"])
+	assert.Equal(t, "localhost", values["RDS_HOST"], "variables in the env files take precedence")
+	assert.Equal(t, "true", values["REDIS_TLS_ENABLED"])
+	assert.Equal(t, "[1,2]", values["REDIS_NODES"])
+	assert.Equal(t, "redis", values["HOST_COPY"])
+}
+
+func TestResolve_ExpandErrors(t *testing.T) {
+	expand := []parser.Annotation{{Name: parser.AnnotationExpand}}
+	env := buildEnv(
+		parser.Entry{Key: "PLAIN", Value: "ref://aws/plain", IsRef: true, Annotations: expand},
+		parser.Entry{Key: "CLASH", Value: "ref://aws/clash", IsRef: true, Annotations: expand},
+		parser.Entry{Key: "FILE", Value: "ref://aws/empty?encoding=base64file", IsRef: true, Annotations: expand},
+	)
+	reg := buildRegistry(newMockBackend("aws", map[string]string{
+		"proj/plain": "not json",
+		"proj/clash": `{"db-host": "a", "db_host": "b"}`,
+		"proj/empty": "e30=",
+	}))
+
+	result, err := resolve.Resolve(env, reg, "proj")
+	require.NoError(t, err)
+
+	require.Len(t, result.Errors, 3)
+	assert.EqualError(t, result.Errors[0].Err, "@expand: secret is not a JSON object")
+	assert.EqualError(t, result.Errors[1].Err, `@expand: fields "db-host" and "db_host" would both be named CLASH_DB_HOST`)
+	assert.EqualError(t, result.Errors[2].Err, "@expand cannot be combined with encoding=base64file")
+	assert.Equal(t, "PLAIN", result.Entries[0].Key)
+	assert.Equal(t, "ref://aws/plain", result.Entries[0].Value)
+}
+
 func TestResolve_SecretWithSpecialCharacters(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -1126,6 +1466,7 @@ func TestResolve_CacheErrorsAreAlsoCached(t *testing.T) {
 	assert.Len(t, result.Errors, 2)
 	assert.Equal(t, "A", result.Errors[0].Key)
 	assert.Equal(t, "B", result.Errors[1].Key)
+	assert.NotErrorIs(t, result.Errors[0].Err, resolve.ErrBackendUnavailable)
 
 	// Backend should have been queried only once.
 	assert.Equal(t, 1, cb.getCounts["proj/missing"],
@@ -1796,6 +2137,33 @@ func TestResolveWithProfile_ProfileScopedSecretFound(t *testing.T) {
 	assert.True(t, result.Entries[0].WasRef)
 }
 
+func TestResolveWithProfiles_InheritedScopes(t *testing.T) {
+	// Each lookup tries the profile, then the profiles it inherits from,
+	// then the project scope.
+	env := buildEnv(
+		parser.Entry{Key: "API_KEY", Value: "ref://keychain/api_key", IsRef: true},
+		parser.Entry{Key: "DB_PASS", Value: "ref://keychain/db_pass", IsRef: true},
+		parser.Entry{Key: "DB_URL", Value: "postgres://app:ref://keychain/db_pass@db", IsRef: false},
+		parser.Entry{Key: "SENTRY", Value: "ref://keychain/sentry", IsRef: true},
+	)
+	reg := buildRegistry(newMockBackend("keychain", map[string]string{
+		"proj/api_key":         "project-key",
+		"proj/preview/api_key": "preview-key",
+		"proj/db_pass":         "project-db",
+		"proj/staging/db_pass": "staging-db",
+		"proj/sentry":          "project-sentry",
+	}))
+
+	result, err := resolve.ResolveWithProfiles(env, reg, "proj", []string{"preview", "staging"})
+	require.NoError(t, err)
+
+	require.True(t, result.Resolved())
+	assert.Equal(t, "preview-key", result.Entries[0].Value)
+	assert.Equal(t, "staging-db", result.Entries[1].Value)
+	assert.Equal(t, "postgres://app:staging-db@db", result.Entries[2].Value)
+	assert.Equal(t, "project-sentry", result.Entries[3].Value)
+}
+
 func TestResolveWithProfile_FallbackToProjectScope(t *testing.T) {
 	// When profile-scoped secret doesn't exist, fall back to project scope.
 	env := buildEnv(
@@ -1944,3 +2312,75 @@ func TestResolveWithProfile_MultipleProfiles(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "prod-key", resultProd.Entries[0].Value)
 }
+
+// ---------------------------------------------------------------------------
+// Batch Get Tests
+// ---------------------------------------------------------------------------
+
+func TestResolve_BatchGetterFetchesAllRefsAtOnce(t *testing.T) {
+	bb := newBatchBackend("ssm", map[string]string{
+		"proj/key_a": "val_a",
+		"proj/key_b": "val_b",
+	})
+	env := buildEnv(
+		parser.Entry{Key: "A", Value: "ref://ssm/key_a", IsRef: true},
+		parser.Entry{Key: "B", Value: "ref://ssm/key_b", IsRef: true},
+		parser.Entry{Key: "URL", Value: "https://ref://ssm/key_a@host"},
+		parser.Entry{Key: "C", Value: "ref://ssm/missing", IsRef: true},
+	)
+	reg := buildRegistry(bb)
+
+	result, err := resolve.Resolve(env, reg, "proj")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, bb.batchCalls)
+	assert.Empty(t, bb.getCounts, "refs should be served from the batch fetch")
+	assert.Equal(t, "val_a", result.Entries[0].Value)
+	assert.Equal(t, "val_b", result.Entries[1].Value)
+	assert.Equal(t, "https://val_a@host", result.Entries[2].Value)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "C", result.Errors[0].Key)
+	assert.Contains(t, result.Errors[0].Err.Error(), "not found")
+}
+
+func TestResolve_BatchGetterWithProfile(t *testing.T) {
+	bb := newBatchBackend("ssm", map[string]string{
+		"proj/staging/key_a": "staging_a",
+		"proj/key_b":         "val_b",
+	})
+	env := buildEnv(
+		parser.Entry{Key: "A", Value: "ref://ssm/key_a", IsRef: true},
+		parser.Entry{Key: "B", Value: "ref://ssm/key_b", IsRef: true},
+	)
+	reg := buildRegistry(bb)
+
+	result, err := resolve.ResolveWithProfile(env, reg, "proj", "staging")
+	require.NoError(t, err)
+
+	assert.True(t, result.Resolved())
+	assert.Equal(t, "staging_a", result.Entries[0].Value)
+	assert.Equal(t, "val_b", result.Entries[1].Value)
+	// One batch for the project scope and one for the profile scope.
+	assert.Equal(t, 2, bb.batchCalls)
+	assert.Empty(t, bb.getCounts)
+}
+
+func TestResolve_BatchGetterErrorFallsBackToGet(t *testing.T) {
+	bb := newBatchBackend("ssm", map[string]string{
+		"proj/key_a": "val_a",
+		"proj/key_b": "val_b",
+	})
+	bb.batchErr = errors.New("throttled")
+	env := buildEnv(
+		parser.Entry{Key: "A", Value: "ref://ssm/key_a", IsRef: true},
+		parser.Entry{Key: "B", Value: "ref://ssm/key_b", IsRef: true},
+	)
+	reg := buildRegistry(bb)
+
+	result, err := resolve.Resolve(env, reg, "proj")
+	require.NoError(t, err)
+
+	assert.True(t, result.Resolved())
+	assert.Equal(t, 1, bb.getCounts["proj/key_a"])
+	assert.Equal(t, 1, bb.getCounts["proj/key_b"])
+}
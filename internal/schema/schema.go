@@ -12,13 +12,17 @@
 //	    "DB_PORT": { "type": "port", "required": true, "default": "5432" },
 //	    "DEBUG":   { "type": "boolean" },
 //	    "LOG_LEVEL": { "type": "enum", "values": ["debug", "info", "warn", "error"] },
-//	    "API_URL": { "type": "url", "required": true }
+//	    "API_URL": { "type": "url", "required": true },
+//	    "API_KEY": { "required": true, "ref": true }
 //	  }
 //	}
+//
+// The same rules can be declared in the schema: block of .envref.yaml.
 package schema
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -26,35 +30,46 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/xcke/envref/internal/ref"
 )
 
 // Schema represents a parsed .env.schema.json file.
 type Schema struct {
 	// Keys maps environment variable names to their validation rules.
 	Keys map[string]Rule `json:"keys"`
+	// Schemes are the project's custom reference schemes (ref_schemes).
+	// Values in them count as references, as ref:// values do.
+	Schemes ref.Schemes `json:"-"`
 }
 
 // Rule defines validation constraints for a single environment variable.
 type Rule struct {
-	// Type is the expected value type: string, number, boolean, url, enum, email, port.
+	// Type is the expected value type: string, number, int, boolean, url, enum, email, port.
 	// Defaults to "string" if empty.
-	Type string `json:"type,omitempty"`
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
 	// Required indicates whether the key must be present.
-	Required bool `json:"required,omitempty"`
+	Required bool `json:"required,omitempty" yaml:"required,omitempty"`
 	// Default is the default value (informational; not applied during validation).
-	Default string `json:"default,omitempty"`
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
 	// Values lists allowed values when Type is "enum".
-	Values []string `json:"values,omitempty"`
+	Values []string `json:"values,omitempty" yaml:"values,omitempty"`
 	// Pattern is an optional regex pattern the value must match.
-	Pattern string `json:"pattern,omitempty"`
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
 	// Description documents the purpose of this variable.
-	Description string `json:"description,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Ref requires the value in the .env file to be a ref:// reference
+	// or an enc:// value rather than a plaintext value. See CheckRefs.
+	Ref bool `json:"ref,omitempty" yaml:"ref,omitempty"`
 }
 
 // ValidationError represents a single validation failure for a key.
+// Message never includes the value, which may be a secret; callers that
+// are allowed to show it use Value.
 type ValidationError struct {
 	Key     string
 	Message string
+	Value   string
 }
 
 func (e ValidationError) String() string {
@@ -86,10 +101,16 @@ func Parse(data []byte) (*Schema, error) {
 	if err := json.Unmarshal(data, &s); err != nil {
 		return nil, fmt.Errorf("parsing schema JSON: %w", err)
 	}
+	return New(s.Keys)
+}
+
+// New builds a Schema from a map of rules, such as the schema: block of
+// .envref.yaml, and checks that the rules are well-formed.
+func New(keys map[string]Rule) (*Schema, error) {
+	s := Schema{Keys: keys}
 	if s.Keys == nil {
 		s.Keys = make(map[string]Rule)
 	}
-	// Validate the schema itself.
 	if err := s.validate(); err != nil {
 		return nil, err
 	}
@@ -102,6 +123,7 @@ func (s *Schema) validate() error {
 		"":        true, // defaults to string
 		"string":  true,
 		"number":  true,
+		"int":     true,
 		"boolean": true,
 		"url":     true,
 		"enum":    true,
@@ -109,7 +131,8 @@ func (s *Schema) validate() error {
 		"port":    true,
 	}
 
-	for key, rule := range s.Keys {
+	for _, key := range sortedKeys(s.Keys) {
+		rule := s.Keys[key]
 		if !validTypes[rule.Type] {
 			return fmt.Errorf("schema error: key %q has unknown type %q", key, rule.Type)
 		}
@@ -127,7 +150,9 @@ func (s *Schema) validate() error {
 
 // Validate checks a set of key-value pairs against the schema rules.
 // The entries parameter maps environment variable names to their resolved values.
-// Returns a Result containing any validation errors found.
+// Values that are still ref:// references are not type-checked, since they
+// stand in for the real value. Returns a Result containing any validation
+// errors found.
 func (s *Schema) Validate(entries map[string]string) *Result {
 	var errs []ValidationError
 
@@ -161,9 +186,13 @@ func (s *Schema) Validate(entries map[string]string) *Result {
 			continue
 		}
 
+		if ref.IsRef(s.Schemes.Rewrite(value)) {
+			continue
+		}
+
 		// Type validation.
 		if err := validateType(rule, value); err != nil {
-			errs = append(errs, ValidationError{Key: key, Message: err.Error()})
+			errs = append(errs, ValidationError{Key: key, Message: err.Error(), Value: value})
 		}
 
 		// Pattern validation.
@@ -172,7 +201,8 @@ func (s *Schema) Validate(entries map[string]string) *Result {
 			if !matched {
 				errs = append(errs, ValidationError{
 					Key:     key,
-					Message: fmt.Sprintf("value %q does not match pattern %q", value, rule.Pattern),
+					Message: fmt.Sprintf("value does not match pattern %q", rule.Pattern),
+					Value:   value,
 				})
 			}
 		}
@@ -181,6 +211,98 @@ func (s *Schema) Validate(entries map[string]string) *Result {
 	return &Result{Errors: errs}
 }
 
+// CheckRefs reports keys whose rule sets Ref but whose value is a plaintext
+// value instead of a ref:// reference or an enc:// value. The entries parameter maps keys to
+// their unresolved values as written in the .env files. Missing and empty
+// values are left to Validate.
+func (s *Schema) CheckRefs(entries map[string]string) *Result {
+	var errs []ValidationError
+	for _, key := range sortedKeys(s.Keys) {
+		if !s.Keys[key].Ref {
+			continue
+		}
+		if value := entries[key]; value != "" && !ref.IsRef(s.Schemes.Rewrite(value)) && !ref.IsEncrypted(value) {
+			errs = append(errs, ValidationError{Key: key, Message: errNotRef.Error()})
+		}
+	}
+	return &Result{Errors: errs}
+}
+
+// errNotRef is reported when a Ref rule sees a plaintext value. The value is
+// deliberately not included since it is likely a secret.
+var errNotRef = errors.New("must be a " + ref.Prefix + " reference, not a plaintext value")
+
+// typeAliases maps alternative type names accepted in "# @type:" annotations
+// to their canonical schema type.
+var typeAliases = map[string]string{
+	"integer": "int",
+	"float":   "number",
+	"bool":    "boolean",
+	"str":     "string",
+}
+
+// RuleFromType builds a Rule from a type annotation such as "int", "url",
+// or "enum(debug, info, warn)". Common aliases (integer, bool, float) are
+// accepted. Returns an error if the type is unknown or the enum has no values.
+func RuleFromType(annotation string) (Rule, error) {
+	typ := strings.ToLower(strings.TrimSpace(annotation))
+	var rule Rule
+
+	if strings.HasPrefix(typ, "enum(") && strings.HasSuffix(typ, ")") {
+		// Keep the original casing of enum values.
+		inner := strings.TrimSpace(annotation)
+		inner = inner[len("enum(") : len(inner)-1]
+		for _, v := range strings.Split(inner, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				rule.Values = append(rule.Values, v)
+			}
+		}
+		typ = "enum"
+	}
+	if canonical, ok := typeAliases[typ]; ok {
+		typ = canonical
+	}
+	rule.Type = typ
+
+	s := Schema{Keys: map[string]Rule{"": rule}}
+	if err := s.validate(); err != nil {
+		return Rule{}, fmt.Errorf("invalid type annotation %q", annotation)
+	}
+	return rule, nil
+}
+
+// ValidateKey checks value against the rule for key, if s has one, after
+// rewriting it from any of s.Schemes to ref://.
+func (s *Schema) ValidateKey(key, value string) error {
+	rule, ok := s.Keys[key]
+	if !ok {
+		return nil
+	}
+	return ValidateValue(rule, s.Schemes.Rewrite(value))
+}
+
+// ValidateValue checks a single value against a rule's type, enum values,
+// and pattern. Empty values are accepted, matching Validate. A ref:// or
+// enc:// value satisfies any rule; a plaintext value fails a rule that sets
+// Ref.
+func ValidateValue(rule Rule, value string) error {
+	if value == "" || ref.IsRef(value) || ref.IsEncrypted(value) {
+		return nil
+	}
+	if rule.Ref {
+		return errNotRef
+	}
+	if err := validateType(rule, value); err != nil {
+		return err
+	}
+	if rule.Pattern != "" {
+		if matched, _ := regexp.MatchString(rule.Pattern, value); !matched {
+			return fmt.Errorf("value does not match pattern %q", rule.Pattern)
+		}
+	}
+	return nil
+}
+
 // validateType checks that a value conforms to the expected type.
 func validateType(rule Rule, value string) error {
 	typ := rule.Type
@@ -195,7 +317,13 @@ func validateType(rule Rule, value string) error {
 
 	case "number":
 		if _, err := strconv.ParseFloat(value, 64); err != nil {
-			return fmt.Errorf("expected a number, got %q", value)
+			return errors.New("expected a number")
+		}
+		return nil
+
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return errors.New("expected an integer")
 		}
 		return nil
 
@@ -208,14 +336,14 @@ func validateType(rule Rule, value string) error {
 			"on": true, "off": true,
 		}
 		if !validBools[lower] {
-			return fmt.Errorf("expected a boolean (true/false/1/0/yes/no/on/off), got %q", value)
+			return errors.New("expected a boolean (true/false/1/0/yes/no/on/off)")
 		}
 		return nil
 
 	case "url":
 		u, err := url.Parse(value)
 		if err != nil || u.Scheme == "" || u.Host == "" {
-			return fmt.Errorf("expected a valid URL with scheme and host, got %q", value)
+			return errors.New("expected a valid URL with scheme and host")
 		}
 		return nil
 
@@ -225,24 +353,24 @@ func validateType(rule Rule, value string) error {
 				return nil
 			}
 		}
-		return fmt.Errorf("expected one of [%s], got %q", strings.Join(rule.Values, ", "), value)
+		return fmt.Errorf("expected one of [%s]", strings.Join(rule.Values, ", "))
 
 	case "email":
 		// Simple email validation: must contain exactly one @ with text on both sides.
 		atIdx := strings.LastIndex(value, "@")
 		if atIdx < 1 || atIdx >= len(value)-1 {
-			return fmt.Errorf("expected a valid email address, got %q", value)
+			return errors.New("expected a valid email address")
 		}
 		domain := value[atIdx+1:]
 		if !strings.Contains(domain, ".") {
-			return fmt.Errorf("expected a valid email address, got %q", value)
+			return errors.New("expected a valid email address")
 		}
 		return nil
 
 	case "port":
 		n, err := strconv.Atoi(value)
 		if err != nil || n < 1 || n > 65535 {
-			return fmt.Errorf("expected a port number (1-65535), got %q", value)
+			return errors.New("expected a port number (1-65535)")
 		}
 		return nil
 
@@ -5,6 +5,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/xcke/envref/internal/ref"
 )
 
 func TestParse_ValidSchema(t *testing.T) {
@@ -231,7 +233,8 @@ func TestValidate_EnumType(t *testing.T) {
 		result := s.Validate(map[string]string{"LEVEL": "trace"})
 		assert.False(t, result.OK())
 		assert.Contains(t, result.Errors[0].Message, "expected one of")
-		assert.Contains(t, result.Errors[0].Message, "trace")
+		assert.NotContains(t, result.Errors[0].Message, "trace")
+		assert.Equal(t, "trace", result.Errors[0].Value)
 	})
 
 	t.Run("case sensitive", func(t *testing.T) {
@@ -391,3 +394,120 @@ func TestResult_OK(t *testing.T) {
 		assert.False(t, r.OK())
 	})
 }
+
+func TestValidate_IntType(t *testing.T) {
+	s := &Schema{Keys: map[string]Rule{"WORKERS": {Type: "int"}}}
+
+	assert.True(t, s.Validate(map[string]string{"WORKERS": "8"}).OK())
+	assert.True(t, s.Validate(map[string]string{"WORKERS": "-3"}).OK())
+
+	result := s.Validate(map[string]string{"WORKERS": "2.5"})
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Message, "expected an integer")
+}
+
+func TestRuleFromType(t *testing.T) {
+	tests := []struct {
+		annotation string
+		wantType   string
+		wantValues []string
+		wantErr    bool
+	}{
+		{annotation: "int", wantType: "int"},
+		{annotation: "Integer", wantType: "int"},
+		{annotation: "bool", wantType: "boolean"},
+		{annotation: "float", wantType: "number"},
+		{annotation: "url", wantType: "url"},
+		{annotation: "port", wantType: "port"},
+		{annotation: "enum(debug, Info, warn)", wantType: "enum", wantValues: []string{"debug", "Info", "warn"}},
+		{annotation: "enum", wantErr: true},
+		{annotation: "enum()", wantErr: true},
+		{annotation: "uuid", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.annotation, func(t *testing.T) {
+			rule, err := RuleFromType(tt.annotation)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantType, rule.Type)
+			assert.Equal(t, tt.wantValues, rule.Values)
+		})
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	assert.NoError(t, ValidateValue(Rule{Type: "int"}, "42"))
+	assert.NoError(t, ValidateValue(Rule{Type: "int"}, ""))
+	assert.Error(t, ValidateValue(Rule{Type: "int"}, "forty-two"))
+	assert.Error(t, ValidateValue(Rule{Type: "string", Pattern: "^[a-z]+$"}, "ABC"))
+	assert.NoError(t, ValidateValue(Rule{Type: "enum", Values: []string{"a", "b"}}, "b"))
+}
+
+func TestValidateValue_Ref(t *testing.T) {
+	rule := Rule{Type: "url", Ref: true}
+	assert.NoError(t, ValidateValue(rule, "ref://vault/api_url"))
+	assert.NoError(t, ValidateValue(rule, ""))
+	err := ValidateValue(rule, "https://example.com")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be a ref:// reference")
+	assert.NotContains(t, err.Error(), "example.com", "plaintext value must not be echoed")
+}
+
+func TestValidate_SkipsRefValues(t *testing.T) {
+	s, err := New(map[string]Rule{
+		"DB_PORT": {Type: "port", Required: true},
+	})
+	require.NoError(t, err)
+
+	result := s.Validate(map[string]string{"DB_PORT": "ref://secrets/db_port"})
+	assert.True(t, result.OK())
+}
+
+func TestCheckRefs(t *testing.T) {
+	s, err := New(map[string]Rule{
+		"API_KEY":  {Ref: true, Required: true},
+		"DB_PASS":  {Ref: true},
+		"LOG_MODE": {},
+	})
+	require.NoError(t, err)
+
+	result := s.CheckRefs(map[string]string{
+		"API_KEY":  "sk-plaintext",
+		"DB_PASS":  "ref://vault/db_pass",
+		"LOG_MODE": "json",
+	})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "API_KEY", result.Errors[0].Key)
+
+	// Missing and empty values are left to Validate.
+	assert.True(t, s.CheckRefs(map[string]string{"DB_PASS": ""}).OK())
+}
+
+func TestSchemes(t *testing.T) {
+	s, err := New(map[string]Rule{
+		"API_KEY": {Ref: true},
+		"DB_PORT": {Type: "port"},
+	})
+	require.NoError(t, err)
+	s.Schemes = ref.Schemes{"secret": "", "op": "1password"}
+
+	assert.NoError(t, s.ValidateKey("API_KEY", "secret://vault/api_key"))
+	assert.NoError(t, s.ValidateKey("API_KEY", "op://Personal/api/key"))
+	assert.Error(t, s.ValidateKey("API_KEY", "other://vault/api_key"))
+	assert.NoError(t, s.ValidateKey("UNKNOWN", "anything"))
+	assert.True(t, s.CheckRefs(map[string]string{"API_KEY": "secret://vault/api_key"}).OK())
+	assert.True(t, s.Validate(map[string]string{"DB_PORT": "secret://vault/db_port"}).OK())
+}
+
+func TestNew_InvalidRule(t *testing.T) {
+	_, err := New(map[string]Rule{"MODE": {Type: "enum"}})
+	assert.Error(t, err)
+
+	s, err := New(nil)
+	require.NoError(t, err)
+	assert.NotNil(t, s.Keys)
+}
@@ -9,6 +9,10 @@
 //
 // These utilities follow the same best-effort approach used by Go's own
 // crypto/subtle and x/crypto packages.
+//
+// The package also keeps secret values out of output: values recorded with
+// Track are replaced by Mask wherever Redact or RedactError is applied, which
+// envref does for every error, log line, and audit detail it prints.
 package secret
 
 // ClearBytes overwrites a byte slice with zeros. This is a best-effort
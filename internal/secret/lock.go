@@ -0,0 +1,60 @@
+package secret
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrLockUnsupported is returned by Lock on platforms without mlock(2).
+var ErrLockUnsupported = errors.New("memory locking is not supported on this platform")
+
+// lockingEnabled reports whether Lock pins buffers. Locking is opt-in
+// because the amount of memory a process may lock is limited (see
+// RLIMIT_MEMLOCK), and exceeding it only produces errors.
+var lockingEnabled atomic.Bool
+
+// EnableLocking makes Lock pin buffers in physical memory from now on. It
+// is called once the config enables memory locking ("memory: {lock: true}").
+func EnableLocking() {
+	lockingEnabled.Store(true)
+}
+
+// LockingEnabled reports whether EnableLocking has been called.
+func LockingEnabled() bool {
+	return lockingEnabled.Load()
+}
+
+// Lock pins b in physical memory with mlock(2), so that the kernel never
+// writes it to swap. It does
+// nothing unless locking is enabled. Locking is best-effort: callers may
+// ignore the error, which usually means the RLIMIT_MEMLOCK limit was
+// reached.
+//
+// Release a locked buffer with Wipe once it is no longer needed.
+func Lock(b []byte) error {
+	if !lockingEnabled.Load() || len(b) == 0 {
+		return nil
+	}
+	return lock(b)
+}
+
+// Wipe clears b and releases a lock taken on it with Lock.
+//
+// Locks apply to whole memory pages and do not nest, so unlocking b also
+// unlocks other locked buffers that share its first or last page. Those
+// buffers are still cleared by their own Wipe.
+func Wipe(b []byte) {
+	ClearBytes(b)
+	if lockingEnabled.Load() && len(b) > 0 {
+		_ = unlock(b)
+	}
+}
+
+// TakeString returns the contents of b as a string and clears b. Use it
+// where an API needs a string, so that the byte copy of the secret does
+// not outlive the conversion.
+func TakeString(b []byte) string {
+	s := string(b)
+	ClearBytes(b)
+	return s
+}
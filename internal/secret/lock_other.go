@@ -0,0 +1,13 @@
+//go:build !unix
+
+package secret
+
+// lock always fails on platforms without mlock(2).
+func lock([]byte) error {
+	return ErrLockUnsupported
+}
+
+// unlock does nothing on platforms without mlock(2).
+func unlock([]byte) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package secret
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock_Disabled(t *testing.T) {
+	assert.False(t, LockingEnabled())
+	b := []byte("passphrase")
+	assert.NoError(t, Lock(b))
+	Wipe(b)
+	assert.Equal(t, make([]byte, len(b)), b)
+}
+
+func TestLock_Enabled(t *testing.T) {
+	EnableLocking()
+	t.Cleanup(func() { lockingEnabled.Store(false) })
+
+	b := []byte("derived-key-material")
+	if err := Lock(b); err != nil {
+		// Locking is best-effort; RLIMIT_MEMLOCK may be zero in sandboxes.
+		t.Skipf("mlock unavailable: %v", err)
+	}
+	Wipe(b)
+	assert.Equal(t, make([]byte, len(b)), b)
+
+	assert.NoError(t, Lock(nil), "empty buffers are never locked")
+}
+
+func TestTakeString(t *testing.T) {
+	b := []byte("s3cret")
+	assert.Equal(t, "s3cret", TakeString(b))
+	assert.Equal(t, make([]byte, 6), b)
+	assert.Equal(t, "", TakeString(nil))
+}
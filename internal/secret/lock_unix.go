@@ -0,0 +1,15 @@
+//go:build unix
+
+package secret
+
+import "golang.org/x/sys/unix"
+
+// lock pins the pages holding b with mlock(2).
+func lock(b []byte) error {
+	return unix.Mlock(b)
+}
+
+// unlock releases the pages holding b with munlock(2).
+func unlock(b []byte) error {
+	return unix.Munlock(b)
+}
@@ -0,0 +1,107 @@
+package secret
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask is shown in place of a redacted secret value.
+const Mask = "***"
+
+// minTrackedLen is the length below which values are not tracked. Replacing
+// every occurrence of a one- or two-character value would garble messages
+// without protecting anything.
+const minTrackedLen = 3
+
+// tracked holds every secret value this process has read or written, so
+// Redact can remove them from messages.
+var tracked struct {
+	mu     sync.RWMutex
+	values map[string]struct{}
+	sorted []string // longest first, rebuilt lazily
+}
+
+// Track records secret values so that Redact and RedactError remove them
+// from messages. Backends track every value they read or write; code that
+// obtains a secret some other way (e.g., a prompt) should track it too.
+func Track(values ...string) {
+	tracked.mu.Lock()
+	defer tracked.mu.Unlock()
+	for _, v := range values {
+		if len(v) < minTrackedLen {
+			continue
+		}
+		if _, ok := tracked.values[v]; ok {
+			continue
+		}
+		if tracked.values == nil {
+			tracked.values = make(map[string]struct{})
+		}
+		tracked.values[v] = struct{}{}
+		tracked.sorted = nil
+	}
+}
+
+// Redact returns s with every tracked secret value replaced by Mask. Longer
+// values are replaced first, so a value containing another is masked whole.
+func Redact(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, v := range trackedValues() {
+		s = strings.ReplaceAll(s, v, Mask)
+	}
+	return s
+}
+
+// RedactError returns err with tracked secret values removed from its
+// message. errors.Is and errors.As still see the original error. It returns
+// err unchanged when the message contains no tracked value.
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	redacted := Redact(msg)
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{err: err, msg: redacted}
+}
+
+// redactedError is an error whose message has been redacted.
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// trackedValues returns the tracked values, longest first.
+func trackedValues() []string {
+	tracked.mu.RLock()
+	sorted := tracked.sorted
+	n := len(tracked.values)
+	tracked.mu.RUnlock()
+	if len(sorted) == n {
+		return sorted
+	}
+
+	tracked.mu.Lock()
+	defer tracked.mu.Unlock()
+	sorted = make([]string, 0, len(tracked.values))
+	for v := range tracked.values {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	tracked.sorted = sorted
+	return sorted
+}
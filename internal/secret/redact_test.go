@@ -0,0 +1,42 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	Track("redact-test-token", "redact-test-token-extended", "ab")
+
+	t.Run("replaces tracked values", func(t *testing.T) {
+		assert.Equal(t, "bad value ***", Redact("bad value redact-test-token"))
+	})
+
+	t.Run("masks the longest value whole", func(t *testing.T) {
+		assert.Equal(t, "got ***", Redact("got redact-test-token-extended"))
+	})
+
+	t.Run("ignores short values", func(t *testing.T) {
+		assert.Equal(t, "ab", Redact("ab"))
+	})
+
+	t.Run("leaves other text alone", func(t *testing.T) {
+		assert.Equal(t, "nothing to hide", Redact("nothing to hide"))
+	})
+}
+
+func TestRedactError(t *testing.T) {
+	Track("redact-error-secret")
+	sentinel := errors.New("sentinel")
+
+	err := RedactError(fmt.Errorf("storing redact-error-secret: %w", sentinel))
+	assert.EqualError(t, err, "storing ***: sentinel")
+	assert.ErrorIs(t, err, sentinel)
+
+	plain := errors.New("no secrets here")
+	assert.Same(t, plain, RedactError(plain))
+	assert.NoError(t, RedactError(nil))
+}
@@ -0,0 +1,79 @@
+package suggest
+
+import (
+	"sort"
+	"strings"
+)
+
+// Scores of a fuzzy match: every matched character scores matchScore, plus
+// a bonus when it directly follows the previous match or starts a word,
+// minus gapPenalty for every character skipped between matches.
+const (
+	matchScore       = 1
+	consecutiveBonus = 4
+	boundaryBonus    = 3
+	gapPenalty       = 1
+)
+
+// Fuzzy returns the candidates that contain the characters of query in
+// order, as fzf matches them, best matches first. Matches score higher
+// when the characters are consecutive or start words of the candidate
+// (after "_", "-", ".", or "/"); ties keep the order of candidates.
+// An empty query matches every candidate.
+//
+// Comparison is case-insensitive, but original candidate strings are returned.
+func Fuzzy(query string, candidates []string) []string {
+	query = strings.ToLower(query)
+
+	type scored struct {
+		key   string
+		score int
+	}
+
+	var matches []scored
+	for _, c := range candidates {
+		if score, ok := fuzzyScore(query, strings.ToLower(c)); ok {
+			matches = append(matches, scored{key: c, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	result := make([]string, len(matches))
+	for i, m := range matches {
+		result[i] = m.key
+	}
+	return result
+}
+
+// fuzzyScore matches each character of query to its first occurrence in
+// candidate after the previous one, and reports whether all matched.
+func fuzzyScore(query, candidate string) (int, bool) {
+	score := 0
+	last := -1
+	runes := []rune(candidate)
+	i := 0
+	for _, q := range query {
+		for i < len(runes) && runes[i] != q {
+			i++
+		}
+		if i == len(runes) {
+			return 0, false
+		}
+		score += matchScore
+		switch {
+		case last >= 0 && i == last+1:
+			score += consecutiveBonus
+		case i == 0 || strings.ContainsRune("_-./", runes[i-1]):
+			score += boundaryBonus
+		}
+		if last >= 0 {
+			score -= (i - last - 1) * gapPenalty
+		}
+		last = i
+		i++
+	}
+	return score, true
+}
@@ -0,0 +1,32 @@
+package suggest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzy(t *testing.T) {
+	candidates := []string{"USER_ROLE", "DATABASE_URL", "DB_PASSWORD", "API_KEY", "REDIS_URL", "DEBUG"}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"empty query keeps order", "", candidates},
+		{"subsequence", "dburl", []string{"DATABASE_URL"}},
+		{"case-insensitive", "apikey", []string{"API_KEY"}},
+		{"consecutive beats scattered", "url", []string{"DATABASE_URL", "REDIS_URL", "USER_ROLE"}},
+		{"word starts beat gaps", "dp", []string{"DB_PASSWORD"}},
+		{"prefix", "de", []string{"DEBUG", "DATABASE_URL"}},
+		{"no match", "xyz", []string{}},
+		{"order matters", "lru", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Fuzzy(tt.query, candidates))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Package tui provides the terminal primitives behind envref's interactive
+// commands: decoding key presses from a raw-mode terminal and drawing full
+// screens with ANSI escape sequences.
+package tui
+
+import (
+	"bufio"
+)
+
+// KeyCode identifies a key press.
+type KeyCode int
+
+const (
+	// KeyRune is a printable character; Key.Rune holds it.
+	KeyRune KeyCode = iota
+	// KeyEnter is Enter or Return.
+	KeyEnter
+	// KeyEsc is a lone Escape.
+	KeyEsc
+	// KeyBackspace is Backspace or Delete.
+	KeyBackspace
+	// KeyTab is Tab.
+	KeyTab
+	// KeyUp is the up arrow.
+	KeyUp
+	// KeyDown is the down arrow.
+	KeyDown
+	// KeyLeft is the left arrow.
+	KeyLeft
+	// KeyRight is the right arrow.
+	KeyRight
+	// KeyCtrlC is Ctrl-C, which raw mode delivers as a key instead of a
+	// signal.
+	KeyCtrlC
+	// KeyCtrlU is Ctrl-U, which clears an input line.
+	KeyCtrlU
+	// KeyUnknown is any other control character or escape sequence.
+	KeyUnknown
+)
+
+// Key is a decoded key press.
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// ReadKey reads one key press from r, which reads a terminal in raw mode.
+// Arrow keys arrive as escape sequences (ESC [ A); an escape byte with
+// nothing buffered after it is a lone Escape.
+func ReadKey(r *bufio.Reader) (Key, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+	switch c {
+	case '\r', '\n':
+		return Key{Code: KeyEnter}, nil
+	case '\t':
+		return Key{Code: KeyTab}, nil
+	case 0x7f, 0x08:
+		return Key{Code: KeyBackspace}, nil
+	case 0x03:
+		return Key{Code: KeyCtrlC}, nil
+	case 0x15:
+		return Key{Code: KeyCtrlU}, nil
+	case 0x1b:
+		return readEscape(r)
+	}
+	if c < 0x20 {
+		return Key{Code: KeyUnknown}, nil
+	}
+	return Key{Code: KeyRune, Rune: c}, nil
+}
+
+// readEscape decodes the rest of an escape sequence.
+func readEscape(r *bufio.Reader) (Key, error) {
+	if r.Buffered() == 0 {
+		return Key{Code: KeyEsc}, nil
+	}
+	b, err := r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+	if b != '[' && b != 'O' {
+		return Key{Code: KeyUnknown}, nil
+	}
+	// Parameters (e.g. "1;5" for Ctrl-arrows) precede the final byte.
+	for {
+		b, err = r.ReadByte()
+		if err != nil {
+			return Key{}, err
+		}
+		if b < '0' || b > '?' {
+			break
+		}
+	}
+	switch b {
+	case 'A':
+		return Key{Code: KeyUp}, nil
+	case 'B':
+		return Key{Code: KeyDown}, nil
+	case 'C':
+		return Key{Code: KeyRight}, nil
+	case 'D':
+		return Key{Code: KeyLeft}, nil
+	}
+	return Key{Code: KeyUnknown}, nil
+}
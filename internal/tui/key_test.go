@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("aé\r\x7f\t\x03\x15\x1b[A\x1b[B\x1b[C\x1bOD\x1b[1;5A\x01\x1b"))
+	want := []Key{
+		{Code: KeyRune, Rune: 'a'},
+		{Code: KeyRune, Rune: 'é'},
+		{Code: KeyEnter},
+		{Code: KeyBackspace},
+		{Code: KeyTab},
+		{Code: KeyCtrlC},
+		{Code: KeyCtrlU},
+		{Code: KeyUp},
+		{Code: KeyDown},
+		{Code: KeyRight},
+		{Code: KeyLeft},
+		{Code: KeyUp},
+		{Code: KeyUnknown},
+		{Code: KeyEsc},
+	}
+	for i, w := range want {
+		got, err := ReadKey(r)
+		require.NoError(t, err, "key %d", i)
+		assert.Equal(t, w, got, "key %d", i)
+	}
+	_, err := ReadKey(r)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestFit(t *testing.T) {
+	assert.Equal(t, "abc  ", Fit("abc", 5))
+	assert.Equal(t, "abcd…", Fit("abcdefg", 5))
+	assert.Equal(t, "é", Fit("é", 1))
+	assert.Equal(t, "", Fit("abc", 0))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", Truncate("abc", 5))
+	assert.Equal(t, "abcd…", Truncate("abcdefg", 5))
+	assert.Equal(t, "", Truncate("abc", 0))
+}
@@ -0,0 +1,88 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xcke/envref/internal/suggest"
+)
+
+// ErrCancelled is returned by Pick when the user leaves it with Esc or
+// Ctrl-C instead of picking an item.
+var ErrCancelled = errors.New("cancelled")
+
+// Screen is a terminal that interactive views read keys from and draw on.
+// *Terminal implements it.
+type Screen interface {
+	ReadKey() (Key, error)
+	Draw(lines []string) error
+	Size() (width, height int)
+}
+
+// Pick lets the user choose one of items, narrowed down as they type by a
+// fuzzy query (see suggest.Fuzzy), and returns the item chosen with Enter.
+func Pick(s Screen, prompt string, items []string) (string, error) {
+	var query []rune
+	matches := items
+	cursor, top := 0, 0
+	for {
+		width, height := s.Size()
+		// The prompt and the match count take two lines.
+		listHeight := max(height-2, 1)
+		if cursor < top {
+			top = cursor
+		}
+		if cursor >= top+listHeight {
+			top = cursor - listHeight + 1
+		}
+
+		lines := []string{
+			Truncate(prompt+"> "+string(query), width),
+			Truncate(fmt.Sprintf("  %d/%d", len(matches), len(items)), width),
+		}
+		for i := top; i < len(matches) && i < top+listHeight; i++ {
+			if i == cursor {
+				lines = append(lines, Reverse(Fit("> "+matches[i], width)))
+			} else {
+				lines = append(lines, Truncate("  "+matches[i], width))
+			}
+		}
+		if err := s.Draw(lines); err != nil {
+			return "", err
+		}
+
+		key, err := s.ReadKey()
+		if err != nil {
+			return "", err
+		}
+		switch key.Code {
+		case KeyEnter:
+			if len(matches) > 0 {
+				return matches[cursor], nil
+			}
+			continue
+		case KeyEsc, KeyCtrlC:
+			return "", ErrCancelled
+		case KeyUp:
+			cursor = max(cursor-1, 0)
+			continue
+		case KeyDown:
+			cursor = min(cursor+1, max(len(matches)-1, 0))
+			continue
+		case KeyBackspace:
+			if len(query) == 0 {
+				continue
+			}
+			query = query[:len(query)-1]
+		case KeyCtrlU:
+			query = nil
+		case KeyRune:
+			query = append(query, key.Rune)
+		default:
+			continue
+		}
+		// The query changed: match again from the best match.
+		matches = suggest.Fuzzy(string(query), items)
+		cursor, top = 0, 0
+	}
+}
@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeScreen replays scripted key presses and records the frames drawn.
+type fakeScreen struct {
+	keys   []Key
+	frames [][]string
+}
+
+func (f *fakeScreen) ReadKey() (Key, error) {
+	if len(f.keys) == 0 {
+		return Key{}, io.EOF
+	}
+	k := f.keys[0]
+	f.keys = f.keys[1:]
+	return k, nil
+}
+
+func (f *fakeScreen) Draw(lines []string) error {
+	f.frames = append(f.frames, lines)
+	return nil
+}
+
+func (f *fakeScreen) Size() (int, int) { return 40, 5 }
+
+// typed returns the key presses of typing s.
+func typed(s string) []Key {
+	var keys []Key
+	for _, r := range s {
+		keys = append(keys, Key{Code: KeyRune, Rune: r})
+	}
+	return keys
+}
+
+var items = []string{"DATABASE_URL", "DB_PASSWORD", "API_KEY", "REDIS_URL", "DEBUG"}
+
+func TestPick_Enter(t *testing.T) {
+	s := &fakeScreen{keys: []Key{{Code: KeyEnter}}}
+	got, err := Pick(s, "key", items)
+	require.NoError(t, err)
+	assert.Equal(t, "DATABASE_URL", got)
+
+	frame := strings.Join(s.frames[0], "\n")
+	assert.Contains(t, frame, "key> \n  5/5\n")
+	// The list scrolls within the three lines left by a 5-line screen.
+	assert.Len(t, s.frames[0], 5)
+}
+
+func TestPick_Query(t *testing.T) {
+	s := &fakeScreen{keys: append(typed("url"), Key{Code: KeyDown}, Key{Code: KeyEnter})}
+	got, err := Pick(s, "key", items)
+	require.NoError(t, err)
+	assert.Equal(t, "REDIS_URL", got)
+	assert.Contains(t, strings.Join(s.frames[len(s.frames)-1], "\n"), "key> url\n  2/5\n")
+}
+
+func TestPick_Backspace(t *testing.T) {
+	keys := append(typed("xq"), Key{Code: KeyBackspace}, Key{Code: KeyBackspace}, Key{Code: KeyCtrlU})
+	keys = append(keys, typed("api")...)
+	s := &fakeScreen{keys: append(keys, Key{Code: KeyEnter})}
+	got, err := Pick(s, "key", items)
+	require.NoError(t, err)
+	assert.Equal(t, "API_KEY", got)
+}
+
+func TestPick_NoMatchIgnoresEnter(t *testing.T) {
+	s := &fakeScreen{keys: append(typed("zz"), Key{Code: KeyEnter}, Key{Code: KeyEsc})}
+	_, err := Pick(s, "key", items)
+	assert.ErrorIs(t, err, ErrCancelled)
+	assert.Contains(t, strings.Join(s.frames[len(s.frames)-1], "\n"), "0/5")
+}
+
+func TestPick_Cancel(t *testing.T) {
+	_, err := Pick(&fakeScreen{keys: []Key{{Code: KeyCtrlC}}}, "key", items)
+	assert.ErrorIs(t, err, ErrCancelled)
+
+	_, err = Pick(&fakeScreen{}, "key", items)
+	assert.ErrorIs(t, err, io.EOF)
+}
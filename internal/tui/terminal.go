@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ANSI escape sequences used to take over the screen.
+const (
+	altScreenOn  = "\033[?1049h"
+	altScreenOff = "\033[?1049l"
+	cursorHide   = "\033[?25l"
+	cursorShow   = "\033[?25h"
+	clearScreen  = "\033[H\033[2J"
+)
+
+// Terminal is a terminal in raw mode showing a full-screen interface on
+// its alternate screen.
+type Terminal struct {
+	in    *os.File
+	out   io.Writer
+	r     *bufio.Reader
+	state *term.State
+}
+
+// Open puts the terminal in into raw mode and switches out to the
+// alternate screen. Close restores both.
+func Open(in *os.File, out io.Writer) (*Terminal, error) {
+	t := &Terminal{in: in, out: out, r: bufio.NewReader(in)}
+	if err := t.Resume(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// IsTerminal reports whether f is a terminal.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// ReadKey reads the next key press.
+func (t *Terminal) ReadKey() (Key, error) {
+	return ReadKey(t.r)
+}
+
+// Size returns the width and height of the terminal, or 80x24 when it
+// cannot be determined.
+func (t *Terminal) Size() (width, height int) {
+	width, height, err := term.GetSize(int(t.in.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// Draw replaces the screen with lines.
+func (t *Terminal) Draw(lines []string) error {
+	_, err := io.WriteString(t.out, clearScreen+strings.Join(lines, "\r\n"))
+	return err
+}
+
+// Suspend returns the terminal to its normal mode and screen, e.g. while
+// another prompt reads from it. Resume takes it over again.
+func (t *Terminal) Suspend() error {
+	if t.state == nil {
+		return nil
+	}
+	_, _ = io.WriteString(t.out, cursorShow+altScreenOff)
+	err := term.Restore(int(t.in.Fd()), t.state)
+	t.state = nil
+	return err
+}
+
+// Resume puts the terminal back into raw mode on the alternate screen.
+func (t *Terminal) Resume() error {
+	if t.state != nil {
+		return nil
+	}
+	state, err := term.MakeRaw(int(t.in.Fd()))
+	if err != nil {
+		return fmt.Errorf("entering raw mode: %w", err)
+	}
+	t.state = state
+	_, _ = io.WriteString(t.out, altScreenOn+cursorHide)
+	return nil
+}
+
+// Close restores the terminal.
+func (t *Terminal) Close() error {
+	return t.Suspend()
+}
+
+// Fit pads or truncates s to exactly width runes (see Truncate).
+func Fit(s string, width int) string {
+	s = Truncate(s, width)
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
+
+// Truncate shortens s to at most width runes, marking truncation with an
+// ellipsis. Escape sequences are not accounted for, so s must be plain.
+func Truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// Reverse renders s in reverse video, as used for the selected line.
+func Reverse(s string) string {
+	return "\033[7m" + s + "\033[0m"
+}
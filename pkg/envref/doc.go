@@ -0,0 +1,44 @@
+// Package envref is the public Go API of envref: it parses .env files,
+// loads envref projects and merges their env layers, and resolves ref://
+// references through secret backends, so that Go tools can embed envref
+// instead of running the CLI.
+//
+// # Stability
+//
+// The package follows semantic versioning: within a major version, exported
+// identifiers are not removed or changed incompatibly, and new fields are
+// only added to structs that are not compared or constructed positionally.
+// Everything under internal/ may change at any time; this package is the
+// only supported way to use envref from Go.
+//
+// # Overview
+//
+// A [Project] is a directory with an .envref.yaml. [LoadProject] finds it
+// from any directory inside it, and [Project.Env] merges the env layers of
+// a profile (.env, .env.<profile>, .env.local, .env.<profile>.local, or the
+// configured env_files) and interpolates ${VAR} references, as 'envref
+// resolve' does. [Project.Resolve] then replaces every ref:// value with
+// the secret read from a [Backend], looking it up under the profile and
+// the profiles it inherits from:
+//
+//	p, err := envref.LoadProject(".")
+//	if err != nil {
+//		return err
+//	}
+//	env, err := p.Env("")
+//	if err != nil {
+//		return err
+//	}
+//	res, err := p.Resolve(ctx, env, "", myBackend)
+//	if err != nil {
+//		return err
+//	}
+//	if err := res.Err(); err != nil {
+//		return err
+//	}
+//	vars := res.Map()
+//
+// Programs that read their own configuration can call [Load] instead: it
+// does all of the above with the backends configured in .envref.yaml, and
+// can set the variables in the process environment.
+package envref
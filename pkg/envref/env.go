@@ -0,0 +1,103 @@
+package envref
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/envfile"
+	"github.com/xcke/envref/internal/ref"
+	"github.com/xcke/envref/internal/remote"
+)
+
+// Env is a merged, interpolated set of variables whose ref:// values are
+// not resolved yet. It is safe to read from several goroutines.
+type Env struct {
+	env *envfile.Env
+}
+
+// LoadEnv merges the .env files at paths, later files overriding earlier
+// ones, and interpolates ${VAR} references. Missing files are skipped. A
+// path may also be the http(s) URL of a remote env file, which must exist.
+func LoadEnv(paths ...string) (*Env, error) {
+	return loadEnv(paths, "", &config.Config{})
+}
+
+// loadEnv merges the files at paths, as 'envref resolve' does, in order:
+//
+//  1. Each path is loaded, later ones overriding earlier ones. Remote env
+//     files are fetched with cfg's remote settings. Remote files and
+//     required, if set, must exist; other missing files are skipped.
+//  2. Values in one of cfg's ref schemes are rewritten to ref://.
+//  3. enc:// values become references that decrypt them with cfg's
+//     encryption key.
+//  4. ${VAR} references are interpolated, also from the process
+//     environment with interpolate_system_env.
+//  5. Renamed keys are also set under their key_aliases.
+func loadEnv(paths []string, required string, cfg *config.Config) (*Env, error) {
+	var fetcher *remote.Fetcher
+	merged := envfile.NewEnv()
+	for _, path := range paths {
+		var layer *envfile.Env
+		var err error
+		switch {
+		case remote.IsURL(path):
+			if fetcher == nil {
+				if fetcher, err = cfg.Remote.Fetcher(nil); err != nil {
+					return nil, err
+				}
+			}
+			var data []byte
+			if data, err = fetcher.Fetch(path); err == nil {
+				layer, _, err = envfile.Read(bytes.NewReader(data))
+			}
+		case path == required:
+			layer, _, err = envfile.Load(path)
+		default:
+			layer, _, err = envfile.LoadOptional(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", path, err)
+		}
+		merged = envfile.Merge(merged, layer)
+	}
+	merged.ApplySchemes(ref.Schemes(cfg.RefSchemes))
+	merged.ApplyEncryption(cfg.Encryption.Key)
+	if cfg.InterpolateSystemEnv {
+		envfile.InterpolateWithEnviron(merged, os.Environ())
+	} else {
+		envfile.Interpolate(merged)
+	}
+	envfile.ApplyKeyAliases(merged, cfg.KeyAliases)
+	return &Env{env: merged}, nil
+}
+
+// Keys returns the variable names, in the order they were first defined.
+func (e *Env) Keys() []string {
+	return e.env.Keys()
+}
+
+// Len returns the number of variables.
+func (e *Env) Len() int {
+	return e.env.Len()
+}
+
+// Get returns the entry for key.
+func (e *Env) Get(key string) (Entry, bool) {
+	entry, ok := e.env.Get(key)
+	if !ok {
+		return Entry{}, false
+	}
+	return fromParserEntry(entry), true
+}
+
+// Entries returns every entry, in key order.
+func (e *Env) Entries() []Entry {
+	return fromParserEntries(e.env.All())
+}
+
+// Refs returns the entries whose value is a ref:// reference.
+func (e *Env) Refs() []Entry {
+	return fromParserEntries(e.env.Refs())
+}
@@ -0,0 +1,372 @@
+package envref
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xcke/envref/internal/ref"
+)
+
+// mapBackend is a Backend holding fixed secrets.
+type mapBackend struct {
+	name    string
+	secrets map[string]string
+}
+
+func (b mapBackend) Name() string { return b.name }
+
+func (b mapBackend) Get(key string) (string, error) {
+	if v, ok := b.secrets[key]; ok {
+		return v, nil
+	}
+	return "", ErrNotFound
+}
+
+// writeFiles writes files, by name relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+}
+
+func TestParse(t *testing.T) {
+	entries, err := Parse(strings.NewReader("# The port.\nPORT=3000\nAPI_KEY=\"ref://secrets/API_KEY\"\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Key: "PORT", Value: "3000", Line: 2, Comment: "The port."},
+		{Key: "API_KEY", Value: "ref://secrets/API_KEY", Line: 3, IsRef: true},
+	}, entries)
+}
+
+func TestProject_Env(t *testing.T) {
+	t.Setenv("ENVREF_PROFILE", "")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".envref.yaml": "project: app\nprofiles:\n  staging:\n    env_file: .env.staging\n",
+		".env":         "HOST=localhost\nURL=http://${HOST}:3000\nAPI_KEY=ref://secrets/API_KEY\n",
+		".env.staging": "HOST=staging.example.com\n",
+	})
+	sub := filepath.Join(dir, "cmd")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+
+	p, err := LoadProject(sub)
+	require.NoError(t, err)
+	assert.Equal(t, "app", p.Name())
+	assert.Equal(t, []string{"staging"}, p.Profiles())
+	assert.Equal(t, []string{
+		filepath.Join(p.Dir(), ".env"),
+		filepath.Join(p.Dir(), ".env.staging"),
+		filepath.Join(p.Dir(), ".env.local"),
+		filepath.Join(p.Dir(), ".env.staging.local"),
+	}, p.EnvFiles("staging"))
+
+	env, err := p.Env("staging")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"HOST", "URL", "API_KEY"}, env.Keys())
+	url, ok := env.Get("URL")
+	require.True(t, ok)
+	assert.Equal(t, "http://staging.example.com:3000", url.Value)
+	assert.Len(t, env.Refs(), 1)
+}
+
+func TestLoadProject_NoProject(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, err := LoadProject(t.TempDir())
+	assert.ErrorIs(t, err, ErrNoProject)
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".env": "PORT=3000\nAPI_KEY=ref://secrets/API_KEY\nDB_PASS=ref://secrets/DB_PASS\n",
+	})
+	env, err := LoadEnv(filepath.Join(dir, ".env"), filepath.Join(dir, ".env.local"))
+	require.NoError(t, err)
+
+	secrets := mapBackend{name: "secrets", secrets: map[string]string{
+		"app/API_KEY":         "sk-default",
+		"app/staging/API_KEY": "sk-staging",
+	}}
+	res, err := Resolve(context.Background(), env, "app", "staging", secrets)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Var{
+		{Key: "PORT", Value: "3000"},
+		{Key: "API_KEY", Value: "sk-staging", Secret: true},
+		{Key: "DB_PASS", Value: "ref://secrets/DB_PASS"},
+	}, res.Vars)
+	require.Len(t, res.Errors, 1)
+	assert.Equal(t, "DB_PASS", res.Errors[0].Key)
+	assert.ErrorContains(t, res.Err(), "1 reference(s) could not be resolved")
+	var keyErr *KeyError
+	require.True(t, errors.As(res.Err(), &keyErr))
+	assert.Equal(t, "ref://secrets/DB_PASS", keyErr.Ref)
+	assert.Contains(t, res.Environ(), "API_KEY=sk-staging")
+	assert.Equal(t, "3000", res.Map()["PORT"])
+}
+
+func TestResolve_CombinedProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{".env": "API_KEY=ref://secrets/API_KEY\nREGION=ref://secrets/REGION\n"})
+	env, err := LoadEnv(filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+
+	secrets := mapBackend{name: "secrets", secrets: map[string]string{
+		"app/production/API_KEY": "sk-production",
+		"app/eu/REGION":          "eu-west-1",
+	}}
+	res, err := Resolve(context.Background(), env, "app", "production,eu", secrets)
+	require.NoError(t, err)
+	require.NoError(t, res.Err())
+	assert.Equal(t, map[string]string{"API_KEY": "sk-production", "REGION": "eu-west-1"}, res.Map())
+}
+
+func TestResolve_NoRefs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{".env": "PORT=3000\n"})
+	env, err := LoadEnv(filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+
+	res, err := Resolve(context.Background(), env, "app", "")
+	require.NoError(t, err)
+	assert.NoError(t, res.Err())
+	assert.Equal(t, []string{"PORT=3000"}, res.Environ())
+}
+
+func TestResolve_CanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Resolve(ctx, &Env{}, "app", "")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// writeLoadProject writes a project whose "secrets" memory backend holds
+// secrets.
+func writeLoadProject(t *testing.T, secrets string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("ENVREF_PROFILE", "")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".envref.yaml": "project: app\nbackends:\n  - name: secrets\n    type: memory\n    config:\n      path: " +
+			filepath.Join(dir, "secrets.json") + "\nprofiles:\n  staging:\n    env_file: .env.staging\n",
+		".env":         "ENVREF_TEST_PORT=3000\nENVREF_TEST_API_KEY=ref://secrets/API_KEY\n",
+		".env.staging": "ENVREF_TEST_PORT=8080\n",
+		"secrets.json": secrets,
+	})
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeLoadProject(t, `{"app/API_KEY":"sk-default","app/staging/API_KEY":"sk-staging"}`)
+
+	vars, err := Load(context.Background(), Options{Dir: dir, Profile: "staging"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ENVREF_TEST_PORT": "8080", "ENVREF_TEST_API_KEY": "sk-staging"}, vars)
+	_, set := os.LookupEnv("ENVREF_TEST_PORT")
+	assert.False(t, set, "Load must not set variables without Setenv")
+}
+
+// writeInheritingProject writes a project whose staging profile inherits
+// from base, with the API key stored only under base.
+func writeInheritingProject(t *testing.T) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("ENVREF_PROFILE", "")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".envref.yaml": "project: app\nbackends:\n  - name: secrets\n    type: memory\n    config:\n      path: " +
+			filepath.Join(dir, "secrets.json") + "\nprofiles:\n  base: {}\n  staging:\n    inherits: base\n",
+		".env":         "ENVREF_TEST_API_KEY=ref://secrets/API_KEY\n",
+		"secrets.json": `{"app/base/API_KEY":"sk-base"}`,
+	})
+	return dir
+}
+
+func TestLoad_InheritedProfile(t *testing.T) {
+	dir := writeInheritingProject(t)
+
+	vars, err := Load(context.Background(), Options{Dir: dir, Profile: "staging"})
+	require.NoError(t, err)
+	assert.Equal(t, "sk-base", vars["ENVREF_TEST_API_KEY"])
+}
+
+func TestProject_Resolve(t *testing.T) {
+	dir := writeInheritingProject(t)
+	p, err := LoadProject(dir)
+	require.NoError(t, err)
+	env, err := p.Env("staging")
+	require.NoError(t, err)
+
+	secrets := mapBackend{name: "secrets", secrets: map[string]string{"app/base/API_KEY": "sk-base"}}
+	res, err := p.Resolve(context.Background(), env, "staging", secrets)
+	require.NoError(t, err)
+	require.NoError(t, res.Err())
+	assert.Equal(t, "sk-base", res.Map()["ENVREF_TEST_API_KEY"])
+
+	res, err = Resolve(context.Background(), env, "app", "staging", secrets)
+	require.NoError(t, err)
+	assert.Error(t, res.Err(), "Resolve has no config to follow inherits with")
+}
+
+func TestLoad_Setenv(t *testing.T) {
+	dir := writeLoadProject(t, `{"app/API_KEY":"sk-default"}`)
+	t.Setenv("ENVREF_TEST_PORT", "9000")
+	t.Setenv("ENVREF_TEST_API_KEY", "")
+	require.NoError(t, os.Unsetenv("ENVREF_TEST_API_KEY"))
+
+	_, err := Load(context.Background(), Options{Dir: dir, Setenv: true})
+	require.NoError(t, err)
+	assert.Equal(t, "9000", os.Getenv("ENVREF_TEST_PORT"))
+	assert.Equal(t, "sk-default", os.Getenv("ENVREF_TEST_API_KEY"))
+
+	_, err = Load(context.Background(), Options{Dir: dir, Setenv: true, Override: true})
+	require.NoError(t, err)
+	assert.Equal(t, "3000", os.Getenv("ENVREF_TEST_PORT"))
+}
+
+func TestLoad_Unresolved(t *testing.T) {
+	dir := writeLoadProject(t, `{}`)
+
+	_, err := Load(context.Background(), Options{Dir: dir})
+	var keyErr *KeyError
+	require.True(t, errors.As(err, &keyErr), "got %v", err)
+	assert.Equal(t, "ENVREF_TEST_API_KEY", keyErr.Key)
+
+	vars, err := Load(context.Background(), Options{Dir: dir, AllowUnresolved: true})
+	require.NoError(t, err)
+	assert.Equal(t, "ref://secrets/API_KEY", vars["ENVREF_TEST_API_KEY"])
+}
+
+func TestProject_Env_InterpolateSystemEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("ENVREF_PROFILE", "")
+	t.Setenv("ENVREF_TEST_HOST", "db.internal")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".envref.yaml": "project: app\ninterpolate_system_env: true\n",
+		".env":         "URL=postgres://${ENVREF_TEST_HOST}/app\n",
+	})
+
+	p, err := LoadProject(dir)
+	require.NoError(t, err)
+	env, err := p.Env("")
+	require.NoError(t, err)
+	url, _ := env.Get("URL")
+	assert.Equal(t, "postgres://db.internal/app", url.Value)
+
+	// Without the setting, unknown variables expand to nothing.
+	env, err = LoadEnv(filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+	url, _ = env.Get("URL")
+	assert.Equal(t, "postgres:///app", url.Value)
+}
+
+func TestProject_Env_KeyAliases(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("ENVREF_PROFILE", "")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".envref.yaml": "project: app\nkey_aliases:\n  DATABASE_URL: [DB_URL]\n",
+		".env":         "DB_URL=postgres://localhost/app\n",
+	})
+
+	vars, err := Load(context.Background(), Options{Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"DB_URL":       "postgres://localhost/app",
+		"DATABASE_URL": "postgres://localhost/app",
+	}, vars)
+}
+
+func TestLoad_Encrypted(t *testing.T) {
+	const key = "0123456789abcdef0123456789abcdef"
+	token, err := ref.Encrypt("tok123", key)
+	require.NoError(t, err)
+	dir := writeLoadProject(t, `{"app/API_KEY":"sk-default","app/envref_encryption_key":"`+key+`"}`)
+	writeFiles(t, dir, map[string]string{".env": "ENVREF_TEST_TOKEN=" + token + "\n"})
+	f, err := os.OpenFile(filepath.Join(dir, ".envref.yaml"), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("encryption:\n  key: ref://secrets/envref_encryption_key\n")
+	require.NoError(t, f.Close())
+	require.NoError(t, err)
+
+	vars, err := Load(context.Background(), Options{Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, "tok123", vars["ENVREF_TEST_TOKEN"])
+}
+
+func TestProject_Env_RemoteEnvFile(t *testing.T) {
+	const baseline = "LOG_LEVEL=info\nREGION=eu-west-1\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(baseline))
+	}))
+	defer srv.Close()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("ENVREF_PROFILE", "")
+
+	sum := sha256.Sum256([]byte(baseline))
+	url := srv.URL + "/service.env#sha256=" + hex.EncodeToString(sum[:])
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".envref.yaml": "project: app\nenv_files:\n  - " + url + "\n  - .env\n",
+		".env":         "LOG_LEVEL=debug\n",
+	})
+
+	p, err := LoadProject(dir)
+	require.NoError(t, err)
+	assert.Equal(t, url, p.EnvFiles("")[0])
+	vars, err := Load(context.Background(), Options{Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"LOG_LEVEL": "debug", "REGION": "eu-west-1"}, vars)
+
+	// A pinned digest that does not match is an error.
+	writeFiles(t, dir, map[string]string{".envref.yaml": "project: app\nenv_file: " + srv.URL + "/service.env#sha256=" + strings.Repeat("0", 64) + "\n"})
+	_, err = Load(context.Background(), Options{Dir: dir})
+	assert.ErrorContains(t, err, "checksum mismatch")
+}
+
+func TestLoad_ACL(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+	dir := writeLoadProject(t, `{"app/API_KEY":"sk-default","app/PROD_DB":"secret"}`)
+	config, err := os.ReadFile(filepath.Join(dir, ".envref.yaml"))
+	require.NoError(t, err)
+	writeFiles(t, dir, map[string]string{
+		".envref.yaml": string(config) + `team:
+  - name: alice
+    public_key: age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+    roles: [contributor]
+    identities: ["` + u.Username + `"]
+acl:
+  PROD_*: [admin]
+`,
+		".env": "ENVREF_TEST_API_KEY=ref://secrets/API_KEY\nENVREF_TEST_DB=ref://secrets/PROD_DB\n",
+	})
+
+	_, err = Load(context.Background(), Options{Dir: dir})
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	var keyErr *KeyError
+	require.True(t, errors.As(err, &keyErr), "got %v", err)
+	assert.Equal(t, "ENVREF_TEST_DB", keyErr.Key)
+
+	vars, err := Load(context.Background(), Options{Dir: dir, AllowUnresolved: true})
+	require.NoError(t, err)
+	assert.Equal(t, "sk-default", vars["ENVREF_TEST_API_KEY"])
+	assert.Equal(t, "ref://secrets/PROD_DB", vars["ENVREF_TEST_DB"])
+}
@@ -0,0 +1,100 @@
+package envref
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/backend/factory"
+	"github.com/xcke/envref/internal/config"
+)
+
+// Options configures Load.
+type Options struct {
+	// Dir is a directory inside the project. It defaults to the working
+	// directory.
+	Dir string
+
+	// Profile is the environment profile. It defaults to the active
+	// profile (see Project.ActiveProfile).
+	Profile string
+
+	// AllowUnresolved makes Load succeed when references fail to resolve;
+	// such variables keep their ref:// URI as value. By default any
+	// failure is an error.
+	AllowUnresolved bool
+
+	// Setenv sets every variable in the process environment, as
+	// os.Setenv does, in addition to returning it.
+	Setenv bool
+
+	// Override lets Setenv replace variables that are already set. By
+	// default the process environment wins, as with most dotenv loaders.
+	Override bool
+}
+
+// Load reads the project's .envref.yaml, merges the env layers of the
+// profile, and resolves its references through the backends configured
+// for the project, as 'envref resolve' does. It returns the variables by
+// name. It is meant to be called once when a program starts:
+//
+//	vars, err := envref.Load(ctx, envref.Options{Setenv: true})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Backends are opened without prompting: a vault backend needs its
+// passphrase in ENVREF_VAULT_PASSPHRASE or its config, and a running
+// envref agent is not used. The acl block is enforced as in the CLI.
+func Load(ctx context.Context, opts Options) (map[string]string, error) {
+	dir := opts.Dir
+	if dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("getting working directory: %w", err)
+		}
+		dir = cwd
+	}
+	p, err := LoadProject(dir)
+	if err != nil {
+		return nil, err
+	}
+	env, err := p.Env(opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	registry, err := factory.Registry(p.cfg, func(bc config.BackendConfig) (backend.Backend, error) {
+		return factory.Chained(bc, factory.Vault)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("initializing backends: %w", err)
+	}
+	defer registry.CloseAll()
+
+	res, err := resolveWith(env, registry, p.Name(), p.profileChain(opts.Profile))
+	if err != nil {
+		return nil, err
+	}
+	if !opts.AllowUnresolved {
+		if err := res.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Setenv {
+		for _, v := range res.Vars {
+			if _, set := os.LookupEnv(v.Key); set && !opts.Override {
+				continue
+			}
+			if err := os.Setenv(v.Key, v.Value); err != nil {
+				return nil, fmt.Errorf("setting %s: %w", v.Key, err)
+			}
+		}
+	}
+	return res.Map(), nil
+}
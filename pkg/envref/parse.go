@@ -0,0 +1,63 @@
+package envref
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/xcke/envref/internal/parser"
+)
+
+// Entry is a variable read from an .env file.
+type Entry struct {
+	// Key is the variable name.
+	Key string
+	// Value is the value with quotes and escapes processed.
+	Value string
+	// Line is the 1-based line where the entry starts, or 0 for entries
+	// that were not read from a file.
+	Line int
+	// IsRef reports whether Value is a ref:// reference.
+	IsRef bool
+	// Comment is the block of comment lines directly above the entry,
+	// without the leading "#".
+	Comment string
+}
+
+// Parse reads .env entries from r, in file order. A key that appears more
+// than once is returned each time it appears.
+func Parse(r io.Reader) ([]Entry, error) {
+	entries, _, err := parser.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return fromParserEntries(entries), nil
+}
+
+// ParseFile reads the .env entries of the file at path.
+func ParseFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	entries, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// fromParserEntries converts parser entries to the public type.
+func fromParserEntries(entries []parser.Entry) []Entry {
+	out := make([]Entry, len(entries))
+	for i, e := range entries {
+		out[i] = fromParserEntry(e)
+	}
+	return out
+}
+
+// fromParserEntry converts a parser entry to the public type.
+func fromParserEntry(e parser.Entry) Entry {
+	return Entry{Key: e.Key, Value: e.Value, Line: e.Line, IsRef: e.IsRef, Comment: e.Comment}
+}
@@ -0,0 +1,95 @@
+package envref
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/remote"
+)
+
+// ErrNoProject is returned by LoadProject when no .envref.yaml is found.
+var ErrNoProject = config.ErrNotFound
+
+// Project is an envref project: a directory with an .envref.yaml, merged
+// with the global config.
+type Project struct {
+	cfg *config.Config
+	dir string
+}
+
+// LoadProject loads the project whose .envref.yaml is in dir or the
+// nearest of its parents. It returns an error wrapping ErrNoProject if
+// there is none.
+func LoadProject(dir string) (*Project, error) {
+	cfg, projectDir, err := config.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Project{cfg: cfg, dir: projectDir}, nil
+}
+
+// Name returns the project name, which namespaces its secrets.
+func (p *Project) Name() string {
+	return p.cfg.Project
+}
+
+// Dir returns the directory that holds the project's .envref.yaml.
+func (p *Project) Dir() string {
+	return p.dir
+}
+
+// ActiveProfile returns the profile used when none is given: the
+// ENVREF_PROFILE environment variable, the profile branch_profiles selects
+// for the checked out git branch, or active_profile, or "".
+func (p *Project) ActiveProfile() string {
+	return p.cfg.EffectiveProfile("")
+}
+
+// Profiles returns the names of the profiles defined in the config, sorted.
+func (p *Project) Profiles() []string {
+	names := make([]string, 0, len(p.cfg.Profiles))
+	for name := range p.cfg.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// EnvFiles returns the paths of the env layers of profile, lowest
+// precedence first. An empty profile means the active profile.
+func (p *Project) EnvFiles(profile string) []string {
+	layers := p.cfg.EnvLayers(p.cfg.EffectiveProfile(profile))
+	paths := make([]string, len(layers))
+	for i, layer := range layers {
+		paths[i] = p.path(layer)
+	}
+	return paths
+}
+
+// Env merges the env layers of profile and interpolates them. An empty
+// profile means the active profile. The main env file must exist; the
+// other layers are optional.
+func (p *Project) Env(profile string) (*Env, error) {
+	env, err := loadEnv(p.EnvFiles(profile), p.path(p.cfg.EnvFile), p.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("project %s: %w", p.Name(), err)
+	}
+	return env, nil
+}
+
+// profileChain returns the profiles secrets are looked up under for
+// profile, nearest first. An empty profile means the active profile.
+func (p *Project) profileChain(profile string) []string {
+	return p.cfg.ProfileChain(p.cfg.EffectiveProfile(profile))
+}
+
+// path returns file relative to the project directory. URLs of remote env
+// files are returned as is.
+func (p *Project) path(file string) string {
+	if filepath.IsAbs(file) || remote.IsURL(file) {
+		return file
+	}
+	return filepath.Join(p.dir, file)
+}
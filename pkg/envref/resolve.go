@@ -0,0 +1,190 @@
+package envref
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xcke/envref/internal/backend"
+	"github.com/xcke/envref/internal/config"
+	"github.com/xcke/envref/internal/resolve"
+)
+
+// ErrNotFound is returned by a Backend's Get for a key it does not hold.
+var ErrNotFound = backend.ErrNotFound
+
+// ErrAccessDenied is wrapped by the KeyError of a reference to a secret
+// that the acl block of .envref.yaml does not let the caller read.
+var ErrAccessDenied = backend.ErrAccessDenied
+
+// Backend is a secret store that Resolve reads from.
+type Backend interface {
+	// Name returns the backend name used in ref:// URIs, such as
+	// "keychain" in ref://keychain/API_KEY.
+	Name() string
+
+	// Get returns the secret stored under key, or an error wrapping
+	// ErrNotFound. Keys are namespaced by project, and by profile when
+	// one is given, such as "myapp/API_KEY" or "myapp/staging/API_KEY".
+	Get(key string) (string, error)
+}
+
+// Var is a resolved variable.
+type Var struct {
+	// Key is the variable name.
+	Key string
+	// Value is the resolved value. It is the ref:// URI itself for a
+	// reference that failed to resolve.
+	Value string
+	// Secret reports whether Value was read from a backend.
+	Secret bool
+}
+
+// KeyError is a reference that failed to resolve.
+type KeyError struct {
+	// Key is the variable name.
+	Key string
+	// Ref is the ref:// URI.
+	Ref string
+	// Err is the reason.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("%s: failed to resolve %s: %v", e.Key, e.Ref, e.Err)
+}
+
+// Unwrap returns the reason.
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// Result is the outcome of Resolve.
+type Result struct {
+	// Vars holds every variable, in key order.
+	Vars []Var
+	// Errors holds the references that failed to resolve.
+	Errors []*KeyError
+}
+
+// Err returns an error listing the references that failed to resolve, or
+// nil if every reference resolved.
+func (r *Result) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(r.Errors))
+	errs := make([]error, len(r.Errors))
+	for i, e := range r.Errors {
+		msgs[i] = e.Error()
+		errs[i] = e
+	}
+	return &resolveError{msg: fmt.Sprintf("%d reference(s) could not be resolved: %s", len(msgs), strings.Join(msgs, "; ")), errs: errs}
+}
+
+// Map returns the variables as a map from name to value.
+func (r *Result) Map() map[string]string {
+	m := make(map[string]string, len(r.Vars))
+	for _, v := range r.Vars {
+		m[v.Key] = v.Value
+	}
+	return m
+}
+
+// Environ returns the variables as KEY=value strings, in the form of
+// os.Environ and exec.Cmd.Env.
+func (r *Result) Environ() []string {
+	environ := make([]string, len(r.Vars))
+	for i, v := range r.Vars {
+		environ[i] = v.Key + "=" + v.Value
+	}
+	return environ
+}
+
+// resolveError is the error returned by Result.Err.
+type resolveError struct {
+	msg  string
+	errs []error
+}
+
+func (e *resolveError) Error() string   { return e.msg }
+func (e *resolveError) Unwrap() []error { return e.errs }
+
+// Resolve replaces the ref:// values of env with secrets read from
+// backends, trying a reference's named backend first and the others in
+// order if it names none. Secrets are looked up under project, and first
+// under project/profile when profile is set. A combined profile such as
+// "production,eu" is looked up under each part, the last first. Profiles
+// that inherit from others are followed by Project.Resolve, which has the
+// config that declares them.
+//
+// A reference that fails to resolve is reported in Result.Errors, not as
+// an error; use Result.Err to treat any failure as fatal.
+func Resolve(ctx context.Context, env *Env, project, profile string, backends ...Backend) (*Result, error) {
+	return resolveChain(ctx, env, project, (&config.Config{}).ProfileChain(profile), backends)
+}
+
+// Resolve is Resolve for the project: secrets are looked up under its
+// name, and under profile and the profiles it inherits from, as in Load.
+// An empty profile means the active profile.
+func (p *Project) Resolve(ctx context.Context, env *Env, profile string, backends ...Backend) (*Result, error) {
+	return resolveChain(ctx, env, p.Name(), p.profileChain(profile), backends)
+}
+
+// resolveChain resolves env through backends, looking secrets up under
+// each of profiles in turn before the project.
+func resolveChain(ctx context.Context, env *Env, project string, profiles []string, backends []Backend) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	registry := backend.NewRegistry()
+	for _, b := range backends {
+		if err := registry.Register(readOnlyBackend{b}); err != nil {
+			return nil, err
+		}
+	}
+	return resolveWith(env, registry, project, profiles)
+}
+
+// resolveWith resolves env through registry, looking secrets up under
+// each of profiles in turn before the project.
+func resolveWith(env *Env, registry *backend.Registry, project string, profiles []string) (*Result, error) {
+	if env == nil {
+		return nil, errors.New("env must not be nil")
+	}
+	if !env.env.HasAnyRefs() {
+		vars := make([]Var, 0, env.Len())
+		for _, e := range env.env.All() {
+			vars = append(vars, Var{Key: e.Key, Value: e.Value})
+		}
+		return &Result{Vars: vars}, nil
+	}
+
+	res, err := resolve.ResolveWithProfiles(env.env, registry, project, profiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Vars: make([]Var, len(res.Entries))}
+	failed := make(map[string]bool, len(res.Errors))
+	for _, e := range res.Errors {
+		result.Errors = append(result.Errors, &KeyError{Key: e.Key, Ref: e.Ref, Err: e.Err})
+		failed[e.Key] = true
+	}
+	for i, e := range res.Entries {
+		result.Vars[i] = Var{Key: e.Key, Value: e.Value, Secret: e.WasRef && !failed[e.Key]}
+	}
+	return result, nil
+}
+
+// readOnlyBackend adapts a Backend to the internal interface.
+type readOnlyBackend struct {
+	Backend
+}
+
+var errReadOnly = errors.New("envref: backends are read-only")
+
+func (readOnlyBackend) Set(key, value string) error { return errReadOnly }
+func (readOnlyBackend) Delete(key string) error     { return errReadOnly }
+func (readOnlyBackend) List() ([]string, error)     { return nil, errReadOnly }
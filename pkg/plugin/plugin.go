@@ -0,0 +1,263 @@
+// Package plugin implements version 3 of the envref plugin protocol, in
+// which a backend plugin is a long-lived process serving gRPC with
+// github.com/hashicorp/go-plugin.
+//
+// A plugin implements Backend and calls Serve from its main function:
+//
+//	func main() {
+//		plugin.Serve(&myBackend{})
+//	}
+//
+// and is configured in .envref.yaml with protocol "3":
+//
+//	backends:
+//	  - name: my-vault
+//	    type: plugin
+//	    config:
+//	      protocol: "3"
+//
+// envref and the plugin agree on the protocol version during the go-plugin
+// handshake, so a plugin built against a newer version of this package
+// keeps working with an older envref for as long as both speak a common
+// version. The service is defined in pluginpb/backend.proto; plugins in
+// other languages can implement it with any go-plugin compatible server.
+package plugin
+
+import (
+	"context"
+	"errors"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/xcke/envref/pkg/plugin/pluginpb"
+)
+
+//go:generate protoc -I pluginpb --go_out=pluginpb --go_opt=paths=source_relative --go-grpc_out=pluginpb --go-grpc_opt=paths=source_relative backend.proto
+
+// ProtocolVersion is the plugin protocol version served by this package.
+const ProtocolVersion = 3
+
+// PluginName is the name of the backend plugin in the go-plugin plugin set.
+const PluginName = "backend"
+
+// Handshake is the go-plugin handshake shared by envref and its plugins.
+// The magic cookie only keeps a plugin from being run by hand; it is not a
+// security measure.
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  ProtocolVersion,
+	MagicCookieKey:   "ENVREF_PLUGIN",
+	MagicCookieValue: "a3d5c1e0-envref-backend",
+}
+
+// ErrNotFound is returned by Backend.Get and Backend.Delete for a key that
+// does not exist. It is sent as the gRPC NotFound status code.
+var ErrNotFound = errors.New("not found")
+
+// ErrUnimplemented is returned by Client.GetMany and Client.Ping when the
+// plugin does not implement them.
+var ErrUnimplemented = errors.New("not implemented by plugin")
+
+// Backend is a secret backend served by a plugin.
+type Backend interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string) error
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context) ([]string, error)
+}
+
+// BatchGetter is implemented by a Backend that can retrieve many secrets in
+// one call. GetMany returns only the keys that exist.
+type BatchGetter interface {
+	GetMany(ctx context.Context, keys []string) (map[string]string, error)
+}
+
+// Pinger is implemented by a Backend that can check it reaches its store.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PluginSet returns the go-plugin plugin sets for the protocol versions
+// this package speaks, serving impl (nil on the envref side).
+func PluginSet(impl Backend) map[int]goplugin.PluginSet {
+	return map[int]goplugin.PluginSet{
+		ProtocolVersion: {PluginName: &GRPCPlugin{Impl: impl}},
+	}
+}
+
+// Serve serves b as an envref backend plugin. It does not return until
+// envref stops the plugin.
+func Serve(b Backend) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig:  Handshake,
+		VersionedPlugins: PluginSet(b),
+		GRPCServer:       goplugin.DefaultGRPCServer,
+	})
+}
+
+// GRPCPlugin is the go-plugin plugin of an envref backend.
+type GRPCPlugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+
+	// Impl is the backend served by the plugin process.
+	Impl Backend
+}
+
+// GRPCServer registers the backend service on s.
+func (p *GRPCPlugin) GRPCServer(_ *goplugin.GRPCBroker, s *grpc.Server) error {
+	pluginpb.RegisterBackendServer(s, &server{impl: p.Impl})
+	return nil
+}
+
+// GRPCClient returns a *Client talking to the plugin over conn.
+func (p *GRPCPlugin) GRPCClient(_ context.Context, _ *goplugin.GRPCBroker, conn *grpc.ClientConn) (any, error) {
+	return NewClient(conn), nil
+}
+
+// server adapts a Backend to the gRPC service.
+type server struct {
+	pluginpb.UnimplementedBackendServer
+	impl Backend
+}
+
+func (s *server) Get(ctx context.Context, req *pluginpb.GetRequest) (*pluginpb.GetResponse, error) {
+	value, err := s.impl.Get(ctx, req.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pluginpb.GetResponse{Value: value}, nil
+}
+
+func (s *server) GetMany(ctx context.Context, req *pluginpb.GetManyRequest) (*pluginpb.GetManyResponse, error) {
+	bg, ok := s.impl.(BatchGetter)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "get_many is not implemented")
+	}
+	values, err := bg.GetMany(ctx, req.GetKeys())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pluginpb.GetManyResponse{Values: values}, nil
+}
+
+func (s *server) Set(ctx context.Context, req *pluginpb.SetRequest) (*pluginpb.Empty, error) {
+	if err := s.impl.Set(ctx, req.GetKey(), req.GetValue()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pluginpb.Empty{}, nil
+}
+
+func (s *server) Delete(ctx context.Context, req *pluginpb.DeleteRequest) (*pluginpb.Empty, error) {
+	if err := s.impl.Delete(ctx, req.GetKey()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pluginpb.Empty{}, nil
+}
+
+func (s *server) List(ctx context.Context, _ *pluginpb.Empty) (*pluginpb.ListResponse, error) {
+	keys, err := s.impl.List(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pluginpb.ListResponse{Keys: keys}, nil
+}
+
+func (s *server) Ping(ctx context.Context, _ *pluginpb.Empty) (*pluginpb.Empty, error) {
+	if p, ok := s.impl.(Pinger); ok {
+		if err := p.Ping(ctx); err != nil {
+			return nil, toStatus(err)
+		}
+	}
+	return &pluginpb.Empty{}, nil
+}
+
+// toStatus converts a Backend error to a gRPC status error.
+func toStatus(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// Client is the envref side of a backend plugin.
+type Client struct {
+	client pluginpb.BackendClient
+}
+
+// NewClient returns a Client for the backend service served on conn.
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{client: pluginpb.NewBackendClient(conn)}
+}
+
+// Get retrieves a secret value, or returns ErrNotFound.
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	resp, err := c.client.Get(ctx, &pluginpb.GetRequest{Key: key})
+	if err != nil {
+		return "", fromStatus(err)
+	}
+	return resp.GetValue(), nil
+}
+
+// GetMany retrieves the secrets that exist among keys, or returns
+// ErrUnimplemented if the plugin cannot.
+func (c *Client) GetMany(ctx context.Context, keys []string) (map[string]string, error) {
+	resp, err := c.client.GetMany(ctx, &pluginpb.GetManyRequest{Keys: keys})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	if resp.GetValues() == nil {
+		return map[string]string{}, nil
+	}
+	return resp.GetValues(), nil
+}
+
+// Set stores a secret value.
+func (c *Client) Set(ctx context.Context, key, value string) error {
+	_, err := c.client.Set(ctx, &pluginpb.SetRequest{Key: key, Value: value})
+	return fromStatus(err)
+}
+
+// Delete removes a secret, or returns ErrNotFound.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	_, err := c.client.Delete(ctx, &pluginpb.DeleteRequest{Key: key})
+	return fromStatus(err)
+}
+
+// List returns all secret keys.
+func (c *Client) List(ctx context.Context) ([]string, error) {
+	resp, err := c.client.List(ctx, &pluginpb.Empty{})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	if resp.GetKeys() == nil {
+		return []string{}, nil
+	}
+	return resp.GetKeys(), nil
+}
+
+// Ping checks that the plugin reaches its store.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.client.Ping(ctx, &pluginpb.Empty{})
+	return fromStatus(err)
+}
+
+// fromStatus converts a gRPC status error to ErrNotFound, ErrUnimplemented,
+// or an error with the plugin's message.
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.NotFound:
+		return ErrNotFound
+	case codes.Unimplemented:
+		return ErrUnimplemented
+	}
+	return errors.New(st.Message())
+}
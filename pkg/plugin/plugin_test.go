@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memBackend is a Backend without GetMany or Ping.
+type memBackend map[string]string
+
+func (m memBackend) Get(_ context.Context, key string) (string, error) {
+	v, ok := m[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func (m memBackend) Set(_ context.Context, key, value string) error {
+	m[key] = value
+	return nil
+}
+
+func (m memBackend) Delete(_ context.Context, key string) error {
+	if _, ok := m[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m, key)
+	return nil
+}
+
+func (m memBackend) List(_ context.Context) ([]string, error) {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// failingPinger is a memBackend whose store cannot be reached.
+type failingPinger struct {
+	memBackend
+}
+
+func (failingPinger) Ping(context.Context) error {
+	return errors.New("store unreachable")
+}
+
+// testClient serves impl over an in-process gRPC connection and returns a
+// client for it.
+func testClient(t *testing.T, impl Backend) *Client {
+	t.Helper()
+	conn, server := goplugin.TestPluginGRPCConn(t, false, PluginSet(impl)[ProtocolVersion])
+	t.Cleanup(func() {
+		_ = conn.Close()
+		server.Stop()
+	})
+	raw, err := conn.Dispense(PluginName)
+	require.NoError(t, err)
+	client, ok := raw.(*Client)
+	require.True(t, ok, "dispensed %T", raw)
+	return client
+}
+
+func TestClient_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t, memBackend{})
+
+	require.NoError(t, c.Set(ctx, "API_KEY", "secret"))
+	v, err := c.Get(ctx, "API_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", v)
+
+	keys, err := c.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"API_KEY"}, keys)
+
+	_, err = c.Get(ctx, "MISSING")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, c.Delete(ctx, "MISSING"), ErrNotFound)
+	require.NoError(t, c.Delete(ctx, "API_KEY"))
+
+	keys, err = c.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	assert.NotNil(t, keys)
+
+	// Without BatchGetter and Pinger, GetMany is unimplemented and Ping
+	// succeeds.
+	_, err = c.GetMany(ctx, []string{"API_KEY"})
+	assert.ErrorIs(t, err, ErrUnimplemented)
+	assert.NoError(t, c.Ping(ctx))
+}
+
+func TestClient_PingError(t *testing.T) {
+	c := testClient(t, failingPinger{memBackend{}})
+	err := c.Ping(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "store unreachable", err.Error())
+}